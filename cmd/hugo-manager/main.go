@@ -1,29 +1,62 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/fernandezvara/hugo-manager/internal/config"
 	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/logging"
+	"github.com/fernandezvara/hugo-manager/internal/ports"
 	"github.com/fernandezvara/hugo-manager/internal/server"
+	"github.com/fernandezvara/hugo-manager/internal/starters"
+	"github.com/fernandezvara/hugo-manager/internal/synth"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/users"
 	"github.com/fernandezvara/hugo-manager/web"
 )
 
 var version = "0.1.0"
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "synth" {
+		runSynth(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "new-site" {
+		runNewSite(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "add-user" {
+		runAddUser(os.Args[2:])
+		return
+	}
+
 	// Command line flags
+	host := flag.String("host", "", "Address to bind the web interface to (e.g. 0.0.0.0)")
 	port := flag.Int("port", 8080, "Port for the web interface")
 	hugoPort := flag.Int("hugo-port", 1313, "Port for Hugo server")
 	projectDir := flag.String("dir", ".", "Hugo project directory")
 	showVersion := flag.Bool("version", false, "Show version")
 	initConfig := flag.Bool("init", false, "Initialize hugo-manager.yaml config file")
+	readOnly := flag.Bool("read-only", false, "Disable all mutating endpoints (demo/audit mode)")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file to serve HTTPS")
+	tlsKey := flag.String("tls-key", "", "PEM private key file to serve HTTPS")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "Serve HTTPS with a generated self-signed certificate")
+	logLevel := flag.String("log-level", "", "Log level: debug, info, warn or error (overrides config)")
 	flag.Parse()
 
 	if *showVersion {
@@ -57,6 +90,9 @@ func main() {
 		// Use default config if none exists
 		fmt.Printf("Using default config due to load error\n")
 		cfg = config.Default()
+		if err := config.ApplyEnv(cfg); err != nil {
+			log.Fatalf("Invalid environment override: %v", err)
+		}
 	}
 
 	if *initConfig {
@@ -67,16 +103,54 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Override ports from command line if specified
-	if *port != 8080 {
-		cfg.Server.Port = *port
+	// Override host and ports from command line if specified. They're kept as overrides, so saving
+	// or reloading the configuration leaves them out of the file.
+	cfg.Override(func(cfg *config.Config) {
+		if *host != "" {
+			cfg.Server.Host = *host
+		}
+		if *port != 8080 {
+			cfg.Server.Port = *port
+		}
+		if *hugoPort != 1313 {
+			cfg.Hugo.Port = *hugoPort
+		}
+		if *readOnly {
+			cfg.Server.ReadOnly = true
+		}
+
+		if *tlsCert != "" || *tlsKey != "" {
+			cfg.Server.TLSCert = *tlsCert
+			cfg.Server.TLSKey = *tlsKey
+		}
+		if *tlsSelfSigned {
+			cfg.Server.TLSSelfSigned = true
+		}
+		if *logLevel != "" {
+			cfg.Server.LogLevel = *logLevel
+		}
+	})
+
+	// Set up structured logging
+	logCloser, err := logging.Setup(cfg.Server)
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
 	}
-	if *hugoPort != 1313 {
-		cfg.Hugo.Port = *hugoPort
+	defer logCloser.Close()
+
+	slog.Info("Starting hugo-manager", "version", version)
+	slog.Info("Project directory", "dir", absProjectDir)
+	if cfg.Server.ReadOnly {
+		slog.Info("Read-only mode enabled: mutating endpoints are disabled")
 	}
 
-	log.Printf("Starting hugo-manager v%s", version)
-	log.Printf("Project directory: %s", absProjectDir)
+	// Set up OpenTelemetry tracing
+	shutdownTracing, err := tracing.Setup(cfg.Server.Tracing, version)
+	if err != nil {
+		slog.Warn("Tracing disabled", "error", err)
+	} else if cfg.Server.Tracing.Enabled {
+		slog.Info("Tracing enabled", "exporter", cfg.Server.Tracing.Exporter)
+	}
 
 	// Create Hugo manager
 	hugoMgr := hugo.NewManager(absProjectDir, cfg.Hugo)
@@ -90,26 +164,92 @@ func main() {
 
 	go func() {
 		<-sigChan
-		log.Println("\nShutting down...")
-		hugoMgr.Stop()
+		slog.Info("Shutting down")
+		for _, inst := range hugoMgr.Instances() {
+			inst.Stop()
+		}
+		srv.StopPreviews()
+
+		// Flush pending spans
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		shutdownTracing(ctx)
+		cancel()
+
 		os.Exit(0)
 	}()
 
+	// Stop Hugo servers left running by a previous instance that didn't shut down cleanly
+	for _, inst := range hugoMgr.Instances() {
+		if pid, err := inst.CleanupOrphan(); err != nil {
+			slog.Warn("Failed to stop orphaned Hugo server", "instance", inst.Name(), "error", err)
+		} else if pid != 0 {
+			slog.Info("Stopped orphaned Hugo server", "instance", inst.Name(), "pid", pid)
+		}
+	}
+
 	// Auto-start Hugo if configured
 	if cfg.Hugo.AutoStart {
 		if err := hugoMgr.Start(); err != nil {
-			log.Printf("Warning: Failed to auto-start Hugo: %v", err)
+			slog.Warn("Failed to auto-start Hugo", "error", err)
+		}
+	}
+	for _, instCfg := range cfg.Hugo.Instances {
+		if inst, err := hugoMgr.Instance(instCfg.Name); err == nil && instCfg.AutoStart {
+			if err := inst.Start(); err != nil {
+				slog.Warn("Failed to auto-start Hugo", "instance", instCfg.Name, "error", err)
+			}
 		}
 	}
 
 	// Start the web server
-	addr := fmt.Sprintf("localhost:%d", cfg.Server.Port)
-	log.Printf("Web interface available at http://%s", addr)
-	log.Printf("Hugo server will run at http://localhost:%d", cfg.Hugo.Port)
+	bindHost := cfg.Server.Host
+	if bindHost == "" {
+		bindHost = "localhost"
+	}
+	if !isLoopback(bindHost) && !cfg.Server.EnableAuth {
+		slog.Warn("!!! The web interface is reachable from other machines and authentication is disabled. "+
+			"Anyone who can reach it can edit and delete project files. Set server.enable_auth or bind to localhost.",
+			"host", bindHost)
+	}
+	webPort, err := ports.Free(bindHost, cfg.Server.Port, cfg.Server.PortRange)
+	if err != nil {
+		slog.Error("Cannot start the web interface", "error", err)
+		logCloser.Close()
+		os.Exit(1)
+	}
+	if webPort != cfg.Server.Port {
+		slog.Warn("Web port in use, falling back", "configured", cfg.Server.Port, "port", webPort)
+		cfg.Override(func(cfg *config.Config) { cfg.Server.Port = webPort })
+	}
+	addr := net.JoinHostPort(bindHost, strconv.Itoa(cfg.Server.Port))
+	scheme := "http"
+	if cfg.Server.TLSCert != "" || cfg.Server.TLSSelfSigned {
+		scheme = "https"
+	}
+	slog.Info("Web interface available", "url", scheme+"://"+addr)
+	slog.Info("Hugo server will run", "url", fmt.Sprintf("http://localhost:%d", hugoMgr.GetPort()))
+
+	err = srv.Start(addr)
+
+	// Flush pending spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownTracing(ctx)
+	cancel()
+
+	if err != nil {
+		slog.Error("Server error", "error", err)
+		logCloser.Close()
+		os.Exit(1)
+	}
+}
 
-	if err := srv.Start(addr); err != nil {
-		log.Fatalf("Server error: %v", err)
+// isLoopback reports whether host only accepts connections from this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
 	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 func isHugoProject(dir string) bool {
@@ -129,3 +269,149 @@ func isHugoProject(dir string) bool {
 	}
 	return false
 }
+
+// runSynth generates a synthetic Hugo project for benchmarking
+func runSynth(args []string) {
+	fs := flag.NewFlagSet("synth", flag.ExitOnError)
+	outDir := fs.String("out", "synth-site", "Output directory (must be empty or missing)")
+	size := fs.String("size", "", "Size class: small, medium or large (overrides -pages/-images/-sections)")
+	pages := fs.Int("pages", 100, "Number of content pages")
+	images := fs.Int("images", 50, "Number of images")
+	sections := fs.Int("sections", 3, "Number of content sections")
+	variants := fs.String("variants", "320,640", "Comma-separated widths of processed variants per image (empty for none)")
+	seed := fs.Int64("seed", 1, "Random seed for reproducible output")
+	fs.Parse(args)
+
+	opts := synth.Options{
+		OutputDir: *outDir,
+		Pages:     *pages,
+		Images:    *images,
+		Sections:  *sections,
+		Seed:      *seed,
+	}
+
+	if *size != "" {
+		class, ok := synth.SizeClasses[*size]
+		if !ok {
+			log.Fatalf("Unknown size class %q (use small, medium or large)", *size)
+		}
+		opts.Pages = class.Pages
+		opts.Images = class.Images
+		opts.Sections = class.Sections
+	}
+
+	for _, w := range strings.Split(*variants, ",") {
+		if width, err := strconv.Atoi(strings.TrimSpace(w)); err == nil && width > 0 {
+			opts.Variants = append(opts.Variants, width)
+		}
+	}
+
+	stats, err := synth.Generate(opts)
+	if err != nil {
+		log.Fatalf("Failed to generate project: %v", err)
+	}
+
+	fmt.Printf("Generated %d pages and %d images (%d files) in %s [%s]\n",
+		stats.Pages, stats.Images, stats.Files, *outDir, stats.Duration.Round(time.Millisecond))
+}
+
+// runNewSite creates a Hugo project from a starter kit
+func runNewSite(args []string) {
+	fs := flag.NewFlagSet("new-site", flag.ExitOnError)
+	outDir := fs.String("out", "", "Output directory (must be empty or missing)")
+	kitName := fs.String("kit", "blog", "Starter kit to instantiate")
+	title := fs.String("title", "", "Site title")
+	baseURL := fs.String("base-url", "", "Site baseURL (default https://example.org/)")
+	catalogPath := fs.String("catalog", starters.DefaultCatalogPath(), "YAML file with additional starter kits")
+	noTheme := fs.Bool("no-theme", false, "Don't clone the kit's theme")
+	list := fs.Bool("list", false, "List available starter kits")
+	fs.Parse(args)
+
+	catalog, err := starters.LoadCatalog(*catalogPath)
+	if err != nil {
+		log.Fatalf("Failed to load starter catalog: %v", err)
+	}
+
+	if *list {
+		for _, kit := range catalog.Sorted() {
+			fmt.Printf("%-12s %s\n", kit.Name, kit.Description)
+		}
+		return
+	}
+
+	kit, ok := catalog[*kitName]
+	if !ok {
+		log.Fatalf("Unknown starter kit %q (use -list to see available kits)", *kitName)
+	}
+	if *outDir == "" {
+		log.Fatalf("-out is required")
+	}
+
+	result, err := starters.Create(kit, starters.Options{
+		OutputDir:  *outDir,
+		Title:      *title,
+		BaseURL:    *baseURL,
+		FetchTheme: !*noTheme,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create site: %v", err)
+	}
+
+	fmt.Printf("Created %s site in %s (%d files)\n", kit.Name, *outDir, result.Files)
+	switch {
+	case result.ThemeFetched:
+		fmt.Printf("Theme %s installed in themes/%s\n", kit.Theme.Name, kit.Theme.Name)
+	case result.ThemeError != nil:
+		fmt.Printf("Warning: failed to install theme %s: %v\n", kit.Theme.Name, result.ThemeError)
+		fmt.Printf("Install it manually: git clone %s %s\n", kit.Theme.Repo, filepath.Join(*outDir, "themes", kit.Theme.Name))
+	case kit.Theme.Repo != "":
+		fmt.Printf("Install the theme with: git clone %s %s\n", kit.Theme.Repo, filepath.Join(*outDir, "themes", kit.Theme.Name))
+	}
+	fmt.Printf("Start managing it with: hugo-manager -dir %s\n", *outDir)
+}
+
+// runAddUser adds a user to a project, reading its password from the first line of stdin
+func runAddUser(args []string) {
+	fs := flag.NewFlagSet("add-user", flag.ExitOnError)
+	projectDir := fs.String("dir", ".", "Hugo project directory")
+	username := fs.String("username", "", "Username (lowercase letters, digits, dots, dashes or underscores)")
+	role := fs.String("role", config.RoleEditor, "Role: admin or editor")
+	name := fs.String("name", "", "Full name, for commit authors")
+	email := fs.String("email", "", "Email, for commit authors")
+	token := fs.Bool("token", false, "Issue an API token and print it")
+	noPassword := fs.Bool("no-password", false, "Don't read a password; the user can only use its API token")
+	fs.Parse(args)
+
+	if *username == "" {
+		log.Fatalf("-username is required")
+	}
+	store, err := users.NewStore(*projectDir, 0)
+	if err != nil {
+		log.Fatalf("Failed to load users: %v", err)
+	}
+
+	var password string
+	if !*noPassword {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Failed to read password: %v", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	} else if !*token {
+		log.Fatalf("-no-password needs -token, or the user can't sign in")
+	}
+
+	user, err := store.Create(users.User{Username: *username, Name: *name, Email: *email, Role: *role}, password)
+	if err != nil {
+		log.Fatalf("Failed to add user: %v", err)
+	}
+	fmt.Printf("Added %s user %s\n", user.Role, user.Username)
+	if *token {
+		t, err := store.NewToken(user.Username)
+		if err != nil {
+			log.Fatalf("Failed to issue token: %v", err)
+		}
+		fmt.Printf("API token (shown only once): %s\n", t)
+	}
+}
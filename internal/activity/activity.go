@@ -0,0 +1,164 @@
+package activity
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// dirName is where the activity log is kept, inside the .hugo-manager directory
+const dirName = "activity"
+
+// segmentLayout names the files of the log, one per month of UTC time, e.g. 2024-05.jsonl. Files
+// are only ever appended to; old months can be archived or deleted by hand.
+const segmentLayout = "2006-01"
+
+// Entry is a change made to the project, and who made it
+type Entry struct {
+	Time      time.Time              `json:"time"`
+	Operation string                 `json:"operation"`         // A webhook event, e.g. file.saved, or the route, e.g. POST /api/v1/hugo/build
+	Path      string                 `json:"path,omitempty"`    // Project-relative path of the file changed
+	OldSize   *int64                 `json:"oldSize,omitempty"` // Bytes before the change; unset for new files
+	NewSize   *int64                 `json:"newSize,omitempty"` // Bytes after the change; unset for deleted files
+	User      string                 `json:"user,omitempty"`    // Username or token name, empty without auth
+	Author    string                 `json:"author,omitempty"`  // The user as a git author, "Name <email>"
+	Status    int                    `json:"status,omitempty"`  // HTTP status of the request
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Query selects entries of the log; zero fields don't filter
+type Query struct {
+	Since time.Time // Entries at or after this time
+	Path  string    // Entries for this path or files inside it
+	User  string
+	Limit int // At most this many entries, the newest
+}
+
+// Log appends entries to JSON Lines files, one entry per line
+type Log struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewLog creates the activity log of a project
+func NewLog(projectDir string) *Log {
+	return &Log{dir: filepath.Join(projectDir, storage.DirName, dirName)}
+}
+
+// Record appends an entry, stamping its time when unset
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, e.Time.Format(segmentLayout)+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Find returns the entries matching a query, newest first. Only the months from q.Since on are read.
+func (l *Log) Find(q Query) ([]Entry, error) {
+	segments, err := l.segments()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(q.Path)), "/")
+
+	found := []Entry{}
+	for _, month := range segments {
+		if !q.Since.IsZero() && month.AddDate(0, 1, 0).Before(q.Since) {
+			break
+		}
+		entries, err := l.read(month)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			switch {
+			case !q.Since.IsZero() && e.Time.Before(q.Since):
+				continue
+			case q.User != "" && e.User != q.User:
+				continue
+			case prefix != "" && e.Path != prefix && !strings.HasPrefix(e.Path, prefix+"/"):
+				continue
+			}
+			found = append(found, e)
+			if q.Limit > 0 && len(found) == q.Limit {
+				return found, nil
+			}
+		}
+	}
+	return found, nil
+}
+
+// segments returns the months the log has a file for, newest first
+func (l *Log) segments() ([]time.Time, error) {
+	files, err := os.ReadDir(l.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var months []time.Time
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ".jsonl")
+		if !ok || f.IsDir() {
+			continue
+		}
+		if month, err := time.Parse(segmentLayout, name); err == nil {
+			months = append(months, month)
+		}
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].After(months[j]) })
+	return months, nil
+}
+
+// read returns the entries of a month, oldest first, skipping lines it can't parse
+func (l *Log) read(month time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(filepath.Join(l.dir, month.Format(segmentLayout)+".jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
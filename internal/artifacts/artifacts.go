@@ -0,0 +1,341 @@
+// Package artifacts records what each build writes to the publish directory, so the output of a
+// release can be checked, and compared with the build before it, before it's deployed.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// dirName is where the manifests of the last two builds are kept, inside the .hugo-manager directory
+const dirName = "builds"
+
+// Manifest files, inside dirName
+const (
+	latestFile   = "latest.json"
+	previousFile = "previous.json"
+)
+
+// Changes of a file since the previous build
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// ErrNoBuild is returned when no build has been recorded yet
+var ErrNoBuild = errors.New("no build recorded")
+
+// File is a file of the publish directory
+type File struct {
+	Path   string `json:"path"` // Relative to the publish directory
+	Size   int64  `json:"size"`
+	Hash   string `json:"hash"`             // SHA-256 of the content
+	Change string `json:"change,omitempty"` // Since the previous build: added or changed
+}
+
+// Manifest is what a build wrote to the publish directory
+type Manifest struct {
+	BuiltAt   time.Time `json:"builtAt"`
+	Profile   string    `json:"profile,omitempty"`
+	OutputDir string    `json:"outputDir"`
+	Files     []File    `json:"files"`
+	TotalSize int64     `json:"totalSize"`
+	Checks    *Checks   `json:"checks,omitempty"`
+}
+
+// Build summarizes a recorded build
+type Build struct {
+	BuiltAt   time.Time `json:"builtAt"`
+	Profile   string    `json:"profile,omitempty"`
+	Files     int       `json:"files"`
+	Pages     int       `json:"pages"` // HTML files
+	TotalSize int64     `json:"totalSize"`
+}
+
+// Changes lists the paths added, changed and removed since the previous build
+type Changes struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// Comparison is the difference between the latest build and the one before it
+type Comparison struct {
+	Previous  Build   `json:"previous"`
+	Pages     Changes `json:"pages"` // HTML files only
+	Files     Changes `json:"files"` // Every file, pages included
+	SizeDelta int64   `json:"sizeDelta"`
+}
+
+// Report is the output of the latest build, compared with the previous one when there is one
+type Report struct {
+	Build
+	OutputDir  string      `json:"outputDir"`
+	Entries    []File      `json:"entries"`
+	Comparison *Comparison `json:"comparison,omitempty"`
+}
+
+// Manager records the output of builds and checks it
+type Manager struct {
+	projectDir string
+	checks     config.BuildChecksConfig
+	mu         sync.Mutex
+}
+
+// NewManager creates a build artifacts manager
+func NewManager(projectDir string, checks config.BuildChecksConfig) *Manager {
+	return &Manager{projectDir: projectDir, checks: checks}
+}
+
+// Record reads the publish directory after a build with profile, checks it and keeps it as the
+// latest build, the one it replaces becoming the previous build
+func (m *Manager) Record(profile string) (*Manifest, error) {
+	publishDir, baseURL := "public", ""
+	if siteCfg, err := site.Load(m.projectDir); err == nil {
+		publishDir = siteCfg.PublishDir()
+		baseURL = siteCfg.BaseURL()
+	}
+	root := publishDir
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(m.projectDir, filepath.FromSlash(publishDir))
+	}
+
+	manifest := &Manifest{BuiltAt: time.Now().UTC(), Profile: profile, OutputDir: filepath.ToSlash(publishDir), Files: []File{}}
+	var pages []pageScan
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if isPage(rel) {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(data)
+			manifest.Files = append(manifest.Files, File{Path: rel, Size: int64(len(data)), Hash: hex.EncodeToString(sum[:])})
+			manifest.TotalSize += int64(len(data))
+			pages = append(pages, scanPage(rel, data))
+			return nil
+		}
+		size, hash, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, File{Path: rel, Size: size, Hash: hash})
+		manifest.TotalSize += size
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", publishDir, err)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous, err := m.load(latestFile)
+	if err != nil {
+		previous = nil // Check against no previous build rather than failing on a damaged file
+	}
+	manifest.Checks = runChecks(m.checks, baseURL, manifest, previous, pages)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	dir := m.dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to save build manifest: %w", err)
+	}
+	if err := os.Rename(filepath.Join(dir, latestFile), filepath.Join(dir, previousFile)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to save build manifest: %w", err)
+	}
+	if err := atomicfile.WriteFile(filepath.Join(dir, latestFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save build manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Report returns the files of the latest build under prefix, all of them when it's empty, and
+// how the whole build differs from the previous one
+func (m *Manager) Report(prefix string) (*Report, error) {
+	m.mu.Lock()
+	latest, err := m.load(latestFile)
+	var previous *Manifest
+	if err == nil {
+		previous, err = m.load(previousFile)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: build the site first", ErrNoBuild)
+	}
+
+	report := &Report{Build: summarize(latest), OutputDir: latest.OutputDir, Entries: []File{}}
+	var before map[string]File
+	if previous != nil {
+		before = make(map[string]File, len(previous.Files))
+		for _, f := range previous.Files {
+			before[f.Path] = f
+		}
+		report.Comparison = compare(previous, latest, before)
+	}
+
+	prefix = strings.Trim(path.Clean("/"+prefix), "/")
+	for _, f := range latest.Files {
+		if prefix != "" && f.Path != prefix && !strings.HasPrefix(f.Path, prefix+"/") {
+			continue
+		}
+		if before != nil {
+			if old, ok := before[f.Path]; !ok {
+				f.Change = ChangeAdded
+			} else if old.Hash != f.Hash {
+				f.Change = ChangeChanged
+			}
+		}
+		report.Entries = append(report.Entries, f)
+	}
+	return report, nil
+}
+
+// Checks returns the result of the checks of the latest build
+func (m *Manager) Checks() (*CheckReport, error) {
+	m.mu.Lock()
+	latest, err := m.load(latestFile)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: build the site first", ErrNoBuild)
+	}
+	report := &CheckReport{Build: summarize(latest), OutputDir: latest.OutputDir}
+	if latest.Checks != nil {
+		report.Checks = *latest.Checks
+	}
+	return report, nil
+}
+
+// compare lists the files added, changed and removed between two builds
+func compare(previous, latest *Manifest, before map[string]File) *Comparison {
+	c := &Comparison{
+		Previous:  summarize(previous),
+		Pages:     Changes{Added: []string{}, Changed: []string{}, Removed: []string{}},
+		Files:     Changes{Added: []string{}, Changed: []string{}, Removed: []string{}},
+		SizeDelta: latest.TotalSize - previous.TotalSize,
+	}
+	add := func(p, change string) {
+		c.Files.add(p, change)
+		if isPage(p) {
+			c.Pages.add(p, change)
+		}
+	}
+
+	seen := make(map[string]bool, len(latest.Files))
+	for _, f := range latest.Files {
+		seen[f.Path] = true
+		if old, ok := before[f.Path]; !ok {
+			add(f.Path, ChangeAdded)
+		} else if old.Hash != f.Hash {
+			add(f.Path, ChangeChanged)
+		}
+	}
+	for _, f := range previous.Files {
+		if !seen[f.Path] {
+			add(f.Path, ChangeRemoved)
+		}
+	}
+	return c
+}
+
+// add lists a path under its change
+func (c *Changes) add(p, change string) {
+	switch change {
+	case ChangeAdded:
+		c.Added = append(c.Added, p)
+	case ChangeChanged:
+		c.Changed = append(c.Changed, p)
+	case ChangeRemoved:
+		c.Removed = append(c.Removed, p)
+	}
+}
+
+// summarize counts the files, pages and bytes of a build
+func summarize(manifest *Manifest) Build {
+	b := Build{BuiltAt: manifest.BuiltAt, Profile: manifest.Profile, Files: len(manifest.Files), TotalSize: manifest.TotalSize}
+	for _, f := range manifest.Files {
+		if isPage(f.Path) {
+			b.Pages++
+		}
+	}
+	return b
+}
+
+// isPage reports whether a file of the publish directory is a page
+func isPage(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".html" || ext == ".htm"
+}
+
+// hashFile returns the size and SHA-256 of a file
+func hashFile(p string) (int64, string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// load reads a manifest. A missing file is no manifest.
+func (m *Manager) load(name string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(m.dir(), name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid build manifest %s: %w", name, err)
+	}
+	return &manifest, nil
+}
+
+// dir returns the directory the manifests are kept in
+func (m *Manager) dir() string {
+	return filepath.Join(m.projectDir, storage.DirName, dirName)
+}
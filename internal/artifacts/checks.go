@@ -0,0 +1,181 @@
+package artifacts
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// maxItems is the number of problems listed per check; the count covers all of them
+const maxItems = 100
+
+var (
+	assetTagRe = regexp.MustCompile(`(?is)<(img|link|script|source|video|audio)\b([^>]*)>`)
+	tagAttrRe  = regexp.MustCompile(`(?s)([a-zA-Z_:@][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	titleRe    = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	headRe     = regexp.MustCompile(`(?i)<(head|html)\b`)
+)
+
+// assetAttrs is the attribute of each checked tag that references an asset
+var assetAttrs = map[string]string{"img": "src", "link": "href", "script": "src", "source": "src", "video": "src", "audio": "src"}
+
+// Check is one of the checks of a build's output, with the problems it found
+type Check struct {
+	Count    int      `json:"count"`
+	Limit    int      `json:"limit"` // -1 = no limit
+	Exceeded bool     `json:"exceeded"`
+	Items    []string `json:"items"` // The first problems found
+}
+
+// Checks are the checks of a build's output: pages of the previous build gone from it, references
+// to local assets that don't exist, and pages with no title
+type Checks struct {
+	Enforced     bool     `json:"enforced"` // The build fails when a limit is exceeded
+	Passed       bool     `json:"passed"`   // No limit is exceeded
+	Failures     []string `json:"failures"` // The limits exceeded
+	MissingPages Check    `json:"missingPages"`
+	BrokenAssets Check    `json:"brokenAssets"` // Items are "page: reference"
+	EmptyTitles  Check    `json:"emptyTitles"`
+}
+
+// CheckReport is the result of the checks of the latest build
+type CheckReport struct {
+	Build
+	OutputDir string `json:"outputDir"`
+	Checks
+}
+
+// Failed reports whether the checks fail the build: a limit is exceeded and limits are enforced
+func (c *Checks) Failed() bool {
+	return c.Enforced && !c.Passed
+}
+
+// add counts a problem, listing it while there's room
+func (c *Check) add(item string) {
+	c.Count++
+	if len(c.Items) < maxItems {
+		c.Items = append(c.Items, item)
+	}
+}
+
+// pageScan is what the checks need of a page of the build
+type pageScan struct {
+	path   string
+	refs   []string
+	titled bool // Has a non-empty <title>, or isn't a full document
+}
+
+// scanPage collects the asset references and title of a page
+func scanPage(p string, data []byte) pageScan {
+	scan := pageScan{path: p, titled: true}
+	content := string(data)
+	for _, match := range assetTagRe.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		attrs := parseAttrs(match[2])
+		if tag == "link" && !strings.Contains(attrs["rel"], "stylesheet") && !strings.Contains(attrs["rel"], "icon") {
+			continue // Canonical, alternate and feed links point at pages, not assets
+		}
+		if ref := strings.TrimSpace(attrs[assetAttrs[tag]]); ref != "" {
+			scan.refs = append(scan.refs, ref)
+		}
+	}
+	if headRe.MatchString(content) {
+		// Fragments, such as verification files, have no head and need no title
+		title := titleRe.FindStringSubmatch(content)
+		scan.titled = title != nil && strings.TrimSpace(html.UnescapeString(title[1])) != ""
+	}
+	return scan
+}
+
+// runChecks checks the pages of a build against its files and those of the previous build
+func runChecks(cfg config.BuildChecksConfig, baseURL string, latest, previous *Manifest, pages []pageScan) *Checks {
+	checks := &Checks{
+		Enforced:     cfg.Enforce,
+		MissingPages: Check{Limit: cfg.MaxMissingPages, Items: []string{}},
+		BrokenAssets: Check{Limit: cfg.MaxBrokenAssets, Items: []string{}},
+		EmptyTitles:  Check{Limit: cfg.MaxEmptyTitles, Items: []string{}},
+	}
+
+	exists := make(map[string]bool, len(latest.Files))
+	for _, f := range latest.Files {
+		exists[f.Path] = true
+	}
+	if previous != nil {
+		for _, f := range previous.Files {
+			if isPage(f.Path) && !exists[f.Path] {
+				checks.MissingPages.add(f.Path)
+			}
+		}
+	}
+
+	host, basePath := "", ""
+	if u, err := url.Parse(baseURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	for _, page := range pages {
+		if !page.titled {
+			checks.EmptyTitles.add(page.path)
+		}
+		for _, ref := range page.refs {
+			target, ok := localTarget(page.path, ref, host, basePath)
+			if ok && !exists[target] && !exists[path.Join(target, "index.html")] {
+				checks.BrokenAssets.add(page.path + ": " + ref)
+			}
+		}
+	}
+
+	checks.Failures = []string{}
+	for _, c := range []struct {
+		name  string
+		check *Check
+	}{{"missing pages", &checks.MissingPages}, {"broken asset references", &checks.BrokenAssets}, {"pages without a title", &checks.EmptyTitles}} {
+		c.check.Exceeded = c.check.Limit >= 0 && c.check.Count > c.check.Limit
+		if c.check.Exceeded {
+			checks.Failures = append(checks.Failures, fmt.Sprintf("%d %s (limit %d)", c.check.Count, c.name, c.check.Limit))
+		}
+	}
+	checks.Passed = len(checks.Failures) == 0
+	return checks
+}
+
+// localTarget returns the path in the publish directory a reference of a page points to, or false
+// when it points to another site
+func localTarget(page, ref, host, basePath string) (string, bool) {
+	if strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "data:") {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		if (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Hostname(), host) {
+			return "", false
+		}
+	}
+
+	p := u.Path
+	if strings.HasPrefix(p, "/") {
+		if basePath != "" && (p == basePath || strings.HasPrefix(p, basePath+"/")) {
+			p = strings.TrimPrefix(p, basePath)
+		}
+	} else {
+		p = path.Join(path.Dir("/"+page), p)
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/"), true
+}
+
+// parseAttrs returns the attributes of a tag by lowercase name
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, match := range tagAttrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attrs
+}
@@ -0,0 +1,65 @@
+// Package atomicfile writes files so that a crash or power loss mid-write leaves either the old
+// content or the new one on disk, never a mix of both, and readers never see a partial file.
+package atomicfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to a temporary file in the same directory as name, syncs it to disk and
+// renames it over name. Like os.WriteFile, it creates the file with perm and an existing file keeps
+// its permissions. A symbolic link is written through, to the file it points to, and stays a link.
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	_, err := WriteFrom(name, bytes.NewReader(data), perm)
+	return err
+}
+
+// WriteFrom is WriteFile for content read from r, such as uploads and copies, which needn't fit
+// in memory. It returns the number of bytes written.
+func WriteFrom(name string, r io.Reader, perm os.FileMode) (int64, error) {
+	if resolved, err := filepath.EvalSymlinks(name); err == nil {
+		name = resolved
+	}
+	if stat, err := os.Stat(name); err == nil {
+		perm = stat.Mode().Perm()
+	}
+
+	dir := filepath.Dir(name)
+	// Hidden, so file trees and site builds skip it while it exists
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // Gone already once renamed
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return n, err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return n, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return n, err
+	}
+	if err := tmp.Close(); err != nil {
+		return n, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return n, err
+	}
+
+	// Persist the rename itself. Directories can't be synced on Windows, where renames are
+	// journaled by the file system anyway.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return n, nil
+}
@@ -0,0 +1,207 @@
+// Package autosave keeps the unsaved content of files open in the editor, so work survives a
+// browser crash or a closed tab. Each user's drafts are stored as JSON in the drafts directory of
+// .hugo-manager, at the path of the file.
+package autosave
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// Errors returned by the draft store
+var (
+	ErrNotFound     = errors.New("no unsaved draft")
+	ErrInvalidDraft = errors.New("invalid draft")
+)
+
+// dirName is where drafts are kept, inside the .hugo-manager directory
+const dirName = "drafts"
+
+// anonymousDir holds the drafts made without auth. Usernames start with a letter or digit, so it
+// can't be a user's.
+const anonymousDir = "_"
+
+// draftExt is added to the path of a file to name its draft
+const draftExt = ".json"
+
+// Draft is the unsaved content of a file
+type Draft struct {
+	Path    string    `json:"path"` // Project-relative file the draft is of
+	User    string    `json:"user,omitempty"`
+	Content string    `json:"content"`
+	SavedAt time.Time `json:"savedAt"`
+	Base    string    `json:"base"`  // SHA-256 of the file when the draft was saved
+	Stale   bool      `json:"stale"` // The file changed on disk after the draft was saved
+}
+
+// Store keeps the drafts of each user
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates the draft store of a project
+func NewStore(projectDir string) *Store {
+	return &Store{dir: filepath.Join(projectDir, storage.DirName, dirName)}
+}
+
+// Save keeps content as the user's draft of a file whose saved content is current
+func (s *Store) Save(user, p, content string, current []byte) (*Draft, error) {
+	file, err := s.file(user, p)
+	if err != nil {
+		return nil, err
+	}
+	d := &Draft{Path: cleanPath(p), User: user, Content: content, SavedAt: time.Now().UTC(), Base: hash(current)}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, fmt.Errorf("failed to save draft: %w", err)
+	}
+	if err := atomicfile.WriteFile(file, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save draft: %w", err)
+	}
+	return d, nil
+}
+
+// Get returns the user's draft of a file, marked stale when current, the file's saved content,
+// is no longer what the draft was made from
+func (s *Store) Get(user, p string, current []byte) (*Draft, error) {
+	file, err := s.file(user, p)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	data, err := os.ReadFile(file)
+	s.mu.Unlock()
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w for %s", ErrNotFound, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d Draft
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid draft of %s: %w", p, err)
+	}
+	// Drafts are where their file is; moves don't rewrite them
+	d.Path, d.User = cleanPath(p), user
+	d.Stale = d.Base != hash(current)
+	return &d, nil
+}
+
+// Delete discards the user's draft of a file. A missing draft isn't an error.
+func (s *Store) Delete(user, p string) error {
+	file, err := s.file(user, p)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteAll discards every user's drafts of a file, or of the files inside a directory
+func (s *Store) DeleteAll(p string) error {
+	return s.each(p, func(userDir, rel string) error {
+		if err := os.Remove(filepath.Join(userDir, rel+draftExt)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(userDir, rel)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// Move moves every user's drafts of a file, or of the files inside a directory, to its new path
+func (s *Store) Move(oldPath, newPath string) error {
+	newRel := filepath.FromSlash(cleanPath(newPath))
+	if newRel == "" {
+		return fmt.Errorf("%w: %s is not a path in the project", ErrInvalidDraft, newPath)
+	}
+	return s.each(oldPath, func(userDir, rel string) error {
+		for _, suffix := range []string{draftExt, ""} {
+			from, to := filepath.Join(userDir, rel+suffix), filepath.Join(userDir, newRel+suffix)
+			if _, err := os.Stat(from); os.IsNotExist(err) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(from, to); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// each calls fn with the directory of each user's drafts and the path of a file within it
+func (s *Store) each(p string, fn func(userDir, rel string) error) error {
+	rel := filepath.FromSlash(cleanPath(p))
+	if rel == "" {
+		return fmt.Errorf("%w: %s is not a path in the project", ErrInvalidDraft, p)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() {
+			if err := fn(filepath.Join(s.dir, e.Name()), rel); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// file returns where the user's draft of a file is kept
+func (s *Store) file(user, p string) (string, error) {
+	rel := filepath.FromSlash(cleanPath(p))
+	if rel == "" {
+		return "", fmt.Errorf("%w: %s is not a path in the project", ErrInvalidDraft, p)
+	}
+	userDir := user
+	if userDir == "" {
+		userDir = anonymousDir
+	}
+	return filepath.Join(s.dir, userDir, rel+draftExt), nil
+}
+
+// cleanPath makes a path relative to the project, so it can't point outside it
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// hash returns the SHA-256 of content
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,235 @@
+// Package calendar lays out the site's content on its dates for editorial planning: when pages
+// were or will be published and when they expire, drafts included.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Errors returned by the calendar
+var (
+	ErrInvalidRange      = errors.New("invalid calendar range")
+	ErrInvalidReschedule = errors.New("invalid reschedule")
+)
+
+// Date fields of the front matter a page is placed on
+const (
+	FieldDate        = "date"
+	FieldPublishDate = "publishDate"
+	FieldExpiryDate  = "expiryDate"
+)
+
+// fields are the date fields in the order a page's entries are listed
+var fields = []string{FieldDate, FieldPublishDate, FieldExpiryDate}
+
+// Statuses of a page on the calendar
+const (
+	StatusDraft     = "draft"     // draft: true, whatever its dates
+	StatusScheduled = "scheduled" // Published once its publishDate, or date, comes
+	StatusPublished = "published"
+	StatusExpired   = "expired" // Its expiryDate has passed, so Hugo leaves it out
+)
+
+// DateLayout is the layout of the days in a range and of rescheduled dates without a time
+const DateLayout = "2006-01-02"
+
+// Entry is a page on one of its dates
+type Entry struct {
+	Date     time.Time `json:"date"`
+	Field    string    `json:"field"` // date, publishDate or expiryDate
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	Section  string    `json:"section"`
+	Language string    `json:"language"`
+	Status   string    `json:"status"`
+}
+
+// Calendar is the entries between two days, both included, in date order
+type Calendar struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Entries []Entry `json:"entries"`
+}
+
+// Manager reads and reschedules the dates of the site's content
+type Manager struct {
+	projectDir string
+}
+
+// NewManager creates a calendar for a project
+func NewManager(projectDir string) *Manager {
+	return &Manager{projectDir: projectDir}
+}
+
+// Range returns the entries from one day to another, given as YYYY-MM-DD. Without from, the range
+// starts on the first day of the current month; without to, it covers a month.
+func (m *Manager) Range(from, to string) (*Calendar, error) {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	if from != "" {
+		t, err := time.ParseInLocation(DateLayout, from, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidRange)
+		}
+		start = t
+	}
+	end := start.AddDate(0, 1, -1)
+	if to != "" {
+		t, err := time.ParseInLocation(DateLayout, to, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidRange)
+		}
+		end = t
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidRange)
+	}
+
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := contentSite.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{From: start.Format(DateLayout), To: end.Format(DateLayout), Entries: []Entry{}}
+	for _, page := range pages {
+		for _, entry := range m.entries(page) {
+			day := entry.Date.Format(DateLayout)
+			if day >= cal.From && day <= cal.To {
+				cal.Entries = append(cal.Entries, entry)
+			}
+		}
+	}
+	sort.SliceStable(cal.Entries, func(i, j int) bool {
+		a, b := cal.Entries[i], cal.Entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Path < b.Path
+	})
+	return cal, nil
+}
+
+// Reschedule moves a date field of a page to another day, given as YYYY-MM-DD, or to a full
+// date and time. A date that had a time keeps it when moved to another day. The rest of the front
+// matter and the body are kept as they are.
+func (m *Manager) Reschedule(relPath, field, date string) (*Entry, error) {
+	key := ""
+	for _, f := range fields {
+		if strings.EqualFold(f, field) {
+			key = f
+		}
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%w: field must be one of %s", ErrInvalidReschedule, strings.Join(fields, ", "))
+	}
+
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	page, err := contentSite.Page(relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if day, err := time.ParseInLocation(DateLayout, date, time.Local); err == nil {
+		value = hugocontent.Date(day)
+		if old, ok := page.FrontMatter.Time(key); ok && hasTime(page.FrontMatter.Get(key), old) {
+			value = time.Date(day.Year(), day.Month(), day.Day(), old.Hour(), old.Minute(), old.Second(), old.Nanosecond(), old.Location())
+		}
+	} else if t, ok := hugocontent.ParseDate(date); ok {
+		value = t
+	} else {
+		return nil, fmt.Errorf("%w: date must be YYYY-MM-DD or RFC 3339", ErrInvalidReschedule)
+	}
+
+	full := filepath.Join(m.projectDir, filepath.FromSlash(page.Path))
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	out, err := hugocontent.SetFields(data, map[string]interface{}{key: value})
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(full, out, 0644); err != nil {
+		return nil, err
+	}
+
+	if page, err = contentSite.Page(page.Path); err != nil {
+		return nil, err
+	}
+	for _, entry := range m.entries(page) {
+		if entry.Field == key {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s has no %s after rescheduling", ErrInvalidReschedule, page.Path, key)
+}
+
+// entries returns a page on each of its dates
+func (m *Manager) entries(page *hugocontent.Page) []Entry {
+	status := m.status(page)
+	title := page.Title()
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(page.Path), filepath.Ext(page.Path))
+	}
+
+	var entries []Entry
+	for _, field := range fields {
+		if t, ok := page.FrontMatter.Time(field); ok {
+			entries = append(entries, Entry{
+				Date:     t,
+				Field:    field,
+				Path:     page.Path,
+				Title:    title,
+				Section:  page.Section,
+				Language: page.Language,
+				Status:   status,
+			})
+		}
+	}
+	return entries
+}
+
+// status tells whether Hugo publishes a page now. Its publishDate defaults to its date, as in Hugo.
+func (m *Manager) status(page *hugocontent.Page) string {
+	now := time.Now()
+	if page.Draft() {
+		return StatusDraft
+	}
+	if expiry, ok := page.FrontMatter.Time(FieldExpiryDate); ok && !expiry.After(now) {
+		return StatusExpired
+	}
+	publish, ok := page.FrontMatter.Time(FieldPublishDate)
+	if !ok {
+		publish, ok = page.FrontMatter.Time(FieldDate)
+	}
+	if ok && publish.After(now) {
+		return StatusScheduled
+	}
+	return StatusPublished
+}
+
+// hasTime reports whether a front matter date was written with a time of day
+func hasTime(raw interface{}, t time.Time) bool {
+	if s, ok := raw.(string); ok {
+		return len(strings.TrimSpace(s)) > len(DateLayout)
+	}
+	hour, minute, second := t.Clock()
+	return hour != 0 || minute != 0 || second != 0 || t.Nanosecond() != 0
+}
@@ -0,0 +1,280 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+)
+
+// Sentinel errors returned by Manager operations. Use errors.Is to check for them.
+var (
+	ErrInvalidSKU      = errors.New("invalid SKU")
+	ErrInvalidProduct  = errors.New("invalid product")
+	ErrProductExists   = errors.New("product already exists")
+	ErrProductNotFound = errors.New("product does not exist")
+)
+
+// skuRe restricts SKUs to a single safe path segment, as they also name product pages and photos
+var skuRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Product is an entry of the products data file
+type Product struct {
+	SKU         string                 `yaml:"sku" json:"sku"`
+	Name        string                 `yaml:"name" json:"name"`
+	Price       float64                `yaml:"price" json:"price"`
+	Currency    string                 `yaml:"currency,omitempty" json:"currency,omitempty"` // Defaults to catalog.currency
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Images      []string               `yaml:"images,omitempty" json:"images,omitempty"` // Site-relative URLs; the first is the main photo
+	Categories  []string               `yaml:"categories,omitempty" json:"categories,omitempty"`
+	Weight      float64                `yaml:"weight,omitempty" json:"weight,omitempty"` // Grams, used by checkout shipping rates
+	Stock       *int                   `yaml:"stock,omitempty" json:"stock,omitempty"`   // Units in stock (empty = not tracked)
+	Extra       map[string]interface{} `yaml:",inline" json:"extra,omitempty"`           // Other fields of the data file, kept as is
+}
+
+// ImageResult describes a product photo added through the image pipeline
+type ImageResult struct {
+	Product *Product              `json:"product"`
+	Image   *images.ProcessResult `json:"image"`
+}
+
+// Manager manages the products data file, product photos and product pages
+type Manager struct {
+	projectDir string
+	config     config.CatalogConfig
+	images     *images.Processor
+	mu         sync.Mutex // Serializes read-modify-write cycles of the data file
+}
+
+// NewManager creates a new catalog manager. Product photos are processed by processor.
+func NewManager(projectDir string, cfg config.CatalogConfig, processor *images.Processor) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		images:     processor,
+	}
+}
+
+// dataFile returns the project-relative path of the products data file
+func (m *Manager) dataFile() string {
+	if m.config.DataFile == "" {
+		return "data/products.yaml"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.DataFile))
+}
+
+// currency returns the currency of a product's price
+func (m *Manager) currency(p *Product) string {
+	if p.Currency != "" {
+		return p.Currency
+	}
+	if m.config.Currency == "" {
+		return "USD"
+	}
+	return m.config.Currency
+}
+
+// Products returns the products in data file order. A missing data file is an empty catalog.
+func (m *Manager) Products() ([]Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.load()
+}
+
+// Product returns the product with the given SKU
+func (m *Manager) Product(sku string) (*Product, error) {
+	products, err := m.Products()
+	if err != nil {
+		return nil, err
+	}
+	i := find(products, sku)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+	return &products[i], nil
+}
+
+// Create adds a product to the data file
+func (m *Manager) Create(p Product) (*Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.check(&p); err != nil {
+		return nil, err
+	}
+	if find(products, p.SKU) >= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProductExists, p.SKU)
+	}
+
+	products = append(products, p)
+	if err := m.save(products); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Update replaces the product with the given SKU. p.SKU may differ to rename the product.
+func (m *Manager) Update(sku string, p Product) (*Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(products, sku)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+	if p.SKU == "" {
+		p.SKU = products[i].SKU
+	}
+	if err := m.check(&p); err != nil {
+		return nil, err
+	}
+	if j := find(products, p.SKU); j >= 0 && j != i {
+		return nil, fmt.Errorf("%w: %s", ErrProductExists, p.SKU)
+	}
+
+	products[i] = p
+	if err := m.save(products); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Delete removes the product with the given SKU. Its page and photos are kept.
+func (m *Manager) Delete(sku string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, err := m.load()
+	if err != nil {
+		return err
+	}
+	i := find(products, sku)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+	return m.save(append(products[:i], products[i+1:]...))
+}
+
+// AddImage processes an uploaded photo into the product image folder, cropped to the configured aspect
+// ratio, and appends its largest variant to the product's images
+func (m *Manager) AddImage(ctx context.Context, sku string, reader io.Reader) (*ImageResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(products, sku)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProductNotFound, sku)
+	}
+
+	folder := m.config.ImageFolder
+	if folder == "" {
+		folder = "static/images/products"
+	}
+	result, err := m.images.Process(ctx, reader, images.UploadOptions{
+		Folder:   folder,
+		Filename: fmt.Sprintf("%s-%d", strings.ToLower(products[i].SKU), len(products[i].Images)+1),
+		Widths:   append([]int(nil), m.config.ImageWidths...), // Process sorts the widths in place
+		Aspect:   m.config.ImageAspect,
+		Alt:      products[i].Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products[i].Images = append(products[i].Images, result.Original)
+	if err := m.save(products); err != nil {
+		return nil, err
+	}
+	return &ImageResult{Product: &products[i], Image: result}, nil
+}
+
+// check validates a product before it's saved, rejecting it on any error-level issue
+func (m *Manager) check(p *Product) error {
+	p.SKU = strings.TrimSpace(p.SKU)
+	if !skuRe.MatchString(p.SKU) {
+		return fmt.Errorf("%w: %q (use letters, digits, '.', '_' and '-')", ErrInvalidSKU, p.SKU)
+	}
+	for _, issue := range m.validateProduct(p) {
+		if issue.Severity == SeverityError {
+			return fmt.Errorf("%w: %s %s", ErrInvalidProduct, issue.Field, issue.Message)
+		}
+	}
+	return nil
+}
+
+// load reads the data file. Callers must hold mu.
+func (m *Manager) load() ([]Product, error) {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, m.dataFile()))
+	if os.IsNotExist(err) {
+		return []Product{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	products := []Product{}
+	if err := yaml.Unmarshal(data, &products); err != nil { // JSON is valid YAML
+		return nil, fmt.Errorf("failed to parse %s: %w", m.dataFile(), err)
+	}
+	return products, nil
+}
+
+// save writes the data file in the format of its extension. Callers must hold mu.
+func (m *Manager) save(products []Product) error {
+	data, err := yaml.Marshal(products)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(m.dataFile()), ".json") {
+		// Round trip through YAML so extra fields stay inline
+		var generic interface{}
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(generic, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+
+	path := filepath.Join(m.projectDir, m.dataFile())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0644)
+}
+
+// find returns the index of the product with the given SKU, compared case-insensitively, or -1
+func find(products []Product, sku string) int {
+	for i, p := range products {
+		if strings.EqualFold(p.SKU, sku) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// GenerateResult summarizes a product page generation run
+type GenerateResult struct {
+	Created   []string `json:"created"`
+	Updated   []string `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Orphans   []string `json:"orphans"` // Product pages whose SKU is no longer in the data file
+	Skipped   []Issue  `json:"skipped"` // Products with errors, left without a page
+}
+
+// pagesDir returns the project-relative directory of product pages
+func (m *Manager) pagesDir() string {
+	if m.config.PagesDir == "" {
+		return "content/products"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.PagesDir))
+}
+
+// pagesBySKU maps the lower-cased sku front matter of existing product pages to their project-relative paths
+func (m *Manager) pagesBySKU() (map[string]string, error) {
+	pages := map[string]string{}
+	root := filepath.Join(m.projectDir, m.pagesDir())
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" || strings.HasPrefix(d.Name(), "_index.") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fm, _, _, err := hugocontent.Parse(data)
+		if err != nil {
+			return nil
+		}
+		if sku := fm.String("sku"); sku != "" {
+			rel, _ := filepath.Rel(m.projectDir, path)
+			pages[strings.ToLower(sku)] = filepath.ToSlash(rel)
+		}
+		return nil
+	})
+	return pages, err
+}
+
+// GeneratePages creates a page for every valid product and refreshes the product fields of existing pages,
+// keeping their body and other front matter. Pages are matched by their sku front matter.
+func (m *Manager) GeneratePages() (*GenerateResult, error) {
+	products, err := m.Products()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := m.pagesBySKU()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GenerateResult{Created: []string{}, Updated: []string{}, Orphans: []string{}, Skipped: []Issue{}}
+	for i := range products {
+		p := &products[i]
+		if err := m.check(p); err != nil {
+			result.Skipped = append(result.Skipped, Issue{SKU: p.SKU, Field: "product", Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+
+		key := strings.ToLower(p.SKU)
+		rel, exists := pages[key]
+		delete(pages, key)
+		if !exists {
+			rel = m.pagesDir() + "/" + key + ".md"
+		}
+		path := filepath.Join(m.projectDir, filepath.FromSlash(rel))
+
+		var data []byte
+		if exists {
+			if data, err = os.ReadFile(path); err != nil {
+				return nil, err
+			}
+		}
+		out, err := hugocontent.SetFields(data, m.pageFields(p))
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case exists && bytes.Equal(out, data):
+			result.Unchanged++
+			continue
+		case exists:
+			result.Updated = append(result.Updated, rel)
+		default:
+			result.Created = append(result.Created, rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := atomicfile.WriteFile(path, out, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rel := range pages {
+		result.Orphans = append(result.Orphans, rel)
+	}
+	sort.Strings(result.Orphans)
+	return result, nil
+}
+
+// pageFields returns the front matter a product page gets from its product
+func (m *Manager) pageFields(p *Product) map[string]interface{} {
+	fields := map[string]interface{}{
+		"title":    p.Name,
+		"sku":      p.SKU,
+		"price":    p.Price,
+		"currency": m.currency(p),
+	}
+	if p.Description != "" {
+		fields["description"] = p.Description
+	}
+	if len(p.Images) > 0 {
+		fields["images"] = p.Images
+	}
+	if len(p.Categories) > 0 {
+		fields["categories"] = p.Categories
+	}
+	if p.Weight > 0 {
+		fields["weight_grams"] = p.Weight // Hugo reserves weight for page ordering
+	}
+	if p.Stock != nil {
+		fields["stock"] = *p.Stock
+	}
+	return fields
+}
@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/fernandezvara/hugo-manager/internal/images"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // Checkout would reject or misprice the product
+	SeverityWarning = "warning" // The product works but is incomplete
+)
+
+// Issue is a validation problem of a product (empty SKU for catalog-wide problems)
+type Issue struct {
+	SKU      string `json:"sku,omitempty"`
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+var currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// currencyDecimals lists ISO 4217 currencies whose minor unit isn't cents
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Validate checks every product for the fields checkouts such as Snipcart and Stripe need,
+// duplicate SKUs, missing photos and products without a generated page
+func (m *Manager) Validate() ([]Issue, error) {
+	products, err := m.Products()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := m.pagesBySKU()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []Issue{}
+	seen := map[string]bool{}
+	for i := range products {
+		p := &products[i]
+		key := strings.ToLower(p.SKU)
+		switch {
+		case p.SKU == "":
+			issues = append(issues, Issue{Field: "sku", Severity: SeverityError, Message: fmt.Sprintf("Product %d (%q) has no SKU", i+1, p.Name)})
+			continue
+		case !skuRe.MatchString(p.SKU):
+			issues = append(issues, Issue{SKU: p.SKU, Field: "sku", Severity: SeverityError, Message: "SKU may only contain letters, digits, '.', '_' and '-'"})
+		case seen[key]:
+			issues = append(issues, Issue{SKU: p.SKU, Field: "sku", Severity: SeverityError, Message: "Duplicate SKU"})
+		}
+		seen[key] = true
+
+		issues = append(issues, m.validateProduct(p)...)
+		if _, ok := pages[key]; !ok {
+			issues = append(issues, Issue{SKU: p.SKU, Field: "page", Severity: SeverityWarning, Message: "No product page; generate pages to create it"})
+		}
+	}
+	return issues, nil
+}
+
+// validateProduct checks the fields of a single product
+func (m *Manager) validateProduct(p *Product) []Issue {
+	var issues []Issue
+	add := func(field, severity, message string) {
+		issues = append(issues, Issue{SKU: p.SKU, Field: field, Severity: severity, Message: message})
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		add("name", SeverityError, "Name is required")
+	}
+
+	currency := m.currency(p)
+	if !currencyRe.MatchString(currency) {
+		add("currency", SeverityError, fmt.Sprintf("%q is not an ISO 4217 currency code such as USD", currency))
+	}
+	decimals, ok := currencyDecimals[currency]
+	if !ok {
+		decimals = 2
+	}
+	switch {
+	case math.IsNaN(p.Price) || math.IsInf(p.Price, 0) || p.Price < 0:
+		add("price", SeverityError, "Price must be a positive amount")
+	case p.Price == 0:
+		add("price", SeverityWarning, "Price is zero, so the product is free")
+	default:
+		scaled := p.Price * math.Pow10(decimals)
+		if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+			add("price", SeverityError, fmt.Sprintf("%s prices have at most %d decimals", currency, decimals))
+		}
+	}
+
+	if p.Weight < 0 {
+		add("weight", SeverityError, "Weight can't be negative")
+	}
+	if p.Stock != nil && *p.Stock < 0 {
+		add("stock", SeverityError, "Stock can't be negative")
+	}
+
+	if len(p.Images) == 0 {
+		add("images", SeverityWarning, "Product has no photo")
+	}
+	ratio, _ := images.ParseAspect(m.config.ImageAspect)
+	for _, img := range p.Images {
+		if !strings.HasPrefix(img, "/") || strings.HasPrefix(img, "//") {
+			continue // Remote images aren't checked
+		}
+		f, err := os.Open(filepath.Join(m.projectDir, "static", filepath.FromSlash(path.Clean(img)))) // Clean stops at the root
+		if err != nil {
+			add("images", SeverityError, "Image not found: static"+img)
+			continue
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || ratio <= 0 || cfg.Height == 0 {
+			continue
+		}
+		if actual := float64(cfg.Width) / float64(cfg.Height); math.Abs(actual-ratio)/ratio > 0.02 {
+			add("images", SeverityWarning, fmt.Sprintf("%s is %dx%d, not %s; re-upload it to crop it", img, cfg.Width, cfg.Height, m.config.ImageAspect))
+		}
+	}
+
+	return issues
+}
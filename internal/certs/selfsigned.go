@@ -0,0 +1,129 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File names of the generated certificate and key
+const (
+	CertFile = "cert.pem"
+	KeyFile  = "key.pem"
+)
+
+// validity is how long a generated certificate is valid
+const validity = 365 * 24 * time.Hour
+
+// renewBefore regenerates certificates that expire within this window
+const renewBefore = 7 * 24 * time.Hour
+
+// EnsureSelfSigned returns the paths of a self-signed certificate and key in dir, generating them when missing,
+// expiring soon or not covering every host. localhost and the loopback addresses are always included.
+func EnsureSelfSigned(dir string, hosts ...string) (string, string, error) {
+	certPath := filepath.Join(dir, CertFile)
+	keyPath := filepath.Join(dir, KeyFile)
+
+	names := []string{"localhost", "127.0.0.1", "::1"}
+	for _, h := range hosts {
+		if h != "" && h != "0.0.0.0" && h != "::" {
+			names = append(names, h)
+		}
+	}
+
+	if valid(certPath, keyPath, names) {
+		return certPath, keyPath, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	if err := generate(certPath, keyPath, names); err != nil {
+		return "", "", fmt.Errorf("failed to generate self-signed certificate: %w", err)
+	}
+	return certPath, keyPath, nil
+}
+
+// valid reports whether an existing certificate can be reused
+func valid(certPath, keyPath string, names []string) bool {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil || len(pair.Certificate) == 0 {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil || time.Until(cert.NotAfter) < renewBefore {
+		return false
+	}
+	for _, name := range names {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func generate(certPath, keyPath string, names []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "hugo-manager", Organization: []string{"hugo-manager self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := writePEM(keyPath, "EC PRIVATE KEY", keyDER, 0600); err != nil {
+		return err
+	}
+	return writePEM(certPath, "CERTIFICATE", der, 0644)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
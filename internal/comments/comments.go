@@ -0,0 +1,489 @@
+// Package comments keeps review comments on content files, anchored to a range of lines, and
+// the approval of each file once its comments are resolved. Each file's comments are stored as
+// JSON in the comments directory of .hugo-manager, at the path of the file.
+package comments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// Errors returned by the comment store
+var (
+	ErrNotFound       = errors.New("comment not found")
+	ErrInvalidComment = errors.New("invalid comment")
+	ErrOpenComments   = errors.New("file has open comments")
+)
+
+// dirName is where comments are kept, inside the .hugo-manager directory
+const dirName = "comments"
+
+// maxBody is the longest comment accepted, in bytes
+const maxBody = 10000
+
+// Statuses a query can select comments by
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Comment is a remark on a range of lines of a file
+type Comment struct {
+	ID         string     `json:"id"`
+	Path       string     `json:"path"`      // Project-relative file the comment is on
+	StartLine  int        `json:"startLine"` // First line of the range, from 1
+	EndLine    int        `json:"endLine"`   // Last line of the range, inclusive
+	Body       string     `json:"body"`
+	Author     string     `json:"author,omitempty"` // Empty when auth is disabled
+	Created    time.Time  `json:"created"`
+	Updated    time.Time  `json:"updated"`
+	Resolved   bool       `json:"resolved"`
+	ResolvedBy string     `json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// Update holds the fields of a comment to change; nil fields are kept
+type Update struct {
+	Body      *string `json:"body"`
+	StartLine *int    `json:"startLine"`
+	EndLine   *int    `json:"endLine"`
+	Resolved  *bool   `json:"resolved"`
+}
+
+// Approval is a reviewer's sign-off on a file
+type Approval struct {
+	User string    `json:"user,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// Review is the review state of a file
+type Review struct {
+	Path     string    `json:"path"`
+	Open     int       `json:"open"`     // Comments not resolved yet
+	Resolved int       `json:"resolved"` // Comments resolved
+	Approval *Approval `json:"approval,omitempty"`
+}
+
+// Query selects comments; zero fields don't filter
+type Query struct {
+	Path   string // Comments on this file or files inside this folder
+	Status string // StatusOpen or StatusResolved
+	Author string
+}
+
+// thread is the comments of a file as stored
+type thread struct {
+	Path     string     `json:"path"`
+	Comments []*Comment `json:"comments"`
+	Approval *Approval  `json:"approval,omitempty"`
+}
+
+// Store keeps the comments of a project in memory, writing each file's thread when it changes
+type Store struct {
+	dir string
+
+	mu      sync.RWMutex
+	threads map[string]*thread // By path
+	byID    map[string]string  // Path of each comment
+}
+
+// NewStore loads the comments of a project. A store is returned even when some threads can't be
+// read, without them.
+func NewStore(projectDir string) (*Store, error) {
+	s := &Store{
+		dir:     filepath.Join(projectDir, storage.DirName, dirName),
+		threads: map[string]*thread{},
+		byID:    map[string]string{},
+	}
+
+	var errs []error
+	err := filepath.WalkDir(s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == s.dir {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		var t thread
+		if err := json.Unmarshal(data, &t); err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", p, err))
+			return nil
+		}
+		s.threads[t.Path] = &t
+		for _, c := range t.Comments {
+			s.byID[c.ID] = t.Path
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return s, errors.Join(errs...)
+}
+
+// List returns the comments a query selects, by file and then line
+func (s *Store) List(q Query) []Comment {
+	folder := cleanPath(q.Path)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []Comment{}
+	for p, t := range s.threads {
+		if folder != "" && p != folder && !strings.HasPrefix(p, folder+"/") {
+			continue
+		}
+		for _, c := range t.Comments {
+			if (q.Status == StatusOpen && c.Resolved) || (q.Status == StatusResolved && !c.Resolved) {
+				continue
+			}
+			if q.Author != "" && c.Author != q.Author {
+				continue
+			}
+			list = append(list, *c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.Created.Before(b.Created)
+	})
+	return list
+}
+
+// Get returns a comment by ID
+func (s *Store) Get(id string) (*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	found := *c
+	return &found, nil
+}
+
+// Create adds a comment to a file, withdrawing the file's approval
+func (s *Store) Create(c Comment) (*Comment, error) {
+	c.Path = cleanPath(c.Path)
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Path == "" {
+		return nil, fmt.Errorf("%w: path required", ErrInvalidComment)
+	}
+	if c.EndLine == 0 {
+		c.EndLine = c.StartLine
+	}
+	if err := validate(&c); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	c.ID = hex.EncodeToString(id)
+	c.Created, c.Updated = now, now
+	c.Resolved, c.ResolvedBy, c.ResolvedAt = false, "", nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threads[c.Path]
+	if t == nil {
+		t = &thread{Path: c.Path}
+	}
+	next := &thread{Path: t.Path, Comments: append(append([]*Comment{}, t.Comments...), &c)}
+	if err := s.save(next); err != nil {
+		return nil, err
+	}
+	s.threads[c.Path] = next
+	s.byID[c.ID] = c.Path
+	created := c
+	return &created, nil
+}
+
+// Update changes the fields of a comment that are set. user is who resolves it; reopening a
+// comment withdraws the approval of its file.
+func (s *Store) Update(id string, u Update, user string) (*Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *current
+	if u.Body != nil {
+		c.Body = strings.TrimSpace(*u.Body)
+	}
+	if u.StartLine != nil {
+		c.StartLine = *u.StartLine
+	}
+	if u.EndLine != nil {
+		c.EndLine = *u.EndLine
+	}
+	if err := validate(&c); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if u.Body != nil || u.StartLine != nil || u.EndLine != nil {
+		c.Updated = now
+	}
+	reopened := false
+	if u.Resolved != nil && *u.Resolved != c.Resolved {
+		c.Resolved = *u.Resolved
+		if c.Resolved {
+			c.ResolvedBy, c.ResolvedAt = user, &now
+		} else {
+			c.ResolvedBy, c.ResolvedAt = "", nil
+			reopened = true
+		}
+	}
+
+	t := s.threads[c.Path]
+	next := &thread{Path: t.Path, Approval: t.Approval}
+	if reopened {
+		next.Approval = nil
+	}
+	for _, existing := range t.Comments {
+		if existing.ID == id {
+			existing = &c
+		}
+		next.Comments = append(next.Comments, existing)
+	}
+	if err := s.save(next); err != nil {
+		return nil, err
+	}
+	s.threads[c.Path] = next
+	updated := c
+	return &updated, nil
+}
+
+// Delete removes a comment, returning it
+func (s *Store) Delete(id string) (*Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t := s.threads[c.Path]
+	next := &thread{Path: t.Path, Approval: t.Approval}
+	for _, existing := range t.Comments {
+		if existing.ID != id {
+			next.Comments = append(next.Comments, existing)
+		}
+	}
+	if err := s.save(next); err != nil {
+		return nil, err
+	}
+	s.set(next)
+	delete(s.byID, id)
+	deleted := *c
+	return &deleted, nil
+}
+
+// Review returns the review state of a file
+func (s *Store) Review(p string) Review {
+	p = cleanPath(p)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return review(p, s.threads[p])
+}
+
+// Reviews returns the review state of every file with comments or an approval, by path
+func (s *Store) Reviews() []Review {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Review, 0, len(s.threads))
+	for p, t := range s.threads {
+		list = append(list, review(p, t))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// Approve signs off a file for user. It fails with ErrOpenComments while any of its comments is
+// unresolved; a new or reopened comment withdraws the approval.
+func (s *Store) Approve(p, user string) (Review, error) {
+	p = cleanPath(p)
+	if p == "" {
+		return Review{}, fmt.Errorf("%w: path required", ErrInvalidComment)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threads[p]
+	if t == nil {
+		t = &thread{Path: p}
+	}
+	if r := review(p, t); r.Open > 0 {
+		return r, fmt.Errorf("%w: %d to resolve in %s", ErrOpenComments, r.Open, p)
+	}
+
+	next := &thread{Path: p, Comments: t.Comments, Approval: &Approval{User: user, Time: time.Now().UTC().Truncate(time.Second)}}
+	if err := s.save(next); err != nil {
+		return Review{}, err
+	}
+	s.threads[p] = next
+	return review(p, next), nil
+}
+
+// Withdraw drops the approval of a file. A file without one is left as it is.
+func (s *Store) Withdraw(p string) (Review, error) {
+	p = cleanPath(p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threads[p]
+	if t == nil || t.Approval == nil {
+		return review(p, t), nil
+	}
+	next := &thread{Path: p, Comments: t.Comments}
+	if err := s.save(next); err != nil {
+		return Review{}, err
+	}
+	s.set(next)
+	return review(p, next), nil
+}
+
+// Move follows a renamed file or folder, so the comments of the files in it aren't left behind
+func (s *Store) Move(oldPath, newPath string) error {
+	oldPath, newPath = cleanPath(oldPath), cleanPath(newPath)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paths []string
+	for p := range s.threads {
+		if p == oldPath || strings.HasPrefix(p, oldPath+"/") {
+			paths = append(paths, p)
+		}
+	}
+	var errs []error
+	for _, p := range paths {
+		t := s.threads[p]
+		moved := &thread{Path: newPath + strings.TrimPrefix(p, oldPath), Approval: t.Approval}
+		for _, c := range t.Comments {
+			c := *c
+			c.Path = moved.Path
+			moved.Comments = append(moved.Comments, &c)
+		}
+		if err := s.save(moved); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.save(&thread{Path: p}); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.threads, p)
+		s.threads[moved.Path] = moved
+		for _, c := range moved.Comments {
+			s.byID[c.ID] = moved.Path
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// find returns the comment with an ID. The caller holds mu.
+func (s *Store) find(id string) (*Comment, error) {
+	if t := s.threads[s.byID[id]]; t != nil {
+		for _, c := range t.Comments {
+			if c.ID == id {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// set keeps a thread, forgetting it once it has nothing left. The caller holds mu.
+func (s *Store) set(t *thread) {
+	if len(t.Comments) == 0 && t.Approval == nil {
+		delete(s.threads, t.Path)
+		return
+	}
+	s.threads[t.Path] = t
+}
+
+// save writes a thread, removing its file once it has nothing left. The caller holds mu.
+func (s *Store) save(t *thread) error {
+	file := filepath.Join(s.dir, filepath.FromSlash(t.Path)+".json")
+	if len(t.Comments) == 0 && t.Approval == nil {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(file, data, 0644)
+}
+
+// review counts the comments of a thread, which may be nil
+func review(p string, t *thread) Review {
+	r := Review{Path: p}
+	if t == nil {
+		return r
+	}
+	for _, c := range t.Comments {
+		if c.Resolved {
+			r.Resolved++
+		} else {
+			r.Open++
+		}
+	}
+	if t.Approval != nil {
+		approval := *t.Approval
+		r.Approval = &approval
+	}
+	return r
+}
+
+// validate checks the body and line range of a comment
+func validate(c *Comment) error {
+	switch {
+	case c.Body == "":
+		return fmt.Errorf("%w: body required", ErrInvalidComment)
+	case len(c.Body) > maxBody:
+		return fmt.Errorf("%w: body is longer than %d bytes", ErrInvalidComment, maxBody)
+	case c.StartLine < 1:
+		return fmt.Errorf("%w: startLine must be 1 or more", ErrInvalidComment)
+	case c.EndLine < c.StartLine:
+		return fmt.Errorf("%w: endLine can't be before startLine", ErrInvalidComment)
+	}
+	return nil
+}
+
+// cleanPath returns a project-relative path in the form threads are kept by, or "" for the root
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
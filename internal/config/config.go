@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
 )
 
 const ConfigFileName = "hugo-manager.yaml"
@@ -18,30 +22,135 @@ type Config struct {
 	Images    ImagesConfig    `yaml:"images" json:"images"`
 	FileTree  FileTreeConfig  `yaml:"file_tree" json:"file_tree"`
 	Templates TemplatesConfig `yaml:"templates" json:"templates"`
+	Domain    DomainConfig    `yaml:"domain" json:"domain"`
+	Features  FeaturesConfig  `yaml:"features" json:"features"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks" json:"webhooks"`
+	Storage   StorageConfig   `yaml:"storage" json:"storage"`
+	Docs      DocsConfig      `yaml:"docs" json:"docs"`
+	Events    EventsConfig    `yaml:"events" json:"events"`
+	Podcast   PodcastConfig   `yaml:"podcast" json:"podcast"`
+
+	StructuredData StructuredDataConfig `yaml:"structured_data" json:"structured_data"`
+	Catalog        CatalogConfig        `yaml:"catalog" json:"catalog"`
+	Forms          FormsConfig          `yaml:"forms" json:"forms"`
+	Scripts        ScriptsConfig        `yaml:"scripts" json:"scripts"`
+	Snippets       SnippetsConfig       `yaml:"snippets" json:"snippets"`
+	Drafts         DraftsConfig         `yaml:"drafts" json:"drafts"`
+	Health         HealthConfig         `yaml:"health" json:"health"`
+	Dashboard      DashboardConfig      `yaml:"dashboard" json:"dashboard"`
+	Uploads        UploadsConfig        `yaml:"uploads" json:"uploads"`
+	Links          LinksConfig          `yaml:"links" json:"links"`
+	Prose          ProseConfig          `yaml:"prose" json:"prose"`
+	FrontMatter    FrontMatterConfig    `yaml:"front_matter" json:"front_matter"`
+	Media          MediaConfig          `yaml:"media" json:"media"`
+	Tasks          TasksConfig          `yaml:"tasks" json:"tasks"`
+	Workspaces     WorkspacesConfig     `yaml:"workspaces" json:"workspaces"`
+	BuildChecks    BuildChecksConfig    `yaml:"build_checks" json:"build_checks"`
+	TemplatePaths  []TemplatePath       `yaml:"template_paths" json:"template_paths"`   // Template new content files get by path, first match wins
+	TemplateBodies map[string]string    `yaml:"template_bodies" json:"template_bodies"` // Per template, the markdown a new file starts with, a Go template
+
+	overrides []override // Settings from the environment or command line flags, which Save leaves out
 }
 
 type ServerConfig struct {
-	Port            int      `yaml:"port" json:"port"`
-	Timeout         int      `yaml:"timeout" json:"timeout"`                   // Request timeout in seconds
-	ReadTimeout     int      `yaml:"read_timeout" json:"read_timeout"`         // Read timeout in seconds
-	WriteTimeout    int      `yaml:"write_timeout" json:"write_timeout"`       // Write timeout in seconds
-	IdleTimeout     int      `yaml:"idle_timeout" json:"idle_timeout"`         // Idle timeout in seconds
-	CORSOrigins     []string `yaml:"cors_origins" json:"cors_origins"`         // CORS allowed origins
-	CORSMethods     []string `yaml:"cors_methods" json:"cors_methods"`         // CORS allowed methods
-	CORSHeaders     []string `yaml:"cors_headers" json:"cors_headers"`         // CORS allowed headers
-	WSOrigins       []string `yaml:"ws_origins" json:"ws_origins"`             // WebSocket allowed origins
-	RateLimit       int      `yaml:"rate_limit" json:"rate_limit"`             // Requests per minute (0 = disabled)
-	MaxRequestSize  int      `yaml:"max_request_size" json:"max_request_size"` // Max request size in MB
-	EnableAuth      bool     `yaml:"enable_auth" json:"enable_auth"`           // Enable authentication
-	AuthToken       string   `yaml:"auth_token" json:"auth_token"`             // Simple auth token
-	ShutdownTimeout int      `yaml:"shutdown_timeout" json:"shutdown_timeout"` // Graceful shutdown timeout in seconds
+	Host            string        `yaml:"host" json:"host"` // Bind address (e.g. 0.0.0.0 for all interfaces)
+	Port            int           `yaml:"port" json:"port"`
+	PortRange       int           `yaml:"port_range" json:"port_range"`             // Following ports tried when port is in use (0 = fail instead)
+	Timeout         int           `yaml:"timeout" json:"timeout"`                   // Request timeout in seconds
+	ReadTimeout     int           `yaml:"read_timeout" json:"read_timeout"`         // Read timeout in seconds
+	WriteTimeout    int           `yaml:"write_timeout" json:"write_timeout"`       // Write timeout in seconds
+	IdleTimeout     int           `yaml:"idle_timeout" json:"idle_timeout"`         // Idle timeout in seconds
+	CORSOrigins     []string      `yaml:"cors_origins" json:"cors_origins"`         // CORS allowed origins
+	CORSMethods     []string      `yaml:"cors_methods" json:"cors_methods"`         // CORS allowed methods
+	CORSHeaders     []string      `yaml:"cors_headers" json:"cors_headers"`         // CORS allowed headers
+	WSOrigins       []string      `yaml:"ws_origins" json:"ws_origins"`             // WebSocket allowed origins
+	RateLimit       int           `yaml:"rate_limit" json:"rate_limit"`             // Requests per minute (0 = disabled)
+	MaxRequestSize  int           `yaml:"max_request_size" json:"max_request_size"` // Max request size in MB
+	Compression     bool          `yaml:"compression" json:"compression"`           // gzip or deflate API responses for clients that accept it
+	EnableAuth      bool          `yaml:"enable_auth" json:"enable_auth"`           // Enable authentication
+	AuthToken       string        `yaml:"auth_token" json:"auth_token"`             // Simple auth token, with the admin role
+	Tokens          []AuthToken   `yaml:"tokens" json:"tokens"`                     // Named auth tokens, each with a role
+	SessionHours    int           `yaml:"session_hours" json:"session_hours"`       // How long a login to the UI lasts
+	ShutdownTimeout int           `yaml:"shutdown_timeout" json:"shutdown_timeout"` // Graceful shutdown timeout in seconds
+	ReadOnly        bool          `yaml:"read_only" json:"read_only"`               // Reject all mutating requests
+	Tracing         TracingConfig `yaml:"tracing" json:"tracing"`                   // OpenTelemetry tracing
+	LogLevel        string        `yaml:"log_level" json:"log_level"`               // debug, info, warn or error
+	LogFormat       string        `yaml:"log_format" json:"log_format"`             // text or json
+	LogFile         string        `yaml:"log_file" json:"log_file"`                 // Write logs to this file instead of stderr
+	TLSCert         string        `yaml:"tls_cert" json:"tls_cert"`                 // PEM certificate file; enables HTTPS
+	TLSKey          string        `yaml:"tls_key" json:"tls_key"`                   // PEM private key file
+	TLSSelfSigned   bool          `yaml:"tls_self_signed" json:"tls_self_signed"`   // Serve HTTPS with a generated self-signed certificate
+}
+
+// Roles of auth tokens
+const (
+	RoleAdmin  = "admin"  // Everything
+	RoleEditor = "editor" // Content, images and media, but not hugo-manager.yaml, templates, shortcodes or deploys
+)
+
+// AuthToken is an auth token and the role of whoever uses it
+type AuthToken struct {
+	Name  string `yaml:"name" json:"name"` // Who uses the token, for the logs
+	Token string `yaml:"token" json:"token"`
+	Role  string `yaml:"role" json:"role"` // admin or editor
+}
+
+// TracingConfig configures OpenTelemetry trace export
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled" json:"enabled"`
+	Exporter    string  `yaml:"exporter" json:"exporter"`         // "otlp" (HTTP) or "stdout"
+	Endpoint    string  `yaml:"endpoint" json:"endpoint"`         // OTLP collector host:port (defaults to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318)
+	Insecure    bool    `yaml:"insecure" json:"insecure"`         // Use plain HTTP for the OTLP endpoint
+	ServiceName string  `yaml:"service_name" json:"service_name"` // Reported service.name
+	SampleRatio float64 `yaml:"sample_ratio" json:"sample_ratio"` // Fraction of traces sampled (0-1)
 }
 
 type HugoConfig struct {
-	Port              int      `yaml:"port" json:"port"`
-	AutoStart         bool     `yaml:"auto_start" json:"auto_start"`
-	AdditionalArgs    []string `yaml:"additional_args" json:"additional_args"`
-	DisableFastRender bool     `yaml:"disable_fast_render" json:"disable_fast_render"`
+	Port              int            `yaml:"port" json:"port"`
+	PortRange         int            `yaml:"port_range" json:"port_range"`       // Following ports tried when port is in use (0 = fail instead)
+	StartTimeout      int            `yaml:"start_timeout" json:"start_timeout"` // Seconds Hugo has to build and start serving before its status becomes error
+	StopTimeout       int            `yaml:"stop_timeout" json:"stop_timeout"`   // Seconds Hugo is given to exit before it's killed
+	AutoStart         bool           `yaml:"auto_start" json:"auto_start"`
+	AdditionalArgs    []string       `yaml:"additional_args" json:"additional_args"`
+	DisableFastRender bool           `yaml:"disable_fast_render" json:"disable_fast_render"`
+	BuildDrafts       bool           `yaml:"build_drafts" json:"build_drafts"`   // Render drafts (can be toggled at runtime)
+	BuildFuture       bool           `yaml:"build_future" json:"build_future"`   // Render content with a future publish date
+	BuildExpired      bool           `yaml:"build_expired" json:"build_expired"` // Render content past its expiry date
+	Profile           string         `yaml:"profile" json:"profile"`             // Profile the server starts with (empty = Hugo's default environment)
+	Profiles          []HugoProfile  `yaml:"profiles" json:"profiles"`
+	MaxLogs           int            `yaml:"max_logs" json:"max_logs"`               // Log entries kept in memory
+	LogRetention      int            `yaml:"log_retention" json:"log_retention"`     // Drop log entries older than this many minutes (0 = keep until evicted)
+	MaxLineLength     int            `yaml:"max_line_length" json:"max_line_length"` // Truncate longer log lines (0 = no limit)
+	Watchdog          WatchdogConfig `yaml:"watchdog" json:"watchdog"`
+	Instances         []HugoInstance `yaml:"instances" json:"instances"` // More Hugo servers run beside this one, with other flags
+}
+
+// HugoInstance is another Hugo server of the project, on its own port and with its own content
+// flags and profile. The rest of its settings are hugo's.
+type HugoInstance struct {
+	Name         string `yaml:"name" json:"name"`
+	Port         int    `yaml:"port" json:"port"` // Following ports up to hugo.port_range are tried when it's in use
+	AutoStart    bool   `yaml:"auto_start" json:"auto_start"`
+	BuildDrafts  bool   `yaml:"build_drafts" json:"build_drafts"`
+	BuildFuture  bool   `yaml:"build_future" json:"build_future"`
+	BuildExpired bool   `yaml:"build_expired" json:"build_expired"`
+	Profile      string `yaml:"profile" json:"profile"` // Empty = Hugo's default environment
+}
+
+// HugoProfile is a named environment and baseURL to run the server or build the site with
+type HugoProfile struct {
+	Name        string `yaml:"name" json:"name"`
+	Environment string `yaml:"environment" json:"environment"` // --environment, selecting config/<environment>/ (empty = Hugo's default)
+	BaseURL     string `yaml:"base_url" json:"base_url"`       // --baseURL override (empty = the site's)
+}
+
+// WatchdogConfig configures health checks of the running Hugo server
+type WatchdogConfig struct {
+	Interval    int  `yaml:"interval" json:"interval"`         // Seconds between health checks (0 = disabled)
+	Failures    int  `yaml:"failures" json:"failures"`         // Consecutive failed checks before Hugo is considered unresponsive
+	AutoRestart bool `yaml:"auto_restart" json:"auto_restart"` // Restart Hugo when it dies or stops responding
+	Backoff     int  `yaml:"backoff" json:"backoff"`           // Seconds before the first restart, doubled for each restart in a row
+	MaxBackoff  int  `yaml:"max_backoff" json:"max_backoff"`   // Upper bound for the restart delay in seconds
 }
 
 type EditorConfig struct {
@@ -53,37 +162,350 @@ type EditorConfig struct {
 	AutoSave           bool     `yaml:"auto_save" json:"auto_save"`
 	AutoSaveDelay      int      `yaml:"auto_save_delay" json:"auto_save_delay"`
 	EditableExtensions []string `yaml:"editable_extensions" json:"editable_extensions"`
+	MaxFileSizeMB      int      `yaml:"max_file_size_mb" json:"max_file_size_mb"` // Larger files open as a download, not in the editor (0 = unlimited)
+	LockTTL            int      `yaml:"lock_ttl" json:"lock_ttl"`                 // Seconds an advisory file lock lasts without being renewed
 }
 
 type TemplateField struct {
-	Type    string `yaml:"type" json:"type"`
-	Default string `yaml:"default" json:"default"`
+	Type    string   `yaml:"type" json:"type"`
+	Default string   `yaml:"default" json:"default"`
+	Options []string `yaml:"options,omitempty" json:"options,omitempty"` // Choices of a select field
+	Section string   `yaml:"section,omitempty" json:"section,omitempty"` // Section of content/ a reference field picks pages from (empty = any)
 }
 
 type TemplatesConfig map[string]map[string]TemplateField
 
+// TemplatePath binds a template to the content files whose path matches a glob
+type TemplatePath struct {
+	Path     string `yaml:"path" json:"path"`         // Project-relative glob, where ** matches any number of directories, e.g. content/blog/**
+	Template string `yaml:"template" json:"template"` // Name of a template in templates
+}
+
 type ImagesConfig struct {
 	DefaultQuality int           `yaml:"default_quality" json:"default_quality"`
 	Presets        []ImagePreset `yaml:"presets" json:"presets"`
 	OutputFormat   string        `yaml:"output_format" json:"output_format"`
+	MaxConcurrent  int           `yaml:"max_concurrent" json:"max_concurrent"` // Max images decoded/encoded at once (0 = unlimited)
+	MaxMegapixels  float64       `yaml:"max_megapixels" json:"max_megapixels"` // Reject source images larger than this (0 = unlimited)
+	KeepOriginals  bool          `yaml:"keep_originals" json:"keep_originals"` // Keep uploads untouched in an originals/ subfolder of their folder
+
+	VariantName     string                `yaml:"variant_name" json:"variant_name"`         // Name of the variants written, from {name}, {width}, {height}, {hash} and {ext}
+	VariantPatterns []ImageVariantPattern `yaml:"variant_patterns" json:"variant_patterns"` // Naming schemes of variants made by other tools
+	Output          ImageOutputConfig     `yaml:"output" json:"output"`                     // Markup of the snippets of processed images
+}
+
+// ImageOutputConfig shapes the shortcode and HTML returned for processed images to the theme's conventions
+type ImageOutputConfig struct {
+	Sizes     string `yaml:"sizes" json:"sizes"`         // sizes attribute of responsive images (empty = the built-in one)
+	Class     string `yaml:"class" json:"class"`         // Class of the image
+	Shortcode string `yaml:"shortcode" json:"shortcode"` // Go template of the shortcode call (empty = {{< img >}})
+	HTML      string `yaml:"html" json:"html"`           // Go template of the raw HTML (empty = <img>, in a <figure> when captioned)
 }
 
 type ImagePreset struct {
-	Name   string `yaml:"name" json:"name"`
-	Widths []int  `yaml:"widths" json:"widths"`
+	Name    string `yaml:"name" json:"name"`
+	Widths  []int  `yaml:"widths" json:"widths"`                       // Empty, as in Custom, uses the widths of the upload
+	Quality int    `yaml:"quality,omitempty" json:"quality,omitempty"` // JPEG quality (0 = images.default_quality)
+	Format  string `yaml:"format,omitempty" json:"format,omitempty"`   // jpg or png (empty = images.output_format)
+}
+
+// ImageVariantPattern recognizes existing variants by file name, tried after the built-in name.WIDTHxHEIGHT.ext
+type ImageVariantPattern struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"` // Regexp with the named groups base, ext and w (width) or x (pixel density); h (height) is optional
+}
+
+type DomainConfig struct {
+	Name          string `yaml:"name" json:"name"`                     // Production domain (defaults to the host of Hugo's baseURL)
+	CheckInterval int    `yaml:"check_interval" json:"check_interval"` // Check interval in minutes (0 = disabled)
+	WarnDays      int    `yaml:"warn_days" json:"warn_days"`           // Warn when the certificate expires within this many days
+	CriticalDays  int    `yaml:"critical_days" json:"critical_days"`   // Critical when the certificate expires within this many days
+}
+
+// FeaturesConfig enables or disables whole subsystems per deployment
+type FeaturesConfig struct {
+	Images      bool `yaml:"images" json:"images"`             // Image processing endpoints
+	ConfigEdit  bool `yaml:"config_edit" json:"config_edit"`   // Editing hugo-manager.yaml from the UI
+	HugoControl bool `yaml:"hugo_control" json:"hugo_control"` // Starting, stopping and restarting Hugo
+	Uploads     bool `yaml:"uploads" json:"uploads"`           // File and image uploads
+}
+
+// WebhooksConfig configures HTTP notifications sent on content and build events
+type WebhooksConfig struct {
+	Endpoints []WebhookEndpoint `yaml:"endpoints" json:"endpoints"`
+	Timeout   int               `yaml:"timeout" json:"timeout"` // Delivery timeout in seconds
+}
+
+type WebhookEndpoint struct {
+	URL    string   `yaml:"url" json:"url"`
+	Secret string   `yaml:"secret" json:"secret"` // HMAC-SHA256 signing key (optional)
+	Events []string `yaml:"events" json:"events"` // Events to deliver (empty = all)
+}
+
+// StorageConfig bounds the trash, history and cache areas kept in the .hugo-manager directory
+type StorageConfig struct {
+	GCInterval int           `yaml:"gc_interval" json:"gc_interval"` // GC interval in minutes (0 = disabled)
+	Trash      StorageLimits `yaml:"trash" json:"trash"`
+	History    StorageLimits `yaml:"history" json:"history"`
+	Cache      StorageLimits `yaml:"cache" json:"cache"`
+}
+
+type StorageLimits struct {
+	MaxSizeMB  int `yaml:"max_size_mb" json:"max_size_mb"`   // Oldest files are removed above this size (0 = unlimited)
+	MaxAgeDays int `yaml:"max_age_days" json:"max_age_days"` // Files older than this are removed (0 = keep forever)
+}
+
+// DocsConfig configures documentation site helpers
+type DocsConfig struct {
+	ContentDir   string `yaml:"content_dir" json:"content_dir"`     // Directory with one subdirectory per docs version
+	VersionsFile string `yaml:"versions_file" json:"versions_file"` // Data file listing versions for the theme's switcher
+}
+
+// EventsConfig configures recurring content generated from templates
+type EventsConfig struct {
+	Interval int           `yaml:"interval" json:"interval"` // Hours between background generation runs (0 = manual only)
+	Series   []EventSeries `yaml:"series" json:"series"`
+}
+
+// EventSeries describes a recurring event such as a weekly meetup or a monthly report
+type EventSeries struct {
+	Name      string `yaml:"name" json:"name"`
+	Directory string `yaml:"directory" json:"directory"` // Project-relative directory for generated pages
+	Template  string `yaml:"template" json:"template"`   // Project-relative page template (empty = default front matter)
+	Frequency string `yaml:"frequency" json:"frequency"` // weekly, biweekly or monthly
+	Weekday   string `yaml:"weekday" json:"weekday"`     // Day of the week for weekly, biweekly and monthly-by-week series
+	Week      string `yaml:"week" json:"week"`           // Week of the month for monthly series: first, second, third, fourth or last
+	Day       int    `yaml:"day" json:"day"`             // Day of the month for monthly series (overrides weekday)
+	Time      string `yaml:"time" json:"time"`           // Start time, HH:MM
+	Duration  int    `yaml:"duration" json:"duration"`   // Minutes
+	Timezone  string `yaml:"timezone" json:"timezone"`   // IANA zone (empty = server local time)
+	Location  string `yaml:"location" json:"location"`
+	Ahead     int    `yaml:"ahead" json:"ahead"` // Future occurrences to keep generated (default 4)
+	Start     string `yaml:"start" json:"start"` // First date of the series, YYYY-MM-DD (sets the biweekly phase)
+}
+
+// PodcastConfig configures podcast episode management
+type PodcastConfig struct {
+	EpisodesDir string `yaml:"episodes_dir" json:"episodes_dir"` // Directory of episode pages
+}
+
+// StructuredDataConfig configures JSON-LD generation from front matter
+type StructuredDataConfig struct {
+	Sections       map[string]string            `yaml:"sections" json:"sections"`                 // Section to schema.org type (Article, Recipe, Event, FAQPage)
+	Templates      map[string]map[string]string `yaml:"templates" json:"templates"`               // Per type, schema.org property to front matter key or {{key}} template
+	FrontMatterKey string                       `yaml:"front_matter_key" json:"front_matter_key"` // Key the generated JSON-LD is written to
+}
+
+// CatalogConfig configures product management for e-commerce sites
+type CatalogConfig struct {
+	DataFile    string `yaml:"data_file" json:"data_file"`       // Products data file (YAML or JSON) read by the theme
+	PagesDir    string `yaml:"pages_dir" json:"pages_dir"`       // Directory of the product pages generated from the data file
+	Currency    string `yaml:"currency" json:"currency"`         // Default ISO 4217 currency of prices
+	ImageFolder string `yaml:"image_folder" json:"image_folder"` // Folder product photos are processed into
+	ImageAspect string `yaml:"image_aspect" json:"image_aspect"` // Crop product photos to W:H (empty = keep)
+	ImageWidths []int  `yaml:"image_widths" json:"image_widths"` // Widths generated for each photo
+}
+
+// FormsConfig configures the third-party endpoints that receive the site's form submissions
+type FormsConfig struct {
+	DataFile string       `yaml:"data_file" json:"data_file"` // Data file the endpoints are generated into for templates
+	Timeout  int          `yaml:"timeout" json:"timeout"`     // Test submission and endpoint check timeout in seconds
+	Forms    []FormConfig `yaml:"forms" json:"forms"`
+}
+
+type FormConfig struct {
+	Name     string   `yaml:"name" json:"name"`         // Key of the form in the data file (e.g. contact)
+	Provider string   `yaml:"provider" json:"provider"` // formspree, netlify or webhook
+	ID       string   `yaml:"id" json:"id"`             // Formspree form ID
+	URL      string   `yaml:"url" json:"url"`           // Self-hosted webhook receiving the submissions
+	Redirect string   `yaml:"redirect" json:"redirect"` // Page shown after a successful submission (optional)
+	Fields   []string `yaml:"fields" json:"fields"`     // Field names (default name, email, message)
+	Honeypot string   `yaml:"honeypot" json:"honeypot"` // Hidden anti-spam field left empty by humans (optional)
+}
+
+// ScriptsConfig configures the third-party scripts and cookie consent banner generated into partials
+type ScriptsConfig struct {
+	PartialsDir string         `yaml:"partials_dir" json:"partials_dir"` // Directory under layouts/partials the partials are written to
+	Consent     ConsentConfig  `yaml:"consent" json:"consent"`
+	Scripts     []ScriptConfig `yaml:"scripts" json:"scripts"`
+}
+
+type ConsentConfig struct {
+	Enabled    bool     `yaml:"enabled" json:"enabled"`       // Hold back scripts outside the necessary category until the visitor consents
+	Categories []string `yaml:"categories" json:"categories"` // Categories visitors can accept
+	Message    string   `yaml:"message" json:"message"`       // Banner text
+	PolicyURL  string   `yaml:"policy_url" json:"policy_url"` // Privacy policy linked from the banner
+	Expiry     int      `yaml:"expiry" json:"expiry"`         // Days the visitor's choice is remembered
+}
+
+type ScriptConfig struct {
+	Name           string            `yaml:"name" json:"name"`
+	Category       string            `yaml:"category" json:"category"` // necessary (always loaded) or a consent category
+	Src            string            `yaml:"src" json:"src"`           // External script URL
+	Inline         string            `yaml:"inline" json:"inline"`     // Inline code, used when src is empty
+	Position       string            `yaml:"position" json:"position"` // head (default) or body
+	Async          bool              `yaml:"async" json:"async"`
+	Defer          bool              `yaml:"defer" json:"defer"`
+	Attributes     map[string]string `yaml:"attributes" json:"attributes"`           // Extra attributes, e.g. data-domain
+	ProductionOnly bool              `yaml:"production_only" json:"production_only"` // Leave out of hugo server previews
+}
+
+// SnippetsConfig sets the accessibility defaults of the generated image, gallery and button markup
+type SnippetsConfig struct {
+	Lang        string `yaml:"lang" json:"lang"`                 // Language of snippet text when it differs from the page's (empty = inherit)
+	Dir         string `yaml:"dir" json:"dir"`                   // Text direction: ltr, rtl or auto (empty = inherit, rtl for right-to-left languages)
+	NewTabText  string `yaml:"new_tab_text" json:"new_tab_text"` // Told to screen readers on links that open a new tab
+	HiddenClass string `yaml:"hidden_class" json:"hidden_class"` // The theme's class for text only screen readers see
+}
+
+// DraftsConfig decides which drafts the cleanup tool treats as abandoned
+type DraftsConfig struct {
+	MinAgeMonths int    `yaml:"min_age_months" json:"min_age_months"` // Drafts dated at least this many months ago
+	IdleMonths   int    `yaml:"idle_months" json:"idle_months"`       // And not edited for this many months
+	ArchiveDir   string `yaml:"archive_dir" json:"archive_dir"`       // Project directory archived drafts are moved to, keeping their paths
+}
+
+// HealthConfig weights the checks of the project health score
+type HealthConfig struct {
+	Weights     map[string]int `yaml:"weights" json:"weights"`           // Weight per check (0 = left out): links, alt_text, stale, validation, build, assets
+	MaxAssetKB  int            `yaml:"max_asset_kb" json:"max_asset_kb"` // Images, styles, scripts and fonts above this size are oversized
+	HistoryDays int            `yaml:"history_days" json:"history_days"` // Daily scores kept for the trend
+}
+
+// DashboardConfig picks the widgets of the UI's landing view
+type DashboardConfig struct {
+	Widgets []DashboardWidget `yaml:"widgets" json:"widgets"` // Shown in this order
+}
+
+type DashboardWidget struct {
+	Type  string `yaml:"type" json:"type"`   // recent_content, build_status, deploy_history, analytics, health_score or domain
+	Title string `yaml:"title" json:"title"` // Heading in the UI (empty = the widget's default)
+	Limit int    `yaml:"limit" json:"limit"` // Items listed by list widgets (0 = default)
+}
+
+// UploadsConfig limits resumable uploads and the types of file uploads
+type UploadsConfig struct {
+	MaxSizeMB   int           `yaml:"max_size_mb" json:"max_size_mb"`     // Largest file accepted (0 = unlimited)
+	ChunkSizeMB int           `yaml:"chunk_size_mb" json:"chunk_size_mb"` // Largest chunk accepted per request
+	ExpireHours int           `yaml:"expire_hours" json:"expire_hours"`   // Unfinished uploads without new chunks for this long are discarded (0 = never)
+	Types       []UploadTypes `yaml:"types" json:"types"`                 // Extensions file uploads may have, per folder (empty = any)
+}
+
+// UploadTypes lists the extensions of the files uploads may place below a folder. The rule of
+// the deepest folder holding a file applies to it.
+type UploadTypes struct {
+	Folder     string   `yaml:"folder" json:"folder"`         // Project-relative; empty for the whole project
+	Extensions []string `yaml:"extensions" json:"extensions"` // Without the dot, e.g. pdf; * allows any
+}
+
+// LinksConfig paces the checks of external links made by the link checker
+type LinksConfig struct {
+	RequestsPerSecond int `yaml:"requests_per_second" json:"requests_per_second"` // External links requested per second, across all hosts
+	Timeout           int `yaml:"timeout" json:"timeout"`                         // Seconds an external link has to respond
+}
+
+// ProseConfig sets up the spell checker and style rules of the prose linter. Content in a language
+// with no dictionary is checked for style only.
+type ProseConfig struct {
+	Dictionaries     map[string][]string `yaml:"dictionaries" json:"dictionaries"`             // Per language code, word list files with one word per line, relative to the project or absolute
+	MaxSentenceWords int                 `yaml:"max_sentence_words" json:"max_sentence_words"` // Words a sentence may have before it's flagged as too long (0 = no limit)
+}
+
+// FrontMatterConfig declares the front matter fields of the pages of each section, checked when a
+// page is saved and by the front matter linter
+type FrontMatterConfig struct {
+	OnSave   string                       `yaml:"on_save" json:"on_save"`   // off, warn (save and report the problems) or block (refuse saves with errors)
+	Default  FrontMatterSchema            `yaml:"default" json:"default"`   // Pages at the root of the content and in sections without a schema
+	Sections map[string]FrontMatterSchema `yaml:"sections" json:"sections"` // By section, the first directory of a language's content
+}
+
+// FrontMatterSchema lists the front matter fields of the pages of a section
+type FrontMatterSchema struct {
+	Fields map[string]FrontMatterField `yaml:"fields" json:"fields"`
+	Strict bool                        `yaml:"strict" json:"strict"` // Report fields that aren't listed
+}
+
+// FrontMatterField is a front matter field a schema expects
+type FrontMatterField struct {
+	Type     string   `yaml:"type" json:"type"` // A template field type: text, textarea, number, bool, date, image, array, select, tags or reference
+	Required bool     `yaml:"required" json:"required"`
+	Options  []string `yaml:"options,omitempty" json:"options,omitempty"` // Values a select field takes
+}
+
+// MediaConfig sets where the media library uploads files and which files each type takes
+type MediaConfig struct {
+	Folder   string    `yaml:"folder" json:"folder"` // Upload folder under static/ when a request names none
+	PDF      MediaType `yaml:"pdf" json:"pdf"`
+	Video    MediaType `yaml:"video" json:"video"`
+	Audio    MediaType `yaml:"audio" json:"audio"`
+	Download MediaType `yaml:"download" json:"download"` // Archives, documents and other files offered for download
+
+	Posters MediaPostersConfig `yaml:"posters" json:"posters"` // Poster images of videos, made with ffmpeg
+}
+
+type MediaType struct {
+	Extensions []string `yaml:"extensions" json:"extensions"`   // Without the dot, e.g. mp4
+	MaxSizeMB  int      `yaml:"max_size_mb" json:"max_size_mb"` // Largest upload accepted (0 = unlimited)
+}
+
+// MediaPostersConfig picks the frame of a video its poster is made from and the widths of the poster set
+type MediaPostersConfig struct {
+	FFmpeg  string  `yaml:"ffmpeg" json:"ffmpeg"`   // ffmpeg binary, looked up at startup (empty = ffmpeg in PATH)
+	At      float64 `yaml:"at" json:"at"`           // Second the frame is taken at; shorter videos use their first frame
+	Widths  []int   `yaml:"widths" json:"widths"`   // Widths of the responsive poster set, never above the video's
+	Quality int     `yaml:"quality" json:"quality"` // JPEG quality (0 = images.default_quality)
+}
+
+// TasksConfig lists the external commands, such as npm scripts, that /api/tasks can run. Nothing
+// else can be run, and requests can't change a command or its arguments.
+type TasksConfig struct {
+	Timeout   int           `yaml:"timeout" json:"timeout"`       // Seconds a task may run before it's stopped, unless the task sets its own (0 = no limit)
+	MaxOutput int           `yaml:"max_output" json:"max_output"` // Output lines kept of each task's last run (0 = 2000)
+	Commands  []TaskCommand `yaml:"commands" json:"commands"`
+}
+
+// WorkspacesConfig configures workspaces: copies of the site that are edited and previewed apart
+// from the live tree, then merged into it
+type WorkspacesConfig struct {
+	Port      int      `yaml:"port" json:"port"`             // First port of the Hugo servers previewing workspaces
+	PortRange int      `yaml:"port_range" json:"port_range"` // Following ports tried, as each running preview takes one
+	Exclude   []string `yaml:"exclude" json:"exclude"`       // Project-relative directories not copied into workspaces, such as build output
+}
+
+// BuildChecksConfig limits the problems found in the output of a build. Past a limit the build
+// is reported as failed when enforce is on.
+type BuildChecksConfig struct {
+	Enforce         bool `yaml:"enforce" json:"enforce"`                     // Fail builds that go past a limit, rather than only reporting them
+	MaxMissingPages int  `yaml:"max_missing_pages" json:"max_missing_pages"` // Pages of the previous build gone from this one (-1 = no limit)
+	MaxBrokenAssets int  `yaml:"max_broken_assets" json:"max_broken_assets"` // References to local images, styles, scripts and fonts that don't exist (-1 = no limit)
+	MaxEmptyTitles  int  `yaml:"max_empty_titles" json:"max_empty_titles"`   // Pages with a missing or empty <title> (-1 = no limit)
+}
+
+// TaskCommand is a named command run in the project
+type TaskCommand struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Command     []string `yaml:"command" json:"command"` // Program and arguments, run without a shell, e.g. [npm, run, build]
+	Dir         string   `yaml:"dir" json:"dir"`         // Project-relative working directory (empty = the project)
+	Env         []string `yaml:"env" json:"env"`         // KEY=value pairs added to hugo-manager's environment
+	Timeout     int      `yaml:"timeout" json:"timeout"` // Seconds before it's stopped (0 = tasks.timeout)
 }
 
 type FileTreeConfig struct {
 	ShowDirs    []string `yaml:"show_dirs" json:"show_dirs"`
 	HiddenFiles []string `yaml:"hidden_files" json:"hidden_files"`
 	HiddenDirs  []string `yaml:"hidden_dirs" json:"hidden_dirs"`
+	Cache       bool     `yaml:"cache" json:"cache"`       // Keep the tree in memory, refreshed from file system events
+	Symlinks    string   `yaml:"symlinks" json:"symlinks"` // deny, or within_project to follow links that resolve inside the project
 }
 
 // Default returns a default configuration
 func Default() *Config {
 	return &Config{
 		Server: ServerConfig{
+			Host:            "localhost",
 			Port:            8080,
+			PortRange:       10,
 			Timeout:         60,
 			ReadTimeout:     30,
 			WriteTimeout:    30,
@@ -94,15 +516,42 @@ func Default() *Config {
 			WSOrigins:       []string{"*"},
 			RateLimit:       0,  // Disabled by default
 			MaxRequestSize:  50, // 50MB
+			Compression:     true,
 			EnableAuth:      false,
 			AuthToken:       "",
+			SessionHours:    24,
 			ShutdownTimeout: 30,
+			ReadOnly:        false,
+			LogLevel:        "info",
+			LogFormat:       "text",
+			LogFile:         "",
+			TLSCert:         "",
+			TLSKey:          "",
+			TLSSelfSigned:   false,
+			Tracing: TracingConfig{
+				Enabled:     false,
+				Exporter:    "otlp",
+				ServiceName: "hugo-manager",
+				SampleRatio: 1,
+			},
 		},
 		Hugo: HugoConfig{
 			Port:              1313,
+			PortRange:         10,
+			StartTimeout:      60,
+			StopTimeout:       10,
 			AutoStart:         true,
 			DisableFastRender: true,
 			AdditionalArgs:    []string{"--bind", "0.0.0.0"},
+			MaxLogs:           1000,
+			LogRetention:      0,
+			MaxLineLength:     4096,
+			Watchdog: WatchdogConfig{
+				Interval:   10,
+				Failures:   3,
+				Backoff:    2,
+				MaxBackoff: 60,
+			},
 		},
 		Editor: EditorConfig{
 			Theme:         "one-dark",
@@ -112,6 +561,8 @@ func Default() *Config {
 			LineNumbers:   true,
 			AutoSave:      false,
 			AutoSaveDelay: 1000,
+			MaxFileSizeMB: 5,
+			LockTTL:       300,
 			EditableExtensions: []string{
 				"md", "html", "css", "js", "json",
 				"yaml", "yml", "toml", "go", "scss",
@@ -125,9 +576,13 @@ func Default() *Config {
 				{Name: "Desktop only", Widths: []int{1024, 1920}},
 				{Name: "Thumbnail", Widths: []int{150, 300}},
 				{Name: "Social media", Widths: []int{1200}},
+				{Name: "Inline", Widths: []int{640, 1280}},
 				{Name: "Custom", Widths: []int{}},
 			},
-			OutputFormat: "jpg",
+			OutputFormat:  "jpg",
+			VariantName:   DefaultVariantName,
+			MaxConcurrent: 2,
+			MaxMegapixels: 50,
 		},
 		FileTree: FileTreeConfig{
 			ShowDirs: []string{
@@ -147,8 +602,153 @@ func Default() *Config {
 				"public",
 				"resources",
 			},
+			Cache:    true,
+			Symlinks: "within_project",
 		},
 		Templates: TemplatesConfig{},
+		Domain: DomainConfig{
+			Name:          "",
+			CheckInterval: 720, // Every 12 hours
+			WarnDays:      21,
+			CriticalDays:  7,
+		},
+		Features: FeaturesConfig{
+			Images:      true,
+			ConfigEdit:  true,
+			HugoControl: true,
+			Uploads:     true,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout: 10,
+		},
+		Storage: StorageConfig{
+			GCInterval: 60,
+			Trash:      StorageLimits{MaxSizeMB: 500, MaxAgeDays: 30},
+			History:    StorageLimits{MaxSizeMB: 200, MaxAgeDays: 90},
+			Cache:      StorageLimits{MaxSizeMB: 1024, MaxAgeDays: 14},
+		},
+		Docs: DocsConfig{
+			ContentDir:   "content/docs",
+			VersionsFile: "data/versions.yaml",
+		},
+		Events: EventsConfig{
+			Interval: 24,
+		},
+		Podcast: PodcastConfig{
+			EpisodesDir: "content/episodes",
+		},
+		StructuredData: StructuredDataConfig{
+			Sections: map[string]string{
+				"blog":    "Article",
+				"posts":   "Article",
+				"recipes": "Recipe",
+				"events":  "Event",
+				"faq":     "FAQPage",
+			},
+			FrontMatterKey: "structured_data",
+		},
+		Catalog: CatalogConfig{
+			DataFile:    "data/products.yaml",
+			PagesDir:    "content/products",
+			Currency:    "USD",
+			ImageFolder: "static/images/products",
+			ImageAspect: "1:1",
+			ImageWidths: []int{1200, 600, 300},
+		},
+		Forms: FormsConfig{
+			DataFile: "data/forms.yaml",
+			Timeout:  10,
+		},
+		Scripts: ScriptsConfig{
+			PartialsDir: "hugo-manager",
+			Consent: ConsentConfig{
+				Categories: []string{"analytics", "marketing"},
+				Message:    "We use cookies to measure traffic and improve the site. Choose which ones you accept.",
+				Expiry:     180,
+			},
+		},
+		Snippets: SnippetsConfig{
+			NewTabText:  "(opens in a new tab)",
+			HiddenClass: "visually-hidden",
+		},
+		Drafts: DraftsConfig{
+			MinAgeMonths: 12,
+			IdleMonths:   6,
+			ArchiveDir:   "archive",
+		},
+		Health: HealthConfig{
+			Weights: map[string]int{
+				"links":      25,
+				"alt_text":   15,
+				"stale":      10,
+				"validation": 20,
+				"build":      20,
+				"assets":     10,
+			},
+			MaxAssetKB:  500,
+			HistoryDays: 90,
+		},
+		Dashboard: DashboardConfig{
+			Widgets: []DashboardWidget{
+				{Type: "build_status"},
+				{Type: "recent_content", Limit: 10},
+				{Type: "health_score"},
+				{Type: "domain"},
+			},
+		},
+		Uploads: UploadsConfig{
+			MaxSizeMB:   4096,
+			ChunkSizeMB: 16,
+			ExpireHours: 24,
+			Types: []UploadTypes{
+				{Folder: "", Extensions: []string{
+					"md", "markdown", "html", "htm", "css", "scss", "sass", "js", "mjs", "json", "yaml", "yml", "toml", "xml", "csv", "txt",
+					"jpg", "jpeg", "png", "gif", "webp", "avif", "svg", "ico", "bmp",
+					"pdf", "mp3", "wav", "ogg", "m4a", "mp4", "webm", "mov", "vtt", "srt",
+					"woff", "woff2", "ttf", "otf", "eot", "zip", "gz", "webmanifest",
+				}},
+				{Folder: "data", Extensions: []string{"json", "yaml", "yml", "toml", "csv", "xml"}},
+			},
+		},
+		Links: LinksConfig{
+			RequestsPerSecond: 2,
+			Timeout:           10,
+		},
+		Prose: ProseConfig{
+			Dictionaries:     map[string][]string{},
+			MaxSentenceWords: 40,
+		},
+		FrontMatter: FrontMatterConfig{
+			OnSave:   "warn",
+			Sections: map[string]FrontMatterSchema{},
+		},
+		Media: MediaConfig{
+			Folder: "media",
+			PDF:    MediaType{Extensions: []string{"pdf"}, MaxSizeMB: 50},
+			Video:  MediaType{Extensions: []string{"mp4", "m4v", "mov", "webm", "ogv"}, MaxSizeMB: 500},
+			Audio:  MediaType{Extensions: []string{"mp3", "m4a", "wav", "ogg", "oga", "opus", "flac"}, MaxSizeMB: 200},
+			Download: MediaType{
+				Extensions: []string{
+					"zip", "gz", "tgz", "7z", "epub", "csv", "txt",
+					"doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "odp",
+				},
+				MaxSizeMB: 100,
+			},
+			Posters: MediaPostersConfig{
+				At:     1,
+				Widths: []int{1920, 1280, 640},
+			},
+		},
+		Tasks: TasksConfig{
+			Timeout:   600,
+			MaxOutput: 2000,
+			Commands:  []TaskCommand{},
+		},
+		Workspaces: WorkspacesConfig{
+			Port:      1414,
+			PortRange: 10,
+			Exclude:   []string{"public", "resources", "node_modules"},
+		},
 	}
 }
 
@@ -165,6 +765,9 @@ func Load(projectDir string) (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
+	if err := ApplyEnv(cfg); err != nil {
+		return nil, fmt.Errorf("environment override error: %w", err)
+	}
 
 	if cfg.Templates == nil {
 		cfg.Templates = TemplatesConfig{}
@@ -173,19 +776,19 @@ func Load(projectDir string) (*Config, error) {
 		cfg.Templates["Blank File"] = map[string]TemplateField{}
 	}
 
-	// Validate template configuration
-	if err := validateTemplates(cfg.Templates); err != nil {
-		return nil, fmt.Errorf("template configuration error: %w", err)
+	if err := FirstError(Validate(cfg, projectDir)); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
 	}
 
 	return cfg, nil
 }
 
-// Save saves the configuration to the project directory
+// Save saves the configuration to the project directory. Settings overridden by the environment or
+// a command line flag keep the value of the file, unless they were changed from the override.
 func Save(projectDir string, cfg *Config) error {
 	configPath := filepath.Join(projectDir, ConfigFileName)
 
-	data, err := yaml.Marshal(cfg)
+	data, err := yaml.Marshal(cfg.fileValues())
 	if err != nil {
 		return err
 	}
@@ -196,43 +799,23 @@ func Save(projectDir string, cfg *Config) error {
 `)
 	data = append(header, data...)
 
-	return os.WriteFile(configPath, data, 0644)
+	return atomicfile.WriteFile(configPath, data, 0644)
 }
 
-// validateTemplates validates the template configuration
-func validateTemplates(templates TemplatesConfig) error {
-	validTypes := map[string]bool{
-		"text":     true,
-		"textarea": true,
-		"number":   true,
-		"bool":     true,
-		"date":     true,
-		"image":    true,
-		"array":    true,
-	}
-
-	for templateName, fields := range templates {
-		if templateName == "" {
-			return fmt.Errorf("template name cannot be empty")
+// Changed returns the YAML keys of the top-level sections that differ between two configurations
+func Changed(a, b *Config) []string {
+	var keys []string
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	for i := 0; i < va.NumField(); i++ {
+		if !va.Type().Field(i).IsExported() {
+			continue
 		}
-
-		for fieldName, field := range fields {
-			if fieldName == "" {
-				return fmt.Errorf("template '%s': field name cannot be empty", templateName)
-			}
-
-			if field.Type == "" {
-				return fmt.Errorf("template '%s': field '%s': type cannot be empty", templateName, fieldName)
-			}
-
-			if !validTypes[field.Type] {
-				return fmt.Errorf("template '%s': field '%s': invalid type '%s', must be one of: text, textarea, number, bool, date",
-					templateName, fieldName, field.Type)
-			}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			key, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("yaml"), ",")
+			keys = append(keys, key)
 		}
 	}
-
-	return nil
+	return keys
 }
 
 // GetConfigPath returns the path to the config file
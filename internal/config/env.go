@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables that override the configuration
+const EnvPrefix = "HUGO_MANAGER_"
+
+// ApplyEnv overrides the server and hugo settings with environment variables, for containers where
+// editing hugo-manager.yaml is awkward. A variable is named after the YAML keys of its field, e.g.
+// HUGO_MANAGER_SERVER_PORT or HUGO_MANAGER_SERVER_TRACING_ENABLED. Lists of strings are separated
+// by commas; lists of objects, like server.tokens, are JSON. The settings are recorded as
+// overrides, so Save doesn't write their values, such as tokens, to the file.
+func ApplyEnv(cfg *Config) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, section := range []string{"Server", "Hugo"} {
+		field, _ := v.Type().FieldByName(section)
+		if err := applyEnv(cfg, v.FieldByIndex(field.Index), field.Index, EnvPrefix+strings.ToUpper(section)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEnv sets the fields of a struct from the variables named prefix_KEY, recursing into nested
+// structs. index is that of the struct in cfg.
+func applyEnv(cfg *Config, v reflect.Value, index []int, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		field := v.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(cfg, field, fieldIndex, name); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		file := field.Interface()
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		cfg.setOverride(fieldIndex, file, field.Interface())
+	}
+	return nil
+}
+
+// setField parses an environment variable's value into a field
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			items := []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items))
+			return nil
+		}
+		fallthrough
+	default:
+		ptr := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		field.Set(ptr.Elem())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import "reflect"
+
+// override is a setting whose value came from an environment variable or a command line flag
+// rather than from hugo-manager.yaml
+type override struct {
+	index []int       // Of the field, from Config
+	file  interface{} // Value in the file, written back by Save
+	value interface{} // Value of the override
+}
+
+// Override applies set to the configuration and records the settings it changed as overrides, so
+// Save keeps their values in the file. It's for command line flags.
+func (c *Config) Override(set func(*Config)) {
+	before := *c
+	set(c)
+	diffFields(reflect.ValueOf(&before).Elem(), reflect.ValueOf(c).Elem(), nil, func(index []int, old, value reflect.Value) {
+		c.setOverride(index, old.Interface(), value.Interface())
+	})
+}
+
+// KeepOverrides carries the overrides of from over to a configuration edited from it, such as one
+// sent to PUT /api/config. The overrides still win, and a setting left at its override's value keeps
+// the value of the file; one changed by the edit gets that value in the file.
+func (c *Config) KeepOverrides(from *Config) {
+	v := reflect.ValueOf(c).Elem()
+	for _, o := range from.overrides {
+		field := v.FieldByIndex(o.index)
+		file := field.Interface()
+		if reflect.DeepEqual(file, o.value) {
+			file = o.file
+		}
+		setValue(field, o.value)
+		c.setOverride(o.index, file, o.value)
+	}
+}
+
+// ReapplyOverrides applies the overrides of from to a configuration reloaded from the file. The
+// environment variables were read again by Load; the command line flags weren't.
+func (c *Config) ReapplyOverrides(from *Config) {
+	v := reflect.ValueOf(c).Elem()
+	for _, o := range from.overrides {
+		field := v.FieldByIndex(o.index)
+		if reflect.DeepEqual(field.Interface(), o.value) {
+			continue
+		}
+		file := field.Interface()
+		if existing := c.overrideOf(o.index); existing != nil {
+			file = existing.file
+		}
+		setValue(field, o.value)
+		c.setOverride(o.index, file, o.value)
+	}
+}
+
+// fileValues returns a copy of the configuration with the values of the file in place of the
+// overrides still in effect
+func (c *Config) fileValues() *Config {
+	out := *c
+	v := reflect.ValueOf(&out).Elem()
+	for _, o := range c.overrides {
+		field := v.FieldByIndex(o.index)
+		if reflect.DeepEqual(field.Interface(), o.value) {
+			setValue(field, o.file)
+		}
+	}
+	return &out
+}
+
+// overrideOf returns the override of the field at index, or nil
+func (c *Config) overrideOf(index []int) *override {
+	for i := range c.overrides {
+		if reflect.DeepEqual(c.overrides[i].index, index) {
+			return &c.overrides[i]
+		}
+	}
+	return nil
+}
+
+// setOverride records an override, keeping the file value of an earlier one of the same field. The
+// list is copied, as copies of the configuration share it.
+func (c *Config) setOverride(index []int, file, value interface{}) {
+	list := make([]override, 0, len(c.overrides)+1)
+	for _, o := range c.overrides {
+		if reflect.DeepEqual(o.index, index) {
+			file = o.file
+			continue
+		}
+		list = append(list, o)
+	}
+	c.overrides = append(list, override{index: append([]int(nil), index...), file: file, value: value})
+}
+
+// diffFields calls fn with each exported field that differs between two structs of the same type,
+// recursing into nested structs
+func diffFields(a, b reflect.Value, index []int, fn func(index []int, a, b reflect.Value)) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct {
+			diffFields(fa, fb, fieldIndex, fn)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			fn(fieldIndex, fa, fb)
+		}
+	}
+}
+
+// setValue sets a field to a recorded value, which is nil for nil slices and maps
+func setValue(field reflect.Value, value interface{}) {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return
+	}
+	field.Set(reflect.ValueOf(value))
+}
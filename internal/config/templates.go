@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateFor returns the template template_paths binds to a project-relative path, or "" when none does
+func (c *Config) TemplateFor(relPath string) string {
+	relPath = strings.Trim(path.Clean(filepath.ToSlash(relPath)), "/")
+	for _, tp := range c.TemplatePaths {
+		if MatchGlob(tp.Path, relPath) {
+			return tp.Template
+		}
+	}
+	return ""
+}
+
+// Defaults returns the front matter a template gives a new file in dir. Defaults may use {{date}},
+// today's date, and {{section}}, the directory under content/ the file is in; date fields without
+// a default get today's date.
+func (t TemplatesConfig) Defaults(name, dir string, now time.Time) map[string]interface{} {
+	today := now.Format("2006-01-02")
+	r := strings.NewReplacer("{{date}}", today, "{{section}}", contentSection(dir))
+
+	data := map[string]interface{}{}
+	for fieldName, field := range t[name] {
+		value := r.Replace(field.Default)
+		if value == "" && field.Type == "date" {
+			value = today
+		}
+		if value != "" {
+			data[fieldName] = value
+		}
+	}
+	return data
+}
+
+// BodyData is what the placeholders of a template body read: {{.Title}}, {{.Date}}, {{.Section}},
+// {{.Slug}} and any front matter field as {{.Fields.name}}
+type BodyData struct {
+	Title   string
+	Date    string
+	Section string
+	Slug    string // The slug field, or the file name without its extension; the folder's name for a bundle's index
+	Fields  map[string]interface{}
+}
+
+// RenderBody returns the markdown a new file at relPath starts with, from the body template_bodies
+// gives its template and the front matter it's created with; fields it's created without are
+// empty. Without a body it's "".
+func (c *Config) RenderBody(name, relPath string, fields map[string]interface{}, now time.Time) (string, error) {
+	body, ok := c.TemplateBodies[name]
+	if !ok {
+		return "", nil
+	}
+	tmpl, err := parseBody(name, body)
+	if err != nil {
+		return "", err
+	}
+
+	relPath = path.Clean(filepath.ToSlash(relPath))
+	slug := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+	if slug == "index" || slug == "_index" {
+		slug = path.Base(path.Dir(relPath))
+	}
+	data := BodyData{
+		Date:    now.Format("2006-01-02"),
+		Section: contentSection(path.Dir(relPath)),
+		Slug:    slug,
+		Fields:  map[string]interface{}{},
+	}
+	for fieldName := range c.Templates[name] {
+		data.Fields[fieldName] = ""
+	}
+	for key, value := range fields {
+		data.Fields[key] = value
+	}
+	if title, ok := fields["title"]; ok {
+		data.Title = fmt.Sprint(title)
+	}
+	if slug, ok := fields["slug"].(string); ok && slug != "" {
+		data.Slug = slug
+	}
+	if date, ok := fields["date"].(string); ok && date != "" {
+		data.Date = date
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("template body '%s': %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// parseBody parses a template body
+func parseBody(name, body string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("template body '%s': %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// contentSection returns the top-level section of content/ a directory is in, e.g. blog for
+// content/blog/2024, or "" outside of any section
+func contentSection(dir string) string {
+	rest, ok := strings.CutPrefix(strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")+"/", "content/")
+	if !ok {
+		return ""
+	}
+	section, _, _ := strings.Cut(rest, "/")
+	return section
+}
+
+// MatchGlob reports whether a slash-separated path matches a glob. Each segment is matched with
+// path.Match, except **, which matches any number of directories, none included; so content/blog/**
+// matches content/blog itself and everything inside it.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validGlob returns the error of a malformed glob
+func validGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageTemplates are the parsed templates of images.output; a nil template means the built-in markup
+type ImageTemplates struct {
+	Shortcode *template.Template
+	HTML      *htmltemplate.Template
+}
+
+// shortcodeDelims keeps Hugo's shortcode delimiters in image templates as text, so
+// {{< figure src="{{ .Src }}" >}} calls the figure shortcode with the image's src
+var shortcodeDelims = strings.NewReplacer(
+	"{{<", `{{"{{<"}}`, ">}}", `{{">}}"}}`,
+	"{{%", `{{"{{%"}}`, "%}}", `{{"%}}"}}`,
+)
+
+// imageFuncs are the functions image templates can call besides the built-in ones
+var imageFuncs = template.FuncMap{
+	// param renders a shortcode parameter, or nothing when the value is empty. Values are escaped
+	// for quoted parameters before they reach the template.
+	"param": func(name, value string) string {
+		if value == "" {
+			return ""
+		}
+		return " " + name + `="` + value + `"`
+	},
+}
+
+// Parse parses the shortcode template as text and the HTML one as HTML, which escapes what it
+// inserts for the attribute or text it's in
+func (o ImageOutputConfig) Parse() (*ImageTemplates, error) {
+	shortcode, err := parseImageShortcode(o.Shortcode)
+	if err != nil {
+		return nil, fmt.Errorf("images.output.shortcode: %w", err)
+	}
+	html, err := parseImageHTML(o.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("images.output.html: %w", err)
+	}
+	return &ImageTemplates{Shortcode: shortcode, HTML: html}, nil
+}
+
+// parseImageShortcode parses the shortcode template of images; nil when it's empty
+func parseImageShortcode(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	return template.New("shortcode").Option("missingkey=error").Funcs(imageFuncs).Parse(shortcodeDelims.Replace(text))
+}
+
+// parseImageHTML parses the HTML template of images; nil when it's empty
+func parseImageHTML(text string) (*htmltemplate.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	return htmltemplate.New("html").Option("missingkey=error").Parse(text)
+}
@@ -0,0 +1,675 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Severities of validation issues
+const (
+	SeverityError   = "error"   // The configuration can't be used as it is
+	SeverityWarning = "warning" // The configuration works, but likely not as intended
+)
+
+// minTokenLength is the length below which auth tokens are reported as easy to guess
+const minTokenLength = 16
+
+// Issue is a problem found in the configuration
+type Issue struct {
+	Path     string `json:"path"`     // YAML path of the setting, e.g. images.presets[1].widths[0]
+	Severity string `json:"severity"` // error or warning
+	Message  string `json:"message"`
+}
+
+func (i Issue) Error() string {
+	return i.Path + ": " + i.Message
+}
+
+// FirstError returns the first issue that is an error, or nil when there are only warnings
+func FirstError(issues []Issue) error {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return issue
+		}
+	}
+	return nil
+}
+
+// Validate checks the whole configuration and returns every issue found rather than only the
+// first. The directories the file tree shows are looked up in projectDir.
+func Validate(cfg *Config, projectDir string) []Issue {
+	v := &validator{issues: []Issue{}}
+	v.server(cfg.Server)
+	v.hugo(cfg.Hugo, cfg.Server)
+	v.editor(cfg.Editor)
+	v.images(cfg.Images)
+	v.fileTree(cfg.FileTree, projectDir)
+	v.templates(cfg.Templates)
+	v.templatePaths(cfg.TemplatePaths, cfg.Templates)
+	v.templateBodies(cfg.TemplateBodies, cfg.Templates)
+	v.dashboard(cfg.Dashboard)
+	v.media(cfg.Media)
+	v.uploads(cfg.Uploads)
+	v.tasks(cfg.Tasks)
+	v.workspaces(cfg.Workspaces, cfg.Hugo)
+	v.buildChecks(cfg.BuildChecks)
+	v.prose(cfg.Prose, projectDir)
+	v.frontMatter(cfg.FrontMatter)
+	return v.issues
+}
+
+// validator collects the issues of a configuration
+type validator struct {
+	issues []Issue
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// setting is a numeric setting and its YAML path
+type setting struct {
+	path  string
+	value int
+}
+
+// nonNegative reports each of the settings that is below zero
+func (v *validator) nonNegative(settings ...setting) {
+	for _, s := range settings {
+		if s.value < 0 {
+			v.errorf(s.path, "can't be negative")
+		}
+	}
+}
+
+// ports checks a port and the range of ports tried after it
+func (v *validator) ports(section string, port, portRange int) {
+	if port < 0 || port > 65535 {
+		v.errorf(section+".port", "%d is not a port number (0-65535)", port)
+	}
+	if portRange < 0 {
+		v.errorf(section+".port_range", "can't be negative")
+	} else if port+portRange > 65535 {
+		v.errorf(section+".port_range", "ports %d to %d go past 65535", port, port+portRange)
+	}
+}
+
+// server checks the server settings, including auth and tracing
+func (v *validator) server(server ServerConfig) {
+	v.ports("server", server.Port, server.PortRange)
+	v.nonNegative(
+		setting{"server.timeout", server.Timeout},
+		setting{"server.read_timeout", server.ReadTimeout},
+		setting{"server.write_timeout", server.WriteTimeout},
+		setting{"server.idle_timeout", server.IdleTimeout},
+		setting{"server.shutdown_timeout", server.ShutdownTimeout},
+		setting{"server.rate_limit", server.RateLimit},
+		setting{"server.max_request_size", server.MaxRequestSize},
+		setting{"server.session_hours", server.SessionHours},
+	)
+
+	switch strings.ToLower(strings.TrimSpace(server.LogLevel)) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		v.errorf("server.log_level", "unknown level '%s', must be one of: debug, info, warn, error", server.LogLevel)
+	}
+	switch strings.ToLower(server.LogFormat) {
+	case "", "text", "json":
+	default:
+		v.errorf("server.log_format", "unknown format '%s', must be text or json", server.LogFormat)
+	}
+
+	if (server.TLSCert == "") != (server.TLSKey == "") {
+		v.errorf("server.tls_cert", "tls_cert and tls_key must be set together")
+	} else if server.TLSCert != "" && server.TLSSelfSigned {
+		v.warnf("server.tls_self_signed", "ignored because tls_cert and tls_key are set")
+	}
+
+	switch server.Tracing.Exporter {
+	case "", "otlp", "stdout":
+	default:
+		v.errorf("server.tracing.exporter", "unknown exporter '%s', must be otlp or stdout", server.Tracing.Exporter)
+	}
+	if server.Tracing.SampleRatio < 0 || server.Tracing.SampleRatio > 1 {
+		v.errorf("server.tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	v.auth(server)
+}
+
+// auth checks the tokens: each needs a known role and a value no other token has, long enough not to be guessed
+func (v *validator) auth(server ServerConfig) {
+	seen := map[string]string{}
+	if server.AuthToken != "" {
+		seen[server.AuthToken] = "auth_token"
+		if len(server.AuthToken) < minTokenLength {
+			v.warnf("server.auth_token", "shorter than %d characters, so easy to guess", minTokenLength)
+		}
+	}
+	for i, t := range server.Tokens {
+		path := fmt.Sprintf("server.tokens[%d]", i)
+		if other, ok := seen[t.Token]; t.Token == "" {
+			v.errorf(path+".token", "token cannot be empty")
+		} else if ok {
+			v.errorf(path+".token", "same token as %s", other)
+		} else {
+			seen[t.Token] = path
+			if len(t.Token) < minTokenLength {
+				v.warnf(path+".token", "shorter than %d characters, so easy to guess", minTokenLength)
+			}
+		}
+		if t.Role != RoleAdmin && t.Role != RoleEditor {
+			v.errorf(path+".role", "unknown role '%s' (use %s or %s)", t.Role, RoleAdmin, RoleEditor)
+		}
+	}
+	if !server.EnableAuth && len(seen) > 0 {
+		v.warnf("server.enable_auth", "tokens are ignored while enable_auth is false")
+	}
+}
+
+// hugo checks the Hugo server settings
+func (v *validator) hugo(hugo HugoConfig, server ServerConfig) {
+	v.ports("hugo", hugo.Port, hugo.PortRange)
+	if hugo.Port != 0 && hugo.Port == server.Port {
+		v.errorf("hugo.port", "same port as server.port")
+	} else if hugo.Port != 0 && server.Port != 0 && hugo.Port <= server.Port+server.PortRange && server.Port <= hugo.Port+hugo.PortRange {
+		v.warnf("hugo.port_range", "overlaps the ports of server.port_range, so both may try the same port")
+	}
+	v.nonNegative(
+		setting{"hugo.start_timeout", hugo.StartTimeout},
+		setting{"hugo.stop_timeout", hugo.StopTimeout},
+		setting{"hugo.max_logs", hugo.MaxLogs},
+		setting{"hugo.log_retention", hugo.LogRetention},
+		setting{"hugo.max_line_length", hugo.MaxLineLength},
+		setting{"hugo.watchdog.interval", hugo.Watchdog.Interval},
+		setting{"hugo.watchdog.failures", hugo.Watchdog.Failures},
+		setting{"hugo.watchdog.backoff", hugo.Watchdog.Backoff},
+		setting{"hugo.watchdog.max_backoff", hugo.Watchdog.MaxBackoff},
+	)
+	if hugo.Watchdog.MaxBackoff > 0 && hugo.Watchdog.MaxBackoff < hugo.Watchdog.Backoff {
+		v.warnf("hugo.watchdog.max_backoff", "lower than backoff, so every restart waits max_backoff")
+	}
+
+	names := map[string]bool{}
+	for i, p := range hugo.Profiles {
+		path := fmt.Sprintf("hugo.profiles[%d].name", i)
+		if p.Name == "" {
+			v.errorf(path, "name cannot be empty")
+		} else if names[p.Name] {
+			v.errorf(path, "name '%s' is already used", p.Name)
+		}
+		names[p.Name] = true
+	}
+	if hugo.Profile != "" && !names[hugo.Profile] {
+		v.warnf("hugo.profile", "no profile is named '%s', so Hugo starts with its defaults", hugo.Profile)
+	}
+
+	instances := map[string]bool{}
+	for i, inst := range hugo.Instances {
+		path := fmt.Sprintf("hugo.instances[%d]", i)
+		switch {
+		case !instanceNameRe.MatchString(inst.Name):
+			v.errorf(path+".name", "'%s' must be lowercase letters, digits, - or _", inst.Name)
+		case inst.Name == "main":
+			v.errorf(path+".name", "main is the name of the hugo server itself")
+		case instances[inst.Name]:
+			v.errorf(path+".name", "name '%s' is already used", inst.Name)
+		}
+		instances[inst.Name] = true
+
+		v.ports(path, inst.Port, hugo.PortRange)
+		switch {
+		case inst.Port == 0:
+			v.errorf(path+".port", "is required")
+		case inst.Port == hugo.Port || inst.Port == server.Port:
+			v.errorf(path+".port", "same port as hugo.port or server.port")
+		case inst.Port <= hugo.Port+hugo.PortRange && hugo.Port <= inst.Port+hugo.PortRange:
+			v.warnf(path+".port", "overlaps the ports of hugo.port_range, so the servers may take each other's port")
+		}
+		if inst.Profile != "" && !names[inst.Profile] {
+			v.warnf(path+".profile", "no profile is named '%s', so it starts with Hugo's defaults", inst.Profile)
+		}
+	}
+}
+
+// instanceNameRe matches the names of Hugo instances, which appear in URLs and file names
+var instanceNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// images checks the quality, output format, presets and variant naming patterns
+func (v *validator) editor(editor EditorConfig) {
+	if editor.MaxFileSizeMB < 0 {
+		v.errorf("editor.max_file_size_mb", "can't be negative")
+	}
+	if editor.LockTTL < 0 {
+		v.errorf("editor.lock_ttl", "can't be negative")
+	}
+}
+
+func (v *validator) images(images ImagesConfig) {
+	if images.DefaultQuality < 1 || images.DefaultQuality > 100 {
+		v.errorf("images.default_quality", "must be between 1 and 100")
+	}
+	switch strings.ToLower(images.OutputFormat) {
+	case "", "jpg", "jpeg", "png":
+	default:
+		v.warnf("images.output_format", "'%s' can't be written; images are saved as JPEG", images.OutputFormat)
+	}
+	if images.MaxConcurrent < 0 {
+		v.errorf("images.max_concurrent", "can't be negative")
+	}
+	if images.MaxMegapixels < 0 {
+		v.errorf("images.max_megapixels", "can't be negative")
+	}
+
+	names := map[string]bool{}
+	for i, p := range images.Presets {
+		path := fmt.Sprintf("images.presets[%d]", i)
+		if p.Name == "" {
+			v.errorf(path+".name", "name cannot be empty")
+		} else if names[p.Name] {
+			v.errorf(path+".name", "name '%s' is already used", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Quality < 0 || p.Quality > 100 {
+			v.errorf(path+".quality", "must be between 1 and 100")
+		}
+		switch strings.ToLower(p.Format) {
+		case "", "jpg", "jpeg", "png":
+		default:
+			v.warnf(path+".format", "'%s' can't be written; images are saved as JPEG", p.Format)
+		}
+
+		widths := map[int]bool{}
+		for j, w := range p.Widths {
+			if w <= 0 {
+				v.errorf(fmt.Sprintf("%s.widths[%d]", path, j), "widths must be positive")
+			} else if widths[w] {
+				v.warnf(fmt.Sprintf("%s.widths[%d]", path, j), "width %d is listed twice", w)
+			}
+			widths[w] = true
+		}
+	}
+
+	if images.Output.Sizes != "" && strings.ContainsAny(images.Output.Sizes, "\"<>") {
+		v.errorf("images.output.sizes", "can't contain quotes or angle brackets")
+	}
+	if _, err := parseImageShortcode(images.Output.Shortcode); err != nil {
+		v.errorf("images.output.shortcode", "%v", err)
+	}
+	if _, err := parseImageHTML(images.Output.HTML); err != nil {
+		v.errorf("images.output.html", "%v", err)
+	}
+
+	if images.VariantName != "" {
+		if _, err := VariantNamePattern(images.VariantName); err != nil {
+			v.errorf("images.variant_name", "%v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, p := range images.VariantPatterns {
+		path := fmt.Sprintf("images.variant_patterns[%d]", i)
+		if p.Name == "" {
+			v.errorf(path+".name", "name cannot be empty")
+		} else if seen[p.Name] || p.Name == "hugo-manager" || p.Name == "classic" {
+			v.errorf(path+".name", "name '%s' is already used", p.Name)
+		}
+		seen[p.Name] = true
+
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			v.errorf(path+".pattern", "%v", err)
+			continue
+		}
+		groups := map[string]bool{}
+		for _, name := range re.SubexpNames() {
+			groups[name] = true
+		}
+		if !groups["base"] || !groups["ext"] || (!groups["w"] && !groups["x"]) {
+			v.errorf(path+".pattern", "needs the named groups base, ext and w (width) or x (pixel density)")
+		}
+	}
+}
+
+// fileTree checks the symlink policy, and that the directories shown are inside the project and exist
+func (v *validator) fileTree(tree FileTreeConfig, projectDir string) {
+	switch tree.Symlinks {
+	case "deny", "within_project":
+	default:
+		v.errorf("file_tree.symlinks", "unknown policy '%s', must be one of: deny, within_project", tree.Symlinks)
+	}
+
+	for i, dir := range tree.ShowDirs {
+		path := fmt.Sprintf("file_tree.show_dirs[%d]", i)
+		clean := filepath.Clean(filepath.FromSlash(dir))
+		if dir == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			v.errorf(path, "'%s' is not a directory inside the project", dir)
+			continue
+		}
+		if projectDir == "" {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(projectDir, clean)); err != nil {
+			v.warnf(path, "directory '%s' doesn't exist", dir)
+		} else if !info.IsDir() {
+			v.warnf(path, "'%s' is not a directory", dir)
+		}
+	}
+}
+
+// fieldTypes are the types of the fields of metadata templates and front matter schemas
+var fieldTypes = map[string]bool{
+	"text":      true,
+	"textarea":  true,
+	"number":    true,
+	"bool":      true,
+	"date":      true,
+	"image":     true,
+	"array":     true,
+	"select":    true,
+	"tags":      true,
+	"reference": true,
+}
+
+// fieldTypeList names the field types in errors
+const fieldTypeList = "text, textarea, number, bool, date, image, array, select, tags, reference"
+
+// templates checks the field types of the metadata templates
+func (v *validator) templates(templates TemplatesConfig) {
+	for _, templateName := range sortedKeys(templates) {
+		if templateName == "" {
+			v.errorf("templates", "template name cannot be empty")
+			continue
+		}
+
+		fields := templates[templateName]
+		for _, fieldName := range sortedKeys(fields) {
+			field := fields[fieldName]
+			path := fmt.Sprintf("templates.%s.%s", templateName, fieldName)
+			if fieldName == "" {
+				v.errorf("templates."+templateName, "field name cannot be empty")
+				continue
+			}
+
+			if field.Type == "" {
+				v.errorf(path+".type", "type cannot be empty")
+			} else if !fieldTypes[field.Type] {
+				v.errorf(path+".type", "invalid type '%s', must be one of: %s", field.Type, fieldTypeList)
+			}
+			if field.Type == "select" {
+				if len(field.Options) == 0 {
+					v.errorf(path+".options", "a select field needs options")
+				} else if field.Default != "" && !slices.Contains(field.Options, field.Default) {
+					v.warnf(path+".default", "'%s' is not one of the options", field.Default)
+				}
+			}
+		}
+	}
+}
+
+// templatePaths checks that each binding has a well-formed glob and names an existing template
+func (v *validator) templatePaths(bindings []TemplatePath, templates TemplatesConfig) {
+	for i, tp := range bindings {
+		path := fmt.Sprintf("template_paths[%d]", i)
+		if tp.Path == "" {
+			v.errorf(path+".path", "path cannot be empty")
+		} else if err := validGlob(tp.Path); err != nil {
+			v.errorf(path+".path", "invalid glob '%s'", tp.Path)
+		}
+		if _, ok := templates[tp.Template]; !ok {
+			v.errorf(path+".template", "no template is named '%s'", tp.Template)
+		}
+	}
+}
+
+// templateBodies checks that each body parses and belongs to an existing template
+func (v *validator) templateBodies(bodies map[string]string, templates TemplatesConfig) {
+	for _, name := range sortedKeys(bodies) {
+		path := "template_bodies." + name
+		if _, ok := templates[name]; !ok {
+			v.errorf(path, "no template is named '%s'", name)
+		}
+		if _, err := parseBody(name, bodies[name]); err != nil {
+			v.errorf(path, "%v", err)
+		}
+	}
+}
+
+// dashboard checks the widget types of the dashboard
+func (v *validator) dashboard(dashboard DashboardConfig) {
+	validTypes := map[string]bool{
+		"recent_content": true,
+		"build_status":   true,
+		"deploy_history": true,
+		"analytics":      true,
+		"health_score":   true,
+		"domain":         true,
+	}
+
+	for i, widget := range dashboard.Widgets {
+		path := fmt.Sprintf("dashboard.widgets[%d]", i)
+		if !validTypes[widget.Type] {
+			v.errorf(path+".type", "invalid type '%s', must be one of: recent_content, build_status, deploy_history, analytics, health_score, domain", widget.Type)
+		}
+		if widget.Limit < 0 {
+			v.errorf(path+".limit", "can't be negative")
+		}
+	}
+}
+
+// media checks that every extension belongs to a single media type, and the poster settings
+func (v *validator) media(media MediaConfig) {
+	owner := map[string]string{}
+	types := []struct {
+		name string
+		t    MediaType
+	}{{"pdf", media.PDF}, {"video", media.Video}, {"audio", media.Audio}, {"download", media.Download}}
+
+	for _, mt := range types {
+		if mt.t.MaxSizeMB < 0 {
+			v.errorf("media."+mt.name+".max_size_mb", "can't be negative")
+		}
+		for i, ext := range mt.t.Extensions {
+			path := fmt.Sprintf("media.%s.extensions[%d]", mt.name, i)
+			ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+			if ext == "" {
+				v.errorf(path, "extension cannot be empty")
+				continue
+			}
+			if other, ok := owner[ext]; ok && other != mt.name {
+				v.errorf(path, "extension '%s' is in both %s and %s", ext, other, mt.name)
+			}
+			owner[ext] = mt.name
+		}
+	}
+
+	if media.Posters.At < 0 {
+		v.errorf("media.posters.at", "can't be negative")
+	}
+	for i, w := range media.Posters.Widths {
+		if w <= 0 {
+			v.errorf(fmt.Sprintf("media.posters.widths[%d]", i), "widths must be positive")
+		}
+	}
+}
+
+// uploads checks that every upload type rule names a folder inside the project, once, and extensions
+func (v *validator) uploads(uploads UploadsConfig) {
+	folders := map[string]bool{}
+	for i, rule := range uploads.Types {
+		path := fmt.Sprintf("uploads.types[%d]", i)
+		folder := strings.Trim(filepath.ToSlash(filepath.Clean(rule.Folder)), "/")
+		if filepath.IsAbs(rule.Folder) || folder == ".." || strings.HasPrefix(folder, "../") {
+			v.errorf(path+".folder", "'%s' is outside the project", rule.Folder)
+		} else if folders[folder] {
+			v.errorf(path+".folder", "folder '%s' already has a rule", rule.Folder)
+		}
+		folders[folder] = true
+
+		if len(rule.Extensions) == 0 {
+			v.errorf(path+".extensions", "list at least one extension, or * for any")
+		}
+		for j, ext := range rule.Extensions {
+			if ext = strings.TrimPrefix(ext, "."); ext == "" || strings.ContainsAny(ext, "./\\") {
+				v.errorf(fmt.Sprintf("%s.extensions[%d]", path, j), "'%s' is not an extension", rule.Extensions[j])
+			}
+		}
+	}
+}
+
+// tasks checks that every task has a unique name and a command, and runs inside the project
+func (v *validator) tasks(tasks TasksConfig) {
+	v.nonNegative(
+		setting{"tasks.timeout", tasks.Timeout},
+		setting{"tasks.max_output", tasks.MaxOutput},
+	)
+
+	names := map[string]bool{}
+	for i, task := range tasks.Commands {
+		path := fmt.Sprintf("tasks.commands[%d]", i)
+		if task.Name == "" {
+			v.errorf(path+".name", "name cannot be empty")
+		} else if !taskNameRe.MatchString(task.Name) {
+			v.errorf(path+".name", "'%s' may only have letters, digits, '.', '_', ':' and '-'", task.Name)
+		} else if names[task.Name] {
+			v.errorf(path+".name", "name '%s' is already used", task.Name)
+		}
+		names[task.Name] = true
+
+		if len(task.Command) == 0 || strings.TrimSpace(task.Command[0]) == "" {
+			v.errorf(path+".command", "command cannot be empty")
+		}
+		if dir := filepath.ToSlash(filepath.Clean(task.Dir)); filepath.IsAbs(task.Dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			v.errorf(path+".dir", "'%s' is outside the project", task.Dir)
+		}
+		for j, env := range task.Env {
+			if key, _, ok := strings.Cut(env, "="); !ok || key == "" {
+				v.errorf(fmt.Sprintf("%s.env[%d]", path, j), "'%s' is not KEY=value", env)
+			}
+		}
+		if task.Timeout < 0 {
+			v.errorf(path+".timeout", "can't be negative")
+		}
+	}
+}
+
+// taskNameRe matches the names of tasks, which are part of their URL
+var taskNameRe = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// sortedKeys returns the keys of a map in order, so issues are reported in the same order every time
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// workspaces checks the ports of workspace previews and that excluded directories are in the project
+func (v *validator) workspaces(workspaces WorkspacesConfig, hugo HugoConfig) {
+	v.ports("workspaces", workspaces.Port, workspaces.PortRange)
+	if workspaces.Port != 0 && hugo.Port != 0 && workspaces.Port <= hugo.Port+hugo.PortRange && hugo.Port <= workspaces.Port+workspaces.PortRange {
+		v.warnf("workspaces.port_range", "overlaps the ports of hugo.port_range, so previews may take the port of the live server")
+	}
+	for i, dir := range workspaces.Exclude {
+		clean := filepath.ToSlash(filepath.Clean(dir))
+		if dir == "" || clean == "." || filepath.IsAbs(dir) || clean == ".." || strings.HasPrefix(clean, "../") {
+			v.errorf(fmt.Sprintf("workspaces.exclude[%d]", i), "'%s' is not a directory inside the project", dir)
+		}
+	}
+}
+
+// buildChecks checks the limits of build checks, where -1 turns a limit off
+func (v *validator) buildChecks(checks BuildChecksConfig) {
+	for _, s := range []setting{
+		{"build_checks.max_missing_pages", checks.MaxMissingPages},
+		{"build_checks.max_broken_assets", checks.MaxBrokenAssets},
+		{"build_checks.max_empty_titles", checks.MaxEmptyTitles},
+	} {
+		if s.value < -1 {
+			v.errorf(s.path, "must be -1 (no limit) or more")
+		}
+	}
+}
+
+// prose checks the sentence limit and that every dictionary lists files, warning about the ones
+// that can't be found, as spelling in their language goes unchecked without them
+func (v *validator) prose(prose ProseConfig, projectDir string) {
+	v.nonNegative(setting{"prose.max_sentence_words", prose.MaxSentenceWords})
+
+	for _, lang := range sortedKeys(prose.Dictionaries) {
+		path := "prose.dictionaries." + lang
+		if strings.TrimSpace(lang) == "" {
+			v.errorf(path, "language code cannot be empty")
+		}
+		files := prose.Dictionaries[lang]
+		if len(files) == 0 {
+			v.errorf(path, "list at least one word list file")
+		}
+		for i, file := range files {
+			if strings.TrimSpace(file) == "" {
+				v.errorf(fmt.Sprintf("%s[%d]", path, i), "file cannot be empty")
+				continue
+			}
+			if !filepath.IsAbs(file) {
+				if projectDir == "" {
+					continue
+				}
+				file = filepath.Join(projectDir, file)
+			}
+			if _, err := os.Stat(file); err != nil {
+				v.warnf(fmt.Sprintf("%s[%d]", path, i), "word list '%s' doesn't exist", files[i])
+			}
+		}
+	}
+}
+
+// frontMatter checks the save policy and the fields of the front matter schemas
+func (v *validator) frontMatter(fm FrontMatterConfig) {
+	switch fm.OnSave {
+	case "off", "warn", "block":
+	default:
+		v.errorf("front_matter.on_save", "unknown policy '%s', must be one of: off, warn, block", fm.OnSave)
+	}
+
+	v.frontMatterSchema("front_matter.default", fm.Default)
+	for _, section := range sortedKeys(fm.Sections) {
+		path := "front_matter.sections." + section
+		if section == "" || strings.ContainsAny(section, "/\\") {
+			v.errorf(path, "'%s' is not a section, the name of a directory of the content", section)
+			continue
+		}
+		v.frontMatterSchema(path, fm.Sections[section])
+	}
+}
+
+// frontMatterSchema checks the fields of a front matter schema
+func (v *validator) frontMatterSchema(path string, schema FrontMatterSchema) {
+	for _, name := range sortedKeys(schema.Fields) {
+		field := schema.Fields[name]
+		fieldPath := path + ".fields." + name
+		if strings.TrimSpace(name) == "" {
+			v.errorf(path+".fields", "field name cannot be empty")
+			continue
+		}
+		if field.Type == "" {
+			v.errorf(fieldPath+".type", "type cannot be empty")
+		} else if !fieldTypes[field.Type] {
+			v.errorf(fieldPath+".type", "invalid type '%s', must be one of: %s", field.Type, fieldTypeList)
+		}
+		if field.Type == "select" && len(field.Options) == 0 {
+			v.errorf(fieldPath+".options", "a select field needs options")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultVariantName is the name of processed image variants: photo.1920x1080.jpg
+const DefaultVariantName = "{name}.{width}x{height}{ext}"
+
+// variantPlaceholders are what a variant name is made of, with the pattern each one matches
+var variantPlaceholders = map[string]string{
+	"{name}":   `(?P<base>.+?)`,
+	"{width}":  `(?P<w>\d+)`,
+	"{height}": `(?P<h>\d+)`,
+	"{hash}":   `[0-9a-f]{8}`,
+	"{ext}":    `(?P<ext>\.[^.]+)`,
+}
+
+// placeholderRe matches the placeholders of a variant name, known or not
+var placeholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// VariantNamePattern returns the regexp that recognizes the variants a name template writes, so
+// the files written and the files found follow the same template. The template needs {name},
+// {width} and, at its end, {ext}; {height} and {hash}, the first 8 hex digits of the SHA-256 of
+// the variant, are optional.
+func VariantNamePattern(name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("'%s' can't contain a path separator", name)
+	}
+	if !strings.HasSuffix(name, "{ext}") {
+		return "", fmt.Errorf("'%s' must end with {ext}", name)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	seen := map[string]bool{}
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(name, -1) {
+		placeholder := name[loc[0]:loc[1]]
+		group, ok := variantPlaceholders[placeholder]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder %s (use {name}, {width}, {height}, {hash} or {ext})", placeholder)
+		}
+		if seen[placeholder] {
+			return "", fmt.Errorf("%s is used twice", placeholder)
+		}
+		seen[placeholder] = true
+		pattern.WriteString(regexp.QuoteMeta(name[last:loc[0]]) + group)
+		last = loc[1]
+	}
+	if !seen["{name}"] || !seen["{width}"] {
+		return "", fmt.Errorf("'%s' needs {name} and {width}", name)
+	}
+	pattern.WriteString("$")
+	return pattern.String(), nil
+}
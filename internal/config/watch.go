@@ -0,0 +1,82 @@
+package config
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets the burst of events an editor's save makes settle before the file is read
+const reloadDelay = 300 * time.Millisecond
+
+// Watcher reloads hugo-manager.yaml when it changes
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	mu      sync.Mutex
+	timer   *time.Timer
+}
+
+// Watch calls onChange with the configuration every time hugo-manager.yaml changes. The project
+// directory is watched rather than the file, so saves that replace the file are seen too. A file
+// that fails to load is logged and ignored, keeping the configuration in use.
+func Watch(projectDir string, onChange func(*Config)) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(projectDir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &Watcher{watcher: watcher}
+	reload := func() {
+		cfg, err := Load(projectDir)
+		if err != nil {
+			slog.Warn("Ignoring changes to the configuration file", "file", ConfigFileName, "error", err)
+			return
+		}
+		onChange(cfg)
+	}
+	go w.run(reload)
+	return w, nil
+}
+
+// Close stops watching the file
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	return w.watcher.Close()
+}
+
+// run schedules a reload for the events on the configuration file
+func (w *Watcher) run(reload func()) {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != ConfigFileName || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+				continue
+			}
+			w.mu.Lock()
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			w.timer = time.AfterFunc(reloadDelay, reload)
+			w.mu.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("Configuration file watcher failed", "error", err)
+		}
+	}
+}
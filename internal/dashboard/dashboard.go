@@ -0,0 +1,239 @@
+package dashboard
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/domain"
+	"github.com/fernandezvara/hugo-manager/internal/health"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Widget types
+const (
+	WidgetRecentContent = "recent_content" // Content pages edited last
+	WidgetBuildStatus   = "build_status"   // Hugo server status with the errors and warnings of its build
+	WidgetDeployHistory = "deploy_history" // Past deploys
+	WidgetAnalytics     = "analytics"      // Traffic of the live site
+	WidgetHealthScore   = "health_score"   // Last recorded project health score
+	WidgetDomain        = "domain"         // DNS and TLS certificate of the production domain
+)
+
+// defaultTitles are the headings of widgets configured without a title
+var defaultTitles = map[string]string{
+	WidgetRecentContent: "Recent content",
+	WidgetBuildStatus:   "Build status",
+	WidgetDeployHistory: "Deploy history",
+	WidgetAnalytics:     "Analytics",
+	WidgetHealthScore:   "Health score",
+	WidgetDomain:        "Production domain",
+}
+
+// defaultLimit is the number of items list widgets show without a configured limit
+const defaultLimit = 10
+
+// maxBuildEvents is the number of build errors and warnings the build status lists
+const maxBuildEvents = 5
+
+// Widget is a configured widget with its data
+type Widget struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Available bool        `json:"available"`
+	Message   string      `json:"message,omitempty"` // Why the widget has no data
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// Dashboard is the landing view of the UI
+type Dashboard struct {
+	Widgets []Widget `json:"widgets"`
+}
+
+// RecentPage is a content page of the recent content widget
+type RecentPage struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	Draft    bool      `json:"draft"`
+	Modified time.Time `json:"modified"`
+}
+
+// BuildStatus is the data of the build status widget
+type BuildStatus struct {
+	Status   hugo.Status     `json:"status"`
+	Message  string          `json:"message"`
+	Port     int             `json:"port"`
+	Options  hugo.Options    `json:"options"`
+	Errors   int             `json:"errors"`
+	Warnings int             `json:"warnings"`
+	Events   []hugo.LogEvent `json:"events"` // First errors, then warnings
+}
+
+// HealthScore is the data of the health score widget
+type HealthScore struct {
+	health.Point
+	Trend health.Trend `json:"trend"`
+}
+
+// Sources are the managers the widgets take their data from
+type Sources struct {
+	Hugo   *hugo.Manager
+	Health *health.Manager
+	Domain *domain.Checker
+}
+
+// Manager builds the dashboard from the configured widgets
+type Manager struct {
+	projectDir string
+	sources    Sources
+}
+
+// NewManager creates a new dashboard manager
+func NewManager(projectDir string, sources Sources) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		sources:    sources,
+	}
+}
+
+// Build returns the configured widgets in order, with their data. Widgets that have no data
+// are still returned, marked unavailable with the reason.
+func (m *Manager) Build(cfg config.DashboardConfig) *Dashboard {
+	dashboard := &Dashboard{Widgets: make([]Widget, 0, len(cfg.Widgets))}
+	for _, w := range cfg.Widgets {
+		widget := Widget{Type: w.Type, Title: w.Title}
+		if widget.Title == "" {
+			widget.Title = defaultTitles[w.Type]
+		}
+		limit := w.Limit
+		if limit <= 0 {
+			limit = defaultLimit
+		}
+
+		var data interface{}
+		var message string
+		switch w.Type {
+		case WidgetRecentContent:
+			data, message = m.recentContent(limit)
+		case WidgetBuildStatus:
+			data, message = m.buildStatus()
+		case WidgetHealthScore:
+			data, message = m.healthScore()
+		case WidgetDomain:
+			data, message = m.domainReport()
+		case WidgetDeployHistory:
+			message = "No deploy integration is configured"
+		case WidgetAnalytics:
+			message = "No analytics provider is configured"
+		default:
+			message = "Unknown widget type"
+		}
+		widget.Data, widget.Message = data, message
+		widget.Available = data != nil
+		dashboard.Widgets = append(dashboard.Widgets, widget)
+	}
+	return dashboard
+}
+
+// recentContent lists the content pages modified last
+func (m *Manager) recentContent(limit int) (interface{}, string) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, "Failed to load site config: " + err.Error()
+	}
+
+	pages := []RecentPage{}
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		root := filepath.Join(m.projectDir, filepath.FromSlash(lang.ContentDir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil // Missing content directories have no pages
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(m.projectDir, path)
+			pages = append(pages, RecentPage{Path: filepath.ToSlash(rel), Modified: info.ModTime()})
+			return nil
+		})
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Modified.After(pages[j].Modified) })
+	if len(pages) > limit {
+		pages = pages[:limit]
+	}
+	// Only the listed pages are parsed for their title
+	for i := range pages {
+		data, err := os.ReadFile(filepath.Join(m.projectDir, filepath.FromSlash(pages[i].Path)))
+		if err != nil {
+			continue
+		}
+		if fm, _, _, err := hugocontent.Parse(data); err == nil {
+			pages[i].Title = fm.String("title")
+			pages[i].Draft = fm.Bool("draft")
+		}
+	}
+	return pages, ""
+}
+
+// buildStatus reports the Hugo server and its current build
+func (m *Manager) buildStatus() (interface{}, string) {
+	if m.sources.Hugo == nil {
+		return nil, "Hugo isn't managed"
+	}
+	status, message := m.sources.Hugo.GetStatus()
+	buildErrors, warnings := m.sources.Hugo.BuildErrors(), m.sources.Hugo.BuildWarnings()
+
+	events := append(buildErrors, warnings...)
+	if len(events) > maxBuildEvents {
+		events = events[:maxBuildEvents]
+	}
+	return &BuildStatus{
+		Status:   status,
+		Message:  message,
+		Port:     m.sources.Hugo.GetPort(),
+		Options:  m.sources.Hugo.GetOptions(),
+		Errors:   len(buildErrors),
+		Warnings: len(warnings),
+		Events:   events,
+	}, ""
+}
+
+// healthScore returns the last recorded health score, without running the checks
+func (m *Manager) healthScore() (interface{}, string) {
+	if m.sources.Health == nil {
+		return nil, "The health score isn't available"
+	}
+	point, trend, err := m.sources.Health.Latest()
+	if err != nil {
+		return nil, err.Error()
+	}
+	if point == nil {
+		return nil, "No score recorded yet; request /api/v1/health/score to compute one"
+	}
+	return &HealthScore{Point: *point, Trend: trend}, ""
+}
+
+// domainReport returns the last report of the production domain checks, without running them
+func (m *Manager) domainReport() (interface{}, string) {
+	if m.sources.Domain == nil || m.sources.Domain.Domain() == "" {
+		return nil, "No production domain configured"
+	}
+	report := m.sources.Domain.Latest()
+	if report == nil {
+		return nil, "No check has run yet; POST /api/v1/domain/check to run one"
+	}
+	return report, ""
+}
@@ -0,0 +1,425 @@
+package docs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Source kinds accepted by Ingest
+const (
+	KindOpenAPI    = "openapi"
+	KindJSONSchema = "jsonschema"
+)
+
+// generatedKey marks front matter of generated pages so re-ingesting only replaces its own output
+const generatedKey = "generated_from"
+
+// ErrNotGenerated is returned when an ingest would overwrite a page that wasn't generated from the same source
+var ErrNotGenerated = errors.New("refusing to overwrite a page that was not generated")
+
+// IngestResult lists the reference pages written by Ingest
+type IngestResult struct {
+	Kind  string   `json:"kind"`
+	Pages []string `json:"pages"`
+}
+
+// Ingest converts an OpenAPI document or JSON schema into markdown reference pages in outputDir.
+// Both paths are project-relative; kind may be empty to detect it from the document.
+func (m *Manager) Ingest(sourcePath, outputDir, kind string) (*IngestResult, error) {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, sourcePath))
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, so one decoder handles both formats
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", sourcePath, err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%s is empty", sourcePath)
+	}
+
+	if kind == "" {
+		kind = KindJSONSchema
+		if _, ok := doc["openapi"]; ok {
+			kind = KindOpenAPI
+		} else if _, ok := doc["swagger"]; ok {
+			kind = KindOpenAPI
+		}
+	}
+
+	var pages map[string]string
+	switch kind {
+	case KindOpenAPI:
+		pages = renderOpenAPI(doc, sourcePath)
+	case KindJSONSchema:
+		pages = renderJSONSchema(doc, sourcePath)
+	default:
+		return nil, fmt.Errorf("unknown source kind: %s", kind)
+	}
+
+	// Check every target first so a conflict doesn't leave a partial set of pages behind
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := m.checkGenerated(filepath.Join(outputDir, name), sourcePath); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &IngestResult{Kind: kind, Pages: []string{}}
+	for _, name := range names {
+		rel := filepath.ToSlash(filepath.Join(outputDir, name))
+		path := filepath.Join(m.projectDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := atomicfile.WriteFile(path, []byte(pages[name]), 0644); err != nil {
+			return nil, err
+		}
+		result.Pages = append(result.Pages, rel)
+	}
+	return result, nil
+}
+
+// checkGenerated fails if rel exists and wasn't generated from source
+func (m *Manager) checkGenerated(rel, source string) error {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, rel))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fm, _, _, err := hugocontent.Parse(data)
+	if err != nil || fm.String(generatedKey) != source {
+		return fmt.Errorf("%w: %s", ErrNotGenerated, filepath.ToSlash(rel))
+	}
+	return nil
+}
+
+// page renders a markdown page with YAML front matter
+func page(title, description, source string, weight int, body string) string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.WriteString(fmt.Sprintf("title: %q\n", title))
+	if description != "" {
+		sb.WriteString(fmt.Sprintf("description: %q\n", firstLine(description)))
+	}
+	if weight > 0 {
+		sb.WriteString(fmt.Sprintf("weight: %d\n", weight))
+	}
+	sb.WriteString(fmt.Sprintf("%s: %q\n", generatedKey, source))
+	sb.WriteString("---\n\n")
+	sb.WriteString("<!-- Generated by hugo-manager from " + source + ". Edits are overwritten on the next ingest. -->\n\n")
+	sb.WriteString(strings.TrimRight(body, "\n"))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// renderOpenAPI renders an index page, one page per tag and a schemas page
+func renderOpenAPI(doc map[string]interface{}, source string) map[string]string {
+	info := mapOf(doc["info"])
+	title := str(info["title"])
+	if title == "" {
+		title = "API reference"
+	}
+
+	type operation struct {
+		method, path string
+		op           map[string]interface{}
+	}
+	byTag := map[string][]operation{}
+	var tags []string
+
+	paths := mapOf(doc["paths"])
+	for _, path := range sortedKeys(paths) {
+		item := mapOf(paths[path])
+		for _, method := range []string{"get", "put", "post", "delete", "patch", "head", "options"} {
+			op := mapOf(item[method])
+			if op == nil {
+				continue
+			}
+			tag := "default"
+			if t := list(op["tags"]); len(t) > 0 && str(t[0]) != "" {
+				tag = str(t[0])
+			}
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], operation{method: method, path: path, op: op})
+		}
+	}
+
+	// Schemas are rendered on their own page, a sibling of the tag pages
+	const schemasRef = "../schemas/"
+
+	pages := map[string]string{}
+	var index strings.Builder
+	if v := str(info["version"]); v != "" {
+		index.WriteString(fmt.Sprintf("Version **%s**\n\n", v))
+	}
+	if d := str(info["description"]); d != "" {
+		index.WriteString(d + "\n\n")
+	}
+	index.WriteString("| Section | Operations |\n| --- | --- |\n")
+
+	for i, tag := range tags {
+		file := refSlug(tag)
+		var body strings.Builder
+		for _, o := range byTag[tag] {
+			renderOperation(&body, o.method, o.path, o.op, schemasRef)
+		}
+		pages[file+".md"] = page(tag, tagDescription(doc, tag), source, i+1, body.String())
+		index.WriteString(fmt.Sprintf("| [%s](%s/) | %d |\n", tag, file, len(byTag[tag])))
+	}
+
+	schemas := mapOf(mapOf(doc["components"])["schemas"])
+	if schemas == nil {
+		schemas = mapOf(doc["definitions"]) // Swagger 2
+	}
+	if len(schemas) > 0 {
+		var body strings.Builder
+		for _, name := range sortedKeys(schemas) {
+			body.WriteString(fmt.Sprintf("## %s\n\n", name))
+			renderSchemaBody(&body, mapOf(schemas[name]), schemasRef)
+		}
+		pages["schemas.md"] = page("Schemas", "Data types used by "+title, source, len(tags)+1, body.String())
+		index.WriteString(fmt.Sprintf("| [Schemas](schemas/) | %d types |\n", len(schemas)))
+	}
+
+	pages["_index.md"] = page(title, str(info["description"]), source, 0, index.String())
+	return pages
+}
+
+func tagDescription(doc map[string]interface{}, tag string) string {
+	for _, t := range list(doc["tags"]) {
+		if m := mapOf(t); str(m["name"]) == tag {
+			return str(m["description"])
+		}
+	}
+	return ""
+}
+
+func renderOperation(sb *strings.Builder, method, path string, op map[string]interface{}, refBase string) {
+	sb.WriteString(fmt.Sprintf("## %s `%s`\n\n", strings.ToUpper(method), path))
+	if s := str(op["summary"]); s != "" {
+		sb.WriteString(s + "\n\n")
+	}
+	if d := str(op["description"]); d != "" {
+		sb.WriteString(d + "\n\n")
+	}
+	if op["deprecated"] == true {
+		sb.WriteString("> **Deprecated**\n\n")
+	}
+
+	if params := list(op["parameters"]); len(params) > 0 {
+		sb.WriteString("**Parameters**\n\n| Name | In | Type | Required | Description |\n| --- | --- | --- | --- | --- |\n")
+		for _, p := range params {
+			pm := mapOf(p)
+			schema := mapOf(pm["schema"])
+			if schema == nil {
+				schema = pm // Swagger 2 puts the type on the parameter
+			}
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s | %s |\n",
+				str(pm["name"]), str(pm["in"]), typeOf(schema, refBase), yesNo(pm["required"] == true), cell(str(pm["description"]))))
+		}
+		sb.WriteString("\n")
+	}
+
+	if body := mapOf(op["requestBody"]); body != nil {
+		sb.WriteString("**Request body**\n\n")
+		for _, ct := range sortedKeys(mapOf(body["content"])) {
+			schema := mapOf(mapOf(mapOf(body["content"])[ct])["schema"])
+			sb.WriteString(fmt.Sprintf("- `%s`: %s\n", ct, typeOf(schema, refBase)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if responses := mapOf(op["responses"]); len(responses) > 0 {
+		sb.WriteString("**Responses**\n\n| Status | Description | Type |\n| --- | --- | --- |\n")
+		for _, status := range sortedKeys(responses) {
+			resp := mapOf(responses[status])
+			typ := ""
+			for _, ct := range sortedKeys(mapOf(resp["content"])) {
+				typ = typeOf(mapOf(mapOf(mapOf(resp["content"])[ct])["schema"]), refBase)
+				break
+			}
+			if typ == "" && resp["schema"] != nil {
+				typ = typeOf(mapOf(resp["schema"]), refBase)
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", status, cell(str(resp["description"])), typ))
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// renderJSONSchema renders a single page with the root schema and its definitions
+func renderJSONSchema(doc map[string]interface{}, source string) map[string]string {
+	title := str(doc["title"])
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+
+	var body strings.Builder
+	renderSchemaBody(&body, doc, "")
+
+	defs := mapOf(doc["$defs"])
+	if defs == nil {
+		defs = mapOf(doc["definitions"])
+	}
+	for _, name := range sortedKeys(defs) {
+		body.WriteString(fmt.Sprintf("## %s\n\n", name))
+		renderSchemaBody(&body, mapOf(defs[name]), "")
+	}
+
+	return map[string]string{refSlug(title) + ".md": page(title, str(doc["description"]), source, 0, body.String())}
+}
+
+// renderSchemaBody renders a schema's description and its properties table
+func renderSchemaBody(sb *strings.Builder, schema map[string]interface{}, refBase string) {
+	if d := str(schema["description"]); d != "" {
+		sb.WriteString(d + "\n\n")
+	}
+
+	props := mapOf(schema["properties"])
+	if len(props) == 0 {
+		sb.WriteString(fmt.Sprintf("Type: %s\n\n", typeOf(schema, refBase)))
+		return
+	}
+
+	required := map[string]bool{}
+	for _, r := range list(schema["required"]) {
+		required[str(r)] = true
+	}
+
+	sb.WriteString("| Property | Type | Required | Description |\n| --- | --- | --- | --- |\n")
+	for _, name := range sortedKeys(props) {
+		prop := mapOf(props[name])
+		desc := str(prop["description"])
+		if enum := list(prop["enum"]); len(enum) > 0 {
+			values := make([]string, len(enum))
+			for i, v := range enum {
+				values[i] = "`" + fmt.Sprint(v) + "`"
+			}
+			desc = strings.TrimSpace(desc + " One of " + strings.Join(values, ", ") + ".")
+		}
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s |\n", name, typeOf(prop, refBase), yesNo(required[name]), cell(desc)))
+	}
+	sb.WriteString("\n")
+}
+
+// typeOf describes a schema's type, following $ref, arrays and compositions. References link to the
+// schema's heading on the page at refBase.
+func typeOf(schema map[string]interface{}, refBase string) string {
+	if schema == nil {
+		return ""
+	}
+	if ref := str(schema["$ref"]); ref != "" {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		return fmt.Sprintf("[%s](%s#%s)", name, refBase, refSlug(name))
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if options := list(schema[key]); len(options) > 0 {
+			parts := make([]string, len(options))
+			for i, o := range options {
+				parts[i] = typeOf(mapOf(o), refBase)
+			}
+			sep := " or "
+			if key == "allOf" {
+				sep = " and "
+			}
+			return strings.Join(parts, sep)
+		}
+	}
+
+	typ := str(schema["type"])
+	if types := list(schema["type"]); len(types) > 0 {
+		parts := make([]string, len(types))
+		for i, t := range types {
+			parts[i] = str(t)
+		}
+		typ = strings.Join(parts, " or ")
+	}
+	switch typ {
+	case "array":
+		return "array of " + typeOf(mapOf(schema["items"]), refBase)
+	case "":
+		if schema["properties"] != nil {
+			return "object"
+		}
+		return "any"
+	}
+	if format := str(schema["format"]); format != "" {
+		return typ + " (" + format + ")"
+	}
+	return typ
+}
+
+// Helpers for walking decoded YAML/JSON
+
+func mapOf(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// cell makes text safe for a single markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return strings.TrimSpace(s)
+}
+
+// refSlug converts a name into a file name and anchor, matching Hugo's heading IDs for simple names
+func refSlug(name string) string {
+	if s := slug.Make(name); s != "" {
+		return s
+	}
+	return "reference"
+}
@@ -0,0 +1,264 @@
+package docs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Sentinel errors returned by Manager operations. Use errors.Is to check for them.
+var (
+	ErrInvalidVersion  = errors.New("invalid version name")
+	ErrVersionExists   = errors.New("version already exists")
+	ErrVersionNotFound = errors.New("version does not exist")
+)
+
+// versionNameRe restricts version names to a single safe path segment such as v1, 2.0 or next
+var versionNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Version is an entry of the version switcher
+type Version struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+}
+
+// VersionList is the data file read by the theme to render the version switcher
+type VersionList struct {
+	Latest   string    `yaml:"latest" json:"latest"`
+	Versions []Version `yaml:"versions" json:"versions"`
+}
+
+// CreateResult summarizes a new docs version
+type CreateResult struct {
+	Version        Version      `json:"version"`
+	Files          int          `json:"files"`
+	RewrittenFiles int          `json:"rewrittenFiles"`
+	Versions       *VersionList `json:"versions"`
+}
+
+// Manager manages versioned documentation and generated reference pages
+type Manager struct {
+	projectDir string
+	config     config.DocsConfig
+}
+
+// NewManager creates a new docs manager
+func NewManager(projectDir string, cfg config.DocsConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// contentDir returns the project-relative directory holding one subdirectory per version
+func (m *Manager) contentDir() string {
+	if m.config.ContentDir == "" {
+		return "content/docs"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.ContentDir))
+}
+
+// versionsFile returns the project-relative path of the version list data file
+func (m *Manager) versionsFile() string {
+	if m.config.VersionsFile == "" {
+		return "data/versions.yaml"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.VersionsFile))
+}
+
+// section returns the URL section of the docs content dir, e.g. "docs" for content/docs
+func (m *Manager) section() string {
+	return strings.Trim(strings.TrimPrefix(m.contentDir(), "content"), "/")
+}
+
+// versionURL returns the URL a version is published at
+func (m *Manager) versionURL(name string) string {
+	if section := m.section(); section != "" {
+		return "/" + section + "/" + name + "/"
+	}
+	return "/" + name + "/"
+}
+
+// Versions returns the version list. Without a data file, versions are detected from the content directory.
+func (m *Manager) Versions() (*VersionList, error) {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, m.versionsFile()))
+	if err == nil {
+		list := &VersionList{}
+		if err := yaml.Unmarshal(data, list); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", m.versionsFile(), err)
+		}
+		if list.Versions == nil {
+			list.Versions = []Version{}
+		}
+		return list, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	list := &VersionList{Versions: []Version{}}
+	entries, err := os.ReadDir(filepath.Join(m.projectDir, m.contentDir()))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && versionNameRe.MatchString(entry.Name()) {
+			list.Versions = append(list.Versions, Version{Name: entry.Name(), URL: m.versionURL(entry.Name())})
+		}
+	}
+	// Newest first, comparing numeric parts so v10 sorts after v9
+	sort.Slice(list.Versions, func(i, j int) bool {
+		return versionLess(list.Versions[j].Name, list.Versions[i].Name)
+	})
+	if len(list.Versions) > 0 {
+		list.Latest = list.Versions[0].Name
+	}
+	return list, nil
+}
+
+// SaveVersions writes the version list data file
+func (m *Manager) SaveVersions(list *VersionList) error {
+	for _, v := range list.Versions {
+		if !versionNameRe.MatchString(v.Name) {
+			return fmt.Errorf("%w: %q", ErrInvalidVersion, v.Name)
+		}
+	}
+	if list.Latest != "" && findVersion(list, list.Latest) < 0 {
+		return fmt.Errorf("latest %w: %s", ErrVersionNotFound, list.Latest)
+	}
+
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.projectDir, m.versionsFile())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0644)
+}
+
+// CreateVersion copies the docs of version from into a new version to, rewriting links that point into from,
+// and adds it to the version list
+func (m *Manager) CreateVersion(from, to string, setLatest bool) (*CreateResult, error) {
+	if !versionNameRe.MatchString(from) || !versionNameRe.MatchString(to) {
+		return nil, ErrInvalidVersion
+	}
+
+	srcDir := filepath.Join(m.projectDir, m.contentDir(), from)
+	dstDir := filepath.Join(m.projectDir, m.contentDir(), to)
+	if stat, err := os.Stat(srcDir); err != nil || !stat.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrVersionNotFound, from)
+	}
+	if _, err := os.Stat(dstDir); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVersionExists, to)
+	}
+
+	list, err := m.Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Links may be absolute URLs (/docs/v1/...) or ref paths (docs/v1/...); both contain this prefix
+	oldPrefix := strings.TrimPrefix(m.versionURL(from), "/")
+	newPrefix := strings.TrimPrefix(m.versionURL(to), "/")
+
+	result := &CreateResult{Version: Version{Name: to, URL: m.versionURL(to)}}
+	err = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dstDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isTextContent(path) && strings.Contains(string(data), oldPrefix) {
+			data = []byte(strings.ReplaceAll(string(data), oldPrefix, newPrefix))
+			result.RewrittenFiles++
+		}
+		if err := atomicfile.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		result.Files++
+		return nil
+	})
+	if err != nil {
+		os.RemoveAll(dstDir)
+		return nil, fmt.Errorf("failed to copy version %s: %w", from, err)
+	}
+
+	if i := findVersion(list, to); i >= 0 {
+		list.Versions = append(list.Versions[:i], list.Versions[i+1:]...)
+	}
+	list.Versions = append([]Version{result.Version}, list.Versions...)
+	if setLatest || list.Latest == "" {
+		list.Latest = to
+	}
+	if err := m.SaveVersions(list); err != nil {
+		return nil, err
+	}
+	result.Versions = list
+	return result, nil
+}
+
+// findVersion returns the index of the named version, or -1
+func findVersion(list *VersionList, name string) int {
+	for i, v := range list.Versions {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// isTextContent reports whether links in a file should be rewritten
+func isTextContent(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".html", ".htm", ".yaml", ".yml", ".toml", ".json":
+		return true
+	}
+	return false
+}
+
+// numberRe splits version names into numeric and non-numeric runs
+var numberRe = regexp.MustCompile(`\d+|\D+`)
+
+// versionLess compares version names, treating runs of digits as numbers
+func versionLess(a, b string) bool {
+	pa, pb := numberRe.FindAllString(a, -1), numberRe.FindAllString(b, -1)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		na, errA := strconv.ParseUint(pa[i], 10, 64)
+		nb, errB := strconv.ParseUint(pb[i], 10, 64)
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		return pa[i] < pb[i]
+	}
+	return len(pa) < len(pb)
+}
@@ -0,0 +1,337 @@
+package domain
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Level represents the severity of a domain check result
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "warning"
+	LevelCritical Level = "critical"
+	LevelError    Level = "error"
+	LevelUnknown  Level = "unknown"
+)
+
+// severity orders the levels, from the least to the most severe
+func (l Level) severity() int {
+	switch l {
+	case LevelWarning:
+		return 1
+	case LevelCritical:
+		return 2
+	case LevelError:
+		return 3
+	}
+	return 0
+}
+
+// DNSResult contains the DNS records found for the domain
+type DNSResult struct {
+	Addresses []string `json:"addresses"`
+	CNAME     string   `json:"cname,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// CertificateResult contains details about the served TLS certificate
+type CertificateResult struct {
+	Subject       string    `json:"subject,omitempty"`
+	Issuer        string    `json:"issuer,omitempty"`
+	DNSNames      []string  `json:"dnsNames,omitempty"`
+	NotBefore     time.Time `json:"notBefore"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining int       `json:"daysRemaining"`
+	HostnameError string    `json:"hostnameError,omitempty"` // Why the certificate isn't valid for the domain
+	Error         string    `json:"error,omitempty"`
+}
+
+// Report is the result of a domain check
+type Report struct {
+	Domain      string            `json:"domain"`
+	Level       Level             `json:"level"`
+	Message     string            `json:"message"`
+	CheckedAt   time.Time         `json:"checkedAt"`
+	DNS         DNSResult         `json:"dns"`
+	Certificate CertificateResult `json:"certificate"`
+}
+
+// Checker periodically checks DNS records and TLS certificate expiry
+type Checker struct {
+	projectDir string
+	config     config.DomainConfig
+	report     *Report
+	onChange   func(previous Level, report *Report)
+	mu         sync.RWMutex
+	stop       chan struct{}
+}
+
+// NewChecker creates a new domain checker
+func NewChecker(projectDir string, cfg config.DomainConfig) *Checker {
+	return &Checker{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// Domain returns the configured domain, falling back to the host of Hugo's baseURL
+func (c *Checker) Domain() string {
+	if c.config.Name != "" {
+		return strings.TrimSpace(c.config.Name)
+	}
+	return detectBaseURLHost(c.projectDir)
+}
+
+// Start runs checks in the background at the configured interval
+func (c *Checker) Start() {
+	if c.config.CheckInterval <= 0 || c.Domain() == "" {
+		return
+	}
+
+	c.mu.Lock()
+	if c.stop != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stop = make(chan struct{})
+	stop := c.stop
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(c.config.CheckInterval) * time.Minute)
+		defer ticker.Stop()
+
+		c.Check()
+		for {
+			select {
+			case <-ticker.C:
+				c.Check()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background checks
+func (c *Checker) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// OnChange registers a callback invoked when a check ends at a different level than the one before.
+// The first check is compared with LevelOK, so a domain with problems is reported on start. It
+// must be called before Start.
+func (c *Checker) OnChange(fn func(previous Level, report *Report)) {
+	c.onChange = fn
+}
+
+// Latest returns the most recent report without running a check, or nil when none has run yet
+func (c *Checker) Latest() *Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.report
+}
+
+// LastReport returns the most recent report, running a check if none exists yet
+func (c *Checker) LastReport() *Report {
+	c.mu.RLock()
+	report := c.report
+	c.mu.RUnlock()
+
+	if report == nil {
+		return c.Check()
+	}
+	return report
+}
+
+// Check runs the DNS and certificate checks immediately
+func (c *Checker) Check() *Report {
+	domain := c.Domain()
+	report := &Report{
+		Domain:    domain,
+		Level:     LevelUnknown,
+		CheckedAt: time.Now(),
+		DNS:       DNSResult{Addresses: []string{}},
+	}
+
+	if domain == "" {
+		report.Message = "No production domain configured"
+		c.setReport(report)
+		return report
+	}
+
+	report.DNS = lookupDNS(domain)
+	report.Certificate = fetchCertificate(domain)
+	report.Level, report.Message = c.evaluate(report)
+
+	if report.Level != LevelOK {
+		slog.Warn("Domain check", "domain", domain, "level", report.Level, "message", report.Message)
+	}
+
+	previous := c.setReport(report)
+	if report.Level != previous && c.onChange != nil {
+		c.onChange(previous, report)
+	}
+	return report
+}
+
+// setReport stores a report and returns the level of the one it replaces
+func (c *Checker) setReport(report *Report) Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := LevelOK
+	if c.report != nil {
+		previous = c.report.Level
+	}
+	c.report = report
+	return previous
+}
+
+// evaluate determines the overall level of a report
+func (c *Checker) evaluate(report *Report) (Level, string) {
+	if report.DNS.Error != "" {
+		return LevelError, "DNS lookup failed: " + report.DNS.Error
+	}
+	if report.Certificate.Error != "" {
+		return LevelError, "TLS check failed: " + report.Certificate.Error
+	}
+
+	level, message := c.expiry(report.Certificate.DaysRemaining)
+	if report.Certificate.HostnameError != "" {
+		// A certificate browsers reject for the domain is critical, and an expiry problem is still reported
+		problem := "Certificate isn't valid for the domain: " + report.Certificate.HostnameError
+		if level == LevelOK {
+			message = problem
+		} else {
+			message += "; " + problem
+		}
+		if LevelCritical.severity() > level.severity() {
+			level = LevelCritical
+		}
+	}
+	return level, message
+}
+
+// expiry determines the level of a certificate from the days it has left
+func (c *Checker) expiry(days int) (Level, string) {
+	switch {
+	case days < 0:
+		return LevelCritical, fmt.Sprintf("Certificate expired %d days ago", -days)
+	case days <= c.config.CriticalDays:
+		return LevelCritical, fmt.Sprintf("Certificate expires in %d days", days)
+	case days <= c.config.WarnDays:
+		return LevelWarning, fmt.Sprintf("Certificate expires in %d days", days)
+	default:
+		return LevelOK, fmt.Sprintf("Certificate valid for %d more days", days)
+	}
+}
+
+func lookupDNS(domain string) DNSResult {
+	result := DNSResult{Addresses: []string{}}
+
+	addrs, err := net.LookupHost(domain)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Addresses = addrs
+
+	if cname, err := net.LookupCNAME(domain); err == nil {
+		cname = strings.TrimSuffix(cname, ".")
+		if cname != domain {
+			result.CNAME = cname
+		}
+	}
+
+	return result
+}
+
+func fetchCertificate(domain string) CertificateResult {
+	var result CertificateResult
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(domain, "443"), &tls.Config{
+		ServerName: domain,
+		// Verification is reported separately so expired certificates can still be inspected
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		result.Error = "no certificate presented"
+		return result
+	}
+
+	cert := certs[0]
+	result.Subject = cert.Subject.CommonName
+	result.Issuer = cert.Issuer.CommonName
+	result.DNSNames = cert.DNSNames
+	result.NotBefore = cert.NotBefore
+	result.NotAfter = cert.NotAfter
+	result.DaysRemaining = int(time.Until(cert.NotAfter).Hours() / 24)
+
+	if err := cert.VerifyHostname(domain); err != nil {
+		result.HostnameError = err.Error()
+	}
+
+	return result
+}
+
+// baseURLRe matches baseURL in TOML, YAML and JSON site configuration files
+var baseURLRe = regexp.MustCompile(`(?im)^\s*"?baseURL"?\s*[=:]\s*["']?([^"'\s,]+)`)
+
+// detectBaseURLHost reads the host from Hugo's baseURL setting
+func detectBaseURLHost(projectDir string) string {
+	configFiles := []string{
+		"hugo.toml",
+		"hugo.yaml",
+		"hugo.json",
+		"config.toml",
+		"config.yaml",
+		"config.json",
+	}
+
+	for _, f := range configFiles {
+		data, err := os.ReadFile(filepath.Join(projectDir, f))
+		if err != nil {
+			continue
+		}
+		m := baseURLRe.FindSubmatch(data)
+		if m == nil {
+			continue
+		}
+		u, err := url.Parse(string(m[1]))
+		if err != nil {
+			continue
+		}
+		host := u.Hostname()
+		if host == "" || host == "localhost" || net.ParseIP(host) != nil || host == "example.org" {
+			return ""
+		}
+		return host
+	}
+	return ""
+}
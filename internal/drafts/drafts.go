@@ -0,0 +1,294 @@
+package drafts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Errors returned by the draft cleanup
+var (
+	ErrInvalidAction   = errors.New("invalid cleanup action")
+	ErrInvalidCriteria = errors.New("invalid cleanup criteria")
+)
+
+// Cleanup actions
+const (
+	ActionArchive = "archive" // Move the draft out of the content tree into the archive directory
+	ActionDelete  = "delete"  // Move the draft to the trash, where storage GC removes it
+)
+
+// Criteria decide which drafts are abandoned
+type Criteria struct {
+	MinAgeMonths int `json:"minAgeMonths"` // Drafts dated at least this long ago
+	IdleMonths   int `json:"idleMonths"`   // And not edited for this long
+}
+
+// Draft is an abandoned draft
+type Draft struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	Date     time.Time `json:"date"`             // Front matter date, or the file's modification time without one
+	LastEdit time.Time `json:"lastEdit"`         // Later of lastmod and the modification time of the page's files
+	Bundle   string    `json:"bundle,omitempty"` // Page bundle directory cleaned up with the page
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// List is the result of looking for abandoned drafts
+type List struct {
+	Criteria
+	Drafts  int     `json:"drafts"` // Drafts found in the content tree
+	Stale   []Draft `json:"stale"`
+	Archive string  `json:"archive"` // Directory archived drafts are moved to
+}
+
+// Move is a draft that was, or would be, cleaned up
+type Move struct {
+	Path string `json:"path"` // Page, or bundle directory
+	To   string `json:"to"`   // Project-relative destination
+}
+
+// Skip is a requested path that was left in place
+type Skip struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Result is the outcome of a cleanup
+type Result struct {
+	Action  string `json:"action"`
+	DryRun  bool   `json:"dryRun"` // Nothing was moved, as the cleanup wasn't confirmed
+	Moved   []Move `json:"moved"`
+	Skipped []Skip `json:"skipped"`
+}
+
+// Manager finds abandoned drafts and archives or deletes them
+type Manager struct {
+	projectDir string
+	config     config.DraftsConfig
+	trashDir   string
+}
+
+// NewManager creates a new draft cleanup manager. Deleted drafts go to trashDir.
+func NewManager(projectDir string, cfg config.DraftsConfig, trashDir string) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		trashDir:   trashDir,
+	}
+}
+
+// Criteria returns the configured criteria, overridden by the non-zero fields of c
+func (m *Manager) Criteria(c Criteria) (Criteria, error) {
+	if c.MinAgeMonths < 0 || c.IdleMonths < 0 {
+		return c, fmt.Errorf("%w: months can't be negative", ErrInvalidCriteria)
+	}
+	if c.MinAgeMonths == 0 {
+		c.MinAgeMonths = m.config.MinAgeMonths
+	}
+	if c.IdleMonths == 0 {
+		c.IdleMonths = m.config.IdleMonths
+	}
+	return c, nil
+}
+
+// Stale lists the drafts matching the criteria, least recently edited first
+func (m *Manager) Stale(c Criteria) (*List, error) {
+	c, err := m.Criteria(c)
+	if err != nil {
+		return nil, err
+	}
+	drafts, err := m.drafts()
+	if err != nil {
+		return nil, err
+	}
+
+	list := &List{Criteria: c, Drafts: len(drafts), Stale: []Draft{}, Archive: m.archiveDir()}
+	now := time.Now()
+	for _, d := range drafts {
+		if stale(d, c, now) {
+			list.Stale = append(list.Stale, d)
+		}
+	}
+	sort.Slice(list.Stale, func(i, j int) bool { return list.Stale[i].LastEdit.Before(list.Stale[j].LastEdit) })
+	return list, nil
+}
+
+// Cleanup archives or deletes the given drafts. Paths that are no longer abandoned drafts are skipped,
+// so a list fetched earlier can't remove a page that was since published or edited. Without confirm
+// it only reports what would be moved.
+func (m *Manager) Cleanup(action string, paths []string, c Criteria, confirm bool) (*Result, error) {
+	if action != ActionArchive && action != ActionDelete {
+		return nil, fmt.Errorf("%w: %q (use %s or %s)", ErrInvalidAction, action, ActionArchive, ActionDelete)
+	}
+	list, err := m.Stale(c)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]Draft, len(list.Stale))
+	for _, d := range list.Stale {
+		candidates[d.Path] = d
+		if d.Bundle != "" {
+			candidates[d.Bundle] = d
+		}
+	}
+
+	result := &Result{Action: action, DryRun: !confirm, Moved: []Move{}, Skipped: []Skip{}}
+	dest := m.archiveDir()
+	if action == ActionDelete {
+		rel, _ := filepath.Rel(m.projectDir, m.trashDir)
+		dest = path.Join(filepath.ToSlash(rel), "drafts-"+time.Now().Format("20060102-150405"))
+	}
+
+	seen := map[string]bool{}
+	for _, p := range paths {
+		p = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+		if seen[p] {
+			continue
+		}
+		d, ok := candidates[p]
+		if !ok {
+			seen[p] = true
+			result.Skipped = append(result.Skipped, Skip{Path: p, Reason: "not an abandoned draft"})
+			continue
+		}
+		src := d.Path
+		if d.Bundle != "" {
+			src = d.Bundle
+		}
+		if seen[src] {
+			continue
+		}
+		seen[p], seen[src] = true, true
+
+		to := path.Join(dest, src)
+		if _, err := os.Stat(m.abs(to)); err == nil {
+			result.Skipped = append(result.Skipped, Skip{Path: p, Reason: "already exists at " + to})
+			continue
+		}
+		if confirm {
+			if err := m.move(src, to, action == ActionDelete); err != nil {
+				result.Skipped = append(result.Skipped, Skip{Path: p, Reason: err.Error()})
+				continue
+			}
+		}
+		result.Moved = append(result.Moved, Move{Path: src, To: to})
+	}
+	return result, nil
+}
+
+// drafts returns every draft page in the site's content directories
+func (m *Manager) drafts() ([]Draft, error) {
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	pages, err := contentSite.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []Draft
+	for _, page := range pages {
+		if !page.Draft() {
+			continue
+		}
+		draft, err := m.draft(m.abs(page.Path), page.FrontMatter)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, *draft)
+	}
+	return drafts, nil
+}
+
+// draft describes a draft page. A leaf bundle (index.md) is cleaned up with its whole directory,
+// while a section's _index.md is cleaned up alone.
+func (m *Manager) draft(file string, fm hugocontent.FrontMatter) (*Draft, error) {
+	rel, _ := filepath.Rel(m.projectDir, file)
+	d := &Draft{Path: filepath.ToSlash(rel), Title: fm.String("title")}
+
+	root := file
+	if filepath.Base(file) == "index.md" {
+		root = filepath.Dir(file)
+		d.Bundle = path.Dir(d.Path)
+	}
+	err := filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		d.Files++
+		d.Bytes += info.Size()
+		if info.ModTime().After(d.LastEdit) {
+			d.LastEdit = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if lastmod, ok := fm.Time("lastmod"); ok && lastmod.After(d.LastEdit) {
+		d.LastEdit = lastmod
+	}
+	d.Date = d.LastEdit
+	if date, ok := fm.Time("date"); ok {
+		d.Date = date
+	}
+	return d, nil
+}
+
+// stale reports whether a draft matches the criteria
+func stale(d Draft, c Criteria, now time.Time) bool {
+	return !d.Date.After(now.AddDate(0, -c.MinAgeMonths, 0)) && !d.LastEdit.After(now.AddDate(0, -c.IdleMonths, 0))
+}
+
+// move moves a page or bundle to a project-relative destination. Trashed files get the current
+// modification time, so the trash's age limit counts from the deletion.
+func (m *Manager) move(src, to string, touch bool) error {
+	target := m.abs(to)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(m.abs(src), target); err != nil {
+		return err
+	}
+	if touch {
+		now := time.Now()
+		filepath.WalkDir(target, func(p string, e fs.DirEntry, err error) error {
+			if err == nil && !e.IsDir() {
+				os.Chtimes(p, now, now)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// archiveDir returns the project-relative archive directory
+func (m *Manager) archiveDir() string {
+	dir := strings.Trim(path.Clean("/"+filepath.ToSlash(m.config.ArchiveDir)), "/")
+	if dir == "" {
+		return "archive"
+	}
+	return dir
+}
+
+// abs returns the absolute path of a project-relative path
+func (m *Manager) abs(rel string) string {
+	return filepath.Join(m.projectDir, filepath.FromSlash(rel))
+}
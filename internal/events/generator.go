@@ -0,0 +1,389 @@
+package events
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Schedule frequencies
+const (
+	FrequencyWeekly   = "weekly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyMonthly  = "monthly"
+)
+
+// defaultAhead is the number of future occurrences kept when a series doesn't set one
+const defaultAhead = 4
+
+// Event is an upcoming dated page of a series
+type Event struct {
+	Series      string    `json:"series"`
+	Title       string    `json:"title"`
+	Path        string    `json:"path"`
+	URL         string    `json:"url,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// GenerateResult lists the pages created by a generation run
+type GenerateResult struct {
+	Created []string `json:"created"`
+	Skipped int      `json:"skipped"` // Occurrences whose page already exists
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// Generator creates future-dated pages for recurring event series
+type Generator struct {
+	projectDir string
+	config     config.EventsConfig
+	mu         sync.Mutex
+	stop       chan struct{}
+}
+
+// NewGenerator creates a new event generator
+func NewGenerator(projectDir string, cfg config.EventsConfig) *Generator {
+	return &Generator{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// Start generates pages in the background at the configured interval
+func (g *Generator) Start() {
+	if g.config.Interval <= 0 || len(g.config.Series) == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	if g.stop != nil {
+		g.mu.Unlock()
+		return
+	}
+	g.stop = make(chan struct{})
+	stop := g.stop
+	g.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(g.config.Interval) * time.Hour)
+		defer ticker.Stop()
+
+		g.run()
+		for {
+			select {
+			case <-ticker.C:
+				g.run()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background generation
+func (g *Generator) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil {
+		close(g.stop)
+		g.stop = nil
+	}
+}
+
+func (g *Generator) run() {
+	result := g.Generate(time.Now())
+	if len(result.Created) > 0 {
+		slog.Info("Generated event pages", "count", len(result.Created))
+	}
+	for _, e := range result.Errors {
+		slog.Warn("Event generation failed", "error", e)
+	}
+}
+
+// Generate creates the pages of the next occurrences of every series. Existing pages are left untouched.
+func (g *Generator) Generate(now time.Time) *GenerateResult {
+	result := &GenerateResult{Created: []string{}}
+
+	for _, series := range g.config.Series {
+		occurrences, err := Occurrences(series, now)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", series.Name, err))
+			continue
+		}
+
+		template := ""
+		if series.Template != "" {
+			data, err := os.ReadFile(filepath.Join(g.projectDir, series.Template))
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", series.Name, err))
+				continue
+			}
+			template = string(data)
+		}
+
+		for _, start := range occurrences {
+			rel := filepath.ToSlash(filepath.Join(series.Directory, start.Format("2006-01-02")+"-"+seriesSlug(series.Name)+".md"))
+			path := filepath.Join(g.projectDir, rel)
+			if _, err := os.Stat(path); err == nil {
+				result.Skipped++
+				continue
+			}
+
+			page := render(series, template, start, now)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", series.Name, err))
+				break
+			}
+			if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", series.Name, err))
+				break
+			}
+			result.Created = append(result.Created, rel)
+		}
+	}
+
+	return result
+}
+
+// Occurrences returns the start times of the next occurrences of a series after now
+func Occurrences(series config.EventSeries, now time.Time) ([]time.Time, error) {
+	loc := time.Local
+	if series.Timezone != "" {
+		tz, err := time.LoadLocation(series.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		loc = tz
+	}
+
+	hour, minute := 0, 0
+	if series.Time != "" {
+		t, err := time.Parse("15:04", series.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, use HH:MM", series.Time)
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+
+	ahead := series.Ahead
+	if ahead <= 0 {
+		ahead = defaultAhead
+	}
+
+	now = now.In(loc)
+	anchor := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if series.Start != "" {
+		start, err := time.ParseInLocation("2006-01-02", series.Start, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date %q, use YYYY-MM-DD", series.Start)
+		}
+		anchor = start
+	}
+
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+	}
+
+	var out []time.Time
+	switch series.Frequency {
+	case FrequencyWeekly, FrequencyBiweekly:
+		weekday, err := parseWeekday(series.Weekday)
+		if err != nil {
+			return nil, err
+		}
+		step := 7
+		if series.Frequency == FrequencyBiweekly {
+			step = 14
+		}
+		day := anchor.AddDate(0, 0, (int(weekday)-int(anchor.Weekday())+7)%7)
+		// Skip whole periods up to today without losing the biweekly phase set by Start
+		for at(day).Before(now) {
+			day = day.AddDate(0, 0, step)
+		}
+		for len(out) < ahead {
+			out = append(out, at(day))
+			day = day.AddDate(0, 0, step)
+		}
+
+	case FrequencyMonthly:
+		month := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, loc)
+		for i := 0; len(out) < ahead && i < ahead+24; i++ {
+			day, ok, err := monthlyDay(series, month)
+			if err != nil {
+				return nil, err
+			}
+			if ok && !at(day).Before(now) && !day.Before(anchor) {
+				out = append(out, at(day))
+			}
+			month = month.AddDate(0, 1, 0)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown frequency %q (use weekly, biweekly or monthly)", series.Frequency)
+	}
+
+	return out, nil
+}
+
+// monthlyDay returns the day a monthly series falls on in month; ok is false if the month has no such day
+func monthlyDay(series config.EventSeries, month time.Time) (time.Time, bool, error) {
+	if series.Day > 0 {
+		day := month.AddDate(0, 0, series.Day-1)
+		return day, day.Month() == month.Month(), nil
+	}
+
+	weekday, err := parseWeekday(series.Weekday)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	first := month.AddDate(0, 0, (int(weekday)-int(month.Weekday())+7)%7)
+
+	weeks := map[string]int{"first": 0, "second": 1, "third": 2, "fourth": 3}
+	if series.Week == "last" {
+		day := first
+		for next := day.AddDate(0, 0, 7); next.Month() == month.Month(); next = next.AddDate(0, 0, 7) {
+			day = next
+		}
+		return day, true, nil
+	}
+	n, ok := weeks[series.Week]
+	if !ok && series.Week != "" {
+		return time.Time{}, false, fmt.Errorf("invalid week %q (use first, second, third, fourth or last)", series.Week)
+	}
+	day := first.AddDate(0, 0, 7*n)
+	return day, day.Month() == month.Month(), nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) || strings.EqualFold(name, d.String()[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// render fills the series template, or a default page, for one occurrence
+func render(series config.EventSeries, template string, start, now time.Time) string {
+	end := start.Add(time.Duration(series.Duration) * time.Minute)
+	title := series.Name + " – " + start.Format("January 2, 2006")
+
+	if template == "" {
+		template = "---\n" +
+			"title: \"{{title}}\"\n" +
+			"date: {{date}}\n" +
+			"endDate: {{end}}\n" +
+			"publishDate: {{now}}\n" +
+			"location: \"{{location}}\"\n" +
+			"series: \"{{series}}\"\n" +
+			"---\n"
+	}
+
+	return strings.NewReplacer(
+		"{{title}}", title,
+		"{{series}}", series.Name,
+		"{{date}}", start.Format(time.RFC3339),
+		"{{end}}", end.Format(time.RFC3339),
+		"{{now}}", now.Format(time.RFC3339),
+		"{{day}}", start.Format("2006-01-02"),
+		"{{time}}", start.Format("15:04"),
+		"{{month}}", start.Format("January 2006"),
+		"{{location}}", series.Location,
+	).Replace(template)
+}
+
+// Upcoming returns the events of every series that haven't ended yet, soonest first
+func (g *Generator) Upcoming(now time.Time) ([]Event, error) {
+	siteCfg, _ := site.Load(g.projectDir)
+
+	events := []Event{}
+	for _, series := range g.config.Series {
+		dir := filepath.Join(g.projectDir, series.Directory)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") || entry.Name() == "_index.md" {
+				continue
+			}
+			rel := filepath.ToSlash(filepath.Join(series.Directory, entry.Name()))
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			fm, _, _, err := hugocontent.Parse(data)
+			if err != nil || fm.Bool("draft") {
+				continue
+			}
+
+			start, ok := fm.Time("date")
+			if !ok {
+				continue
+			}
+			end, ok := fm.Time("endDate")
+			if !ok {
+				end = start.Add(time.Duration(series.Duration) * time.Minute)
+			}
+			if end.Before(now) {
+				continue
+			}
+
+			event := Event{
+				Series:      series.Name,
+				Title:       fm.String("title"),
+				Path:        rel,
+				Start:       start,
+				End:         end,
+				Location:    fm.String("location"),
+				Description: fm.String("description"),
+			}
+			if event.Location == "" {
+				event.Location = series.Location
+			}
+			if siteCfg != nil {
+				if loc, err := hugocontent.Locate(siteCfg, rel); err == nil {
+					event.URL = absoluteURL(siteCfg.BaseURL(), hugocontent.PermalinkFor(siteCfg, loc, fm, rel).URL)
+				}
+			}
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+// absoluteURL joins the scheme and host of baseURL with a site-relative URL
+func absoluteURL(baseURL, rel string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return rel
+	}
+	return base.Scheme + "://" + base.Host + rel
+}
+
+// seriesSlug returns the slug of a series name for its page file names
+func seriesSlug(name string) string {
+	if s := slug.Make(name); s != "" {
+		return s
+	}
+	return "event"
+}
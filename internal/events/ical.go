@@ -0,0 +1,74 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// icalTime is the UTC date-time format of iCalendar (RFC 5545)
+const icalTime = "20060102T150405Z"
+
+// ICal renders events as an iCalendar feed
+func ICal(name string, events []Event, now time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(fold(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//hugo-manager//events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escape(name))
+	}
+
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s@hugo-manager", strings.NewReplacer("/", "-", ".", "-").Replace(e.Path)))
+		line("DTSTAMP:" + now.UTC().Format(icalTime))
+		line("DTSTART:" + e.Start.UTC().Format(icalTime))
+		line("DTEND:" + e.End.UTC().Format(icalTime))
+		line("SUMMARY:" + escape(e.Title))
+		if e.Location != "" {
+			line("LOCATION:" + escape(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.URL != "" {
+			line("URL:" + e.URL)
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escape escapes text values as required by RFC 5545
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// fold splits content lines longer than 75 octets, without breaking UTF-8 sequences
+func fold(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package files
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// storedExts are formats that are compressed already, so deflating them again only costs time
+var storedExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mp3": true, ".m4a": true, ".ogg": true,
+	".zip": true, ".gz": true, ".tgz": true, ".7z": true, ".woff": true, ".woff2": true, ".pdf": true,
+}
+
+// WriteZip writes a zip archive of a directory to w, with the directory as the archive's top-level
+// folder. Hidden files and directories are left out, as in the file tree, and so are symlinks, which
+// could point outside the project. Files and directories for which exclude, when it isn't nil,
+// returns true for their project-relative path are left out too.
+func (m *Manager) WriteZip(w io.Writer, relativePath string, exclude func(string) bool) error {
+	if !m.isValidPath(relativePath) {
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
+	}
+	root := filepath.Join(m.projectDir, relativePath)
+	stat, err := os.Stat(root)
+	if err != nil {
+		return wrapNotExist(err, relativePath)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%w: %s", ErrNotDir, relativePath)
+	}
+
+	top := filepath.Base(root)
+	if abs, err := filepath.Abs(root); err == nil {
+		top = filepath.Base(abs) // The project root's own name rather than "."
+	}
+
+	zw := zip.NewWriter(w)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if p != root && (m.isHidden(d.Name(), d.IsDir()) || exclude != nil && exclude(filepath.Join(relativePath, rel))) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(top, filepath.ToSlash(rel))
+		if d.IsDir() {
+			header.Name += "/"
+			_, err := zw.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+		if storedExts[strings.ToLower(filepath.Ext(p))] {
+			header.Method = zip.Store
+		}
+
+		dst, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(dst, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,47 @@
+package files
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// sniffLen is how much of a file isBinaryFile looks at
+const sniffLen = 8000
+
+// IsBinary reports whether data isn't text the editor can round-trip: it has a NUL byte or isn't
+// valid UTF-8
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// isBinaryFile reports whether the start of a file is binary. A missing file isn't.
+func isBinaryFile(fullPath string) (bool, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	buf = buf[:n]
+	if n == sniffLen {
+		// The sample may end in the middle of a character
+		i := len(buf) - 1
+		for i > 0 && i > len(buf)-utf8.UTFMax && !utf8.RuneStart(buf[i]) {
+			i--
+		}
+		if !utf8.FullRune(buf[i:]) {
+			buf = buf[:i]
+		}
+	}
+	return IsBinary(buf), nil
+}
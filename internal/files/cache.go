@@ -0,0 +1,259 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// errNoCache means a tree can't be served from the cache and has to be read from disk
+var errNoCache = errors.New("file tree cache unavailable")
+
+// treeCache keeps the unfiltered tree of every directory read, by absolute path. The directories
+// are watched, and a change drops the changed directory and its ancestors, so the next request
+// reads only them again and takes the untouched subtrees from memory.
+type treeCache struct {
+	mu      sync.Mutex
+	dirs    map[string]*cachedDir
+	gen     uint64 // Incremented by every invalidation
+	watcher *fsnotify.Watcher
+}
+
+// cachedDir is the tree of a directory with a summary used as fingerprint
+type cachedDir struct {
+	info   FileInfo
+	count  int   // Entries in the tree, the directory included
+	latest int64 // Latest modification time in the tree, in nanoseconds
+}
+
+// Start watches the directories of the tree so it can be kept in memory. Without it, or when the
+// file system can't be watched, every tree request reads the directories.
+func (m *Manager) Start() {
+	if !m.config.Cache {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("File tree cache disabled: failed to watch files", "error", err)
+		return
+	}
+	m.cache = &treeCache{dirs: map[string]*cachedDir{}, watcher: watcher}
+	go m.cache.run(watcher)
+}
+
+// Stop stops watching the directories and drops the cached tree
+func (m *Manager) Stop() {
+	if m.cache != nil {
+		m.cache.disable()
+	}
+}
+
+// run drops the trees the watcher reports changes in
+func (c *treeCache) run(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			c.invalidate(event.Name, event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, so nothing cached can be trusted
+			slog.Warn("File tree watcher failed; dropping the cached tree", "error", err)
+			c.mu.Lock()
+			c.gen++
+			clear(c.dirs)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// invalidate drops the cached trees a change to a path affects: the path, its ancestors and,
+// when it was removed or renamed, everything below it
+func (c *treeCache) invalidate(path string, removed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	path = filepath.Clean(path)
+	delete(c.dirs, path)
+	if removed {
+		prefix := path + string(filepath.Separator)
+		for dir := range c.dirs {
+			if strings.HasPrefix(dir, prefix) {
+				delete(c.dirs, dir)
+			}
+		}
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		delete(c.dirs, dir)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+}
+
+// disable stops the watcher and drops the cache for good
+func (c *treeCache) disable() {
+	c.mu.Lock()
+	watcher := c.watcher
+	c.watcher, c.dirs = nil, nil
+	c.gen++
+	c.mu.Unlock()
+
+	if watcher != nil {
+		watcher.Close()
+	}
+}
+
+// changed drops the cached trees a change made through the manager affects, without waiting for
+// the watcher to report it
+func (m *Manager) changed(fullPath string, removed bool) {
+	if m.cache != nil {
+		m.cache.invalidate(fullPath, removed)
+	}
+}
+
+// cachedTree returns the unfiltered tree of a directory, from the cache or read and cached.
+// It returns errNoCache when the directory has to be read without the cache. linked is set below
+// a link to a directory.
+func (m *Manager) cachedTree(fullPath string, linked bool) (*cachedDir, error) {
+	c := m.cache
+	if c == nil {
+		return nil, errNoCache
+	}
+	fullPath = filepath.Clean(fullPath)
+
+	c.mu.Lock()
+	if d, ok := c.dirs[fullPath]; ok {
+		c.mu.Unlock()
+		return d, nil
+	}
+	gen, watcher := c.gen, c.watcher
+	c.mu.Unlock()
+	if watcher == nil {
+		return nil, errNoCache
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, errNoCache
+	}
+	// Watch before reading, so a change made while reading invalidates the result
+	if err := watcher.Add(fullPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		slog.Warn("File tree cache disabled: failed to watch directory", "path", fullPath, "error", err)
+		c.disable()
+		return nil, errNoCache
+	}
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	relativePath, err := filepath.Rel(m.projectDir, fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, fullPath)
+	}
+	d := &cachedDir{
+		info: FileInfo{
+			Name:    filepath.Base(relativePath),
+			Path:    filepath.ToSlash(relativePath),
+			IsDir:   true,
+			ModTime: stat.ModTime().Unix(),
+		},
+		count:  1,
+		latest: stat.ModTime().UnixNano(),
+	}
+
+	var children []FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if m.isHidden(name, entry.IsDir()) {
+			continue
+		}
+
+		childPath := filepath.Join(fullPath, name)
+		allowed, childLink := m.treeEntry(childPath, entry, linked)
+		if !allowed {
+			continue
+		}
+		childStat, err := os.Stat(childPath)
+		if err != nil {
+			continue
+		}
+		if childStat.IsDir() {
+			child, err := m.cachedTree(childPath, linked || childLink)
+			if errors.Is(err, errNoCache) {
+				return nil, errNoCache
+			}
+			if err != nil {
+				continue
+			}
+			children = append(children, child.info)
+			d.count += child.count
+			d.latest = max(d.latest, child.latest)
+			continue
+		}
+
+		children = append(children, FileInfo{
+			Name:    name,
+			Path:    filepath.ToSlash(filepath.Join(relativePath, name)),
+			Size:    childStat.Size(),
+			ModTime: childStat.ModTime().Unix(),
+			Type:    getFileType(childPath),
+		})
+		d.count++
+		d.latest = max(d.latest, childStat.ModTime().UnixNano())
+	}
+	sortEntries(children)
+	d.info.Children = children
+
+	// A tree read while something in it changed is returned but not kept
+	c.mu.Lock()
+	if c.gen == gen && c.dirs != nil {
+		c.dirs[fullPath] = d
+	}
+	c.mu.Unlock()
+	return d, nil
+}
+
+// filterTree returns a filtered copy of a cached tree, leaving the cached one untouched
+func filterTree(info FileInfo, query string, allowedTypes map[string]bool, pruneEmptyDirs bool) (FileInfo, bool) {
+	if !info.IsDir {
+		if allowedTypes != nil && !allowedTypes[info.Type] {
+			return FileInfo{}, false
+		}
+		if query != "" && !strings.Contains(strings.ToLower(info.Name), query) {
+			return FileInfo{}, false
+		}
+		return info, true
+	}
+
+	var children []FileInfo
+	for _, child := range info.Children {
+		if filtered, ok := filterTree(child, query, allowedTypes, pruneEmptyDirs); ok {
+			children = append(children, filtered)
+		}
+	}
+	if pruneEmptyDirs && len(children) == 0 {
+		return FileInfo{}, false
+	}
+	info.Children = children
+	return info, true
+}
@@ -0,0 +1,13 @@
+package files
+
+import "errors"
+
+// Sentinel errors returned by Manager operations. Use errors.Is to check for them.
+var (
+	ErrExists      = errors.New("already exists")
+	ErrNotFound    = errors.New("does not exist")
+	ErrInvalidPath = errors.New("invalid path")
+	ErrNotEmpty    = errors.New("directory not empty")
+	ErrNotDir      = errors.New("not a directory")
+	ErrBinary      = errors.New("binary file")
+)
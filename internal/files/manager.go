@@ -1,32 +1,41 @@
 package files
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
 	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
 )
 
 // FileInfo represents a file or directory in the tree
 type FileInfo struct {
-	Name     string     `json:"name"`
-	Path     string     `json:"path"`
-	IsDir    bool       `json:"isDir"`
-	Size     int64      `json:"size,omitempty"`
-	ModTime  int64      `json:"modTime,omitempty"`
-	Children []FileInfo `json:"children,omitempty"`
-	Type     string     `json:"type,omitempty"` // "markdown", "html", "yaml", "image", etc.
+	Name     string             `json:"name"`
+	Path     string             `json:"path"`
+	IsDir    bool               `json:"isDir"`
+	Size     int64              `json:"size,omitempty"`
+	ModTime  int64              `json:"modTime,omitempty"`
+	Children []FileInfo         `json:"children,omitempty"`
+	Type     string             `json:"type,omitempty"`  // "markdown", "html", "yaml", "image", etc.
+	Stats    *hugocontent.Stats `json:"stats,omitempty"` // Of a markdown file, added by WithStats
 }
 
 // Manager handles file operations
 type Manager struct {
 	projectDir string
 	config     config.FileTreeConfig
+	cache      *treeCache // Nil until Start, or when the tree can't be watched
 }
 
 // NewManager creates a new file manager
@@ -38,15 +47,20 @@ func NewManager(projectDir string, cfg config.FileTreeConfig) *Manager {
 }
 
 // GetTree returns the file tree for configured directories
-func (m *Manager) GetTree() ([]FileInfo, error) {
-	return m.GetTreeForRoots(m.config.ShowDirs)
+func (m *Manager) GetTree(ctx context.Context) ([]FileInfo, error) {
+	return m.GetTreeForRoots(ctx, m.config.ShowDirs)
 }
 
-func (m *Manager) GetTreeForRoots(roots []string) ([]FileInfo, error) {
-	return m.GetFilteredTree(roots, "", nil, false)
+func (m *Manager) GetTreeForRoots(ctx context.Context, roots []string) ([]FileInfo, error) {
+	return m.GetFilteredTree(ctx, roots, "", nil, false)
 }
 
-func (m *Manager) GetFilteredTree(roots []string, query string, allowedTypes map[string]bool, pruneEmptyDirs bool) ([]FileInfo, error) {
+// GetFilteredTree returns the trees of roots, filtered by name and type. Reading a tree that isn't
+// cached stops with the error of ctx once it's done.
+func (m *Manager) GetFilteredTree(ctx context.Context, roots []string, query string, allowedTypes map[string]bool, pruneEmptyDirs bool) ([]FileInfo, error) {
+	if err := m.checkRoots(roots); err != nil {
+		return nil, err
+	}
 	var tree []FileInfo
 	q := strings.ToLower(strings.TrimSpace(query))
 
@@ -59,7 +73,10 @@ func (m *Manager) GetFilteredTree(roots []string, query string, allowedTypes map
 			continue
 		}
 
-		info, ok := m.buildFilteredTree(fullPath, dir, q, allowedTypes, pruneEmptyDirs)
+		info, ok := m.tree(ctx, fullPath, dir, q, allowedTypes, pruneEmptyDirs)
+		if err := ctx.Err(); err != nil {
+			return nil, err // The tree was cut short
+		}
 		if !ok {
 			continue
 		}
@@ -67,17 +84,107 @@ func (m *Manager) GetFilteredTree(roots []string, query string, allowedTypes map
 	}
 
 	// Sort roots alphabetically
-	sort.Slice(tree, func(i, j int) bool {
-		if tree[i].IsDir != tree[j].IsDir {
-			return tree[i].IsDir
-		}
-		return strings.ToLower(tree[i].Name) < strings.ToLower(tree[j].Name)
-	})
+	sortEntries(tree)
 
 	return tree, nil
 }
 
-func (m *Manager) buildFilteredTree(fullPath, relativePath, query string, allowedTypes map[string]bool, pruneEmptyDirs bool) (FileInfo, bool) {
+// tree returns the filtered tree of a root, from the cache when the tree is watched
+func (m *Manager) tree(ctx context.Context, fullPath, relativePath, query string, allowedTypes map[string]bool, pruneEmptyDirs bool) (FileInfo, bool) {
+	linked := isLink(fullPath)
+	d, err := m.cachedTree(fullPath, linked)
+	switch {
+	case errors.Is(err, errNoCache):
+		return m.buildFilteredTree(ctx, fullPath, relativePath, query, allowedTypes, pruneEmptyDirs, linked)
+	case err != nil:
+		return FileInfo{}, false
+	case query == "" && allowedTypes == nil && !pruneEmptyDirs:
+		return d.info, true
+	}
+	return filterTree(d.info, query, allowedTypes, pruneEmptyDirs)
+}
+
+// Fingerprint returns a cheap fingerprint of the trees under roots, built from the number of
+// entries and their latest modification time without reading or sorting them. Creating, saving,
+// renaming or deleting a file below a root changes it. A watched tree is fingerprinted from memory.
+func (m *Manager) Fingerprint(roots []string) (string, error) {
+	if err := m.checkRoots(roots); err != nil {
+		return "", err
+	}
+	if fingerprint, ok := m.cachedFingerprint(roots); ok {
+		return fingerprint, nil
+	}
+
+	var count int
+	var latest int64
+	for _, dir := range roots {
+		if dir == "" {
+			continue
+		}
+		fullPath := filepath.Join(m.projectDir, dir)
+		filepath.WalkDir(fullPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Missing roots and unreadable entries are left out of the tree too
+			}
+			if path != fullPath && m.isHidden(d.Name(), d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			count++
+			if mod := info.ModTime().UnixNano(); mod > latest {
+				latest = mod
+			}
+			return nil
+		})
+	}
+	return fmt.Sprintf("%x-%x", count, latest), nil
+}
+
+// checkRoots returns ErrInvalidPath for the first root of a tree outside the project, or behind a
+// link the symlink policy doesn't follow
+func (m *Manager) checkRoots(roots []string) error {
+	for _, dir := range roots {
+		if dir != "" && !m.isValidPath(dir) {
+			return fmt.Errorf("%w: %s", ErrInvalidPath, dir)
+		}
+	}
+	return nil
+}
+
+// cachedFingerprint returns the fingerprint of the trees under roots from the cache
+func (m *Manager) cachedFingerprint(roots []string) (string, bool) {
+	if m.cache == nil {
+		return "", false
+	}
+	var count int
+	var latest int64
+	for _, dir := range roots {
+		if dir == "" {
+			continue
+		}
+		full := filepath.Join(m.projectDir, dir)
+		d, err := m.cachedTree(full, isLink(full))
+		if errors.Is(err, errNoCache) {
+			return "", false
+		}
+		if err != nil {
+			continue // Missing roots are left out of the tree too
+		}
+		count += d.count
+		latest = max(latest, d.latest)
+	}
+	return fmt.Sprintf("%x-%x", count, latest), true
+}
+
+// buildFilteredTree reads the tree of a path. linked is set below a link to a directory. Once ctx
+// is done no more directories are read.
+func (m *Manager) buildFilteredTree(ctx context.Context, fullPath, relativePath, query string, allowedTypes map[string]bool, pruneEmptyDirs, linked bool) (FileInfo, bool) {
 	stat, err := os.Stat(fullPath)
 	if err != nil {
 		return FileInfo{}, false
@@ -105,6 +212,9 @@ func (m *Manager) buildFilteredTree(fullPath, relativePath, query string, allowe
 		return info, true
 	}
 
+	if ctx.Err() != nil {
+		return FileInfo{}, false
+	}
 	entries, err := os.ReadDir(fullPath)
 	if err != nil {
 		return FileInfo{}, false
@@ -118,8 +228,12 @@ func (m *Manager) buildFilteredTree(fullPath, relativePath, query string, allowe
 		}
 
 		childPath := filepath.Join(fullPath, name)
+		allowed, childLink := m.treeEntry(childPath, entry, linked)
+		if !allowed {
+			continue
+		}
 		childRelPath := filepath.Join(relativePath, name)
-		childInfo, ok := m.buildFilteredTree(childPath, childRelPath, q, allowedTypes, pruneEmptyDirs)
+		childInfo, ok := m.buildFilteredTree(ctx, childPath, childRelPath, q, allowedTypes, pruneEmptyDirs, linked || childLink)
 		if !ok {
 			continue
 		}
@@ -130,27 +244,32 @@ func (m *Manager) buildFilteredTree(fullPath, relativePath, query string, allowe
 		return FileInfo{}, false
 	}
 
-	sort.Slice(children, func(i, j int) bool {
-		if children[i].IsDir != children[j].IsDir {
-			return children[i].IsDir
-		}
-		return strings.ToLower(children[i].Name) < strings.ToLower(children[j].Name)
-	})
+	sortEntries(children)
 
 	info.Children = children
 	return info, true
 }
 
+// sortEntries sorts directories first, then by name
+func sortEntries(entries []FileInfo) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+}
+
 // ReadFile reads a file's content
 func (m *Manager) ReadFile(relativePath string) (string, error) {
 	if !m.isValidPath(relativePath) {
-		return "", fmt.Errorf("invalid path: %s", relativePath)
+		return "", fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		return "", err
+		return "", wrapNotExist(err, relativePath)
 	}
 
 	return string(content), nil
@@ -158,18 +277,46 @@ func (m *Manager) ReadFile(relativePath string) (string, error) {
 
 func (m *Manager) ReadFileBytes(relativePath string) ([]byte, error) {
 	if !m.isValidPath(relativePath) {
-		return nil, fmt.Errorf("invalid path: %s", relativePath)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
-	return os.ReadFile(fullPath)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, wrapNotExist(err, relativePath)
+	}
+	return data, nil
+}
+
+// Open opens a file for streaming, returning it with its info. The caller closes it.
+func (m *Manager) Open(relativePath string) (*os.File, fs.FileInfo, error) {
+	if !m.isValidPath(relativePath) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
+	}
+
+	f, err := os.Open(filepath.Join(m.projectDir, relativePath))
+	if err != nil {
+		return nil, nil, wrapNotExist(err, relativePath)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		f.Close()
+		return nil, nil, fmt.Errorf("%w: %s is a directory", ErrInvalidPath, relativePath)
+	}
+	return f, stat, nil
 }
 
 func (m *Manager) IsValidPath(relativePath string) bool {
 	return m.isValidPath(relativePath)
 }
 
-func (m *Manager) SearchImages(folders []string, query string) ([]FileInfo, error) {
+// SearchImages returns the images below folders whose name contains query. It stops with the
+// error of ctx once it's done.
+func (m *Manager) SearchImages(ctx context.Context, folders []string, query string) ([]FileInfo, error) {
 	allowedExt := map[string]bool{
 		".jpg":  true,
 		".jpeg": true,
@@ -198,6 +345,9 @@ func (m *Manager) SearchImages(folders []string, query string) ([]FileInfo, erro
 		}
 
 		walkErr := filepath.WalkDir(rootAbs, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err != nil {
 				return nil
 			}
@@ -215,6 +365,9 @@ func (m *Manager) SearchImages(folders []string, query string) ([]FileInfo, erro
 			if m.isHidden(name, false) {
 				return nil
 			}
+			if d.Type()&fs.ModeSymlink != 0 && !m.symlinkAllowed(path) {
+				return nil
+			}
 
 			ext := strings.ToLower(filepath.Ext(name))
 			if !allowedExt[ext] {
@@ -260,47 +413,60 @@ func (m *Manager) SearchImages(folders []string, query string) ([]FileInfo, erro
 // WriteFile writes content to a file
 func (m *Manager) WriteFile(relativePath, content string) error {
 	if !m.isValidPath(relativePath) {
-		return fmt.Errorf("invalid path: %s", relativePath)
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
 
+	// Saving text over a binary file would corrupt it
+	binary, err := isBinaryFile(fullPath)
+	if err != nil {
+		return err
+	}
+	if binary {
+		return fmt.Errorf("%w: %s", ErrBinary, relativePath)
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	if err := atomicfile.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	m.changed(fullPath, false)
+	return nil
 }
 
 // CreateFile creates a new file
 func (m *Manager) CreateFile(relativePath, content string) error {
 	if !m.isValidPath(relativePath) {
-		return fmt.Errorf("invalid path: %s", relativePath)
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
 
 	// Check if file already exists
 	if _, err := os.Stat(fullPath); err == nil {
-		return fmt.Errorf("file already exists: %s", relativePath)
+		return fmt.Errorf("file %w: %s", ErrExists, relativePath)
 	}
 
 	return m.WriteFile(relativePath, content)
 }
 
-// CreateFileFromTemplate creates a new file using a template
-func (m *Manager) CreateFileFromTemplate(relativePath, templateName string, templateData map[string]interface{}, templates config.TemplatesConfig) error {
+// CreateFileFromTemplate creates a new file using a template, with the front matter of its fields followed by body
+func (m *Manager) CreateFileFromTemplate(relativePath, templateName string, templateData map[string]interface{}, body string, templates config.TemplatesConfig) error {
 	if !m.isValidPath(relativePath) {
-		return fmt.Errorf("invalid path: %s", relativePath)
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
 
 	// Check if file already exists
 	if _, err := os.Stat(fullPath); err == nil {
-		return fmt.Errorf("file already exists: %s", relativePath)
+		return fmt.Errorf("file %w: %s", ErrExists, relativePath)
 	}
 
 	// Get the template
@@ -309,86 +475,188 @@ func (m *Manager) CreateFileFromTemplate(relativePath, templateName string, temp
 		return fmt.Errorf("template not found: %s", templateName)
 	}
 
-	// Generate front matter YAML
-	frontMatter := generateFrontMatter(template, templateData)
-
-	// Create content with front matter
-	content := fmt.Sprintf("---\n%s---\n\n", frontMatter)
+	// Write the front matter in the format of the section's archetype
+	format := hugocontent.ArchetypeFormat(m.projectDir, relativePath)
+	content, err := hugocontent.Encode(generateFrontMatter(template, templateData), "\n"+body, format)
+	if err != nil {
+		return err
+	}
 
-	return m.WriteFile(relativePath, content)
+	return m.WriteFile(relativePath, string(content))
 }
 
-// generateFrontMatter generates YAML front matter from template data
-func generateFrontMatter(template map[string]config.TemplateField, data map[string]interface{}) string {
-	var lines []string
-
+// generateFrontMatter generates front matter from template data, typed by the template's fields
+func generateFrontMatter(template map[string]config.TemplateField, data map[string]interface{}) hugocontent.FrontMatter {
+	fm := hugocontent.FrontMatter{}
 	for fieldName, field := range template {
 		value, exists := data[fieldName]
-		if !exists || value == "" {
+		if !exists || value == nil || value == "" {
 			continue
 		}
+		if v := frontMatterValue(field, value); v != nil {
+			fm[fieldName] = v
+		}
+	}
+	return fm
+}
+
+// frontMatterValue converts a value sent for a template field to the type it's written as, or nil
+// to leave it out
+func frontMatterValue(field config.TemplateField, value interface{}) interface{} {
+	if nested, ok := value.(map[string]interface{}); ok {
+		// An object, such as an image with its alt text, becomes a nested mapping
+		return nested
+	}
 
-		switch field.Type {
-		case "text", "textarea", "date":
-			lines = append(lines, fmt.Sprintf("%s: %q", fieldName, value))
-		case "number":
-			lines = append(lines, fmt.Sprintf("%s: %v", fieldName, value))
-		case "bool":
-			if boolVal, ok := value.(bool); ok && boolVal {
-				lines = append(lines, fmt.Sprintf("%s: true", fieldName))
-			} else if strVal, ok := value.(string); ok == true && strVal == "true" {
-				lines = append(lines, fmt.Sprintf("%s: true", fieldName))
-			} else {
-				lines = append(lines, fmt.Sprintf("%s: false", fieldName))
+	switch field.Type {
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return int64(v)
+			}
+			return v
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
 			}
+			return v
+		}
+		return value
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v
+		case string:
+			return strings.TrimSpace(v) == "true"
+		}
+		return false
+	case "date":
+		str := strings.TrimSpace(fmt.Sprint(value))
+		if t, err := time.Parse("2006-01-02", str); err == nil {
+			return hugocontent.Date(t)
+		}
+		if t, ok := hugocontent.ParseDate(str); ok {
+			return t
+		}
+		return str
+	case "tags", "array":
+		if items := listItems(value); len(items) > 0 {
+			return items
 		}
+		return nil
+	case "reference":
+		// References are relative to content/, as Hugo's ref and GetPage take them
+		return strings.TrimPrefix(path.Clean(filepath.ToSlash(fmt.Sprint(value))), "content/")
 	}
+	return fmt.Sprint(value)
+}
 
-	return strings.Join(lines, "\n")
+// listItems returns the items of a list field, sent as a JSON array or a comma-separated string
+func listItems(value interface{}) []string {
+	var raw []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			raw = append(raw, fmt.Sprint(item))
+		}
+	case []string:
+		raw = v
+	case string:
+		raw = strings.Split(v, ",")
+	}
+
+	var items []string
+	for _, item := range raw {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // DeleteFile deletes a file
 func (m *Manager) DeleteFile(relativePath string) error {
 	if !m.isValidPath(relativePath) {
-		return fmt.Errorf("invalid path: %s", relativePath)
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
-	return os.Remove(fullPath)
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		return wrapNotExist(err, relativePath)
+	}
+	if stat.IsDir() {
+		entries, err := os.ReadDir(fullPath)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("%w: %s", ErrNotEmpty, relativePath)
+		}
+	}
+
+	if err := os.Remove(fullPath); err != nil {
+		return err
+	}
+	m.changed(fullPath, true)
+	return nil
 }
 
 // RenameFile renames/moves a file
 func (m *Manager) RenameFile(oldPath, newPath string) error {
 	if !m.isValidPath(oldPath) || !m.isValidPath(newPath) {
-		return fmt.Errorf("invalid path")
+		return ErrInvalidPath
 	}
 
 	oldFull := filepath.Join(m.projectDir, oldPath)
 	newFull := filepath.Join(m.projectDir, newPath)
 
+	if _, err := os.Stat(oldFull); err != nil {
+		return wrapNotExist(err, oldPath)
+	}
+	if _, err := os.Stat(newFull); err == nil {
+		return fmt.Errorf("destination %w: %s", ErrExists, newPath)
+	}
+
 	// Ensure target directory exists
 	dir := filepath.Dir(newFull)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	return os.Rename(oldFull, newFull)
+	if err := os.Rename(oldFull, newFull); err != nil {
+		return err
+	}
+	m.changed(oldFull, true)
+	m.changed(newFull, false)
+	return nil
 }
 
 // CreateDir creates a new directory
 func (m *Manager) CreateDir(relativePath string) error {
 	if !m.isValidPath(relativePath) {
-		return fmt.Errorf("invalid path: %s", relativePath)
+		return fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
-	return os.MkdirAll(fullPath, 0755)
+	if _, err := os.Stat(fullPath); err == nil {
+		return fmt.Errorf("directory %w: %s", ErrExists, relativePath)
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		return err
+	}
+	m.changed(fullPath, false)
+	return nil
 }
 
 // CopyFile copies a file
 func (m *Manager) CopyFile(srcPath, dstPath string) error {
 	if !m.isValidPath(srcPath) || !m.isValidPath(dstPath) {
-		return fmt.Errorf("invalid path")
+		return ErrInvalidPath
 	}
 
 	srcFull := filepath.Join(m.projectDir, srcPath)
@@ -406,31 +674,41 @@ func (m *Manager) CopyFile(srcPath, dstPath string) error {
 		return err
 	}
 
-	dst, err := os.Create(dstFull)
-	if err != nil {
+	if _, err := atomicfile.WriteFrom(dstFull, src, 0644); err != nil {
 		return err
 	}
-	defer dst.Close()
+	m.changed(dstFull, false)
+	return nil
+}
 
-	_, err = io.Copy(dst, src)
-	return err
+// Stat returns the file system info of a file, with its modification time in full resolution
+func (m *Manager) Stat(relativePath string) (fs.FileInfo, error) {
+	if !m.isValidPath(relativePath) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
+	}
+
+	stat, err := os.Stat(filepath.Join(m.projectDir, relativePath))
+	if err != nil {
+		return nil, wrapNotExist(err, relativePath)
+	}
+	return stat, nil
 }
 
 // GetFileInfo returns info about a specific file
 func (m *Manager) GetFileInfo(relativePath string) (*FileInfo, error) {
 	if !m.isValidPath(relativePath) {
-		return nil, fmt.Errorf("invalid path: %s", relativePath)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, relativePath)
 	}
 
 	fullPath := filepath.Join(m.projectDir, relativePath)
 	stat, err := os.Stat(fullPath)
 	if err != nil {
-		return nil, err
+		return nil, wrapNotExist(err, relativePath)
 	}
 
 	return &FileInfo{
 		Name:    filepath.Base(relativePath),
-		Path:    relativePath,
+		Path:    filepath.ToSlash(relativePath),
 		IsDir:   stat.IsDir(),
 		Size:    stat.Size(),
 		ModTime: stat.ModTime().Unix(),
@@ -438,6 +716,15 @@ func (m *Manager) GetFileInfo(relativePath string) (*FileInfo, error) {
 	}, nil
 }
 
+// WithStats adds the word count, reading time, headings and front matter summary of a markdown
+// file to its info, from content already read, so the file isn't read and parsed again
+func (fi *FileInfo) WithStats(content []byte) *FileInfo {
+	if fi != nil && fi.Type == "markdown" && !IsBinary(content) {
+		fi.Stats = hugocontent.PageStats(content)
+	}
+	return fi
+}
+
 // ListDataFiles returns files from a specific data directory (for shortcode file selectors)
 func (m *Manager) ListDataFiles(dataType string) ([]FileInfo, error) {
 	var results []FileInfo
@@ -471,6 +758,7 @@ func (m *Manager) ListDataFiles(dataType string) ([]FileInfo, error) {
 			ext := strings.ToLower(filepath.Ext(path))
 			if ext == ".md" || ext == ".html" {
 				relPath, _ := filepath.Rel(m.projectDir, path)
+				relPath = filepath.ToSlash(relPath)
 				// Remove extension for Hugo page references
 				refPath := strings.TrimSuffix(relPath, ext)
 				refPath = strings.TrimPrefix(refPath, "content/")
@@ -501,8 +789,38 @@ func (m *Manager) Exists(relativePath string) bool {
 	return err == nil
 }
 
-// isValidPath checks if a path is safe (no directory traversal)
+// AvailableName returns the first of slug, slug-2, slug-3, ... that no file or directory in dir is
+// named after, whatever its extension, so a new page gets neither the URL of a page nor of a
+// bundle already there. It reports whether slug itself was taken.
+func (m *Manager) AvailableName(dir, slug string) (string, bool, error) {
+	if !m.isValidPath(dir) {
+		return "", false, fmt.Errorf("%w: %s", ErrInvalidPath, dir)
+	}
+	entries, err := os.ReadDir(filepath.Join(m.projectDir, dir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", false, err
+	}
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		taken[strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))] = true
+	}
+
+	name := slug
+	for n := 2; taken[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s-%d", slug, n)
+	}
+	return name, name != slug, nil
+}
+
+// isValidPath checks if a path is safe (no directory traversal). Paths use forward slashes; on
+// Windows backslashes work too.
 func (m *Manager) isValidPath(relativePath string) bool {
+	// A drive letter or UNC share would point outside the project (Windows only)
+	if filepath.VolumeName(relativePath) != "" {
+		return false
+	}
+
 	// Clean the path
 	cleaned := filepath.Clean(relativePath)
 
@@ -511,12 +829,30 @@ func (m *Manager) isValidPath(relativePath string) bool {
 		return false
 	}
 
-	// Ensure it's within project directory
-	fullPath := filepath.Join(m.projectDir, cleaned)
-	absProject, _ := filepath.Abs(m.projectDir)
-	absPath, _ := filepath.Abs(fullPath)
+	// Ensure it's within project directory. Rel, unlike a prefix check, doesn't match a sibling
+	// directory named like the project, and compares names case-insensitively on Windows.
+	absProject, err := filepath.Abs(m.projectDir)
+	if err != nil {
+		return false
+	}
+	// Joined as callers join it: Join resolves a ".." after a leading "/" against the project,
+	// which Clean alone drops
+	fullPath := filepath.Join(absProject, relativePath)
+	rel, err := filepath.Rel(absProject, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
 
-	return strings.HasPrefix(absPath, absProject)
+	// A link inside the project may point outside it
+	return m.symlinkAllowed(fullPath)
+}
+
+// wrapNotExist converts "not exist" errors into ErrNotFound, leaving other errors untouched
+func wrapNotExist(err error, relativePath string) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, relativePath)
+	}
+	return err
 }
 
 func (m *Manager) isHidden(name string, isDir bool) bool {
@@ -0,0 +1,83 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Symlink policies of file_tree.symlinks
+const (
+	SymlinksDeny          = "deny"           // No path may go through a symbolic link
+	SymlinksWithinProject = "within_project" // Links are followed when they resolve inside the project
+)
+
+// symlinkAllowed reports whether the symlink policy lets a path be used. The path is resolved with
+// its links: within_project requires it to end up inside the project, deny to have no links below
+// the project directory. A path that doesn't exist yet is checked by its deepest existing ancestor,
+// and a dangling link is never allowed, as writing to it would create its target.
+func (m *Manager) symlinkAllowed(fullPath string) bool {
+	absProject, err := filepath.Abs(m.projectDir)
+	if err != nil {
+		return false
+	}
+	realProject, err := filepath.EvalSymlinks(absProject)
+	if err != nil {
+		return false
+	}
+	existing, err := filepath.Abs(fullPath)
+	if err != nil {
+		return false
+	}
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false
+		}
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realProject, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if m.config.Symlinks == SymlinksDeny {
+		// Windows resolves names to their case on disk
+		lexical, err := filepath.Rel(absProject, existing)
+		return err == nil && (lexical == rel || runtime.GOOS == "windows" && strings.EqualFold(lexical, rel))
+	}
+	return true
+}
+
+// treeEntry decides whether a tree lists an entry of a directory, and reports whether the entry is
+// a link. Links to directories are followed one level deep: inside a linked directory, links to
+// other directories are left out, so links pointing back up can't make the tree endless.
+func (m *Manager) treeEntry(fullPath string, entry fs.DirEntry, linked bool) (ok, isLink bool) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return true, false
+	}
+	if !m.symlinkAllowed(fullPath) {
+		return false, true
+	}
+	if linked {
+		if stat, err := os.Stat(fullPath); err != nil || stat.IsDir() {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// isLink reports whether a path is a symbolic link
+func isLink(fullPath string) bool {
+	stat, err := os.Lstat(fullPath)
+	return err == nil && stat.Mode()&fs.ModeSymlink != 0
+}
@@ -0,0 +1,313 @@
+package forms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// Sentinel errors returned by Manager operations. Use errors.Is to check for them.
+var (
+	ErrUnknownForm = errors.New("form is not configured")
+	ErrInvalidForm = errors.New("invalid form configuration")
+)
+
+// Form providers
+const (
+	ProviderFormspree = "formspree"
+	ProviderNetlify   = "netlify"
+	ProviderWebhook   = "webhook"
+)
+
+// defaultFields are the fields of a form that doesn't list any
+var defaultFields = []string{"name", "email", "message"}
+
+// Endpoint is the generated description of a form, read by templates from the forms data file
+type Endpoint struct {
+	Provider   string            `yaml:"provider" json:"provider"`
+	Action     string            `yaml:"action" json:"action"` // Value of the form's action attribute
+	Method     string            `yaml:"method" json:"method"`
+	Attributes map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"` // Extra attributes of the <form> tag
+	Hidden     map[string]string `yaml:"hidden,omitempty" json:"hidden,omitempty"`         // Hidden inputs, name to value
+	Fields     []string          `yaml:"fields" json:"fields"`
+	Honeypot   string            `yaml:"honeypot,omitempty" json:"honeypot,omitempty"` // Hidden input bots fill in
+	Redirect   string            `yaml:"redirect,omitempty" json:"redirect,omitempty"`
+}
+
+// GenerateResult describes a write of the forms data file
+type GenerateResult struct {
+	File    string              `json:"file"`
+	Changed bool                `json:"changed"`
+	Forms   map[string]Endpoint `json:"forms"`
+}
+
+// TestResult is the outcome of a test submission
+type TestResult struct {
+	Form       string `json:"form"`
+	Provider   string `json:"provider"`
+	URL        string `json:"url"`
+	Status     int    `json:"status,omitempty"`
+	OK         bool   `json:"ok"`
+	DurationMs int64  `json:"durationMs"`
+	Response   string `json:"response,omitempty"` // Start of the response body
+	Error      string `json:"error,omitempty"`
+}
+
+// Manager generates form endpoint configuration for templates and checks the endpoints work
+type Manager struct {
+	projectDir string
+	config     config.FormsConfig
+	client     *http.Client
+}
+
+// NewManager creates a new forms manager
+func NewManager(projectDir string, cfg config.FormsConfig) *Manager {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// dataFile returns the project-relative path of the forms data file
+func (m *Manager) dataFile() string {
+	if m.config.DataFile == "" {
+		return "data/forms.yaml"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.DataFile))
+}
+
+// baseURL returns Hugo's baseURL, or "" when the site config can't be read
+func (m *Manager) baseURL() string {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return ""
+	}
+	return siteCfg.BaseURL()
+}
+
+// form returns the configuration of the named form
+func (m *Manager) form(name string) (*config.FormConfig, error) {
+	for i := range m.config.Forms {
+		if m.config.Forms[i].Name == name {
+			return &m.config.Forms[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownForm, name)
+}
+
+// Endpoints returns the endpoint of every configured form, keyed by form name
+func (m *Manager) Endpoints() (map[string]Endpoint, error) {
+	baseURL := m.baseURL()
+	endpoints := map[string]Endpoint{}
+	for i := range m.config.Forms {
+		form := &m.config.Forms[i]
+		if form.Name == "" {
+			return nil, fmt.Errorf("%w: form %d has no name", ErrInvalidForm, i+1)
+		}
+		if _, ok := endpoints[form.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate form %s", ErrInvalidForm, form.Name)
+		}
+		endpoint, err := endpointFor(form, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		endpoints[form.Name] = *endpoint
+	}
+	return endpoints, nil
+}
+
+// endpointFor builds the endpoint of a form for its provider
+func endpointFor(form *config.FormConfig, baseURL string) (*Endpoint, error) {
+	endpoint := &Endpoint{
+		Provider: strings.ToLower(form.Provider),
+		Method:   http.MethodPost,
+		Fields:   form.Fields,
+		Honeypot: form.Honeypot,
+		Redirect: form.Redirect,
+	}
+	if len(endpoint.Fields) == 0 {
+		endpoint.Fields = defaultFields
+	}
+
+	switch endpoint.Provider {
+	case ProviderFormspree:
+		if form.ID == "" {
+			return nil, fmt.Errorf("%w: %s needs the id of the Formspree form", ErrInvalidForm, form.Name)
+		}
+		endpoint.Action = "https://formspree.io/f/" + url.PathEscape(form.ID)
+		endpoint.Hidden = map[string]string{}
+		if form.Redirect != "" {
+			// Formspree only redirects to absolute URLs
+			endpoint.Hidden["_next"] = absoluteURL(baseURL, form.Redirect)
+		}
+		if form.Honeypot != "" {
+			endpoint.Honeypot = "_gotcha" // The only honeypot Formspree recognizes
+		}
+	case ProviderNetlify:
+		// Netlify detects forms at deploy time by these attributes and shows the action page on success
+		endpoint.Action = form.Redirect
+		endpoint.Attributes = map[string]string{"name": form.Name, "data-netlify": "true"}
+		if form.Honeypot != "" {
+			endpoint.Attributes["netlify-honeypot"] = form.Honeypot
+		}
+		endpoint.Hidden = map[string]string{"form-name": form.Name}
+	case ProviderWebhook:
+		u, err := url.Parse(form.URL)
+		if form.URL == "" || err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%w: %s needs the http(s) url of the webhook", ErrInvalidForm, form.Name)
+		}
+		endpoint.Action = form.URL
+	default:
+		return nil, fmt.Errorf("%w: %s has unknown provider %q (use formspree, netlify or webhook)", ErrInvalidForm, form.Name, form.Provider)
+	}
+	return endpoint, nil
+}
+
+// Generate writes the endpoints of every configured form to the forms data file,
+// where templates read them as site.Data.forms.<name>
+func (m *Manager) Generate() (*GenerateResult, error) {
+	endpoints, err := m.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(m.dataFile()), ".json") {
+		data, err = json.MarshalIndent(endpoints, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(endpoints)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GenerateResult{File: m.dataFile(), Forms: endpoints}
+	path := filepath.Join(m.projectDir, filepath.FromSlash(m.dataFile()))
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == string(data) {
+		return result, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// Test posts a sample submission to the named form's endpoint, the way a visitor's browser would.
+// Netlify forms only exist on the deployed site, so they are submitted to Hugo's baseURL.
+func (m *Manager) Test(name string) (*TestResult, error) {
+	form, err := m.form(name)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := m.baseURL()
+	endpoint, err := endpointFor(form, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TestResult{Form: name, Provider: endpoint.Provider, URL: endpoint.Action}
+	if endpoint.Provider == ProviderNetlify {
+		if !deployed(baseURL) {
+			result.Error = "Netlify forms can only be tested on the deployed site; set Hugo's baseURL to its address"
+			return result, nil
+		}
+		result.URL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+	if !strings.Contains(result.URL, "://") {
+		if !deployed(baseURL) {
+			result.Error = "Relative endpoints can only be tested on the deployed site; set Hugo's baseURL to its address"
+			return result, nil
+		}
+		result.URL = absoluteURL(baseURL, result.URL)
+	}
+
+	values := url.Values{}
+	for k, v := range endpoint.Hidden {
+		values.Set(k, v)
+	}
+	for _, field := range endpoint.Fields {
+		values.Set(field, sampleValue(field))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, result.URL, strings.NewReader(values.Encode()))
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json") // Formspree answers JSON instead of its thank-you page
+	req.Header.Set("User-Agent", "hugo-manager")
+
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	result.Status = resp.StatusCode
+	result.Response = strings.TrimSpace(string(body))
+	result.OK = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !result.OK {
+		result.Error = fmt.Sprintf("Endpoint answered %s", resp.Status)
+	}
+	return result, nil
+}
+
+// sampleValue returns a test value for a form field
+func sampleValue(field string) string {
+	lower := strings.ToLower(field)
+	switch {
+	case strings.Contains(lower, "email"):
+		return "test@example.com"
+	case strings.Contains(lower, "name"):
+		return "hugo-manager"
+	default:
+		return "Test submission sent by hugo-manager. You can delete it."
+	}
+}
+
+// deployed reports whether baseURL points at a public site rather than a placeholder or local address
+func deployed(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := u.Hostname()
+	return host != "localhost" && host != "127.0.0.1" && host != "example.org" && host != "example.com"
+}
+
+// absoluteURL joins a site-relative path to baseURL, leaving absolute URLs untouched
+func absoluteURL(baseURL, rel string) string {
+	if rel == "" || strings.Contains(rel, "://") || baseURL == "" {
+		return rel
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(rel, "/")
+}
@@ -0,0 +1,295 @@
+package forms
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// Finding levels
+const (
+	LevelOK      = "ok"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Finding is a form found in a template or content file, or a problem with a configured form
+type Finding struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Form    string `json:"form,omitempty"` // Configured form the template uses
+	Action  string `json:"action,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// ScanReport lists the forms of the site and whether their endpoints can receive submissions
+type ScanReport struct {
+	FilesChecked int       `json:"filesChecked"`
+	Forms        int       `json:"forms"` // <form> tags found
+	Errors       int       `json:"errors"`
+	Warnings     int       `json:"warnings"`
+	Findings     []Finding `json:"findings"`
+}
+
+var (
+	// formTagRe matches <form> opening tags, allowing quoted values and template actions to contain '>'
+	formTagRe = regexp.MustCompile(`(?is)<form\b((?:[^>"'{]|"[^"]*"|'[^']*'|\{\{.*?\}\}|\{)*)>`)
+	attrRe    = regexp.MustCompile(`(?s)([a-zA-Z_:@][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	actionRe  = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	// formspreeRe extracts the form ID of a Formspree endpoint
+	formspreeRe = regexp.MustCompile(`(?i)^https?://(?:www\.)?formspree\.io/(?:f/)?([A-Za-z0-9]+)`)
+)
+
+// scanExts are the extensions of files that can contain forms
+var scanExts = map[string]bool{".html": true, ".htm": true, ".md": true}
+
+// Scan looks for forms in the project's layouts, local themes and content, reporting forms that post
+// nowhere, to the static site itself or to forms that aren't configured, and references to forms missing
+// from the data file. With probe, remote endpoints are requested and reported dead when they don't exist.
+func (m *Manager) Scan(probe bool) (*ScanReport, error) {
+	report := &ScanReport{Findings: []Finding{}}
+	used := map[string]bool{}
+	probed := map[string]Finding{}
+
+	refRe := m.referenceRe()
+	for _, root := range m.scanRoots() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() || !scanExts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(m.projectDir, path)
+			rel = filepath.ToSlash(rel)
+			report.FilesChecked++
+
+			text := string(data)
+			if refRe != nil {
+				for _, match := range refRe.FindAllStringSubmatchIndex(text, -1) {
+					var name string
+					if match[2] >= 0 {
+						name = text[match[2]:match[3]]
+					} else {
+						name = text[match[4]:match[5]]
+					}
+					used[name] = true
+					if _, err := m.form(name); err != nil {
+						report.Findings = append(report.Findings, Finding{File: rel, Line: lineOf(text, match[0]), Form: name, Level: LevelError,
+							Message: fmt.Sprintf("Template uses form %q, which isn't configured, so its action is empty", name)})
+					}
+				}
+			}
+
+			for _, match := range formTagRe.FindAllStringSubmatchIndex(text, -1) {
+				report.Forms++
+				finding := m.checkForm(text[match[2]:match[3]], probe, probed, used)
+				finding.File = rel
+				finding.Line = lineOf(text, match[0])
+				report.Findings = append(report.Findings, finding)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Configured forms no template uses
+	for _, form := range m.config.Forms {
+		if !used[form.Name] {
+			report.Findings = append(report.Findings, Finding{Form: form.Name, Level: LevelWarning, Message: "Configured but not used by any template"})
+		}
+	}
+	// Templates read the data file, so it must exist once they reference it
+	if len(used) > 0 && len(m.config.Forms) > 0 {
+		if _, err := os.Stat(filepath.Join(m.projectDir, filepath.FromSlash(m.dataFile()))); os.IsNotExist(err) {
+			report.Findings = append(report.Findings, Finding{File: m.dataFile(), Level: LevelError, Message: "Forms data file is missing; generate it"})
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, f := range report.Findings {
+		switch f.Level {
+		case LevelError:
+			report.Errors++
+		case LevelWarning:
+			report.Warnings++
+		}
+	}
+	return report, nil
+}
+
+// scanRoots returns the directories searched for forms
+func (m *Manager) scanRoots() []string {
+	roots := []string{filepath.Join(m.projectDir, "layouts")}
+	contentDir := "content"
+	if siteCfg, err := site.Load(m.projectDir); err == nil {
+		contentDir = siteCfg.ContentDir()
+		for _, theme := range siteCfg.Themes() {
+			roots = append(roots, filepath.Join(m.projectDir, "themes", theme, "layouts"))
+		}
+	}
+	return append(roots, filepath.Join(m.projectDir, filepath.FromSlash(contentDir)))
+}
+
+// referenceRe matches templates reading a form from the data file, as site.Data.forms.contact or
+// (index site.Data.forms "contact"). It's nil when the data file is outside the data directory.
+func (m *Manager) referenceRe() *regexp.Regexp {
+	rel, ok := strings.CutPrefix(m.dataFile(), "data/")
+	if !ok {
+		return nil
+	}
+	key := regexp.QuoteMeta(strings.ReplaceAll(strings.TrimSuffix(rel, filepath.Ext(rel)), "/", "."))
+	return regexp.MustCompile(`(?:\.Site|site)\.Data\.` + key + `(?:\.([A-Za-z0-9_]+)|\s+"([^"]+)")`)
+}
+
+// checkForm checks the endpoint of a <form> tag from its attributes
+func (m *Manager) checkForm(tag string, probe bool, probed map[string]Finding, used map[string]bool) Finding {
+	attrs := parseAttrs(tag)
+	action := strings.TrimSpace(attrs["action"])
+	finding := Finding{Action: action, Level: LevelOK}
+	_, netlify := attrs["netlify"]
+	if v, ok := attrs["data-netlify"]; ok && v != "false" {
+		netlify = true
+	}
+
+	switch {
+	case actionRe.MatchString(action) || (action == "" && actionRe.MatchString(tag)):
+		finding.Message = "Action is set by the template"
+		return finding
+	case netlify:
+		if attrs["name"] == "" {
+			finding.Level, finding.Message = LevelError, "Netlify forms need a name attribute to be detected"
+			return finding
+		}
+		finding.Form = m.formNamed(ProviderNetlify, attrs["name"], used)
+		finding.Message = "Netlify form " + attrs["name"]
+		if finding.Form == "" && len(m.config.Forms) > 0 {
+			finding.Level, finding.Message = LevelWarning, fmt.Sprintf("Netlify form %q isn't configured in forms", attrs["name"])
+		}
+		return finding
+	case action == "" || action == "#":
+		finding.Level, finding.Message = LevelError, "Form has no action, so submissions go nowhere"
+		return finding
+	case strings.HasPrefix(strings.ToLower(action), "mailto:"):
+		finding.Level, finding.Message = LevelWarning, "mailto: forms depend on the visitor's mail client and often send nothing"
+		return finding
+	}
+
+	if !strings.Contains(action, "://") && !strings.HasPrefix(action, "//") {
+		if form := m.formWithURL(action, used); form != "" {
+			finding.Form, finding.Message = form, "Self-hosted webhook "+form
+			return finding
+		}
+		finding.Level, finding.Message = LevelWarning, "Posts to the static site itself, which can't process submissions"
+		return finding
+	}
+
+	finding.Message = "External endpoint"
+	if match := formspreeRe.FindStringSubmatch(action); match != nil {
+		finding.Message = "Formspree form " + match[1]
+		if finding.Form = m.formNamed(ProviderFormspree, match[1], used); finding.Form == "" && len(m.config.Forms) > 0 {
+			finding.Level, finding.Message = LevelWarning, fmt.Sprintf("Formspree form %q isn't configured in forms", match[1])
+		}
+	} else if form := m.formWithURL(action, used); form != "" {
+		finding.Form, finding.Message = form, "Self-hosted webhook "+form
+	}
+	if method := strings.ToUpper(attrs["method"]); method != http.MethodPost {
+		finding.Level, finding.Message = LevelWarning, "Form is sent with GET (the default without method=\"post\"); form services only accept POST"
+	}
+
+	if probe {
+		if _, ok := probed[action]; !ok {
+			probed[action] = m.probe(action)
+		}
+		if result := probed[action]; result.Level == LevelError || (result.Level == LevelWarning && finding.Level == LevelOK) {
+			finding.Level, finding.Message = result.Level, result.Message
+		}
+	}
+	return finding
+}
+
+// formNamed returns the configured form of provider whose Netlify name or Formspree ID is id, marking it used
+func (m *Manager) formNamed(provider, id string, used map[string]bool) string {
+	for _, form := range m.config.Forms {
+		if !strings.EqualFold(form.Provider, provider) {
+			continue
+		}
+		if (provider == ProviderNetlify && form.Name == id) || (provider == ProviderFormspree && form.ID == id) {
+			used[form.Name] = true
+			return form.Name
+		}
+	}
+	return ""
+}
+
+// formWithURL returns the configured webhook form posting to action, marking it used
+func (m *Manager) formWithURL(action string, used map[string]bool) string {
+	for _, form := range m.config.Forms {
+		if strings.EqualFold(form.Provider, ProviderWebhook) && strings.TrimSuffix(form.URL, "/") == strings.TrimSuffix(action, "/") {
+			used[form.Name] = true
+			return form.Name
+		}
+	}
+	return ""
+}
+
+// probe requests an endpoint, returning the level and message of the problem found. Form services answer
+// GET requests to live endpoints with a page or 405, and unknown ones with 404 or 410. Network errors
+// are only warnings, as they may be on this side.
+func (m *Manager) probe(action string) Finding {
+	if strings.HasPrefix(action, "//") {
+		action = "https:" + action
+	}
+	req, err := http.NewRequest(http.MethodGet, action, nil)
+	if err != nil {
+		return Finding{Level: LevelError, Message: "Invalid endpoint: " + err.Error()}
+	}
+	req.Header.Set("User-Agent", "hugo-manager")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Finding{Level: LevelWarning, Message: "Endpoint unreachable: " + err.Error()}
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return Finding{Level: LevelError, Message: "Dead endpoint: " + resp.Status}
+	}
+	return Finding{Level: LevelOK}
+}
+
+// parseAttrs returns the attributes of a tag, with lower-cased names. Template actions are kept in values.
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, match := range attrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attrs
+}
+
+// lineOf returns the 1-based line of offset in text
+func lineOf(text string, offset int) int {
+	return strings.Count(text[:offset], "\n") + 1
+}
@@ -0,0 +1,328 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/structured"
+)
+
+// Points the build score loses per error and warning
+const (
+	buildErrorCost   = 25
+	buildWarningCost = 5
+)
+
+var (
+	tagRe     = regexp.MustCompile(`(?is)<(a|img|link|script|source)\b([^>]*)>`)
+	tagAttrRe = regexp.MustCompile(`(?s)([a-zA-Z_:@][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+)
+
+// linkAttrs is the attribute of each checked tag that references another file
+var linkAttrs = map[string]string{"a": "href", "img": "src", "link": "href", "script": "src", "source": "src"}
+
+// assetExts are the file types checked for size: images, styles, scripts and fonts
+var assetExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".svg": true,
+	".css": true, ".js": true, ".woff": true, ".woff2": true, ".ttf": true, ".otf": true,
+}
+
+// siteChecks are the checks made on the built site
+type siteChecks struct {
+	links   Check
+	altText Check
+}
+
+// siteWalk resolves the links of the built site's pages
+type siteWalk struct {
+	root     string
+	host     string
+	basePath string // Path of the baseURL, without the trailing slash
+	exists   map[string]bool
+}
+
+// checkSite reads the HTML of the built site for broken internal links and images without alt text
+func (m *Manager) checkSite() *siteChecks {
+	publishDir, baseURL := "public", ""
+	if siteCfg, err := site.Load(m.projectDir); err == nil {
+		publishDir = siteCfg.PublishDir()
+		baseURL = siteCfg.BaseURL()
+	}
+	w := &siteWalk{root: publishDir, exists: map[string]bool{}}
+	if !filepath.IsAbs(w.root) {
+		w.root = filepath.Join(m.projectDir, filepath.FromSlash(publishDir))
+	}
+	if u, err := url.Parse(baseURL); err == nil {
+		w.host = strings.ToLower(u.Hostname())
+		w.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	result := &siteChecks{links: Check{}, altText: Check{}}
+	if _, err := os.Stat(w.root); err != nil {
+		skip := Check{Skipped: true, Message: fmt.Sprintf("No built site in %s; build the site first", publishDir)}
+		result.links, result.altText = skip, skip
+		return result
+	}
+
+	err := filepath.WalkDir(w.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".html") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(w.root, p)
+		page := filepath.ToSlash(rel)
+
+		for _, match := range tagRe.FindAllStringSubmatch(string(data), -1) {
+			tag := strings.ToLower(match[1])
+			attrs := parseAttrs(match[2])
+			if tag == "img" {
+				result.altText.Checked++
+				if _, ok := attrs["alt"]; !ok && attrs["aria-hidden"] != "true" && attrs["role"] != "presentation" {
+					result.altText.Issues++
+					result.altText.example(page + ": " + attrs["src"])
+				}
+			}
+			if tag == "link" && !strings.Contains(attrs["rel"], "stylesheet") && !strings.Contains(attrs["rel"], "icon") {
+				continue // Canonical, alternate and feed links may point at pages of other sites or outputs
+			}
+			ref := attrs[linkAttrs[tag]]
+			target, ok := w.target(page, ref)
+			if !ok {
+				continue
+			}
+			result.links.Checked++
+			if !w.found(target) {
+				result.links.Issues++
+				result.links.example(page + ": " + ref)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		skip := Check{Skipped: true, Message: "Failed to read the built site: " + err.Error()}
+		result.links, result.altText = skip, skip
+		return result
+	}
+
+	result.links = ratio(result.links)
+	result.links.Message = fmt.Sprintf("%d of %d internal links are broken", result.links.Issues, result.links.Checked)
+	result.altText = ratio(result.altText)
+	result.altText.Message = fmt.Sprintf("%d of %d images have no alt text", result.altText.Issues, result.altText.Checked)
+	return result
+}
+
+// target returns the path in the built site an internal reference points to
+func (w *siteWalk) target(page, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Path == "" {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		if (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Hostname(), w.host) {
+			return "", false // External, or mailto:, tel: and the like
+		}
+	}
+
+	p := u.Path
+	if strings.HasPrefix(p, "/") {
+		if w.basePath != "" && (p == w.basePath || strings.HasPrefix(p, w.basePath+"/")) {
+			p = strings.TrimPrefix(p, w.basePath)
+		}
+	} else {
+		p = path.Join(path.Dir("/"+page), p)
+	}
+	return path.Clean("/" + p), true
+}
+
+// found reports whether a path of the built site exists, as a file or a directory with an index page
+func (w *siteWalk) found(target string) bool {
+	if ok, cached := w.exists[target]; cached {
+		return ok
+	}
+	full := filepath.Join(w.root, filepath.FromSlash(target))
+	ok := false
+	if stat, err := os.Stat(full); err == nil {
+		ok = !stat.IsDir()
+		if stat.IsDir() {
+			_, err := os.Stat(filepath.Join(full, "index.html"))
+			ok = err == nil
+		}
+	} else if _, err := os.Stat(full + ".html"); err == nil {
+		ok = true // uglyURLs
+	}
+	w.exists[target] = ok
+	return ok
+}
+
+// checkStale scores the share of drafts that are abandoned
+func (m *Manager) checkStale() Check {
+	if m.sources.Drafts == nil {
+		return Check{Skipped: true, Message: "Draft cleanup isn't available"}
+	}
+	list, err := m.sources.Drafts.Stale(drafts.Criteria{})
+	if err != nil {
+		return Check{Skipped: true, Message: err.Error()}
+	}
+
+	check := Check{Checked: list.Drafts, Issues: len(list.Stale)}
+	for _, d := range list.Stale {
+		check.example(d.Path)
+	}
+	check = ratio(check)
+	check.Message = fmt.Sprintf("%d of %d drafts are older than %d months and not edited for %d", check.Issues, check.Checked, list.MinAgeMonths, list.IdleMonths)
+	return check
+}
+
+// checkValidation scores content by shortcode lint errors and invalid structured data
+func (m *Manager) checkValidation() Check {
+	var check Check
+	var parts []string
+	if m.sources.Linter != nil {
+		if report, err := m.sources.Linter.LintAll(context.Background()); err == nil {
+			check.Checked += report.FilesChecked
+			check.Issues += report.Errors
+			for _, d := range report.Diagnostics {
+				if d.Severity == lint.SeverityError {
+					check.example(fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message))
+				}
+			}
+			parts = append(parts, fmt.Sprintf("%d shortcode errors in %d files", report.Errors, report.FilesChecked))
+		}
+	}
+	if m.sources.Structured != nil {
+		if report, err := m.sources.Structured.Report(); err == nil && report.Checked > 0 {
+			invalid := report.Checked - report.Valid
+			check.Checked += report.Checked
+			check.Issues += invalid
+			for _, page := range report.Pages {
+				for _, issue := range page.Issues {
+					if issue.Severity == structured.SeverityError {
+						check.example(fmt.Sprintf("%s: %s %s", page.Path, issue.Property, issue.Message))
+						break
+					}
+				}
+			}
+			parts = append(parts, fmt.Sprintf("%d of %d pages with invalid structured data", invalid, report.Checked))
+		}
+	}
+	if len(parts) == 0 {
+		return Check{Skipped: true, Message: "No content could be validated"}
+	}
+
+	check = ratio(check)
+	check.Message = strings.Join(parts, ", ")
+	return check
+}
+
+// checkBuild scores the errors and warnings of the current Hugo build
+func (m *Manager) checkBuild() Check {
+	if m.sources.Hugo == nil {
+		return Check{Skipped: true, Message: "Hugo isn't managed"}
+	}
+	buildErrors, warnings := m.sources.Hugo.BuildErrors(), m.sources.Hugo.BuildWarnings()
+	status, _ := m.sources.Hugo.GetStatus()
+	if status != hugo.StatusRunning && len(buildErrors)+len(warnings) == 0 {
+		return Check{Skipped: true, Message: "Hugo isn't running, so there's no current build"}
+	}
+
+	check := Check{Issues: len(buildErrors) + len(warnings)}
+	for _, events := range [][]hugo.LogEvent{buildErrors, warnings} {
+		for _, e := range events {
+			if e.File != "" {
+				check.example(fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message))
+			} else {
+				check.example(e.Message)
+			}
+		}
+	}
+	check.Score = max(0, 100-buildErrorCost*len(buildErrors)-buildWarningCost*len(warnings))
+	check.Message = fmt.Sprintf("%d errors and %d warnings in the current build", len(buildErrors), len(warnings))
+	return check
+}
+
+// checkAssets scores the share of images, styles, scripts and fonts over the size limit
+func (m *Manager) checkAssets() Check {
+	limit := int64(m.config.MaxAssetKB) * 1024
+	if limit <= 0 {
+		return Check{Skipped: true, Message: "No asset size limit configured"}
+	}
+
+	dirs := []string{"static", "assets"}
+	if siteCfg, err := site.Load(m.projectDir); err == nil {
+		for _, lang := range siteCfg.Languages() {
+			dirs = append(dirs, lang.ContentDir)
+		}
+	} else {
+		dirs = append(dirs, "content")
+	}
+
+	var check Check
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		root := filepath.Join(m.projectDir, filepath.FromSlash(dir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Missing directories have no assets
+			}
+			if strings.HasPrefix(d.Name(), ".") && p != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !assetExts[strings.ToLower(filepath.Ext(p))] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			check.Checked++
+			if info.Size() > limit {
+				check.Issues++
+				rel, _ := filepath.Rel(m.projectDir, p)
+				check.example(fmt.Sprintf("%s (%d KB)", filepath.ToSlash(rel), info.Size()/1024))
+			}
+			return nil
+		})
+	}
+
+	check = ratio(check)
+	check.Message = fmt.Sprintf("%d of %d assets are larger than %d KB", check.Issues, check.Checked, m.config.MaxAssetKB)
+	return check
+}
+
+// parseAttrs returns the attributes of a tag, with lowercase names
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, match := range tagAttrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attrs
+}
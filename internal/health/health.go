@@ -0,0 +1,274 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+	"github.com/fernandezvara/hugo-manager/internal/structured"
+)
+
+// Checks that make up the score
+const (
+	CheckLinks      = "links"      // Broken internal links in the built site
+	CheckAltText    = "alt_text"   // Images without alt text in the built site
+	CheckStale      = "stale"      // Abandoned drafts
+	CheckValidation = "validation" // Shortcode lint errors and invalid structured data
+	CheckBuild      = "build"      // Errors and warnings of the current Hugo build
+	CheckAssets     = "assets"     // Oversized static files, assets and page resources
+)
+
+// Checks lists every check in report order
+var Checks = []string{CheckLinks, CheckAltText, CheckStale, CheckValidation, CheckBuild, CheckAssets}
+
+// maxExamples is the number of items with issues listed per check
+const maxExamples = 10
+
+// historyFile is where daily scores are kept, inside the .hugo-manager directory
+const historyFile = "health.json"
+
+// Check is the outcome of one of the checks
+type Check struct {
+	Name     string   `json:"name"`
+	Weight   int      `json:"weight"`
+	Score    int      `json:"score"`   // 0-100
+	Checked  int      `json:"checked"` // Items checked
+	Issues   int      `json:"issues"`
+	Skipped  bool     `json:"skipped"` // Couldn't run or is disabled, so it's left out of the score
+	Message  string   `json:"message"`
+	Examples []string `json:"examples"` // Some of the items with issues
+}
+
+// Point is the score recorded for a day
+type Point struct {
+	Date   string         `json:"date"` // YYYY-MM-DD
+	Score  int            `json:"score"`
+	Checks map[string]int `json:"checks"` // Score per check that ran
+}
+
+// Trend is the change of the score against earlier days. Nil when there's no score that old.
+type Trend struct {
+	Day   *int `json:"day"`
+	Week  *int `json:"week"`
+	Month *int `json:"month"`
+}
+
+// Report is the project health score with the checks it's made of
+type Report struct {
+	Score   int       `json:"score"` // Weighted average of the checks that ran, 0-100
+	Time    time.Time `json:"time"`
+	Checks  []Check   `json:"checks"`
+	Trend   Trend     `json:"trend"`
+	History []Point   `json:"history"` // Oldest first, including today
+}
+
+// Sources are the managers whose checks the score aggregates
+type Sources struct {
+	Hugo       *hugo.Manager
+	Linter     *lint.ShortcodeLinter
+	Structured *structured.Manager
+	Drafts     *drafts.Manager
+}
+
+// Manager computes the project health score and keeps its history
+type Manager struct {
+	projectDir string
+	config     config.HealthConfig
+	sources    Sources
+	mu         sync.Mutex
+}
+
+// NewManager creates a new health score manager
+func NewManager(projectDir string, cfg config.HealthConfig, sources Sources) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		sources:    sources,
+	}
+}
+
+// Score runs every check, records today's score and returns it with its trend
+func (m *Manager) Score() (*Report, error) {
+	now := time.Now()
+	report := &Report{Time: now, Checks: make([]Check, 0, len(Checks))}
+
+	// Links and alt text come from one pass over the built site, made when either check is enabled
+	var site *siteChecks
+	fromSite := func(pick func(*siteChecks) Check) func() Check {
+		return func() Check {
+			if site == nil {
+				site = m.checkSite()
+			}
+			return pick(site)
+		}
+	}
+	run := map[string]func() Check{
+		CheckLinks:      fromSite(func(s *siteChecks) Check { return s.links }),
+		CheckAltText:    fromSite(func(s *siteChecks) Check { return s.altText }),
+		CheckStale:      m.checkStale,
+		CheckValidation: m.checkValidation,
+		CheckBuild:      m.checkBuild,
+		CheckAssets:     m.checkAssets,
+	}
+
+	var total, weights int
+	point := Point{Date: now.Format("2006-01-02"), Checks: map[string]int{}}
+	for _, name := range Checks {
+		weight := m.weight(name)
+		check := Check{Name: name, Skipped: true, Message: "Disabled", Examples: []string{}}
+		if weight > 0 {
+			check = run[name]()
+			check.Name = name
+			if check.Examples == nil {
+				check.Examples = []string{}
+			}
+		}
+		check.Weight = weight
+		if !check.Skipped {
+			total += check.Score * weight
+			weights += weight
+			point.Checks[name] = check.Score
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	report.Score = 100
+	if weights > 0 {
+		report.Score = int(math.Round(float64(total) / float64(weights)))
+	}
+	point.Score = report.Score
+
+	history, err := m.record(point)
+	if err != nil {
+		return nil, err
+	}
+	report.History = history
+	report.Trend = trend(history, point, now)
+	return report, nil
+}
+
+// Latest returns the last recorded score with its trend, without running the checks.
+// The point is nil when no score was recorded yet.
+func (m *Manager) Latest() (*Point, Trend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history, err := m.load()
+	if err != nil || len(history) == 0 {
+		return nil, Trend{}, err
+	}
+	last := history[len(history)-1]
+	day, err := time.ParseInLocation("2006-01-02", last.Date, time.Local)
+	if err != nil {
+		return &last, Trend{}, nil
+	}
+	return &last, trend(history[:len(history)-1], last, day), nil
+}
+
+// weight returns the configured weight of a check, 0 when it's disabled
+func (m *Manager) weight(name string) int {
+	w, ok := m.config.Weights[name]
+	if !ok {
+		return config.Default().Health.Weights[name]
+	}
+	return max(w, 0)
+}
+
+// record stores today's point, replacing an earlier one of the same day, and returns the kept history
+func (m *Manager) record(point Point) ([]Point, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history, _ := m.load() // Start over rather than failing on a damaged file
+
+	kept := history[:0]
+	for _, p := range history {
+		if p.Date != point.Date {
+			kept = append(kept, p)
+		}
+	}
+	history = append(kept, point)
+	sort.Slice(history, func(i, j int) bool { return history[i].Date < history[j].Date })
+	if days := m.config.HistoryDays; days > 0 && len(history) > days {
+		history = history[len(history)-days:]
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := m.historyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to save health history: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save health history: %w", err)
+	}
+	return history, nil
+}
+
+// load reads the recorded history. A missing file is an empty history.
+func (m *Manager) load() ([]Point, error) {
+	data, err := os.ReadFile(m.historyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []Point
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("invalid health history: %w", err)
+	}
+	return history, nil
+}
+
+// historyPath returns the file daily scores are kept in
+func (m *Manager) historyPath() string {
+	return filepath.Join(m.projectDir, storage.DirName, historyFile)
+}
+
+// trend compares today's point with the latest ones at least a day, a week and a month older
+func trend(history []Point, today Point, now time.Time) Trend {
+	var t Trend
+	since := func(days int) *int {
+		limit := now.AddDate(0, 0, -days).Format("2006-01-02")
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Date <= limit {
+				change := today.Score - history[i].Score
+				return &change
+			}
+		}
+		return nil
+	}
+	t.Day, t.Week, t.Month = since(1), since(7), since(30)
+	return t
+}
+
+// ratio scores a check by the share of checked items without issues
+func ratio(check Check) Check {
+	check.Score = 100
+	if check.Checked > 0 {
+		check.Score = int(math.Round(100 * (1 - float64(check.Issues)/float64(check.Checked))))
+	}
+	if check.Score < 0 {
+		check.Score = 0
+	}
+	return check
+}
+
+// example adds an item to the examples of a check
+func (c *Check) example(item string) {
+	if len(c.Examples) < maxExamples {
+		c.Examples = append(c.Examples, item)
+	}
+}
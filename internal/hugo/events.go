@@ -0,0 +1,142 @@
+package hugo
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of events parsed from Hugo output
+const (
+	EventError   = "error"   // Build error, located in a file when Hugo reports one
+	EventWarning = "warning" // WARN line
+	EventRebuild = "rebuild" // A change was detected and the site is being rebuilt
+	EventBuilt   = "built"   // A build or rebuild finished
+)
+
+// LogEvent is a typed event parsed from a line of Hugo output
+type LogEvent struct {
+	Kind     string   `json:"kind"`
+	Message  string   `json:"message,omitempty"`    // Line without its level and timestamp
+	File     string   `json:"file,omitempty"`       // Project-relative when the file is in the project
+	Line     int      `json:"line,omitempty"`       // 1-based, 0 when unknown
+	Column   int      `json:"column,omitempty"`     // 1-based, 0 when unknown
+	Duration float64  `json:"durationMs,omitempty"` // Build time of a built event
+	Changed  []string `json:"changed,omitempty"`    // Files that triggered the rebuild
+}
+
+var (
+	// levelPrefixRe matches the level and optional timestamp Hugo puts before errors and warnings
+	levelPrefixRe = regexp.MustCompile(`^(?:ERROR|WARN|Error:|WARNING:?)\s*(?:\d{4}/\d\d/\d\d \d\d:\d\d:\d\d\s+)?`)
+	// locationRe matches file:line[:column] positions, optionally quoted, as in "/site/layouts/index.html:12:5"
+	locationRe = regexp.MustCompile(`"?((?:[A-Za-z]:)?[^\s"':]*[^\s"':/]\.[A-Za-z][A-Za-z0-9]*):(\d+)(?::(\d+))?"?`)
+	// builtRe matches the duration Hugo prints when a build or rebuild finishes
+	builtRe = regexp.MustCompile(`^(?:Built|Total|Rebuilt) in (\d+(?:\.\d+)?)\s*(µs|us|ms|s)\b`)
+	// changedRe matches the file reported by "Source changed" and "Template changed" lines, with an optional operation
+	changedRe = regexp.MustCompile(`^(?:Source|Template) changed:?\s+("[^"]+"|\S+)`)
+)
+
+// parseEvent turns a line of Hugo output into a typed event, updating the current build errors
+// and the files changed in the rebuild in progress. Lines without an event return nil. Callers must hold logMu.
+func (m *Manager) parseEvent(message, logType, level string) *LogEvent {
+	if logType == "system" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(message)
+
+	if match := changedRe.FindStringSubmatch(trimmed); match != nil {
+		file := match[1]
+		if unquoted, err := strconv.Unquote(file); err == nil {
+			file = unquoted
+		}
+		m.changed = append(m.changed, m.relativePath(file))
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "Change detected"):
+		m.buildErrors = nil
+		m.buildWarnings = nil
+		m.changed = nil
+		return &LogEvent{Kind: EventRebuild, Message: trimmed}
+
+	case builtRe.MatchString(trimmed):
+		match := builtRe.FindStringSubmatch(trimmed)
+		value, _ := strconv.ParseFloat(match[1], 64)
+		unit := map[string]time.Duration{"µs": time.Microsecond, "us": time.Microsecond, "ms": time.Millisecond, "s": time.Second}[match[2]]
+		event := &LogEvent{
+			Kind:     EventBuilt,
+			Message:  trimmed,
+			Duration: value * float64(unit) / float64(time.Millisecond),
+			Changed:  m.changed,
+		}
+		m.changed = nil
+		return event
+
+	case level == LevelError && trimmed != "":
+		event := m.located(EventError, trimmed)
+		for _, existing := range m.buildErrors {
+			// Hugo repeats the first error when it exits ("Error: error building site: ...")
+			if existing.File == event.File && existing.Line == event.Line && (event.File != "" || existing.Message == event.Message) {
+				return event
+			}
+		}
+		m.buildErrors = append(m.buildErrors, *event)
+		return event
+
+	case level == LevelWarn:
+		event := m.located(EventWarning, trimmed)
+		m.buildWarnings = append(m.buildWarnings, *event)
+		return event
+	}
+	return nil
+}
+
+// located builds an event for an error or warning line, taking the first file position it mentions
+func (m *Manager) located(kind, line string) *LogEvent {
+	event := &LogEvent{Kind: kind, Message: levelPrefixRe.ReplaceAllString(line, "")}
+	if match := locationRe.FindStringSubmatch(event.Message); match != nil {
+		event.File = m.relativePath(match[1])
+		event.Line, _ = strconv.Atoi(match[2])
+		event.Column, _ = strconv.Atoi(match[3])
+	}
+	return event
+}
+
+// relativePath makes absolute paths inside the project relative to it, with forward slashes
+func (m *Manager) relativePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	root, err := filepath.Abs(m.projectDir)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// BuildErrors returns the errors of the current build: those reported since the last detected change,
+// or since Hugo started when it hasn't rebuilt yet
+func (m *Manager) BuildErrors() []LogEvent {
+	m.logMu.RLock()
+	defer m.logMu.RUnlock()
+
+	result := make([]LogEvent, len(m.buildErrors))
+	copy(result, m.buildErrors)
+	return result
+}
+
+// BuildWarnings returns the warnings of the current build, reset like the errors
+func (m *Manager) BuildWarnings() []LogEvent {
+	m.logMu.RLock()
+	defer m.logMu.RUnlock()
+
+	result := make([]LogEvent, len(m.buildWarnings))
+	copy(result, m.buildWarnings)
+	return result
+}
@@ -0,0 +1,93 @@
+package hugo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// MainInstance is the name of the Hugo server of hugo's own settings, which the instances of
+// hugo.instances run beside
+const MainInstance = "main"
+
+// ErrUnknownInstance is returned for an instance that isn't in hugo.instances
+var ErrUnknownInstance = errors.New("unknown Hugo instance")
+
+// Instance describes one of the Hugo servers of the project
+type Instance struct {
+	Name     string         `json:"name"`
+	Status   Status         `json:"status"`
+	Message  string         `json:"message,omitempty"`
+	Port     int            `json:"port"` // The port it runs on, or the configured one while stopped
+	Options  Options        `json:"options"`
+	Logs     LogUsage       `json:"logs"`
+	Watchdog WatchdogStatus `json:"watchdog"`
+}
+
+// newInstances creates the managers of hugo.instances. Each gets its own port, content flags and
+// profile, and shares the rest of the settings of the main server.
+func newInstances(projectDir string, cfg config.HugoConfig) []*Manager {
+	instances := make([]*Manager, 0, len(cfg.Instances))
+	for _, inst := range cfg.Instances {
+		instCfg := cfg
+		instCfg.Instances = nil
+		instCfg.Port = inst.Port
+		instCfg.AutoStart = inst.AutoStart
+		instCfg.BuildDrafts = inst.BuildDrafts
+		instCfg.BuildFuture = inst.BuildFuture
+		instCfg.BuildExpired = inst.BuildExpired
+		instCfg.Profile = inst.Profile
+
+		m := NewManager(projectDir, instCfg)
+		m.name = inst.Name
+		instances = append(instances, m)
+	}
+	return instances
+}
+
+// Name returns the name of the instance the manager runs
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// Instance returns the manager of a named instance, or the main one for an empty name or main
+func (m *Manager) Instance(name string) (*Manager, error) {
+	if name == "" || name == m.name {
+		return m, nil
+	}
+	for _, inst := range m.instances {
+		if inst.name == name {
+			return inst, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownInstance, name)
+}
+
+// Instances returns the managers of every instance, the main one first
+func (m *Manager) Instances() []*Manager {
+	return append([]*Manager{m}, m.instances...)
+}
+
+// Describe returns the state of the instance
+func (m *Manager) Describe() Instance {
+	status, msg := m.GetStatus()
+	return Instance{
+		Name:     m.name,
+		Status:   status,
+		Message:  msg,
+		Port:     m.GetPort(),
+		Options:  m.GetOptions(),
+		Logs:     m.GetLogUsage(),
+		Watchdog: m.GetWatchdogStatus(),
+	}
+}
+
+// instanceTag returns the name logs and status events of the instance carry, empty for the main
+// one so its events look as they did before there were instances
+func (m *Manager) instanceTag() string {
+	if m.name == MainInstance {
+		return ""
+	}
+	return m.name
+}
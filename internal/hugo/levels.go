@@ -0,0 +1,45 @@
+package hugo
+
+import "strings"
+
+// Log levels assigned to Hugo output
+const (
+	LevelError      = "error"
+	LevelWarn       = "warn"
+	LevelInfo       = "info"
+	LevelRebuild    = "rebuild"
+	LevelLiveReload = "livereload"
+)
+
+// classify assigns a level to a line of Hugo output
+func classify(message, logType string) string {
+	trimmed := strings.TrimSpace(message)
+	lower := strings.ToLower(trimmed)
+
+	switch {
+	case strings.HasPrefix(trimmed, "ERROR") || strings.HasPrefix(trimmed, "Error: ") ||
+		strings.Contains(lower, "rebuild failed") || strings.Contains(lower, "error building site"):
+		return LevelError
+	case strings.HasPrefix(trimmed, "WARN") || strings.HasPrefix(lower, "warning"):
+		return LevelWarn
+	case strings.HasPrefix(trimmed, "Change detected") || strings.HasPrefix(trimmed, "Source changed") ||
+		strings.HasPrefix(trimmed, "Total in ") || strings.HasPrefix(trimmed, "Rebuilt in ") ||
+		strings.HasPrefix(trimmed, "Template changed") || strings.HasPrefix(trimmed, "Syncing "):
+		return LevelRebuild
+	case strings.Contains(lower, "livereload") || strings.HasPrefix(trimmed, "Reload ") ||
+		strings.Contains(lower, "fast render mode"):
+		return LevelLiveReload
+	case logType == "stderr" && trimmed != "":
+		return LevelError
+	}
+	return LevelInfo
+}
+
+// IsLevel reports whether level is a known log level
+func IsLevel(level string) bool {
+	switch level {
+	case LevelError, LevelWarn, LevelInfo, LevelRebuild, LevelLiveReload:
+		return true
+	}
+	return false
+}
@@ -2,13 +2,21 @@ package hugo
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/ports"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
 )
 
 // Status represents the Hugo server status
@@ -21,36 +29,101 @@ const (
 	StatusError    Status = "error"
 )
 
+var (
+	// ErrRunning is returned when starting the Hugo server while it runs
+	ErrRunning = errors.New("Hugo is already running")
+
+	// ErrNotRunning is returned when stopping the Hugo server while it's stopped
+	ErrNotRunning = errors.New("Hugo is not running")
+)
+
 // Manager handles the Hugo server process
 type Manager struct {
-	projectDir  string
-	config      config.HugoConfig
-	cmd         *exec.Cmd
-	status      Status
-	statusMsg   string
-	logs        []LogEntry
-	logMu       sync.RWMutex
-	statusMu    sync.RWMutex
-	subscribers []chan LogEntry
-	subMu       sync.RWMutex
-	maxLogs     int
+	name          string     // MainInstance, or the name of one of hugo.instances
+	instances     []*Manager // Those of hugo.instances, on the main instance only
+	projectDir    string
+	config        config.HugoConfig
+	args          []string // Additional args without the content flags, which options decide
+	options       Options
+	buildMu       sync.Mutex // Serializes site builds
+	cmd           *exec.Cmd
+	status        Status
+	statusMsg     string
+	port          int           // Port of the running server, which may differ from the configured one
+	done          chan struct{} // Closed when the running process exits
+	stopping      bool          // Set by Stop so the exit isn't reported as an error
+	watchdog      watchdog
+	logs          []LogEntry
+	logMu         sync.RWMutex
+	statusMu      sync.RWMutex
+	subscribers   []chan LogEntry
+	statusSubs    []chan StatusEvent
+	subMu         sync.RWMutex
+	maxLogs       int
+	retention     time.Duration
+	dropped       int
+	truncated     int
+	onBuild       func(BuildEvent)
+	buildFailed   bool
+	buildErrors   []LogEvent // Errors of the current build
+	buildWarnings []LogEvent // Warnings of the current build
+	changed       []string   // Files changed in the rebuild in progress
+}
+
+// BuildEvent reports the outcome of a Hugo build or rebuild
+type BuildEvent struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
 }
 
 // LogEntry represents a single log entry
 type LogEntry struct {
-	Time    time.Time `json:"time"`
-	Message string    `json:"message"`
-	Type    string    `json:"type"` // "stdout", "stderr", "system"
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message"`
+	Type     string    `json:"type"`  // "stdout", "stderr", "system"
+	Level    string    `json:"level"` // "error", "warn", "info", "rebuild", "livereload"
+	Event    *LogEvent `json:"event,omitempty"`
+	Instance string    `json:"instance,omitempty"` // Unset for the main instance
+}
+
+// LogUsage describes the state of the in-memory log buffer
+type LogUsage struct {
+	Entries   int `json:"entries"`
+	Capacity  int `json:"capacity"`
+	Retention int `json:"retention"` // Minutes, 0 = keep until evicted
+	Dropped   int `json:"dropped"`   // Entries evicted by capacity or retention
+	Truncated int `json:"truncated"` // Lines shortened to max_line_length
 }
 
 // NewManager creates a new Hugo manager
 func NewManager(projectDir string, cfg config.HugoConfig) *Manager {
+	maxLogs := cfg.MaxLogs
+	if maxLogs <= 0 {
+		maxLogs = 1000
+	}
+
+	args, options := splitOptions(cfg.AdditionalArgs, Options{
+		BuildDrafts:  cfg.BuildDrafts,
+		BuildFuture:  cfg.BuildFuture,
+		BuildExpired: cfg.BuildExpired,
+		Profile:      cfg.Profile,
+	})
+	if _, err := findProfile(cfg.Profiles, cfg.Profile); cfg.Profile != "" && err != nil {
+		slog.Warn("Unknown Hugo profile, using Hugo's defaults", "profile", cfg.Profile)
+		options.Profile = ""
+	}
+
 	return &Manager{
+		name:       MainInstance,
+		instances:  newInstances(projectDir, cfg),
 		projectDir: projectDir,
 		config:     cfg,
+		args:       args,
+		options:    options,
 		status:     StatusStopped,
-		logs:       make([]LogEntry, 0, 1000),
-		maxLogs:    1000,
+		logs:       make([]LogEntry, 0, maxLogs),
+		maxLogs:    maxLogs,
+		retention:  time.Duration(cfg.LogRetention) * time.Minute,
 	}
 }
 
@@ -59,26 +132,53 @@ func (m *Manager) Start() error {
 	m.statusMu.Lock()
 	if m.status == StatusRunning || m.status == StatusStarting {
 		m.statusMu.Unlock()
-		return fmt.Errorf("Hugo is already running")
+		return ErrRunning
 	}
 	m.status = StatusStarting
 	m.statusMsg = "Starting Hugo server..."
 	m.statusMu.Unlock()
+	m.broadcastStatus(StatusEvent{Type: "status", Time: time.Now(), Status: StatusStarting, Message: "Starting Hugo server..."})
 
+	m.logMu.Lock()
+	m.buildErrors = nil
+	m.buildWarnings = nil
+	m.changed = nil
+	m.logMu.Unlock()
 	m.addLog("Starting Hugo server...", "system")
 
+	// Fall back to the next free port if the configured one is taken
+	port, err := ports.Free(bindAddress(m.config.AdditionalArgs), m.config.Port, m.config.PortRange)
+	if err != nil {
+		m.addLog(fmt.Sprintf("Cannot start Hugo: %v", err), "system")
+		m.setStatus(StatusError, err.Error())
+		return err
+	}
+	if port != m.config.Port {
+		m.addLog(fmt.Sprintf("Port %d is in use, using port %d", m.config.Port, port), "system")
+		slog.Warn("Hugo port in use, falling back", "instance", m.name, "configured", m.config.Port, "port", port)
+	}
+	m.statusMu.Lock()
+	m.port = port
+	m.statusMu.Unlock()
+
 	// Build Hugo command
 	args := []string{"server"}
-	args = append(args, "--port", fmt.Sprintf("%d", m.config.Port))
+	args = append(args, "--port", fmt.Sprintf("%d", port))
 
 	if m.config.DisableFastRender {
 		args = append(args, "--disableFastRender")
 	}
 
-	args = append(args, m.config.AdditionalArgs...)
+	options := m.GetOptions()
+	args = append(args, options.args()...)
+	if profile, err := m.profile(options.Profile); err == nil {
+		args = append(args, profile.args()...)
+	}
+	args = append(args, m.args...)
 
 	m.cmd = exec.Command("hugo", args...)
 	m.cmd.Dir = m.projectDir
+	setProcessGroup(m.cmd) // So Stop reaches the processes Hugo spawns too
 
 	// Get stdout and stderr pipes
 	stdout, err := m.cmd.StdoutPipe()
@@ -99,49 +199,66 @@ func (m *Manager) Start() error {
 		return err
 	}
 
+	cmd := m.cmd
+	done := make(chan struct{})
+	m.statusMu.Lock()
+	m.done = done
+	m.stopping = false
+	m.statusMu.Unlock()
+
+	if err := m.writePidfile(cmd.Process.Pid); err != nil {
+		slog.Warn("Failed to write Hugo pidfile", "instance", m.name, "error", err)
+	}
+
 	// Monitor stdout
 	go m.streamLogs(stdout, "stdout")
 	go m.streamLogs(stderr, "stderr")
 
 	// Monitor process
 	go func() {
-		err := m.cmd.Wait()
-		if err != nil {
-			m.addLog(fmt.Sprintf("Hugo exited with error: %v", err), "system")
-			m.setStatus(StatusError, err.Error())
-		} else {
+		err := cmd.Wait()
+		m.removePidfile()
+
+		m.statusMu.RLock()
+		stopping := m.stopping
+		m.statusMu.RUnlock()
+
+		switch {
+		case stopping:
 			m.addLog("Hugo server stopped", "system")
 			m.setStatus(StatusStopped, "")
+		case err != nil:
+			m.addLog(fmt.Sprintf("Hugo exited with error: %v", err), "system")
+			m.setStatus(StatusError, err.Error())
+		default:
+			m.addLog("Hugo exited unexpectedly", "system")
+			m.setStatus(StatusError, "Hugo exited unexpectedly")
 		}
+		close(done)
 	}()
 
-	// Give Hugo a moment to start, then update status
-	go func() {
-		time.Sleep(2 * time.Second)
-		m.statusMu.Lock()
-		if m.status == StatusStarting {
-			m.status = StatusRunning
-			m.statusMsg = fmt.Sprintf("Running on port %d", m.config.Port)
-		}
-		m.statusMu.Unlock()
-	}()
+	// Hugo is running once it accepts connections or reports its address, whichever comes first
+	go m.waitReady(port, done)
 
 	return nil
 }
 
-// Stop stops the Hugo server
+// Stop stops the Hugo server and its child processes. Hugo is asked to exit first and killed
+// if it's still running after the stop timeout.
 func (m *Manager) Stop() error {
 	m.statusMu.Lock()
 	if m.status == StatusStopped {
 		m.statusMu.Unlock()
-		return fmt.Errorf("Hugo is not running")
+		return ErrNotRunning
 	}
+	m.stopping = true
+	cmd, done := m.cmd, m.done
 	m.statusMu.Unlock()
 
 	m.addLog("Stopping Hugo server...", "system")
 
-	if m.cmd != nil && m.cmd.Process != nil {
-		if err := m.cmd.Process.Kill(); err != nil {
+	if cmd != nil && cmd.Process != nil && done != nil {
+		if err := m.terminate(cmd.Process.Pid, done); err != nil {
 			m.addLog(fmt.Sprintf("Error stopping Hugo: %v", err), "system")
 			return err
 		}
@@ -151,6 +268,98 @@ func (m *Manager) Stop() error {
 	return nil
 }
 
+// stopTimeout returns how long Hugo is given to exit before it's killed
+func (m *Manager) stopTimeout() time.Duration {
+	if m.config.StopTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(m.config.StopTimeout) * time.Second
+}
+
+// terminate signals the process group of pid to exit, killing it after the stop timeout.
+// exited reports when the process is gone.
+func (m *Manager) terminate(pid int, exited <-chan struct{}) error {
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+
+	if err := terminateGroup(pid); err != nil {
+		m.addLog(fmt.Sprintf("Failed to signal Hugo (%v), killing it", err), "system")
+	} else {
+		select {
+		case <-exited:
+			return nil
+		case <-time.After(m.stopTimeout()):
+			m.addLog(fmt.Sprintf("Hugo did not exit within %s, killing it", m.stopTimeout()), "system")
+		}
+	}
+
+	if err := killGroup(pid); err != nil {
+		return err
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("Hugo (pid %d) did not exit after being killed", pid)
+	}
+}
+
+// pidfile returns the path recording the pid of the running Hugo server, one per instance
+func (m *Manager) pidfile() string {
+	if m.name != MainInstance {
+		return filepath.Join(m.projectDir, storage.DirName, "hugo-"+m.name+".pid")
+	}
+	return filepath.Join(m.projectDir, storage.DirName, "hugo.pid")
+}
+
+func (m *Manager) writePidfile(pid int) error {
+	if err := os.MkdirAll(filepath.Dir(m.pidfile()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.pidfile(), []byte(strconv.Itoa(pid)+"\n"), 0644)
+}
+
+func (m *Manager) removePidfile() {
+	os.Remove(m.pidfile())
+}
+
+// CleanupOrphan stops a Hugo server left running by a previous hugo-manager that exited without
+// stopping it, as recorded in the pidfile. It returns the pid of the stopped process, or 0.
+func (m *Manager) CleanupOrphan() (int, error) {
+	data, err := os.ReadFile(m.pidfile())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer m.removePidfile()
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || !processAlive(pid) {
+		return 0, nil
+	}
+	// The pid may have been reused by an unrelated process since the pidfile was written
+	if name := strings.TrimSuffix(strings.ToLower(processName(pid)), ".exe"); name != "hugo" {
+		return 0, nil
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		for processAlive(pid) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		close(exited)
+	}()
+	if err := m.terminate(pid, exited); err != nil {
+		return 0, err
+	}
+	return pid, nil
+}
+
 // Restart restarts the Hugo server
 func (m *Manager) Restart() error {
 	m.addLog("Restarting Hugo server...", "system")
@@ -159,8 +368,6 @@ func (m *Manager) Restart() error {
 		if err := m.Stop(); err != nil {
 			return err
 		}
-		// Wait for process to fully stop
-		time.Sleep(500 * time.Millisecond)
 	}
 
 	return m.Start()
@@ -175,23 +382,65 @@ func (m *Manager) GetStatus() (Status, string) {
 
 // GetLogs returns the recent logs
 func (m *Manager) GetLogs(limit int) []LogEntry {
+	return m.GetLogsByLevel(limit, nil)
+}
+
+// GetLogsByLevel returns the recent logs with one of the given levels (all levels if empty)
+func (m *Manager) GetLogsByLevel(limit int, levels []string) []LogEntry {
 	m.logMu.RLock()
 	defer m.logMu.RUnlock()
 
-	if limit <= 0 || limit > len(m.logs) {
-		limit = len(m.logs)
+	logs := m.logs[m.firstRetained(time.Now()):]
+	if len(levels) > 0 {
+		filtered := make([]LogEntry, 0, len(logs))
+		for _, entry := range logs {
+			for _, level := range levels {
+				if entry.Level == level {
+					filtered = append(filtered, entry)
+					break
+				}
+			}
+		}
+		logs = filtered
 	}
 
-	start := len(m.logs) - limit
-	if start < 0 {
-		start = 0
+	if limit <= 0 || limit > len(logs) {
+		limit = len(logs)
 	}
 
 	result := make([]LogEntry, limit)
-	copy(result, m.logs[start:])
+	copy(result, logs[len(logs)-limit:])
 	return result
 }
 
+// GetLogUsage returns the current log buffer usage
+func (m *Manager) GetLogUsage() LogUsage {
+	m.logMu.RLock()
+	defer m.logMu.RUnlock()
+
+	return LogUsage{
+		Entries:   len(m.logs) - m.firstRetained(time.Now()),
+		Capacity:  m.maxLogs,
+		Retention: m.config.LogRetention,
+		Dropped:   m.dropped,
+		Truncated: m.truncated,
+	}
+}
+
+// firstRetained returns the index of the oldest log entry within the retention window.
+// Callers must hold logMu.
+func (m *Manager) firstRetained(now time.Time) int {
+	if m.retention <= 0 {
+		return 0
+	}
+	cutoff := now.Add(-m.retention)
+	i := 0
+	for i < len(m.logs) && m.logs[i].Time.Before(cutoff) {
+		i++
+	}
+	return i
+}
+
 // Subscribe creates a new log subscription channel
 func (m *Manager) Subscribe() chan LogEntry {
 	ch := make(chan LogEntry, 100)
@@ -215,28 +464,108 @@ func (m *Manager) Unsubscribe(ch chan LogEntry) {
 	}
 }
 
-// GetPort returns the Hugo server port
+// SubscribeStatus creates a new subscription to status transitions
+func (m *Manager) SubscribeStatus() chan StatusEvent {
+	ch := make(chan StatusEvent, 10)
+	m.subMu.Lock()
+	m.statusSubs = append(m.statusSubs, ch)
+	m.subMu.Unlock()
+	return ch
+}
+
+// UnsubscribeStatus removes a status subscription channel
+func (m *Manager) UnsubscribeStatus(ch chan StatusEvent) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	for i, sub := range m.statusSubs {
+		if sub == ch {
+			m.statusSubs = append(m.statusSubs[:i], m.statusSubs[i+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
+
+func (m *Manager) broadcastStatus(event StatusEvent) {
+	event.Instance = m.instanceTag()
+	m.subMu.RLock()
+	for _, ch := range m.statusSubs {
+		select {
+		case ch <- event:
+		default:
+			// Skip if channel is full
+		}
+	}
+	m.subMu.RUnlock()
+}
+
+// OnBuild registers a callback invoked when Hugo finishes a build or a build fails.
+// It must be called before Start.
+func (m *Manager) OnBuild(fn func(BuildEvent)) {
+	m.onBuild = fn
+}
+
+// GetPort returns the port Hugo runs on, or the configured port if it hasn't been started
 func (m *Manager) GetPort() int {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	if m.port != 0 {
+		return m.port
+	}
+	return m.config.Port
+}
+
+// GetConfiguredPort returns the port set in configuration
+func (m *Manager) GetConfiguredPort() int {
 	return m.config.Port
 }
 
+// bindAddress returns the interface Hugo listens on, taken from a --bind argument
+func bindAddress(args []string) string {
+	for i, arg := range args {
+		if arg == "--bind" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, "--bind=") {
+			return strings.TrimPrefix(arg, "--bind=")
+		}
+	}
+	return "127.0.0.1" // Hugo's default
+}
+
+// setStatus changes the status and notifies status subscribers of the transition
 func (m *Manager) setStatus(status Status, msg string) {
 	m.statusMu.Lock()
+	changed := m.status != status || m.statusMsg != msg
 	m.status = status
 	m.statusMsg = msg
+	port := m.port
 	m.statusMu.Unlock()
+
+	if changed {
+		m.broadcastStatus(StatusEvent{Type: "status", Time: time.Now(), Status: status, Message: msg, Port: port})
+	}
 }
 
 func (m *Manager) addLog(message, logType string) {
 	entry := LogEntry{
-		Time:    time.Now(),
-		Message: message,
-		Type:    logType,
+		Time:     time.Now(),
+		Message:  message,
+		Type:     logType,
+		Level:    classify(message, logType),
+		Instance: m.instanceTag(),
 	}
 
 	m.logMu.Lock()
+	entry.Event = m.parseEvent(message, logType, entry.Level)
+	if expired := m.firstRetained(entry.Time); expired > 0 {
+		m.logs = m.logs[expired:]
+		m.dropped += expired
+	}
 	m.logs = append(m.logs, entry)
 	if len(m.logs) > m.maxLogs {
+		m.dropped += len(m.logs) - m.maxLogs
 		m.logs = m.logs[len(m.logs)-m.maxLogs:]
 	}
 	m.logMu.Unlock()
@@ -254,18 +583,83 @@ func (m *Manager) addLog(message, logType string) {
 }
 
 func (m *Manager) streamLogs(reader io.Reader, logType string) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text()
+	br := bufio.NewReader(reader)
+	for {
+		line, err := m.readLine(br)
+		if err != nil {
+			return
+		}
 		m.addLog(line, logType)
 
 		// Detect successful startup
-		if logType == "stdout" && (contains(line, "Web Server is available") || contains(line, "Serving pages from")) {
-			m.setStatus(StatusRunning, fmt.Sprintf("Running on port %d", m.config.Port))
+		if logType == "stdout" && contains(line, "Web Server is available") {
+			m.serverAvailable(line)
+		}
+
+		m.detectBuild(line, classify(line, logType))
+	}
+}
+
+// detectBuild reports build results from Hugo's output. Only the first error of a build is reported.
+func (m *Manager) detectBuild(line, level string) {
+	if m.onBuild == nil {
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(line, "Built in ") || strings.HasPrefix(line, "Total in "):
+		m.logMu.Lock()
+		m.buildFailed = false
+		m.logMu.Unlock()
+		m.onBuild(BuildEvent{Success: true, Message: line})
+
+	case strings.HasPrefix(line, "Change detected"):
+		m.logMu.Lock()
+		m.buildFailed = false
+		m.logMu.Unlock()
+
+	case level == LevelError:
+		m.logMu.Lock()
+		report := !m.buildFailed
+		m.buildFailed = true
+		m.logMu.Unlock()
+		if report {
+			m.onBuild(BuildEvent{Success: false, Message: line})
 		}
 	}
 }
 
+// readLine reads a full line, keeping at most MaxLineLength bytes of it
+func (m *Manager) readLine(br *bufio.Reader) (string, error) {
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := br.ReadLine()
+		if err != nil {
+			if len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+		if limit := m.config.MaxLineLength; limit > 0 && len(line)+len(chunk) > limit {
+			chunk = chunk[:max(limit-len(line), 0)]
+			truncated = true
+		}
+		line = append(line, chunk...)
+		if !isPrefix {
+			break
+		}
+	}
+
+	if truncated {
+		m.logMu.Lock()
+		m.truncated++
+		m.logMu.Unlock()
+		return string(line) + " … [truncated]", nil
+	}
+	return string(line), nil
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsAt(s, substr))
 }
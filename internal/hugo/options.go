@@ -0,0 +1,111 @@
+package hugo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Options are the content flags and profile Hugo is started with, which can be changed at runtime
+type Options struct {
+	BuildDrafts  bool   `json:"buildDrafts"`
+	BuildFuture  bool   `json:"buildFuture"`
+	BuildExpired bool   `json:"buildExpired"`
+	Profile      string `json:"profile"` // Environment and baseURL profile (empty = Hugo's defaults)
+}
+
+// OptionsUpdate changes some of the options. Nil fields keep their current value.
+type OptionsUpdate struct {
+	BuildDrafts  *bool   `json:"buildDrafts,omitempty"`
+	BuildFuture  *bool   `json:"buildFuture,omitempty"`
+	BuildExpired *bool   `json:"buildExpired,omitempty"`
+	Profile      *string `json:"profile,omitempty"` // "" switches back to Hugo's defaults
+}
+
+// optionFlags maps the long and short forms of Hugo's content flags to the option they set
+var optionFlags = map[string]func(*Options) *bool{
+	"--buildDrafts":  func(o *Options) *bool { return &o.BuildDrafts },
+	"-D":             func(o *Options) *bool { return &o.BuildDrafts },
+	"--buildFuture":  func(o *Options) *bool { return &o.BuildFuture },
+	"-F":             func(o *Options) *bool { return &o.BuildFuture },
+	"--buildExpired": func(o *Options) *bool { return &o.BuildExpired },
+	"-E":             func(o *Options) *bool { return &o.BuildExpired },
+}
+
+// splitOptions takes the content flags out of additional args, so the options alone decide them.
+// Flags in the args turn the option on, or set it to their value in the --flag=value form.
+func splitOptions(args []string, options Options) ([]string, Options) {
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		field, ok := optionFlags[name]
+		if !ok {
+			rest = append(rest, arg)
+			continue
+		}
+		enabled := true
+		if hasValue {
+			enabled, _ = strconv.ParseBool(value)
+		}
+		*field(&options) = enabled
+	}
+	return rest, options
+}
+
+// args returns the Hugo flags of the options
+func (o Options) args() []string {
+	var args []string
+	if o.BuildDrafts {
+		args = append(args, "--buildDrafts")
+	}
+	if o.BuildFuture {
+		args = append(args, "--buildFuture")
+	}
+	if o.BuildExpired {
+		args = append(args, "--buildExpired")
+	}
+	return args
+}
+
+// GetOptions returns the content flags and profile Hugo runs, or will run, with
+func (m *Manager) GetOptions() Options {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return m.options
+}
+
+// SetOptions changes the content flags and profile, restarting Hugo when it's running and one changed.
+// It reports whether Hugo was restarted.
+func (m *Manager) SetOptions(update OptionsUpdate) (Options, bool, error) {
+	if update.Profile != nil && *update.Profile != "" {
+		if _, err := m.profile(*update.Profile); err != nil {
+			return m.GetOptions(), false, err
+		}
+	}
+
+	m.statusMu.Lock()
+	options := m.options
+	if update.BuildDrafts != nil {
+		options.BuildDrafts = *update.BuildDrafts
+	}
+	if update.BuildFuture != nil {
+		options.BuildFuture = *update.BuildFuture
+	}
+	if update.BuildExpired != nil {
+		options.BuildExpired = *update.BuildExpired
+	}
+	if update.Profile != nil {
+		options.Profile = *update.Profile
+	}
+	changed := options != m.options
+	m.options = options
+	running := m.status == StatusRunning || m.status == StatusStarting
+	m.statusMu.Unlock()
+
+	if !changed || !running {
+		return options, false, nil
+	}
+	if err := m.Restart(); err != nil {
+		return options, false, err
+	}
+	return options, true, nil
+}
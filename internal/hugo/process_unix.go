@@ -0,0 +1,58 @@
+//go:build !windows
+
+package hugo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group led by the command itself
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateGroup sends SIGTERM to the process group led by pid
+func terminateGroup(pid int) error {
+	return signalGroup(pid, syscall.SIGTERM)
+}
+
+// killGroup sends SIGKILL to the process group led by pid
+func killGroup(pid int) error {
+	return signalGroup(pid, syscall.SIGKILL)
+}
+
+func signalGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil // Already gone
+	}
+	return err
+}
+
+// processAlive reports whether a process with the given pid exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processName returns the executable name of a process, or "" if it can't be determined
+func processName(pid int) string {
+	if data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	// No procfs (macOS, BSD)
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSpace(string(out))
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+package hugo
+
+import (
+	"encoding/csv"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group so its tree can be stopped together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminateGroup asks the process tree rooted at pid to close
+func terminateGroup(pid int) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// killGroup forcefully ends the process tree rooted at pid
+func killGroup(pid int) error {
+	if !processAlive(pid) {
+		return nil
+	}
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// processAlive reports whether a process with the given pid exists
+func processAlive(pid int) bool {
+	return processName(pid) != ""
+}
+
+// processName returns the image name of a process, or "" if it doesn't exist
+func processName(pid int) string {
+	out, err := exec.Command("tasklist", "/FI", "PID eq "+strconv.Itoa(pid), "/NH", "/FO", "CSV").Output()
+	if err != nil {
+		return ""
+	}
+	// Without a match tasklist prints an informational line instead of a CSV record
+	record, err := csv.NewReader(strings.NewReader(string(out))).Read()
+	if err != nil || len(record) < 2 || record[1] != strconv.Itoa(pid) {
+		return ""
+	}
+	return record[0]
+}
@@ -0,0 +1,149 @@
+package hugo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// ErrUnknownProfile is returned for profile names missing from the configuration
+var ErrUnknownProfile = errors.New("unknown Hugo profile")
+
+// buildTimeout bounds a site build
+const buildTimeout = 10 * time.Minute
+
+// maxBuildOutput is the number of output lines kept in a build result
+const maxBuildOutput = 200
+
+// Profile is a named environment and baseURL Hugo can run with
+type Profile config.HugoProfile
+
+// ProfilesInfo lists the configured profiles and the one the server runs with
+type ProfilesInfo struct {
+	Current  string               `json:"current"`
+	Profiles []config.HugoProfile `json:"profiles"`
+}
+
+// BuildResult is the outcome of a site build
+type BuildResult struct {
+	Profile     string     `json:"profile,omitempty"`
+	Environment string     `json:"environment,omitempty"`
+	BaseURL     string     `json:"baseURL,omitempty"`
+	Args        []string   `json:"args"`
+	Success     bool       `json:"success"`
+	Duration    float64    `json:"durationMs"`
+	Errors      []LogEvent `json:"errors"`
+	Output      []string   `json:"output"` // Last lines of Hugo's output
+}
+
+// findProfile returns the profile called name. The empty name is Hugo's defaults.
+func findProfile(profiles []config.HugoProfile, name string) (Profile, error) {
+	if name == "" {
+		return Profile{}, nil
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return Profile(p), nil
+		}
+	}
+	return Profile{}, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+}
+
+// profile returns the configured profile called name
+func (m *Manager) profile(name string) (Profile, error) {
+	return findProfile(m.config.Profiles, name)
+}
+
+// args returns the Hugo flags of a profile
+func (p Profile) args() []string {
+	var args []string
+	if p.Environment != "" {
+		args = append(args, "--environment", p.Environment)
+	}
+	if p.BaseURL != "" {
+		args = append(args, "--baseURL", p.BaseURL)
+	}
+	return args
+}
+
+// Profiles returns the configured profiles and the one the server runs, or will run, with
+func (m *Manager) Profiles() ProfilesInfo {
+	profiles := m.config.Profiles
+	if profiles == nil {
+		profiles = []config.HugoProfile{}
+	}
+	return ProfilesInfo{Current: m.GetOptions().Profile, Profiles: profiles}
+}
+
+// Build renders the site into its publish directory with a profile, as a deploy would.
+// Content flags and additional args are server settings, so builds leave them out.
+// Builds run one at a time and don't affect the running server.
+func (m *Manager) Build(name string) (*BuildResult, error) {
+	profile, err := m.profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.buildMu.Lock()
+	defer m.buildMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), buildTimeout)
+	defer cancel()
+
+	result := &BuildResult{
+		Profile:     name,
+		Environment: profile.Environment,
+		BaseURL:     profile.BaseURL,
+		Args:        profile.args(),
+		Errors:      []LogEvent{},
+		Output:      []string{},
+	}
+	if result.Args == nil {
+		result.Args = []string{}
+	}
+
+	cmd := exec.CommandContext(ctx, "hugo", result.Args...)
+	cmd.Dir = m.projectDir
+	m.addLog(fmt.Sprintf("Building site with %s", strings.Join(append([]string{"hugo"}, result.Args...), " ")), "system")
+
+	started := time.Now()
+	output, runErr := cmd.CombinedOutput()
+	result.Duration = float64(time.Since(started)) / float64(time.Millisecond)
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if classify(line, "stdout") == LevelError {
+			result.Errors = append(result.Errors, *m.located(EventError, strings.TrimSpace(line)))
+		}
+		result.Output = append(result.Output, line)
+		if len(result.Output) > maxBuildOutput {
+			result.Output = result.Output[1:]
+		}
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("build timed out after %s", buildTimeout)
+	case runErr != nil:
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return nil, fmt.Errorf("failed to run hugo: %w", runErr)
+		}
+		m.addLog(fmt.Sprintf("Site build failed after %.0f ms", result.Duration), "system")
+	default:
+		result.Success = true
+		m.addLog(fmt.Sprintf("Site built in %.0f ms", result.Duration), "system")
+	}
+	return result, nil
+}
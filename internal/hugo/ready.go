@@ -0,0 +1,111 @@
+package hugo
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// StatusEvent reports a status transition. Its Type is always "status", which tells it apart
+// from log entries on the WebSocket stream.
+type StatusEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Status   Status    `json:"status"`
+	Message  string    `json:"message"`
+	Port     int       `json:"port"`
+	Instance string    `json:"instance,omitempty"` // Unset for the main instance
+}
+
+// startTimeout returns how long Hugo has to build the site and start serving
+func (m *Manager) startTimeout() time.Duration {
+	if m.config.StartTimeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(m.config.StartTimeout) * time.Second
+}
+
+// waitReady polls the Hugo port until it accepts connections, which Hugo only does after the
+// first build. The status becomes error if that doesn't happen within the start timeout.
+func (m *Manager) waitReady(port int, done <-chan struct{}) {
+	timeout := time.NewTimer(m.startTimeout())
+	defer timeout.Stop()
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return // Reported by the process monitor
+
+		case <-timeout.C:
+			msg := fmt.Sprintf("Hugo did not start serving on port %d within %s", port, m.startTimeout())
+			if m.transition(StatusError, msg, StatusStarting) {
+				m.addLog(msg, "system")
+			}
+			return
+
+		case <-ticker.C:
+			if status, _ := m.GetStatus(); status != StatusStarting {
+				return // Detected from Hugo's output
+			}
+			conn, err := net.DialTimeout("tcp", m.localAddress(port), time.Second)
+			if err != nil {
+				continue
+			}
+			conn.Close()
+			m.markRunning()
+			return
+		}
+	}
+}
+
+var serverURLRe = regexp.MustCompile(`https?://\S+`)
+
+// serverAvailable handles Hugo's "Web Server is available at <url>" line, taking the port from the URL
+func (m *Manager) serverAvailable(line string) {
+	if u, err := url.Parse(serverURLRe.FindString(line)); err == nil {
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			m.statusMu.Lock()
+			changed := m.port != port
+			m.port = port
+			m.statusMu.Unlock()
+			if changed {
+				m.addLog(fmt.Sprintf("Hugo is serving on port %d", port), "system")
+			}
+		}
+	}
+	m.markRunning()
+}
+
+// markRunning moves a starting server, or one that exceeded the start timeout, to running
+func (m *Manager) markRunning() {
+	m.transition(StatusRunning, fmt.Sprintf("Running on port %d", m.GetPort()), StatusStarting, StatusError)
+}
+
+// transition sets the status if the current one is one of from and Hugo isn't being stopped.
+// It reports whether the status changed.
+func (m *Manager) transition(to Status, msg string, from ...Status) bool {
+	m.statusMu.Lock()
+	allowed := false
+	for _, s := range from {
+		if m.status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed || m.stopping {
+		m.statusMu.Unlock()
+		return false
+	}
+	m.status = to
+	m.statusMsg = msg
+	port := m.port
+	m.statusMu.Unlock()
+
+	m.broadcastStatus(StatusEvent{Type: "status", Time: time.Now(), Status: to, Message: msg, Port: port})
+	return true
+}
@@ -0,0 +1,108 @@
+package hugo
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+)
+
+// Outcomes of a watched rebuild
+const (
+	RebuildDone       = "rebuilt"     // Hugo rebuilt the site after the change
+	RebuildFailed     = "failed"      // The rebuild reported errors
+	RebuildTimeout    = "timeout"     // Hugo didn't finish rebuilding in time
+	RebuildUnchanged  = "unchanged"   // Nothing changed, so Hugo had nothing to rebuild
+	RebuildNotRunning = "not-running" // Hugo isn't running, so there's no preview to wait for
+)
+
+// errorSettle is how long a failed rebuild is followed for further errors
+const errorSettle = 250 * time.Millisecond
+
+// RebuildResult is the outcome of the rebuild triggered by a change
+type RebuildResult struct {
+	Status   string     `json:"status"`
+	Duration float64    `json:"durationMs,omitempty"` // Build time Hugo reported
+	Waited   float64    `json:"waitedMs"`             // Time from the change to the outcome
+	Changed  []string   `json:"changed,omitempty"`    // Files Hugo reported as changed
+	Errors   []LogEvent `json:"errors"`
+}
+
+// RebuildWatch follows Hugo's output for the rebuild a change triggers. Create it before
+// making the change so the rebuild can't be missed, and close it when done.
+type RebuildWatch struct {
+	m       *Manager
+	ch      chan LogEntry
+	started time.Time
+}
+
+// WatchRebuild starts following Hugo's output
+func (m *Manager) WatchRebuild() *RebuildWatch {
+	return &RebuildWatch{m: m, ch: m.Subscribe(), started: time.Now()}
+}
+
+// Close stops following Hugo's output
+func (w *RebuildWatch) Close() {
+	w.m.Unsubscribe(w.ch)
+}
+
+// Wait returns once Hugo finishes the rebuild that includes file, reports errors for it, or ctx is done.
+// Rebuilds that only list other changed files are skipped, as they were triggered by someone else.
+func (w *RebuildWatch) Wait(ctx context.Context, file string) *RebuildResult {
+	result := &RebuildResult{Status: RebuildTimeout, Errors: []LogEvent{}}
+	var settle <-chan time.Time
+	finish := func(status string) *RebuildResult {
+		result.Status = status
+		result.Waited = float64(time.Since(w.started)) / float64(time.Millisecond)
+		return result
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return finish(result.Status)
+		case <-settle:
+			return finish(RebuildFailed)
+		case entry, ok := <-w.ch:
+			if !ok {
+				return finish(result.Status)
+			}
+			if entry.Event == nil {
+				continue
+			}
+			switch entry.Event.Kind {
+			case EventRebuild:
+				result.Errors = []LogEvent{}
+				settle = nil
+			case EventError:
+				result.Errors = append(result.Errors, *entry.Event)
+				if settle == nil {
+					settle = time.After(errorSettle)
+				}
+			case EventBuilt:
+				if len(entry.Event.Changed) > 0 && !changedFile(entry.Event.Changed, file) {
+					continue
+				}
+				result.Duration = entry.Event.Duration
+				result.Changed = entry.Event.Changed
+				if len(result.Errors) > 0 {
+					return finish(RebuildFailed)
+				}
+				return finish(RebuildDone)
+			}
+		}
+	}
+}
+
+// changedFile reports whether file is among the changed files of a rebuild. Hugo reports them
+// relative to the project or to their content directory, so paths are matched by suffix.
+func changedFile(changed []string, file string) bool {
+	file = "/" + strings.TrimPrefix(path.Clean("/"+file), "/")
+	for _, c := range changed {
+		c = "/" + strings.TrimPrefix(path.Clean("/"+c), "/")
+		if strings.HasSuffix(file, c) || strings.HasSuffix(c, file) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package hugo
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WatchdogStatus describes the health checks of the Hugo server
+type WatchdogStatus struct {
+	Enabled     bool      `json:"enabled"`
+	AutoRestart bool      `json:"autoRestart"`
+	LastCheck   time.Time `json:"lastCheck"`
+	Failures    int       `json:"failures"` // Consecutive failed checks
+	Restarts    int       `json:"restarts"` // Restarts in a row since Hugo was last healthy
+	NextRestart time.Time `json:"nextRestart"`
+}
+
+// watchdog periodically checks that Hugo is alive and answering HTTP requests
+type watchdog struct {
+	mu     sync.Mutex
+	status WatchdogStatus
+	stop   chan struct{}
+}
+
+// StartWatchdog starts health checks of the Hugo server at the configured interval
+func (m *Manager) StartWatchdog() {
+	cfg := m.config.Watchdog
+	if cfg.Interval <= 0 {
+		return
+	}
+
+	m.watchdog.mu.Lock()
+	if m.watchdog.stop != nil {
+		m.watchdog.mu.Unlock()
+		return
+	}
+	m.watchdog.stop = make(chan struct{})
+	m.watchdog.status.Enabled = true
+	m.watchdog.status.AutoRestart = cfg.AutoRestart
+	stop := m.watchdog.stop
+	m.watchdog.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopWatchdog stops the health checks
+func (m *Manager) StopWatchdog() {
+	m.watchdog.mu.Lock()
+	defer m.watchdog.mu.Unlock()
+	if m.watchdog.stop != nil {
+		close(m.watchdog.stop)
+		m.watchdog.stop = nil
+	}
+}
+
+// GetWatchdogStatus returns the state of the health checks
+func (m *Manager) GetWatchdogStatus() WatchdogStatus {
+	m.watchdog.mu.Lock()
+	defer m.watchdog.mu.Unlock()
+	return m.watchdog.status
+}
+
+// check runs a single health check. A running server that fails enough checks in a row is marked as
+// errored; an errored server is restarted with exponential backoff when auto-restart is enabled.
+func (m *Manager) check() {
+	cfg := m.config.Watchdog
+	status, _ := m.GetStatus()
+	now := time.Now()
+
+	m.watchdog.mu.Lock()
+	m.watchdog.status.LastCheck = now
+	m.watchdog.mu.Unlock()
+
+	switch status {
+	case StatusRunning:
+		if err := m.ping(); err != nil {
+			m.watchdog.mu.Lock()
+			m.watchdog.status.Failures++
+			failures := m.watchdog.status.Failures
+			m.watchdog.mu.Unlock()
+
+			threshold := cfg.Failures
+			if threshold <= 0 {
+				threshold = 1
+			}
+			if failures < threshold {
+				return
+			}
+			msg := fmt.Sprintf("Hugo stopped responding on port %d: %v", m.GetPort(), err)
+			m.addLog(msg, "system")
+			slog.Warn("Hugo is not responding", "port", m.GetPort(), "error", err)
+			m.setStatus(StatusError, msg)
+			m.scheduleRestart(now)
+			return
+		}
+
+		m.watchdog.mu.Lock()
+		m.watchdog.status.Failures = 0
+		m.watchdog.status.Restarts = 0
+		m.watchdog.status.NextRestart = time.Time{}
+		m.watchdog.mu.Unlock()
+
+	case StatusError:
+		if !cfg.AutoRestart {
+			return
+		}
+		m.watchdog.mu.Lock()
+		next := m.watchdog.status.NextRestart
+		m.watchdog.mu.Unlock()
+		if next.IsZero() {
+			// The process died between checks
+			m.scheduleRestart(now)
+			return
+		}
+		if now.Before(next) {
+			return
+		}
+
+		m.watchdog.mu.Lock()
+		m.watchdog.status.Restarts++
+		m.watchdog.status.Failures = 0
+		m.watchdog.status.NextRestart = time.Time{}
+		restarts := m.watchdog.status.Restarts
+		m.watchdog.mu.Unlock()
+
+		m.addLog(fmt.Sprintf("Watchdog restarting Hugo (attempt %d)", restarts), "system")
+		slog.Info("Restarting Hugo", "attempt", restarts)
+		if err := m.Restart(); err != nil {
+			slog.Warn("Watchdog failed to restart Hugo", "error", err)
+			m.scheduleRestart(time.Now())
+		}
+	}
+}
+
+// scheduleRestart sets the time of the next automatic restart, doubling the delay for each restart in a row
+func (m *Manager) scheduleRestart(now time.Time) {
+	cfg := m.config.Watchdog
+	if !cfg.AutoRestart {
+		return
+	}
+
+	m.watchdog.mu.Lock()
+	defer m.watchdog.mu.Unlock()
+
+	delay := time.Duration(cfg.Backoff) * time.Second
+	maxDelay := time.Duration(cfg.MaxBackoff) * time.Second
+	for i := 0; i < m.watchdog.status.Restarts && i < 16 && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	m.watchdog.status.NextRestart = now.Add(delay)
+}
+
+// ping requests the Hugo home page. Any HTTP response counts as healthy.
+func (m *Manager) ping() error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + m.localAddress(m.GetPort()) + "/")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// localAddress returns the address to reach Hugo on port from this machine, mapping wildcard binds to loopback
+func (m *Manager) localAddress(port int) string {
+	host := bindAddress(m.config.AdditionalArgs)
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
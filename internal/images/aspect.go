@@ -0,0 +1,61 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidAspect is returned for aspect ratios that aren't of the form W:H
+var ErrInvalidAspect = errors.New("invalid aspect ratio")
+
+// ParseAspect parses an aspect ratio such as "1:1", "4:3" or "16:9" into width over height.
+// An empty string returns 0, meaning the original aspect is kept.
+func ParseAspect(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	w, h, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("%w: %q (use W:H, e.g. 4:3)", ErrInvalidAspect, s)
+	}
+	width, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+	height, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return 0, fmt.Errorf("%w: %q (use W:H, e.g. 4:3)", ErrInvalidAspect, s)
+	}
+	return width / height, nil
+}
+
+// fitSize returns the size of a variant targetWidth wide, cropped to ratio when ratio is set.
+// The width is capped to the (cropped) source so images are never upscaled.
+func fitSize(img image.Image, targetWidth int, ratio float64) (int, int) {
+	origWidth, origHeight := img.Bounds().Dx(), img.Bounds().Dy()
+
+	if ratio <= 0 {
+		if targetWidth > origWidth {
+			targetWidth = origWidth
+		}
+		return targetWidth, int(float64(origHeight) * float64(targetWidth) / float64(origWidth))
+	}
+
+	cropWidth := origWidth
+	if float64(origWidth)/float64(origHeight) > ratio {
+		cropWidth = int(float64(origHeight) * ratio)
+	}
+	if targetWidth > cropWidth {
+		targetWidth = cropWidth
+	}
+	return targetWidth, max(int(float64(targetWidth)/ratio+0.5), 1)
+}
+
+// fit scales img to width x height, cropping it around the center first when ratio is set
+func fit(img image.Image, width, height int, ratio float64) image.Image {
+	if ratio <= 0 {
+		return resize(img, width, height)
+	}
+	return cropAndResize(img, width, height)
+}
@@ -0,0 +1,439 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// DefaultDuplicateDistance is how many of the 64 bits of two perceptual hashes may differ for the
+// images to count as near duplicates: the same picture re-encoded, resized or slightly retouched
+const DefaultDuplicateDistance = 6
+
+// MaxDuplicateDistance caps the distance; beyond it different pictures start to match
+const MaxDuplicateDistance = 16
+
+// hashFile caches the hashes of images by size and modification time, in the storage cache area
+const hashFile = "image-hashes.json"
+
+// duplicateRoots are the project directories searched for duplicates when no folder is given
+var duplicateRoots = []string{"static", "assets", "content"}
+
+// hashExts are the image formats with a registered decoder
+var hashExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
+
+// imageHash is what's known of an image file to find its duplicates
+type imageHash struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"` // Unix nanoseconds
+	SHA256  string `json:"sha256"`  // Of the file's bytes, for identical copies
+	DHash   uint64 `json:"dhash"`   // Difference hash of the picture, for near duplicates
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+// hashCache holds the hashes of the project's images, saved between runs
+type hashCache struct {
+	mu      sync.Mutex
+	dir     string // Project directory
+	path    string
+	entries map[string]imageHash // By project-relative path; nil until loaded
+}
+
+// DuplicateFile is an image of a group of duplicates
+type DuplicateFile struct {
+	Path     string `json:"path"`
+	URL      string `json:"url,omitempty"` // For files of the static directory
+	Size     int64  `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Distance int    `json:"distance"` // Bits of its perceptual hash that differ from the first file's
+}
+
+// DuplicateGroup is a set of images that look the same. The first is the one to keep: the largest.
+type DuplicateGroup struct {
+	Identical   bool            `json:"identical"`   // Every file has the same bytes
+	Reclaimable int64           `json:"reclaimable"` // Bytes freed by keeping only the first file
+	Files       []DuplicateFile `json:"files"`
+}
+
+// DuplicateReport lists the groups of duplicate images below some folders
+type DuplicateReport struct {
+	Folders     []string         `json:"folders"`
+	Scanned     int              `json:"scanned"`
+	Distance    int              `json:"distance"`
+	Reclaimable int64            `json:"reclaimable"`
+	Groups      []DuplicateGroup `json:"groups"`
+	Failed      []string         `json:"failed"` // Images that couldn't be decoded
+}
+
+// Duplicates finds identical and near-identical images below a project folder, or below static,
+// assets and content without one. Two images are near duplicates when their perceptual hashes
+// differ in at most distance bits (DefaultDuplicateDistance when 0). Variants of the same image,
+// such as photo.1920x1080.jpg and photo.800x450.jpg, are only reported when they're identical.
+// Once ctx is done the search stops with its error, keeping the hashes made so far.
+func (p *Processor) Duplicates(ctx context.Context, folder string, distance int) (*DuplicateReport, error) {
+	if distance <= 0 {
+		distance = DefaultDuplicateDistance
+	}
+	distance = min(distance, MaxDuplicateDistance)
+
+	folders := duplicateRoots
+	if folder != "" {
+		folder = filepath.ToSlash(filepath.Clean(folder))
+		stat, err := os.Stat(filepath.Join(p.projectDir, filepath.FromSlash(folder)))
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", folder)
+		}
+		folders = []string{folder}
+	}
+
+	report := &DuplicateReport{Folders: []string{}, Distance: distance, Groups: []DuplicateGroup{}, Failed: []string{}}
+	var paths []string
+	for _, f := range folders {
+		root := filepath.Join(p.projectDir, filepath.FromSlash(f))
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		report.Folders = append(report.Folders, f)
+		filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") && full != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && hashExts[strings.ToLower(filepath.Ext(full))] {
+				if rel, err := filepath.Rel(p.projectDir, full); err == nil {
+					paths = append(paths, filepath.ToSlash(rel))
+				}
+			}
+			return nil
+		})
+	}
+	sort.Strings(paths)
+
+	hashes := map[string]imageHash{}
+	for _, rel := range paths {
+		h, err := p.hash(ctx, rel)
+		if ctx.Err() != nil {
+			p.hashes.save()
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, rel)
+			continue
+		}
+		hashes[rel] = h
+	}
+	p.hashes.save()
+	report.Scanned = len(hashes)
+
+	report.Groups = p.group(hashes, distance)
+	for _, g := range report.Groups {
+		report.Reclaimable += g.Reclaimable
+	}
+	return report, nil
+}
+
+// similar returns the images already hashed that look like an image, leaving out its own variants
+func (p *Processor) similar(rel string, h imageHash, distance int) []string {
+	var found []string
+	for other, o := range p.hashes.all() {
+		if other == rel || (o.SHA256 != h.SHA256 && (p.sameImage(rel, other) || bits.OnesCount64(o.DHash^h.DHash) > distance)) {
+			continue
+		}
+		if stat, err := os.Stat(filepath.Join(p.projectDir, filepath.FromSlash(other))); err != nil || stat.Size() != o.Size {
+			continue // Gone or changed since it was hashed
+		}
+		found = append(found, other)
+	}
+	sort.Strings(found)
+	return found
+}
+
+// flagDuplicates hashes the largest variant of an upload, already decoded, and lists the images
+// hashed so far, by earlier uploads or searches for duplicates, that look the same
+func (p *Processor) flagDuplicates(result *ProcessResult, full string, img image.Image) {
+	rel, err := filepath.Rel(p.projectDir, full)
+	if err != nil {
+		return
+	}
+	stat, err := os.Stat(full)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+	result.Duplicates = p.similar(rel, p.record(rel, data, img, stat), DefaultDuplicateDistance)
+	p.hashes.save()
+}
+
+// group joins images that are identical or within distance of each other
+func (p *Processor) group(hashes map[string]imageHash, distance int) []DuplicateGroup {
+	paths := make([]string, 0, len(hashes))
+	for rel := range hashes {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	parent := make([]int, len(paths))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range paths {
+		a := hashes[paths[i]]
+		for j := i + 1; j < len(paths); j++ {
+			b := hashes[paths[j]]
+			if a.SHA256 != b.SHA256 && (bits.OnesCount64(a.DHash^b.DHash) > distance || p.sameImage(paths[i], paths[j])) {
+				continue
+			}
+			parent[find(j)] = find(i)
+		}
+	}
+
+	members := map[int][]string{}
+	for i, rel := range paths {
+		root := find(i)
+		members[root] = append(members[root], rel)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, rels := range members {
+		if len(rels) < 2 {
+			continue
+		}
+		sort.Slice(rels, func(i, j int) bool {
+			a, b := hashes[rels[i]], hashes[rels[j]]
+			if a.Width*a.Height != b.Width*b.Height {
+				return a.Width*a.Height > b.Width*b.Height
+			}
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+			return rels[i] < rels[j]
+		})
+
+		first := hashes[rels[0]]
+		g := DuplicateGroup{Identical: true}
+		for i, rel := range rels {
+			h := hashes[rel]
+			g.Files = append(g.Files, DuplicateFile{
+				Path:     rel,
+				URL:      staticURL(rel),
+				Size:     h.Size,
+				Width:    h.Width,
+				Height:   h.Height,
+				Distance: bits.OnesCount64(h.DHash ^ first.DHash),
+			})
+			if h.SHA256 != first.SHA256 {
+				g.Identical = false
+			}
+			if i > 0 {
+				g.Reclaimable += h.Size
+			}
+		}
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Reclaimable != groups[j].Reclaimable {
+			return groups[i].Reclaimable > groups[j].Reclaimable
+		}
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+	return groups
+}
+
+// sameImage reports whether two files are variants of one image or its kept original: in the same
+// folder, or its originals subfolder, with the same base name in a variant naming scheme
+func (p *Processor) sameImage(a, b string) bool {
+	folder := func(rel string) string {
+		dir := path.Dir(rel)
+		if path.Base(dir) == OriginalsDir {
+			return path.Dir(dir)
+		}
+		return dir
+	}
+	return folder(a) == folder(b) && p.baseName(path.Base(a)) == p.baseName(path.Base(b))
+}
+
+// hash returns the hashes of an image, from the cache while the file is unchanged
+func (p *Processor) hash(ctx context.Context, rel string) (imageHash, error) {
+	full := filepath.Join(p.projectDir, filepath.FromSlash(rel))
+	stat, err := os.Stat(full)
+	if err != nil {
+		return imageHash{}, err
+	}
+	if h, ok := p.hashes.get(rel); ok && h.Size == stat.Size() && h.ModTime == stat.ModTime().UnixNano() {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return imageHash{}, err
+	}
+	if err := p.acquire(ctx); err != nil {
+		return imageHash{}, err
+	}
+	img, _, err := p.decodeLimited(bytes.NewReader(data))
+	p.release()
+	if err != nil {
+		return imageHash{}, err
+	}
+	return p.record(rel, data, img, stat), nil
+}
+
+// record caches the hashes of an image file from its bytes and decoded picture
+func (p *Processor) record(rel string, data []byte, img image.Image, stat os.FileInfo) imageHash {
+	sum := sha256.Sum256(data)
+	bounds := img.Bounds()
+	h := imageHash{
+		Size:    stat.Size(),
+		ModTime: stat.ModTime().UnixNano(),
+		SHA256:  hex.EncodeToString(sum[:]),
+		DHash:   dHash(img),
+		Width:   bounds.Dx(),
+		Height:  bounds.Dy(),
+	}
+	p.hashes.set(rel, h)
+	return h
+}
+
+// dHash computes the difference hash of an image: it's shrunk to 9x8 gray cells and each bit tells
+// whether a cell is brighter than the one to its right. Re-encoding and resizing barely change it.
+// Transparent pixels count as white, as pages usually show them on a light background.
+func dHash(img image.Image) uint64 {
+	const cols, rows, samples = 9, 8, 12
+	b := img.Bounds()
+	var cells [rows][cols]float64
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			var sum float64
+			for sy := 0; sy < samples; sy++ {
+				py := b.Min.Y + (y*samples+sy)*b.Dy()/(rows*samples)
+				for sx := 0; sx < samples; sx++ {
+					px := b.Min.X + (x*samples+sx)*b.Dx()/(cols*samples)
+					r, g, bl, a := img.At(px, py).RGBA()
+					// Premultiplied, so adding the missing alpha composites over white
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl) + float64(0xffff-a)
+				}
+			}
+			cells[y][x] = sum
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// staticURL returns the URL of a file of the static directory, "" for other files
+func staticURL(rel string) string {
+	if rest, ok := strings.CutPrefix(rel, "static/"); ok {
+		return "/" + rest
+	}
+	return ""
+}
+
+// newHashCache creates the cache of a project's image hashes
+func newHashCache(projectDir string) *hashCache {
+	return &hashCache{dir: projectDir, path: filepath.Join(projectDir, storage.DirName, storage.AreaCache, hashFile)}
+}
+
+// load reads the saved hashes the first time they're needed; the lock must be held
+func (c *hashCache) load() {
+	if c.entries != nil {
+		return
+	}
+	c.entries = map[string]imageHash{}
+	if data, err := os.ReadFile(c.path); err == nil {
+		json.Unmarshal(data, &c.entries) // A broken cache is rebuilt
+	}
+}
+
+// get returns the cached hashes of an image
+func (c *hashCache) get(rel string) (imageHash, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	h, ok := c.entries[rel]
+	return h, ok
+}
+
+// set caches the hashes of an image
+func (c *hashCache) set(rel string, h imageHash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	c.entries[rel] = h
+}
+
+// all returns a copy of the cached hashes
+func (c *hashCache) all() map[string]imageHash {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	entries := make(map[string]imageHash, len(c.entries))
+	for rel, h := range c.entries {
+		entries[rel] = h
+	}
+	return entries
+}
+
+// save writes the cached hashes of images that still exist
+func (c *hashCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	for rel := range c.entries {
+		if _, err := os.Stat(filepath.Join(c.dir, filepath.FromSlash(rel))); err != nil {
+			delete(c.entries, rel)
+		}
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
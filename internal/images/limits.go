@@ -0,0 +1,58 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// ErrTooLarge is returned when a source image exceeds the configured megapixel limit
+var ErrTooLarge = errors.New("image too large")
+
+// acquire blocks until an image processing slot is available, or returns the error of ctx when
+// it's done first
+func (p *Processor) acquire(ctx context.Context) error {
+	if p.slots == nil {
+		return ctx.Err()
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees an image processing slot
+func (p *Processor) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// decodeLimited decodes an image after checking its dimensions against the megapixel limit.
+// Only the header is read before the check, so oversized images are rejected without allocating pixels.
+func (p *Processor) decodeLimited(reader io.Reader) (image.Image, string, error) {
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(reader, &header))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if p.config.MaxMegapixels > 0 {
+		megapixels := float64(cfg.Width) * float64(cfg.Height) / 1e6
+		if megapixels > p.config.MaxMegapixels {
+			return nil, "", fmt.Errorf("%w: %dx%d is %.1f megapixels, limit is %.1f",
+				ErrTooLarge, cfg.Width, cfg.Height, megapixels, p.config.MaxMegapixels)
+		}
+	}
+
+	img, format, err := image.Decode(io.MultiReader(&header, reader))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, format, nil
+}
@@ -0,0 +1,172 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+)
+
+// ErrNotImage is returned for metadata of a file that isn't an image
+var ErrNotImage = errors.New("not an image")
+
+// metaSuffix names the sidecar of an image: photo.meta.yaml for photo.jpg and its variants
+const metaSuffix = ".meta.yaml"
+
+// metaExts are the image types metadata can be kept for
+var metaExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".svg": true,
+}
+
+// Meta describes an image, kept in a sidecar file next to it and shared by all its variants
+type Meta struct {
+	Alt     string `yaml:"alt,omitempty" json:"alt"`         // Alt text of the snippets made from the image
+	Caption string `yaml:"caption,omitempty" json:"caption"` // Shown below the image
+	Credit  string `yaml:"credit,omitempty" json:"credit"`   // Author or source
+	License string `yaml:"license,omitempty" json:"license"` // E.g. "CC BY 4.0"
+}
+
+// ImageMeta is the metadata of an image with the sidecar it's kept in
+type ImageMeta struct {
+	Meta
+	Path    string `json:"path"`    // Image the metadata was asked for
+	Sidecar string `json:"sidecar"` // Sidecar file, shared by every variant of the image
+	Stored  bool   `json:"stored"`  // Whether the sidecar exists
+}
+
+// empty reports whether no field is set
+func (m Meta) empty() bool {
+	return m == Meta{}
+}
+
+// trim removes the spaces around every field
+func (m Meta) trim() Meta {
+	return Meta{
+		Alt:     strings.TrimSpace(m.Alt),
+		Caption: strings.TrimSpace(m.Caption),
+		Credit:  strings.TrimSpace(m.Credit),
+		License: strings.TrimSpace(m.License),
+	}
+}
+
+// Meta returns the metadata of an image, a project-relative path, which is empty when the image
+// has no sidecar
+func (p *Processor) Meta(rel string) (*ImageMeta, error) {
+	sidecar, err := p.sidecar(rel)
+	if err != nil {
+		return nil, err
+	}
+	meta, stored, err := p.readMeta(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageMeta{Meta: meta, Path: filepath.ToSlash(rel), Sidecar: sidecar, Stored: stored}, nil
+}
+
+// SetMeta replaces the metadata of an image. Metadata without any field removes the sidecar.
+func (p *Processor) SetMeta(rel string, meta Meta) (*ImageMeta, error) {
+	sidecar, err := p.sidecar(rel)
+	if err != nil {
+		return nil, err
+	}
+	meta = meta.trim()
+	if err := p.writeMeta(sidecar, meta); err != nil {
+		return nil, err
+	}
+	return &ImageMeta{Meta: meta, Path: filepath.ToSlash(rel), Sidecar: sidecar, Stored: !meta.empty()}, nil
+}
+
+// sidecar returns the sidecar file of an existing image. Variants share the sidecar of the image
+// they were made from, named after the base name the variant schemes find.
+func (p *Processor) sidecar(rel string) (string, error) {
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	if !metaExts[strings.ToLower(path.Ext(rel))] {
+		return "", fmt.Errorf("%w: %s", ErrNotImage, rel)
+	}
+	stat, err := os.Stat(filepath.Join(p.projectDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", err
+	}
+	if stat.IsDir() {
+		return "", fmt.Errorf("%w: %s is a directory", ErrNotImage, rel)
+	}
+	dir, name := path.Split(rel)
+	return path.Join(dir, p.baseName(name)+metaSuffix), nil
+}
+
+// baseName returns the name of the image a file is a variant of, without extension
+func (p *Processor) baseName(name string) string {
+	for _, scheme := range p.schemes {
+		if v, ok := scheme.parse(name); ok {
+			return v.base
+		}
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// readMeta reads a sidecar; stored is false when it doesn't exist
+func (p *Processor) readMeta(sidecar string) (meta Meta, stored bool, err error) {
+	data, err := os.ReadFile(filepath.Join(p.projectDir, filepath.FromSlash(sidecar)))
+	if errors.Is(err, os.ErrNotExist) {
+		return Meta{}, false, nil
+	}
+	if err != nil {
+		return Meta{}, false, err
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return Meta{}, false, fmt.Errorf("invalid image metadata in %s: %w", sidecar, err)
+	}
+	return meta.trim(), true, nil
+}
+
+// writeMeta writes a sidecar, or removes it when the metadata is empty
+func (p *Processor) writeMeta(sidecar string, meta Meta) error {
+	full := filepath.Join(p.projectDir, filepath.FromSlash(sidecar))
+	if meta.empty() {
+		if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(full, data, 0644)
+}
+
+// storedAlt returns the alt text kept for the image with a base name in a project-relative
+// directory, "" when there's none
+func (p *Processor) storedAlt(dir, baseName string) string {
+	meta, _, err := p.readMeta(path.Join(filepath.ToSlash(dir), baseName+metaSuffix))
+	if err != nil {
+		return ""
+	}
+	return meta.Alt
+}
+
+// rememberMeta keeps the alt text and caption given for an upload in the sidecar of the image,
+// without replacing the ones already kept there
+func (p *Processor) rememberMeta(dir, baseName string, opts UploadOptions) {
+	sidecar := path.Join(filepath.ToSlash(dir), baseName+metaSuffix)
+	meta, _, err := p.readMeta(sidecar)
+	if err != nil {
+		return // Left for the editor to fix rather than overwritten
+	}
+	updated := meta
+	if updated.Alt == "" {
+		updated.Alt = strings.TrimSpace(opts.Alt)
+	}
+	if updated.Caption == "" {
+		updated.Caption = strings.TrimSpace(opts.Caption)
+	}
+	if updated != meta {
+		p.writeMeta(sidecar, updated)
+	}
+}
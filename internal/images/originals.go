@@ -0,0 +1,78 @@
+package images
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+)
+
+// OriginalsDir is the subfolder of an upload folder the untouched uploads are kept in
+const OriginalsDir = "originals"
+
+// keepOriginal reports whether an upload is kept as it was sent: as opts asks, or as
+// images.keep_originals says when it doesn't
+func (p *Processor) keepOriginal(opts UploadOptions) bool {
+	if opts.KeepOriginal != nil {
+		return *opts.KeepOriginal
+	}
+	return p.config.KeepOriginals
+}
+
+// saveOriginal writes the bytes of an upload to the originals subfolder of its folder, named after
+// its variants with the extension of its format, and returns its project-relative path
+func (p *Processor) saveOriginal(folder, baseName, format string, data []byte) (string, error) {
+	dir := filepath.Join(p.projectDir, folder, OriginalsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// An earlier original of another format would be found instead of this one
+	if old := p.originalIn(dir, baseName); old != "" {
+		os.Remove(old)
+	}
+	full := filepath.Join(dir, baseName+getExtension(format))
+	if err := atomicfile.WriteFile(full, data, 0644); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(p.projectDir, full)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// OriginalOf returns the kept original of an image, a project-relative path, or "" when there's
+// none. An image in an originals folder is its own original.
+func (p *Processor) OriginalOf(rel string) string {
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	dir, name := path.Split(rel)
+	if path.Base(dir) == OriginalsDir {
+		return rel
+	}
+	full := p.originalIn(filepath.Join(p.projectDir, filepath.FromSlash(dir), OriginalsDir), p.baseName(name))
+	if full == "" {
+		return ""
+	}
+	found, err := filepath.Rel(p.projectDir, full)
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(found)
+}
+
+// originalIn returns the original with a base name in an originals folder, "" when there's none
+func (p *Processor) originalIn(dir, baseName string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && hashExts[strings.ToLower(filepath.Ext(name))] && strings.TrimSuffix(name, filepath.Ext(name)) == baseName {
+			return filepath.Join(dir, name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package images
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+)
+
+// OutputData is what the shortcode and HTML templates of images.output read
+type OutputData struct {
+	Src      string // URL of the largest variant
+	Srcset   string // Every variant with its width or density; empty for a single image
+	Sizes    string // images.output.sizes, or the built-in sizes
+	Alt      string
+	Caption  string // The caption given, or the one kept in the image's sidecar
+	Class    string // images.output.class
+	Lang     string // Language and direction of the text, when they differ from the page's
+	Dir      string
+	Width    int // Of the largest variant; 0 when unknown
+	Height   int
+	Credit   string // From the image's sidecar
+	License  string
+	Variants []ProcessedImage // Largest first
+}
+
+// applyTemplates replaces the built-in shortcode and HTML of a result with the ones rendered by
+// the templates of images.output. A template that fails keeps the built-in markup, with a warning.
+func (p *Processor) applyTemplates(dir, baseName string, img snippets.Image, result *ProcessResult) {
+	if p.templates == nil || (p.templates.Shortcode == nil && p.templates.HTML == nil) {
+		return
+	}
+	opts, err := p.snippets.Resolve(img.Options)
+	if err != nil {
+		return // Already refused by the built-in snippet
+	}
+	meta, _, _ := p.readMeta(path.Join(filepath.ToSlash(dir), baseName+metaSuffix))
+
+	data := OutputData{
+		Src:      img.Src,
+		Srcset:   img.Srcset,
+		Sizes:    img.Sizes,
+		Alt:      img.Alt,
+		Caption:  opts.Caption,
+		Class:    img.Class,
+		Lang:     opts.Lang,
+		Dir:      opts.Dir,
+		Width:    result.Variants[0].Width,
+		Height:   result.Variants[0].Height,
+		Credit:   meta.Credit,
+		License:  meta.License,
+		Variants: result.Variants,
+	}
+	if data.Sizes == "" {
+		data.Sizes = snippets.DefaultSizes
+	}
+	if data.Caption == "" {
+		data.Caption = meta.Caption
+	}
+
+	if tmpl := p.templates.Shortcode; tmpl != nil {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data.quoted()); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The shortcode template failed, so the built-in shortcode is used: %v", err))
+		} else {
+			result.Shortcode = strings.TrimSpace(b.String())
+		}
+	}
+	if tmpl := p.templates.HTML; tmpl != nil {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The HTML template failed, so the built-in HTML is used: %v", err))
+		} else {
+			result.HTML = strings.TrimSpace(b.String())
+		}
+	}
+}
+
+// quoted returns the data with the quotes of its text escaped, for quoted shortcode parameters
+func (d OutputData) quoted() OutputData {
+	q := strings.NewReplacer(`"`, `\"`).Replace
+	d.Src, d.Srcset, d.Sizes, d.Alt, d.Caption = q(d.Src), q(d.Srcset), q(d.Sizes), q(d.Alt), q(d.Caption)
+	d.Class, d.Lang, d.Dir, d.Credit, d.License = q(d.Class), q(d.Lang), q(d.Dir), q(d.Credit), q(d.License)
+	return d
+}
@@ -0,0 +1,38 @@
+package images
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+)
+
+// pasteFolder is where images pasted into pages go, below the section of the page
+const pasteFolder = "static/images"
+
+// PasteName returns the file name of an image pasted into a page: the slug of the page and the
+// time, as in my-post-20261016-153045. The slug of a bundle's index is the bundle's.
+func PasteName(page string, now time.Time) string {
+	page = path.Clean(strings.ReplaceAll(page, "\\", "/"))
+	name := strings.TrimSuffix(path.Base(page), path.Ext(page))
+	if name == "index" || name == "_index" {
+		name = path.Base(path.Dir(page))
+	}
+	base := slug.Make(name)
+	if base == "" {
+		base = "image"
+	}
+	return base + "-" + now.Format("20060102-150405")
+}
+
+// PasteFolder returns the folder of images pasted into a page: static/images, followed by the
+// folder of the page below content, as in static/images/blog for content/blog/my-post.md
+func PasteFolder(page string) string {
+	dir := path.Dir(path.Clean(strings.ReplaceAll(page, "\\", "/")))
+	section := strings.TrimPrefix(strings.TrimPrefix(dir, "content"), "/")
+	if dir != "content" && !strings.HasPrefix(dir, "content/") {
+		section = ""
+	}
+	return path.Join(pasteFolder, section)
+}
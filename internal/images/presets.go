@@ -0,0 +1,77 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// ErrUnknownPreset is returned for a preset the configuration doesn't define
+var ErrUnknownPreset = errors.New("unknown preset")
+
+// Presets known whether they're configured or not: Custom, for uploads with their own widths, and
+// Inline, for images pasted into a page
+const (
+	CustomPreset = "Custom"
+	InlinePreset = "Inline"
+)
+
+// builtinPresets are used when images.presets doesn't define presets of the same name
+var builtinPresets = []config.ImagePreset{
+	{Name: CustomPreset},
+	{Name: InlinePreset, Widths: []int{640, 1280}},
+}
+
+// defaultWidth is the single width of an upload without a preset or widths
+const defaultWidth = 1920
+
+// resolve fills in the widths, quality and format of an upload from its preset, then from the
+// defaults of images. A preset without widths, like Custom, keeps the widths given; a quality or
+// format given wins over the preset's.
+func (p *Processor) resolve(opts UploadOptions) (UploadOptions, error) {
+	if opts.PresetName != "" {
+		preset, ok := p.preset(opts.PresetName)
+		if !ok {
+			return opts, fmt.Errorf("%w: %s", ErrUnknownPreset, opts.PresetName)
+		}
+		if len(preset.Widths) > 0 {
+			opts.Widths = append([]int(nil), preset.Widths...)
+		}
+		if opts.Quality <= 0 {
+			opts.Quality = preset.Quality
+		}
+		if opts.Format == "" {
+			opts.Format = preset.Format
+		}
+	}
+
+	if opts.Quality <= 0 {
+		opts.Quality = p.config.DefaultQuality
+	}
+	if opts.Quality <= 0 {
+		opts.Quality = jpeg.DefaultQuality
+	}
+	opts.Quality = min(opts.Quality, 100)
+	if opts.Format == "" {
+		opts.Format = p.config.OutputFormat // Empty keeps the format of the source
+	}
+	if len(opts.Widths) == 0 {
+		opts.Widths = []int{defaultWidth}
+	}
+	return opts, nil
+}
+
+// preset returns the preset with a name, compared without case, configured or built in
+func (p *Processor) preset(name string) (config.ImagePreset, bool) {
+	for _, presets := range [][]config.ImagePreset{p.GetPresets(), builtinPresets} {
+		for _, preset := range presets {
+			if strings.EqualFold(preset.Name, strings.TrimSpace(name)) {
+				return preset, true
+			}
+		}
+	}
+	return config.ImagePreset{}, false
+}
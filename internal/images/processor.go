@@ -1,6 +1,9 @@
 package images
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,39 +13,61 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 
 	_ "image/gif"
 
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
 	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+)
+
+var (
+	// ErrInvalidInput is returned when a request lacks a path or folder the operation needs
+	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrNotFound is returned for an image that doesn't exist
+	ErrNotFound = errors.New("image not found")
 )
 
 // Processor handles image operations
 type Processor struct {
 	projectDir string
 	config     config.ImagesConfig
+	snippets   *snippets.Generator
+	slots      chan struct{}          // Limits concurrent decodes/encodes; nil means unlimited
+	schemes    []variantScheme        // Naming schemes of processed variants, in the order they're tried
+	presetsMu  sync.RWMutex           // Guards config.Presets, which is replaced when the configuration file changes
+	hashes     *hashCache             // Hashes of the project's images, to find duplicates
+	templates  *config.ImageTemplates // Shortcode and HTML templates of images.output
 }
 
 // ProcessedImage represents a processed image variant
 type ProcessedImage struct {
-	Width    int    `json:"width"`
-	Height   int    `json:"height"`
-	Path     string `json:"path"`
-	URL      string `json:"url"`
-	Size     int64  `json:"size"`
-	Filename string `json:"filename"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Path     string  `json:"path"`
+	URL      string  `json:"url"`
+	Size     int64   `json:"size"`
+	Filename string  `json:"filename"`
+	Density  float64 `json:"density,omitempty"` // Pixel density of variants named by density, like image@2x.png
 }
 
 // ProcessResult contains all generated image variants
 type ProcessResult struct {
-	Original  string           `json:"original"`
-	Variants  []ProcessedImage `json:"variants"`
-	Srcset    string           `json:"srcset"`
-	Shortcode string           `json:"shortcode"`
-	HTML      string           `json:"html"`
+	Original   string           `json:"original"`
+	Variants   []ProcessedImage `json:"variants"`
+	Srcset     string           `json:"srcset"`
+	Shortcode  string           `json:"shortcode"`
+	HTML       string           `json:"html"`
+	Markdown   string           `json:"markdown"`
+	Warnings   []string         `json:"warnings,omitempty"`   // Accessibility warnings about the snippets
+	Scheme     string           `json:"scheme,omitempty"`     // Naming scheme existing variants were found with
+	Duplicates []string         `json:"duplicates,omitempty"` // Existing images that look the same as an upload
+	Source     string           `json:"source,omitempty"`     // Kept original of the image, to process it again from
 }
 
 // UploadOptions contains options for image upload
@@ -51,7 +76,14 @@ type UploadOptions struct {
 	Filename   string `json:"filename"`
 	Quality    int    `json:"quality"`
 	Widths     []int  `json:"widths"`
-	PresetName string `json:"presetName"`
+	PresetName string `json:"presetName"` // Preset the widths, quality and format come from (empty = the ones given)
+	Format     string `json:"format"`     // Output format, jpg or png (empty = the preset's, then images.output_format, then the source's)
+	Aspect     string `json:"aspect"`     // Crop to this aspect ratio, e.g. "1:1" (empty = keep the original)
+	Alt        string `json:"alt"`        // Alt text of the snippets (empty = derived from the file name, with a warning)
+
+	KeepOriginal *bool `json:"keepOriginal,omitempty"` // Keep the upload untouched in the originals subfolder (nil = images.keep_originals)
+
+	snippets.Options // Language, direction and caption of the snippets
 }
 
 // FolderInfo represents an image folder
@@ -61,11 +93,21 @@ type FolderInfo struct {
 }
 
 // NewProcessor creates a new image processor
-func NewProcessor(projectDir string, cfg config.ImagesConfig) *Processor {
-	return &Processor{
+func NewProcessor(projectDir string, cfg config.ImagesConfig, generator *snippets.Generator) *Processor {
+	p := &Processor{
 		projectDir: projectDir,
 		config:     cfg,
+		snippets:   generator,
+		schemes:    variantSchemes(cfg.VariantName, cfg.VariantPatterns),
+		hashes:     newHashCache(projectDir),
 	}
+	if templates, err := cfg.Output.Parse(); err == nil {
+		p.templates = templates // Invalid ones are rejected when the configuration is loaded
+	}
+	if cfg.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return p
 }
 
 // GetFolders returns available image folders from common locations
@@ -84,11 +126,11 @@ func (p *Processor) GetFolders() []FolderInfo {
 		fullPath := filepath.Join(p.projectDir, dir)
 		if entries, err := os.ReadDir(fullPath); err == nil {
 			for _, entry := range entries {
-				if entry.IsDir() {
+				if entry.IsDir() && entry.Name() != OriginalsDir {
 					name := entry.Name()
 					folders = append(folders, FolderInfo{
 						Name: name,
-						Path: filepath.Join(dir, name),
+						Path: filepath.ToSlash(filepath.Join(dir, name)),
 					})
 				}
 			}
@@ -105,38 +147,63 @@ func (p *Processor) GetFolders() []FolderInfo {
 
 // GetPresets returns available image presets
 func (p *Processor) GetPresets() []config.ImagePreset {
+	p.presetsMu.RLock()
+	defer p.presetsMu.RUnlock()
 	return p.config.Presets
 }
 
-// Process processes an uploaded image
-func (p *Processor) Process(reader io.Reader, opts UploadOptions) (*ProcessResult, error) {
-	// Set defaults
-	if opts.Quality <= 0 {
-		opts.Quality = p.config.DefaultQuality
+// SetPresets replaces the image presets
+func (p *Processor) SetPresets(presets []config.ImagePreset) {
+	p.presetsMu.Lock()
+	p.config.Presets = presets
+	p.presetsMu.Unlock()
+}
+
+// Process processes an uploaded image. Once ctx is done no more variants are made and its error
+// is returned.
+func (p *Processor) Process(ctx context.Context, reader io.Reader, opts UploadOptions) (*ProcessResult, error) {
+	opts, err := p.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	ratio, err := ParseAspect(opts.Aspect)
+	if err != nil {
+		return nil, err
 	}
-	if len(opts.Widths) == 0 {
-		opts.Widths = []int{1920} // Default to single full-size
+	if _, err := p.snippets.Resolve(opts.Options); err != nil {
+		return nil, err
+	}
+
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	// A kept original gets every byte that was sent, including any the decoder doesn't read
+	keep := p.keepOriginal(opts)
+	var raw bytes.Buffer
+	if keep {
+		reader = io.TeeReader(reader, &raw)
 	}
 
 	// Decode the image
-	img, format, err := image.Decode(reader)
+	img, format, err := p.decodeLimited(reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+		return nil, err
+	}
+	if keep {
+		io.Copy(io.Discard, reader)
 	}
-
-	bounds := img.Bounds()
-	origWidth := bounds.Dx()
-	origHeight := bounds.Dy()
 
 	// Determine output format
-	outputFormat := p.config.OutputFormat
+	outputFormat := opts.Format
 	if outputFormat == "" {
 		outputFormat = format
 	}
 
 	// Create output directory
 	if opts.Folder == "" {
-		return nil, fmt.Errorf("folder is required for image upload")
+		return nil, fmt.Errorf("%w: folder is required for image upload", ErrInvalidInput)
 	}
 
 	// Use folder directly (always a complete path)
@@ -146,7 +213,7 @@ func (p *Processor) Process(reader io.Reader, opts UploadOptions) (*ProcessResul
 	}
 
 	// Sanitize filename
-	baseName := sanitizeFilename(opts.Filename)
+	baseName := SanitizeFilename(opts.Filename)
 	if baseName == "" {
 		baseName = "image"
 	}
@@ -156,44 +223,48 @@ func (p *Processor) Process(reader io.Reader, opts UploadOptions) (*ProcessResul
 	result := &ProcessResult{
 		Variants: []ProcessedImage{},
 	}
+	if keep {
+		if result.Source, err = p.saveOriginal(opts.Folder, baseName, format, raw.Bytes()); err != nil {
+			return nil, fmt.Errorf("failed to keep the original image: %w", err)
+		}
+	}
 
 	// Sort widths descending for srcset
 	sort.Sort(sort.Reverse(sort.IntSlice(opts.Widths)))
 
 	// Process each width
 	for _, targetWidth := range opts.Widths {
-		// Skip if target width is larger than original
-		if targetWidth > origWidth {
-			targetWidth = origWidth
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		// Calculate height maintaining aspect ratio
-		targetHeight := int(float64(origHeight) * float64(targetWidth) / float64(origWidth))
+		// Never wider than the original, cropped to the aspect ratio if one is set
+		targetWidth, targetHeight := fitSize(img, targetWidth, ratio)
+		if hasWidth(result.Variants, targetWidth) {
+			continue // Several widths were capped to the source width
+		}
 
 		// Resize the image
-		resized := resize(img, targetWidth, targetHeight)
+		resized := fit(img, targetWidth, targetHeight, ratio)
 
-		// Generate filename
-		ext := getExtension(outputFormat)
-		filename := fmt.Sprintf("%s.%dx%d%s", baseName, targetWidth, targetHeight, ext)
+		// Encode the image, then name it, as the name may have a hash of the bytes
+		data, err := encodeImage(resized, outputFormat, opts.Quality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		filename := p.variantName(baseName, targetWidth, targetHeight, getExtension(outputFormat), data)
 		outputPath := filepath.Join(outputDir, filename)
 
 		// Save the image
-		if err := saveImage(resized, outputPath, outputFormat, opts.Quality); err != nil {
+		if err := atomicfile.WriteFile(outputPath, data, 0644); err != nil {
 			return nil, fmt.Errorf("failed to save image %s: %w", filename, err)
 		}
-
-		// Get file size
-		stat, _ := os.Stat(outputPath)
-		size := int64(0)
-		if stat != nil {
-			size = stat.Size()
-		}
+		size := int64(len(data))
 
 		// Calculate URL path
 		relPath, _ := filepath.Rel(p.projectDir, outputPath)
 		relPath = strings.TrimPrefix(relPath, "static")
-		urlPath := "/" + strings.ReplaceAll(relPath, "\\", "/")
+		urlPath := "/" + strings.TrimPrefix(strings.ReplaceAll(relPath, "\\", "/"), "/")
 
 		variant := ProcessedImage{
 			Width:    targetWidth,
@@ -208,17 +279,20 @@ func (p *Processor) Process(reader io.Reader, opts UploadOptions) (*ProcessResul
 		// First (largest) is the original reference
 		if result.Original == "" {
 			result.Original = urlPath
+			p.flagDuplicates(result, outputPath, resized)
 		}
 	}
 
 	// Generate srcset string
 	result.Srcset = p.generateSrcset(result.Variants)
 
-	// Generate shortcode
-	result.Shortcode = p.generateShortcode(baseName, result)
+	// Keep the alt text and caption for the next snippets of the image
+	p.rememberMeta(opts.Folder, baseName, opts)
 
-	// Generate raw HTML
-	result.HTML = p.generateHTML(baseName, result)
+	// Generate the shortcode and raw HTML
+	if err := p.generateSnippets(opts.Folder, baseName, result, opts); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -226,38 +300,86 @@ func (p *Processor) Process(reader io.Reader, opts UploadOptions) (*ProcessResul
 func (p *Processor) BuildResultFromProcessedVariants(selectedPath string) (*ProcessResult, error) {
 	selectedPath = filepath.ToSlash(strings.TrimSpace(selectedPath))
 	if selectedPath == "" {
-		return nil, fmt.Errorf("path is required")
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidInput)
 	}
 
 	fullSelectedPath := filepath.Join(p.projectDir, filepath.FromSlash(selectedPath))
 	stat, err := os.Stat(fullSelectedPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, selectedPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat selected image: %w", err)
 	}
 	if stat.IsDir() {
-		return nil, fmt.Errorf("selected path is a directory")
+		return nil, fmt.Errorf("%w: %s is a directory", ErrNotImage, selectedPath)
 	}
 
 	dirAbs := filepath.Dir(fullSelectedPath)
 	fileName := filepath.Base(fullSelectedPath)
-	fileExt := strings.ToLower(filepath.Ext(fileName))
-
-	variantRe := regexp.MustCompile(`^(?P<base>.+)\.(?P<w>\d+)x(?P<h>\d+)(?P<ext>\.[^.]+)$`)
-	m := variantRe.FindStringSubmatch(fileName)
-
-	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-	if m != nil {
-		baseName = m[variantRe.SubexpIndex("base")]
-		fileExt = strings.ToLower(m[variantRe.SubexpIndex("ext")])
-	}
 
 	entries, err := os.ReadDir(dirAbs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image directory: %w", err)
 	}
 
+	// Every scheme is tried; the one that finds the most variants of the selected image wins,
+	// the built-in one on a tie
 	result := &ProcessResult{Variants: []ProcessedImage{}}
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	for _, scheme := range p.schemes {
+		base, variants := p.findVariants(scheme, dirAbs, fileName, entries)
+		if len(variants) > len(result.Variants) {
+			result.Variants, result.Scheme, baseName = variants, scheme.name, base
+		}
+	}
 
+	if len(result.Variants) == 0 {
+		relPath, _ := filepath.Rel(p.projectDir, fullSelectedPath)
+		relPath = strings.TrimPrefix(relPath, "static")
+		urlPath := strings.ReplaceAll(filepath.ToSlash(relPath), "\\", "/")
+		result.Variants = append(result.Variants, ProcessedImage{
+			Width:    0,
+			Height:   0,
+			Path:     filepath.ToSlash(relPath),
+			URL:      urlPath,
+			Size:     stat.Size(),
+			Filename: filepath.Base(relPath),
+		})
+	}
+
+	sort.Slice(result.Variants, func(i, j int) bool {
+		a, b := result.Variants[i], result.Variants[j]
+		if a.Density != b.Density {
+			return a.Density > b.Density
+		}
+		if a.Width == b.Width {
+			return a.Filename < b.Filename
+		}
+		return a.Width > b.Width
+	})
+
+	result.Original = result.Variants[0].URL
+	result.Srcset = p.generateSrcset(result.Variants)
+	result.Source = p.OriginalOf(selectedPath)
+	dir, _ := filepath.Rel(p.projectDir, dirAbs)
+	if err := p.generateSnippets(dir, baseName, result, UploadOptions{}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// findVariants returns the variants of the selected image a scheme finds in its directory, with
+// their base name. The selected image may be a variant or the original the variants were made from.
+func (p *Processor) findVariants(scheme variantScheme, dirAbs, fileName string, entries []os.DirEntry) (string, []ProcessedImage) {
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	fileExt := strings.ToLower(filepath.Ext(fileName))
+	if selected, ok := scheme.parse(fileName); ok {
+		baseName, fileExt = selected.base, selected.ext
+	}
+
+	var variants []ProcessedImage
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -266,17 +388,8 @@ func (p *Processor) BuildResultFromProcessedVariants(selectedPath string) (*Proc
 		if strings.ToLower(filepath.Ext(name)) != fileExt {
 			continue
 		}
-		mm := variantRe.FindStringSubmatch(name)
-		if mm == nil {
-			continue
-		}
-		entryBase := mm[variantRe.SubexpIndex("base")]
-		if entryBase != baseName {
-			continue
-		}
-		w, errW := strconv.Atoi(mm[variantRe.SubexpIndex("w")])
-		h, errH := strconv.Atoi(mm[variantRe.SubexpIndex("h")])
-		if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		v, ok := scheme.parse(name)
+		if !ok || v.base != baseName || v.ext != fileExt {
 			continue
 		}
 
@@ -285,52 +398,58 @@ func (p *Processor) BuildResultFromProcessedVariants(selectedPath string) (*Proc
 		if err != nil {
 			continue
 		}
+		// Schemes that don't name both dimensions get them from the image
+		if v.width == 0 || v.height == 0 {
+			width, height := dimensions(absPath)
+			if v.width == 0 {
+				v.width = width
+			}
+			if v.height == 0 {
+				v.height = height
+			}
+		}
 
 		relPath, _ := filepath.Rel(p.projectDir, absPath)
 		relPath = strings.TrimPrefix(relPath, "static")
 		urlPath := strings.ReplaceAll(filepath.ToSlash(relPath), "\\", "/")
 
-		result.Variants = append(result.Variants, ProcessedImage{
-			Width:    w,
-			Height:   h,
+		variants = append(variants, ProcessedImage{
+			Width:    v.width,
+			Height:   v.height,
 			Path:     filepath.ToSlash(relPath),
 			URL:      urlPath,
 			Size:     st.Size(),
 			Filename: name,
+			Density:  v.density,
 		})
 	}
+	return baseName, variants
+}
 
-	if len(result.Variants) == 0 {
-		relPath, _ := filepath.Rel(p.projectDir, fullSelectedPath)
-		relPath = strings.TrimPrefix(relPath, "static")
-		urlPath := strings.ReplaceAll(filepath.ToSlash(relPath), "\\", "/")
-		result.Variants = append(result.Variants, ProcessedImage{
-			Width:    0,
-			Height:   0,
-			Path:     filepath.ToSlash(relPath),
-			URL:      urlPath,
-			Size:     stat.Size(),
-			Filename: filepath.Base(relPath),
-		})
+// ProcessExistingImage processes an existing image file with the given options. A variant of an
+// image whose original was kept is processed from the original instead. Like Process, it stops
+// once ctx is done.
+func (p *Processor) ProcessExistingImage(ctx context.Context, sourcePath string, opts UploadOptions) (*ProcessResult, error) {
+	opts, err := p.resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	ratio, err := ParseAspect(opts.Aspect)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.snippets.Resolve(opts.Options); err != nil {
+		return nil, err
 	}
 
-	sort.Slice(result.Variants, func(i, j int) bool {
-		if result.Variants[i].Width == result.Variants[j].Width {
-			return result.Variants[i].Filename < result.Variants[j].Filename
+	requested := sourcePath
+	original := ""
+	if rel, err := filepath.Rel(p.projectDir, sourcePath); err == nil {
+		if original = p.OriginalOf(rel); original != "" {
+			sourcePath = filepath.Join(p.projectDir, filepath.FromSlash(original))
 		}
-		return result.Variants[i].Width > result.Variants[j].Width
-	})
-
-	result.Original = result.Variants[0].URL
-	result.Srcset = p.generateSrcset(result.Variants)
-	result.Shortcode = p.generateShortcode(baseName, result)
-	result.HTML = p.generateHTML(baseName, result)
-
-	return result, nil
-}
+	}
 
-// ProcessExistingImage processes an existing image file with the given options
-func (p *Processor) ProcessExistingImage(sourcePath string, opts UploadOptions) (*ProcessResult, error) {
 	// Open the existing image file
 	file, err := os.Open(sourcePath)
 	if err != nil {
@@ -338,16 +457,17 @@ func (p *Processor) ProcessExistingImage(sourcePath string, opts UploadOptions)
 	}
 	defer file.Close()
 
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
 	// Decode the image
-	img, format, err := image.Decode(file)
+	img, format, err := p.decodeLimited(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+		return nil, err
 	}
 
-	// Get image dimensions
-	origWidth := img.Bounds().Dx()
-	origHeight := img.Bounds().Dy()
-
 	// Create output directory
 	outputDir := filepath.Join(p.projectDir, opts.Folder)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -358,7 +478,7 @@ func (p *Processor) ProcessExistingImage(sourcePath string, opts UploadOptions)
 	baseName := strings.TrimSuffix(opts.Filename, filepath.Ext(opts.Filename))
 
 	// Determine output format
-	outputFormat := p.config.OutputFormat
+	outputFormat := opts.Format
 	if outputFormat == "" {
 		outputFormat = format
 	}
@@ -366,42 +486,42 @@ func (p *Processor) ProcessExistingImage(sourcePath string, opts UploadOptions)
 	// Initialize result
 	result := &ProcessResult{
 		Variants: []ProcessedImage{},
+		Source:   original,
 	}
 
 	// Process each width
 	for _, targetWidth := range opts.Widths {
-		// Skip if target width is larger than original
-		if targetWidth > origWidth {
-			targetWidth = origWidth
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		// Calculate height maintaining aspect ratio
-		targetHeight := int(float64(origHeight) * float64(targetWidth) / float64(origWidth))
+		// Never wider than the original, cropped to the aspect ratio if one is set
+		targetWidth, targetHeight := fitSize(img, targetWidth, ratio)
+		if hasWidth(result.Variants, targetWidth) {
+			continue // Several widths were capped to the source width
+		}
 
 		// Resize the image
-		resized := resize(img, targetWidth, targetHeight)
+		resized := fit(img, targetWidth, targetHeight, ratio)
 
-		// Generate filename
-		ext := getExtension(outputFormat)
-		filename := fmt.Sprintf("%s.%dx%d%s", baseName, targetWidth, targetHeight, ext)
+		// Encode the image, then name it, as the name may have a hash of the bytes
+		data, err := encodeImage(resized, outputFormat, opts.Quality)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		filename := p.variantName(baseName, targetWidth, targetHeight, getExtension(outputFormat), data)
 		outputPath := filepath.Join(outputDir, filename)
 
 		// Save the image
-		if err := saveImage(resized, outputPath, outputFormat, opts.Quality); err != nil {
+		if err := atomicfile.WriteFile(outputPath, data, 0644); err != nil {
 			return nil, fmt.Errorf("failed to save image %s: %w", filename, err)
 		}
-
-		// Get file size
-		stat, _ := os.Stat(outputPath)
-		size := int64(0)
-		if stat != nil {
-			size = stat.Size()
-		}
+		size := int64(len(data))
 
 		// Calculate URL path
 		relPath, _ := filepath.Rel(p.projectDir, outputPath)
 		relPath = strings.TrimPrefix(relPath, "static")
-		urlPath := "/" + strings.ReplaceAll(relPath, "\\", "/")
+		urlPath := "/" + strings.TrimPrefix(strings.ReplaceAll(relPath, "\\", "/"), "/")
 
 		variant := ProcessedImage{
 			Width:    targetWidth,
@@ -422,65 +542,97 @@ func (p *Processor) ProcessExistingImage(sourcePath string, opts UploadOptions)
 	// Generate srcset string
 	result.Srcset = p.generateSrcset(result.Variants)
 
-	// Generate shortcode
-	result.Shortcode = p.generateShortcode(baseName, result)
+	// The new variants share the metadata of the source image
+	if opts.Alt == "" || opts.Caption == "" {
+		if rel, err := filepath.Rel(p.projectDir, requested); err == nil {
+			if meta, err := p.Meta(rel); err == nil {
+				if opts.Alt == "" {
+					opts.Alt = meta.Alt
+				}
+				if opts.Caption == "" {
+					opts.Caption = meta.Caption
+				}
+			}
+		}
+	}
+	p.rememberMeta(opts.Folder, baseName, opts)
 
-	// Generate raw HTML
-	result.HTML = p.generateHTML(baseName, result)
+	// Generate the shortcode and raw HTML
+	if err := p.generateSnippets(opts.Folder, baseName, result, opts); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
+func hasWidth(variants []ProcessedImage, width int) bool {
+	for _, v := range variants {
+		if v.Width == width {
+			return true
+		}
+	}
+	return false
+}
+
 // generateSrcset creates the srcset attribute value
 func (p *Processor) generateSrcset(variants []ProcessedImage) string {
 	var parts []string
 	for _, v := range variants {
-		parts = append(parts, fmt.Sprintf("%s %dw", v.URL, v.Width))
+		parts = append(parts, v.URL+" "+descriptor(v))
 	}
 	return strings.Join(parts, ", ")
 }
 
-// generateShortcode creates a responsive image shortcode
-func (p *Processor) generateShortcode(baseName string, result *ProcessResult) string {
+// generateSnippets sets the shortcode and raw HTML of a result, from the templates of images.output
+// when there are any. Without alt text in opts, the alt kept in the sidecar of the image in dir is
+// used; without one either, it is derived from the file name and the result warns to describe the image.
+func (p *Processor) generateSnippets(dir, baseName string, result *ProcessResult, opts UploadOptions) error {
 	if len(result.Variants) == 0 {
-		return ""
+		return nil
 	}
 
-	// Use the largest variant as the default src
-	largest := result.Variants[0]
-
-	// If only one variant, simple shortcode
-	if len(result.Variants) == 1 {
-		return fmt.Sprintf(`{{< img src="%s" alt="%s" >}}`,
-			largest.URL,
-			baseName)
+	// Use the largest variant as the default src, with a srcset when there are several
+	img := snippets.Image{Src: result.Variants[0].URL, Alt: opts.Alt, Class: p.config.Output.Class, Options: opts.Options}
+	if len(result.Variants) > 1 {
+		img.Srcset = result.Srcset
+		img.Sizes = p.config.Output.Sizes
+	}
+	var warnings []string
+	if strings.TrimSpace(img.Alt) == "" {
+		img.Alt = p.storedAlt(dir, baseName)
+	}
+	if strings.TrimSpace(img.Alt) == "" {
+		img.Alt = altFromName(baseName)
+		warnings = append(warnings, fmt.Sprintf("Alt text %q comes from the file name; describe the image", img.Alt))
 	}
 
-	// Multiple variants - include srcset
-	return fmt.Sprintf(`{{< img src="%s" alt="%s" srcset="%s" >}}`,
-		largest.URL,
-		baseName,
-		result.Srcset)
-}
-
-// generateHTML creates a raw HTML img tag with srcset
-func (p *Processor) generateHTML(baseName string, result *ProcessResult) string {
-	if len(result.Variants) == 0 {
-		return ""
+	snippet, err := p.snippets.Image(img)
+	if err != nil {
+		return err
 	}
+	result.Shortcode = snippet.Shortcode
+	result.HTML = snippet.HTML
+	result.Markdown = markdownImage(img.Src, img.Alt)
+	result.Warnings = append(warnings, snippet.Warnings...)
+	p.applyTemplates(dir, baseName, img, result)
+	return nil
+}
 
-	largest := result.Variants[0]
+// markdownImage returns the Markdown image of src, with alt text on one line and its brackets escaped
+func markdownImage(src, alt string) string {
+	alt = strings.Join(strings.Fields(alt), " ")
+	alt = strings.NewReplacer(`[`, `\[`, `]`, `\]`).Replace(alt)
+	return "![" + alt + "](" + src + ")"
+}
 
-	if len(result.Variants) == 1 {
-		return fmt.Sprintf(`<img src="%s" alt="%s" loading="lazy" decoding="async">`,
-			largest.URL,
-			baseName)
+// altFromName turns a file name such as "john-doe" into placeholder alt text ("John doe")
+func altFromName(baseName string) string {
+	alt := strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(baseName))
+	if alt == "" {
+		return "Image"
 	}
-
-	return fmt.Sprintf(`<img src="%s" srcset="%s" sizes="(max-width: 640px) 100vw, (max-width: 1024px) 75vw, 50vw" alt="%s" loading="lazy" decoding="async">`,
-		largest.URL,
-		result.Srcset,
-		baseName)
+	runes := []rune(alt)
+	return strings.ToUpper(string(runes[:1])) + string(runes[1:])
 }
 
 // DeleteImage deletes an image and all its variants
@@ -579,21 +731,20 @@ func cropAndResize(src image.Image, width, height int) image.Image {
 	return resize(cropped, width, height)
 }
 
-func saveImage(img image.Image, path, format string, quality int) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
+// encodeImage encodes an image in the output format, JPEG for formats that can't be written
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
 	switch strings.ToLower(format) {
-	case "jpeg", "jpg":
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: quality})
 	case "png":
-		return png.Encode(file, img)
+		err = png.Encode(&b, img)
 	default:
-		return jpeg.Encode(file, img, &jpeg.Options{Quality: quality})
+		err = jpeg.Encode(&b, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, err
 	}
+	return b.Bytes(), nil
 }
 
 func getExtension(format string) string {
@@ -611,17 +762,13 @@ func getExtension(format string) string {
 	}
 }
 
-func sanitizeFilename(name string) string {
+// SanitizeFilename makes a file name safe for URLs, as the names of image variants are
+func SanitizeFilename(name string) string {
 	// Replace spaces with hyphens
 	name = strings.ReplaceAll(name, " ", "-")
 
-	// Remove or replace problematic characters
-	replacer := strings.NewReplacer(
-		"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u",
-		"Á", "A", "É", "E", "Í", "I", "Ó", "O", "Ú", "U",
-		"ñ", "n", "Ñ", "N", "ü", "u", "Ü", "U",
-	)
-	name = replacer.Replace(name)
+	// Spell accented, Greek and Cyrillic letters in ASCII
+	name = slug.Transliterate(name)
 
 	// Keep only alphanumeric, hyphens, underscores, and dots
 	var result strings.Builder
@@ -0,0 +1,159 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// BuiltinScheme is the naming scheme of the variants this processor writes, images.variant_name
+const BuiltinScheme = "hugo-manager"
+
+// ClassicScheme recognizes variants named name.WIDTHxHEIGHT.ext when images.variant_name names
+// them otherwise, so the ones written before it changed keep their srcset
+const ClassicScheme = "classic"
+
+// variantScheme is a naming convention of processed variants, a regexp with the named groups
+// base, ext and w (width) or x (pixel density), and optionally h (height)
+type variantScheme struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// variant is a file name parsed by a scheme
+type variant struct {
+	base    string
+	ext     string
+	width   int
+	height  int
+	density float64
+}
+
+// variantSchemes returns the built-in scheme of a variant name template, the classic one when the
+// template isn't the default, and the configured ones, in the order they're tried
+func variantSchemes(variantName string, patterns []config.ImageVariantPattern) []variantScheme {
+	classic, _ := config.VariantNamePattern(config.DefaultVariantName)
+	builtin, err := config.VariantNamePattern(variantName)
+	if err != nil {
+		builtin = classic // Rejected when the configuration is loaded; empty means the default
+	}
+	schemes := []variantScheme{{name: BuiltinScheme, re: regexp.MustCompile(builtin)}}
+	if builtin != classic {
+		schemes = append(schemes, variantScheme{name: ClassicScheme, re: regexp.MustCompile(classic)})
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			continue // Rejected when the configuration is loaded
+		}
+		schemes = append(schemes, variantScheme{name: p.Name, re: re})
+	}
+	return schemes
+}
+
+// variantName names a variant this processor writes, with images.variant_name; the hash is that
+// of the variant's encoded bytes
+func (p *Processor) variantName(baseName string, width, height int, ext string, data []byte) string {
+	name := p.config.VariantName
+	if name == "" {
+		name = config.DefaultVariantName
+	}
+	hash := ""
+	if strings.Contains(name, "{hash}") {
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:4])
+	}
+	return strings.NewReplacer(
+		"{name}", baseName,
+		"{width}", strconv.Itoa(width),
+		"{height}", strconv.Itoa(height),
+		"{hash}", hash,
+		"{ext}", ext,
+	).Replace(name)
+}
+
+// ParseVariant reads a file name with the naming schemes of the processor, in order, and returns
+// the base name of the image the variant was made from and its width, 0 when the scheme doesn't
+// name it; ok is false when no scheme recognizes the name
+func (p *Processor) ParseVariant(name string) (base string, width int, ok bool) {
+	for _, scheme := range p.schemes {
+		if v, ok := scheme.parse(name); ok {
+			return v.base, v.width, true
+		}
+	}
+	return "", 0, false
+}
+
+// parse reads a file name; ok is false when the name doesn't follow the scheme
+func (s variantScheme) parse(name string) (variant, bool) {
+	m := s.re.FindStringSubmatch(name)
+	if m == nil {
+		return variant{}, false
+	}
+	group := func(name string) string {
+		if i := s.re.SubexpIndex(name); i >= 0 {
+			return m[i]
+		}
+		return ""
+	}
+
+	v := variant{base: group("base"), ext: strings.ToLower(group("ext"))}
+	if v.base == "" {
+		return variant{}, false
+	}
+	if w := group("w"); w != "" {
+		width, err := strconv.Atoi(w)
+		if err != nil || width <= 0 {
+			return variant{}, false
+		}
+		v.width = width
+	}
+	if h := group("h"); h != "" {
+		height, err := strconv.Atoi(h)
+		if err != nil || height <= 0 {
+			return variant{}, false
+		}
+		v.height = height
+	}
+	if s.re.SubexpIndex("x") >= 0 {
+		v.density = 1 // A name without the density suffix is the 1x image
+		if x := group("x"); x != "" {
+			density, err := strconv.ParseFloat(x, 64)
+			if err != nil || density <= 0 {
+				return variant{}, false
+			}
+			v.density = density
+		}
+	}
+	return v, true
+}
+
+// dimensions reads the width and height of an image from its header
+func dimensions(path string) (int, int) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0 // Formats without a registered decoder, like SVG
+	}
+	return cfg.Width, cfg.Height
+}
+
+// descriptor returns the srcset descriptor of a variant: its density for x-descriptor schemes,
+// otherwise its width
+func descriptor(v ProcessedImage) string {
+	if v.Density > 0 {
+		return strconv.FormatFloat(v.Density, 'f', -1, 64) + "x"
+	}
+	return strconv.Itoa(v.Width) + "w"
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ghostURL is the placeholder Ghost writes for its own address in exported content
+const ghostURL = "__GHOST_URL__"
+
+// ghostExport is the part of a Ghost JSON export the importer reads. Exports wrap the data in
+// db[0]; older ones have it at the top.
+type ghostExport struct {
+	DB   []struct{ Data ghostData } `json:"db"`
+	Data *ghostData                 `json:"data"`
+}
+
+type ghostData struct {
+	Posts        []ghostPost `json:"posts"`
+	Tags         []ghostTerm `json:"tags"`
+	Users        []ghostTerm `json:"users"`
+	PostsTags    []ghostLink `json:"posts_tags"`
+	PostsAuthors []ghostLink `json:"posts_authors"`
+}
+
+type ghostPost struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	HTML         string `json:"html"`
+	FeatureImage string `json:"feature_image"`
+	Status       string `json:"status"`
+	Type         string `json:"type"`
+	Page         bool   `json:"page"` // Before Ghost 3, pages were posts with page set
+	Excerpt      string `json:"custom_excerpt"`
+	Description  string `json:"meta_description"`
+	AuthorID     string `json:"author_id"` // Before Ghost 1, the only author
+	PublishedAt  string `json:"published_at"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+type ghostTerm struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ghostLink struct {
+	PostID   string `json:"post_id"`
+	TagID    string `json:"tag_id"`
+	AuthorID string `json:"author_id"`
+}
+
+// readGhost reads the posts and pages of a Ghost export. Internal tags, those starting with #,
+// are left out; Ghost's placeholder for its address becomes siteURL, or nothing without one.
+func readGhost(data []byte, siteURL string) ([]*post, error) {
+	var doc ghostExport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	var d ghostData
+	switch {
+	case len(doc.DB) > 0:
+		d = doc.DB[0].Data
+	case doc.Data != nil:
+		d = *doc.Data
+	default:
+		return nil, fmt.Errorf("%w: no db or data in the Ghost export", ErrInvalidExport)
+	}
+
+	tags := map[string]string{}
+	for _, t := range d.Tags {
+		if !strings.HasPrefix(t.Name, "#") {
+			tags[t.ID] = t.Name
+		}
+	}
+	users := map[string]string{}
+	for _, u := range d.Users {
+		users[u.ID] = u.Name
+	}
+	postTags := map[string][]string{}
+	for _, l := range d.PostsTags {
+		if name, ok := tags[l.TagID]; ok {
+			postTags[l.PostID] = append(postTags[l.PostID], name)
+		}
+	}
+	postAuthors := map[string][]string{}
+	for _, l := range d.PostsAuthors {
+		if name, ok := users[l.AuthorID]; ok {
+			postAuthors[l.PostID] = append(postAuthors[l.PostID], name)
+		}
+	}
+
+	base := strings.TrimRight(siteURL, "/")
+	var posts []*post
+	for _, gp := range d.Posts {
+		p := &post{
+			Title:   strings.TrimSpace(gp.Title),
+			Slug:    gp.Slug,
+			Page:    gp.Type == "page" || gp.Page,
+			Draft:   gp.Status != "published" && gp.Status != "scheduled",
+			Body:    strings.ReplaceAll(gp.HTML, ghostURL, base),
+			HTML:    true,
+			Summary: gp.Excerpt,
+			Image:   strings.ReplaceAll(gp.FeatureImage, ghostURL, base),
+			Tags:    postTags[gp.ID],
+			Authors: postAuthors[gp.ID],
+			OldURL:  base + "/" + gp.Slug + "/",
+		}
+		if p.Summary == "" {
+			p.Summary = gp.Description
+		}
+		if len(p.Authors) == 0 && users[gp.AuthorID] != "" {
+			p.Authors = []string{users[gp.AuthorID]}
+		}
+		for _, s := range []string{gp.PublishedAt, gp.CreatedAt} {
+			if t, ok := ghostTime(s); ok {
+				p.Date = t
+				break
+			}
+		}
+		if t, ok := ghostTime(gp.UpdatedAt); ok && t.After(p.Date) {
+			p.Lastmod = t
+		}
+		posts = append(posts, p)
+	}
+	return posts, nil
+}
+
+// ghostTime parses the dates of Ghost exports, in RFC 3339 or as "2006-01-02 15:04:05" in UTC
+func ghostTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse(wordpressTime, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,433 @@
+package importer
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// tokenRe splits HTML into comments, tags and the text between them
+	tokenRe = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+
+	// attrRe matches the attributes of a tag, quoted, unquoted or without a value
+	attrRe = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+	// captionRe matches WordPress [caption] shortcodes around an image, optionally linked
+	captionRe = regexp.MustCompile(`(?s)\[caption[^\]]*\]\s*((?:<a\b[^>]*>)?\s*<img\b[^>]*>\s*(?:</a>)?)\s*(.*?)\[/caption\]`)
+
+	// blankLineRe matches whitespace with an empty line in it, a paragraph break in WordPress content
+	blankLineRe = regexp.MustCompile(`[ \t\r]*\n[ \t\r]*\n\s*`)
+
+	// spaceRe matches runs of whitespace, collapsed to one space as browsers do
+	spaceRe = regexp.MustCompile(`\s+`)
+
+	// extraBlankRe matches more than one empty line
+	extraBlankRe = regexp.MustCompile(`\n{3,}`)
+)
+
+// voidTags have no closing tag
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// blockTags start a block of their own; other tags are part of the text around them
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "details": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true,
+	"main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "ul": true, "iframe": true, "video": true, "audio": true,
+}
+
+// droppedTags are left out with everything in them
+var droppedTags = map[string]bool{"script": true, "style": true, "noscript": true, "form": true, "button": true}
+
+// node is an element or, without a tag, a text of parsed HTML
+type node struct {
+	tag      string
+	attrs    map[string]string
+	text     string
+	children []*node
+}
+
+// parseHTML builds a tree from HTML, forgiving of unclosed and stray tags as exports are
+func parseHTML(src string) *node {
+	root := &node{tag: "body"}
+	stack := []*node{root}
+	top := func() *node { return stack[len(stack)-1] }
+
+	addText := func(text string) {
+		if text != "" {
+			top().children = append(top().children, &node{text: html.UnescapeString(text)})
+		}
+	}
+
+	last := 0
+	for _, m := range tokenRe.FindAllStringSubmatchIndex(src, -1) {
+		addText(src[last:m[0]])
+		last = m[1]
+		if m[4] < 0 {
+			continue // Comment
+		}
+		tag := strings.ToLower(src[m[4]:m[5]])
+		if src[m[2]:m[3]] == "/" {
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		rawAttrs := src[m[6]:m[7]]
+		n := &node{tag: tag, attrs: map[string]string{}}
+		for _, a := range attrRe.FindAllStringSubmatch(rawAttrs, -1) {
+			n.attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2] + a[3] + a[4])
+		}
+		// A new paragraph or list item closes the one that is still open
+		if tag == "p" || tag == "li" {
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+				if tag == "p" && blockTags[stack[i].tag] || tag == "li" && (stack[i].tag == "ul" || stack[i].tag == "ol") {
+					break
+				}
+			}
+		}
+		top().children = append(top().children, n)
+		if !voidTags[tag] && !strings.HasSuffix(strings.TrimSpace(rawAttrs), "/") {
+			stack = append(stack, n)
+		}
+	}
+	addText(src[last:])
+	return root
+}
+
+// htmlToMarkdown converts the HTML of a post to Markdown. Markup Markdown has no syntax for, such
+// as embeds, is kept as HTML.
+func htmlToMarkdown(src string) string {
+	src = captionRe.ReplaceAllString(src, "<figure>$1<figcaption>$2</figcaption></figure>")
+	out := blocks(parseHTML(src).children)
+	return strings.TrimSpace(extraBlankRe.ReplaceAllString(out, "\n\n")) + "\n"
+}
+
+// blocks renders nodes as Markdown blocks separated by empty lines, gathering the inline nodes
+// between blocks into paragraphs
+func blocks(nodes []*node) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		for _, para := range strings.Split(inline.String(), "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				parts = append(parts, para)
+			}
+		}
+		inline.Reset()
+	}
+	for _, n := range nodes {
+		if n.tag != "" && blockTags[n.tag] {
+			flush()
+			if b := strings.TrimSpace(block(n)); b != "" {
+				parts = append(parts, b)
+			}
+			continue
+		}
+		inline.WriteString(inlineText(n, true))
+	}
+	flush()
+	return strings.Join(parts, "\n\n")
+}
+
+// block renders a block element
+func block(n *node) string {
+	switch n.tag {
+	case "p", "dt", "summary":
+		return strings.TrimSpace(inlines(n.children, false))
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return strings.Repeat("#", int(n.tag[1]-'0')) + " " + strings.TrimSpace(inlines(n.children, false))
+	case "hr":
+		return "---"
+	case "blockquote":
+		return prefixLines(blocks(n.children), "> ", "> ")
+	case "ul", "ol":
+		return list(n)
+	case "pre":
+		return codeBlock(n)
+	case "figcaption":
+		if caption := strings.TrimSpace(inlines(n.children, false)); caption != "" {
+			return "*" + caption + "*"
+		}
+		return ""
+	case "table":
+		return table(n)
+	case "iframe", "video", "audio":
+		return rawElement(n)
+	}
+	return blocks(n.children)
+}
+
+// list renders the items of a list, with nested blocks indented under them
+func list(n *node) string {
+	var items []string
+	number := 1
+	for _, child := range n.children {
+		if child.tag != "li" {
+			continue
+		}
+		marker := "- "
+		if n.tag == "ol" {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		items = append(items, prefixLines(blocks(child.children), marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock renders a <pre> as a fenced code block, in the language of its class when it has one
+func codeBlock(n *node) string {
+	lang := language(n.attrs["class"])
+	if len(n.children) == 1 && n.children[0].tag == "code" && lang == "" {
+		lang = language(n.children[0].attrs["class"])
+	}
+	code := strings.Trim(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// language returns the language of a class such as "language-go" or "lang-go"
+func language(class string) string {
+	for _, c := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-", "brush:"} {
+			if strings.HasPrefix(c, prefix) {
+				return strings.TrimPrefix(c, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// table renders a table as a Markdown table, its first row the header
+func table(n *node) string {
+	var rows [][]string
+	var walk func(*node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			switch child.tag {
+			case "tr":
+				var cells []string
+				for _, cell := range child.children {
+					if cell.tag == "td" || cell.tag == "th" {
+						text := strings.TrimSpace(inlines(cell.children, false))
+						cells = append(cells, strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`))
+					}
+				}
+				rows = append(rows, cells)
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// rawElement writes an embed back as HTML, with its attributes and sources
+func rawElement(n *node) string {
+	var b strings.Builder
+	writeTag(&b, n)
+	for _, child := range n.children {
+		if child.tag == "source" || child.tag == "track" {
+			writeTag(&b, child)
+		}
+	}
+	b.WriteString("</" + n.tag + ">")
+	return b.String()
+}
+
+// writeTag writes the opening tag of an element, its attributes sorted
+func writeTag(b *strings.Builder, n *node) {
+	names := make([]string, 0, len(n.attrs))
+	for name := range n.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString("<" + n.tag)
+	for _, name := range names {
+		b.WriteString(" " + name + `="` + html.EscapeString(n.attrs[name]) + `"`)
+	}
+	b.WriteString(">")
+}
+
+// inlines renders nodes as the text of a paragraph. With breaks, empty lines in text are kept as
+// paragraph breaks, as WordPress writes paragraphs without <p>.
+func inlines(nodes []*node, breaks bool) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(inlineText(n, breaks))
+	}
+	return b.String()
+}
+
+// inlineText renders a text or an element inside a paragraph
+func inlineText(n *node, breaks bool) string {
+	if n.tag == "" {
+		text := n.text
+		if breaks {
+			text = blankLineRe.ReplaceAllString(text, "\x00")
+		}
+		text = escapeMarkdown(spaceRe.ReplaceAllString(text, " "))
+		return strings.ReplaceAll(text, "\x00", "\n\n")
+	}
+	if droppedTags[n.tag] {
+		return ""
+	}
+
+	inner := func() string { return inlines(n.children, breaks) }
+	switch n.tag {
+	case "strong", "b":
+		return wrap(inner(), "**")
+	case "em", "i", "cite":
+		return wrap(inner(), "*")
+	case "del", "s", "strike":
+		return wrap(inner(), "~~")
+	case "code", "kbd", "samp":
+		code := spaceRe.ReplaceAllString(textContent(n), " ")
+		if code == "" {
+			return ""
+		}
+		tick := "`"
+		for strings.Contains(code, tick) {
+			tick += "`"
+		}
+		return tick + code + tick
+	case "br":
+		return "\\\n"
+	case "img":
+		src := n.attrs["src"]
+		if src == "" {
+			return ""
+		}
+		alt := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(spaceRe.ReplaceAllString(n.attrs["alt"], " "))
+		return "![" + strings.TrimSpace(alt) + "](" + destination(src) + title(n.attrs["title"]) + ")"
+	case "a":
+		text := inner()
+		href := n.attrs["href"]
+		if href == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		lead, rest := splitSpace(text)
+		body, trail := splitTrailingSpace(rest)
+		return lead + "[" + body + "](" + destination(href) + title(n.attrs["title"]) + ")" + trail
+	}
+	if blockTags[n.tag] {
+		// A block inside text, such as a <div> inside a link, adds its text
+		return " " + strings.TrimSpace(inner()) + " "
+	}
+	return inner()
+}
+
+// wrap puts a marker around text, keeping the spaces at its ends outside so the marker still works
+func wrap(text, marker string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	lead, rest := splitSpace(text)
+	body, trail := splitTrailingSpace(rest)
+	return lead + marker + body + marker + trail
+}
+
+func splitSpace(s string) (string, string) {
+	trimmed := strings.TrimLeft(s, " \n")
+	return s[:len(s)-len(trimmed)], trimmed
+}
+
+func splitTrailingSpace(s string) (string, string) {
+	trimmed := strings.TrimRight(s, " \n")
+	return trimmed, s[len(trimmed):]
+}
+
+// destination returns a URL as a link destination, in angle brackets when it has spaces
+func destination(u string) string {
+	u = strings.TrimSpace(u)
+	if strings.ContainsAny(u, " ()") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+// title returns the title part of a link, empty when there's no title
+func title(t string) string {
+	if t = strings.TrimSpace(t); t == "" {
+		return ""
+	}
+	return ` "` + strings.ReplaceAll(t, `"`, `\"`) + `"`
+}
+
+// textContent returns the text in an element, as written
+func textContent(n *node) string {
+	if n.tag == "" {
+		return n.text
+	}
+	if n.tag == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// markdownEscaper escapes the characters that would turn text into Markdown markup
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// prefixLines puts first before the first line of text and rest before the others, leaving empty
+// lines empty
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if strings.TrimSpace(line) == "" && i > 0 {
+			lines[i] = strings.TrimRight(prefix, " ")
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,534 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Export formats accepted by Import
+const (
+	KindWordPress = "wordpress" // WordPress eXtended RSS (WXR) XML
+	KindGhost     = "ghost"     // Ghost JSON export
+	KindJekyll    = "jekyll"    // Jekyll site, a folder or a zip of one
+)
+
+// Statuses of imported posts
+const (
+	StatusCreated  = "created"
+	StatusReplaced = "replaced"
+	StatusSkipped  = "skipped" // A file already has the path; overwrite replaces it
+)
+
+// Default folders of imported posts, pages and images
+const (
+	defaultOutput = "content/posts"
+	defaultPages  = "content"
+	defaultImages = "static/images/imported"
+)
+
+// maxImageSize caps the bytes of an image downloaded during an import
+const maxImageSize = 50 << 20
+
+var (
+	// ErrUnknownKind is returned for an export whose format isn't known or can't be detected
+	ErrUnknownKind = errors.New("unknown export format")
+
+	// ErrInvalidExport is returned for an export that can't be read as its format
+	ErrInvalidExport = errors.New("invalid export")
+
+	// ErrNotStatic is returned for an images folder outside static/, which has no public URL
+	ErrNotStatic = errors.New("images folder must be inside static/")
+)
+
+var (
+	// markdownImageRe matches the URLs of Markdown images
+	markdownImageRe = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+
+	// htmlImageRe matches the src of images left as HTML
+	htmlImageRe = regexp.MustCompile(`(?i)<img\b[^>]*?\ssrc\s*=\s*["']([^"']+)["']`)
+)
+
+// Options chooses where imported posts and images go
+type Options struct {
+	Kind      string `json:"kind"`      // wordpress, ghost or jekyll (empty = detected)
+	Output    string `json:"output"`    // Folder of posts (default content/posts)
+	Pages     string `json:"pages"`     // Folder of pages, for WordPress and Ghost (default content)
+	Images    string `json:"images"`    // Folder of images, inside static (default static/images/imported)
+	SiteURL   string `json:"siteUrl"`   // Address of the old site, to download images with relative URLs (default the one in the export)
+	Aliases   bool   `json:"aliases"`   // Add the old URL of each post to its aliases, so Hugo redirects it
+	Overwrite bool   `json:"overwrite"` // Replace files that already have the path of a post
+}
+
+// Entry maps the old URL of an imported post to its new file and URL
+type Entry struct {
+	Title    string   `json:"title"`
+	OldURL   string   `json:"oldUrl,omitempty"`
+	Path     string   `json:"path"`
+	URL      string   `json:"url,omitempty"`
+	Draft    bool     `json:"draft"`
+	Status   string   `json:"status"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Image is an image of the export saved in the images folder
+type Image struct {
+	Source string `json:"source"` // URL in the export
+	Path   string `json:"path"`   // Project-relative path
+	URL    string `json:"url"`
+}
+
+// Result reports what an import wrote
+type Result struct {
+	Kind   string  `json:"kind"`
+	Posts  []Entry `json:"posts"`
+	Images []Image `json:"images"`
+}
+
+// post is a post or page read from an export, before it's written
+type post struct {
+	Title      string
+	Slug       string
+	Date       time.Time
+	Lastmod    time.Time
+	Draft      bool
+	Page       bool
+	Tags       []string
+	Categories []string
+	Authors    []string
+	Summary    string
+	Image      string // Featured image
+	Body       string
+	HTML       bool // Body is HTML rather than Markdown
+	OldURL     string
+	Params     map[string]interface{} // Other front matter, kept as it is
+	Warnings   []string
+}
+
+// Manager imports content exported from other blogging platforms
+type Manager struct {
+	projectDir string
+	fileMgr    *files.Manager
+	client     *http.Client
+}
+
+// NewManager creates a new importer. Paths of exports in the project are validated by fileMgr.
+func NewManager(projectDir string, fileMgr *files.Manager) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		fileMgr:    fileMgr,
+		client:     newClient(),
+	}
+}
+
+// Import converts an export to Hugo content: a WordPress WXR file, a Ghost JSON file or a zip of a
+// Jekyll site. The format is detected when opts doesn't give it.
+func (m *Manager) Import(data []byte, opts Options) (*Result, error) {
+	kind := opts.Kind
+	if kind == "" {
+		kind = detect(data)
+	}
+
+	switch kind {
+	case KindWordPress:
+		if !bytes.Contains(data, []byte("wordpress.org/export/")) {
+			return nil, fmt.Errorf("%w: not a WordPress export", ErrInvalidExport)
+		}
+		posts, siteURL, err := readWordPress(data)
+		if err != nil {
+			return nil, err
+		}
+		return m.run(kind, posts, nil, siteURL, opts)
+
+	case KindGhost:
+		posts, err := readGhost(data, opts.SiteURL)
+		if err != nil {
+			return nil, err
+		}
+		return m.run(kind, posts, nil, "", opts)
+
+	case KindJekyll:
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: a Jekyll site must be a zip: %v", ErrInvalidExport, err)
+		}
+		return m.jekyll(archive, opts)
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("%w: not a WXR, JSON or zip file; set the kind", ErrUnknownKind)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+}
+
+// ImportPath imports a project-relative export file, or a Jekyll site folder
+func (m *Manager) ImportPath(rel string, opts Options) (*Result, error) {
+	if !m.fileMgr.IsValidPath(rel) {
+		return nil, fmt.Errorf("%w: %s", files.ErrInvalidPath, rel)
+	}
+	full := filepath.Join(m.projectDir, filepath.FromSlash(rel))
+	stat, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		if opts.Kind != "" && opts.Kind != KindJekyll {
+			return nil, fmt.Errorf("%w: a folder can only be a Jekyll site", ErrUnknownKind)
+		}
+		return m.jekyll(os.DirFS(full), opts)
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	return m.Import(data, opts)
+}
+
+// jekyll imports the Jekyll site in fsys, whose files are the images its posts point to
+func (m *Manager) jekyll(fsys fs.FS, opts Options) (*Result, error) {
+	root, err := jekyllRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+	posts, siteURL, err := readJekyll(root)
+	if err != nil {
+		return nil, err
+	}
+	return m.run(KindJekyll, posts, root, siteURL, opts)
+}
+
+// detect returns the format of an export from its first bytes, "" when it isn't known
+func detect(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return KindJekyll
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return KindWordPress
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return KindGhost
+	}
+	return ""
+}
+
+// importRun is the state of one import: the images saved so far and the paths taken
+type importRun struct {
+	opts    Options
+	assets  fs.FS  // Files of the exported site, for images with relative URLs
+	siteURL string // Address of the old site, for images with relative URLs
+	siteCfg *site.Config
+	result  *Result
+	images  map[string]string // New URL by URL in the export, "" for images that failed
+	paths   map[string]bool   // Content files written by this import
+}
+
+// run writes the posts of an export to content files and their images to the images folder
+func (m *Manager) run(kind string, posts []*post, assets fs.FS, siteURL string, opts Options) (*Result, error) {
+	opts.Output = cleanFolder(opts.Output, defaultOutput)
+	opts.Pages = cleanFolder(opts.Pages, defaultPages)
+	opts.Images = cleanFolder(opts.Images, defaultImages)
+	if opts.Images != "static" && !strings.HasPrefix(opts.Images, "static/") {
+		return nil, ErrNotStatic
+	}
+	if opts.SiteURL != "" {
+		siteURL = opts.SiteURL
+	}
+
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		siteCfg = nil // New URLs are left out of the report
+	}
+	r := &importRun{
+		opts:    opts,
+		assets:  assets,
+		siteURL: strings.TrimRight(siteURL, "/"),
+		siteCfg: siteCfg,
+		result:  &Result{Kind: kind, Posts: []Entry{}, Images: []Image{}},
+		images:  map[string]string{},
+		paths:   map[string]bool{},
+	}
+	for _, p := range posts {
+		entry, err := m.write(r, p)
+		if err != nil {
+			return nil, err
+		}
+		r.result.Posts = append(r.result.Posts, entry)
+	}
+	return r.result, nil
+}
+
+// cleanFolder returns a project-relative folder, or def when it's empty
+func cleanFolder(folder, def string) string {
+	folder = strings.Trim(path.Clean("/"+filepath.ToSlash(strings.TrimSpace(folder))), "/")
+	if folder == "" {
+		return def
+	}
+	return folder
+}
+
+// write converts a post to a content file, with its images saved, unless a file already has its
+// path and the import doesn't overwrite
+func (m *Manager) write(r *importRun, p *post) (Entry, error) {
+	folder := r.opts.Output
+	if p.Page {
+		folder = r.opts.Pages
+	}
+	name := postSlug(p)
+	rel := path.Join(folder, name+".md")
+	for n := 2; r.paths[rel]; n++ {
+		rel = path.Join(folder, name+"-"+strconv.Itoa(n)+".md")
+	}
+	r.paths[rel] = true
+
+	entry := Entry{Title: p.Title, OldURL: p.OldURL, Path: rel, Draft: p.Draft, Status: StatusCreated, Warnings: p.Warnings}
+	full := filepath.Join(m.projectDir, filepath.FromSlash(rel))
+	if _, err := os.Stat(full); err == nil {
+		if !r.opts.Overwrite {
+			entry.Status = StatusSkipped
+			return entry, nil
+		}
+		entry.Status = StatusReplaced
+	}
+
+	body := p.Body
+	summary := strings.TrimSpace(p.Summary)
+	if p.HTML {
+		body = htmlToMarkdown(body)
+		summary = strings.TrimSpace(spaceRe.ReplaceAllString(textContent(parseHTML(summary)), " "))
+	}
+
+	// Images are saved once, however many posts show them
+	var sources []string
+	for _, re := range []*regexp.Regexp{markdownImageRe, htmlImageRe} {
+		for _, match := range re.FindAllStringSubmatch(body, -1) {
+			sources = append(sources, match[1])
+		}
+	}
+	for _, src := range sources {
+		if u := m.image(r, src, &entry); u != "" {
+			body = strings.ReplaceAll(body, src, u)
+		}
+	}
+
+	fm := hugocontent.FrontMatter{}
+	for key, value := range p.Params {
+		fm[key] = value
+	}
+	fm["title"] = p.Title
+	fm["slug"] = name // Permalinks with :slug would otherwise use the title
+	if !p.Date.IsZero() {
+		fm["date"] = p.Date
+	}
+	if !p.Lastmod.IsZero() {
+		fm["lastmod"] = p.Lastmod
+	}
+	if p.Draft {
+		fm["draft"] = true
+	}
+	if len(p.Tags) > 0 {
+		fm["tags"] = p.Tags
+	}
+	if len(p.Categories) > 0 {
+		fm["categories"] = p.Categories
+	}
+	if len(p.Authors) > 0 {
+		fm["authors"] = p.Authors
+	}
+	if summary != "" {
+		fm["description"] = summary
+	}
+	if p.Image != "" {
+		image := p.Image
+		if u := m.image(r, p.Image, &entry); u != "" {
+			image = u
+		}
+		fm["images"] = []string{image}
+	}
+
+	if r.siteCfg != nil {
+		if loc, err := hugocontent.Locate(r.siteCfg, rel); err == nil {
+			entry.URL = hugocontent.PermalinkFor(r.siteCfg, loc, fm, rel).URL
+		}
+	}
+	if alias := urlPath(p.OldURL); r.opts.Aliases && alias != "" && alias != "/" && alias != entry.URL {
+		fm["aliases"] = []string{alias}
+	}
+
+	out, err := hugocontent.Encode(fm, "\n"+body, hugocontent.ArchetypeFormat(m.projectDir, rel))
+	if err != nil {
+		return entry, err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return entry, err
+	}
+	if err := atomicfile.WriteFile(full, out, 0644); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// postSlug returns the file name of a post: its slug, or the slug of its title
+func postSlug(p *post) string {
+	name := p.Slug
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped // WordPress escapes slugs that aren't ASCII
+	}
+	if s := slug.Make(name); s != "" {
+		return s
+	}
+	if s := slug.Make(p.Title); s != "" {
+		return s
+	}
+	return "post"
+}
+
+// urlPath returns the path of a URL, "" when it has none
+func urlPath(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Path
+}
+
+// image saves an image of a post to the images folder and returns its new URL, or "" with a
+// warning on the entry when it can't be read
+func (m *Manager) image(r *importRun, src string, entry *Entry) string {
+	src = strings.TrimSpace(src)
+	if u, ok := r.images[src]; ok {
+		return u
+	}
+	r.images[src] = ""
+	if src == "" || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "/"+strings.TrimPrefix(r.opts.Images, "static/")+"/") {
+		return ""
+	}
+
+	data, name, err := m.readImage(r, src)
+	if err != nil {
+		entry.Warnings = append(entry.Warnings, fmt.Sprintf("Image %s was not imported: %v", src, err))
+		return ""
+	}
+	rel, err := m.saveImage(r.opts.Images, name, data)
+	if err != nil {
+		entry.Warnings = append(entry.Warnings, fmt.Sprintf("Image %s was not saved: %v", src, err))
+		return ""
+	}
+
+	u := strings.TrimPrefix(rel, "static")
+	r.images[src] = u
+	r.result.Images = append(r.result.Images, Image{Source: src, Path: rel, URL: u})
+	return u
+}
+
+// readImage reads an image from the files of the exported site or downloads it, and returns its
+// bytes and file name
+func (m *Manager) readImage(r *importRun, src string) ([]byte, string, error) {
+	u := src
+	if strings.HasPrefix(u, "//") {
+		u = "https:" + u
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !parsed.IsAbs() {
+		if r.assets != nil {
+			name := strings.TrimPrefix(path.Clean("/"+parsed.Path), "/")
+			if data, err := fs.ReadFile(r.assets, name); err == nil {
+				return data, path.Base(name), nil
+			}
+		}
+		if r.siteURL == "" {
+			return nil, "", errors.New("its URL is relative and there's no site URL to download it from")
+		}
+		base, err := url.Parse(r.siteURL + "/")
+		if err != nil {
+			return nil, "", err
+		}
+		parsed = base.ResolveReference(parsed)
+	}
+	if err := checkURL(parsed); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := m.client.Get(parsed.String())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", fmt.Errorf("larger than %d MB", maxImageSize>>20)
+	}
+
+	name := path.Base(parsed.Path)
+	if path.Ext(name) == "" {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if !strings.HasPrefix(mediaType, "image/") {
+			return nil, "", fmt.Errorf("not an image (%s)", mediaType)
+		}
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return data, name, nil
+}
+
+// saveImage writes an image to a folder under a free name, or returns the path of the image
+// with its name when it's the same
+func (m *Manager) saveImage(folder, name string, data []byte) (string, error) {
+	name = images.SanitizeFilename(name)
+	ext := strings.ToLower(path.Ext(name))
+	base := strings.TrimSuffix(name, path.Ext(name))
+	if base == "" || base == "." {
+		base = "image"
+	}
+
+	dir := filepath.Join(m.projectDir, filepath.FromSlash(folder))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	for n := 1; ; n++ {
+		candidate := base + ext
+		if n > 1 {
+			candidate = base + "-" + strconv.Itoa(n) + ext
+		}
+		full := filepath.Join(dir, candidate)
+		existing, err := os.ReadFile(full)
+		if err == nil && !bytes.Equal(existing, data) {
+			continue
+		}
+		if err != nil {
+			if err := atomicfile.WriteFile(full, data, 0644); err != nil {
+				return "", err
+			}
+		}
+		return path.Join(folder, candidate), nil
+	}
+}
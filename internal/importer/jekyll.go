@@ -0,0 +1,281 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+var (
+	// postNameRe matches the file names of Jekyll posts, YYYY-MM-DD-title.ext
+	postNameRe = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})-(.+)$`)
+
+	// Liquid tags Hugo has an equivalent or no need for
+	highlightRe = regexp.MustCompile(`\{%-?\s*highlight\s+([\w+#-]+)[^%]*-?%\}`)
+	endHighRe   = regexp.MustCompile(`\{%-?\s*endhighlight\s*-?%\}`)
+	rawRe       = regexp.MustCompile(`\{%-?\s*(?:end)?raw\s*-?%\}`)
+	siteURLRe   = regexp.MustCompile(`\{\{-?\s*site\.(?:baseurl|url)\s*-?\}\}`)
+	urlFilterRe = regexp.MustCompile(`\{\{-?\s*["']([^"']*)["']\s*\|\s*(?:relative_url|absolute_url)\s*-?\}\}`)
+	postURLRe   = regexp.MustCompile(`\{%-?\s*post_url\s+(\S+)\s*-?%\}`)
+	liquidRe    = regexp.MustCompile(`\{%.*?%\}`)
+)
+
+// jekyllStyles are the built-in permalink styles of Jekyll
+var jekyllStyles = map[string]string{
+	"date":    "/:categories/:year/:month/:day/:title:output_ext",
+	"pretty":  "/:categories/:year/:month/:day/:title/",
+	"ordinal": "/:categories/:year/:y_day/:title:output_ext",
+	"none":    "/:categories/:title:output_ext",
+}
+
+// jekyllExts are the extensions of Jekyll posts, and whether they are HTML
+var jekyllExts = map[string]bool{".md": false, ".markdown": false, ".mkd": false, ".mkdn": false, ".html": true}
+
+// jekyllKeys are the front matter keys the importer converts; the others are kept as they are
+var jekyllKeys = map[string]bool{
+	"layout": true, "title": true, "date": true, "categories": true, "category": true, "tags": true,
+	"permalink": true, "published": true, "excerpt": true, "description": true, "image": true,
+	"author": true, "authors": true, "slug": true,
+}
+
+// jekyllConfig is the part of _config.yml the importer reads
+type jekyllConfig struct {
+	URL       string `yaml:"url"`
+	BaseURL   string `yaml:"baseurl"`
+	Permalink string `yaml:"permalink"`
+}
+
+// jekyllRoot returns the folder of a Jekyll site in fsys: its root, or the only folder at its root
+// when that's where _posts is, as in a zip of a repository
+func jekyllRoot(fsys fs.FS) (fs.FS, error) {
+	if isJekyllSite(fsys) {
+		return fsys, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if sub, err := fs.Sub(fsys, entry.Name()); err == nil && isJekyllSite(sub) {
+				return sub, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no _posts or _config.yml in the Jekyll site", ErrInvalidExport)
+}
+
+func isJekyllSite(fsys fs.FS) bool {
+	for _, name := range []string{"_posts", "_config.yml", "_drafts"} {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readJekyll reads the posts and drafts of a Jekyll site, with the URL of the site
+func readJekyll(fsys fs.FS) ([]*post, string, error) {
+	var cfg jekyllConfig
+	if data, err := fs.ReadFile(fsys, "_config.yml"); err == nil {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, "", fmt.Errorf("%w: _config.yml: %v", ErrInvalidExport, err)
+		}
+	}
+	pattern := cfg.Permalink
+	if style, ok := jekyllStyles[pattern]; ok {
+		pattern = style
+	} else if pattern == "" {
+		pattern = jekyllStyles["date"]
+	}
+	baseURL := strings.TrimRight(cfg.URL, "/") + strings.TrimRight(cfg.BaseURL, "/")
+
+	var posts []*post
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if base := path.Base(name); name != "." && (base == "_site" || base == "node_modules" || base == "vendor" || strings.HasPrefix(base, ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		dir := path.Base(path.Dir(name))
+		if dir != "_posts" && dir != "_drafts" {
+			return nil
+		}
+		isHTML, ok := jekyllExts[strings.ToLower(path.Ext(name))]
+		if !ok {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		p, err := jekyllPost(name, data, isHTML, dir == "_drafts", pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if dir == "_drafts" {
+			p.OldURL = "" // Never published
+		} else {
+			p.OldURL = baseURL + p.OldURL
+		}
+		posts = append(posts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, strings.TrimRight(cfg.URL, "/"), nil
+}
+
+// jekyllPost converts one post; its old URL is the path of its permalink
+func jekyllPost(name string, data []byte, isHTML, draft bool, pattern string) (*post, error) {
+	fm, body, _, err := hugocontent.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	p := &post{Slug: base, Draft: draft, HTML: isHTML, Params: map[string]interface{}{}}
+	if m := postNameRe.FindStringSubmatch(base); m != nil {
+		p.Slug = m[4]
+		p.Date, _ = time.Parse("2006-01-02", m[1]+"-"+m[2]+"-"+m[3])
+	}
+	if t, ok := fm.Time("date"); ok {
+		p.Date = t
+	}
+	p.Title = fm.String("title")
+	if p.Title == "" {
+		p.Title = strings.ReplaceAll(p.Slug, "-", " ")
+	}
+	if published, ok := fm["published"].(bool); ok && !published {
+		p.Draft = true
+	}
+	p.Categories = words(fm["categories"])
+	p.Categories = append(p.Categories, words(fm["category"])...)
+	// Folders above _posts are categories too
+	for _, c := range strings.Split(path.Dir(path.Dir(name)), "/") {
+		if c != "." && c != "" && !strings.HasPrefix(c, "_") {
+			p.Categories = append(p.Categories, c)
+		}
+	}
+	p.Tags = words(fm["tags"])
+	switch author := fm["author"].(type) {
+	case string:
+		p.Authors = []string{strings.TrimSpace(author)}
+	case []interface{}:
+		p.Authors = words(author)
+	}
+	p.Authors = append(p.Authors, words(fm["authors"])...)
+	p.Summary = fm.String("excerpt")
+	if p.Summary == "" {
+		p.Summary = fm.String("description")
+	}
+	switch image := fm["image"].(type) {
+	case string:
+		p.Image = image
+	case map[string]interface{}:
+		p.Image, _ = image["path"].(string)
+	}
+	for key, value := range fm {
+		if !jekyllKeys[key] {
+			p.Params[key] = value
+		}
+	}
+
+	urlPattern := pattern
+	if permalink := fm.String("permalink"); permalink != "" {
+		urlPattern = permalink
+	}
+	urlSlug := p.Slug
+	if s := fm.String("slug"); s != "" {
+		urlSlug = s
+	}
+	p.OldURL = jekyllURL(urlPattern, p, urlSlug)
+
+	p.Body, p.Warnings = liquid(body)
+	return p, nil
+}
+
+// jekyllURL expands the placeholders of a permalink pattern for a post
+func jekyllURL(pattern string, p *post, title string) string {
+	var categories []string
+	for _, c := range p.Categories {
+		if s := slug.Make(c); s != "" {
+			categories = append(categories, s)
+		}
+	}
+	d := p.Date
+	u := strings.NewReplacer(
+		":categories", strings.Join(categories, "/"),
+		":year", d.Format("2006"),
+		":short_year", d.Format("06"),
+		":month", d.Format("01"),
+		":i_month", strconv.Itoa(int(d.Month())),
+		":day", d.Format("02"),
+		":i_day", strconv.Itoa(d.Day()),
+		":y_day", fmt.Sprintf("%03d", d.YearDay()),
+		":title", title,
+		":slug", title,
+		":output_ext", ".html",
+	).Replace(pattern)
+	for strings.Contains(u, "//") {
+		u = strings.ReplaceAll(u, "//", "/")
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return u
+}
+
+// words returns a front matter list, or a string of space-separated words, as a list
+func words(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s := strings.TrimSpace(fmt.Sprint(item)); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// liquid replaces the Liquid of a Jekyll post that has a Markdown or Hugo equivalent, and warns
+// about the tags it leaves in
+func liquid(body string) (string, []string) {
+	body = highlightRe.ReplaceAllString(body, "```$1")
+	body = endHighRe.ReplaceAllString(body, "```")
+	body = rawRe.ReplaceAllString(body, "")
+	body = siteURLRe.ReplaceAllString(body, "")
+	body = urlFilterRe.ReplaceAllString(body, "$1")
+	body = postURLRe.ReplaceAllStringFunc(body, func(tag string) string {
+		name := path.Base(postURLRe.FindStringSubmatch(tag)[1])
+		if m := postNameRe.FindStringSubmatch(name); m != nil {
+			name = m[4]
+		}
+		return `{{< ref "` + name + `" >}}`
+	})
+
+	var warnings []string
+	for _, tag := range liquidRe.FindAllString(body, -1) {
+		warnings = append(warnings, "Liquid tag left as it is: "+tag)
+	}
+	return body, warnings
+}
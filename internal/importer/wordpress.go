@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// wxr is the part of a WordPress eXtended RSS export the importer reads
+type wxr struct {
+	Channel struct {
+		Link  string    `xml:"link"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title    string        `xml:"title"`
+	Link     string        `xml:"link"`
+	Creator  string        `xml:"creator"`
+	Encoded  []wxrEncoded  `xml:"encoded"`
+	ID       string        `xml:"post_id"`
+	Date     string        `xml:"post_date"`
+	DateGMT  string        `xml:"post_date_gmt"`
+	Modified string        `xml:"post_modified_gmt"`
+	Name     string        `xml:"post_name"`
+	Status   string        `xml:"status"`
+	Type     string        `xml:"post_type"`
+	URL      string        `xml:"attachment_url"`
+	Terms    []wxrTerm     `xml:"category"`
+	Meta     []wxrPostMeta `xml:"postmeta"`
+}
+
+// wxrEncoded is the content or the excerpt of an item, told apart by namespace
+type wxrEncoded struct {
+	XMLName xml.Name `xml:"encoded"`
+	Value   string   `xml:",chardata"`
+}
+
+type wxrTerm struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type wxrPostMeta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+// wordpressTime is how WordPress writes dates
+const wordpressTime = "2006-01-02 15:04:05"
+
+// readWordPress reads the posts and pages of a WXR export. Attachments give the featured image of
+// the posts that point to them; revisions, menus, trashed and auto-draft items are left out.
+func readWordPress(data []byte) ([]*post, string, error) {
+	var doc wxr
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	attachments := map[string]string{}
+	for _, item := range doc.Channel.Items {
+		if item.Type == "attachment" && item.URL != "" {
+			attachments[item.ID] = item.URL
+		}
+	}
+
+	var posts []*post
+	for _, item := range doc.Channel.Items {
+		if item.Type != "post" && item.Type != "page" {
+			continue
+		}
+		if item.Status == "trash" || item.Status == "auto-draft" || item.Status == "inherit" {
+			continue
+		}
+
+		p := &post{
+			Title:  strings.TrimSpace(item.Title),
+			Slug:   item.Name,
+			Page:   item.Type == "page",
+			Draft:  item.Status != "publish" && item.Status != "future",
+			OldURL: item.Link,
+			HTML:   true,
+		}
+		if item.Creator != "" {
+			p.Authors = []string{item.Creator}
+		}
+		for _, enc := range item.Encoded {
+			if strings.Contains(enc.XMLName.Space, "/excerpt/") {
+				p.Summary = strings.TrimSpace(enc.Value)
+			} else {
+				p.Body = enc.Value
+			}
+		}
+		// Drafts have no GMT date, only the local one
+		if t, err := time.Parse(wordpressTime, item.DateGMT); err == nil {
+			p.Date = t.UTC()
+		} else if t, err := time.Parse(wordpressTime, item.Date); err == nil {
+			p.Date = t
+		}
+		if t, err := time.Parse(wordpressTime, item.Modified); err == nil && t.After(p.Date) {
+			p.Lastmod = t.UTC()
+		}
+		for _, term := range item.Terms {
+			name := strings.TrimSpace(term.Name)
+			switch {
+			case name == "":
+			case term.Domain == "category" && name != "Uncategorized":
+				p.Categories = append(p.Categories, name)
+			case term.Domain == "post_tag":
+				p.Tags = append(p.Tags, name)
+			}
+		}
+		for _, meta := range item.Meta {
+			if meta.Key == "_thumbnail_id" {
+				p.Image = attachments[meta.Value]
+			}
+		}
+		posts = append(posts, p)
+	}
+	return posts, doc.Channel.Link, nil
+}
@@ -0,0 +1,263 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// FrontMatterLinter checks the front matter of pages against the schema of their section
+type FrontMatterLinter struct {
+	projectDir string
+	config     config.FrontMatterConfig
+}
+
+// NewFrontMatterLinter creates a new front matter linter
+func NewFrontMatterLinter(projectDir string, cfg config.FrontMatterConfig) *FrontMatterLinter {
+	return &FrontMatterLinter{projectDir: projectDir, config: cfg}
+}
+
+// LintAll checks every page in the content directories. It stops with the error of ctx once it's
+// done.
+func (l *FrontMatterLinter) LintAll(ctx context.Context) (*Report, error) {
+	siteCfg, err := site.Load(l.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	paths, err := contentFiles(l.projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		diagnostics = append(diagnostics, l.lint(siteCfg, p, string(data))...)
+	}
+	return newReport(len(paths), diagnostics), nil
+}
+
+// LintFile checks a single file
+func (l *FrontMatterLinter) LintFile(path string) (*Report, error) {
+	data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+	return l.LintContent(path, string(data))
+}
+
+// LintContent checks unsaved content of the file at path. Files that aren't pages in a content
+// directory have no schema and nothing to report.
+func (l *FrontMatterLinter) LintContent(path, content string) (*Report, error) {
+	siteCfg, err := site.Load(l.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	return newReport(1, l.lint(siteCfg, filepath.ToSlash(path), content)), nil
+}
+
+// lint checks the front matter of a page against the schema of its section
+func (l *FrontMatterLinter) lint(siteCfg *site.Config, file, content string) []Diagnostic {
+	schema, ok := l.schema(siteCfg, file)
+	if !ok {
+		return nil
+	}
+
+	fm, body, format, err := hugocontent.Parse([]byte(content))
+	if err != nil {
+		return []Diagnostic{{File: file, Line: 1, Column: 1, Severity: SeverityError, Rule: "frontmatter-invalid", Message: err.Error()}}
+	}
+	block := ""
+	if strings.HasSuffix(content, body) {
+		block = content[:len(content)-len(body)]
+	}
+	report := func(severity Severity, rule, key, message string) Diagnostic {
+		line, text := fieldLine(block, format, key)
+		return Diagnostic{File: file, Line: line, Column: 1, EndLine: line, EndColumn: len(text) + 1, Severity: severity, Rule: rule, Message: message}
+	}
+
+	var diagnostics []Diagnostic
+	names := make([]string, 0, len(schema.Fields))
+	for name := range schema.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := schema.Fields[name]
+		value := fm.Get(name)
+		switch {
+		case value == nil:
+			if field.Required {
+				diagnostics = append(diagnostics, report(SeverityError, "frontmatter-required", name, fmt.Sprintf("%q is required", name)))
+			}
+		case isEmptyValue(value):
+			if field.Required {
+				diagnostics = append(diagnostics, report(SeverityError, "frontmatter-required", name, fmt.Sprintf("%q is required and can't be empty", name)))
+			}
+		default:
+			if problem := checkFieldValue(field, value); problem != "" {
+				diagnostics = append(diagnostics, report(SeverityError, "frontmatter-type", name, fmt.Sprintf("%q %s", name, problem)))
+			}
+		}
+	}
+
+	if schema.Strict {
+		known := make(map[string]bool, len(schema.Fields))
+		for name := range schema.Fields {
+			known[strings.ToLower(name)] = true
+		}
+		keys := make([]string, 0, len(fm))
+		for key := range fm {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !known[strings.ToLower(key)] {
+				diagnostics = append(diagnostics, report(SeverityWarning, "frontmatter-unknown", key, fmt.Sprintf("%q is not a field of this section", key)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// schema returns the schema of the section of a page. Section pages (_index files) and files
+// outside the content directories have none.
+func (l *FrontMatterLinter) schema(siteCfg *site.Config, file string) (config.FrontMatterSchema, bool) {
+	if ext := strings.ToLower(path.Ext(file)); ext != ".md" && ext != ".markdown" {
+		return config.FrontMatterSchema{}, false
+	}
+	loc, err := hugocontent.Locate(siteCfg, file)
+	if err != nil || loc.Base == "_index" {
+		return config.FrontMatterSchema{}, false
+	}
+
+	schema := l.config.Default
+	if section, _, _ := strings.Cut(loc.Dir, "/"); section != "" {
+		if s, ok := l.config.Sections[section]; ok {
+			schema = s
+		}
+	}
+	return schema, len(schema.Fields) > 0 || schema.Strict
+}
+
+// checkFieldValue returns what's wrong with the value of a field for its type, or "" when it fits
+func checkFieldValue(field config.FrontMatterField, value interface{}) string {
+	switch field.Type {
+	case "text", "textarea", "image", "reference":
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("should be text, not %s", describeValue(value))
+		}
+	case "select":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("should be text, not %s", describeValue(value))
+		}
+		for _, option := range field.Options {
+			if s == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("is %q, not one of: %s", s, strings.Join(field.Options, ", "))
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+		default:
+			return fmt.Sprintf("should be a number, not %s", describeValue(value))
+		}
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("should be true or false, not %s", describeValue(value))
+		}
+	case "date":
+		switch v := value.(type) {
+		case time.Time:
+		case string:
+			if _, ok := hugocontent.ParseDate(v); !ok {
+				return fmt.Sprintf("is %q, not a date Hugo reads, such as 2006-01-02 or 2006-01-02T15:04:05Z07:00", v)
+			}
+		default:
+			return fmt.Sprintf("should be a date, not %s", describeValue(value))
+		}
+	case "array", "tags":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprintf("should be a list, not %s", describeValue(value))
+		}
+		if field.Type == "tags" {
+			for _, item := range list {
+				if _, ok := item.(string); !ok {
+					return fmt.Sprintf("should be a list of text, but has %s", describeValue(item))
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// describeValue names the type of a front matter value in messages
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "text"
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("the number %v", v)
+	case time.Time:
+		return "a date"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a map"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// isEmptyValue reports whether a value is blank text or an empty list
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// fieldLine returns the line of a top-level key in the front matter block of a file and its text,
+// or the first line when the key isn't there
+func fieldLine(block, format, key string) (int, string) {
+	lines := strings.Split(block, "\n")
+	for i, line := range lines {
+		if format == hugocontent.FormatTOML && strings.HasPrefix(strings.TrimSpace(line), "[") {
+			break // Keys after a table header belong to the table
+		}
+		if format != hugocontent.FormatJSON && strings.TrimLeft(line, " \t") != line {
+			continue // Nested in YAML
+		}
+		name := strings.Trim(strings.TrimSpace(line), `"'`)
+		if len(name) < len(key) || !strings.EqualFold(name[:len(key)], key) {
+			continue
+		}
+		rest := strings.TrimLeft(strings.TrimLeft(strings.TrimSpace(line)[len(key):], `"'`), " \t")
+		if strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=") {
+			return i + 1, strings.TrimRight(line, "\r")
+		}
+	}
+	return 1, strings.TrimRight(lines[0], "\r")
+}
@@ -0,0 +1,626 @@
+package lint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// userAgent identifies the link checker to the sites it requests
+const userAgent = "Mozilla/5.0 (compatible; hugo-manager link checker)"
+
+var (
+	// Match [text](target) and ![alt](src), the target optionally in angle brackets
+	mdLinkRe = regexp.MustCompile(`(!?)\[(?:[^\[\]\n]|\[[^\[\]\n]*\])*\]\(\s*(<[^<>\n]*>|(?:[^\s()<>]|\([^\s()<>]*\))+)`)
+
+	// Match reference definitions, [id]: target, but not footnotes
+	mdRefDefRe = regexp.MustCompile(`(?m)^ {0,3}\[[^\]^\n][^\]\n]*\]:[ \t]*(<[^<>\n]*>|\S+)`)
+
+	// Match the href of <a> and the src of <img> in raw HTML
+	htmlRefRe = regexp.MustCompile(`(?is)<(a|img)\b[^>]*?\s(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// LinkLinter reports references in content to pages, files and images that don't exist
+type LinkLinter struct {
+	projectDir string
+	config     config.LinksConfig
+	client     *http.Client
+}
+
+// NewLinkLinter creates a new link linter
+func NewLinkLinter(projectDir string, cfg config.LinksConfig) *LinkLinter {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &LinkLinter{
+		projectDir: projectDir,
+		config:     cfg,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// linkIndex is what references in content can point to
+type linkIndex struct {
+	projectDir string
+	pages      map[string]*linkedPage     // Markdown content files by project-relative path
+	refs       map[string]bool            // Content paths refs resolve, lowercased, with and without extension
+	names      map[string]map[string]bool // Pages by file or bundle name, for refs by name only
+	urls       map[string]bool            // URL paths of pages, aliases and published files, lowercased
+	host       string
+	basePath   string // Path of the baseURL, without the trailing slash
+}
+
+// linkedPage is a markdown content file references are resolved from
+type linkedPage struct {
+	dirs []string // Content-relative directories relative refs are tried from
+	url  string   // URL path, without the baseURL path
+}
+
+// externalLink is a link to another site, checked only when asked for
+type externalLink struct {
+	url  string
+	diag Diagnostic // Reported when the link fails, once the message is known
+}
+
+// LintAll checks the references of every markdown file in the content directories. With external,
+// links to other sites are requested too, which can take a while at the configured rate. It stops
+// with the error of ctx once it's done.
+func (l *LinkLinter) LintAll(ctx context.Context, external bool) (*Report, error) {
+	idx, err := l.index()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(idx.pages))
+	for p := range idx.pages {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var diagnostics []Diagnostic
+	var links []externalLink
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		found, ext := idx.lint(p, string(data))
+		diagnostics = append(diagnostics, found...)
+		links = append(links, ext...)
+	}
+	if external {
+		diagnostics = append(diagnostics, l.checkExternal(ctx, links)...)
+		if err := ctx.Err(); err != nil {
+			return nil, err // Some links weren't checked
+		}
+	}
+
+	return newReport(len(paths), diagnostics), nil
+}
+
+// LintFile checks the references of a single file
+func (l *LinkLinter) LintFile(ctx context.Context, path string, external bool) (*Report, error) {
+	idx, err := l.index()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics, links := idx.lint(filepath.ToSlash(path), string(data))
+	if external {
+		diagnostics = append(diagnostics, l.checkExternal(ctx, links)...)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return newReport(1, diagnostics), nil
+}
+
+// index collects the pages, page resources and static files of the site
+func (l *LinkLinter) index() (*linkIndex, error) {
+	siteCfg, err := site.Load(l.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+
+	idx := &linkIndex{
+		projectDir: l.projectDir,
+		pages:      map[string]*linkedPage{},
+		refs:       map[string]bool{},
+		names:      map[string]map[string]bool{},
+		urls:       map[string]bool{},
+	}
+	if u, err := url.Parse(siteCfg.BaseURL()); err == nil {
+		idx.host = strings.ToLower(u.Hostname())
+		idx.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	contentSite, err := hugocontent.Open(l.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	pages, err := contentSite.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resources are published next to the page of their bundle
+	bundles := map[string]string{} // Project-relative bundle directory to page URL
+	for _, p := range pages {
+		loc := p.Location()
+		page := &linkedPage{dirs: []string{loc.Dir}, url: idx.stripBase(p.URL)}
+		idx.addPage(loc, page)
+		for _, alias := range p.FrontMatter.Strings("aliases") {
+			idx.urls[urlKey(alias)] = true
+		}
+		if loc.Base == "index" || loc.Base == "_index" {
+			bundles[path.Dir(p.Path)] = page.url
+		}
+		if ext := strings.ToLower(path.Ext(p.Path)); ext == ".md" || ext == ".markdown" {
+			idx.pages[p.Path] = page
+		}
+	}
+
+	var resources [][2]string // Project-relative path and content-relative path
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		root := filepath.Join(l.projectDir, filepath.FromSlash(lang.ContentDir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		walkFiles(root, func(full, rel string) {
+			ext := strings.ToLower(path.Ext(rel))
+			if ext == ".md" || ext == ".markdown" || ext == ".html" {
+				return
+			}
+			if projectRel, err := filepath.Rel(l.projectDir, full); err == nil {
+				resources = append(resources, [2]string{filepath.ToSlash(projectRel), rel})
+			}
+		})
+	}
+
+	for _, r := range resources {
+		idx.urls[urlKey(r[1])] = true
+		for dir := path.Dir(r[0]); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if pageURL, ok := bundles[dir]; ok {
+				idx.urls[urlKey(path.Join(pageURL, strings.TrimPrefix(r[0], dir+"/")))] = true
+				break
+			}
+		}
+	}
+
+	for _, root := range l.staticRoots(siteCfg) {
+		walkFiles(root, func(full, rel string) {
+			idx.urls[urlKey(rel)] = true
+		})
+	}
+	return idx, nil
+}
+
+// staticRoots returns the directories whose files are published at the site root: the project's
+// static directory, its assets for images resolved by render hooks, and those of themes and modules
+func (l *LinkLinter) staticRoots(siteCfg *site.Config) []string {
+	staticDir := siteCfg.String("staticDir")
+	if staticDir == "" {
+		staticDir = "static"
+	}
+	roots := []string{
+		filepath.Join(l.projectDir, filepath.FromSlash(staticDir)),
+		filepath.Join(l.projectDir, "assets"),
+	}
+
+	var dirs []string
+	for _, mod := range siteCfg.ModuleImports() {
+		if dir := site.ModuleDir(l.projectDir, mod); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, theme := range siteCfg.Themes() {
+		themeDir := filepath.Join(l.projectDir, "themes", theme)
+		if stat, err := os.Stat(themeDir); err == nil && stat.IsDir() {
+			dirs = append(dirs, themeDir)
+		} else if dir := site.ModuleDir(l.projectDir, theme); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range dirs {
+		roots = append(roots, filepath.Join(dir, "static"), filepath.Join(dir, "assets"))
+	}
+	return roots
+}
+
+// walkFiles calls fn with the absolute and root-relative slash path of every visible file below root
+func walkFiles(root string, fn func(full, rel string)) {
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Missing directories have no files
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, p); err == nil {
+			fn(p, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+// addPage records the paths a ref can name a page by and its URL
+func (idx *linkIndex) addPage(loc *hugocontent.Location, page *linkedPage) {
+	idx.urls[urlKey(page.url)] = true
+
+	key := refKey(path.Join(loc.Dir, loc.Base))
+	idx.refs[refKey(loc.Rel)] = true
+	idx.refs[key] = true
+
+	name := loc.Base
+	switch loc.Base {
+	case "index":
+		// A leaf bundle is named after its directory and resolves refs like a page of its parent
+		key = refKey(loc.Dir)
+		name = path.Base(loc.Dir)
+		page.dirs = append([]string{path.Dir(loc.Dir)}, page.dirs...)
+		idx.refs[key] = true
+	case "_index":
+		key = refKey(loc.Dir)
+		name = path.Base(loc.Dir)
+		idx.refs[key] = true
+	}
+	if name == "" || name == "." {
+		return
+	}
+	name = strings.ToLower(name)
+	if idx.names[name] == nil {
+		idx.names[name] = map[string]bool{}
+	}
+	idx.names[name][key] = true // Translations share the key, so they don't make a name ambiguous
+}
+
+// lint returns the broken references of a file and the external links it has
+func (idx *linkIndex) lint(file, text string) ([]Diagnostic, []externalLink) {
+	page := idx.pages[file]
+	var diagnostics []Diagnostic
+	var links []externalLink
+
+	diag := func(offset int, severity Severity, rule, format string, args ...interface{}) Diagnostic {
+		line, col := position(text, offset)
+		return Diagnostic{
+			File:     file,
+			Line:     line,
+			Column:   col,
+			Severity: severity,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+		}
+	}
+
+	for _, call := range FindShortcodeCalls(text) {
+		if call.Name != "ref" && call.Name != "relref" {
+			continue
+		}
+		target := call.Named["path"]
+		if target == "" && len(call.Positional) > 0 {
+			target = call.Positional[0]
+		}
+		target, _, _ = strings.Cut(target, "#")
+		if target == "" {
+			continue // A fragment of the page itself
+		}
+
+		d := Diagnostic{File: file, Line: call.Line, Column: call.Column}
+		switch n := idx.findRef(page, target); {
+		case n == 0:
+			d.Severity, d.Rule, d.Message = SeverityError, "broken-ref", fmt.Sprintf("Reference %q doesn't match any page", target)
+		case n > 1:
+			d.Severity, d.Rule, d.Message = SeverityWarning, "ambiguous-ref", fmt.Sprintf("Reference %q matches %d pages; use its path", target, n)
+		default:
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	for _, ref := range FindLinks(text) {
+		if ref.Kind == LinkRef {
+			continue // Checked above, where the shortcode is reported
+		}
+		target := ref.Target
+		if target == "" || strings.HasPrefix(target, "#") || strings.Contains(target, "{{") {
+			continue // Fragments of the page itself, or refs and other shortcodes checked above
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			continue
+		}
+
+		kind, rule := "Link", "broken-link"
+		if ref.Kind == LinkImage {
+			kind, rule = "Image", "broken-image"
+		}
+		if u.Scheme != "" || u.Host != "" {
+			if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+				continue // mailto:, tel:, data: and the like
+			}
+			if !strings.EqualFold(u.Hostname(), idx.host) || idx.host == "" {
+				if u.Scheme == "" {
+					u.Scheme = "https"
+				}
+				u.Fragment = ""
+				links = append(links, externalLink{url: u.String(), diag: diag(ref.Offset, SeverityWarning, "external-link", "")})
+				continue
+			}
+		}
+		if u.Path == "" || idx.findLink(file, page, u.Path) {
+			continue
+		}
+		diagnostics = append(diagnostics, diag(ref.Offset, SeverityError, rule, "%s target %q doesn't exist", kind, target))
+	}
+
+	return diagnostics, links
+}
+
+// Kinds of links
+const (
+	LinkRef   = "ref"   // The target of a ref or relref shortcode
+	LinkPage  = "link"  // A markdown link, reference definition or <a href>
+	LinkImage = "image" // A markdown image or <img src>
+)
+
+// Link is a reference in content to a page, file or image
+type Link struct {
+	Kind   string
+	Target string // As written, without quotes, angle brackets or surrounding spaces
+	Offset int    // Byte offset of Target in the content
+	Line   int
+	Column int
+}
+
+// FindLinks returns the refs, links and images of a content file in the order they appear. Front
+// matter and code blocks are skipped, as Hugo doesn't render links there.
+func FindLinks(text string) []Link {
+	start := 0
+	if _, body, _, _ := hugocontent.Parse([]byte(text)); strings.HasSuffix(text, body) {
+		start = len(text) - len(body)
+	}
+	fences := codeFenceRanges(text)
+
+	var links []Link
+	add := func(kind string, offset, end int) {
+		if offset < start || inRanges(offset, fences) {
+			return
+		}
+		raw := text[offset:end]
+		target := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">"))
+		offset += strings.Index(raw, target)
+		line, col := position(text, offset)
+		links = append(links, Link{Kind: kind, Target: target, Offset: offset, Line: line, Column: col})
+	}
+
+	for _, m := range shortcodeCallRe.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[6]:m[7]]
+		if m[4] != m[5] || (name != "ref" && name != "relref") {
+			continue
+		}
+		// The path is the named argument or the first positional one
+		for _, a := range shortcodeArgRe.FindAllStringSubmatchIndex(text[m[8]:m[9]], -1) {
+			value := 6
+			if a[2] >= 0 {
+				if text[m[8]+a[2]:m[8]+a[3]] != "path" {
+					continue
+				}
+				value = 4
+			}
+			offset, end := m[8]+a[value], m[8]+a[value+1]
+			if end-offset >= 2 && strings.ContainsRune("\"`", rune(text[offset])) {
+				offset, end = offset+1, end-1
+			}
+			add(LinkRef, offset, end)
+			break
+		}
+	}
+	for _, m := range mdLinkRe.FindAllStringSubmatchIndex(text, -1) {
+		kind := LinkPage
+		if m[3] > m[2] {
+			kind = LinkImage
+		}
+		add(kind, m[4], m[5])
+	}
+	for _, m := range mdRefDefRe.FindAllStringSubmatchIndex(text, -1) {
+		add(LinkPage, m[2], m[3])
+	}
+	for _, m := range htmlRefRe.FindAllStringSubmatchIndex(text, -1) {
+		group := 4
+		if m[4] < 0 {
+			group = 6
+		}
+		kind := LinkPage
+		if strings.EqualFold(text[m[2]:m[3]], "img") {
+			kind = LinkImage
+		}
+		add(kind, m[group], m[group+1])
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Offset < links[j].Offset })
+	return links
+}
+
+// findRef resolves a ref or relref target the way Hugo does: from the content root when it starts
+// with a slash, otherwise from the page's directory, then from the content root, and a bare name by
+// the pages with that name. It returns how many pages match.
+func (idx *linkIndex) findRef(page *linkedPage, target string) int {
+	if strings.HasPrefix(target, "/") {
+		if idx.refs[refKey(target)] {
+			return 1
+		}
+		return 0
+	}
+	if page != nil {
+		for _, dir := range page.dirs {
+			if idx.refs[refKey(path.Join(dir, target))] {
+				return 1
+			}
+		}
+	}
+	if idx.refs[refKey(target)] {
+		return 1
+	}
+	if strings.Contains(strings.Trim(target, "/"), "/") {
+		return 0
+	}
+	name := strings.ToLower(path.Base(target))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return len(idx.names[name])
+}
+
+// findLink reports whether the path of an internal link exists: as a page, alias or published file,
+// relative to the page's URL or, as editors and render hooks resolve them, to the file itself
+func (idx *linkIndex) findLink(file string, page *linkedPage, p string) bool {
+	if strings.HasPrefix(p, "/") {
+		if idx.urls[urlKey(p)] || idx.urls[urlKey(idx.stripBase(p))] {
+			return true
+		}
+	} else {
+		if page != nil {
+			dir := page.url
+			if !strings.HasSuffix(dir, "/") {
+				dir = path.Dir(dir) // uglyURLs
+			}
+			if idx.urls[urlKey(path.Join(dir, p))] {
+				return true
+			}
+		}
+		if _, err := os.Stat(filepath.Join(idx.projectDir, filepath.FromSlash(path.Join(path.Dir(file), p)))); err == nil {
+			return true
+		}
+	}
+
+	// Links to content files, which Hugo's render hooks resolve like refs
+	if ext := strings.ToLower(path.Ext(p)); ext == ".md" || ext == ".markdown" {
+		return idx.findRef(page, p) > 0
+	}
+	return false
+}
+
+// stripBase removes the path of the baseURL from a URL path
+func (idx *linkIndex) stripBase(p string) string {
+	if idx.basePath != "" && (p == idx.basePath || strings.HasPrefix(p, idx.basePath+"/")) {
+		return "/" + strings.TrimPrefix(strings.TrimPrefix(p, idx.basePath), "/")
+	}
+	return p
+}
+
+// refKey normalizes a content path for ref lookups
+func refKey(p string) string {
+	return strings.Trim(path.Clean("/"+strings.ToLower(p)), "/")
+}
+
+// urlKey normalizes a URL path for lookups, a directory and its index page being the same
+func urlKey(p string) string {
+	p = path.Clean("/" + strings.ToLower(p))
+	if path.Base(p) == "index.html" {
+		p = path.Dir(p)
+	}
+	return p
+}
+
+// checkExternal requests every external link once, no faster than the configured rate, and reports
+// those that fail. It stops early, without reporting the links left, when ctx is cancelled.
+func (l *LinkLinter) checkExternal(ctx context.Context, links []externalLink) []Diagnostic {
+	byURL := map[string][]Diagnostic{}
+	var urls []string
+	for _, link := range links {
+		if _, ok := byURL[link.url]; !ok {
+			urls = append(urls, link.url)
+		}
+		byURL[link.url] = append(byURL[link.url], link.diag)
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(max(l.config.RequestsPerSecond, 1)))
+	defer ticker.Stop()
+
+	var diagnostics []Diagnostic
+	for i, u := range urls {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return diagnostics
+			case <-ticker.C:
+			}
+		}
+		problem := l.probe(ctx, u)
+		if ctx.Err() != nil {
+			return diagnostics
+		}
+		if problem == "" {
+			continue
+		}
+		for _, d := range byURL[u] {
+			d.Message = fmt.Sprintf("External link %q %s", u, problem)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// probe requests a URL with HEAD, or GET for servers that don't answer HEAD, and describes why it
+// failed; "" means the link works
+func (l *LinkLinter) probe(ctx context.Context, u string) string {
+	status, err := l.request(ctx, http.MethodHead, u)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = l.request(ctx, http.MethodGet, u)
+	}
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "is unreachable: " + err.Error()
+	}
+	if status >= 400 {
+		return fmt.Sprintf("returned %d %s", status, http.StatusText(status))
+	}
+	return ""
+}
+
+// request makes a request and returns the response status
+func (l *LinkLinter) request(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
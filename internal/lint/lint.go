@@ -0,0 +1,187 @@
+package lint
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Diagnostic represents a single problem found in a file
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+
+	// End of the text the problem covers, for diagnostics that mark a span rather than a point
+	EndLine   int `json:"endLine,omitempty"`
+	EndColumn int `json:"endColumn,omitempty"`
+}
+
+// Report groups the diagnostics of a lint run
+type Report struct {
+	FilesChecked int          `json:"filesChecked"`
+	Errors       int          `json:"errors"`
+	Warnings     int          `json:"warnings"`
+	Diagnostics  []Diagnostic `json:"diagnostics"`
+}
+
+// newReport builds a report from a list of diagnostics
+func newReport(filesChecked int, diagnostics []Diagnostic) *Report {
+	report := &Report{
+		FilesChecked: filesChecked,
+		Diagnostics:  diagnostics,
+	}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []Diagnostic{}
+	}
+
+	for _, d := range report.Diagnostics {
+		switch d.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarning:
+			report.Warnings++
+		}
+	}
+
+	sort.SliceStable(report.Diagnostics, func(i, j int) bool {
+		a, b := report.Diagnostics[i], report.Diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return report
+}
+
+// markdownFiles returns the project-relative paths of all markdown files under contentDir
+func markdownFiles(projectDir, contentDir string) ([]string, error) {
+	root := filepath.Join(projectDir, contentDir)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		if ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return nil
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// contentFiles returns the project-relative markdown files of every language's content directory
+func contentFiles(projectDir string) ([]string, error) {
+	dirs := []string{"content"}
+	if siteCfg, err := site.Load(projectDir); err == nil {
+		dirs = dirs[:0]
+		for _, lang := range siteCfg.Languages() {
+			dirs = append(dirs, lang.ContentDir)
+		}
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		found, err := markdownFiles(projectDir, filepath.FromSlash(dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range found {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(content string, offset int) (int, int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return line, col
+}
+
+// codeFenceRanges returns the byte ranges of fenced code blocks
+func codeFenceRanges(content string) [][2]int {
+	var ranges [][2]int
+	offset := 0
+	start := -1
+	fence := ""
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				start = offset
+				fence = trimmed[:3]
+			}
+		} else if strings.HasPrefix(trimmed, fence) {
+			ranges = append(ranges, [2]int{start, offset + len(line)})
+			start = -1
+		}
+		offset += len(line)
+	}
+	if start >= 0 {
+		ranges = append(ranges, [2]int{start, len(content)})
+	}
+	return ranges
+}
+
+// inRanges reports whether offset falls inside any of the ranges
+func inRanges(offset int, ranges [][2]int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,445 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+var (
+	// Match an ATX heading: its indentation and hashes
+	atxHeadingRe = regexp.MustCompile(`^( {0,3})(#{1,6})(?:[ \t]|$)`)
+
+	// Match the underline of a setext heading
+	setextRe = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+	// Match a list item marker
+	listItemRe = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d+[.)])(?:[ \t]|$)`)
+
+	// Match the delimiter row of a table, such as | --- | :-: |
+	tableDelimRe = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+
+	// Match a URL in text
+	bareURLRe = regexp.MustCompile(`https?://[^\s<>\x60]+`)
+
+	// Match where a URL isn't bare: inline code, HTML comments and tags, autolinks, shortcode calls,
+	// links with their text, reference links and reference definitions
+	linkedURLRe = regexp.MustCompile("(?s)`[^`\n]+`|<!--.*?-->|<[^<>\n]+>|\\{\\{[<%].*?[>%]\\}\\}|" +
+		`!?\[[^\]\n]*\]\([^)\n]*\)|\[[^\]\n]*\]\[[^\]\n]*\]|(?m:^ {0,3}\[[^\]\n]+\]:[^\n]*)`)
+)
+
+// MarkdownLinter reports markdown that renders other than intended or is hard to maintain, and
+// fixes it
+type MarkdownLinter struct {
+	projectDir string
+}
+
+// FixedFile is a content file with its problems fixed
+type FixedFile struct {
+	Path    string `json:"path"`
+	Fixes   int    `json:"fixes"`   // Changes made
+	Content string `json:"content"` // The corrected content
+}
+
+// FixFailure is a file that couldn't be fixed
+type FixFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// FixResult is the outcome of fixing markdown files
+type FixResult struct {
+	DryRun bool         `json:"dryRun"` // Nothing was written, as the fixes weren't confirmed
+	Fixes  int          `json:"fixes"`  // Across every file
+	Files  []FixedFile  `json:"files"`  // The files with something to fix
+	Failed []FixFailure `json:"failed"`
+}
+
+// mdLine is a line of markdown
+type mdLine struct {
+	text  string // Without the line ending
+	start int    // Byte offset in the content
+	skip  bool   // In front matter or a code block
+}
+
+// mdEdit replaces the bytes of content between start and end
+type mdEdit struct {
+	start, end int
+	text       string
+}
+
+// NewMarkdownLinter creates a new markdown linter
+func NewMarkdownLinter(projectDir string) *MarkdownLinter {
+	return &MarkdownLinter{projectDir: projectDir}
+}
+
+// LintAll checks every markdown file in the content directories. It stops with the error of ctx
+// once it's done.
+func (l *MarkdownLinter) LintAll(ctx context.Context) (*Report, error) {
+	paths, err := contentFiles(l.projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		found, _, _ := lintMarkdown(p, string(data))
+		diagnostics = append(diagnostics, found...)
+	}
+	return newReport(len(paths), diagnostics), nil
+}
+
+// LintFile checks a single file
+func (l *MarkdownLinter) LintFile(path string) (*Report, error) {
+	data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+	return l.LintContent(path, string(data)), nil
+}
+
+// LintContent checks unsaved content, reporting diagnostics against the given path
+func (l *MarkdownLinter) LintContent(path, content string) *Report {
+	diagnostics, _, _ := lintMarkdown(filepath.ToSlash(path), content)
+	return newReport(1, diagnostics)
+}
+
+// FixContent fixes unsaved content. Nothing is written.
+func (l *MarkdownLinter) FixContent(path, content string) *FixResult {
+	result := &FixResult{DryRun: true, Files: []FixedFile{}, Failed: []FixFailure{}}
+	if _, fixed, fixes := lintMarkdown(filepath.ToSlash(path), content); fixes > 0 {
+		result.Fixes = fixes
+		result.Files = append(result.Files, FixedFile{Path: filepath.ToSlash(path), Fixes: fixes, Content: fixed})
+	}
+	return result
+}
+
+// Fix fixes the file at path, or every markdown file in the content directories when path is
+// empty, and returns the corrected content of each file it changes. With confirm the changes are
+// written. Once ctx is done it stops with its error, but confirmed fixes that started writing
+// finish, so they aren't left half applied.
+func (l *MarkdownLinter) Fix(ctx context.Context, path string, confirm bool) (*FixResult, error) {
+	paths := []string{filepath.ToSlash(path)}
+	if path == "" {
+		var err error
+		if paths, err = contentFiles(l.projectDir); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(filepath.Join(l.projectDir, filepath.FromSlash(path))); err != nil {
+		return nil, err
+	}
+
+	result := &FixResult{DryRun: !confirm, Files: []FixedFile{}, Failed: []FixFailure{}}
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil && (!confirm || result.Fixes == 0) {
+			return nil, err
+		}
+		full := filepath.Join(l.projectDir, filepath.FromSlash(p))
+		data, err := os.ReadFile(full)
+		if err != nil {
+			result.Failed = append(result.Failed, FixFailure{Path: p, Error: err.Error()})
+			continue
+		}
+		_, fixed, fixes := lintMarkdown(p, string(data))
+		if fixes == 0 {
+			continue
+		}
+
+		if confirm {
+			info, err := os.Stat(full)
+			if err == nil {
+				err = atomicfile.WriteFile(full, []byte(fixed), info.Mode().Perm())
+			}
+			if err != nil {
+				result.Failed = append(result.Failed, FixFailure{Path: p, Error: err.Error()})
+				continue
+			}
+		}
+		result.Fixes += fixes
+		result.Files = append(result.Files, FixedFile{Path: p, Fixes: fixes, Content: fixed})
+	}
+	return result, nil
+}
+
+// lintMarkdown checks the markdown of a file and returns its diagnostics, the content with them
+// fixed and the number of changes made. Front matter and code blocks are left alone.
+func lintMarkdown(file, content string) ([]Diagnostic, string, int) {
+	start := 0
+	if _, body, _, _ := hugocontent.Parse([]byte(content)); strings.HasSuffix(content, body) {
+		start = len(content) - len(body)
+	}
+	fences := codeFenceRanges(content)
+
+	var lines []mdLine
+	offset := 0
+	for _, raw := range strings.SplitAfter(content, "\n") {
+		if raw == "" {
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+		lines = append(lines, mdLine{text: text, start: offset, skip: offset < start || inRanges(offset, fences)})
+		offset += len(raw)
+	}
+
+	index := newLineIndex(content)
+	var diagnostics []Diagnostic
+	report := func(rule string, severity Severity, from, to int, message string) {
+		d := Diagnostic{File: file, Severity: severity, Rule: rule, Message: message}
+		d.Line, d.Column = index.position(from)
+		d.EndLine, d.EndColumn = index.position(to)
+		diagnostics = append(diagnostics, d)
+	}
+
+	// Tables go first: their padding replaces the end of a row, trailing spaces included
+	var edits []mdEdit
+	edits = append(edits, lintTables(lines, report)...)
+	edits = append(edits, lintHeadings(lines, report)...)
+	edits = append(edits, lintTrailingSpaces(lines, report)...)
+	edits = append(edits, lintBareURLs(content, start, fences, report)...)
+
+	fixed, fixes := applyEdits(content, edits)
+	return diagnostics, fixed, fixes
+}
+
+// lintHeadings reports headings more than one level below the heading before them. The fix
+// raises them, and the headings under them with them, so the outline keeps its shape.
+func lintHeadings(lines []mdLine, report func(string, Severity, int, int, string)) []mdEdit {
+	var edits []mdEdit
+	prev, fixedPrev := 0, 0 // Level of the heading before, as written and as fixed
+	for i, line := range lines {
+		if line.skip {
+			continue
+		}
+		if i > 0 && setextRe.MatchString(line.text) && isParagraphLine(lines[i-1]) {
+			prev, fixedPrev = 2, 2
+			if strings.Contains(line.text, "=") {
+				prev, fixedPrev = 1, 1
+			}
+			continue
+		}
+		m := atxHeadingRe.FindStringSubmatchIndex(line.text)
+		if m == nil {
+			continue
+		}
+		level := m[5] - m[4]
+		if prev > 0 && level > prev+1 {
+			report("heading-increment", SeverityWarning, line.start+m[4], line.start+len(line.text),
+				fmt.Sprintf("Heading level %d follows level %d; use level %d", level, prev, prev+1))
+		}
+		fixed := level
+		if fixedPrev > 0 && level > fixedPrev+1 {
+			fixed = fixedPrev + 1
+		}
+		if fixed != level {
+			edits = append(edits, mdEdit{start: line.start + m[4], end: line.start + m[5], text: strings.Repeat("#", fixed)})
+		}
+		prev, fixedPrev = level, fixed
+	}
+	return edits
+}
+
+// lintTrailingSpaces reports spaces and tabs at the end of lines. Two spaces ending a line of a
+// paragraph are a line break and are kept.
+func lintTrailingSpaces(lines []mdLine, report func(string, Severity, int, int, string)) []mdEdit {
+	var edits []mdEdit
+	for i, line := range lines {
+		if line.skip {
+			continue
+		}
+		trimmed := strings.TrimRight(line.text, " \t")
+		if len(trimmed) == len(line.text) {
+			continue
+		}
+		trailing := line.text[len(trimmed):]
+		if trailing == "  " && trimmed != "" && i+1 < len(lines) && strings.TrimSpace(lines[i+1].text) != "" {
+			continue
+		}
+		from, to := line.start+len(trimmed), line.start+len(line.text)
+		report("no-trailing-spaces", SeverityInfo, from, to, "Trailing spaces")
+		edits = append(edits, mdEdit{start: from, end: to})
+	}
+	return edits
+}
+
+// lintBareURLs reports URLs in text that aren't links. Most renderers link them anyway, but
+// CommonMark, and so Hugo without the linkify extension, doesn't. The fix makes them autolinks.
+func lintBareURLs(content string, start int, fences [][2]int, report func(string, Severity, int, int, string)) []mdEdit {
+	var linked [][2]int
+	for _, m := range linkedURLRe.FindAllStringIndex(content, -1) {
+		linked = append(linked, [2]int{m[0], m[1]})
+	}
+
+	var edits []mdEdit
+	for _, m := range bareURLRe.FindAllStringIndex(content, -1) {
+		from, to := m[0], m[1]
+		if from < start || inRanges(from, fences) || inRanges(from, linked) {
+			continue
+		}
+		if from > 0 && strings.ContainsRune(`"'=<`, rune(content[from-1])) {
+			continue // An attribute of HTML split across lines, or an autolink
+		}
+		url := trimURL(content[from:to])
+		to = from + len(url)
+		report("no-bare-urls", SeverityWarning, from, to, fmt.Sprintf("Bare URL %s; write it as <%s> or a link", url, url))
+		edits = append(edits, mdEdit{start: from, end: to, text: "<" + url + ">"})
+	}
+	return edits
+}
+
+// trimURL drops the punctuation that ends the sentence a URL is in, and a closing parenthesis
+// the URL doesn't open
+func trimURL(url string) string {
+	for url != "" {
+		last := url[len(url)-1]
+		if strings.IndexByte(".,:;!?'\"*_~", last) >= 0 {
+			url = url[:len(url)-1]
+			continue
+		}
+		if last == ')' && strings.Count(url, "(") < strings.Count(url, ")") {
+			url = url[:len(url)-1]
+			continue
+		}
+		break
+	}
+	return url
+}
+
+// lintTables reports tables whose delimiter row or body rows don't have as many cells as the
+// header. Missing cells are added; extra ones are only reported, as fixing them would drop content.
+func lintTables(lines []mdLine, report func(string, Severity, int, int, string)) []mdEdit {
+	var edits []mdEdit
+	for i := 0; i+1 < len(lines); i++ {
+		header, delim := lines[i], lines[i+1]
+		if header.skip || delim.skip || !strings.Contains(header.text, "|") || !strings.Contains(delim.text, "|") ||
+			!tableDelimRe.MatchString(delim.text) {
+			continue
+		}
+		columns := len(tableCells(header.text))
+		if cells := tableCells(delim.text); len(cells) != columns {
+			report("table-column-count", SeverityError, delim.start, delim.start+len(delim.text),
+				fmt.Sprintf("Delimiter row has %d cells, the header has %d, so this isn't rendered as a table", len(cells), columns))
+			edits = append(edits, mdEdit{start: delim.start, end: delim.start + len(delim.text), text: delimiterRow(delim.text, cells, columns)})
+		}
+
+		j := i + 2
+		for ; j < len(lines) && !lines[j].skip && strings.Contains(lines[j].text, "|") && strings.TrimSpace(lines[j].text) != ""; j++ {
+			row := lines[j]
+			n := len(tableCells(row.text))
+			switch {
+			case n < columns:
+				report("table-column-count", SeverityError, row.start, row.start+len(row.text),
+					fmt.Sprintf("Row has %d cells, the header has %d", n, columns))
+				trimmed := strings.TrimRight(row.text, " \t")
+				pad := strings.Repeat(" |", columns-n)
+				if !hasClosingPipe(trimmed) {
+					pad = " |" + pad
+				}
+				edits = append(edits, mdEdit{start: row.start + len(trimmed), end: row.start + len(row.text), text: pad})
+			case n > columns:
+				report("table-column-count", SeverityError, row.start, row.start+len(row.text),
+					fmt.Sprintf("Row has %d cells, the header has %d; the cells past the header's aren't shown", n, columns))
+			}
+		}
+		i = j - 1
+	}
+	return edits
+}
+
+// tableCells splits a table row into its cells. Pipes that are escaped or in code don't separate
+// cells.
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if hasClosingPipe(row) {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	cell := strings.Builder{}
+	code := false
+	for i := 0; i < len(row); i++ {
+		c := row[i]
+		switch {
+		case c == '\\' && i+1 < len(row):
+			cell.WriteByte(c)
+			cell.WriteByte(row[i+1])
+			i++
+			continue
+		case c == '`':
+			code = !code
+		case c == '|' && !code:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(c)
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// hasClosingPipe reports whether a trimmed row ends with a pipe that isn't escaped
+func hasClosingPipe(row string) bool {
+	return strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`)
+}
+
+// delimiterRow rewrites a delimiter row with one cell per column, keeping the alignment of the
+// columns it had
+func delimiterRow(row string, cells []string, columns int) string {
+	parts := make([]string, columns)
+	for i := range parts {
+		parts[i] = "---"
+		if i < len(cells) && cells[i] != "" {
+			parts[i] = cells[i]
+		}
+	}
+	trimmed := strings.TrimSpace(row)
+	indent := row[:len(row)-len(strings.TrimLeft(row, " "))]
+	text := strings.Join(parts, " | ")
+	if strings.HasPrefix(trimmed, "|") {
+		text = "| " + text
+	}
+	if hasClosingPipe(trimmed) {
+		text += " |"
+	}
+	return indent + text
+}
+
+// isParagraphLine reports whether a line is text a setext underline turns into a heading
+func isParagraphLine(line mdLine) bool {
+	trimmed := strings.TrimSpace(line.text)
+	return !line.skip && trimmed != "" && !atxHeadingRe.MatchString(line.text) && !listItemRe.MatchString(line.text) &&
+		!strings.Contains(trimmed, "|") && !setextRe.MatchString(line.text)
+}
+
+// applyEdits makes the edits to content and returns it with the number made. An edit that
+// overlaps one before it, in the order given for edits at the same offset, is left out.
+func applyEdits(content string, edits []mdEdit) (string, int) {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out strings.Builder
+	pos, made := 0, 0
+	for _, e := range edits {
+		if e.start < pos {
+			continue
+		}
+		out.WriteString(content[pos:e.start])
+		out.WriteString(e.text)
+		pos = e.end
+		made++
+	}
+	out.WriteString(content[pos:])
+	return out.String(), made
+}
@@ -0,0 +1,481 @@
+package lint
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// dictionaryFile is the project word list, inside the .hugo-manager directory
+const dictionaryFile = "dictionary"
+
+// ErrInvalidWord is returned for a word the project word list can't take
+var ErrInvalidWord = errors.New("invalid word")
+
+var (
+	// Match text that isn't prose: inline code, HTML comments and tags, shortcode calls, URLs,
+	// email addresses, link targets and reference definitions
+	proseSkipRe = regexp.MustCompile("(?s)`[^`\n]+`|<!--.*?-->|</?[a-zA-Z!][^<>\n]*>|\\{\\{[<%].*?[>%]\\}\\}|" +
+		`(?:https?://|www\.)[^\s<>()]+|[\w.+-]+@[\w-]+\.[\w.-]+|\]\([^)\n]*\)|(?m:^ {0,3}\[[^\]\n]+\]:[^\n]*)`)
+
+	// Match a line break that starts a new block: a blank line, heading, list item, quote, table
+	// row, code fence or HTML block
+	proseBlockRe = regexp.MustCompile("\n[ \t]*(?:\n|#|[-*+>|`~<]|\\d+[.)])")
+)
+
+// ProseLinter checks the spelling and style of the prose in content files
+type ProseLinter struct {
+	projectDir string
+	config     config.ProseConfig
+	mu         sync.Mutex
+	lists      map[string]*wordList // Word list files read, by path
+}
+
+// wordList is a word list file as last read
+type wordList struct {
+	modTime time.Time
+	size    int64
+	words   map[string]bool // Lowercase
+}
+
+// ProseReport is the result of checking the prose of a file
+type ProseReport struct {
+	Report
+	Language string `json:"language"`
+	Spelling bool   `json:"spelling"` // The language has a dictionary, so spelling was checked
+}
+
+// proseWord is a word of prose and where it is in the content
+type proseWord struct {
+	text       string
+	start, end int // Byte offsets
+}
+
+// NewProseLinter creates a new prose linter
+func NewProseLinter(projectDir string, cfg config.ProseConfig) *ProseLinter {
+	return &ProseLinter{projectDir: projectDir, config: cfg, lists: map[string]*wordList{}}
+}
+
+// LintFile checks the prose of a single file
+func (l *ProseLinter) LintFile(path string) (*ProseReport, error) {
+	data, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+	return l.LintContent(path, string(data))
+}
+
+// LintContent checks the prose of content, unsaved editor content of the file at path. The path
+// picks the language, and so the dictionary.
+func (l *ProseLinter) LintContent(path, content string) (*ProseReport, error) {
+	lang := l.language(path)
+	dict, err := l.dictionary(lang)
+	if err != nil {
+		return nil, err
+	}
+	project, err := l.Words()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(project))
+	for _, w := range project {
+		known[normalizeWord(w)] = true
+	}
+
+	file := filepath.ToSlash(path)
+	lines := newLineIndex(content)
+	var diagnostics []Diagnostic
+	add := func(rule string, severity Severity, start, end int, message string) {
+		d := Diagnostic{File: file, Severity: severity, Rule: rule, Message: message}
+		d.Line, d.Column = lines.position(start)
+		d.EndLine, d.EndColumn = lines.position(end)
+		diagnostics = append(diagnostics, d)
+	}
+
+	skip := proseSkipRanges(content)
+	words := proseWords(content, skip)
+	sentence := 0 // Index of the first word of the current sentence
+	for i, w := range words {
+		if dict != nil && checkSpelling(w.text) && !isKnown(w.text, dict, known) {
+			add("spelling", SeverityWarning, w.start, w.end, fmt.Sprintf("%q is not in the dictionary", w.text))
+		}
+		if i == 0 {
+			continue
+		}
+
+		prev := words[i-1]
+		if sentenceBreak(content, prev.end, w.start, skip) {
+			l.checkSentence(words[sentence:i], add)
+			sentence = i
+			continue
+		}
+		if strings.TrimSpace(content[prev.end:w.start]) == "" && strings.EqualFold(prev.text, w.text) && hasLetter(w.text) {
+			add("repeated-word", SeverityWarning, w.start, w.end, fmt.Sprintf("%q is repeated", w.text))
+		}
+	}
+	if len(words) > 0 {
+		l.checkSentence(words[sentence:], add)
+	}
+
+	return &ProseReport{Report: *newReport(1, diagnostics), Language: lang, Spelling: dict != nil}, nil
+}
+
+// checkSentence reports a sentence longer than prose.max_sentence_words
+func (l *ProseLinter) checkSentence(words []proseWord, add func(string, Severity, int, int, string)) {
+	limit := l.config.MaxSentenceWords
+	if limit <= 0 || len(words) <= limit {
+		return
+	}
+	add("long-sentence", SeverityInfo, words[0].start, words[len(words)-1].end,
+		fmt.Sprintf("Sentence has %d words, more than %d", len(words), limit))
+}
+
+// Words returns the project word list, accepted as spelled right in every language
+func (l *ProseLinter) Words() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readWords()
+}
+
+// AddWord adds a word to the project word list and returns the list
+func (l *ProseLinter) AddWord(word string) ([]string, error) {
+	word = strings.TrimSpace(word)
+	if word == "" || strings.IndexFunc(word, unicode.IsSpace) >= 0 || !hasLetter(word) {
+		return nil, fmt.Errorf("%w: %q must be a single word", ErrInvalidWord, word)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	words, err := l.readWords()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range words {
+		if normalizeWord(w) == normalizeWord(word) {
+			return words, nil
+		}
+	}
+	words = append(words, word)
+	sort.Slice(words, func(i, j int) bool { return strings.ToLower(words[i]) < strings.ToLower(words[j]) })
+	return words, l.writeWords(words)
+}
+
+// RemoveWord removes a word from the project word list and returns the list. A word that isn't in
+// it isn't an error.
+func (l *ProseLinter) RemoveWord(word string) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	words, err := l.readWords()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]string, 0, len(words))
+	for _, w := range words {
+		if normalizeWord(w) != normalizeWord(word) {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == len(words) {
+		return words, nil
+	}
+	return kept, l.writeWords(kept)
+}
+
+// readWords reads the project word list, one word per line. A missing list is empty.
+func (l *ProseLinter) readWords() ([]string, error) {
+	data, err := os.ReadFile(l.wordsPath())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the project word list: %w", err)
+	}
+	words := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if w := strings.TrimSpace(line); w != "" && !strings.HasPrefix(w, "#") {
+			words = append(words, w)
+		}
+	}
+	return words, nil
+}
+
+// writeWords saves the project word list
+func (l *ProseLinter) writeWords(words []string) error {
+	p := l.wordsPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to save the project word list: %w", err)
+	}
+	data := strings.Join(words, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	if err := atomicfile.WriteFile(p, []byte(data), 0644); err != nil {
+		return fmt.Errorf("failed to save the project word list: %w", err)
+	}
+	return nil
+}
+
+// wordsPath returns where the project word list is kept
+func (l *ProseLinter) wordsPath() string {
+	return filepath.Join(l.projectDir, storage.DirName, dictionaryFile)
+}
+
+// language returns the language of a content file, the site's default for files outside the
+// content directories
+func (l *ProseLinter) language(path string) string {
+	siteCfg, err := site.Load(l.projectDir)
+	if err != nil {
+		return "en"
+	}
+	loc, err := hugocontent.Locate(siteCfg, filepath.ToSlash(path))
+	if err != nil {
+		return siteCfg.DefaultLanguage()
+	}
+	return loc.Language
+}
+
+// dictionary returns the words of the word lists of a language, or nil when it has none. A
+// regional code, such as en-us, falls back to the lists of its language.
+func (l *ProseLinter) dictionary(lang string) (map[string]bool, error) {
+	var files []string
+	for _, code := range []string{lang, strings.SplitN(lang, "-", 2)[0]} {
+		for key, list := range l.config.Dictionaries {
+			if strings.EqualFold(key, code) {
+				files = list
+			}
+		}
+		if files != nil {
+			break
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(files) == 1 {
+		return l.readList(files[0])
+	}
+	dict := map[string]bool{}
+	for _, file := range files {
+		words, err := l.readList(file)
+		if err != nil {
+			return nil, err
+		}
+		for w := range words {
+			dict[w] = true
+		}
+	}
+	return dict, nil
+}
+
+// readList returns the words of a word list file, reading it again only when it changed. Hunspell
+// .dic files can be used: the count on their first line and the affix flags after each word are
+// ignored, though the words the affixes would form aren't known.
+func (l *ProseLinter) readList(file string) (map[string]bool, error) {
+	p := file
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(l.projectDir, filepath.FromSlash(p))
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read word list %s: %w", file, err)
+	}
+	if list := l.lists[p]; list != nil && list.modTime.Equal(info.ModTime()) && list.size == info.Size() {
+		return list.words, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read word list %s: %w", file, err)
+	}
+	defer f.Close()
+	words := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		w, _, _ := strings.Cut(fields[0], "/")
+		words[normalizeWord(w)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list %s: %w", file, err)
+	}
+	l.lists[p] = &wordList{modTime: info.ModTime(), size: info.Size(), words: words}
+	return words, nil
+}
+
+// proseSkipRanges returns the byte ranges of content that aren't prose: front matter, code blocks
+// and the matches of proseSkipRe
+func proseSkipRanges(content string) [][2]int {
+	var ranges [][2]int
+	if _, body, _, _ := hugocontent.Parse([]byte(content)); strings.HasSuffix(content, body) && len(body) < len(content) {
+		ranges = append(ranges, [2]int{0, len(content) - len(body)})
+	}
+	ranges = append(ranges, codeFenceRanges(content)...)
+	for _, m := range proseSkipRe.FindAllStringIndex(content, -1) {
+		ranges = append(ranges, [2]int{m[0], m[1]})
+	}
+	return ranges
+}
+
+// proseWords splits content into words, leaving out those that start in a skipped range. Words
+// are letters and digits, joined by apostrophes and hyphens; underscores around them are markdown
+// emphasis and aren't part of them.
+func proseWords(content string, skip [][2]int) []proseWord {
+	var words []proseWord
+	start := -1
+	for i := 0; i <= len(content); {
+		r, size := utf8.RuneError, 1
+		if i < len(content) {
+			r, size = utf8.DecodeRuneInString(content[i:])
+		}
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			i += size
+			continue
+		}
+		if start >= 0 && (r == '\'' || r == '’' || r == '-') && i+size < len(content) {
+			if next, _ := utf8.DecodeRuneInString(content[i+size:]); unicode.IsLetter(next) {
+				i += size
+				continue
+			}
+		}
+		if start >= 0 {
+			text := content[start:i]
+			trimmed := strings.TrimLeft(text, "_")
+			begin := start + len(text) - len(trimmed)
+			trimmed = strings.TrimRight(trimmed, "_")
+			if trimmed != "" && !inRanges(begin, skip) {
+				words = append(words, proseWord{text: trimmed, start: begin, end: begin + len(trimmed)})
+			}
+			start = -1
+		}
+		i += size
+	}
+	return words
+}
+
+// sentenceBreak reports whether the text between two words ends a sentence: it has a full stop,
+// question or exclamation mark that isn't skipped, starts a new block, or ends a heading or table
+// row
+func sentenceBreak(content string, start, end int, skip [][2]int) bool {
+	gap := content[start:end]
+	if !strings.Contains(gap, "\n") {
+		return strings.ContainsAny(gap, ".!?") && hasBreakMark(content, start, end, skip)
+	}
+	if proseBlockRe.MatchString(gap) {
+		return true
+	}
+	line := strings.TrimSpace(content[strings.LastIndex(content[:start], "\n")+1 : start])
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "|") {
+		return true
+	}
+	return hasBreakMark(content, start, end, skip)
+}
+
+// hasBreakMark reports whether content has a full stop, question or exclamation mark between
+// start and end outside the skipped ranges
+func hasBreakMark(content string, start, end int, skip [][2]int) bool {
+	for i := start; i < end; i++ {
+		switch content[i] {
+		case '.', '!', '?':
+			if !inRanges(i, skip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkSpelling reports whether a word is spell checked. Numbers, identifiers, single letters,
+// acronyms and words in camel case are not.
+func checkSpelling(word string) bool {
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+	for i, r := range word {
+		if unicode.IsDigit(r) || r == '_' || (i > 0 && unicode.IsUpper(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKnown reports whether a word is in the dictionary or the project word list, on its own, as
+// the parts of a hyphenated word, or without a possessive ending
+func isKnown(word string, dict, project map[string]bool) bool {
+	w := normalizeWord(word)
+	if dict[w] || project[w] {
+		return true
+	}
+	if strings.Contains(w, "-") {
+		for _, part := range strings.Split(w, "-") {
+			if part != "" && !isKnown(part, dict, project) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, suffix := range []string{"'s", "'"} {
+		if base, ok := strings.CutSuffix(w, suffix); ok && base != "" && (dict[base] || project[base]) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWord lowercases a word and writes its apostrophes as '
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(word)), "’", "'")
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
+
+// hasLetter reports whether s has a letter
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// lineIndex converts byte offsets into positions without rescanning the content for each one
+type lineIndex []int // Offset each line starts at
+
+// newLineIndex indexes the lines of content
+func newLineIndex(content string) lineIndex {
+	idx := lineIndex{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			idx = append(idx, i+1)
+		}
+	}
+	return idx
+}
+
+// position converts a byte offset into a 1-based line and column, as the package's position does
+func (idx lineIndex) position(offset int) (int, int) {
+	line := sort.SearchInts(idx, offset+1) - 1
+	return line + 1, offset - idx[line] + 1
+}
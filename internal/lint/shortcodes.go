@@ -0,0 +1,263 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/shortcodes"
+)
+
+// builtinShortcodes are provided by Hugo itself and never appear in layouts/shortcodes
+var builtinShortcodes = map[string]bool{
+	"details":   true,
+	"figure":    true,
+	"gist":      true,
+	"highlight": true,
+	"instagram": true,
+	"param":     true,
+	"qr":        true,
+	"ref":       true,
+	"relref":    true,
+	"tweet":     true,
+	"vimeo":     true,
+	"x":         true,
+	"youtube":   true,
+}
+
+var (
+	// Match {{< name params >}} and {{% name params %}}, including closing tags
+	shortcodeCallRe = regexp.MustCompile(`(?s)\{\{([<%])\s*(/?)\s*(\w[\w./-]*)(.*?)\s*[>%]\}\}`)
+
+	// Match name="value", name=`value`, name=value or a positional value
+	shortcodeArgRe = regexp.MustCompile("([\\w-]+)\\s*=\\s*(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`|[^\\s\"`]+)|(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`|[^\\s\"`]+)")
+)
+
+// ShortcodeCall represents a shortcode invocation found in content
+type ShortcodeCall struct {
+	Name       string            `json:"name"`
+	Line       int               `json:"line"`
+	Column     int               `json:"column"`
+	Named      map[string]string `json:"named"`
+	Positional []string          `json:"positional"`
+}
+
+// ShortcodeLinter validates shortcode invocations against detected shortcode definitions
+type ShortcodeLinter struct {
+	projectDir string
+	parser     *shortcodes.Parser
+}
+
+// NewShortcodeLinter creates a new shortcode linter
+func NewShortcodeLinter(projectDir string, parser *shortcodes.Parser) *ShortcodeLinter {
+	return &ShortcodeLinter{
+		projectDir: projectDir,
+		parser:     parser,
+	}
+}
+
+// LintAll checks every markdown file in the content directory. It stops with the error of ctx once
+// it's done.
+func (l *ShortcodeLinter) LintAll(ctx context.Context) (*Report, error) {
+	defs, err := l.definitions()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := markdownFiles(l.projectDir, "content")
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+		if err != nil {
+			continue
+		}
+		diagnostics = append(diagnostics, lintShortcodes(path, string(content), defs)...)
+	}
+
+	return newReport(len(paths), diagnostics), nil
+}
+
+// LintFile checks a single file
+func (l *ShortcodeLinter) LintFile(path string) (*Report, error) {
+	defs, err := l.definitions()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(l.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+
+	return newReport(1, lintShortcodes(filepath.ToSlash(path), string(content), defs)), nil
+}
+
+// LintContent checks unsaved content, reporting diagnostics against the given path
+func (l *ShortcodeLinter) LintContent(path, content string) (*Report, error) {
+	defs, err := l.definitions()
+	if err != nil {
+		return nil, err
+	}
+
+	return newReport(1, lintShortcodes(filepath.ToSlash(path), content, defs)), nil
+}
+
+func (l *ShortcodeLinter) definitions() (map[string]shortcodes.Shortcode, error) {
+	all, err := l.parser.DetectAll()
+	if err != nil {
+		return nil, err
+	}
+
+	defs := make(map[string]shortcodes.Shortcode, len(all))
+	for _, sc := range all {
+		defs[sc.Name] = sc
+	}
+	return defs, nil
+}
+
+// FindShortcodeCalls returns all shortcode invocations in content, skipping closing tags and code fences
+func FindShortcodeCalls(content string) []ShortcodeCall {
+	fences := codeFenceRanges(content)
+	var calls []ShortcodeCall
+
+	for _, loc := range shortcodeCallRe.FindAllStringSubmatchIndex(content, -1) {
+		if inRanges(loc[0], fences) {
+			continue
+		}
+		// Skip closing tags
+		if loc[4] != loc[5] {
+			continue
+		}
+
+		line, col := position(content, loc[0])
+		call := ShortcodeCall{
+			Name:       content[loc[6]:loc[7]],
+			Line:       line,
+			Column:     col,
+			Named:      map[string]string{},
+			Positional: []string{},
+		}
+
+		args := strings.TrimSuffix(strings.TrimSpace(content[loc[8]:loc[9]]), "/")
+		for _, m := range shortcodeArgRe.FindAllStringSubmatch(args, -1) {
+			if m[1] != "" {
+				call.Named[m[1]] = unquote(m[2])
+			} else {
+				call.Positional = append(call.Positional, unquote(m[3]))
+			}
+		}
+
+		calls = append(calls, call)
+	}
+
+	return calls
+}
+
+func lintShortcodes(path, content string, defs map[string]shortcodes.Shortcode) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, call := range FindShortcodeCalls(content) {
+		diag := func(severity Severity, rule, format string, args ...interface{}) {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:     path,
+				Line:     call.Line,
+				Column:   call.Column,
+				Severity: severity,
+				Rule:     rule,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		def, ok := defs[call.Name]
+		if !ok {
+			if !builtinShortcodes[call.Name] {
+				diag(SeverityError, "unknown-shortcode", "Unknown shortcode %q", call.Name)
+			}
+			continue
+		}
+
+		// Shortcodes without detected named parameters may read positional ones we can't validate
+		if len(def.Parameters) == 0 {
+			continue
+		}
+
+		known := make(map[string]shortcodes.Parameter, len(def.Parameters))
+		for _, p := range def.Parameters {
+			known[p.Name] = p
+		}
+
+		if len(call.Positional) > 0 && len(call.Named) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(call.Named))
+		for name := range call.Named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value := call.Named[name]
+			param, ok := known[name]
+			if !ok {
+				diag(SeverityWarning, "unknown-param", "Shortcode %q has no parameter %q", call.Name, name)
+				continue
+			}
+			if msg := checkParamType(param, value); msg != "" {
+				diag(SeverityWarning, "param-type", "Shortcode %q parameter %q: %s", call.Name, name, msg)
+			}
+		}
+
+		for _, param := range def.Parameters {
+			if !param.Required {
+				continue
+			}
+			if _, ok := call.Named[param.Name]; !ok {
+				diag(SeverityError, "missing-param", "Shortcode %q is missing required parameter %q", call.Name, param.Name)
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// checkParamType returns a message when value doesn't match the parameter type
+func checkParamType(param shortcodes.Parameter, value string) string {
+	switch param.Type {
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Sprintf("expected true or false, got %q", value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("expected a number, got %q", value)
+		}
+	}
+	return ""
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '`' && value[len(value)-1] == '`') {
+			if value[0] == '"' {
+				if s, err := strconv.Unquote(value); err == nil {
+					return s
+				}
+			}
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
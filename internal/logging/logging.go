@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Setup installs the default slog logger described by cfg. Output from the standard log package is routed
+// through it as well. The returned closer closes the log file, if any.
+func Setup(cfg config.ServerConfig) (io.Closer, error) {
+	level, err := ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+	if cfg.LogFile != "" {
+		file, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out = file
+		closer = file
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.LogFormat) {
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		closer.Close()
+		return nil, fmt.Errorf("unknown log format: %s", cfg.LogFormat)
+	}
+
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+	// slog.SetDefault routes the log package through the handler; drop its own timestamp prefix
+	log.SetFlags(0)
+
+	return closer, nil
+}
+
+// ParseLevel parses a level name; empty means info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level: %s", name)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// contextHandler adds the chi request ID and the active trace and span IDs from the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if id := middleware.GetReqID(ctx); id != "" {
+			record.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,236 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Duration reads the playing time of an audio or video file from its headers. It's zero for
+// formats it can't be read from, such as Ogg and WebM.
+func Duration(path string) (time.Duration, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".mp3", ".m4a", ".m4b", ".mp4", ".m4v", ".mov", ".wav":
+	default:
+		return 0, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	switch ext {
+	case ".mp3":
+		return mp3Duration(f, stat.Size())
+	case ".wav":
+		return wavDuration(f)
+	default:
+		return mp4Duration(f, stat.Size())
+	}
+}
+
+// MPEG audio tables, indexed by [version is MPEG-1][bitrate index] for Layer III
+var (
+	mp3Bitrates = [2][16]int{
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},     // MPEG-2/2.5
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}, // MPEG-1
+	}
+	mp3SampleRates = map[int][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mp3Duration reads the frame count from a Xing/Info or VBRI header, falling back to the
+// bitrate of the first frame for constant bitrate files
+func mp3Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	// Skip an ID3v2 tag
+	var start int64
+	head := make([]byte, 10)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return 0, err
+	}
+	if bytes.HasPrefix(head, []byte("ID3")) {
+		start = 10 + (int64(head[6]&0x7f)<<21 | int64(head[7]&0x7f)<<14 | int64(head[8]&0x7f)<<7 | int64(head[9]&0x7f))
+		if head[5]&0x10 != 0 {
+			start += 10 // Footer
+		}
+	}
+
+	// Find the first frame within the next 64 KiB
+	buf := make([]byte, 64*1024)
+	n, err := r.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	buf = buf[:n]
+
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xff || buf[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		header := binary.BigEndian.Uint32(buf[i:])
+		version := int(header>>19) & 3 // 0 = 2.5, 2 = 2, 3 = 1
+		layer := int(header>>17) & 3   // 1 = Layer III
+		bitrateIdx := int(header>>12) & 15
+		rateIdx := int(header>>10) & 3
+		mono := (header>>6)&3 == 3
+		if version == 1 || layer != 1 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+			continue // Not a valid Layer III header
+		}
+
+		mpeg1 := 0
+		if version == 3 {
+			mpeg1 = 1
+		}
+		sampleRate := mp3SampleRates[version][rateIdx]
+		samplesPerFrame := 576
+		sideInfo := 17
+		if mono {
+			sideInfo = 9
+		}
+		if mpeg1 == 1 {
+			samplesPerFrame = 1152
+			sideInfo = 32
+			if mono {
+				sideInfo = 17
+			}
+		}
+
+		// Xing/Info header of VBR encoders
+		if x := i + 4 + sideInfo; x+12 <= len(buf) {
+			if tag := string(buf[x : x+4]); tag == "Xing" || tag == "Info" {
+				if binary.BigEndian.Uint32(buf[x+4:])&1 != 0 {
+					frames := binary.BigEndian.Uint32(buf[x+8:])
+					return frameDuration(int64(frames), samplesPerFrame, sampleRate), nil
+				}
+			}
+		}
+		// VBRI header of Fraunhofer encoders
+		if v := i + 4 + 32; v+18 <= len(buf) && string(buf[v:v+4]) == "VBRI" {
+			frames := binary.BigEndian.Uint32(buf[v+14:])
+			return frameDuration(int64(frames), samplesPerFrame, sampleRate), nil
+		}
+
+		// Constant bitrate: audio bytes over bytes per second, excluding an ID3v1 tag
+		audioBytes := size - start - int64(i)
+		tag := make([]byte, 3)
+		if _, err := r.ReadAt(tag, size-128); err == nil && string(tag) == "TAG" {
+			audioBytes -= 128
+		}
+		bitrate := int64(mp3Bitrates[mpeg1][bitrateIdx]) * 1000
+		return time.Duration(audioBytes*8*1000/bitrate) * time.Millisecond, nil
+	}
+
+	return 0, fmt.Errorf("%w: no MPEG audio frame found", ErrUnreadable)
+}
+
+func frameDuration(frames int64, samplesPerFrame, sampleRate int) time.Duration {
+	return time.Duration(frames*int64(samplesPerFrame)*1000/int64(sampleRate)) * time.Millisecond
+}
+
+// mp4Duration reads the duration from the movie header (moov/mvhd) atom
+func mp4Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	moov, moovSize, err := findAtom(r, 0, size, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhd, _, err := findAtom(r, moov, moov+moovSize, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	head := make([]byte, 32)
+	if _, err := r.ReadAt(head, mvhd); err != nil {
+		return 0, err
+	}
+	var timescale, duration uint64
+	if head[0] == 1 { // Version 1 uses 64-bit times
+		timescale = uint64(binary.BigEndian.Uint32(head[20:]))
+		duration = binary.BigEndian.Uint64(head[24:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(head[12:]))
+		duration = uint64(binary.BigEndian.Uint32(head[16:]))
+	}
+	if timescale == 0 {
+		return 0, fmt.Errorf("%w: invalid mvhd timescale", ErrUnreadable)
+	}
+	return time.Duration(duration*1000/timescale) * time.Millisecond, nil
+}
+
+// findAtom returns the payload offset and size of the first atom of the given type in [start, end)
+func findAtom(r io.ReaderAt, start, end int64, name string) (int64, int64, error) {
+	head := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(head[:8], offset); err != nil {
+			return 0, 0, err
+		}
+		size := int64(binary.BigEndian.Uint32(head))
+		headerSize := int64(8)
+		switch size {
+		case 0: // Extends to the end of the container
+			size = end - offset
+		case 1: // 64-bit size follows the type
+			if _, err := r.ReadAt(head[8:16], offset+8); err != nil {
+				return 0, 0, err
+			}
+			size = int64(binary.BigEndian.Uint64(head[8:]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			break
+		}
+		if string(head[4:8]) == name {
+			return offset + headerSize, size - headerSize, nil
+		}
+		offset += size
+	}
+	return 0, 0, fmt.Errorf("%w: no %s atom", ErrUnreadable, name)
+}
+
+// wavDuration divides the size of the data chunk by the byte rate of the fmt chunk
+func wavDuration(r io.ReaderAt) (time.Duration, error) {
+	head := make([]byte, 12)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return 0, err
+	}
+	if string(head[0:4]) != "RIFF" || string(head[8:12]) != "WAVE" {
+		return 0, fmt.Errorf("%w: not a RIFF/WAVE file", ErrUnreadable)
+	}
+
+	var byteRate uint32
+	chunk := make([]byte, 20)
+	for offset := int64(12); ; {
+		if _, err := r.ReadAt(chunk[:8], offset); err != nil {
+			return 0, fmt.Errorf("%w: no data chunk", ErrUnreadable)
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch string(chunk[0:4]) {
+		case "fmt ":
+			// Format, channels and sample rate precede the byte rate
+			if _, err := r.ReadAt(chunk[8:20], offset+8); err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(chunk[16:20])
+		case "data":
+			if byteRate == 0 {
+				return 0, fmt.Errorf("%w: data chunk before fmt chunk", ErrUnreadable)
+			}
+			return time.Duration(size*1000/int64(byteRate)) * time.Millisecond, nil
+		}
+		offset += 8 + size + size%2 // Chunks are word aligned
+	}
+}
@@ -0,0 +1,347 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+)
+
+// Errors returned by the media library
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("media file too large")
+	ErrExists          = errors.New("media file already exists")
+	ErrNotFound        = errors.New("media file not found")
+	ErrNotMedia        = errors.New("not a media file")
+	ErrInvalidName     = errors.New("invalid media file name")
+	ErrUnreadable      = errors.New("unreadable media file")
+)
+
+// Media kinds
+const (
+	KindPDF      = "pdf"
+	KindVideo    = "video"
+	KindAudio    = "audio"
+	KindDownload = "download"
+)
+
+// staticDir holds the media files; Hugo publishes its files at the root of the site
+const staticDir = "static"
+
+// mimeTypes are the MIME types of the default media extensions, so they don't depend on the
+// system's MIME database
+var mimeTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".zip":  "application/zip",
+	".gz":   "application/gzip",
+	".tgz":  "application/gzip",
+	".7z":   "application/x-7z-compressed",
+	".epub": "application/epub+zip",
+	".csv":  "text/csv",
+	".txt":  "text/plain",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+}
+
+// Item is a media file in static/ with the metadata its kind has
+type Item struct {
+	Path     string    `json:"path"` // Project-relative, e.g. static/media/talk.mp4
+	URL      string    `json:"url"`  // Site-relative, e.g. /media/talk.mp4
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Type     string    `json:"type"` // MIME type
+	Size     int64     `json:"size"` // Bytes
+	Modified time.Time `json:"modified"`
+	Duration float64   `json:"duration,omitempty"` // Seconds, for MP3, MP4, QuickTime and WAV files
+	Pages    int       `json:"pages,omitempty"`    // For PDFs whose page tree can be read
+	Poster   string    `json:"poster,omitempty"`   // URL of the widest poster image of a video
+}
+
+// Type is a media kind with the extensions it takes and its upload limit
+type Type struct {
+	Kind       string   `json:"kind"`
+	Extensions []string `json:"extensions"`
+	MaxSizeMB  int      `json:"maxSizeMB"` // 0 = unlimited
+}
+
+// Manager uploads and lists PDFs, video, audio and downloads kept in static/
+type Manager struct {
+	projectDir string
+	config     config.MediaConfig
+	images     *images.Processor
+	ffmpeg     string // Path of the ffmpeg binary, empty when it isn't installed
+}
+
+// NewManager creates a new media library. ffmpeg is looked up once, here; without it videos get
+// no posters.
+func NewManager(projectDir string, cfg config.MediaConfig, imageMgr *images.Processor) *Manager {
+	ffmpeg := cfg.Posters.FFmpeg
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	ffmpeg, _ = exec.LookPath(ffmpeg)
+
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		images:     imageMgr,
+		ffmpeg:     ffmpeg,
+	}
+}
+
+// Types returns the media kinds in a fixed order
+func (m *Manager) Types() []Type {
+	types := []Type{
+		{Kind: KindPDF, Extensions: m.config.PDF.Extensions, MaxSizeMB: m.config.PDF.MaxSizeMB},
+		{Kind: KindVideo, Extensions: m.config.Video.Extensions, MaxSizeMB: m.config.Video.MaxSizeMB},
+		{Kind: KindAudio, Extensions: m.config.Audio.Extensions, MaxSizeMB: m.config.Audio.MaxSizeMB},
+		{Kind: KindDownload, Extensions: m.config.Download.Extensions, MaxSizeMB: m.config.Download.MaxSizeMB},
+	}
+	for i := range types {
+		if types[i].Extensions == nil {
+			types[i].Extensions = []string{}
+		}
+	}
+	return types
+}
+
+// typeOf returns the media type of a file name by its extension
+func (m *Manager) typeOf(name string) (Type, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if ext == "" {
+		return Type{}, false
+	}
+	for _, t := range m.Types() {
+		for _, e := range t.Extensions {
+			if strings.ToLower(strings.TrimPrefix(e, ".")) == ext {
+				return t, true
+			}
+		}
+	}
+	return Type{}, false
+}
+
+// List returns the media files in static/, sorted by path. kind and folder (relative to static/)
+// narrow the list when they're set.
+func (m *Manager) List(kind, folder string) ([]Item, error) {
+	switch kind {
+	case "", KindPDF, KindVideo, KindAudio, KindDownload:
+	default:
+		return nil, fmt.Errorf("%w: %s (use pdf, video, audio or download)", ErrUnsupportedType, kind)
+	}
+
+	root := path.Join(staticDir, cleanPath(folder))
+	items := []Item{}
+	err := filepath.WalkDir(m.abs(root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(m.projectDir, p)
+		if err != nil {
+			return err
+		}
+		t, ok := m.typeOf(d.Name())
+		if !ok || (kind != "" && t.Kind != kind) {
+			return nil
+		}
+		item, err := m.item(filepath.ToSlash(rel), t)
+		if err != nil {
+			return err
+		}
+		items = append(items, *item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, nil
+}
+
+// Get returns a media file by its project-relative path
+func (m *Manager) Get(rel string) (*Item, error) {
+	rel = cleanPath(rel)
+	if !strings.HasPrefix(rel, staticDir+"/") {
+		return nil, fmt.Errorf("%w: %s isn't in %s/", ErrNotMedia, rel, staticDir)
+	}
+	t, ok := m.typeOf(rel)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotMedia, rel)
+	}
+	return m.item(rel, t)
+}
+
+// Upload stores a media file in a folder of static/, the configured one when folder is empty.
+// size is the declared size of the file, or -1 when unknown; the limit of the file's type is
+// enforced on the bytes read either way.
+func (m *Manager) Upload(r io.Reader, size int64, folder, filename string, overwrite bool) (*Item, error) {
+	rel, err := m.Dest(folder, filename)
+	if err != nil {
+		return nil, err
+	}
+	t, _ := m.typeOf(rel)
+	limit := int64(t.MaxSizeMB) << 20
+	if limit > 0 && size > limit {
+		return nil, fmt.Errorf("%w: %s files are limited to %d MB", ErrTooLarge, t.Kind, t.MaxSizeMB)
+	}
+
+	full := m.abs(rel)
+	if stat, err := os.Stat(full); err == nil && (!overwrite || stat.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrExists, rel)
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return nil, err
+	}
+
+	// Written next to the destination and renamed, so a rejected upload leaves no partial file
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	written, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && written > limit {
+		return nil, fmt.Errorf("%w: %s files are limited to %d MB", ErrTooLarge, t.Kind, t.MaxSizeMB)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), full); err != nil {
+		return nil, err
+	}
+	return m.item(rel, t)
+}
+
+// Dest returns the project-relative path Upload stores a file at, for checking it before the upload
+func (m *Manager) Dest(folder, filename string) (string, error) {
+	filename = path.Base(cleanPath(filename))
+	if filename == "." || filename == "/" || strings.HasPrefix(filename, ".") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, filename)
+	}
+	if _, ok := m.typeOf(filename); !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, filename)
+	}
+	if folder == "" {
+		folder = m.config.Folder
+	}
+	return path.Join(staticDir, cleanPath(folder), filename), nil
+}
+
+// item reads the metadata of a media file
+func (m *Manager) item(rel string, t Type) (*Item, error) {
+	full := m.abs(rel)
+	stat, err := os.Stat(full)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, rel)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrNotMedia, rel)
+	}
+
+	item := &Item{
+		Path:     rel,
+		URL:      strings.TrimPrefix(rel, staticDir),
+		Name:     path.Base(rel),
+		Kind:     t.Kind,
+		Type:     mimeType(rel),
+		Size:     stat.Size(),
+		Modified: stat.ModTime(),
+	}
+	// Metadata is best effort: a file it can't be read from is still listed
+	switch t.Kind {
+	case KindVideo, KindAudio:
+		if d, err := Duration(full); err == nil {
+			item.Duration = d.Round(time.Millisecond).Seconds()
+		}
+		if t.Kind != KindVideo {
+			break
+		}
+		if poster := m.findPoster(full); poster != "" {
+			item.Poster = path.Join(path.Dir(item.URL), poster)
+		}
+	case KindPDF:
+		if pages, err := pdfPages(full); err == nil {
+			item.Pages = pages
+		}
+	}
+	return item, nil
+}
+
+// abs returns the absolute path of a project-relative path
+func (m *Manager) abs(rel string) string {
+	return filepath.Join(m.projectDir, filepath.FromSlash(rel))
+}
+
+// cleanPath normalizes a relative path, so it can't point outside the directory it's joined to
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// mimeType returns the MIME type of a file name
+func mimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := mimeTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,50 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// pdfPagesRe matches the type of a page tree node, the root of which counts every page
+	pdfPagesRe = regexp.MustCompile(`/Type\s*/Pages\b`)
+	// pdfCountRe matches the page count of a page tree node
+	pdfCountRe = regexp.MustCompile(`/Count\s+(\d+)`)
+	// pdfPageRe matches the type of a single page
+	pdfPageRe = regexp.MustCompile(`/Type\s*/Page\b`)
+)
+
+// pdfPages reads the page count of a PDF from the /Count of its page tree root, the largest one,
+// falling back to counting page objects. Neither is visible when the document keeps its objects
+// in compressed object streams.
+func pdfPages(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return 0, fmt.Errorf("%w: not a PDF document", ErrUnreadable)
+	}
+
+	count := 0
+	for _, obj := range bytes.Split(data, []byte("endobj")) {
+		if !pdfPagesRe.Match(obj) {
+			continue
+		}
+		for _, m := range pdfCountRe.FindAllSubmatch(obj, -1) {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n > count {
+				count = n
+			}
+		}
+	}
+	if count == 0 {
+		count = len(pdfPageRe.FindAllIndex(data, -1))
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("%w: no page tree found", ErrUnreadable)
+	}
+	return count, nil
+}
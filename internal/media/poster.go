@@ -0,0 +1,133 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/images"
+)
+
+// ErrNoFFmpeg is returned for posters when ffmpeg wasn't found at startup
+var ErrNoFFmpeg = errors.New("ffmpeg is not installed")
+
+// frameTimeout bounds a single ffmpeg run, which only decodes up to one frame
+const frameTimeout = time.Minute
+
+// posterSuffix is added to the name of a video to name its poster images
+const posterSuffix = "-poster"
+
+// posterExts are the image types poster images are written as
+var posterExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+
+// FFmpeg returns the path of the ffmpeg binary found at startup, empty without one
+func (m *Manager) FFmpeg() string {
+	return m.ffmpeg
+}
+
+// Poster extracts a frame of a video with ffmpeg and makes a responsive poster set of it with the
+// image pipeline, next to the video and named like other variants, e.g. <name>-poster.WIDTHxHEIGHT.jpg. alt is the alt text of the
+// poster's image snippets, the video's name when empty.
+func (m *Manager) Poster(ctx context.Context, rel, alt string) (*images.ProcessResult, error) {
+	item, err := m.Get(rel)
+	if err != nil {
+		return nil, err
+	}
+	if item.Kind != KindVideo {
+		return nil, fmt.Errorf("%w: %s isn't a video", ErrNotMedia, item.Path)
+	}
+	if m.ffmpeg == "" {
+		return nil, ErrNoFFmpeg
+	}
+
+	frame, err := m.frame(ctx, m.abs(item.Path))
+	if err != nil {
+		return nil, err
+	}
+	if alt == "" {
+		alt = strings.TrimSuffix(item.Name, path.Ext(item.Name))
+	}
+	return m.images.Process(ctx, bytes.NewReader(frame), images.UploadOptions{
+		Folder:   path.Dir(item.Path),
+		Filename: posterName(item.Name),
+		Quality:  m.config.Posters.Quality,
+		Widths:   append([]int(nil), m.config.Posters.Widths...),
+		Alt:      alt,
+	})
+}
+
+// frame extracts a frame of a video as PNG, at the configured second or, when the video is
+// shorter, its first frame
+func (m *Manager) frame(ctx context.Context, full string) ([]byte, error) {
+	seeks := []float64{0}
+	if at := m.config.Posters.At; at > 0 {
+		seeks = []float64{at, 0}
+	}
+
+	var lastErr error
+	for _, seek := range seeks {
+		runCtx, cancel := context.WithTimeout(ctx, frameTimeout)
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(runCtx, m.ffmpeg,
+			"-nostdin", "-v", "error",
+			"-ss", strconv.FormatFloat(seek, 'f', 3, 64),
+			"-i", full,
+			"-frames:v", "1", "-f", "image2pipe", "-c:v", "png", "pipe:1")
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		cancel()
+
+		if err == nil && stdout.Len() > 0 {
+			return stdout.Bytes(), nil
+		}
+		// Seeking past the end succeeds without output
+		lastErr = fmt.Errorf("%w: ffmpeg extracted no frame at %gs", ErrUnreadable, seek)
+		if err != nil {
+			lastErr = fmt.Errorf("%w: ffmpeg: %s", ErrUnreadable, strings.TrimSpace(firstLine(stderr.String(), err.Error())))
+		}
+	}
+	return nil, lastErr
+}
+
+// posterName returns the base name of the poster images of a video, as the image pipeline names files
+func posterName(video string) string {
+	return images.SanitizeFilename(strings.TrimSuffix(video, path.Ext(video))) + posterSuffix
+}
+
+// findPoster returns the name of the widest poster image next to a video, empty when it has none
+func (m *Manager) findPoster(full string) string {
+	entries, err := os.ReadDir(filepath.Dir(full))
+	if err != nil {
+		return ""
+	}
+	poster := posterName(filepath.Base(full))
+
+	best, width := "", 0
+	for _, entry := range entries {
+		if entry.IsDir() || !posterExts[strings.ToLower(path.Ext(entry.Name()))] {
+			continue
+		}
+		if base, w, ok := m.images.ParseVariant(entry.Name()); ok && base == poster && w > width {
+			best, width = entry.Name(), w
+		}
+	}
+	return best
+}
+
+// firstLine returns the first non-empty line of s, or fallback
+func firstLine(s, fallback string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return line
+		}
+	}
+	return fallback
+}
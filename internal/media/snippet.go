@@ -0,0 +1,114 @@
+package media
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strings"
+)
+
+// Snippet is the markup that embeds or links a media file, as Markdown, raw HTML and a shortcode call
+type Snippet struct {
+	Markdown  string `json:"markdown"`
+	HTML      string `json:"html"`
+	Shortcode string `json:"shortcode"` // Calls the shortcode named after the kind: pdf, video, audio or download
+}
+
+// Snippet renders the markup of a media file. title is the text of links, the file name
+// without its extension when empty. Markdown can't embed players, so for video and audio it's
+// a link to the file.
+func (m *Manager) Snippet(rel, title string) (*Snippet, error) {
+	item, err := m.Get(rel)
+	if err != nil {
+		return nil, err
+	}
+	return item.Snippet(title), nil
+}
+
+// Snippet renders the markup of the item, see Manager.Snippet
+func (item *Item) Snippet(title string) *Snippet {
+	if title == "" {
+		title = strings.TrimSuffix(item.Name, path.Ext(item.Name))
+	}
+	src := html.EscapeString(item.URL)
+	label := title + " (" + item.label() + ")"
+
+	shortcode, poster := "{{< "+item.Kind+` src="`+escapeParam(item.URL)+`" title="`+escapeParam(title)+`"`, ""
+	if item.Poster != "" {
+		shortcode += ` poster="` + escapeParam(item.Poster) + `"`
+		poster = ` poster="` + html.EscapeString(item.Poster) + `"`
+	}
+
+	s := &Snippet{
+		Markdown:  "[" + escapeMarkdown(label) + "](" + markdownURL(item.URL) + ")",
+		Shortcode: shortcode + " >}}",
+	}
+	switch item.Kind {
+	case KindVideo, KindAudio:
+		s.HTML = "<" + item.Kind + ` controls preload="metadata"` + poster + ">\n" +
+			`  <source src="` + src + `" type="` + item.Type + `">` + "\n" +
+			`  <a href="` + src + `">` + html.EscapeString(label) + "</a>\n" +
+			"</" + item.Kind + ">"
+	case KindPDF:
+		s.HTML = `<a href="` + src + `" type="` + item.Type + `">` + html.EscapeString(label) + "</a>"
+	default:
+		s.HTML = `<a href="` + src + `" download>` + html.EscapeString(label) + "</a>"
+	}
+	return s
+}
+
+// label describes the file in link text, e.g. "PDF, 1.2 MB, 12 pages", so readers know what they
+// open before they do
+func (item *Item) label() string {
+	parts := []string{strings.ToUpper(strings.TrimPrefix(path.Ext(item.Name), ".")), formatSize(item.Size)}
+	switch {
+	case item.Pages == 1:
+		parts = append(parts, "1 page")
+	case item.Pages > 1:
+		parts = append(parts, fmt.Sprintf("%d pages", item.Pages))
+	case item.Duration > 0:
+		parts = append(parts, formatDuration(item.Duration))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSize formats a size in bytes with a binary unit, e.g. 1.2 MB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+// formatDuration formats seconds as M:SS, or H:MM:SS from an hour up
+func formatDuration(seconds float64) string {
+	s := int64(seconds + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// escapeMarkdown escapes the characters that would end the text of a Markdown link
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(text)
+}
+
+// markdownURL wraps a URL with spaces or parentheses in angle brackets, as Markdown links need
+func markdownURL(url string) string {
+	if strings.ContainsAny(url, " ()") {
+		return "<" + url + ">"
+	}
+	return url
+}
+
+// escapeParam escapes quotes in a quoted shortcode parameter value
+func escapeParam(value string) string {
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
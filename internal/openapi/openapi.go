@@ -0,0 +1,329 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	types map[string]reflect.Type // Go type registered under each component name
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds reusable schemas
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem holds the operations of a path
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+// Operation describes a single API operation
+type Operation struct {
+	Summary     string               `json:"summary"`
+	OperationID string               `json:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes an operation's response
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema subset used by OpenAPI 3
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Route describes an endpoint to add to the document
+type Route struct {
+	Method      string
+	Path        string // chi-style path, e.g. /api/v1/files/{path}
+	Summary     string
+	Tag         string
+	Query       []Parameter
+	Request     interface{} // Zero value of the JSON request type, or nil
+	Form        interface{} // Zero value of a multipart form type, or nil
+	Response    interface{} // Zero value of the JSON response type, or nil
+	Status      int         // Success status, defaults to 200
+	ContentType string      // Non-JSON success content type, e.g. text/event-stream
+}
+
+// New creates an empty document
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+		},
+		types: map[string]reflect.Type{},
+	}
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// versionRe matches the version segment of a path, e.g. v1
+var versionRe = regexp.MustCompile(`^v[0-9]+$`)
+
+// Add adds a route to the document, generating schemas for its Go types
+func (d *Document) Add(route Route, errorType interface{}) {
+	apiPath := strings.ReplaceAll(route.Path, "/*", "/{wildcard}")
+
+	op := &Operation{
+		Summary:     route.Summary,
+		OperationID: operationID(route.Method, apiPath),
+		Responses:   map[string]*Response{},
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	for _, m := range pathParamRe.FindAllStringSubmatch(apiPath, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, q := range route.Query {
+		q.In = "query"
+		if q.Schema == nil {
+			q.Schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, q)
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: d.SchemaFor(route.Request)}},
+		}
+	} else if route.Form != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"multipart/form-data": {Schema: d.SchemaFor(route.Form)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = 200
+	}
+	success := &Response{Description: "Success"}
+	switch {
+	case route.ContentType != "":
+		success.Content = map[string]*MediaType{route.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	case route.Response != nil:
+		success.Content = map[string]*MediaType{"application/json": {Schema: d.SchemaFor(route.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if errorType != nil {
+		op.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     map[string]*MediaType{"application/json": {Schema: d.SchemaFor(errorType)}},
+		}
+	}
+
+	item, ok := d.Paths[apiPath]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[apiPath] = item
+	}
+	switch route.Method {
+	case "GET":
+		item.Get = op
+	case "PUT":
+		item.Put = op
+	case "POST":
+		item.Post = op
+	case "DELETE":
+		item.Delete = op
+	}
+}
+
+// SchemaFor returns the schema of a Go value. Named struct types are registered as components and referenced.
+func (d *Document) SchemaFor(v interface{}) *Schema {
+	return d.schemaForType(reflect.TypeOf(v))
+}
+
+// Binary marks a multipart file field in form types
+type Binary []byte
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	binaryType = reflect.TypeOf(Binary{})
+)
+
+func (d *Document) schemaForType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case binaryType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		if t.PkgPath() == "time" && t.Name() == "Duration" {
+			return &Schema{Type: "integer", Description: "Duration in nanoseconds"}
+		}
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaForType(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := d.componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			d.types[name] = t
+			// Register before recursing so self-referencing types terminate
+			d.Components.Schemas[name] = &Schema{Type: "object"}
+			d.Components.Schemas[name] = d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// structSchema describes the JSON encoding of a struct, flattening embedded structs as encoding/json does
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range d.structSchema(ft).Properties {
+					schema.Properties[k] = v
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = d.schemaForType(field.Type)
+	}
+	return schema
+}
+
+// componentName derives a component name from a Go type, e.g. files.FileInfo -> FileInfo.
+// Types with the same name in different packages are prefixed with their package, e.g. LintReport.
+func (d *Document) componentName(t reflect.Type) string {
+	name := capitalize(t.Name())
+	if existing, ok := d.types[name]; ok && existing != t {
+		return capitalize(path.Base(t.PkgPath())) + name
+	}
+	return name
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// operationID derives an operation ID from the method and path, leaving out the API prefix and
+// version, e.g. GET /api/v1/files/{path} -> getFilesPath
+func operationID(method, route string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(route, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '-' }) {
+		if part == "api" || versionRe.MatchString(part) {
+			continue
+		}
+		sb.WriteString(capitalize(part))
+	}
+	return sb.String()
+}
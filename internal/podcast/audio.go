@@ -0,0 +1,61 @@
+package podcast
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/media"
+)
+
+// ErrUnsupportedAudio is returned for files that aren't a supported podcast audio format
+var ErrUnsupportedAudio = errors.New("unsupported audio format")
+
+// AudioInfo describes an audio file as needed for an RSS enclosure
+type AudioInfo struct {
+	Length   int64         `json:"length"` // Size in bytes
+	Type     string        `json:"type"`   // MIME type
+	Duration time.Duration `json:"duration"`
+}
+
+// audioTypes maps podcast audio extensions to the MIME types used in enclosures
+var audioTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/x-m4a",
+	".m4b":  "audio/x-m4a",
+	".mp4":  "audio/mp4",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+}
+
+// Probe reads the size, MIME type and duration of an audio file. The duration is
+// zero for formats it can't be read from (Ogg).
+func Probe(path string) (*AudioInfo, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	mime, ok := audioTypes[ext]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAudio, ext)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &AudioInfo{Length: stat.Size(), Type: mime}
+
+	info.Duration, err = media.Duration(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read duration: %w", err)
+	}
+	return info, nil
+}
+
+// FormatDuration formats a duration as HH:MM:SS, the form iTunes expects
+func FormatDuration(d time.Duration) string {
+	s := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
@@ -0,0 +1,383 @@
+package podcast
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// ErrNotStatic is returned for audio files outside the static directory, which have no public URL
+var ErrNotStatic = errors.New("audio file must be inside static/")
+
+// Front matter keys written on ingestion and read back for the feed
+const (
+	KeyAudio       = "audio"
+	KeyAudioLength = "audio_length"
+	KeyAudioType   = "audio_type"
+	KeyDuration    = "duration"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // Apple Podcasts rejects the feed
+	SeverityWarning = "warning" // Recommended by Apple Podcasts
+)
+
+// Enclosure is the <enclosure> element of an RSS item
+type Enclosure struct {
+	URL    string `json:"url"`
+	Length int64  `json:"length"`
+	Type   string `json:"type"`
+}
+
+// Episode is an RSS item built from an episode page
+type Episode struct {
+	Path      string    `json:"path"`
+	Title     string    `json:"title"`
+	Date      time.Time `json:"date"`
+	Link      string    `json:"link,omitempty"`
+	Enclosure Enclosure `json:"enclosure"`
+	Duration  string    `json:"duration,omitempty"`
+	Draft     bool      `json:"draft"`
+	XML       string    `json:"xml"` // The enclosure and iTunes tags of the item, as rendered in the feed
+}
+
+// Channel holds the feed-level fields, read from the site title, languageCode and params.podcast
+type Channel struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Link        string `json:"link"`
+	Author      string `json:"author"`
+	Image       string `json:"image"`
+	Category    string `json:"category"`
+	Explicit    string `json:"explicit"`
+	OwnerName   string `json:"ownerName"`
+	OwnerEmail  string `json:"ownerEmail"`
+}
+
+// Feed is a preview of the podcast feed
+type Feed struct {
+	Channel  Channel   `json:"channel"`
+	Episodes []Episode `json:"episodes"`
+}
+
+// Issue is a validation problem of the channel (empty Path) or an episode
+type Issue struct {
+	Path     string `json:"path,omitempty"`
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// IngestResult describes an ingested episode
+type IngestResult struct {
+	Path    string  `json:"path"`
+	Created bool    `json:"created"`
+	Episode Episode `json:"episode"`
+}
+
+// Manager manages podcast episodes
+type Manager struct {
+	projectDir string
+	config     config.PodcastConfig
+}
+
+// NewManager creates a new podcast manager
+func NewManager(projectDir string, cfg config.PodcastConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// episodesDir returns the project-relative directory holding episode pages
+func (m *Manager) episodesDir() string {
+	if m.config.EpisodesDir == "" {
+		return "content/episodes"
+	}
+	return filepath.ToSlash(filepath.Clean(m.config.EpisodesDir))
+}
+
+// Ingest reads the enclosure metadata of a project-relative audio file into the front matter of an episode page.
+// The page is created as a draft when it doesn't exist; page defaults to <episodes dir>/<audio name>.md.
+func (m *Manager) Ingest(audio, page, title string) (*IngestResult, error) {
+	audio = filepath.ToSlash(filepath.Clean(audio))
+	if !strings.HasPrefix(audio, "static/") {
+		return nil, ErrNotStatic
+	}
+
+	info, err := Probe(filepath.Join(m.projectDir, audio))
+	if err != nil {
+		return nil, err
+	}
+
+	if page == "" {
+		name := strings.TrimSuffix(filepath.Base(audio), filepath.Ext(audio))
+		page = m.episodesDir() + "/" + name + ".md"
+	}
+	page = filepath.ToSlash(filepath.Clean(page))
+
+	fields := map[string]interface{}{
+		KeyAudio:       strings.TrimPrefix(audio, "static"),
+		KeyAudioLength: info.Length,
+		KeyAudioType:   info.Type,
+	}
+	if info.Duration > 0 {
+		fields[KeyDuration] = FormatDuration(info.Duration)
+	}
+
+	path := filepath.Join(m.projectDir, page)
+	data, err := os.ReadFile(path)
+	created := os.IsNotExist(err)
+	switch {
+	case created:
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(audio), filepath.Ext(audio))
+		}
+		fields["title"] = title
+		fields["date"] = time.Now().Format(time.RFC3339)
+		fields["draft"] = true
+		data = nil
+	case err != nil:
+		return nil, err
+	case title != "":
+		fields["title"] = title
+	}
+
+	out, err := hugocontent.SetFields(data, fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(path, out, 0644); err != nil {
+		return nil, err
+	}
+
+	siteCfg, _ := site.Load(m.projectDir)
+	fm, _, _, _ := hugocontent.Parse(out)
+	return &IngestResult{
+		Path:    page,
+		Created: created,
+		Episode: m.episode(siteCfg, page, fm),
+	}, nil
+}
+
+// Feed builds a preview of the feed from the site configuration and the episode pages, newest first
+func (m *Manager) Feed() (*Feed, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+
+	feed := &Feed{Channel: channel(siteCfg), Episodes: []Episode{}}
+	root := filepath.Join(m.projectDir, m.episodesDir())
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" || strings.HasPrefix(d.Name(), "_index.") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fm, _, _, err := hugocontent.Parse(data)
+		if err != nil {
+			return nil // Reported by the linter, not the feed preview
+		}
+		rel, _ := filepath.Rel(m.projectDir, path)
+		feed.Episodes = append(feed.Episodes, m.episode(siteCfg, filepath.ToSlash(rel), fm))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(feed.Episodes, func(i, j int) bool { return feed.Episodes[i].Date.After(feed.Episodes[j].Date) })
+	return feed, nil
+}
+
+// Validate checks the fields Apple Podcasts requires or recommends
+func (m *Manager) Validate() ([]Issue, error) {
+	feed, err := m.Feed()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []Issue{}
+	add := func(path, field, severity, message string) {
+		issues = append(issues, Issue{Path: path, Field: field, Severity: severity, Message: message})
+	}
+
+	ch := feed.Channel
+	required := []struct{ field, value, message string }{
+		{"title", ch.Title, "Site title is required"},
+		{"description", ch.Description, "Set params.podcast.description or params.description"},
+		{"language", ch.Language, "Set languageCode, e.g. en-us"},
+		{"image", ch.Image, "Set params.podcast.image to square artwork of 1400-3000 px"},
+		{"category", ch.Category, "Set params.podcast.category to an Apple Podcasts category"},
+		{"explicit", ch.Explicit, "Set params.podcast.explicit to true or false"},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			add("", r.field, SeverityError, r.message)
+		}
+	}
+	if ch.Explicit != "" && ch.Explicit != "true" && ch.Explicit != "false" {
+		add("", "explicit", SeverityError, "params.podcast.explicit must be true or false")
+	}
+	if ch.Author == "" {
+		add("", "author", SeverityWarning, "Set params.podcast.author")
+	}
+	if ch.OwnerEmail == "" {
+		add("", "owner", SeverityWarning, "Set params.podcast.owner.email for Apple Podcasts verification")
+	}
+	if len(feed.Episodes) == 0 {
+		add("", "episodes", SeverityWarning, "No episode pages found in "+m.episodesDir())
+	}
+
+	for _, ep := range feed.Episodes {
+		if ep.Draft {
+			continue
+		}
+		if ep.Title == "" {
+			add(ep.Path, "title", SeverityError, "Episode title is required")
+		}
+		if ep.Enclosure.URL == "" {
+			add(ep.Path, KeyAudio, SeverityError, "Episode has no audio file; ingest one to fill the enclosure")
+			continue
+		}
+		if ep.Enclosure.Length <= 0 {
+			add(ep.Path, KeyAudioLength, SeverityError, "Enclosure length is missing")
+		}
+		if ep.Enclosure.Type == "" {
+			add(ep.Path, KeyAudioType, SeverityError, "Enclosure type is missing")
+		}
+		if ep.Duration == "" {
+			add(ep.Path, KeyDuration, SeverityWarning, "Episode duration is missing")
+		}
+		if ep.Date.IsZero() {
+			add(ep.Path, "date", SeverityWarning, "Episode has no date, so its pubDate is undefined")
+		}
+
+		// Local audio must exist and match the recorded length, or clients will reject the download
+		if strings.HasPrefix(ep.Enclosure.URL, "/") {
+			stat, err := os.Stat(filepath.Join(m.projectDir, "static", filepath.FromSlash(ep.Enclosure.URL)))
+			switch {
+			case err != nil:
+				add(ep.Path, KeyAudio, SeverityError, "Audio file not found: static"+ep.Enclosure.URL)
+			case ep.Enclosure.Length > 0 && stat.Size() != ep.Enclosure.Length:
+				add(ep.Path, KeyAudioLength, SeverityError,
+					fmt.Sprintf("Enclosure length %d doesn't match the file size %d; re-ingest the audio", ep.Enclosure.Length, stat.Size()))
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// episode builds the feed item of an episode page
+func (m *Manager) episode(siteCfg *site.Config, path string, fm hugocontent.FrontMatter) Episode {
+	ep := Episode{
+		Path:     path,
+		Title:    fm.String("title"),
+		Duration: fm.String(KeyDuration),
+		Draft:    fm.Bool("draft"),
+		Enclosure: Enclosure{
+			URL:  fm.String(KeyAudio),
+			Type: fm.String(KeyAudioType),
+		},
+	}
+	ep.Enclosure.Length, _ = strconv.ParseInt(fm.String(KeyAudioLength), 10, 64)
+	ep.Date, _ = fm.Time("date")
+
+	baseURL := ""
+	if siteCfg != nil {
+		baseURL = siteCfg.BaseURL()
+		if loc, err := hugocontent.Locate(siteCfg, path); err == nil {
+			ep.Link = hostURL(baseURL, hugocontent.PermalinkFor(siteCfg, loc, fm, path).URL)
+		}
+	}
+
+	var b strings.Builder
+	if ep.Enclosure.URL != "" {
+		fmt.Fprintf(&b, "<enclosure url=\"%s\" length=\"%d\" type=\"%s\"/>\n",
+			html.EscapeString(absoluteURL(baseURL, ep.Enclosure.URL)), ep.Enclosure.Length, html.EscapeString(ep.Enclosure.Type))
+	}
+	if ep.Link != "" {
+		fmt.Fprintf(&b, "<guid isPermaLink=\"true\">%s</guid>\n", html.EscapeString(ep.Link))
+	}
+	if !ep.Date.IsZero() {
+		fmt.Fprintf(&b, "<pubDate>%s</pubDate>\n", ep.Date.Format(time.RFC1123Z))
+	}
+	if ep.Duration != "" {
+		fmt.Fprintf(&b, "<itunes:duration>%s</itunes:duration>\n", html.EscapeString(ep.Duration))
+	}
+	ep.XML = b.String()
+	return ep
+}
+
+// channel reads the feed-level fields from the site configuration
+func channel(siteCfg *site.Config) Channel {
+	ch := Channel{
+		Title:       siteCfg.String("title"),
+		Description: siteCfg.String("params.podcast.description"),
+		Language:    siteCfg.String("languageCode"),
+		Link:        siteCfg.BaseURL(),
+		Author:      siteCfg.String("params.podcast.author"),
+		Image:       siteCfg.String("params.podcast.image"),
+		Category:    siteCfg.String("params.podcast.category"),
+		OwnerName:   siteCfg.String("params.podcast.owner.name"),
+		OwnerEmail:  siteCfg.String("params.podcast.owner.email"),
+	}
+	if ch.Description == "" {
+		ch.Description = siteCfg.String("params.description")
+	}
+	if ch.Author == "" {
+		ch.Author = siteCfg.String("params.author")
+	}
+	switch v := siteCfg.Get("params.podcast.explicit").(type) {
+	case bool:
+		ch.Explicit = strconv.FormatBool(v)
+	case string:
+		ch.Explicit = v
+	}
+	return ch
+}
+
+// absoluteURL joins the baseURL with a site-relative URL; absolute URLs are returned unchanged
+func absoluteURL(baseURL, rel string) string {
+	if rel == "" || strings.Contains(rel, "://") || baseURL == "" {
+		return rel
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(rel, "/")
+}
+
+// hostURL joins the scheme and host of baseURL with a permalink, which already includes the baseURL path
+func hostURL(baseURL, rel string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return rel
+	}
+	return base.Scheme + "://" + base.Host + rel
+}
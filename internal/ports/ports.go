@@ -0,0 +1,38 @@
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ErrNoFreePort is returned when every port in the probed range is in use
+var ErrNoFreePort = errors.New("no free port")
+
+// Free returns the first port in [port, port+fallback] that can be bound on host.
+// With fallback 0 only the configured port is tried.
+func Free(host string, port, fallback int) (int, error) {
+	if fallback < 0 {
+		fallback = 0
+	}
+	for p := port; p <= port+fallback && p <= 65535; p++ {
+		if Available(host, p) {
+			return p, nil
+		}
+	}
+	if fallback == 0 {
+		return 0, fmt.Errorf("%w: port %d is in use", ErrNoFreePort, port)
+	}
+	return 0, fmt.Errorf("%w: ports %d-%d are in use", ErrNoFreePort, port, port+fallback)
+}
+
+// Available reports whether a TCP listener can be opened on host:port
+func Available(host string, port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
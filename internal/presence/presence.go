@@ -0,0 +1,330 @@
+// Package presence tracks which editor sessions have a file open, and the advisory locks sessions
+// take on files so their saves aren't overwritten by someone else's. Nothing is stored: presence
+// and locks are kept in memory and expire unless they're renewed.
+package presence
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ViewerTTL is how long a session counts as viewing a file after its last heartbeat
+	ViewerTTL = 60 * time.Second
+
+	// DefaultLockTTL is how long a lock lasts without renewal when none is configured
+	DefaultLockTTL = 5 * time.Minute
+
+	// sweepInterval is how often expired viewers and locks are dropped and announced
+	sweepInterval = 15 * time.Second
+)
+
+var (
+	// ErrLocked is returned when another session holds the lock of a file
+	ErrLocked = errors.New("file is locked")
+
+	// ErrInvalidSession is returned for a missing or malformed session ID
+	ErrInvalidSession = errors.New("invalid session")
+)
+
+// sessionRe matches session IDs: what clients generate, such as UUIDs
+var sessionRe = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// Viewer is a session with a file open
+type Viewer struct {
+	Session string    `json:"session"`
+	User    string    `json:"user,omitempty"` // Empty when auth is disabled
+	Since   time.Time `json:"since"`
+	Seen    time.Time `json:"seen"` // Last heartbeat
+}
+
+// Lock is a session's advisory lock on a file
+type Lock struct {
+	Path     string    `json:"path"`
+	Session  string    `json:"session"`
+	User     string    `json:"user,omitempty"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+// State is who has a file open and who holds its lock
+type State struct {
+	Path    string   `json:"path"`
+	Viewers []Viewer `json:"viewers"` // Longest open first
+	Lock    *Lock    `json:"lock,omitempty"`
+}
+
+// Manager keeps the viewers and locks of files
+type Manager struct {
+	mu       sync.Mutex
+	viewers  map[string]map[string]*Viewer // By path, then session
+	locks    map[string]*Lock              // By path
+	onChange func(State)
+	stop     chan struct{}
+}
+
+// NewManager creates a presence manager
+func NewManager() *Manager {
+	return &Manager{
+		viewers: map[string]map[string]*Viewer{},
+		locks:   map[string]*Lock{},
+	}
+}
+
+// OnChange registers a callback invoked with the new state of a file when its viewers or lock
+// change, expiries included. It must be called before Start.
+func (m *Manager) OnChange(fn func(State)) {
+	m.onChange = fn
+}
+
+// Start drops expired viewers and locks in the background, announcing the files they leave
+func (m *Manager) Start() {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sweep()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background sweep
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// State returns who has a file open and who holds its lock
+func (m *Manager) State(path string) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(path, time.Now())
+	return m.state(path)
+}
+
+// Join records that a session has a file open, or renews it. Clients call it again within
+// ViewerTTL while the file stays open.
+func (m *Manager) Join(path, session, user string) (State, error) {
+	if !sessionRe.MatchString(session) {
+		return State{}, fmt.Errorf("%w: %q", ErrInvalidSession, session)
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	changed := m.expire(path, now)
+	if m.viewers[path] == nil {
+		m.viewers[path] = map[string]*Viewer{}
+	}
+	if v, ok := m.viewers[path][session]; ok {
+		v.Seen = now
+	} else {
+		m.viewers[path][session] = &Viewer{Session: session, User: user, Since: now, Seen: now}
+		changed = true
+	}
+	state := m.state(path)
+	m.mu.Unlock()
+
+	if changed {
+		m.notify(state)
+	}
+	return state, nil
+}
+
+// Leave records that a session closed a file, releasing its lock on it
+func (m *Manager) Leave(path, session string) State {
+	m.mu.Lock()
+	changed := m.expire(path, time.Now())
+	if _, ok := m.viewers[path][session]; ok {
+		delete(m.viewers[path], session)
+		changed = true
+	}
+	if lock := m.locks[path]; lock != nil && lock.Session == session {
+		delete(m.locks, path)
+		changed = true
+	}
+	m.prune(path)
+	state := m.state(path)
+	m.mu.Unlock()
+
+	if changed {
+		m.notify(state)
+	}
+	return state
+}
+
+// Acquire takes the lock of a file for ttl, or renews the session's own lock. It fails with
+// ErrLocked while another session holds it.
+func (m *Manager) Acquire(path, session, user string, ttl time.Duration) (*Lock, error) {
+	if !sessionRe.MatchString(session) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSession, session)
+	}
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	m.expire(path, now)
+	lock := m.locks[path]
+	if lock != nil && lock.Session != session {
+		err := lockedError(lock)
+		m.mu.Unlock()
+		return nil, err
+	}
+	if lock == nil {
+		lock = &Lock{Path: path, Session: session, User: user, Acquired: now}
+		m.locks[path] = lock
+	}
+	lock.Expires = now.Add(ttl)
+	held := *lock
+	state := m.state(path)
+	m.mu.Unlock()
+
+	m.notify(state)
+	return &held, nil
+}
+
+// Release drops a session's lock on a file. Releasing a file that isn't locked does nothing;
+// another session's lock is only released with force.
+func (m *Manager) Release(path, session string, force bool) error {
+	m.mu.Lock()
+	m.expire(path, time.Now())
+	lock := m.locks[path]
+	if lock == nil {
+		m.mu.Unlock()
+		return nil
+	}
+	if lock.Session != session && !force {
+		err := lockedError(lock)
+		m.mu.Unlock()
+		return err
+	}
+	delete(m.locks, path)
+	state := m.state(path)
+	m.mu.Unlock()
+
+	m.notify(state)
+	return nil
+}
+
+// CheckWrite returns ErrLocked when a session other than the given one holds the lock of a file.
+// Locks are advisory: a write that names no session, like one from a script, isn't checked.
+func (m *Manager) CheckWrite(path, session string) error {
+	if session == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(path, time.Now())
+	if lock := m.locks[path]; lock != nil && lock.Session != session {
+		return lockedError(lock)
+	}
+	return nil
+}
+
+// sweep drops every expired viewer and lock, announcing the files that changed
+func (m *Manager) sweep() {
+	m.mu.Lock()
+	now := time.Now()
+	paths := map[string]bool{}
+	for path := range m.viewers {
+		paths[path] = true
+	}
+	for path := range m.locks {
+		paths[path] = true
+	}
+	var changed []State
+	for path := range paths {
+		if m.expire(path, now) {
+			changed = append(changed, m.state(path))
+		}
+	}
+	m.mu.Unlock()
+
+	for _, state := range changed {
+		m.notify(state)
+	}
+}
+
+// expire drops the expired viewers and lock of a file, reporting whether there were any. The
+// caller holds mu.
+func (m *Manager) expire(path string, now time.Time) bool {
+	changed := false
+	for session, v := range m.viewers[path] {
+		if now.Sub(v.Seen) > ViewerTTL {
+			delete(m.viewers[path], session)
+			changed = true
+		}
+	}
+	if lock := m.locks[path]; lock != nil && !now.Before(lock.Expires) {
+		delete(m.locks, path)
+		changed = true
+	}
+	m.prune(path)
+	return changed
+}
+
+// prune forgets a file nobody has open. The caller holds mu.
+func (m *Manager) prune(path string) {
+	if viewers, ok := m.viewers[path]; ok && len(viewers) == 0 {
+		delete(m.viewers, path)
+	}
+}
+
+// state returns a copy of the state of a file. The caller holds mu.
+func (m *Manager) state(path string) State {
+	state := State{Path: path, Viewers: []Viewer{}}
+	for _, v := range m.viewers[path] {
+		state.Viewers = append(state.Viewers, *v)
+	}
+	sort.Slice(state.Viewers, func(i, j int) bool {
+		a, b := state.Viewers[i], state.Viewers[j]
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		return a.Session < b.Session
+	})
+	if lock := m.locks[path]; lock != nil {
+		held := *lock
+		state.Lock = &held
+	}
+	return state
+}
+
+// notify passes a file's new state to the OnChange callback
+func (m *Manager) notify(state State) {
+	if m.onChange != nil {
+		m.onChange(state)
+	}
+}
+
+// lockedError describes who holds a lock
+func lockedError(lock *Lock) error {
+	holder := lock.User
+	if holder == "" {
+		holder = "another session"
+	}
+	return fmt.Errorf("%w by %s until %s", ErrLocked, holder, lock.Expires.Format(time.RFC3339))
+}
@@ -0,0 +1,117 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTopic is returned for topic names clients can't subscribe to
+var ErrUnknownTopic = errors.New("unknown topic")
+
+// Topics of realtime messages
+const (
+	TopicLogs      = "logs"      // Hugo output, one LogEntry per message
+	TopicStatus    = "status"    // Hugo status transitions
+	TopicFiles     = "files"     // Files created, saved, renamed or deleted through the API
+	TopicJobs      = "jobs"      // Start and end of long-running jobs such as site builds
+	TopicPresence  = "presence"  // Who has a file open and who holds its lock
+	TopicComments  = "comments"  // Review comments added, edited, resolved or deleted, and approvals
+	TopicInstances = "instances" // Output and status transitions of the Hugo servers of hugo.instances
+	TopicSystem    = "system"    // Replies to the connection's own commands, always delivered
+)
+
+// Topics lists the topics clients can subscribe to
+var Topics = []string{TopicLogs, TopicStatus, TopicFiles, TopicJobs, TopicPresence, TopicComments, TopicInstances}
+
+// Message is a typed realtime event
+type Message struct {
+	Topic string      `json:"topic"`
+	Type  string      `json:"type"` // e.g. "log", "status", "file.saved", "job.finished"
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// Hub fans out messages published by the server to every subscriber
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers []chan Message
+}
+
+// NewHub creates a new message hub
+func NewHub() *Hub {
+	return &Hub{}
+}
+
+// Subscribe creates a new subscription channel
+func (h *Hub) Subscribe() chan Message {
+	ch := make(chan Message, 100)
+	h.mu.Lock()
+	h.subscribers = append(h.subscribers, ch)
+	h.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe removes a subscription channel
+func (h *Hub) Unsubscribe(ch chan Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, sub := range h.subscribers {
+		if sub == ch {
+			h.subscribers = append(h.subscribers[:i], h.subscribers[i+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
+
+// Publish sends a message to every subscriber, skipping those that aren't keeping up
+func (h *Hub) Publish(topic, typ string, data interface{}) {
+	msg := Message{Topic: topic, Type: typ, Time: time.Now(), Data: data}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// ParseTopics parses a comma-separated topic list. The empty list means every topic.
+func ParseTopics(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return append([]string(nil), Topics...), nil
+	}
+	return CheckTopics(strings.Split(s, ","))
+}
+
+// CheckTopics validates topic names, trimming and deduplicating them
+func CheckTopics(topics []string) ([]string, error) {
+	result := make([]string, 0, len(topics))
+	seen := map[string]bool{}
+	for _, topic := range topics {
+		topic = strings.ToLower(strings.TrimSpace(topic))
+		if !known(topic) {
+			return nil, fmt.Errorf("%w: %q (use %s)", ErrUnknownTopic, topic, strings.Join(Topics, ", "))
+		}
+		if !seen[topic] {
+			seen[topic] = true
+			result = append(result, topic)
+		}
+	}
+	return result, nil
+}
+
+func known(topic string) bool {
+	for _, t := range Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
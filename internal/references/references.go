@@ -0,0 +1,686 @@
+// Package references keeps the refs, links and images of the site's content working when a file or
+// directory is renamed or moved: it finds the ones the move breaks and rewrites them to the new path.
+package references
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// ErrInvalidMove is returned for a move that can't be planned
+var ErrInvalidMove = errors.New("invalid move")
+
+// Change is a reference rewritten in a file
+type Change struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Kind   string `json:"kind"` // ref, link or image
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	offset int
+}
+
+// File is a content file with references to rewrite
+type File struct {
+	Path    string   `json:"path"` // Where the file is once moved, which differs when it's moved too
+	Changes []Change `json:"changes"`
+}
+
+// Plan is the references a move breaks and what they become
+type Plan struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Files []File `json:"files"`
+}
+
+// Manager finds and rewrites references to moved files
+type Manager struct {
+	projectDir string
+}
+
+// NewManager creates a new reference manager
+func NewManager(projectDir string) *Manager {
+	return &Manager{projectDir: projectDir}
+}
+
+// How a ref found its page, which is how the rewritten ref finds it again
+const (
+	refAbsolute = iota + 1 // From the content root, with a leading slash
+	refPageDir             // From the directory of the page
+	refRoot                // From the content root, without a leading slash
+	refName                // By file or bundle name only
+)
+
+// Forms of the URL a link reaches a file at
+const (
+	urlPage    = iota + 1 // Permalink of a page
+	urlContent            // Path of a resource in its content directory
+	urlBundle             // Resource under the permalink of its bundle's page
+	urlStatic             // File of a static directory, at the site root
+)
+
+// target is a file a link URL reaches
+type target struct {
+	path string // Project-relative
+	form int
+	url  string // Before the move, without the baseURL path
+}
+
+// planner resolves references before a move and where they lead after it
+type planner struct {
+	projectDir string
+	config     *site.Config
+	from, to   string
+	dir        bool // A directory moves, with everything below it
+	host       string
+	basePath   string
+
+	pages   map[string]*hugocontent.Page          // By project-relative path
+	keys    map[string]map[string]string          // By language, ref keys to the page they resolve
+	names   map[string]map[string]map[string]bool // By language, page names to the ref keys they name
+	urls    map[string]target                     // URL keys of pages, resources and static files
+	bundles map[string]string                     // Bundle directories to the path of their index file
+	statics []string                              // Project-relative directories published at the site root
+	newURLs map[string]string                     // Pages' permalinks after the move, without the baseURL path
+}
+
+// Plan finds the references to a file or directory, and those of the content it holds, that break
+// when it moves from one project-relative path to another. It runs before the move, as references
+// are resolved against the files where they are.
+func (m *Manager) Plan(from, to string) (*Plan, error) {
+	from, to = cleanPath(from), cleanPath(to)
+	if from == "" || to == "" || from == to {
+		return nil, fmt.Errorf("%w: from and to must be two different paths", ErrInvalidMove)
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return nil, fmt.Errorf("%w: %s can't move into itself", ErrInvalidMove, from)
+	}
+	stat, err := os.Stat(filepath.Join(m.projectDir, filepath.FromSlash(from)))
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := m.planner(from, to, stat.IsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{From: from, To: to, Files: []File{}}
+	paths := make([]string, 0, len(p.pages))
+	for rel := range p.pages {
+		if ext := strings.ToLower(path.Ext(rel)); ext == ".md" || ext == ".markdown" {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, rel := range paths {
+		data, err := os.ReadFile(filepath.Join(m.projectDir, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		text := string(data)
+		page := p.pages[rel]
+		dst, _ := p.moved(rel)
+
+		var changes []Change
+		for _, link := range lint.FindLinks(text) {
+			var rewritten string
+			if link.Kind == lint.LinkRef {
+				rewritten = p.rewriteRef(page, link.Target)
+			} else {
+				rewritten = p.rewriteLink(page, link.Target)
+			}
+			if rewritten != "" && rewritten != link.Target {
+				changes = append(changes, Change{
+					Line:   link.Line,
+					Column: link.Column,
+					Kind:   link.Kind,
+					Old:    link.Target,
+					New:    rewritten,
+					offset: link.Offset,
+				})
+			}
+		}
+		if len(changes) > 0 {
+			plan.Files = append(plan.Files, File{Path: dst, Changes: changes})
+		}
+	}
+	return plan, nil
+}
+
+// Apply rewrites the references of a plan once the move is done and returns the files it changed.
+// A reference whose text changed since the plan was made is left alone.
+func (m *Manager) Apply(plan *Plan) ([]string, error) {
+	updated := []string{}
+	for _, file := range plan.Files {
+		full := filepath.Join(m.projectDir, filepath.FromSlash(file.Path))
+		stat, err := os.Stat(full)
+		if err != nil {
+			return updated, err
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return updated, err
+		}
+
+		text := string(data)
+		changes := append([]Change(nil), file.Changes...)
+		sort.Slice(changes, func(i, j int) bool { return changes[i].offset > changes[j].offset })
+		changed := false
+		for _, c := range changes {
+			end := c.offset + len(c.Old)
+			if end > len(text) || text[c.offset:end] != c.Old {
+				continue
+			}
+			text = text[:c.offset] + c.New + text[end:]
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		if err := atomicfile.WriteFile(full, []byte(text), stat.Mode().Perm()); err != nil {
+			return updated, err
+		}
+		updated = append(updated, file.Path)
+	}
+	return updated, nil
+}
+
+// planner indexes what the site's references can lead to before the move
+func (m *Manager) planner(from, to string, dir bool) (*planner, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	pages, err := contentSite.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		projectDir: m.projectDir,
+		config:     siteCfg,
+		from:       from,
+		to:         to,
+		dir:        dir,
+		pages:      map[string]*hugocontent.Page{},
+		keys:       map[string]map[string]string{},
+		names:      map[string]map[string]map[string]bool{},
+		urls:       map[string]target{},
+		bundles:    map[string]string{},
+		newURLs:    map[string]string{},
+	}
+	if u, err := url.Parse(siteCfg.BaseURL()); err == nil {
+		p.host = strings.ToLower(u.Hostname())
+		p.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+
+	for _, page := range pages {
+		p.pages[page.Path] = page
+		p.addPage(page)
+	}
+
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		if seen[lang.ContentDir] {
+			continue
+		}
+		seen[lang.ContentDir] = true
+		p.walk(lang.ContentDir, func(rel string) {
+			if p.pages[rel] != nil || isContentFile(rel) {
+				return
+			}
+			loc, err := hugocontent.Locate(siteCfg, rel)
+			if err != nil {
+				return
+			}
+			p.addURL("/"+loc.Rel, target{path: rel, form: urlContent, url: "/" + loc.Rel})
+			if bundle, index := p.bundle(rel); index != "" {
+				u := path.Join(p.pages[index].URL, strings.TrimPrefix(rel, bundle+"/"))
+				p.addURL(p.stripBase(u), target{path: rel, form: urlBundle, url: p.stripBase(u)})
+			}
+		})
+	}
+
+	staticDir := siteCfg.String("staticDir")
+	if staticDir == "" {
+		staticDir = "static"
+	}
+	p.statics = []string{cleanPath(staticDir), "assets"}
+	for _, root := range p.statics {
+		p.walk(root, func(rel string) {
+			u := "/" + strings.TrimPrefix(rel, root+"/")
+			p.addURL(u, target{path: rel, form: urlStatic, url: u})
+		})
+	}
+	return p, nil
+}
+
+// addPage records the ref keys, name and URL of a page, as the link checker resolves them
+func (p *planner) addPage(page *hugocontent.Page) {
+	loc := page.Location()
+	lang := loc.Language
+	if p.keys[lang] == nil {
+		p.keys[lang] = map[string]string{}
+		p.names[lang] = map[string]map[string]bool{}
+	}
+	p.addURL(p.stripBase(page.URL), target{path: page.Path, form: urlPage, url: p.stripBase(page.URL)})
+
+	key := refKey(path.Join(loc.Dir, loc.Base))
+	p.keys[lang][refKey(loc.Rel)] = page.Path
+	p.keys[lang][key] = page.Path
+
+	name := loc.Base
+	if loc.Base == "index" || loc.Base == "_index" {
+		key = refKey(loc.Dir)
+		name = path.Base(loc.Dir)
+		p.keys[lang][key] = page.Path
+		p.bundles[path.Dir(page.Path)] = page.Path
+	}
+	if name == "" || name == "." {
+		return
+	}
+	name = strings.ToLower(name)
+	if p.names[lang][name] == nil {
+		p.names[lang][name] = map[string]bool{}
+	}
+	p.names[lang][name][key] = true
+}
+
+// addURL records the file a URL reaches, the first one found winning as pages come first
+func (p *planner) addURL(u string, t target) {
+	if _, ok := p.urls[urlKey(u)]; !ok {
+		p.urls[urlKey(u)] = t
+	}
+}
+
+// walk calls fn with the project-relative path of every visible file below a project directory
+func (p *planner) walk(dir string, fn func(rel string)) {
+	root := filepath.Join(p.projectDir, filepath.FromSlash(dir))
+	filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Missing directories have no files
+		}
+		if strings.HasPrefix(d.Name(), ".") && full != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(p.projectDir, full); err == nil {
+			fn(filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+// bundle returns the innermost bundle directory holding a file and its index file
+func (p *planner) bundle(rel string) (string, string) {
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if index, ok := p.bundles[dir]; ok {
+			return dir, index
+		}
+	}
+	return "", ""
+}
+
+// moved returns where a project-relative path is after the move, and whether it moves
+func (p *planner) moved(rel string) (string, bool) {
+	if rel == p.from {
+		return p.to, true
+	}
+	if p.dir && strings.HasPrefix(rel, p.from+"/") {
+		return p.to + strings.TrimPrefix(rel, p.from), true
+	}
+	return rel, false
+}
+
+// rewriteRef returns what a ref or relref target of a page becomes; "" when it stays as it is
+func (p *planner) rewriteRef(page *hugocontent.Page, ref string) string {
+	ref, fragment := cutFragment(ref)
+	if ref == "" {
+		return ""
+	}
+	lang := page.Location().Language
+	found, how := p.resolveRef(lang, refDirs(page.Location()), ref)
+	if found == "" {
+		return ""
+	}
+	dst, srcMoved := p.moved(page.Path)
+	newPath, targetMoved := p.moved(found)
+	if !targetMoved && !(srcMoved && how == refPageDir) {
+		return ""
+	}
+	loc, err := hugocontent.Locate(p.config, newPath)
+	if err != nil {
+		return "" // Moved out of the content, where no ref can reach it
+	}
+
+	form := refForm(loc, ref)
+	switch how {
+	case refAbsolute:
+		return "/" + form + fragment
+	case refPageDir:
+		if srcLoc, err := hugocontent.Locate(p.config, dst); err == nil {
+			for _, dir := range refDirs(srcLoc) {
+				if dir == "" {
+					return form + fragment
+				}
+				if strings.HasPrefix(form, dir+"/") {
+					return strings.TrimPrefix(form, dir+"/") + fragment
+				}
+			}
+		}
+	case refRoot:
+		return form + fragment
+	case refName:
+		name := loc.Base
+		if name == "index" || name == "_index" {
+			name = path.Base(loc.Dir)
+		}
+		// The name must still name this page only
+		unique := true
+		for key := range p.names[lang][strings.ToLower(name)] {
+			if p.keys[lang][key] != found {
+				unique = false
+			}
+		}
+		if unique {
+			if path.Ext(ref) != "" {
+				name += loc.Ext
+			}
+			return name + fragment
+		}
+	}
+	return "/" + form + fragment
+}
+
+// resolveRef finds the page a ref target leads to, as the link checker and Hugo resolve it, and
+// how it was found; "" when it leads to no page or to several
+func (p *planner) resolveRef(lang string, dirs []string, ref string) (string, int) {
+	keys := p.keys[lang]
+	if strings.HasPrefix(ref, "/") {
+		return keys[refKey(ref)], refAbsolute
+	}
+	for _, dir := range dirs {
+		if found := keys[refKey(path.Join(dir, ref))]; found != "" {
+			return found, refPageDir
+		}
+	}
+	if found := keys[refKey(ref)]; found != "" {
+		return found, refRoot
+	}
+	if strings.Contains(strings.Trim(ref, "/"), "/") {
+		return "", 0
+	}
+	name := strings.ToLower(path.Base(ref))
+	named := p.names[lang][strings.TrimSuffix(name, path.Ext(name))]
+	if len(named) != 1 {
+		return "", 0
+	}
+	for key := range named {
+		return keys[key], refName
+	}
+	return "", 0
+}
+
+// rewriteLink returns what the target of a markdown or HTML link or image of a page becomes; ""
+// when it stays as it is or leads outside the site
+func (p *planner) rewriteLink(page *hugocontent.Page, link string) string {
+	if link == "" || strings.HasPrefix(link, "#") || strings.Contains(link, "{{") {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Path == "" {
+		return ""
+	}
+	prefix := ""
+	if u.Scheme != "" || u.Host != "" {
+		if (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") || p.host == "" || !strings.EqualFold(u.Hostname(), p.host) {
+			return ""
+		}
+		prefix = link[:strings.Index(link, u.Host)+len(u.Host)]
+	}
+	rawPath, suffix := strings.TrimPrefix(link, prefix), ""
+	if i := strings.IndexAny(rawPath, "?#"); i >= 0 {
+		rawPath, suffix = rawPath[:i], rawPath[i:]
+	}
+
+	rewritten := p.rewritePath(page, u.Path)
+	if rewritten == "" {
+		return ""
+	}
+	if rawPath != u.Path {
+		rewritten = (&url.URL{Path: rewritten}).EscapedPath()
+	}
+	if strings.HasSuffix(rewritten, "/") && !strings.HasSuffix(u.Path, "/") && path.Ext(u.Path) == "" && len(rewritten) > 1 {
+		rewritten = strings.TrimSuffix(rewritten, "/") // Written without the trailing slash
+	}
+	return prefix + rewritten + suffix
+}
+
+// rewritePath rewrites the path of a link: a URL of the site, a file relative to the page, or a
+// content file resolved like a ref
+func (p *planner) rewritePath(page *hugocontent.Page, link string) string {
+	dst, srcMoved := p.moved(page.Path)
+
+	if strings.HasPrefix(link, "/") {
+		stripped := p.stripBase(link)
+		t, ok := p.urls[urlKey(stripped)]
+		if !ok {
+			return ""
+		}
+		if _, moved := p.moved(t.path); !moved {
+			return ""
+		}
+		newURL := p.newURL(t)
+		if newURL == "" {
+			return ""
+		}
+		if stripped != link {
+			newURL = p.basePath + newURL
+		}
+		return newURL
+	}
+
+	// Files next to the page, as editors and render hooks resolve them
+	file := path.Join(path.Dir(page.Path), link)
+	if _, err := os.Stat(filepath.Join(p.projectDir, filepath.FromSlash(file))); err == nil {
+		newFile, targetMoved := p.moved(file)
+		if !targetMoved && !srcMoved {
+			return ""
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(path.Dir(dst)), filepath.FromSlash(newFile))
+		if err != nil {
+			return ""
+		}
+		if strings.HasSuffix(link, "/") {
+			rel += "/"
+		}
+		return filepath.ToSlash(rel)
+	}
+
+	// URLs relative to the page's own
+	pageURL := p.stripBase(page.URL)
+	if t, ok := p.urls[urlKey(path.Join(urlDir(pageURL), link))]; ok {
+		_, targetMoved := p.moved(t.path)
+		if !targetMoved && !srcMoved {
+			return ""
+		}
+		newURL := t.url
+		if targetMoved {
+			newURL = p.newURL(t)
+		}
+		if newURL == "" {
+			return ""
+		}
+		newPageURL := pageURL
+		if srcMoved {
+			newPageURL = p.newURL(target{path: page.Path, form: urlPage})
+		}
+		if dir := urlDir(newPageURL); dir != "" && strings.HasPrefix(newURL, dir) && newURL != dir {
+			return strings.TrimPrefix(newURL, dir)
+		}
+		return p.basePath + newURL
+	}
+
+	// Links to content files, which Hugo's render hooks resolve like refs
+	if ext := strings.ToLower(path.Ext(link)); ext == ".md" || ext == ".markdown" {
+		return p.rewriteRef(page, link)
+	}
+	return ""
+}
+
+// newURL returns where a file is published after the move, without the baseURL path; "" when
+// it's no longer published
+func (p *planner) newURL(t target) string {
+	newPath, moved := p.moved(t.path)
+	switch t.form {
+	case urlPage:
+		if u, ok := p.newURLs[t.path]; ok {
+			return u
+		}
+		page := p.pages[t.path]
+		u := ""
+		if !moved {
+			u = p.stripBase(page.URL)
+		} else if loc, err := hugocontent.Locate(p.config, newPath); err == nil {
+			u = p.stripBase(hugocontent.PermalinkFor(p.config, loc, page.FrontMatter, newPath).URL)
+		}
+		p.newURLs[t.path] = u
+		return u
+
+	case urlBundle:
+		for dir := path.Dir(newPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if index := p.newBundle(dir); index != "" {
+				bundleURL := p.newURL(target{path: index, form: urlPage})
+				return path.Join(bundleURL, strings.TrimPrefix(newPath, dir+"/"))
+			}
+		}
+		fallthrough
+
+	case urlContent:
+		if loc, err := hugocontent.Locate(p.config, newPath); err == nil {
+			return "/" + loc.Rel
+		}
+
+	case urlStatic:
+		for _, root := range p.statics {
+			if strings.HasPrefix(newPath, root+"/") {
+				return strings.TrimPrefix(newPath, root)
+			}
+		}
+	}
+	return ""
+}
+
+// newBundle returns the index file, as it was before the move, of the bundle a directory is after it
+func (p *planner) newBundle(dir string) string {
+	for bundle, index := range p.bundles {
+		newDir, _ := p.moved(bundle)
+		newIndex, _ := p.moved(index)
+		if newDir == dir && path.Dir(newIndex) == dir {
+			return index
+		}
+	}
+	return ""
+}
+
+// stripBase removes the path of the baseURL from a URL path
+func (p *planner) stripBase(u string) string {
+	if p.basePath != "" && (u == p.basePath || strings.HasPrefix(u, p.basePath+"/")) {
+		return "/" + strings.TrimPrefix(strings.TrimPrefix(u, p.basePath), "/")
+	}
+	return u
+}
+
+// refDirs returns the content-relative directories relative refs of a page are tried from. A leaf
+// bundle resolves refs like a page of its parent.
+func refDirs(loc *hugocontent.Location) []string {
+	if loc.Base == "index" {
+		parent := path.Dir(loc.Dir)
+		if parent == "." {
+			parent = ""
+		}
+		return []string{parent, loc.Dir}
+	}
+	return []string{loc.Dir}
+}
+
+// refForm returns the content-relative path a ref names a page by, written the way the original
+// ref was: a bundle by its directory, a file with or without its extension
+func refForm(loc *hugocontent.Location, ref string) string {
+	base := strings.TrimSuffix(path.Base(ref), path.Ext(ref))
+	if (loc.Base == "index" || loc.Base == "_index") && base != loc.Base {
+		return loc.Dir
+	}
+	if path.Ext(ref) != "" {
+		return loc.Rel
+	}
+	return path.Join(loc.Dir, loc.Base)
+}
+
+// urlDir returns the directory relative URLs of a page resolve from
+func urlDir(u string) string {
+	if strings.HasSuffix(u, "/") {
+		return u
+	}
+	return path.Dir(u) + "/" // uglyURLs
+}
+
+// cutFragment splits the #fragment, kept with its hash, from a target
+func cutFragment(target string) (string, string) {
+	if i := strings.Index(target, "#"); i >= 0 {
+		return target[:i], target[i:]
+	}
+	return target, ""
+}
+
+// isContentFile reports whether a file is one Hugo renders as a page
+func isContentFile(rel string) bool {
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".md", ".markdown", ".html":
+		return true
+	}
+	return false
+}
+
+// refKey normalizes a content path for ref lookups
+func refKey(p string) string {
+	return strings.Trim(path.Clean("/"+strings.ToLower(p)), "/")
+}
+
+// urlKey normalizes a URL path for lookups, a directory and its index page being the same
+func urlKey(p string) string {
+	p = path.Clean("/" + strings.ToLower(p))
+	if path.Base(p) == "index.html" {
+		p = path.Dir(p)
+	}
+	return p
+}
+
+// cleanPath normalizes a project-relative path to slashes without leading or trailing ones
+func cleanPath(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(strings.TrimSpace(p)))
+	return strings.Trim(p, "/")
+}
@@ -0,0 +1,405 @@
+// Package related suggests pages to link from a page: pages that share its taxonomy terms and
+// whose text is similar to its own, scored with TF-IDF over an index of the site's content.
+package related
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// ErrNotContent is returned for a file Hugo doesn't render as a page
+var ErrNotContent = errors.New("not a content page")
+
+// Limits of the number of suggestions
+const (
+	DefaultLimit = 10
+	MaxLimit     = 50
+)
+
+const (
+	minScore    = 0.05 // Suggestions scoring less are left out
+	titleBoost  = 3    // A word of the title counts as this many of the body
+	maxKeywords = 5    // Shared words listed per suggestion
+)
+
+// Suggestion is a page worth linking from another
+type Suggestion struct {
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	URL      string   `json:"url"`
+	Score    float64  `json:"score"`              // 0 to 1, from shared terms and similar text
+	Terms    []string `json:"terms,omitempty"`    // Taxonomy terms both pages have, as taxonomy:term
+	Keywords []string `json:"keywords,omitempty"` // Distinctive words both pages use
+	Linked   bool     `json:"linked"`             // The page already links to it
+	Link     string   `json:"link"`               // Markdown link to insert, using Hugo's ref shortcode
+}
+
+// Result is the suggestions for a page, best first
+type Result struct {
+	Path        string       `json:"path"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// document is a page in the index
+type document struct {
+	path     string
+	title    string
+	url      string
+	rel      string // Path in its language's content directory, as ref takes it
+	language string
+	words    map[string]float64 // Term frequencies, the title boosted
+	terms    map[string]bool    // Taxonomy terms, as taxonomy:term
+	vector   map[string]float64 // TF-IDF weights, normalized to length 1
+}
+
+// index is the documents of the site with the statistics of their words and terms
+type index struct {
+	stamp string
+	docs  map[string]*document
+	n     map[string]int            // Documents per language
+	df    map[string]map[string]int // Per language, the documents each word is in
+	tf    map[string]map[string]int // Per language, the documents each taxonomy term is on
+}
+
+// Manager keeps the index of the site's content and answers suggestions from it
+type Manager struct {
+	projectDir string
+
+	mu  sync.Mutex
+	idx *index
+}
+
+// NewManager creates a related-content manager for a project
+func NewManager(projectDir string) *Manager {
+	return &Manager{projectDir: projectDir}
+}
+
+// Related returns up to limit pages of the same language to link from the page at relPath. Drafts
+// and section pages aren't suggested, as links to them break or go nowhere specific.
+func (m *Manager) Related(relPath string, limit int) (*Result, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	relPath = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(relPath)), "/")
+	if !contentExts[strings.ToLower(path.Ext(relPath))] {
+		return nil, fmt.Errorf("%w: %s", ErrNotContent, relPath)
+	}
+	page, err := contentSite.Page(relPath)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := m.index(contentSite)
+	if err != nil {
+		return nil, err
+	}
+
+	// A page outside the index, such as a draft, is weighed against it all the same
+	target := idx.docs[page.Path]
+	if target == nil {
+		target = newDocument(page, taxonomyNames(contentSite))
+		target.vector = idx.weigh(target)
+	}
+
+	result := &Result{Path: page.Path, Suggestions: []Suggestion{}}
+	for _, doc := range idx.docs {
+		if doc.path == target.path || doc.language != target.language {
+			continue
+		}
+		score, terms := idx.termScore(target, doc)
+		text, keywords := textScore(target, doc)
+		if len(target.terms) > 0 {
+			score = (score + text) / 2
+		} else {
+			score = text
+		}
+		if score < minScore {
+			continue
+		}
+		result.Suggestions = append(result.Suggestions, Suggestion{
+			Path:     doc.path,
+			Title:    doc.title,
+			URL:      doc.url,
+			Score:    math.Round(score*1000) / 1000,
+			Terms:    terms,
+			Keywords: keywords,
+			Linked:   strings.Contains(page.Body, doc.rel) || doc.url != "/" && strings.Contains(page.Body, doc.url),
+			Link:     fmt.Sprintf(`[%s]({{< ref "%s" >}})`, doc.title, doc.rel),
+		})
+	}
+
+	sort.Slice(result.Suggestions, func(i, j int) bool {
+		a, b := result.Suggestions[i], result.Suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Path < b.Path
+	})
+	if len(result.Suggestions) > limit {
+		result.Suggestions = result.Suggestions[:limit]
+	}
+	return result, nil
+}
+
+// index returns the index of the site, rebuilt when a file in a content directory changed since
+func (m *Manager) index(contentSite *hugocontent.Site) (*index, error) {
+	stamp := fingerprint(contentSite)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.idx != nil && m.idx.stamp == stamp {
+		return m.idx, nil
+	}
+
+	pages, err := contentSite.Pages()
+	if err != nil {
+		return nil, err
+	}
+	taxonomies := taxonomyNames(contentSite)
+	idx := &index{
+		stamp: stamp,
+		docs:  map[string]*document{},
+		n:     map[string]int{},
+		df:    map[string]map[string]int{},
+		tf:    map[string]map[string]int{},
+	}
+	for _, page := range pages {
+		if page.Kind != "page" || page.Draft() {
+			continue
+		}
+		doc := newDocument(page, taxonomies)
+		idx.docs[doc.path] = doc
+		idx.n[doc.language]++
+		if idx.df[doc.language] == nil {
+			idx.df[doc.language] = map[string]int{}
+			idx.tf[doc.language] = map[string]int{}
+		}
+		for word := range doc.words {
+			idx.df[doc.language][word]++
+		}
+		for term := range doc.terms {
+			idx.tf[doc.language][term]++
+		}
+	}
+	for _, doc := range idx.docs {
+		doc.vector = idx.weigh(doc)
+	}
+
+	m.idx = idx
+	return idx, nil
+}
+
+// weigh returns the TF-IDF vector of a document, normalized to length 1. Words on every page of
+// its language weigh nothing.
+func (idx *index) weigh(doc *document) map[string]float64 {
+	n := float64(idx.n[doc.language] + 1)
+	vector := make(map[string]float64, len(doc.words))
+	var norm float64
+	for word, tf := range doc.words {
+		weight := (1 + math.Log(tf)) * math.Log(n/float64(idx.df[doc.language][word]+1))
+		if weight <= 0 {
+			continue
+		}
+		vector[word] = weight
+		norm += weight * weight
+	}
+	norm = math.Sqrt(norm)
+	for word := range vector {
+		vector[word] /= norm
+	}
+	return vector
+}
+
+// termScore is the share of the taxonomy terms of two pages that they have in common, each term
+// weighed by how rare it is, so a shared niche tag counts more than a shared broad category
+func (idx *index) termScore(a, b *document) (float64, []string) {
+	n := float64(idx.n[a.language] + 1)
+	rarity := func(term string) float64 {
+		return math.Log(1 + n/float64(idx.tf[a.language][term]+1))
+	}
+
+	var shared, union float64
+	var terms []string
+	for term := range a.terms {
+		union += rarity(term)
+		if b.terms[term] {
+			shared += rarity(term)
+			terms = append(terms, term)
+		}
+	}
+	for term := range b.terms {
+		if !a.terms[term] {
+			union += rarity(term)
+		}
+	}
+	if union == 0 {
+		return 0, nil
+	}
+	sort.Strings(terms)
+	return shared / union, terms
+}
+
+// textScore is the cosine similarity of the text of two pages, with the words that add most to it
+func textScore(a, b *document) (float64, []string) {
+	type contribution struct {
+		word  string
+		value float64
+	}
+	var score float64
+	var shared []contribution
+	for word, weight := range a.vector {
+		if other, ok := b.vector[word]; ok {
+			score += weight * other
+			shared = append(shared, contribution{word, weight * other})
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].value != shared[j].value {
+			return shared[i].value > shared[j].value
+		}
+		return shared[i].word < shared[j].word
+	})
+
+	keywords := make([]string, 0, maxKeywords)
+	for _, c := range shared[:min(len(shared), maxKeywords)] {
+		keywords = append(keywords, c.word)
+	}
+	return score, keywords
+}
+
+// newDocument indexes the words and taxonomy terms of a page
+func newDocument(page *hugocontent.Page, taxonomies []string) *document {
+	doc := &document{
+		path:     page.Path,
+		title:    page.Title(),
+		url:      page.URL,
+		rel:      page.Path,
+		language: page.Language,
+		words:    map[string]float64{},
+		terms:    map[string]bool{},
+	}
+	if loc := page.Location(); loc != nil {
+		doc.rel = loc.Rel
+	}
+	if doc.title == "" {
+		doc.title = strings.TrimSuffix(path.Base(doc.rel), path.Ext(doc.rel))
+	}
+
+	for _, word := range tokenize(doc.title) {
+		doc.words[word] += titleBoost
+	}
+	for _, word := range tokenize(page.FrontMatter.String("description") + " " + page.Body) {
+		doc.words[word]++
+	}
+	for _, taxonomy := range taxonomies {
+		for _, term := range page.FrontMatter.Strings(taxonomy) {
+			if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+				doc.terms[taxonomy+":"+term] = true
+			}
+		}
+	}
+	return doc
+}
+
+// taxonomyNames returns the front matter keys of the site's taxonomies
+func taxonomyNames(contentSite *hugocontent.Site) []string {
+	taxonomies, err := contentSite.Taxonomies("")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(taxonomies))
+	for _, t := range taxonomies {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// contentExts are the file types Hugo renders as pages
+var contentExts = map[string]bool{".md": true, ".markdown": true, ".html": true}
+
+var (
+	codeBlockRe = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~")
+	shortcodeRe = regexp.MustCompile(`(?s)\{\{[<%].*?[>%]\}\}`)
+	markupRe    = regexp.MustCompile("(?s)<[^>]*>|`[^`]*`|\\]\\([^)]*\\)")
+)
+
+// tokenize splits text into the words that tell pages apart: lowercased, without accents,
+// and without code, shortcodes, markup, link targets, numbers, short words and stop words
+func tokenize(text string) []string {
+	text = codeBlockRe.ReplaceAllString(text, " ")
+	text = shortcodeRe.ReplaceAllString(text, " ")
+	text = markupRe.ReplaceAllString(text, " ")
+
+	var words []string
+	for _, word := range strings.FieldsFunc(slug.Transliterate(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 3 || stopWords[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// stopWords are common English and Spanish words that say nothing about a page's subject
+var stopWords = func() map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(`
+		the and for are but not you all any can had her was one our out has have him his how its
+		may new now old see two way who did get let say she too use from that this with they them
+		then than there their what when where which while will would about after also been before
+		being both could does each into just like more most much must only other over same should
+		some such these those through very were your here because between under again further
+		los las del por con una para como mas pero sus esta este esto estos estas ese esa eso
+		entre cuando muy sin sobre tambien hasta desde donde quien todo todos nos porque cual
+		son fue ser han hay era sido tiene otro otra otros otras mismo ella ellos uno`) {
+		words[word] = true
+	}
+	return words
+}()
+
+// fingerprint changes when a file or directory in a content directory is added, removed or
+// modified, from the number of entries and their latest modification time
+func fingerprint(contentSite *hugocontent.Site) string {
+	var count int
+	var latest int64
+	seen := map[string]bool{}
+	for _, lang := range contentSite.Languages() {
+		root := filepath.Join(contentSite.Dir(), filepath.FromSlash(lang.ContentDir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			count++
+			latest = max(latest, info.ModTime().UnixNano())
+			return nil
+		})
+	}
+	return fmt.Sprintf("%x-%x", count, latest)
+}
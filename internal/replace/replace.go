@@ -0,0 +1,266 @@
+package replace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// ErrInvalidQuery is returned for a search that can't be run
+var ErrInvalidQuery = errors.New("invalid replace query")
+
+// contentExts are the content files searched
+var contentExts = map[string]bool{".md": true, ".markdown": true, ".html": true}
+
+// Query is a find-and-replace across the site's content
+type Query struct {
+	Find       string   `json:"find"`
+	Replace    string   `json:"replace"`
+	Regex      bool     `json:"regex"`      // Find is a Go regexp and Replace may use its groups as $1 or ${name}
+	IgnoreCase bool     `json:"ignoreCase"` // Match regardless of case
+	Folder     string   `json:"folder"`     // Only files below this project directory
+	Files      []string `json:"files"`      // Only these files, e.g. the ones kept from the preview
+}
+
+// File is a content file with matches
+type File struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches"`
+	Diff    string `json:"diff"` // Unified diff of the changed lines, without context
+}
+
+// Failure is a file that couldn't be searched or changed
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Result is the outcome of a find-and-replace
+type Result struct {
+	DryRun  bool      `json:"dryRun"`  // Nothing was written, as the replacement wasn't confirmed
+	Matches int       `json:"matches"` // Across every file
+	Files   []File    `json:"files"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Manager finds and replaces text in content files
+type Manager struct {
+	projectDir string
+}
+
+// NewManager creates a new find-and-replace manager
+func NewManager(projectDir string) *Manager {
+	return &Manager{projectDir: projectDir}
+}
+
+// Run searches the content files for the query and returns the diff of every file it changes.
+// With confirm the changes are written; the search runs again, so files edited since a preview
+// get the replacement of their current content. Once ctx is done the search stops with its error,
+// but a confirmed replacement that started writing finishes, so it isn't left half applied.
+func (m *Manager) Run(ctx context.Context, q Query, confirm bool) (*Result, error) {
+	re, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := m.files(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: !confirm, Files: []File{}, Failed: []Failure{}}
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil && (!confirm || result.Matches == 0) {
+			return nil, err
+		}
+		full := filepath.Join(m.projectDir, filepath.FromSlash(p))
+		data, err := os.ReadFile(full)
+		if err != nil {
+			result.Failed = append(result.Failed, Failure{Path: p, Error: err.Error()})
+			continue
+		}
+
+		text := string(data)
+		var matches [][]int
+		for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+			if match[1] > match[0] { // Empty matches, like those of \b, would only insert text
+				matches = append(matches, match)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		replaced, diff := apply(text, matches, func(match []int) string {
+			if !q.Regex {
+				return q.Replace
+			}
+			return string(re.ExpandString(nil, q.Replace, text, match))
+		})
+		if replaced == text {
+			continue
+		}
+
+		if confirm {
+			info, err := os.Stat(full)
+			if err == nil {
+				err = atomicfile.WriteFile(full, []byte(replaced), info.Mode().Perm())
+			}
+			if err != nil {
+				result.Failed = append(result.Failed, Failure{Path: p, Error: err.Error()})
+				continue
+			}
+		}
+		result.Matches += len(matches)
+		result.Files = append(result.Files, File{
+			Path:    p,
+			Matches: len(matches),
+			Diff:    "--- a/" + p + "\n+++ b/" + p + "\n" + diff,
+		})
+	}
+	return result, nil
+}
+
+// compile returns the regexp of a query; plain text is matched literally
+func (q Query) compile() (*regexp.Regexp, error) {
+	if q.Find == "" {
+		return nil, fmt.Errorf("%w: find is required", ErrInvalidQuery)
+	}
+	pattern := q.Find
+	if !q.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if q.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("%w: the pattern matches empty text", ErrInvalidQuery)
+	}
+	return re, nil
+}
+
+// files returns the project-relative content files the query covers, sorted
+func (m *Manager) files(ctx context.Context, q Query) ([]string, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+
+	folder := strings.Trim(path.Clean("/"+filepath.ToSlash(q.Folder)), "/")
+	only := map[string]bool{}
+	for _, f := range q.Files {
+		only[strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(f)), "/")] = true
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		root := filepath.Join(m.projectDir, filepath.FromSlash(lang.ContentDir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				if os.IsNotExist(err) && p == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if strings.HasPrefix(d.Name(), ".") && p != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !contentExts[strings.ToLower(filepath.Ext(p))] {
+				return nil
+			}
+			rel, _ := filepath.Rel(m.projectDir, p)
+			rel = filepath.ToSlash(rel)
+			if folder != "" && !strings.HasPrefix(rel, folder+"/") {
+				return nil
+			}
+			if len(only) > 0 && !only[rel] {
+				return nil
+			}
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// apply replaces the matches of text and returns the new text with the unified diff of the lines it
+// changes. Matches on the same or overlapping lines share a hunk.
+func apply(text string, matches [][]int, replacement func(match []int) string) (string, string) {
+	type hunk struct {
+		start, end int // Byte range of the whole lines changed, without the final newline
+		matches    [][]int
+	}
+	var hunks []*hunk
+	for _, match := range matches {
+		start := strings.LastIndexByte(text[:match[0]], '\n') + 1
+		end := len(text)
+		if i := strings.IndexByte(text[match[1]:], '\n'); i >= 0 {
+			end = match[1] + i
+		}
+		if last := len(hunks) - 1; last >= 0 && start <= hunks[last].end {
+			hunks[last].end = max(hunks[last].end, end)
+			hunks[last].matches = append(hunks[last].matches, match)
+			continue
+		}
+		hunks = append(hunks, &hunk{start: start, end: end, matches: [][]int{match}})
+	}
+
+	var out, diff strings.Builder
+	pos, delta := 0, 0
+	for _, h := range hunks {
+		var after strings.Builder
+		at := h.start
+		for _, match := range h.matches {
+			after.WriteString(text[at:match[0]])
+			after.WriteString(replacement(match))
+			at = match[1]
+		}
+		after.WriteString(text[at:h.end])
+
+		out.WriteString(text[pos:h.start])
+		out.WriteString(after.String())
+		pos = h.end
+
+		before := strings.Split(text[h.start:h.end], "\n")
+		changed := strings.Split(after.String(), "\n")
+		line := strings.Count(text[:h.start], "\n") + 1
+		fmt.Fprintf(&diff, "@@ -%d,%d +%d,%d @@\n", line, len(before), line+delta, len(changed))
+		for _, l := range before {
+			diff.WriteString("-" + l + "\n")
+		}
+		for _, l := range changed {
+			diff.WriteString("+" + l + "\n")
+		}
+		delta += len(changed) - len(before)
+	}
+	out.WriteString(text[pos:])
+	return out.String(), diff.String()
+}
@@ -0,0 +1,295 @@
+package scripts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// Audit levels
+const (
+	LevelOK      = "ok"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Kinds of audited resources
+const (
+	KindExternal = "external" // <script src> from another host
+	KindInline   = "inline"   // <script> with code
+	KindIframe   = "iframe"   // Third-party embed
+)
+
+// maxExamples is the number of pages listed per audit entry
+const maxExamples = 5
+
+// AuditEntry is a script or embed of the built site, with the pages it appears on
+type AuditEntry struct {
+	Kind     string   `json:"kind"`
+	Src      string   `json:"src,omitempty"`     // URL of external scripts and embeds
+	Snippet  string   `json:"snippet,omitempty"` // Start of inline code
+	Script   string   `json:"script,omitempty"`  // Managed script it comes from
+	Gated    bool     `json:"gated"`             // Waits for consent
+	Level    string   `json:"level"`
+	Message  string   `json:"message"`
+	Pages    int      `json:"pages"`
+	Examples []string `json:"examples"` // Some of the pages, relative to the output directory
+}
+
+// AuditReport lists the scripts and embeds of the built site and whether they respect consent
+type AuditReport struct {
+	OutputDir    string       `json:"outputDir"`
+	PagesChecked int          `json:"pagesChecked"`
+	Errors       int          `json:"errors"`
+	Warnings     int          `json:"warnings"`
+	Entries      []AuditEntry `json:"entries"`
+}
+
+var (
+	scriptRe     = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script\s*>`)
+	iframeRe     = regexp.MustCompile(`(?is)<iframe\b([^>]*)>`)
+	tagAttrRe    = regexp.MustCompile(`(?s)([a-zA-Z_:@][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// trackerHosts are hosts of scripts and embeds that set cookies or track visitors, so they need consent
+var trackerHosts = []string{
+	"googletagmanager.com", "google-analytics.com", "doubleclick.net", "googlesyndication.com",
+	"connect.facebook.net", "facebook.com", "hotjar.com", "snap.licdn.com", "analytics.tiktok.com",
+	"bat.bing.com", "clarity.ms", "cdn.segment.com", "hs-scripts.com", "hs-analytics.net",
+	"mc.yandex.ru", "static.ads-twitter.com", "platform.twitter.com", "youtube.com", "vimeo.com",
+	"disqus.com", "addthis.com", "sharethis.com", "maps.google.com", "google.com/maps",
+}
+
+// trackerCode are fragments of inline tracking snippets
+var trackerCode = []string{
+	"gtag(", "googletagmanager", "google-analytics", "GoogleAnalyticsObject", "fbq(", "_paq.push",
+	"hotjar", "_linkedin_partner_id", "ttq.", "clarity(", "uetq", "ym(", "analytics.load(",
+}
+
+// jsTypes are the type attribute values browsers run as scripts
+var jsTypes = map[string]bool{"": true, "text/javascript": true, "application/javascript": true, "module": true}
+
+// Audit scans the HTML of the built site for scripts and third-party embeds, reporting trackers
+// that load before the visitor consents and third-party code the manager doesn't know about
+func (m *Manager) Audit() (*AuditReport, error) {
+	publishDir, siteHost := "public", ""
+	if siteCfg, err := site.Load(m.projectDir); err == nil {
+		publishDir = siteCfg.PublishDir()
+		if u, err := url.Parse(siteCfg.BaseURL()); err == nil {
+			siteHost = strings.ToLower(u.Hostname())
+		}
+	}
+	root := publishDir
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(m.projectDir, filepath.FromSlash(publishDir))
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s doesn't exist; run hugo first", ErrNotBuilt, publishDir)
+	}
+
+	report := &AuditReport{OutputDir: filepath.ToSlash(publishDir), Entries: []AuditEntry{}}
+	entries := map[string]*AuditEntry{}
+	add := func(key, page string, entry AuditEntry) {
+		existing, ok := entries[key]
+		if !ok {
+			entry.Examples = []string{}
+			existing = &entry
+			entries[key] = existing
+		}
+		existing.Pages++
+		if len(existing.Examples) < maxExamples {
+			existing.Examples = append(existing.Examples, page)
+		}
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".html") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		page := filepath.ToSlash(rel)
+		report.PagesChecked++
+
+		text := string(data)
+		for _, match := range scriptRe.FindAllStringSubmatch(text, -1) {
+			attrs := parseAttrs(match[1])
+			if key, entry, ok := m.auditScript(attrs, match[2], siteHost); ok {
+				add(key, page, entry)
+			}
+		}
+		for _, match := range iframeRe.FindAllStringSubmatch(text, -1) {
+			src := parseAttrs(match[1])["src"]
+			if tracker(src) {
+				add("iframe:"+src, page, AuditEntry{Kind: KindIframe, Src: src, Level: LevelWarning,
+					Message: "Embed sets cookies as soon as the page loads; use a privacy-enhanced URL (e.g. youtube-nocookie.com) or load it after consent"})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		report.Entries = append(report.Entries, *entry)
+		switch entry.Level {
+		case LevelError:
+			report.Errors++
+		case LevelWarning:
+			report.Warnings++
+		}
+	}
+	rank := map[string]int{LevelError: 0, LevelWarning: 1, LevelOK: 2}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if rank[a.Level] != rank[b.Level] {
+			return rank[a.Level] < rank[b.Level]
+		}
+		if a.Pages != b.Pages {
+			return a.Pages > b.Pages
+		}
+		return a.Src+a.Snippet < b.Src+b.Snippet
+	})
+	return report, nil
+}
+
+// auditScript classifies a <script> tag. It returns the key that groups identical scripts across
+// pages, and false for tags that don't run code (JSON-LD, templates, ...).
+func (m *Manager) auditScript(attrs map[string]string, code, siteHost string) (string, AuditEntry, bool) {
+	typ := strings.ToLower(strings.TrimSpace(attrs["type"]))
+	name := attrs["data-hm-script"]
+	gated := typ == "text/plain" && attrs["data-consent"] != ""
+	if !jsTypes[typ] && !gated {
+		return "", AuditEntry{}, false
+	}
+
+	src := attrs["src"]
+	if gated {
+		src = attrs["data-src"]
+	}
+	if src != "" {
+		if host := hostOf(src); host == "" || host == siteHost {
+			return "", AuditEntry{}, false // First-party scripts are the site's own
+		}
+		entry := AuditEntry{Kind: KindExternal, Src: src, Script: name, Gated: gated}
+		if entry.Script == "" {
+			entry.Script = m.managedSrc(src)
+		}
+		m.judge(&entry, tracker(src))
+		return "src:" + src, entry, true
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", AuditEntry{}, false
+	}
+	sum := sha256.Sum256([]byte(code))
+	entry := AuditEntry{Kind: KindInline, Snippet: snippet(code), Script: name, Gated: gated}
+	m.judge(&entry, trackingCode(code))
+	return "inline:" + hex.EncodeToString(sum[:8]), entry, true
+}
+
+// judge sets the level and message of a script entry
+func (m *Manager) judge(entry *AuditEntry, tracking bool) {
+	switch {
+	case entry.Gated:
+		entry.Level, entry.Message = LevelOK, "Loads after consent"
+	case tracking && entry.Script != "" && m.config.Consent.Enabled:
+		entry.Level, entry.Message = LevelError, fmt.Sprintf("Tracking script %s is in the necessary category, so it loads before consent", entry.Script)
+	case tracking && entry.Script != "":
+		entry.Level, entry.Message = LevelError, fmt.Sprintf("Tracking script %s loads without consent; enable the consent banner", entry.Script)
+	case tracking:
+		entry.Level, entry.Message = LevelError, "Tracking code loads before consent; move it to the script manager"
+	case entry.Script != "":
+		entry.Level, entry.Message = LevelOK, "Managed script "+entry.Script
+	case entry.Kind == KindExternal:
+		entry.Level, entry.Message = LevelWarning, "Third-party script outside the script manager"
+	default:
+		entry.Level, entry.Message = LevelOK, "Inline script"
+	}
+}
+
+// managedSrc returns the name of the configured script loading src, or ""
+func (m *Manager) managedSrc(src string) string {
+	for _, script := range m.config.Scripts {
+		if script.Src != "" && script.Src == src {
+			return script.Name
+		}
+	}
+	return ""
+}
+
+// hostOf returns the lower-cased host of an absolute or protocol-relative URL, or "" for relative ones
+func hostOf(src string) string {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// tracker reports whether a URL belongs to a known tracking or cookie-setting host
+func tracker(src string) bool {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, t := range trackerHosts {
+		if strings.Contains(t, "/") {
+			if strings.HasPrefix(host+strings.ToLower(u.Path), t) || strings.Contains(host+strings.ToLower(u.Path), "."+t) {
+				return true
+			}
+			continue
+		}
+		if host == t || strings.HasSuffix(host, "."+t) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingCode reports whether inline code contains a known tracking snippet
+func trackingCode(code string) bool {
+	for _, fragment := range trackerCode {
+		if strings.Contains(code, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet returns the start of inline code on a single line
+func snippet(code string) string {
+	runes := []rune(whitespaceRe.ReplaceAllString(code, " "))
+	if len(runes) > 120 {
+		return string(runes[:120]) + "…"
+	}
+	return string(runes)
+}
+
+// parseAttrs returns the attributes of a tag, with lower-cased names
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, match := range tagAttrRe.FindAllStringSubmatch(s, -1) {
+		attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attrs
+}
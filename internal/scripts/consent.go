@@ -0,0 +1,109 @@
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// consentCookie stores the accepted categories. It's necessary for the banner itself, so it needs no consent.
+const consentCookie = "hm_consent"
+
+// consentTemplate is the banner and loader written to the body partial. The loader swaps inert
+// <script type="text/plain" data-consent="..."> tags for live ones once their category is accepted,
+// and window.hmConsent.open() shows the banner again so visitors can change their choice.
+const consentTemplate = `<div id="hm-consent" class="hm-consent" role="dialog" aria-live="polite" aria-label="Cookie consent" hidden>
+  <p>__MESSAGE____POLICY__</p>
+  <div class="hm-consent-categories">__CHECKBOXES__</div>
+  <div class="hm-consent-actions">
+    <button type="button" data-hm-consent="reject">Reject all</button>
+    <button type="button" data-hm-consent="save">Save choice</button>
+    <button type="button" data-hm-consent="accept">Accept all</button>
+  </div>
+</div>
+<style>
+.hm-consent{position:fixed;left:1rem;right:1rem;bottom:1rem;z-index:9999;max-width:40rem;margin:0 auto;padding:1rem 1.25rem;background:#fff;color:#222;border-radius:.5rem;box-shadow:0 4px 24px rgba(0,0,0,.2);font:14px/1.5 system-ui,sans-serif}
+.hm-consent[hidden]{display:none}
+.hm-consent-categories label{margin-right:1rem}
+.hm-consent-actions{display:flex;gap:.5rem;justify-content:flex-end;margin-top:.75rem}
+.hm-consent button{padding:.4rem .9rem;border:1px solid #888;border-radius:.25rem;background:#fff;cursor:pointer}
+.hm-consent button[data-hm-consent="accept"]{background:#222;color:#fff;border-color:#222}
+</style>
+<script>
+(function () {
+  var NAME = "__COOKIE__", DAYS = __DAYS__, ALL = __CATEGORIES__;
+  var banner = document.getElementById("hm-consent");
+  function read() {
+    var m = document.cookie.match(new RegExp("(?:^|; )" + NAME + "=([^;]*)"));
+    return m ? decodeURIComponent(m[1]).split(",").filter(Boolean) : null;
+  }
+  function enable(accepted) {
+    document.querySelectorAll('script[type="text/plain"][data-consent]').forEach(function (inert) {
+      if (accepted.indexOf(inert.getAttribute("data-consent")) < 0) return;
+      var live = document.createElement("script");
+      Array.prototype.forEach.call(inert.attributes, function (a) {
+        if (a.name !== "type" && a.name !== "data-src") live.setAttribute(a.name, a.value);
+      });
+      if (inert.hasAttribute("data-src")) live.src = inert.getAttribute("data-src");
+      else live.text = inert.text;
+      inert.parentNode.replaceChild(live, inert);
+    });
+  }
+  function choose(accepted) {
+    document.cookie = NAME + "=" + encodeURIComponent(accepted.join(",")) + "; max-age=" + DAYS * 86400 + "; path=/; SameSite=Lax";
+    banner.hidden = true;
+    window.hmConsent.categories = accepted;
+    enable(accepted);
+  }
+  banner.addEventListener("click", function (e) {
+    var action = e.target.getAttribute("data-hm-consent");
+    if (action === "accept") choose(ALL);
+    if (action === "reject") choose([]);
+    if (action === "save") choose(Array.prototype.map.call(banner.querySelectorAll("input:checked"), function (c) { return c.value; }));
+  });
+  var saved = read();
+  window.hmConsent = {
+    categories: saved || [],
+    open: function () {
+      banner.querySelectorAll("input").forEach(function (c) { c.checked = window.hmConsent.categories.indexOf(c.value) >= 0; });
+      banner.hidden = false;
+    }
+  };
+  if (saved) enable(saved);
+  else banner.hidden = false;
+})();
+</script>
+`
+
+// consentBanner renders the consent banner and loader for the configured categories
+func (m *Manager) consentBanner() string {
+	consent := m.config.Consent
+	categories := make([]string, 0, len(consent.Categories))
+	var checkboxes strings.Builder
+	for _, c := range consent.Categories {
+		c = strings.ToLower(c)
+		categories = append(categories, c)
+		fmt.Fprintf(&checkboxes, `<label><input type="checkbox" value="%s"> %s</label>`, html.EscapeString(c), html.EscapeString(strings.ToUpper(c[:1])+c[1:]))
+	}
+	all, _ := json.Marshal(categories)
+
+	policy := ""
+	if consent.PolicyURL != "" {
+		policy = fmt.Sprintf(` <a href="%s">Privacy policy</a>`, html.EscapeString(consent.PolicyURL))
+	}
+	days := consent.Expiry
+	if days <= 0 {
+		days = 180
+	}
+
+	return strings.NewReplacer(
+		"__MESSAGE__", html.EscapeString(consent.Message),
+		"__POLICY__", policy,
+		"__CHECKBOXES__", checkboxes.String(),
+		"__COOKIE__", consentCookie,
+		"__DAYS__", strconv.Itoa(days),
+		"__CATEGORIES__", string(all),
+	).Replace(consentTemplate)
+}
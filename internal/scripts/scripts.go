@@ -0,0 +1,291 @@
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Sentinel errors returned by Manager operations. Use errors.Is to check for them.
+var (
+	ErrInvalidScript = errors.New("invalid script configuration")
+	ErrNotBuilt      = errors.New("site has not been built")
+)
+
+// CategoryNecessary scripts are always loaded, without asking for consent
+const CategoryNecessary = "necessary"
+
+// Partial names, relative to the partials directory
+const (
+	HeadPartial = "scripts-head.html"
+	BodyPartial = "scripts-body.html"
+)
+
+var (
+	// attrNameRe matches valid HTML attribute names
+	attrNameRe = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+	// categoryRe matches consent category names, which end up in the consent cookie
+	categoryRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// generatedHeader marks partials written by the manager
+const generatedHeader = "{{/* Generated by hugo-manager from hugo-manager.yaml. Changes are overwritten. */}}\n"
+
+// Partial is a generated partial and how to include it
+type Partial struct {
+	Path     string `json:"path"`     // Project-relative path
+	Include  string `json:"include"`  // Template call to add to the base layout
+	Where    string `json:"where"`    // Where in the layout the call goes
+	Exists   bool   `json:"exists"`   // The partial has been generated
+	UpToDate bool   `json:"upToDate"` // The partial matches the configuration
+}
+
+// Overview describes the managed scripts and their generated partials
+type Overview struct {
+	Consent  config.ConsentConfig  `json:"consent"`
+	Scripts  []config.ScriptConfig `json:"scripts"`
+	Partials []Partial             `json:"partials"`
+}
+
+// GenerateResult lists the partials written by Generate
+type GenerateResult struct {
+	Written   []string `json:"written"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// Manager generates the partials that load third-party scripts and ask for cookie consent,
+// and audits the built site for scripts loaded outside them
+type Manager struct {
+	projectDir string
+	config     config.ScriptsConfig
+}
+
+// NewManager creates a new scripts manager
+func NewManager(projectDir string, cfg config.ScriptsConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// partialsDir returns the directory of the partials under layouts/partials
+func (m *Manager) partialsDir() string {
+	if m.config.PartialsDir == "" {
+		return "hugo-manager"
+	}
+	return strings.Trim(path.Clean(filepath.ToSlash(m.config.PartialsDir)), "/")
+}
+
+// partialPath returns the project-relative path of a partial
+func (m *Manager) partialPath(name string) string {
+	return path.Join("layouts/partials", m.partialsDir(), name)
+}
+
+// Overview returns the configured scripts and the state of their partials
+func (m *Manager) Overview() (*Overview, error) {
+	rendered, err := m.render()
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &Overview{Consent: m.config.Consent, Scripts: m.config.Scripts}
+	if overview.Scripts == nil {
+		overview.Scripts = []config.ScriptConfig{}
+	}
+	for _, name := range []string{HeadPartial, BodyPartial} {
+		partial := Partial{
+			Path:    m.partialPath(name),
+			Include: fmt.Sprintf(`{{ partial "%s/%s" . }}`, m.partialsDir(), name),
+			Where:   "before </head>",
+		}
+		if name == BodyPartial {
+			partial.Where = "before </body>"
+		}
+		if data, err := os.ReadFile(filepath.Join(m.projectDir, filepath.FromSlash(partial.Path))); err == nil {
+			partial.Exists = true
+			partial.UpToDate = string(data) == rendered[name]
+		}
+		overview.Partials = append(overview.Partials, partial)
+	}
+	return overview, nil
+}
+
+// Generate writes the head and body partials. Scripts outside the necessary category are written
+// inert when consent is enabled, and the body partial's consent banner activates them.
+func (m *Manager) Generate() (*GenerateResult, error) {
+	rendered, err := m.render()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GenerateResult{Written: []string{}, Unchanged: []string{}}
+	for _, name := range []string{HeadPartial, BodyPartial} {
+		rel := m.partialPath(name)
+		full := filepath.Join(m.projectDir, filepath.FromSlash(rel))
+		if data, err := os.ReadFile(full); err == nil && string(data) == rendered[name] {
+			result.Unchanged = append(result.Unchanged, rel)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return nil, err
+		}
+		if err := atomicfile.WriteFile(full, []byte(rendered[name]), 0644); err != nil {
+			return nil, err
+		}
+		result.Written = append(result.Written, rel)
+	}
+	return result, nil
+}
+
+// render returns the content of every partial, keyed by name
+func (m *Manager) render() (map[string]string, error) {
+	var head, body strings.Builder
+	head.WriteString(generatedHeader)
+	body.WriteString(generatedHeader)
+
+	seen := map[string]bool{}
+	for i := range m.config.Scripts {
+		script := &m.config.Scripts[i]
+		if err := m.check(script, i); err != nil {
+			return nil, err
+		}
+		if seen[script.Name] {
+			return nil, fmt.Errorf("%w: duplicate script %s", ErrInvalidScript, script.Name)
+		}
+		seen[script.Name] = true
+
+		out := &head
+		if strings.EqualFold(script.Position, "body") {
+			out = &body
+		}
+		if script.ProductionOnly {
+			out.WriteString("{{ if hugo.IsProduction }}\n")
+		}
+		out.WriteString(m.scriptTag(script))
+		out.WriteString("\n")
+		if script.ProductionOnly {
+			out.WriteString("{{ end }}\n")
+		}
+	}
+
+	if m.config.Consent.Enabled {
+		consent := m.config.Consent
+		if strings.Contains(consent.Message+consent.PolicyURL, "{{") {
+			return nil, fmt.Errorf("%w: consent message and policy_url can't contain {{", ErrInvalidScript)
+		}
+		for _, c := range consent.Categories {
+			if !categoryRe.MatchString(c) {
+				return nil, fmt.Errorf("%w: invalid consent category %q (use letters, digits, '_' and '-')", ErrInvalidScript, c)
+			}
+		}
+		body.WriteString(m.consentBanner())
+	}
+	return map[string]string{HeadPartial: head.String(), BodyPartial: body.String()}, nil
+}
+
+// check validates a script of the configuration
+func (m *Manager) check(script *config.ScriptConfig, i int) error {
+	switch {
+	case script.Name == "":
+		return fmt.Errorf("%w: script %d has no name", ErrInvalidScript, i+1)
+	case script.Src == "" && strings.TrimSpace(script.Inline) == "":
+		return fmt.Errorf("%w: %s needs a src or inline code", ErrInvalidScript, script.Name)
+	case script.Src != "" && script.Inline != "":
+		return fmt.Errorf("%w: %s has both src and inline code", ErrInvalidScript, script.Name)
+	case strings.Contains(script.Inline, "{{") || strings.Contains(strings.ToLower(script.Inline), "</script"):
+		// The code is copied into a template, where both would break it
+		return fmt.Errorf("%w: inline code of %s can't contain {{ or </script>", ErrInvalidScript, script.Name)
+	case script.Position != "" && !strings.EqualFold(script.Position, "head") && !strings.EqualFold(script.Position, "body"):
+		return fmt.Errorf("%w: %s has position %q (use head or body)", ErrInvalidScript, script.Name, script.Position)
+	}
+	for k, v := range script.Attributes {
+		if !attrNameRe.MatchString(k) || strings.Contains(v, "{{") {
+			return fmt.Errorf("%w: %s has an invalid attribute %q", ErrInvalidScript, script.Name, k)
+		}
+	}
+	if script.Src != "" {
+		u, err := url.Parse(script.Src)
+		if err != nil || (u.Scheme != "" && u.Scheme != "https" && u.Scheme != "http") || strings.Contains(script.Src, "{{") {
+			return fmt.Errorf("%w: %s has an invalid src", ErrInvalidScript, script.Name)
+		}
+	}
+	if category := m.category(script); category != CategoryNecessary && m.config.Consent.Enabled && !m.hasCategory(category) {
+		return fmt.Errorf("%w: %s is in category %q, which isn't a consent category", ErrInvalidScript, script.Name, category)
+	}
+	return nil
+}
+
+// category returns the consent category of a script, defaulting to necessary
+func (m *Manager) category(script *config.ScriptConfig) string {
+	if script.Category == "" {
+		return CategoryNecessary
+	}
+	return strings.ToLower(script.Category)
+}
+
+// hasCategory reports whether visitors can consent to category
+func (m *Manager) hasCategory(category string) bool {
+	for _, c := range m.config.Consent.Categories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// gated reports whether a script waits for consent
+func (m *Manager) gated(script *config.ScriptConfig) bool {
+	return m.config.Consent.Enabled && m.category(script) != CategoryNecessary
+}
+
+// scriptTag renders the <script> tag of a script. Gated scripts get type="text/plain" so browsers
+// don't run them, and keep their URL in data-src until the consent banner enables them.
+func (m *Manager) scriptTag(script *config.ScriptConfig) string {
+	attrs := map[string]string{"data-hm-script": script.Name}
+	for k, v := range script.Attributes {
+		attrs[strings.ToLower(k)] = v
+	}
+	if m.gated(script) {
+		attrs["type"] = "text/plain"
+		attrs["data-consent"] = m.category(script)
+		if script.Src != "" {
+			attrs["data-src"] = script.Src
+		}
+	} else if script.Src != "" {
+		attrs["src"] = script.Src
+	}
+
+	names := make([]string, 0, len(attrs))
+	for k := range attrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("<script")
+	if script.Async {
+		b.WriteString(" async")
+	}
+	if script.Defer {
+		b.WriteString(" defer")
+	}
+	for _, k := range names {
+		fmt.Fprintf(&b, ` %s="%s"`, k, html.EscapeString(attrs[k]))
+	}
+	b.WriteString(">")
+	if script.Src == "" {
+		b.WriteString("\n" + strings.TrimSpace(script.Inline) + "\n")
+	}
+	b.WriteString("</script>")
+	return b.String()
+}
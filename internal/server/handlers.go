@@ -3,19 +3,32 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"maps"
 	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
-	"log"
+	"log/slog"
 
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
 	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
 	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+	"github.com/fernandezvara/hugo-manager/internal/references"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // handleIndex serves the main HTML page
@@ -33,10 +46,12 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	// Inject configuration
 	configJSON, _ := json.Marshal(map[string]interface{}{
-		"hugoPort":    s.config.Hugo.Port,
-		"editor":      s.config.Editor,
-		"templates":   s.config.Templates,
+		"hugoPort":    s.hugoMgr.GetPort(),
+		"editor":      s.config().Editor,
+		"templates":   s.config().Templates,
 		"projectName": filepath.Base(s.projectDir),
+		"readOnly":    s.config().Server.ReadOnly,
+		"features":    s.config().Features,
 	})
 
 	html := string(data)
@@ -55,12 +70,10 @@ func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	folder := r.URL.Query().Get("folder")
 
-	var tree interface{}
-	var err error
-
+	var roots []string
+	var allowedTypes map[string]bool
 	switch show {
 	case "images":
-		var roots []string
 		if folder != "" {
 			roots = []string{folder}
 		} else {
@@ -68,28 +81,40 @@ func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
 				roots = append(roots, f.Path)
 			}
 		}
-		allowedTypes := map[string]bool{"image": true}
-		tree, err = s.fileMgr.GetFilteredTree(roots, q, allowedTypes, true)
+		allowedTypes = map[string]bool{"image": true}
 	case "markdown":
-		roots := []string{folder}
+		roots = []string{folder}
 		if folder == "" {
-			roots = s.config.FileTree.ShowDirs
+			roots = s.config().FileTree.ShowDirs
 		}
-		allowedTypes := map[string]bool{"markdown": true}
-		tree, err = s.fileMgr.GetFilteredTree(roots, q, allowedTypes, true)
+		allowedTypes = map[string]bool{"markdown": true}
 	case "all":
-		if folder != "" {
-			tree, err = s.fileMgr.GetTreeForRoots([]string{folder})
-		} else {
-			tree, err = s.fileMgr.GetTree()
+		roots = []string{folder}
+		if folder == "" {
+			roots = s.config().FileTree.ShowDirs
 		}
+		q = "" // The full tree isn't filtered
 	default:
 		s.jsonError(w, http.StatusBadRequest, "Invalid show parameter")
 		return
 	}
 
+	// Polls of an unchanged tree are answered from its fingerprint, without building it
+	fingerprint, err := s.filesFor(r).Fingerprint(roots)
+	if err != nil {
+		s.mapError(w, err, "Failed to get file tree")
+		return
+	}
+	if s.notModified(w, r, fingerprint) {
+		return
+	}
+
+	ctx, span := tracing.Start(r.Context(), "files.GetTree", attribute.String("tree.show", show), attribute.String("tree.folder", folder))
+	tree, err := s.filesFor(r).GetFilteredTree(ctx, roots, q, allowedTypes, show != "all")
+	tracing.End(span, err)
+
 	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to get file tree")
+		s.mapError(w, err, "Failed to get file tree")
 		return
 	}
 
@@ -109,9 +134,11 @@ func (s *Server) handleFileSearch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	results, err := s.fileMgr.SearchImages(folders, query)
+	ctx, span := tracing.Start(r.Context(), "files.SearchImages", attribute.String("search.query", query))
+	results, err := s.fileMgr.SearchImages(ctx, folders, query)
+	tracing.End(span, err)
 	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to search files")
+		s.mapError(w, err, "Failed to search files")
 		return
 	}
 
@@ -124,19 +151,18 @@ func (s *Server) handleFileRaw(w http.ResponseWriter, r *http.Request) {
 		s.jsonError(w, http.StatusBadRequest, "path is required")
 		return
 	}
-
-	if !s.fileMgr.IsValidPath(path) {
-		s.jsonError(w, http.StatusBadRequest, "invalid path")
+	if err := checkAdminPaths(r, path); err != nil {
+		s.mapError(w, err, "Forbidden")
 		return
 	}
 
-	data, err := s.fileMgr.ReadFileBytes(path)
+	f, stat, err := s.filesFor(r).Open(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			s.jsonError(w, http.StatusNotFound, "File not found")
-			return
-		}
-		s.jsonError(w, http.StatusInternalServerError, "Failed to read file")
+		s.mapError(w, err, "Failed to read file")
+		return
+	}
+	defer f.Close()
+	if s.notModified(w, r, fmt.Sprintf("%x-%x", stat.Size(), stat.ModTime().UnixNano())) {
 		return
 	}
 
@@ -144,10 +170,63 @@ func (s *Server) handleFileRaw(w http.ResponseWriter, r *http.Request) {
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
-
 	w.Header().Set("Content-Type", contentType)
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(data)
+
+	// Streamed from disk with range support; large files take longer than the write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	_, span := tracing.Start(r.Context(), "files.ServeContent", attribute.String("file.path", path), attribute.Int64("file.size", stat.Size()))
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+	tracing.End(span, nil)
+}
+
+// handleFileDownload streams a directory as an archive in ?format= (only zip), leaving out hidden files
+func (s *Server) handleFileDownload(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" && format != "zip" {
+		s.jsonError(w, http.StatusBadRequest, "Unsupported format "+format+" (use zip)")
+		return
+	}
+	if err := checkAdminPaths(r, path); err != nil {
+		s.mapError(w, err, "Forbidden")
+		return
+	}
+	// Editors get the directory without the admin paths inside it
+	var exclude func(string) bool
+	if !isAdmin(r) {
+		exclude = isAdminPath
+	}
+
+	// Problems are reported before the archive starts, as an error can't be sent once it streams
+	stat, err := s.fileMgr.Stat(path)
+	if err != nil {
+		s.mapError(w, err, "Failed to read directory")
+		return
+	}
+	if !stat.IsDir() {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotDir, path), "Failed to read directory")
+		return
+	}
+
+	name := filepath.Base(filepath.Clean(path))
+	if name == "." || name == string(filepath.Separator) {
+		name = filepath.Base(s.projectDir)
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".zip"}))
+
+	// Large directories take longer than the write timeout to stream
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	_, span := tracing.Start(r.Context(), "files.WriteZip", attribute.String("file.path", path))
+	err = s.fileMgr.WriteZip(w, path, exclude)
+	tracing.End(span, err)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Directory download failed", "path", path, "error", err)
+	}
 }
 
 // handleFileGet handles GET requests for file content
@@ -164,7 +243,7 @@ func (s *Server) handleFileGet(w http.ResponseWriter, r *http.Request) {
 		ext = ext[1:] // remove leading dot
 	}
 	editable := false
-	for _, e := range s.config.Editor.EditableExtensions {
+	for _, e := range s.config().Editor.EditableExtensions {
 		if e == ext {
 			editable = true
 			break
@@ -175,16 +254,37 @@ func (s *Server) handleFileGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := s.fileMgr.ReadFile(path)
+	// Files too large to edit get their metadata only, without being read
+	stat, err := s.filesFor(r).Stat(path)
 	if err != nil {
-		s.jsonError(w, http.StatusNotFound, "Failed to read file: "+err.Error())
+		s.mapError(w, err, "Failed to read file")
 		return
 	}
-	info, _ := s.fileMgr.GetFileInfo(path)
-	s.jsonResponse(w, map[string]interface{}{
-		"content": content,
-		"info":    info,
-	}, http.StatusOK)
+	info, _ := s.filesFor(r).GetFileInfo(path)
+	if limit := s.maxEditableSize(); limit > 0 && stat.Size() > limit {
+		s.jsonResponse(w, &fileGetResponse{TooLarge: true, Size: stat.Size(), Info: info}, http.StatusOK)
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "files.ReadFileBytes", attribute.String("file.path", path))
+	data, err := s.filesFor(r).ReadFileBytes(path)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to read file")
+		return
+	}
+	resp := &fileGetResponse{Size: int64(len(data)), Info: info.WithStats(data)}
+	// Binary content doesn't survive a JSON string; the UI opens it from /api/files/raw instead
+	if resp.IsBinary = files.IsBinary(data); !resp.IsBinary {
+		resp.Content = string(data)
+	}
+	// Unsaved work the editor left behind, unless the file has since been saved with it
+	if requestWorkspaceOf(r) == nil && !resp.IsBinary {
+		if draft, err := s.autosaveStore.Get(requestUser(r).Username, path, data); err == nil && draft.Content != resp.Content {
+			resp.Draft = draft
+		}
+	}
+	s.jsonResponse(w, resp, http.StatusOK)
 }
 
 // handleFilePut handles PUT requests for file updates/renames
@@ -195,38 +295,126 @@ func (s *Server) handleFilePut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Content string `json:"content"`
-		NewName string `json:"newName"`
-	}
+	var req fileWriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
+	// Locks are on the live files; a workspace has copies of its own
+	if requestWorkspaceOf(r) == nil && !s.checkLock(w, r, path) {
+		return
+	}
 
 	if req.NewName != "" {
 		// Rename operation
-		newPath := filepath.Join(filepath.Dir(path), req.NewName)
-		if err := s.fileMgr.RenameFile(path, newPath); err != nil {
-			if strings.Contains(err.Error(), "already exists") {
-				s.jsonError(w, http.StatusConflict, "Destination already exists")
-			} else if strings.Contains(err.Error(), "does not exist") {
-				s.jsonError(w, http.StatusNotFound, "Source does not exist")
-			} else if strings.Contains(err.Error(), "invalid path") {
-				s.jsonError(w, http.StatusBadRequest, "Invalid path")
-			} else {
-				s.jsonError(w, http.StatusInternalServerError, "Failed to rename: "+err.Error())
+		newPath := filepath.ToSlash(filepath.Join(filepath.Dir(path), req.NewName))
+		if err := checkAdminPaths(r, newPath); err != nil {
+			s.mapError(w, err, "Failed to rename")
+			return
+		}
+		ws := requestWorkspaceOf(r)
+		if ws != nil && (req.AddAlias || req.RewriteReferences) {
+			s.jsonError(w, http.StatusBadRequest, "addAlias and rewriteReferences work on the live tree only")
+			return
+		}
+		// The old URL is only known before the move
+		var contentSite *hugocontent.Site
+		oldURL := ""
+		if req.AddAlias {
+			var ok bool
+			if contentSite, ok = s.contentSite(w); !ok {
+				return
 			}
+			oldURL = s.pageURL(contentSite, path)
+		}
+		// So are the references to it
+		var plan *references.Plan
+		var planErr error
+		if req.RewriteReferences {
+			_, span := tracing.Start(r.Context(), "references.Plan", attribute.String("file.path", path), attribute.String("file.new_path", newPath))
+			plan, planErr = s.refsMgr.Plan(path, newPath)
+			tracing.End(span, planErr)
+		}
+		_, span := tracing.Start(r.Context(), "files.RenameFile", attribute.String("file.path", path), attribute.String("file.new_path", newPath))
+		err := s.filesFor(r).RenameFile(path, newPath)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to rename")
 			return
 		}
-		s.jsonResponse(w, &fileUpdateResponse{Path: path, Status: "renamed"}, http.StatusOK)
+		data := map[string]interface{}{"path": path, "newPath": newPath}
+		if ws != nil {
+			data["workspace"] = ws.name
+		} else {
+			// The comments and drafts of it follow the file; a failure leaves them on the old path
+			if err := s.commentsStore.Move(path, newPath); err != nil {
+				slog.WarnContext(r.Context(), "Failed to move review comments", "path", path, "error", err)
+			}
+			if err := s.autosaveStore.Move(path, newPath); err != nil {
+				slog.WarnContext(r.Context(), "Failed to move unsaved drafts", "path", path, "error", err)
+			}
+		}
+		s.recordActivity(r, "file.renamed", data)
+		s.hub.Publish(realtime.TopicFiles, "file.renamed", data)
+
+		resp := &fileUpdateResponse{Path: path, Status: "renamed"}
+		if planErr != nil {
+			resp.Warnings = append(resp.Warnings, "References not rewritten: "+planErr.Error())
+		} else if plan != nil {
+			// Before the alias, which moves the text of the page the plan points into
+			updated, err := s.refsMgr.Apply(plan)
+			if err != nil {
+				resp.Warnings = append(resp.Warnings, "References not rewritten: "+err.Error())
+			}
+			for _, file := range updated {
+				s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": file, "reason": "rename"}, file, nil))
+			}
+			resp.Updated = updated
+		}
+		if req.AddAlias {
+			// The file has moved either way, so an alias that can't be added is a warning
+			switch newURL := s.pageURL(contentSite, newPath); {
+			case oldURL == "":
+				resp.Warnings = append(resp.Warnings, "No alias: "+path+" is not a page")
+			case oldURL == newURL:
+				resp.Warnings = append(resp.Warnings, "No alias: the URL of the page hasn't changed")
+			default:
+				alias, err := s.addAlias(r, contentSite, newPath, contentSite.AliasFor(oldURL))
+				if err != nil {
+					resp.Warnings = append(resp.Warnings, "No alias: "+err.Error())
+				}
+				resp.Alias = alias
+			}
+		}
+		s.jsonResponse(w, resp, http.StatusOK)
 	} else {
 		// Save operation
-		if err := s.fileMgr.WriteFile(path, req.Content); err != nil {
-			s.jsonError(w, http.StatusInternalServerError, "Failed to save file: "+err.Error())
+		if !s.checkEditableSize(w, path, req.Content) {
+			return
+		}
+		warnings, ok := s.checkFrontMatter(w, r, path, req.Content)
+		if !ok {
+			return
+		}
+		before := s.sizeOf(r, path)
+		_, span := tracing.Start(r.Context(), "files.WriteFile", attribute.String("file.path", path), attribute.Int("file.size", len(req.Content)))
+		err := s.filesFor(r).WriteFile(path, req.Content)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to save file")
 			return
 		}
-		s.jsonResponse(w, &fileUpdateResponse{Path: path, Status: "saved"}, http.StatusOK)
+		if requestWorkspaceOf(r) == nil {
+			if err := s.autosaveStore.Delete(requestUser(r).Username, path); err != nil {
+				slog.WarnContext(r.Context(), "Failed to discard unsaved draft", "path", path, "error", err)
+			}
+		}
+		s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": path}, path, before))
+		resp := &fileUpdateResponse{Path: path, Status: "saved", Warnings: warnings}
+		if info, err := s.filesFor(r).GetFileInfo(path); err == nil {
+			resp.Stats = info.WithStats([]byte(req.Content)).Stats
+		}
+		s.jsonResponse(w, resp, http.StatusOK)
 	}
 }
 
@@ -238,50 +426,65 @@ func (s *Server) handleFilePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Content  string                 `json:"content"`
-		IsDir    bool                   `json:"isDir"`
-		Template string                 `json:"template"`
-		Data     map[string]interface{} `json:"data"`
-	}
+	var req fileCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	// An empty file gets the template its path is bound to
+	template := req.Template
+	if template == "" && req.Content == "" && !req.IsDir {
+		template = s.config().TemplateFor(path)
+	}
+
 	if req.IsDir {
-		if err := s.fileMgr.CreateDir(path); err != nil {
-			if strings.Contains(err.Error(), "already exists") {
-				s.jsonError(w, http.StatusConflict, "Directory already exists")
-			} else if strings.Contains(err.Error(), "invalid path") {
-				s.jsonError(w, http.StatusBadRequest, "Invalid directory path")
-			} else {
-				s.jsonError(w, http.StatusInternalServerError, "Failed to create directory: "+err.Error())
-			}
+		_, span := tracing.Start(r.Context(), "files.CreateDir", attribute.String("file.path", path))
+		err := s.filesFor(r).CreateDir(path)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to create directory")
 			return
 		}
-	} else if req.Template != "" {
-		// Create from template
-		if err := s.fileMgr.CreateFileFromTemplate(path, req.Template, req.Data, s.config.Templates); err != nil {
-			if err.Error() == "file already exists: "+path {
-				s.jsonError(w, http.StatusConflict, "File already exists")
-				return
-			}
-			s.jsonError(w, http.StatusInternalServerError, "Failed to create file from template: "+err.Error())
+	} else if template != "" {
+		// Create from template, filling the fields the request leaves out with the template's defaults
+		data := s.config().Templates.Defaults(template, filepath.ToSlash(filepath.Dir(path)), time.Now())
+		for key, value := range req.Data {
+			data[key] = value
+		}
+		templates := s.config().Templates
+		if fields, ok := templates[template]; ok && req.Slug != "" {
+			// The slug is written even when the template has no slug field
+			fields = maps.Clone(fields)
+			fields["slug"] = config.TemplateField{Type: "text"}
+			templates = maps.Clone(templates)
+			templates[template] = fields
+			data["slug"] = slug.Make(req.Slug)
+		}
+		body, err := s.config().RenderBody(template, path, data, time.Now())
+		if err != nil {
+			s.jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		_, span := tracing.Start(r.Context(), "files.CreateFileFromTemplate", attribute.String("file.path", path), attribute.String("file.template", template))
+		err = s.filesFor(r).CreateFileFromTemplate(path, template, data, body, templates)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to create file from template")
 			return
 		}
 	} else {
 		// Regular file creation
-		if err := s.fileMgr.CreateFile(path, req.Content); err != nil {
-			if err.Error() == "file already exists: "+path {
-				s.jsonError(w, http.StatusConflict, "File already exists")
-				return
-			}
-			s.jsonError(w, http.StatusInternalServerError, "Failed to create file: "+err.Error())
+		_, span := tracing.Start(r.Context(), "files.CreateFile", attribute.String("file.path", path))
+		err := s.filesFor(r).CreateFile(path, req.Content)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to create file")
 			return
 		}
 	}
-	s.jsonResponse(w, &fileCreateResponse{Path: path, Status: "created"}, http.StatusOK)
+	s.fileChanged(r, webhooks.EventFileCreated, s.withSizes(r, map[string]interface{}{"path": path, "isDir": req.IsDir}, path, nil))
+	s.jsonResponse(w, &fileCreateResponse{Path: path, Status: "created", Template: template}, http.StatusOK)
 }
 
 // handleFileDelete handles DELETE requests for file/directory deletion
@@ -292,24 +495,33 @@ func (s *Server) handleFileDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.fileMgr.DeleteFile(path); err != nil {
-		if strings.Contains(err.Error(), "does not exist") {
-			s.jsonError(w, http.StatusNotFound, "File or directory does not exist")
-		} else if strings.Contains(err.Error(), "not empty") {
-			s.jsonError(w, http.StatusConflict, "Directory not empty")
-		} else if strings.Contains(err.Error(), "invalid path") {
-			s.jsonError(w, http.StatusBadRequest, "Invalid path")
-		} else {
-			s.jsonError(w, http.StatusInternalServerError, "Failed to delete: "+err.Error())
-		}
+	// Locks are on the live files; a workspace has copies of its own
+	if requestWorkspaceOf(r) == nil && !s.checkLock(w, r, path) {
+		return
+	}
+
+	before := s.sizeOf(r, path)
+	_, span := tracing.Start(r.Context(), "files.DeleteFile", attribute.String("file.path", path))
+	err := s.filesFor(r).DeleteFile(path)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to delete")
 		return
 	}
+	if requestWorkspaceOf(r) == nil {
+		if err := s.autosaveStore.DeleteAll(path); err != nil {
+			slog.WarnContext(r.Context(), "Failed to discard unsaved drafts", "path", path, "error", err)
+		}
+	}
+	s.fileChanged(r, webhooks.EventFileDeleted, s.withSizes(r, map[string]interface{}{"path": path}, path, before))
 	s.jsonResponse(w, &fileDeleteResponse{Path: path, Status: "deleted"}, http.StatusOK)
 }
 
 // handleShortcodes returns all detected shortcodes
 func (s *Server) handleShortcodes(w http.ResponseWriter, r *http.Request) {
+	_, span := tracing.Start(r.Context(), "shortcodes.DetectAll")
 	shortcodes, err := s.shortcodeMgr.DetectAll()
+	tracing.End(span, err)
 	if err != nil {
 		s.jsonError(w, http.StatusInternalServerError, "Failed to detect shortcodes")
 		return
@@ -328,7 +540,78 @@ func (s *Server) handleShortcode(w http.ResponseWriter, r *http.Request) {
 
 	sc, err := s.shortcodeMgr.GetShortcode(name)
 	if err != nil {
-		s.jsonError(w, http.StatusNotFound, err.Error())
+		s.mapError(w, err, "Failed to detect shortcodes")
+		return
+	}
+
+	s.jsonResponse(w, sc, http.StatusOK)
+}
+
+// handleShortcodeTemplate returns the raw template source of a shortcode
+func (s *Server) handleShortcodeTemplate(w http.ResponseWriter, r *http.Request) {
+	name := s.getURLParam(r, "name")
+	if name == "" {
+		s.jsonError(w, http.StatusBadRequest, "Shortcode name required")
+		return
+	}
+
+	content, err := s.shortcodeMgr.ReadTemplate(name)
+	if err != nil {
+		s.mapError(w, err, "Failed to read shortcode template")
+		return
+	}
+
+	s.jsonResponse(w, &shortcodeTemplateResponse{Name: name, Content: content}, http.StatusOK)
+}
+
+// handleShortcodeCreate scaffolds a new shortcode template
+func (s *Server) handleShortcodeCreate(w http.ResponseWriter, r *http.Request) {
+	name := s.getURLParam(r, "name")
+	if name == "" {
+		s.jsonError(w, http.StatusBadRequest, "Shortcode name required")
+		return
+	}
+
+	var req shortcodeCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "shortcodes.Create", attribute.String("shortcode.name", name))
+	sc, err := s.shortcodeMgr.Create(name, req.Content, req.ScaffoldOptions)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to create shortcode")
+		return
+	}
+
+	s.jsonResponse(w, sc, http.StatusCreated)
+}
+
+// handleShortcodeUpdate replaces the template of an existing shortcode
+func (s *Server) handleShortcodeUpdate(w http.ResponseWriter, r *http.Request) {
+	name := s.getURLParam(r, "name")
+	if name == "" {
+		s.jsonError(w, http.StatusBadRequest, "Shortcode name required")
+		return
+	}
+
+	var req shortcodeUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		s.jsonError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "shortcodes.Update", attribute.String("shortcode.name", name))
+	sc, err := s.shortcodeMgr.Update(name, req.Content)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to update shortcode")
 		return
 	}
 
@@ -356,11 +639,25 @@ func (s *Server) handleImageUpload(w http.ResponseWriter, r *http.Request) {
 		filename = header.Filename
 	}
 
+	folder := filepath.ToSlash(strings.TrimSpace(r.FormValue("folder")))
+	if folder != "" && !s.fileMgr.IsValidPath(folder) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrInvalidPath, folder), "Failed to process image")
+		return
+	}
+
 	// Create processing options
 	opts := images.UploadOptions{
-		Folder:   r.FormValue("folder"),
-		Filename: filename,
-		Quality:  85,
+		Folder:     folder,
+		Filename:   filename,
+		PresetName: r.FormValue("preset"),
+		Format:     r.FormValue("format"),
+		Aspect:     r.FormValue("aspect"),
+		Alt:        r.FormValue("alt"),
+		Options: snippets.Options{
+			Lang:    r.FormValue("lang"),
+			Dir:     r.FormValue("dir"),
+			Caption: r.FormValue("caption"),
+		},
 	}
 
 	if quality := r.FormValue("quality"); quality != "" {
@@ -376,12 +673,25 @@ func (s *Server) handleImageUpload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := s.imageMgr.Process(file, opts)
+	if keep := r.FormValue("keepOriginal"); keep != "" {
+		if k, err := strconv.ParseBool(keep); err == nil {
+			opts.KeepOriginal = &k
+		}
+	}
+
+	ctx, span := tracing.Start(r.Context(), "images.Process", attribute.String("image.folder", opts.Folder), attribute.Int64("image.upload_size", header.Size))
+	result, err := s.imageMgr.Process(ctx, file, opts)
+	tracing.End(span, err)
 	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to process image: "+err.Error())
+		s.mapError(w, err, "Failed to process image")
 		return
 	}
 
+	s.fileChanged(r, webhooks.EventImageUploaded, map[string]interface{}{
+		"folder":   opts.Folder,
+		"original": result.Original,
+		"variants": len(result.Variants),
+	})
 	s.jsonResponse(w, result, http.StatusOK)
 }
 
@@ -400,7 +710,7 @@ func (s *Server) handleImageProcessed(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.imageMgr.BuildResultFromProcessedVariants(path)
 	if err != nil {
-		s.jsonError(w, http.StatusBadRequest, err.Error())
+		s.mapError(w, err, "Failed to read processed variants")
 		return
 	}
 
@@ -418,7 +728,48 @@ func (s *Server) handleImagePresets(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, presets, http.StatusOK)
 }
 
-// handleFileUpload handles generic file uploads
+// handleImageDuplicates groups the identical and near-identical images below ?folder=, or below
+// static, assets and content, within ?distance= bits of each other
+func (s *Server) handleImageDuplicates(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	if folder != "" {
+		if !s.fileMgr.IsValidPath(folder) {
+			s.mapError(w, files.ErrInvalidPath, folder)
+			return
+		}
+		stat, err := os.Stat(filepath.Join(s.projectDir, filepath.FromSlash(folder)))
+		if err != nil {
+			s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotFound, folder), folder)
+			return
+		}
+		if !stat.IsDir() {
+			s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotDir, folder), folder)
+			return
+		}
+	}
+	distance := 0
+	if v := r.URL.Query().Get("distance"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 {
+			s.jsonError(w, http.StatusBadRequest, "distance must be a number of bits")
+			return
+		}
+		distance = d
+	}
+
+	ctx, span := tracing.Start(r.Context(), "images.Duplicates", attribute.String("image.folder", folder))
+	report, err := s.imageMgr.Duplicates(ctx, folder, distance)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to find duplicates")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleFileUpload handles generic file uploads. A request can carry several files as repeated
+// file fields; a path field per file, in the same order, places it below folder keeping the
+// structure of a dropped folder. Each file is saved on its own, so one failing doesn't stop the rest.
 func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form (max 50MB)
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
@@ -426,13 +777,12 @@ func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get file from form
-	file, header, err := r.FormFile("file")
-	if err != nil {
+	// Get files from form
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
 		s.jsonError(w, http.StatusBadRequest, "No file provided")
 		return
 	}
-	defer file.Close()
 
 	// Get folder from form (required)
 	folder := r.FormValue("folder")
@@ -441,42 +791,98 @@ func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get filename from form or use original filename
-	filename := r.FormValue("filename")
-	if filename == "" {
-		filename = header.Filename
+	// Relative paths of the files, e.g. from a dragged folder
+	paths := r.MultipartForm.Value["path"]
+	if len(paths) > 0 && len(paths) != len(headers) {
+		s.jsonError(w, http.StatusBadRequest, fmt.Sprintf("Got %d paths for %d files; send one path per file", len(paths), len(headers)))
+		return
 	}
 
-	// Create full file path
-	targetPath := filepath.Join(s.projectDir, folder, filename)
+	results := make([]fileUploadResult, 0, len(headers))
+	var firstErr error
+	for i, header := range headers {
+		// Get filename from form or use original filename
+		name := header.Filename
+		if len(paths) > 0 {
+			name = paths[i]
+		} else if filename := r.FormValue("filename"); filename != "" && len(headers) == 1 {
+			name = filename
+		}
+		rel := filepath.ToSlash(filepath.Join(folder, name))
 
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to create directory")
+		result := fileUploadResult{Filename: filepath.Base(rel), Path: rel, Size: header.Size}
+		before := s.sizeOf(r, rel)
+		err := checkAdminPaths(r, rel)
+		if err == nil {
+			err = s.saveUploadedFile(header, rel)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			s.fileChanged(r, webhooks.EventFileCreated, s.withSizes(r, map[string]interface{}{"path": rel}, rel, before))
+		}
+		results = append(results, result)
+	}
+
+	uploaded := 0
+	for _, result := range results {
+		if result.Error == "" {
+			uploaded++
+		}
+	}
+	if uploaded == 0 {
+		s.mapError(w, firstErr, "Failed to save file")
 		return
 	}
 
-	// Create destination file
-	dst, err := os.Create(targetPath)
+	// The first file is also described at the top level, as single file uploads always were
+	resp := &fileUploadResponse{
+		Message:  "File uploaded successfully",
+		Filename: results[0].Filename,
+		Path:     results[0].Path,
+		Size:     results[0].Size,
+		Files:    results,
+	}
+	status := http.StatusOK
+	if len(results) > 1 {
+		resp.Message = fmt.Sprintf("%d of %d files uploaded", uploaded, len(results))
+	}
+	if uploaded < len(results) {
+		status = http.StatusMultiStatus
+	}
+	s.jsonResponse(w, resp, status)
+}
+
+// saveUploadedFile writes an uploaded file to a project-relative path, creating its directories
+func (s *Server) saveUploadedFile(header *multipart.FileHeader, rel string) error {
+	if !s.fileMgr.IsValidPath(rel) {
+		return fmt.Errorf("%w: %s", files.ErrInvalidPath, rel)
+	}
+	file, err := header.Open()
 	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to create file")
-		return
+		return err
+	}
+	defer file.Close()
+	if err := s.uploadsMgr.CheckType(rel, file); err != nil {
+		return err
 	}
-	defer dst.Close()
 
-	// Copy file content
-	if _, err := io.Copy(dst, file); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to save file")
-		return
+	// Create full file path
+	targetPath := filepath.Join(s.projectDir, filepath.FromSlash(rel))
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Return success response
-	s.jsonResponse(w, map[string]interface{}{
-		"message":  "File uploaded successfully",
-		"filename": filename,
-		"path":     filepath.Join(folder, filename),
-		"size":     header.Size,
-	}, http.StatusOK)
+	// Write the file whole, so a failed upload doesn't leave part of it over an existing file
+	if _, err := atomicfile.WriteFrom(targetPath, file, 0644); err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	return nil
 }
 
 // handleFileCopy copies an existing file
@@ -511,41 +917,38 @@ func (s *Server) handleFileCopy(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Create full paths
-	fullSourcePath := filepath.Join(s.projectDir, sourcePath)
-	fullTargetPath := filepath.Join(s.projectDir, targetFolder, targetFilename)
-
-	// Check if source file exists
-	if _, err := os.Stat(fullSourcePath); os.IsNotExist(err) {
-		s.jsonError(w, http.StatusBadRequest, "Source file not found")
+	if err := checkAdminPaths(r, filepath.Join(targetFolder, targetFilename)); err != nil {
+		s.mapError(w, err, "Failed to copy file")
 		return
 	}
 
-	// Copy file
-	source, err := os.Open(fullSourcePath)
-	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to open source file")
-		return
+	// Both paths go through the files manager, which rejects traversal and links the symlink
+	// policy doesn't allow
+	target := filepath.ToSlash(filepath.Join(targetFolder, targetFilename))
+	for _, p := range []string{sourcePath, target} {
+		if !s.fileMgr.IsValidPath(p) {
+			s.mapError(w, fmt.Errorf("%w: %s", files.ErrInvalidPath, p), "Failed to copy file")
+			return
+		}
 	}
-	defer source.Close()
-
-	destination, err := os.Create(fullTargetPath)
-	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to create destination file")
+	if stat, err := s.fileMgr.Stat(sourcePath); err != nil || stat.IsDir() {
+		s.jsonError(w, http.StatusBadRequest, "Source file not found")
 		return
 	}
-	defer destination.Close()
 
-	if _, err := io.Copy(destination, source); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, "Failed to copy file")
+	before := s.sizeOf(r, target)
+	if err := s.fileMgr.CopyFile(sourcePath, target); err != nil {
+		s.mapError(w, err, "Failed to copy file")
 		return
 	}
+	s.recordActivity(r, "file.copied", s.withSizes(r, map[string]interface{}{"path": target, "source": sourcePath}, target, before))
+	s.hub.Publish(realtime.TopicFiles, "file.copied", map[string]interface{}{"path": sourcePath, "newPath": target})
 
 	// Return success response
-	s.jsonResponse(w, map[string]interface{}{
-		"message": "File copied successfully",
-		"source":  sourcePath,
-		"target":  filepath.Join(targetFolder, targetFilename),
+	s.jsonResponse(w, &fileCopyResponse{
+		Message: "File copied successfully",
+		Source:  sourcePath,
+		Target:  target,
 	}, http.StatusOK)
 }
 
@@ -577,41 +980,49 @@ func (s *Server) handleImageProcess(w http.ResponseWriter, r *http.Request) {
 		filename = filepath.Base(sourcePath)
 	}
 
-	// Get processing options
-	quality := r.FormValue("quality")
-	if quality == "" {
-		quality = "85"
+	// Get processing options; without them the preset's, or the configured defaults, are used
+	quality := 0
+	if q := r.FormValue("quality"); q != "" {
+		quality = parseInt(q)
 	}
-
-	preset := r.FormValue("preset")
-	if preset == "" {
-		preset = "Full responsive"
-	}
-
 	widths := r.FormValue("widths")
 
-	// Create full source path
-	fullSourcePath := filepath.Join(s.projectDir, sourcePath)
-
-	// Check if source file exists
-	if _, err := os.Stat(fullSourcePath); os.IsNotExist(err) {
+	// The source and where the results go must be in the project, as the symlink policy allows
+	for _, p := range []string{sourcePath, targetFolder, filepath.ToSlash(filepath.Join(targetFolder, filename))} {
+		if !s.fileMgr.IsValidPath(p) {
+			s.mapError(w, fmt.Errorf("%w: %s", files.ErrInvalidPath, p), "Failed to process image")
+			return
+		}
+	}
+	if stat, err := s.fileMgr.Stat(sourcePath); err != nil || stat.IsDir() {
 		s.jsonError(w, http.StatusBadRequest, "Source image file not found")
 		return
 	}
+	fullSourcePath := filepath.Join(s.projectDir, filepath.FromSlash(sourcePath))
 
 	// Create processing options similar to upload but with existing file
 	opts := images.UploadOptions{
 		Folder:     targetFolder,
 		Filename:   filename,
-		Quality:    parseInt(quality),
-		PresetName: preset,
+		Quality:    quality,
+		PresetName: r.FormValue("preset"),
+		Format:     r.FormValue("format"),
 		Widths:     parseWidths(widths),
+		Aspect:     r.FormValue("aspect"),
+		Alt:        r.FormValue("alt"),
+		Options: snippets.Options{
+			Lang:    r.FormValue("lang"),
+			Dir:     r.FormValue("dir"),
+			Caption: r.FormValue("caption"),
+		},
 	}
 
 	// Process the existing image
-	result, err := s.imageMgr.ProcessExistingImage(fullSourcePath, opts)
+	ctx, span := tracing.Start(r.Context(), "images.ProcessExistingImage", attribute.String("image.source", sourcePath), attribute.String("image.folder", opts.Folder))
+	result, err := s.imageMgr.ProcessExistingImage(ctx, fullSourcePath, opts)
+	tracing.End(span, err)
 	if err != nil {
-		s.jsonError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to process image: %v", err))
+		s.mapError(w, err, "Failed to process image")
 		return
 	}
 
@@ -641,48 +1052,176 @@ func parseWidths(s string) []int {
 	return widths
 }
 
-// handleHugoStatus returns Hugo server status
+// handleHugoStatus returns the status of the Hugo server of ?instance=, the main one by default
 func (s *Server) handleHugoStatus(w http.ResponseWriter, r *http.Request) {
-	status, msg := s.hugoMgr.GetStatus()
-	s.jsonResponse(w, map[string]interface{}{
-		"status":  status,
-		"message": msg,
-		"port":    s.hugoMgr.GetPort(),
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	status, msg := m.GetStatus()
+	s.jsonResponse(w, &hugoStatusResponse{
+		Status:         status,
+		Message:        msg,
+		Port:           m.GetPort(),
+		ConfiguredPort: m.GetConfiguredPort(),
+		Logs:           m.GetLogUsage(),
+		Watchdog:       m.GetWatchdogStatus(),
+		Options:        m.GetOptions(),
 	}, http.StatusOK)
 }
 
-// handleHugoStart starts the Hugo server
+// handleHugoOptions changes the draft, future and expired content flags, restarting Hugo to apply them
+func (s *Server) handleHugoOptions(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	var req hugo.OptionsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "hugo.SetOptions", attribute.String("hugo.instance", m.Name()))
+	options, restarted, err := m.SetOptions(req)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to change Hugo options")
+		return
+	}
+	s.jsonResponse(w, &hugoOptionsResponse{Options: options, Restarted: restarted}, http.StatusOK)
+}
+
+// handleHugoProfiles returns the configured environment and baseURL profiles
+func (s *Server) handleHugoProfiles(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.hugoMgr.Profiles(), http.StatusOK)
+}
+
+// handleHugoBuild builds the site with a profile, as a deploy would, and records its output
+func (s *Server) handleHugoBuild(w http.ResponseWriter, r *http.Request) {
+	var req hugoBuildRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	job := map[string]interface{}{"id": fmt.Sprintf("build-%d", time.Now().UnixNano()), "kind": "build", "profile": req.Profile}
+	s.hub.Publish(realtime.TopicJobs, "job.started", job)
+
+	_, span := tracing.Start(r.Context(), "hugo.Build", attribute.String("hugo.profile", req.Profile))
+	result, err := s.hugoMgr.Build(req.Profile)
+	tracing.End(span, err)
+	if err != nil {
+		job["error"] = err.Error()
+		s.hub.Publish(realtime.TopicJobs, "job.failed", job)
+		s.mapError(w, err, "Failed to build site")
+		return
+	}
+	response := hugoBuildResponse{BuildResult: result}
+	if result.Success {
+		if manifest, err := s.artifactsMgr.Record(req.Profile); err != nil {
+			slog.WarnContext(r.Context(), "Failed to record build artifacts", "error", err)
+		} else {
+			response.Checks = manifest.Checks
+			if manifest.Checks.Failed() {
+				// The output is broken past the limits of build_checks, so it isn't fit to deploy
+				result.Success = false
+				job["checks"] = manifest.Checks.Failures
+			}
+		}
+	}
+	job["success"], job["durationMs"] = result.Success, result.Duration
+	s.hub.Publish(realtime.TopicJobs, "job.finished", job)
+
+	event := webhooks.EventBuildSucceeded
+	if !result.Success {
+		event = webhooks.EventBuildFailed
+	}
+	s.webhooks.Dispatch(event, map[string]interface{}{"profile": req.Profile, "durationMs": result.Duration})
+	s.jsonResponse(w, response, http.StatusOK)
+}
+
+// handleHugoErrors returns the errors of the current build, for a problems panel, only those in
+// the file or directory of ?path= when it's set
+func (s *Server) handleHugoErrors(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	status, _ := m.GetStatus()
+	buildErrors := m.BuildErrors()
+	if p := r.URL.Query().Get("path"); p != "" {
+		p = strings.Trim(filepath.ToSlash(filepath.Clean("/"+p)), "/")
+		kept := buildErrors[:0]
+		for _, e := range buildErrors {
+			if e.File == p || strings.HasPrefix(e.File, p+"/") {
+				kept = append(kept, e)
+			}
+		}
+		buildErrors = kept
+	}
+	s.jsonResponse(w, &hugoErrorsResponse{Status: status, Count: len(buildErrors), Errors: buildErrors}, http.StatusOK)
+}
+
+// handleHugoStart starts the Hugo server of ?instance=
 func (s *Server) handleHugoStart(w http.ResponseWriter, r *http.Request) {
-	if err := s.hugoMgr.Start(); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, err.Error())
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	_, span := tracing.Start(r.Context(), "hugo.Start", attribute.String("hugo.instance", m.Name()))
+	err := m.Start()
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to start Hugo")
 		return
 	}
 
 	s.jsonResponse(w, &successResponse{Status: "starting"}, http.StatusOK)
 }
 
-// handleHugoStop stops the Hugo server
+// handleHugoStop stops the Hugo server of ?instance=
 func (s *Server) handleHugoStop(w http.ResponseWriter, r *http.Request) {
-	if err := s.hugoMgr.Stop(); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, err.Error())
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	_, span := tracing.Start(r.Context(), "hugo.Stop", attribute.String("hugo.instance", m.Name()))
+	err := m.Stop()
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to stop Hugo")
 		return
 	}
 
 	s.jsonResponse(w, &successResponse{Status: "stopped"}, http.StatusOK)
 }
 
-// handleHugoRestart restarts the Hugo server
+// handleHugoRestart restarts the Hugo server of ?instance=
 func (s *Server) handleHugoRestart(w http.ResponseWriter, r *http.Request) {
-	if err := s.hugoMgr.Restart(); err != nil {
-		s.jsonError(w, http.StatusInternalServerError, err.Error())
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+	_, span := tracing.Start(r.Context(), "hugo.Restart", attribute.String("hugo.instance", m.Name()))
+	err := m.Restart()
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to restart Hugo")
 		return
 	}
 
 	s.jsonResponse(w, &successResponse{Status: "restarting"}, http.StatusOK)
 }
 
-// handleHugoLogs returns recent Hugo logs
+// handleHugoLogs returns recent logs of the Hugo server of ?instance=
 func (s *Server) handleHugoLogs(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
 	limit := 100
 	if l := r.URL.Query().Get("limit"); l != "" {
 		if lInt, err := strconv.Atoi(l); err == nil {
@@ -690,37 +1229,73 @@ func (s *Server) handleHugoLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logs := s.hugoMgr.GetLogs(limit)
+	var levels []string
+	if l := r.URL.Query().Get("level"); l != "" {
+		for _, level := range strings.Split(l, ",") {
+			level = strings.TrimSpace(level)
+			if !hugo.IsLevel(level) {
+				s.jsonError(w, http.StatusBadRequest, "Invalid log level: "+level)
+				return
+			}
+			levels = append(levels, level)
+		}
+	}
+
+	logs := m.GetLogsByLevel(limit, levels)
 	s.jsonResponse(w, logs, http.StatusOK)
 }
 
-// handleHugoWS handles WebSocket connections for live log streaming
+// handleHugoWS handles WebSocket connections for live log streaming. Status transitions are sent on
+// the same stream as messages with type "status", starting with the current status.
 func (s *Server) handleHugoWS(w http.ResponseWriter, r *http.Request) {
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		slog.WarnContext(r.Context(), "WebSocket upgrade failed", "error", err)
 		return
 	}
 	defer conn.Close()
 
-	// Subscribe to log stream
+	// Subscribe to log stream and status changes
 	ch := s.hugoMgr.Subscribe()
 	defer s.hugoMgr.Unsubscribe(ch)
+	statusCh := s.hugoMgr.SubscribeStatus()
+	defer s.hugoMgr.UnsubscribeStatus(statusCh)
 
-	for entry := range ch {
-		data, err := json.Marshal(entry)
-		if err != nil {
-			continue
+	status, msg := s.hugoMgr.GetStatus()
+	var message interface{} = hugo.StatusEvent{Type: "status", Time: time.Now(), Status: status, Message: msg, Port: s.hugoMgr.GetPort()}
+	for {
+		data, err := json.Marshal(message)
+		if err == nil {
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
 		}
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-			return
+
+		select {
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			message = entry
+		case event, ok := <-statusCh:
+			if !ok {
+				return
+			}
+			message = event
 		}
 	}
 }
 
 // handleConfigGet handles GET requests for configuration
 func (s *Server) handleConfigGet(w http.ResponseWriter, r *http.Request) {
-	s.jsonResponse(w, s.config, http.StatusOK)
+	if !isAdmin(r) {
+		// Editors would otherwise read the admin tokens
+		cfg := *s.config()
+		cfg.Server.AuthToken, cfg.Server.Tokens = "", nil
+		s.jsonResponse(w, &cfg, http.StatusOK)
+		return
+	}
+	s.jsonResponse(w, s.config(), http.StatusOK)
 }
 
 // handleConfigPut handles PUT requests for configuration updates
@@ -730,12 +1305,42 @@ func (s *Server) handleConfigPut(w http.ResponseWriter, r *http.Request) {
 		s.jsonError(w, http.StatusBadRequest, "Invalid configuration")
 		return
 	}
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	// Settings from the environment and flags still win, and stay out of the file
+	newConfig.KeepOverrides(s.config())
+
+	issues := config.Validate(&newConfig, s.projectDir)
+	if err := config.FirstError(issues); err != nil {
+		s.jsonResponse(w, &configErrorResponse{
+			errorResponse: errorResponse{
+				Code:      http.StatusUnprocessableEntity,
+				ErrorCode: ErrCodeInvalidConfig,
+				Detail:    "Invalid configuration: " + err.Error(),
+			},
+			Issues: issues,
+		}, http.StatusUnprocessableEntity)
+		return
+	}
+
 	if err := config.Save(s.projectDir, &newConfig); err != nil {
 		s.jsonError(w, http.StatusInternalServerError, "Failed to save configuration")
 		return
 	}
-	s.config = &newConfig
-	s.jsonResponse(w, &successResponse{Status: "saved"}, http.StatusOK)
+	s.cfg.Store(&newConfig)
+	s.jsonResponse(w, &configSaveResponse{Status: "saved", Issues: issues}, http.StatusOK)
+}
+
+// handleConfigValidate checks a configuration without saving it, returning every error and warning with its YAML path
+func (s *Server) handleConfigValidate(w http.ResponseWriter, r *http.Request) {
+	var cfg config.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid configuration")
+		return
+	}
+
+	issues := config.Validate(&cfg, s.projectDir)
+	s.jsonResponse(w, &configValidateResponse{Valid: config.FirstError(issues) == nil, Issues: issues}, http.StatusOK)
 }
 
 // handleDataFiles returns files for shortcode file selectors
@@ -745,7 +1350,9 @@ func (s *Server) handleDataFiles(w http.ResponseWriter, r *http.Request) {
 		dataType = "all"
 	}
 
+	_, span := tracing.Start(r.Context(), "files.ListDataFiles", attribute.String("data.type", dataType))
 	files, err := s.fileMgr.ListDataFiles(dataType)
+	tracing.End(span, err)
 	if err != nil {
 		s.jsonError(w, http.StatusInternalServerError, "Failed to list data files")
 		return
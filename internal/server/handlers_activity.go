@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/activity"
+)
+
+// Bounds of the ?limit= of the activity log
+const (
+	defaultActivityLimit = 100
+	maxActivityLimit     = 1000
+)
+
+// handleActivity returns the changes made through the API, newest first, optionally only those
+// since ?since= (an RFC 3339 time, or a duration back from now such as 24h), for ?path= or the files
+// inside it, or by ?user=
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	q := activity.Query{
+		Path:  r.URL.Query().Get("path"),
+		User:  r.URL.Query().Get("user"),
+		Limit: defaultActivityLimit,
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxActivityLimit {
+			s.jsonError(w, http.StatusBadRequest, fmt.Sprintf("limit must be 1-%d", maxActivityLimit))
+			return
+		}
+		q.Limit = n
+	}
+	if v := r.URL.Query().Get("since"); v != "" {
+		if since, err := time.Parse(time.RFC3339, v); err == nil {
+			q.Since = since
+		} else if ago, err := time.ParseDuration(v); err == nil && ago > 0 {
+			q.Since = time.Now().Add(-ago)
+		} else {
+			s.jsonError(w, http.StatusBadRequest, "since must be an RFC 3339 time or a duration such as 24h")
+			return
+		}
+	}
+
+	entries, err := s.activityLog.Find(q)
+	if err != nil {
+		s.mapError(w, err, "Failed to read activity")
+		return
+	}
+	s.jsonResponse(w, entries, http.StatusOK)
+}
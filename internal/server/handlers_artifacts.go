@@ -0,0 +1,25 @@
+package server
+
+import "net/http"
+
+// handleBuildArtifacts lists the output of the latest build under ?path= with sizes, and what
+// changed since the build before it
+func (s *Server) handleBuildArtifacts(w http.ResponseWriter, r *http.Request) {
+	report, err := s.artifactsMgr.Report(r.URL.Query().Get("path"))
+	if err != nil {
+		s.mapError(w, err, "Failed to read build artifacts")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleBuildReport returns the checks of the latest build: pages gone since the previous build,
+// references to missing local assets and pages without a title
+func (s *Server) handleBuildReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.artifactsMgr.Checks()
+	if err != nil {
+		s.mapError(w, err, "Failed to read build report")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
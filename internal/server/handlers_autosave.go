@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+)
+
+// draftPath returns the file of a draft route, which must exist in the live tree
+func (s *Server) draftPath(w http.ResponseWriter, r *http.Request) (string, []byte, bool) {
+	p := s.getURLParam(r, "path")
+	if p == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return "", nil, false
+	}
+	current, err := s.fileMgr.ReadFileBytes(p)
+	if err != nil {
+		s.mapError(w, err, "Failed to read file")
+		return "", nil, false
+	}
+	return p, current, true
+}
+
+// handleDraftGet returns the requester's unsaved draft of a file
+func (s *Server) handleDraftGet(w http.ResponseWriter, r *http.Request) {
+	p, current, ok := s.draftPath(w, r)
+	if !ok {
+		return
+	}
+	draft, err := s.autosaveStore.Get(requestUser(r).Username, p, current)
+	if err != nil {
+		s.mapError(w, err, "Failed to read draft")
+		return
+	}
+	s.jsonResponse(w, draft, http.StatusOK)
+}
+
+// handleDraftSave keeps the unsaved content of a file open in the editor. The file itself is
+// unchanged, so the request gets no activity entry.
+func (s *Server) handleDraftSave(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	p, current, ok := s.draftPath(w, r)
+	if !ok {
+		return
+	}
+	var req draftSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !s.checkEditableSize(w, p, req.Content) {
+		return
+	}
+	draft, err := s.autosaveStore.Save(requestUser(r).Username, p, req.Content, current)
+	if err != nil {
+		s.mapError(w, err, "Failed to save draft")
+		return
+	}
+	draft.Content = "" // The editor has it
+	s.jsonResponse(w, draft, http.StatusOK)
+}
+
+// handleDraftDelete discards the requester's draft of a file
+func (s *Server) handleDraftDelete(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	p := s.getURLParam(r, "path")
+	if p == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(p) {
+		s.mapError(w, files.ErrInvalidPath, p)
+		return
+	}
+	if err := s.autosaveStore.Delete(requestUser(r).Username, p); err != nil {
+		s.mapError(w, err, "Failed to discard draft")
+		return
+	}
+	s.jsonResponse(w, successResponse{Status: StatusSuccess}, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/calendar"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+)
+
+// handleCalendar lists the content dated between ?from= and ?to= for the editorial calendar
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	cal, err := s.calendarMgr.Range(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		s.mapError(w, err, "Failed to read the calendar")
+		return
+	}
+	s.jsonResponse(w, cal, http.StatusOK)
+}
+
+// handleCalendarReschedule moves a date of a page, as dragging it to another day of the calendar does
+func (s *Server) handleCalendarReschedule(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req calendarRescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Field == "" {
+		req.Field = calendar.FieldDate
+	}
+
+	before := s.sizeOf(r, path)
+	entry, err := s.calendarMgr.Reschedule(path, req.Field, req.Date)
+	if err != nil {
+		s.mapError(w, err, "Failed to reschedule")
+		return
+	}
+	s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": path, "field": entry.Field}, path, before))
+	s.jsonResponse(w, entry, http.StatusOK)
+}
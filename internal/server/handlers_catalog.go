@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/catalog"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// handleCatalogProducts lists the products of the data file
+func (s *Server) handleCatalogProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := s.catalogMgr.Products()
+	if err != nil {
+		s.mapError(w, err, "Failed to read products")
+		return
+	}
+	s.jsonResponse(w, products, http.StatusOK)
+}
+
+// handleCatalogProduct returns a single product
+func (s *Server) handleCatalogProduct(w http.ResponseWriter, r *http.Request) {
+	product, err := s.catalogMgr.Product(s.getURLParam(r, "sku"))
+	if err != nil {
+		s.mapError(w, err, "Failed to read product")
+		return
+	}
+	s.jsonResponse(w, product, http.StatusOK)
+}
+
+// handleCatalogProductCreate adds a product to the data file
+func (s *Server) handleCatalogProductCreate(w http.ResponseWriter, r *http.Request) {
+	var req catalog.Product
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	product, err := s.catalogMgr.Create(req)
+	if err != nil {
+		s.mapError(w, err, "Failed to create product")
+		return
+	}
+	s.jsonResponse(w, product, http.StatusCreated)
+}
+
+// handleCatalogProductUpdate replaces a product, renaming it when the body has a different SKU
+func (s *Server) handleCatalogProductUpdate(w http.ResponseWriter, r *http.Request) {
+	var req catalog.Product
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	product, err := s.catalogMgr.Update(s.getURLParam(r, "sku"), req)
+	if err != nil {
+		s.mapError(w, err, "Failed to update product")
+		return
+	}
+	s.jsonResponse(w, product, http.StatusOK)
+}
+
+// handleCatalogProductDelete removes a product from the data file
+func (s *Server) handleCatalogProductDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.catalogMgr.Delete(s.getURLParam(r, "sku")); err != nil {
+		s.mapError(w, err, "Failed to delete product")
+		return
+	}
+	s.jsonResponse(w, &successResponse{Status: "deleted"}, http.StatusOK)
+}
+
+// handleCatalogProductImage processes an uploaded product photo and adds it to the product
+func (s *Server) handleCatalogProductImage(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form (max 50MB)
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "No image file provided")
+		return
+	}
+	defer file.Close()
+
+	sku := s.getURLParam(r, "sku")
+	ctx, span := tracing.Start(r.Context(), "catalog.AddImage", attribute.String("catalog.sku", sku), attribute.Int64("image.upload_size", header.Size))
+	result, err := s.catalogMgr.AddImage(ctx, sku, file)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to process product image")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleCatalogValidate checks prices, SKUs, photos and pages of every product
+func (s *Server) handleCatalogValidate(w http.ResponseWriter, r *http.Request) {
+	issues, err := s.catalogMgr.Validate()
+	if err != nil {
+		s.mapError(w, err, "Failed to validate products")
+		return
+	}
+
+	valid := true
+	for _, issue := range issues {
+		if issue.Severity == catalog.SeverityError {
+			valid = false
+			break
+		}
+	}
+	s.jsonResponse(w, catalogValidateResponse{Valid: valid, Issues: issues}, http.StatusOK)
+}
+
+// handleCatalogGenerate creates or refreshes a page for every product
+func (s *Server) handleCatalogGenerate(w http.ResponseWriter, r *http.Request) {
+	result, err := s.catalogMgr.GeneratePages()
+	if err != nil {
+		s.mapError(w, err, "Failed to generate product pages")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/comments"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleComments lists review comments, filtered by ?path= (a file or folder), ?status= (open or
+// resolved) and ?author=
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request) {
+	q := comments.Query{
+		Path:   r.URL.Query().Get("path"),
+		Status: r.URL.Query().Get("status"),
+		Author: r.URL.Query().Get("author"),
+	}
+	switch q.Status {
+	case "", comments.StatusOpen, comments.StatusResolved:
+	default:
+		s.jsonError(w, http.StatusBadRequest, "status must be open or resolved")
+		return
+	}
+	s.jsonResponse(w, s.commentsStore.List(q), http.StatusOK)
+}
+
+// handleCommentCreate adds a comment on a range of lines of a text file
+func (s *Server) handleCommentCreate(w http.ResponseWriter, r *http.Request) {
+	var req commentCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path == "" || !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return
+	}
+	// The range must be in the file as it is now; later edits may move the lines it's about
+	data, err := s.fileMgr.ReadFileBytes(req.Path)
+	if err != nil {
+		s.mapError(w, err, "Failed to read file")
+		return
+	}
+	if files.IsBinary(data) {
+		s.jsonErrorCode(w, http.StatusUnsupportedMediaType, ErrCodeBinary, "Only text files can be commented on")
+		return
+	}
+	if lines := strings.Count(strings.TrimSuffix(string(data), "\n"), "\n") + 1; req.EndLine > lines || (req.EndLine == 0 && req.StartLine > lines) {
+		s.jsonError(w, http.StatusBadRequest, "The lines are past the end of the file")
+		return
+	}
+
+	c, err := s.commentsStore.Create(comments.Comment{
+		Path:      req.Path,
+		StartLine: req.StartLine,
+		EndLine:   req.EndLine,
+		Body:      req.Body,
+		Author:    requestUser(r).Username,
+	})
+	if err != nil {
+		s.mapError(w, err, "Failed to add comment")
+		return
+	}
+	s.commentChanged(r, "comment.created", c)
+	s.jsonResponse(w, c, http.StatusCreated)
+}
+
+// handleComment returns a comment
+func (s *Server) handleComment(w http.ResponseWriter, r *http.Request) {
+	c, err := s.commentsStore.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.mapError(w, err, "Failed to get comment")
+		return
+	}
+	s.jsonResponse(w, c, http.StatusOK)
+}
+
+// handleCommentUpdate edits a comment or resolves it. Anyone can resolve or reopen a comment; only
+// its author or an admin can change its text and lines.
+func (s *Server) handleCommentUpdate(w http.ResponseWriter, r *http.Request) {
+	var req comments.Update
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if req.Body != nil || req.StartLine != nil || req.EndLine != nil {
+		if !s.commentOwner(w, r, id) {
+			return
+		}
+	}
+
+	c, err := s.commentsStore.Update(id, req, requestUser(r).Username)
+	if err != nil {
+		s.mapError(w, err, "Failed to update comment")
+		return
+	}
+	event := "comment.updated"
+	if req.Resolved != nil {
+		event = "comment.reopened"
+		if *req.Resolved {
+			event = "comment.resolved"
+		}
+	}
+	s.commentChanged(r, event, c)
+	s.jsonResponse(w, c, http.StatusOK)
+}
+
+// handleCommentDelete deletes a comment; only its author or an admin can
+func (s *Server) handleCommentDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !s.commentOwner(w, r, id) {
+		return
+	}
+	c, err := s.commentsStore.Delete(id)
+	if err != nil {
+		s.mapError(w, err, "Failed to delete comment")
+		return
+	}
+	s.commentChanged(r, "comment.deleted", c)
+	s.jsonResponse(w, successResponse{Status: StatusSuccess}, http.StatusOK)
+}
+
+// handleReviews returns the review state of every file with comments or an approval
+func (s *Server) handleReviews(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.commentsStore.Reviews(), http.StatusOK)
+}
+
+// handleReview returns the review state of a file: its open and resolved comments, and its approval
+func (s *Server) handleReview(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.reviewPath(w, r)
+	if !ok {
+		return
+	}
+	s.jsonResponse(w, s.commentsStore.Review(p), http.StatusOK)
+}
+
+// handleReviewApprove approves a file for publishing, once all its comments are resolved
+func (s *Server) handleReviewApprove(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.reviewPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.fileMgr.Exists(p) {
+		s.mapError(w, files.ErrNotFound, p)
+		return
+	}
+	review, err := s.commentsStore.Approve(p, requestUser(r).Username)
+	if err != nil {
+		s.mapError(w, err, "Failed to approve")
+		return
+	}
+	s.reviewChanged(r, "review.approved", review)
+	s.jsonResponse(w, review, http.StatusOK)
+}
+
+// handleReviewWithdraw withdraws the approval of a file
+func (s *Server) handleReviewWithdraw(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.reviewPath(w, r)
+	if !ok {
+		return
+	}
+	review, err := s.commentsStore.Withdraw(p)
+	if err != nil {
+		s.mapError(w, err, "Failed to withdraw approval")
+		return
+	}
+	s.reviewChanged(r, "review.withdrawn", review)
+	s.jsonResponse(w, review, http.StatusOK)
+}
+
+// reviewPath returns the file of a review route
+func (s *Server) reviewPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p := s.getURLParam(r, "path")
+	if p == "" || !s.fileMgr.IsValidPath(p) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return "", false
+	}
+	return p, true
+}
+
+// commentOwner checks that the request is from the author of a comment or an admin
+func (s *Server) commentOwner(w http.ResponseWriter, r *http.Request, id string) bool {
+	c, err := s.commentsStore.Get(id)
+	if err != nil {
+		s.mapError(w, err, "Failed to get comment")
+		return false
+	}
+	if !isAdmin(r) && c.Author != requestUser(r).Username {
+		s.jsonErrorCode(w, http.StatusForbidden, ErrCodeForbidden, "Only the author or an admin can change a comment")
+		return false
+	}
+	return true
+}
+
+// commentChanged records a comment change in the activity log and tells realtime subscribers
+func (s *Server) commentChanged(r *http.Request, event string, c *comments.Comment) {
+	s.recordActivity(r, event, map[string]interface{}{"path": c.Path, "id": c.ID})
+	s.hub.Publish(realtime.TopicComments, event, c)
+}
+
+// reviewChanged records an approval change in the activity log and tells realtime subscribers
+func (s *Server) reviewChanged(r *http.Request, event string, review comments.Review) {
+	s.recordActivity(r, event, map[string]interface{}{"path": review.Path})
+	s.hub.Publish(realtime.TopicComments, event, review)
+}
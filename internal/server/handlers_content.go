@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/slug"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Time save-and-preview waits for Hugo's rebuild, in seconds
+const (
+	defaultRebuildWait = 15
+	maxRebuildWait     = 120
+)
+
+// handleContentTemplate returns the template template_paths binds to a new file, given its path or
+// the folder it goes in, with the front matter the file starts with
+func (s *Server) handleContentTemplate(w http.ResponseWriter, r *http.Request) {
+	path := s.getURLParam(r, "path")
+	if path == "" || !s.fileMgr.IsValidPath(path) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return
+	}
+
+	dir := path
+	if filepath.Ext(path) != "" {
+		dir = filepath.ToSlash(filepath.Dir(path))
+	}
+	resp := &contentTemplateResponse{Template: s.config().TemplateFor(path), Data: map[string]interface{}{}}
+	if resp.Template != "" {
+		resp.Data = s.config().Templates.Defaults(resp.Template, dir, time.Now())
+	}
+	s.jsonResponse(w, resp, http.StatusOK)
+}
+
+// handleSlug returns the slug of a title and the file name it gives a new page in a folder,
+// numbered when a file or bundle there already has it
+func (s *Server) handleSlug(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	if strings.TrimSpace(title) == "" {
+		s.jsonError(w, http.StatusBadRequest, "Title required")
+		return
+	}
+	base := slug.Make(title)
+	if base == "" {
+		s.jsonError(w, http.StatusBadRequest, "Title has no letters or digits to make a slug from")
+		return
+	}
+
+	dir := r.URL.Query().Get("dir")
+	name, taken, err := s.fileMgr.AvailableName(dir, base)
+	if err != nil {
+		s.mapError(w, err, "Failed to check the folder")
+		return
+	}
+	resp := &slugResponse{Slug: name, Filename: name + ".md", Path: name + ".md", Taken: taken}
+	if dir != "" {
+		resp.Path = filepath.ToSlash(filepath.Join(dir, resp.Filename))
+	}
+	s.jsonResponse(w, resp, http.StatusOK)
+}
+
+// handleContentRelated suggests pages to link from a content page
+func (s *Server) handleContentRelated(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	_, span := tracing.Start(r.Context(), "related.Related", attribute.String("file.path", path))
+	result, err := s.relatedMgr.Related(path, limit)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to find related content")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleContentPermalink returns the URL a content file is rendered at, and its preview URL on the Hugo server of ?instance=
+func (s *Server) handleContentPermalink(w http.ResponseWriter, r *http.Request) {
+	path := s.getURLParam(r, "path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(path) {
+		s.mapError(w, files.ErrInvalidPath, path)
+		return
+	}
+	if !s.fileMgr.Exists(path) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotFound, path), path)
+		return
+	}
+	instance, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+
+	siteCfg, err := site.Load(s.projectDir)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	permalink, err := hugocontent.ComputePermalink(s.projectDir, siteCfg, path)
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.jsonResponse(w, &permalinkResponse{
+		Permalink:  *permalink,
+		PreviewURL: fmt.Sprintf("http://localhost:%d%s", instance.GetPort(), permalink.URL),
+	}, http.StatusOK)
+}
+
+// handleContentSavePreview saves a content file, waits for Hugo (the instance of ?instance=) to rebuild it and returns
+// the page's preview URL. The wait ends early when nothing changed or Hugo isn't running; ?timeout= bounds it in seconds.
+func (s *Server) handleContentSavePreview(w http.ResponseWriter, r *http.Request) {
+	path := s.getURLParam(r, "path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(path) {
+		s.mapError(w, files.ErrInvalidPath, path)
+		return
+	}
+	path = filepath.ToSlash(path)
+
+	var req contentSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !s.checkEditableSize(w, path, req.Content) {
+		return
+	}
+	wait := defaultRebuildWait
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxRebuildWait {
+			s.jsonError(w, http.StatusBadRequest, fmt.Sprintf("timeout must be 0-%d seconds", maxRebuildWait))
+			return
+		}
+		wait = n
+	}
+	instance, ok := s.hugoFor(w, r)
+	if !ok {
+		return
+	}
+
+	// The URL comes from the new front matter, so it's known before Hugo renders the page
+	siteCfg, err := site.Load(s.projectDir)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	loc, err := hugocontent.Locate(siteCfg, path)
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fm, _, _, err := hugocontent.Parse([]byte(req.Content))
+	if err != nil {
+		fm = hugocontent.FrontMatter{} // Saved anyway; the rebuild reports the error
+	}
+	permalink := hugocontent.PermalinkFor(siteCfg, loc, fm, path)
+
+	previous, readErr := s.fileMgr.ReadFile(path)
+	unchanged := readErr == nil && previous == req.Content
+	status, _ := instance.GetStatus()
+
+	var watch *hugo.RebuildWatch
+	if status == hugo.StatusRunning && !unchanged {
+		watch = instance.WatchRebuild()
+		defer watch.Close()
+	}
+
+	before := s.sizeOf(r, path)
+	_, span := tracing.Start(r.Context(), "files.WriteFile", attribute.String("file.path", path), attribute.Int("file.size", len(req.Content)))
+	err = s.fileMgr.WriteFile(path, req.Content)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to save file")
+		return
+	}
+	if !unchanged {
+		s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": path}, path, before))
+	}
+
+	rebuild := &hugo.RebuildResult{Status: hugo.RebuildUnchanged, Errors: []hugo.LogEvent{}}
+	switch {
+	case watch != nil:
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(wait)*time.Second)
+		_, span := tracing.Start(ctx, "hugo.WaitRebuild", attribute.String("file.path", path))
+		rebuild = watch.Wait(ctx, path)
+		tracing.End(span, nil)
+		cancel()
+	case status != hugo.StatusRunning:
+		rebuild.Status = hugo.RebuildNotRunning
+	}
+
+	s.jsonResponse(w, &savePreviewResponse{
+		permalinkResponse: permalinkResponse{
+			Permalink:  *permalink,
+			PreviewURL: fmt.Sprintf("http://localhost:%d%s", instance.GetPort(), permalink.URL),
+		},
+		Rebuild: rebuild,
+	}, http.StatusOK)
+}
@@ -0,0 +1,10 @@
+package server
+
+import (
+	"net/http"
+)
+
+// handleDashboard returns the configured dashboard widgets with their data
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.dashboardMgr.Build(s.config().Dashboard), http.StatusOK)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/fernandezvara/hugo-manager/internal/docs"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+)
+
+// handleDocsVersions returns the documentation versions shown in the version switcher
+func (s *Server) handleDocsVersions(w http.ResponseWriter, r *http.Request) {
+	list, err := s.docsMgr.Versions()
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, "Failed to read docs versions: "+err.Error())
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleDocsVersionCreate copies a docs version into a new one and adds it to the version list
+func (s *Server) handleDocsVersionCreate(w http.ResponseWriter, r *http.Request) {
+	var req docsVersionCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := s.docsMgr.CreateVersion(req.From, req.To, req.Latest)
+	if err != nil {
+		s.mapError(w, err, "Failed to create docs version")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusCreated)
+}
+
+// handleDocsVersionsPut replaces the version list, e.g. to reorder versions or change the latest one
+func (s *Server) handleDocsVersionsPut(w http.ResponseWriter, r *http.Request) {
+	var list docs.VersionList
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if list.Versions == nil {
+		list.Versions = []docs.Version{}
+	}
+
+	if err := s.docsMgr.SaveVersions(&list); err != nil {
+		s.mapError(w, err, "Failed to save docs versions")
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleDocsIngest generates markdown reference pages from an OpenAPI document or JSON schema
+func (s *Server) handleDocsIngest(w http.ResponseWriter, r *http.Request) {
+	var req docsIngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Source == "" || req.Output == "" {
+		s.jsonError(w, http.StatusBadRequest, "source and output are required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(req.Source) || !s.fileMgr.IsValidPath(req.Output) {
+		s.mapError(w, files.ErrInvalidPath, "")
+		return
+	}
+
+	result, err := s.docsMgr.Ingest(req.Source, req.Output, req.Kind)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "Source file not found")
+			return
+		}
+		s.mapError(w, err, "Failed to ingest API description")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+)
+
+// handleDomainStatus returns the latest DNS and TLS certificate report for the production domain
+func (s *Server) handleDomainStatus(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.domainMgr.LastReport(), http.StatusOK)
+}
+
+// handleDomainCheck runs the domain checks immediately
+func (s *Server) handleDomainCheck(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.domainMgr.Check(), http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+)
+
+// handleDraftsStale lists drafts dated ?minAge= months ago and not edited for ?idle= months
+func (s *Server) handleDraftsStale(w http.ResponseWriter, r *http.Request) {
+	var criteria drafts.Criteria
+	for name, field := range map[string]*int{"minAge": &criteria.MinAgeMonths, "idle": &criteria.IdleMonths} {
+		if v := r.URL.Query().Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				s.jsonError(w, http.StatusBadRequest, fmt.Sprintf("%s must be a number of months", name))
+				return
+			}
+			*field = n
+		}
+	}
+
+	list, err := s.draftsMgr.Stale(criteria)
+	if err != nil {
+		s.mapError(w, err, "Failed to list drafts")
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleDraftsCleanup archives or deletes abandoned drafts. Without confirm it only previews the moves.
+func (s *Server) handleDraftsCleanup(w http.ResponseWriter, r *http.Request) {
+	var req draftsCleanupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Paths) == 0 {
+		s.jsonError(w, http.StatusBadRequest, "Paths required")
+		return
+	}
+	if req.Confirm && s.readOnly(w) {
+		return
+	}
+
+	result, err := s.draftsMgr.Cleanup(req.Action, req.Paths, req.Criteria, req.Confirm)
+	if err != nil {
+		s.mapError(w, err, "Failed to clean up drafts")
+		return
+	}
+	if !result.DryRun {
+		for _, move := range result.Moved {
+			s.fileChanged(r, webhooks.EventFileDeleted, map[string]interface{}{"path": move.Path, "movedTo": move.To, "reason": "draft." + req.Action})
+		}
+	} else {
+		skipActivity(r) // A preview changes nothing
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/events"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// handleEvents returns the upcoming pages of every configured event series
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	upcoming, err := s.eventsGen.Upcoming(time.Now())
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, "Failed to list events: "+err.Error())
+		return
+	}
+	s.jsonResponse(w, upcoming, http.StatusOK)
+}
+
+// handleEventsGenerate creates the pages of the next occurrences of every series
+func (s *Server) handleEventsGenerate(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.eventsGen.Generate(time.Now()), http.StatusOK)
+}
+
+// handleEventsICal exports the upcoming events as an iCalendar feed
+func (s *Server) handleEventsICal(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	upcoming, err := s.eventsGen.Upcoming(now)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, "Failed to list events: "+err.Error())
+		return
+	}
+
+	name := ""
+	if siteCfg, err := site.Load(s.projectDir); err == nil {
+		name = siteCfg.String("title")
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	w.Write([]byte(events.ICal(name, upcoming, now)))
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleForms returns the endpoint of every configured form
+func (s *Server) handleForms(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := s.formsMgr.Endpoints()
+	if err != nil {
+		s.mapError(w, err, "Failed to build form endpoints")
+		return
+	}
+	s.jsonResponse(w, endpoints, http.StatusOK)
+}
+
+// handleFormsGenerate writes the form endpoints to the forms data file
+func (s *Server) handleFormsGenerate(w http.ResponseWriter, r *http.Request) {
+	result, err := s.formsMgr.Generate()
+	if err != nil {
+		s.mapError(w, err, "Failed to generate forms data file")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleFormTest sends a sample submission to a form's endpoint
+func (s *Server) handleFormTest(w http.ResponseWriter, r *http.Request) {
+	result, err := s.formsMgr.Test(s.getURLParam(r, "name"))
+	if err != nil {
+		s.mapError(w, err, "Failed to test form")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleFormsScan looks for forms in templates and content that point at dead or unconfigured endpoints
+func (s *Server) handleFormsScan(w http.ResponseWriter, r *http.Request) {
+	probe, _ := strconv.ParseBool(r.URL.Query().Get("probe"))
+	report, err := s.formsMgr.Scan(probe)
+	if err != nil {
+		s.mapError(w, err, "Failed to scan forms")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+)
+
+// handleHealthScore runs the project checks and returns the weighted health score with its trend
+func (s *Server) handleHealthScore(w http.ResponseWriter, r *http.Request) {
+	_, span := tracing.Start(r.Context(), "health.Score")
+	report, err := s.healthMgr.Score()
+	tracing.End(span, err)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
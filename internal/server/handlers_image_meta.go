@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+)
+
+// handleImageMetaGet returns the alt text, caption, credit and license kept for an image
+func (s *Server) handleImageMetaGet(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+	meta, err := s.imageMgr.Meta(path)
+	if err != nil {
+		s.mapError(w, err, "Failed to read image metadata")
+		return
+	}
+	s.jsonResponse(w, meta, http.StatusOK)
+}
+
+// handleImageMetaSet replaces the metadata of an image, shared by all its variants; empty
+// metadata removes the sidecar
+func (s *Server) handleImageMetaSet(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req images.Meta
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	current, err := s.imageMgr.Meta(path)
+	if err != nil {
+		s.mapError(w, err, "Failed to read image metadata")
+		return
+	}
+	before := s.sizeOf(r, current.Sidecar)
+	meta, err := s.imageMgr.SetMeta(path, req)
+	if err != nil {
+		s.mapError(w, err, "Failed to save image metadata")
+		return
+	}
+
+	event := webhooks.EventFileSaved
+	switch {
+	case !current.Stored && meta.Stored:
+		event = webhooks.EventFileCreated
+	case current.Stored && !meta.Stored:
+		event = webhooks.EventFileDeleted
+	}
+	if current.Stored || meta.Stored {
+		s.fileChanged(r, event, s.withSizes(r, map[string]interface{}{"path": meta.Sidecar, "image": meta.Path}, meta.Sidecar, before))
+	}
+	s.jsonResponse(w, meta, http.StatusOK)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// handleImagePaste processes an image pasted from the clipboard into a page. It's named after the
+// page and the time, goes to the page's folder below static/images unless a folder is given, and is
+// processed with the Inline preset unless another is given; the result has the Markdown to insert.
+func (s *Server) handleImagePaste(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "No image file provided")
+		return
+	}
+	defer file.Close()
+
+	page := filepath.ToSlash(strings.TrimSpace(r.FormValue("page")))
+	if page == "" {
+		s.jsonError(w, http.StatusBadRequest, "page is required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(page) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrInvalidPath, page), page)
+		return
+	}
+
+	folder := filepath.ToSlash(strings.TrimSpace(r.FormValue("folder")))
+	if folder == "" {
+		folder = images.PasteFolder(page)
+	} else if !s.fileMgr.IsValidPath(folder) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrInvalidPath, folder), folder)
+		return
+	}
+	preset := r.FormValue("preset")
+	if preset == "" {
+		preset = images.InlinePreset
+	}
+
+	opts := images.UploadOptions{
+		Folder:     folder,
+		Filename:   images.PasteName(page, time.Now()),
+		PresetName: preset,
+		Alt:        r.FormValue("alt"),
+	}
+
+	ctx, span := tracing.Start(r.Context(), "images.Process", attribute.String("image.folder", opts.Folder), attribute.Int64("image.upload_size", header.Size))
+	result, err := s.imageMgr.Process(ctx, file, opts)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to process image")
+		return
+	}
+
+	s.fileChanged(r, webhooks.EventImageUploaded, map[string]interface{}{
+		"folder":   opts.Folder,
+		"original": result.Original,
+		"variants": len(result.Variants),
+		"page":     page,
+	})
+	s.jsonResponse(w, result, http.StatusOK)
+}
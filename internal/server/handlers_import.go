@@ -0,0 +1,96 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/importer"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxImportSize caps the bytes of an uploaded export
+const maxImportSize = 200 << 20
+
+// handleImport converts a WordPress, Ghost or Jekyll export to content: uploaded as file, or a
+// project-relative file or Jekyll folder as source
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
+		return
+	}
+
+	opts := importer.Options{
+		Kind:    r.FormValue("kind"),
+		Output:  r.FormValue("output"),
+		Pages:   r.FormValue("pages"),
+		Images:  r.FormValue("images"),
+		SiteURL: r.FormValue("siteUrl"),
+	}
+	opts.Aliases, _ = strconv.ParseBool(r.FormValue("aliases"))
+	opts.Overwrite, _ = strconv.ParseBool(r.FormValue("overwrite"))
+	for _, folder := range []string{opts.Output, opts.Pages, opts.Images} {
+		if folder != "" && !s.fileMgr.IsValidPath(folder) {
+			s.mapError(w, files.ErrInvalidPath, folder)
+			return
+		}
+	}
+
+	var data []byte
+	source := r.FormValue("source")
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		data, err = io.ReadAll(io.LimitReader(file, maxImportSize+1))
+		if err != nil {
+			s.jsonError(w, http.StatusBadRequest, "Failed to read the export: "+err.Error())
+			return
+		}
+		if len(data) > maxImportSize {
+			s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, "Export is larger than 200 MB; import it from the project with source")
+			return
+		}
+	} else if source == "" {
+		s.jsonError(w, http.StatusBadRequest, "file or source is required")
+		return
+	} else if !s.fileMgr.IsValidPath(source) {
+		s.mapError(w, files.ErrInvalidPath, source)
+		return
+	}
+
+	var result *importer.Result
+	var err error
+	_, span := tracing.Start(r.Context(), "importer.Import", attribute.String("import.kind", opts.Kind), attribute.String("import.source", source))
+	if data != nil {
+		result, err = s.importMgr.Import(data, opts)
+	} else {
+		result, err = s.importMgr.ImportPath(source, opts)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "Source not found")
+			return
+		}
+		s.mapError(w, err, "Failed to import")
+		return
+	}
+
+	for _, post := range result.Posts {
+		event := webhooks.EventFileCreated
+		switch post.Status {
+		case importer.StatusSkipped:
+			continue
+		case importer.StatusReplaced:
+			event = webhooks.EventFileSaved
+		}
+		s.fileChanged(r, event, s.withSizes(r, map[string]interface{}{"path": post.Path, "reason": "import"}, post.Path, nil))
+	}
+	for _, image := range result.Images {
+		s.fileChanged(r, webhooks.EventFileCreated, s.withSizes(r, map[string]interface{}{"path": image.Path, "reason": "import"}, image.Path, nil))
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
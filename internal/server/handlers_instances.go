@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+)
+
+// hugoFor returns the Hugo instance of ?instance=, the main one when it's unset
+func (s *Server) hugoFor(w http.ResponseWriter, r *http.Request) (*hugo.Manager, bool) {
+	m, err := s.hugoMgr.Instance(r.URL.Query().Get("instance"))
+	if err != nil {
+		s.mapError(w, err, "Unknown Hugo instance")
+		return nil, false
+	}
+	return m, true
+}
+
+// handleHugoInstances lists the Hugo servers of the project, the main one first, with their state
+func (s *Server) handleHugoInstances(w http.ResponseWriter, r *http.Request) {
+	instances := s.hugoMgr.Instances()
+	list := make([]hugo.Instance, len(instances))
+	for i, inst := range instances {
+		list[i] = inst.Describe()
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// forwardInstances publishes the output and status transitions of the Hugo servers of
+// hugo.instances on the instances topic, until stop is closed. The main server's go out on the
+// logs and status topics as they always have.
+func (s *Server) forwardInstances(stop <-chan struct{}) {
+	for _, inst := range s.hugoMgr.Instances()[1:] {
+		logs := inst.Subscribe()
+		statuses := inst.SubscribeStatus()
+		go func() {
+			defer inst.Unsubscribe(logs)
+			defer inst.UnsubscribeStatus(statuses)
+			for {
+				select {
+				case entry := <-logs:
+					s.hub.Publish(realtime.TopicInstances, "instance.log", entry)
+				case event := <-statuses:
+					s.hub.Publish(realtime.TopicInstances, "instance.status", event)
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// forwardBuildErrors publishes each error Hugo reports in a file of the project as a build.error on
+// the status topic, with its file, line and column, so an editor with that file open can jump to
+// it. Hugo repeats errors when a build fails; each file and line goes out once per build.
+func (s *Server) forwardBuildErrors(stop <-chan struct{}) {
+	for _, inst := range s.hugoMgr.Instances() {
+		logs := inst.Subscribe()
+		go func() {
+			defer inst.Unsubscribe(logs)
+			seen := map[string]bool{}
+			for {
+				select {
+				case entry := <-logs:
+					event := entry.Event
+					if event == nil {
+						continue
+					}
+					if event.Kind == hugo.EventRebuild {
+						seen = map[string]bool{}
+						continue
+					}
+					if event.Kind != hugo.EventError || event.File == "" || path.IsAbs(event.File) {
+						continue // Not located, or in a theme module or Hugo's cache outside the project
+					}
+					key := fmt.Sprintf("%s:%d", event.File, event.Line)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					s.hub.Publish(realtime.TopicStatus, "build.error", buildErrorEvent{LogEvent: *event, Instance: entry.Instance})
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// handleLintShortcodes validates shortcode invocations across content, or in a single file with ?path=
+func (s *Server) handleLintShortcodes(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		ctx, span := tracing.Start(r.Context(), "lint.LintAll")
+		report, err := s.scLinter.LintAll(ctx)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to lint shortcodes")
+			return
+		}
+		s.jsonResponse(w, report, http.StatusOK)
+		return
+	}
+
+	if !s.fileMgr.IsValidPath(path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "lint.LintFile", attribute.String("file.path", path))
+	report, err := s.scLinter.LintFile(path)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.jsonError(w, http.StatusInternalServerError, "Failed to lint shortcodes: "+err.Error())
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleLintShortcodesContent validates shortcode invocations in unsaved editor content
+func (s *Server) handleLintShortcodesContent(w http.ResponseWriter, r *http.Request) {
+	var req lintContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	report, err := s.scLinter.LintContent(req.Path, req.Content)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, "Failed to lint shortcodes: "+err.Error())
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleLintLinks reports broken refs, internal links and images across content, or in a single file
+// with ?path=. With ?external=true links to other sites are requested as well.
+func (s *Server) handleLintLinks(w http.ResponseWriter, r *http.Request) {
+	external, _ := strconv.ParseBool(r.URL.Query().Get("external"))
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		ctx, span := tracing.Start(r.Context(), "lint.LintLinks", attribute.Bool("lint.external", external))
+		report, err := s.linkLinter.LintAll(ctx, external)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to lint links")
+			return
+		}
+		s.jsonResponse(w, report, http.StatusOK)
+		return
+	}
+
+	if !s.fileMgr.IsValidPath(path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	ctx, span := tracing.Start(r.Context(), "lint.LintFileLinks", attribute.String("file.path", path), attribute.Bool("lint.external", external))
+	report, err := s.linkLinter.LintFile(ctx, path, external)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.mapError(w, err, "Failed to lint links")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleLintMarkdown checks the markdown of content files, or of a single file with ?path=
+func (s *Server) handleLintMarkdown(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		ctx, span := tracing.Start(r.Context(), "lint.LintMarkdown")
+		report, err := s.mdLinter.LintAll(ctx)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to lint markdown")
+			return
+		}
+		s.jsonResponse(w, report, http.StatusOK)
+		return
+	}
+
+	if !s.fileMgr.IsValidPath(path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "lint.LintFileMarkdown", attribute.String("file.path", path))
+	report, err := s.mdLinter.LintFile(path)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.mapError(w, err, "Failed to lint markdown")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleLintMarkdownContent checks the markdown of unsaved editor content
+func (s *Server) handleLintMarkdownContent(w http.ResponseWriter, r *http.Request) {
+	var req lintContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	s.jsonResponse(w, s.mdLinter.LintContent(req.Path, req.Content), http.StatusOK)
+}
+
+// handleLintMarkdownFix fixes the markdown problems of unsaved content, of a file, or of every
+// content file. Without confirm it only returns the corrected content.
+func (s *Server) handleLintMarkdownFix(w http.ResponseWriter, r *http.Request) {
+	var req markdownFixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path != "" && !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+	if req.Confirm && s.readOnly(w) {
+		return
+	}
+
+	if req.Content != "" {
+		if req.Confirm {
+			s.jsonError(w, http.StatusBadRequest, "Unsaved content can't be confirmed; save the corrected content instead")
+			return
+		}
+		skipActivity(r) // Nothing is written
+		s.jsonResponse(w, s.mdLinter.FixContent(req.Path, req.Content), http.StatusOK)
+		return
+	}
+
+	ctx, span := tracing.Start(r.Context(), "lint.FixMarkdown", attribute.String("file.path", req.Path), attribute.Bool("lint.confirm", req.Confirm))
+	result, err := s.mdLinter.Fix(ctx, req.Path, req.Confirm)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.mapError(w, err, "Failed to fix markdown")
+		return
+	}
+	if !result.DryRun {
+		for _, file := range result.Files {
+			s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": file.Path, "reason": "markdown-fix"}, file.Path, nil))
+		}
+	} else {
+		skipActivity(r) // A preview changes nothing
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleLintProse checks the spelling and style of the prose of the file at ?path=
+func (s *Server) handleLintProse(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "lint.LintProse", attribute.String("file.path", path))
+	report, err := s.proseLinter.LintFile(path)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.mapError(w, err, "Failed to lint prose")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleLintProseContent checks the spelling and style of unsaved editor content
+func (s *Server) handleLintProseContent(w http.ResponseWriter, r *http.Request) {
+	var req lintContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path != "" && !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	report, err := s.proseLinter.LintContent(req.Path, req.Content)
+	if err != nil {
+		s.mapError(w, err, "Failed to lint prose")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// handleDictionary returns the project word list
+func (s *Server) handleDictionary(w http.ResponseWriter, r *http.Request) {
+	words, err := s.proseLinter.Words()
+	if err != nil {
+		s.mapError(w, err, "Failed to read the word list")
+		return
+	}
+	s.jsonResponse(w, dictionaryResponse{Words: words}, http.StatusOK)
+}
+
+// handleDictionaryAdd adds a word to the project word list, so it's no longer reported as misspelled
+func (s *Server) handleDictionaryAdd(w http.ResponseWriter, r *http.Request) {
+	var req dictionaryWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	words, err := s.proseLinter.AddWord(req.Word)
+	if err != nil {
+		s.mapError(w, err, "Failed to add the word")
+		return
+	}
+	s.jsonResponse(w, dictionaryResponse{Words: words}, http.StatusOK)
+}
+
+// handleDictionaryRemove removes a word from the project word list
+func (s *Server) handleDictionaryRemove(w http.ResponseWriter, r *http.Request) {
+	words, err := s.proseLinter.RemoveWord(s.getURLParam(r, "word"))
+	if err != nil {
+		s.mapError(w, err, "Failed to remove the word")
+		return
+	}
+	s.jsonResponse(w, dictionaryResponse{Words: words}, http.StatusOK)
+}
+
+// handleLintFrontMatter checks the front matter of pages against their section's schema, of a single
+// file with ?path=
+func (s *Server) handleLintFrontMatter(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		ctx, span := tracing.Start(r.Context(), "lint.LintFrontMatter")
+		report, err := s.fmLinter.LintAll(ctx)
+		tracing.End(span, err)
+		if err != nil {
+			s.mapError(w, err, "Failed to lint front matter")
+			return
+		}
+		s.jsonResponse(w, report, http.StatusOK)
+		return
+	}
+
+	if !s.fileMgr.IsValidPath(path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "lint.LintFileFrontMatter", attribute.String("file.path", path))
+	report, err := s.fmLinter.LintFile(path)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		s.mapError(w, err, "Failed to lint front matter")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
+
+// checkFrontMatter checks the front matter of content about to be saved at path, per
+// front_matter.on_save. It returns the problems to warn about, or writes a 422 and returns false
+// when they block the save.
+func (s *Server) checkFrontMatter(w http.ResponseWriter, r *http.Request, path, content string) ([]string, bool) {
+	policy := s.config().FrontMatter.OnSave
+	if policy == "off" {
+		return nil, true
+	}
+	report, err := s.fmLinter.LintContent(path, content)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to check front matter", "path", path, "error", err)
+		return nil, true
+	}
+	if len(report.Diagnostics) == 0 {
+		return nil, true
+	}
+
+	if policy == "block" && report.Errors > 0 {
+		first := report.Diagnostics[0]
+		for _, d := range report.Diagnostics {
+			if d.Severity == lint.SeverityError {
+				first = d
+				break
+			}
+		}
+		s.jsonResponse(w, &frontMatterErrorResponse{
+			errorResponse: errorResponse{
+				Code:      http.StatusUnprocessableEntity,
+				ErrorCode: ErrCodeInvalidFrontMatter,
+				Detail:    fmt.Sprintf("Invalid front matter: %s (line %d)", first.Message, first.Line),
+			},
+			Diagnostics: report.Diagnostics,
+		}, http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	warnings := make([]string, len(report.Diagnostics))
+	for i, d := range report.Diagnostics {
+		warnings[i] = fmt.Sprintf("Front matter: %s (line %d)", d.Message, d.Line)
+	}
+	return warnings, true
+}
+
+// handleLintFrontMatterContent checks the front matter of unsaved editor content
+func (s *Server) handleLintFrontMatterContent(w http.ResponseWriter, r *http.Request) {
+	var req lintContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path != "" && !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+
+	report, err := s.fmLinter.LintContent(req.Path, req.Content)
+	if err != nil {
+		s.mapError(w, err, "Failed to lint front matter")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
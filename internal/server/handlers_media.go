@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/media"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// handleMedia lists the media files in static/, optionally of one ?kind= and inside a ?folder= of static/
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	items, err := s.mediaMgr.List(r.URL.Query().Get("kind"), r.URL.Query().Get("folder"))
+	if err != nil {
+		s.mapError(w, err, "Failed to list media")
+		return
+	}
+	s.jsonResponse(w, items, http.StatusOK)
+}
+
+// handleMediaTypes returns the media kinds with their extensions and upload limits
+func (s *Server) handleMediaTypes(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.mediaMgr.Types(), http.StatusOK)
+}
+
+// handleMediaUpload stores an uploaded media file under static/ and returns it with its snippets.
+// With poster=true a video also gets a poster set, which its snippets use; the upload succeeds
+// with a warning when the poster can't be made.
+func (s *Server) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form (max 50MB in memory, the rest in temporary files)
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to parse form data")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "No file provided")
+		return
+	}
+	defer file.Close()
+
+	filename := r.FormValue("filename")
+	if filename == "" {
+		filename = header.Filename
+	}
+	overwrite, _ := strconv.ParseBool(r.FormValue("overwrite"))
+	poster, _ := strconv.ParseBool(r.FormValue("poster"))
+
+	// Checked as /files/upload checks its files: the project's path policy and the upload types
+	rel, err := s.mediaMgr.Dest(r.FormValue("folder"), filename)
+	if err == nil && !s.fileMgr.IsValidPath(rel) {
+		err = fmt.Errorf("%w: %s", files.ErrInvalidPath, rel)
+	}
+	if err == nil {
+		err = s.uploadsMgr.CheckType(rel, file)
+	}
+	if err != nil {
+		s.mapError(w, err, "Failed to upload media")
+		return
+	}
+
+	item, err := s.mediaMgr.Upload(file, header.Size, r.FormValue("folder"), filename, overwrite)
+	if err != nil {
+		s.mapError(w, err, "Failed to upload media")
+		return
+	}
+
+	s.fileChanged(r, webhooks.EventFileCreated, map[string]interface{}{"path": item.Path, "size": item.Size, "kind": item.Kind})
+
+	resp := &mediaUploadResponse{Item: item}
+	if poster && item.Kind == media.KindVideo {
+		if !s.config().Features.Images {
+			resp.Warnings = append(resp.Warnings, "No poster: the image processing feature is disabled")
+		} else if item, resp.Poster, err = s.mediaPoster(r, item.Path, r.FormValue("alt")); err != nil {
+			resp.Warnings = append(resp.Warnings, "No poster: "+err.Error())
+		} else {
+			resp.Item = item
+		}
+	}
+	resp.Snippet = resp.Item.Snippet(r.FormValue("title"))
+	s.jsonResponse(w, resp, http.StatusCreated)
+}
+
+// handleMediaPoster makes the poster set of a video already in static/ with ffmpeg, replacing an existing one
+func (s *Server) handleMediaPoster(w http.ResponseWriter, r *http.Request) {
+	var req mediaPosterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path == "" || !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return
+	}
+
+	item, poster, err := s.mediaPoster(r, req.Path, req.Alt)
+	if err != nil {
+		s.mapError(w, err, "Failed to make poster")
+		return
+	}
+	s.jsonResponse(w, &mediaUploadResponse{Item: item, Snippet: item.Snippet(req.Title), Poster: poster}, http.StatusOK)
+}
+
+// mediaPoster makes the poster set of a video and returns the video with its new poster
+func (s *Server) mediaPoster(r *http.Request, path, alt string) (*media.Item, *images.ProcessResult, error) {
+	ctx, span := tracing.Start(r.Context(), "media.Poster", attribute.String("media.path", path))
+	poster, err := s.mediaMgr.Poster(ctx, path, alt)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.fileChanged(r, webhooks.EventImageUploaded, map[string]interface{}{
+		"folder":   filepath.ToSlash(filepath.Dir(path)),
+		"original": poster.Original,
+		"variants": len(poster.Variants),
+	})
+	item, err := s.mediaMgr.Get(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return item, poster, nil
+}
+
+// handleMediaSnippet renders the Markdown, HTML and shortcode that embed or link the media file at ?path=
+func (s *Server) handleMediaSnippet(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" || !s.fileMgr.IsValidPath(path) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return
+	}
+
+	snippet, err := s.mediaMgr.Snippet(path, r.URL.Query().Get("title"))
+	if err != nil {
+		s.mapError(w, err, "Failed to render media snippet")
+		return
+	}
+	s.jsonResponse(w, snippet, http.StatusOK)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/podcast"
+)
+
+// handlePodcastFeed previews the podcast channel and the enclosure data of every episode
+func (s *Server) handlePodcastFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := s.podcastMgr.Feed()
+	if err != nil {
+		s.mapError(w, err, "Failed to build podcast feed")
+		return
+	}
+	s.jsonResponse(w, feed, http.StatusOK)
+}
+
+// handlePodcastIngest reads the size, type and duration of an audio file into an episode's front matter
+func (s *Server) handlePodcastIngest(w http.ResponseWriter, r *http.Request) {
+	var req podcastIngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Audio == "" {
+		s.jsonError(w, http.StatusBadRequest, "audio is required")
+		return
+	}
+	if !s.fileMgr.IsValidPath(req.Audio) || (req.Page != "" && !s.fileMgr.IsValidPath(req.Page)) {
+		s.mapError(w, files.ErrInvalidPath, "")
+		return
+	}
+
+	result, err := s.podcastMgr.Ingest(req.Audio, req.Page, req.Title)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "Audio file not found")
+			return
+		}
+		s.mapError(w, err, "Failed to ingest episode audio")
+		return
+	}
+
+	status := http.StatusOK
+	if result.Created {
+		status = http.StatusCreated
+	}
+	s.jsonResponse(w, result, status)
+}
+
+// handlePodcastValidate checks the channel and episodes against the fields Apple Podcasts requires
+func (s *Server) handlePodcastValidate(w http.ResponseWriter, r *http.Request) {
+	issues, err := s.podcastMgr.Validate()
+	if err != nil {
+		s.mapError(w, err, "Failed to validate podcast feed")
+		return
+	}
+
+	valid := true
+	for _, issue := range issues {
+		if issue.Severity == podcast.SeverityError {
+			valid = false
+			break
+		}
+	}
+	s.jsonResponse(w, podcastValidateResponse{Valid: valid, Issues: issues}, http.StatusOK)
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+)
+
+// lockRequest is the optional body of a lock request
+type lockRequest struct {
+	TTL int `json:"ttl"` // Seconds, at most editor.lock_ttl
+}
+
+// presencePath returns the file of a presence or lock route, in the form locks are kept by
+func (s *Server) presencePath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p := s.getURLParam(r, "path")
+	if p == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return "", false
+	}
+	if !s.fileMgr.IsValidPath(p) {
+		s.mapError(w, files.ErrInvalidPath, p)
+		return "", false
+	}
+	return lockKey(p), true
+}
+
+// lockKey returns the key the presence and lock of a file are kept by
+func lockKey(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// checkLock refuses a write to a file another editor session has locked. The session of the
+// request is in ?session=; writes without one aren't checked, as locks are advisory.
+func (s *Server) checkLock(w http.ResponseWriter, r *http.Request, p string) bool {
+	if err := s.presence.CheckWrite(lockKey(p), r.URL.Query().Get("session")); err != nil {
+		s.mapError(w, err, "File is locked")
+		return false
+	}
+	return true
+}
+
+// handlePresence returns the sessions that have a file open and the lock on it
+func (s *Server) handlePresence(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.presencePath(w, r)
+	if !ok {
+		return
+	}
+	s.jsonResponse(w, s.presence.State(p), http.StatusOK)
+}
+
+// handlePresenceJoin records that the session in ?session= has a file open. Editors call it again
+// while the file stays open, or the session drops out after a minute.
+func (s *Server) handlePresenceJoin(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r) // Nothing in the project changes
+	p, ok := s.presencePath(w, r)
+	if !ok {
+		return
+	}
+	state, err := s.presence.Join(p, r.URL.Query().Get("session"), requestUser(r).Username)
+	if err != nil {
+		s.mapError(w, err, "Failed to join")
+		return
+	}
+	s.jsonResponse(w, state, http.StatusOK)
+}
+
+// handlePresenceLeave records that the session in ?session= closed a file, releasing its lock
+func (s *Server) handlePresenceLeave(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	p, ok := s.presencePath(w, r)
+	if !ok {
+		return
+	}
+	s.jsonResponse(w, s.presence.Leave(p, r.URL.Query().Get("session")), http.StatusOK)
+}
+
+// handleLockAcquire takes the advisory lock of a file for the session in ?session=, or renews it.
+// It lasts editor.lock_ttl seconds, or the shorter ttl of the body.
+func (s *Server) handleLockAcquire(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	p, ok := s.presencePath(w, r)
+	if !ok {
+		return
+	}
+	var req lockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.TTL < 0 {
+		s.jsonError(w, http.StatusBadRequest, "ttl can't be negative")
+		return
+	}
+
+	ttl := time.Duration(s.config().Editor.LockTTL) * time.Second
+	if req.TTL > 0 && (ttl <= 0 || time.Duration(req.TTL)*time.Second < ttl) {
+		ttl = time.Duration(req.TTL) * time.Second
+	}
+	lock, err := s.presence.Acquire(p, r.URL.Query().Get("session"), requestUser(r).Username, ttl)
+	if err != nil {
+		s.mapError(w, err, "Failed to lock")
+		return
+	}
+	s.jsonResponse(w, lock, http.StatusOK)
+}
+
+// handleLockRelease releases the lock the session in ?session= holds on a file. Admins can break
+// another session's lock with ?force=true.
+func (s *Server) handleLockRelease(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	p, ok := s.presencePath(w, r)
+	if !ok {
+		return
+	}
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if force && !isAdmin(r) {
+		s.jsonErrorCode(w, http.StatusForbidden, ErrCodeForbidden, "Only admins can break another session's lock")
+		return
+	}
+	if err := s.presence.Release(p, r.URL.Query().Get("session"), force); err != nil {
+		s.mapError(w, err, "Failed to unlock")
+		return
+	}
+	s.jsonResponse(w, successResponse{Status: StatusSuccess}, http.StatusOK)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// handleRedirects lists the aliases of every page and the URLs they collide on
+func (s *Server) handleRedirects(w http.ResponseWriter, r *http.Request) {
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+	redirects, err := contentSite.Redirects()
+	if err != nil {
+		s.mapError(w, err, "Failed to read content")
+		return
+	}
+	s.jsonResponse(w, redirects, http.StatusOK)
+}
+
+// handleRedirectCreate adds an alias to a page, or to the index file of a bundle directory
+func (s *Server) handleRedirectCreate(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req aliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+	alias, err := s.addAlias(r, contentSite, path, req.Alias)
+	if err != nil {
+		s.mapError(w, err, "Failed to add alias")
+		return
+	}
+	s.jsonResponse(w, alias, http.StatusCreated)
+}
+
+// pageFile returns the content file of a page, or the index file of a bundle directory; "" when
+// the path isn't a page
+func (s *Server) pageFile(contentSite *hugocontent.Site, path string) string {
+	path = filepath.ToSlash(path)
+	if page, err := contentSite.Page(path); err == nil {
+		return page.Path
+	}
+	if bundle, err := contentSite.Bundle(path); err == nil {
+		return bundle.Page.Path
+	}
+	return ""
+}
+
+// pageURL returns the URL of a page, or of the index file of a bundle directory; "" when the path
+// isn't a page
+func (s *Server) pageURL(contentSite *hugocontent.Site, path string) string {
+	file := s.pageFile(contentSite, path)
+	if file == "" {
+		return ""
+	}
+	page, err := contentSite.Page(file)
+	if err != nil {
+		return ""
+	}
+	return page.URL
+}
+
+// addAlias adds an alias to a page and announces the change of its file
+func (s *Server) addAlias(r *http.Request, contentSite *hugocontent.Site, path, alias string) (*hugocontent.Alias, error) {
+	if file := s.pageFile(contentSite, path); file != "" {
+		path = file
+	}
+	before := s.sizeOf(r, path)
+	added, err := contentSite.AddAlias(path, alias)
+	if err != nil {
+		return nil, err
+	}
+	s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": added.Path, "alias": added.URL}, added.Path, before))
+	return added, nil
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// handleFileReferences previews the references in content a rename to ?newName= would rewrite
+func (s *Server) handleFileReferences(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+	newName := r.URL.Query().Get("newName")
+	if newName == "" {
+		s.jsonError(w, http.StatusBadRequest, "newName required")
+		return
+	}
+	newPath := filepath.ToSlash(filepath.Join(filepath.Dir(path), newName))
+	if !s.fileMgr.IsValidPath(newPath) {
+		s.jsonError(w, http.StatusBadRequest, "Invalid path or name")
+		return
+	}
+
+	_, span := tracing.Start(r.Context(), "references.Plan", attribute.String("file.path", path), attribute.String("file.new_path", newPath))
+	plan, err := s.refsMgr.Plan(path, newPath)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to find references")
+		return
+	}
+	s.jsonResponse(w, plan, http.StatusOK)
+}
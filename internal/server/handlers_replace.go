@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+)
+
+// handleContentReplace finds and replaces text across content files. Without confirm it only returns the diffs.
+func (s *Server) handleContentReplace(w http.ResponseWriter, r *http.Request) {
+	var req contentReplaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Confirm && s.readOnly(w) {
+		return
+	}
+
+	result, err := s.replaceMgr.Run(r.Context(), req.Query, req.Confirm)
+	if err != nil {
+		s.mapError(w, err, "Failed to replace")
+		return
+	}
+	if !result.DryRun {
+		for _, file := range result.Files {
+			s.fileChanged(r, webhooks.EventFileSaved, s.withSizes(r, map[string]interface{}{"path": file.Path, "reason": "replace"}, file.Path, nil))
+		}
+	} else {
+		skipActivity(r) // A preview changes nothing
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
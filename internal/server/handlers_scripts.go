@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+)
+
+// handleScripts returns the managed scripts, consent settings and the state of their partials
+func (s *Server) handleScripts(w http.ResponseWriter, r *http.Request) {
+	overview, err := s.scriptsMgr.Overview()
+	if err != nil {
+		s.mapError(w, err, "Failed to read scripts")
+		return
+	}
+	s.jsonResponse(w, overview, http.StatusOK)
+}
+
+// handleScriptsGenerate writes the script and consent partials
+func (s *Server) handleScriptsGenerate(w http.ResponseWriter, r *http.Request) {
+	result, err := s.scriptsMgr.Generate()
+	if err != nil {
+		s.mapError(w, err, "Failed to generate script partials")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleScriptsAudit checks the built site for scripts and embeds that load before consent
+func (s *Server) handleScriptsAudit(w http.ResponseWriter, r *http.Request) {
+	report, err := s.scriptsMgr.Audit()
+	if err != nil {
+		s.mapError(w, err, "Failed to audit scripts")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
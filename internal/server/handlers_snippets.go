@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+)
+
+// handleSnippetImage renders an accessible image snippet, with a caption when one is given
+func (s *Server) handleSnippetImage(w http.ResponseWriter, r *http.Request) {
+	var req snippets.Image
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := s.snippetsGen.Image(req)
+	if err != nil {
+		s.mapError(w, err, "Failed to generate image snippet")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleSnippetGallery renders a named group of images
+func (s *Server) handleSnippetGallery(w http.ResponseWriter, r *http.Request) {
+	var req snippets.Gallery
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := s.snippetsGen.Gallery(req)
+	if err != nil {
+		s.mapError(w, err, "Failed to generate gallery snippet")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
+
+// handleSnippetButton renders a link or action button
+func (s *Server) handleSnippetButton(w http.ResponseWriter, r *http.Request) {
+	var req snippets.Button
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := s.snippetsGen.Button(req)
+	if err != nil {
+		s.mapError(w, err, "Failed to generate button snippet")
+		return
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
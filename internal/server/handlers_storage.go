@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+)
+
+// handleStorageUsage returns the disk usage of the trash, history and cache areas
+func (s *Server) handleStorageUsage(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, storageUsageResponse{
+		Areas:  s.storageMgr.Usage(),
+		LastGC: s.storageMgr.LastReport(),
+	}, http.StatusOK)
+}
+
+// handleStorageGC runs the storage GC immediately and reports the reclaimed space
+func (s *Server) handleStorageGC(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.storageMgr.GC(), http.StatusOK)
+}
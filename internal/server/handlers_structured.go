@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+)
+
+// structuredDataPath reads and validates the content path of a structured data request
+func (s *Server) structuredDataPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	path := s.getURLParam(r, "path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return "", false
+	}
+	if !s.fileMgr.IsValidPath(path) {
+		s.mapError(w, files.ErrInvalidPath, path)
+		return "", false
+	}
+	if !s.fileMgr.Exists(path) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotFound, path), path)
+		return "", false
+	}
+	return path, true
+}
+
+// handleStructuredData previews the JSON-LD generated from a page's front matter and its validation issues
+func (s *Server) handleStructuredData(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.structuredDataPath(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := s.structMgr.Page(path, r.URL.Query().Get("type"))
+	if err != nil {
+		s.mapError(w, err, "Failed to generate structured data")
+		return
+	}
+	s.jsonResponse(w, page, http.StatusOK)
+}
+
+// handleStructuredDataPut writes the JSON-LD generated from a page's front matter into the page
+func (s *Server) handleStructuredDataPut(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.structuredDataPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req structuredDataRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	page, err := s.structMgr.Apply(path, req.Type)
+	if err != nil {
+		s.mapError(w, err, "Failed to write structured data")
+		return
+	}
+	s.jsonResponse(w, page, http.StatusOK)
+}
+
+// handleStructuredDataReport lists pages whose section expects structured data they lack or fail
+func (s *Server) handleStructuredDataReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.structMgr.Report()
+	if err != nil {
+		s.mapError(w, err, "Failed to build structured data report")
+		return
+	}
+	s.jsonResponse(w, report, http.StatusOK)
+}
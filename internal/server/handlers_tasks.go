@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+	"github.com/fernandezvara/hugo-manager/internal/tasks"
+	"github.com/go-chi/chi/v5"
+)
+
+// taskStreamLine is a line of the NDJSON stream of a task run
+type taskStreamLine struct {
+	Type        string     `json:"type"` // start, output, then exit, or running when the stream ends first
+	*tasks.Line            // Output lines only
+	Run         *tasks.Run `json:"run,omitempty"`
+}
+
+// handleTasks lists the configured tasks with the status of their last runs
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.tasksMgr.List(), http.StatusOK)
+}
+
+// handleTask returns a task with the output of its last run
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	task, err := s.tasksMgr.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		s.mapError(w, err, "Failed to read task")
+		return
+	}
+	s.jsonResponse(w, task, http.StatusOK)
+}
+
+// handleTaskRun runs a task and streams its output as NDJSON, ending with its exit status. The
+// task keeps running when the client goes away or the request times out; its last run tells how
+// it ended.
+func (s *Server) handleTaskRun(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	e, err := s.tasksMgr.Start(name)
+	if err != nil {
+		s.mapError(w, err, "Failed to run task")
+		return
+	}
+
+	run := e.Result()
+	job := map[string]interface{}{"id": run.ID, "kind": "task", "task": name}
+	s.hub.Publish(realtime.TopicJobs, "job.started", job)
+	go func() {
+		<-e.Done()
+		run := e.Result()
+		job["status"], job["exitCode"], job["durationMs"] = run.Status, run.ExitCode, run.DurationMs
+		if run.Status != tasks.StatusSucceeded {
+			job["error"] = run.Error
+			s.hub.Publish(realtime.TopicJobs, "job.failed", job)
+			return
+		}
+		s.hub.Publish(realtime.TopicJobs, "job.finished", job)
+	}()
+
+	// Tasks run longer than the write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	send := func(line taskStreamLine) {
+		_ = enc.Encode(line)
+		_ = rc.Flush()
+	}
+	send(taskStreamLine{Type: "start", Run: &run})
+	e.Follow(r.Context(), func(line tasks.Line) {
+		send(taskStreamLine{Type: "output", Line: &line})
+	})
+
+	run = e.Result()
+	end := "exit"
+	if run.Status == tasks.StatusRunning {
+		end = "running"
+	}
+	send(taskStreamLine{Type: end, Run: &run})
+}
+
+// handleTaskStop stops a running task and returns how its run ended
+func (s *Server) handleTaskStop(w http.ResponseWriter, r *http.Request) {
+	run, err := s.tasksMgr.Stop(chi.URLParam(r, "name"))
+	if err != nil {
+		s.mapError(w, err, "Failed to stop task")
+		return
+	}
+	s.jsonResponse(w, run, http.StatusOK)
+}
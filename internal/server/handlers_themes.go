@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/internal/themes"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// themeInstallTimeout caps a git clone or hugo mod get of a theme
+const themeInstallTimeout = 5 * time.Minute
+
+// themeActivateRequest names the theme to switch to
+type themeActivateRequest struct {
+	Name string `json:"name"`
+}
+
+// themeActivateResponse is the theme switched to, with the configuration file changed
+type themeActivateResponse struct {
+	Theme *themes.Theme `json:"theme"`
+	File  string        `json:"file"`
+}
+
+// handleThemes lists the themes in themes/ and the project's Hugo Modules, active ones first
+func (s *Server) handleThemes(w http.ResponseWriter, r *http.Request) {
+	list, err := s.themesMgr.List()
+	if err != nil {
+		s.mapError(w, err, "Failed to list themes")
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleThemeInspect returns a theme with its shortcodes, templates and default params
+func (s *Server) handleThemeInspect(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		s.jsonError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	in, err := s.themesMgr.Inspect(name)
+	if err != nil {
+		s.mapError(w, err, "Failed to inspect theme")
+		return
+	}
+	s.jsonResponse(w, in, http.StatusOK)
+}
+
+// handleThemeActivate switches the theme setting of the site configuration to an installed theme
+func (s *Server) handleThemeActivate(w http.ResponseWriter, r *http.Request) {
+	var req themeActivateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.jsonError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	file, err := s.themesMgr.Activate(req.Name)
+	if err != nil {
+		s.mapError(w, err, "Failed to switch theme")
+		return
+	}
+	s.fileChanged(r, webhooks.EventFileSaved, map[string]interface{}{"path": file, "reason": "theme"})
+
+	theme, err := s.themesMgr.Get(req.Name)
+	if err != nil {
+		s.mapError(w, err, "Failed to read theme")
+		return
+	}
+	s.jsonResponse(w, themeActivateResponse{Theme: theme, File: file}, http.StatusOK)
+}
+
+// handleThemeInstall adds a theme from a git repository as a submodule, a clone or a Hugo Module
+func (s *Server) handleThemeInstall(w http.ResponseWriter, r *http.Request) {
+	var opts themes.InstallOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if opts.URL == "" {
+		s.jsonError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	// Cloning takes longer than the write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	ctx, cancel := context.WithTimeout(r.Context(), themeInstallTimeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "themes.Install", attribute.String("theme.url", opts.URL), attribute.String("theme.method", opts.Method))
+	theme, err := s.themesMgr.Install(ctx, opts)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to install theme")
+		return
+	}
+	if theme.Source == themes.SourceFolder {
+		s.fileChanged(r, webhooks.EventFileCreated, map[string]interface{}{"path": theme.Dir, "reason": "theme"})
+	}
+	if opts.Activate {
+		if siteCfg, err := site.Load(s.projectDir); err == nil {
+			s.fileChanged(r, webhooks.EventFileSaved, map[string]interface{}{"path": siteCfg.File, "reason": "theme"})
+		}
+	}
+	s.jsonResponse(w, theme, http.StatusCreated)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/tracing"
+	"github.com/fernandezvara/hugo-manager/internal/transfer"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// exportRequest selects the sections and pages of an export
+type exportRequest struct {
+	Paths []string `json:"paths"` // Project-relative sections (folders) and pages under a content folder
+}
+
+// handleExport streams a zip of selected sections and pages with the static files they point to,
+// and a manifest another instance imports with /api/import/archive
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r) // Nothing in the project changes
+
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	for _, p := range req.Paths {
+		if !s.fileMgr.IsValidPath(p) {
+			s.mapError(w, files.ErrInvalidPath, p)
+			return
+		}
+	}
+
+	// Problems are reported before the archive starts, as an error can't be sent once it streams
+	_, span := tracing.Start(r.Context(), "transfer.Export", attribute.String("export.paths", strings.Join(req.Paths, ",")))
+	manifest, err := s.transferMgr.Export(req.Paths)
+	tracing.End(span, err)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.jsonError(w, http.StatusNotFound, "Path not found")
+			return
+		}
+		s.mapError(w, err, "Failed to export")
+		return
+	}
+
+	name := "export-" + manifest.CreatedAt.Format("20060102-150405") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+
+	// Large exports take longer than the write timeout to stream
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	if err := s.transferMgr.WriteArchive(w, manifest); err != nil {
+		slog.WarnContext(r.Context(), "Export failed", "paths", req.Paths, "error", err)
+	}
+}
+
+// handleImportArchive writes the files of an archive made by /api/export on this or another
+// instance, after checking them against its manifest
+func (s *Server) handleImportArchive(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to parse form: "+err.Error())
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportSize+1))
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Failed to read the archive: "+err.Error())
+		return
+	}
+	if len(data) > maxImportSize {
+		s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, "Archive is larger than 200 MB; export fewer sections at a time")
+		return
+	}
+
+	var opts transfer.ImportOptions
+	opts.Overwrite, _ = strconv.ParseBool(r.FormValue("overwrite"))
+
+	_, span := tracing.Start(r.Context(), "transfer.Import", attribute.Int("import.size", len(data)))
+	result, err := s.transferMgr.Import(data, opts)
+	tracing.End(span, err)
+	if err != nil {
+		s.mapError(w, err, "Failed to import the archive")
+		return
+	}
+
+	for _, f := range result.Files {
+		event := webhooks.EventFileCreated
+		switch f.Status {
+		case transfer.StatusUnchanged, transfer.StatusSkipped:
+			continue
+		case transfer.StatusReplaced:
+			event = webhooks.EventFileSaved
+		}
+		s.fileChanged(r, event, s.withSizes(r, map[string]interface{}{"path": f.Path, "reason": "import"}, f.Path, nil))
+	}
+	s.jsonResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// contentSite opens the project's content, answering the request itself when it can't
+func (s *Server) contentSite(w http.ResponseWriter) (*hugocontent.Site, bool) {
+	contentSite, err := hugocontent.Open(s.projectDir)
+	if err != nil {
+		s.jsonError(w, http.StatusInternalServerError, "Failed to load site config: "+err.Error())
+		return nil, false
+	}
+	return contentSite, true
+}
+
+// handleContentLanguages returns the languages of the site and where the content of each lives
+func (s *Server) handleContentLanguages(w http.ResponseWriter, r *http.Request) {
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+	languages := contentSite.Languages()
+	s.jsonResponse(w, &languagesResponse{
+		Multilingual:    len(languages) > 1,
+		DefaultLanguage: contentSite.DefaultLanguage(),
+		Languages:       languages,
+	}, http.StatusOK)
+}
+
+// handleTranslationCoverage returns how much of each section is translated into each language
+func (s *Server) handleTranslationCoverage(w http.ResponseWriter, r *http.Request) {
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+	coverage, err := contentSite.Coverage()
+	if err != nil {
+		s.mapError(w, err, "Failed to read content")
+		return
+	}
+	s.jsonResponse(w, coverage, http.StatusOK)
+}
+
+// handleTranslations lists a page in every language, with the path of each missing translation
+func (s *Server) handleTranslations(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+
+	translations, err := contentSite.Translations(path)
+	if err != nil {
+		s.mapError(w, err, "Failed to list translations")
+		return
+	}
+	s.jsonResponse(w, translations, http.StatusOK)
+}
+
+// handleTranslationCreate creates the missing translation of a page as a draft copy of it
+func (s *Server) handleTranslationCreate(w http.ResponseWriter, r *http.Request) {
+	path, ok := s.contentPath(w, r)
+	if !ok {
+		return
+	}
+	contentSite, ok := s.contentSite(w)
+	if !ok {
+		return
+	}
+
+	page, err := contentSite.Translate(path, s.getURLParam(r, "lang"))
+	if err != nil {
+		s.mapError(w, err, "Failed to create translation")
+		return
+	}
+	s.fileChanged(r, webhooks.EventFileCreated, s.withSizes(r, map[string]interface{}{"path": page.Path, "translationOf": path}, page.Path, nil))
+	s.jsonResponse(w, page, http.StatusCreated)
+}
+
+// contentPath reads and validates the path of a request on an existing content file
+func (s *Server) contentPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	path := s.getURLParam(r, "path")
+	if path == "" {
+		s.jsonError(w, http.StatusBadRequest, "Path required")
+		return "", false
+	}
+	if !s.fileMgr.IsValidPath(path) {
+		s.mapError(w, files.ErrInvalidPath, path)
+		return "", false
+	}
+	if !s.fileMgr.Exists(path) {
+		s.mapError(w, fmt.Errorf("%w: %s", files.ErrNotFound, path), path)
+		return "", false
+	}
+	return path, true
+}
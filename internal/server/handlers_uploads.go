@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleUploads lists unfinished uploads, so a client can resume one it lost track of
+func (s *Server) handleUploads(w http.ResponseWriter, r *http.Request) {
+	list, err := s.uploadsMgr.List()
+	if err != nil {
+		s.mapError(w, err, "Failed to list uploads")
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleUploadCreate starts a resumable upload
+func (s *Server) handleUploadCreate(w http.ResponseWriter, r *http.Request) {
+	var req uploadCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Path == "" || !s.fileMgr.IsValidPath(req.Path) {
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+		return
+	}
+	if err := checkAdminPaths(r, req.Path); err != nil {
+		s.mapError(w, err, "Failed to start upload")
+		return
+	}
+
+	upload, err := s.uploadsMgr.Create(req.Path, req.Size, req.SHA256, req.Overwrite)
+	if err != nil {
+		s.mapError(w, err, "Failed to start upload")
+		return
+	}
+	s.jsonResponse(w, upload, http.StatusCreated)
+}
+
+// handleUpload returns an upload with the offset to resume it from
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := s.uploadsMgr.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		s.mapError(w, err, "Failed to get upload")
+		return
+	}
+	s.jsonResponse(w, upload, http.StatusOK)
+}
+
+// handleUploadChunk appends the request body to an upload at ?offset=, the upload's current offset
+func (s *Server) handleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		s.jsonError(w, http.StatusBadRequest, "offset must be the byte the chunk starts at")
+		return
+	}
+
+	upload, err := s.uploadsMgr.Write(chi.URLParam(r, "id"), offset, r.Body)
+	if err != nil {
+		s.mapError(w, err, "Failed to write chunk")
+		return
+	}
+	s.jsonResponse(w, upload, http.StatusOK)
+}
+
+// handleUploadComplete verifies an upload's checksum and places the file in the project
+func (s *Server) handleUploadComplete(w http.ResponseWriter, r *http.Request) {
+	upload, err := s.uploadsMgr.Complete(chi.URLParam(r, "id"))
+	if err != nil {
+		s.mapError(w, err, "Failed to complete upload")
+		return
+	}
+
+	s.fileChanged(r, webhooks.EventFileCreated, map[string]interface{}{"path": upload.Path, "size": upload.Size})
+	s.jsonResponse(w, &fileUploadResponse{
+		Message:  "File uploaded successfully",
+		Filename: path.Base(upload.Path),
+		Path:     upload.Path,
+		Size:     upload.Size,
+	}, http.StatusOK)
+}
+
+// handleUploadAbort discards an upload
+func (s *Server) handleUploadAbort(w http.ResponseWriter, r *http.Request) {
+	if err := s.uploadsMgr.Abort(chi.URLParam(r, "id")); err != nil {
+		s.mapError(w, err, "Failed to abort upload")
+		return
+	}
+	s.jsonResponse(w, &successResponse{Status: StatusDeleted}, http.StatusOK)
+}
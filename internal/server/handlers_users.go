@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/users"
+)
+
+// handleLogin checks a user's password and sets the session cookie the UI signs in with
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	id, user, err := s.usersStore.Login(req.Username, req.Password)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed login", "username", req.Username, "remote", r.RemoteAddr)
+		s.mapError(w, err, "Failed to sign in")
+		return
+	}
+
+	expires := time.Now().Add(s.usersStore.SessionTTL())
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	slog.InfoContext(r.Context(), "User signed in", "username", user.Username)
+	s.jsonResponse(w, &loginResponse{User: user, Expires: expires}, http.StatusOK)
+}
+
+// handleLogout ends the session of the session cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		s.usersStore.Logout(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	s.jsonResponse(w, &successResponse{Status: StatusSuccess}, http.StatusOK)
+}
+
+// handleMe returns who made the request, so the UI can show the user and hide what its role can't do
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, &authMeResponse{User: requestUser(r), AuthEnabled: s.config().Server.EnableAuth}, http.StatusOK)
+}
+
+// handleUsers lists the users
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, s.usersStore.List(), http.StatusOK)
+}
+
+// handleUserCreate adds a user, issuing its API token when asked; the token is only shown here
+func (s *Server) handleUserCreate(w http.ResponseWriter, r *http.Request) {
+	var req userCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Password == "" && !req.Token {
+		s.jsonError(w, http.StatusBadRequest, "A user needs a password, a token or both")
+		return
+	}
+
+	user, err := s.usersStore.Create(users.User{Username: req.Username, Name: req.Name, Email: req.Email, Role: req.Role}, req.Password)
+	if err != nil {
+		s.mapError(w, err, "Failed to create user")
+		return
+	}
+	resp := &userResponse{User: user}
+	if req.Token {
+		if resp.Token, err = s.usersStore.NewToken(user.Username); err != nil {
+			s.mapError(w, err, "Failed to issue token")
+			return
+		}
+		resp.User.HasToken = true
+	}
+	slog.InfoContext(r.Context(), "User created", "username", user.Username, "role", user.Role, "by", requestUser(r).Username)
+	s.jsonResponse(w, resp, http.StatusCreated)
+}
+
+// handleUserUpdate changes a user's name, email, role or password
+func (s *Server) handleUserUpdate(w http.ResponseWriter, r *http.Request) {
+	var req users.Update
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := s.usersStore.Update(s.getURLParam(r, "username"), req)
+	if err != nil {
+		s.mapError(w, err, "Failed to update user")
+		return
+	}
+	s.jsonResponse(w, &userResponse{User: user}, http.StatusOK)
+}
+
+// handleUserDelete removes a user
+func (s *Server) handleUserDelete(w http.ResponseWriter, r *http.Request) {
+	username := s.getURLParam(r, "username")
+	if err := s.usersStore.Delete(username); err != nil {
+		s.mapError(w, err, "Failed to delete user")
+		return
+	}
+	slog.InfoContext(r.Context(), "User deleted", "username", username, "by", requestUser(r).Username)
+	s.jsonResponse(w, &successResponse{Status: StatusDeleted}, http.StatusOK)
+}
+
+// handleUserToken issues a new API token for a user, revoking the previous one
+func (s *Server) handleUserToken(w http.ResponseWriter, r *http.Request) {
+	username := s.getURLParam(r, "username")
+	token, err := s.usersStore.NewToken(username)
+	if err != nil {
+		s.mapError(w, err, "Failed to issue token")
+		return
+	}
+	user, err := s.usersStore.Get(username)
+	if err != nil {
+		s.mapError(w, err, "Failed to issue token")
+		return
+	}
+	s.jsonResponse(w, &userResponse{User: user, Token: token}, http.StatusOK)
+}
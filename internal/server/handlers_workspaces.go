@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/internal/workspaces"
+	"github.com/go-chi/chi/v5"
+)
+
+// workspaceKey is the context key of the workspace a file request works in
+type workspaceKey struct{}
+
+// requestWorkspace is the workspace a file request works in, and a file manager of its copy of the site
+type requestWorkspace struct {
+	name  string
+	dir   string
+	files *files.Manager
+}
+
+// workspaceMiddleware points the file routes at the copy of the site of the workspace in
+// ?workspace=, so reads and writes stay out of the live tree
+func (s *Server) workspaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("workspace")
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		dir, err := s.workspacesMgr.Dir(name)
+		if err != nil {
+			s.mapError(w, err, "Failed to open workspace")
+			return
+		}
+		ws := &requestWorkspace{name: name, dir: dir, files: files.NewManager(dir, s.config().FileTree)}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workspaceKey{}, ws)))
+	})
+}
+
+// requestWorkspaceOf returns the workspace a request works in, nil for the live tree
+func requestWorkspaceOf(r *http.Request) *requestWorkspace {
+	ws, _ := r.Context().Value(workspaceKey{}).(*requestWorkspace)
+	return ws
+}
+
+// filesFor returns the file manager of a request: its workspace's, or the live tree's
+func (s *Server) filesFor(r *http.Request) *files.Manager {
+	if ws := requestWorkspaceOf(r); ws != nil {
+		return ws.files
+	}
+	return s.fileMgr
+}
+
+// handleWorkspaces lists the workspaces with the state of their previews
+func (s *Server) handleWorkspaces(w http.ResponseWriter, r *http.Request) {
+	list, err := s.workspacesMgr.List()
+	if err != nil {
+		s.mapError(w, err, "Failed to list workspaces")
+		return
+	}
+	s.jsonResponse(w, list, http.StatusOK)
+}
+
+// handleWorkspaceCreate copies the site into a new workspace
+func (s *Server) handleWorkspaceCreate(w http.ResponseWriter, r *http.Request) {
+	var req workspaceCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	ws, err := s.workspacesMgr.Create(r.Context(), req.Name, requestUser(r).Username)
+	if err != nil {
+		s.mapError(w, err, "Failed to create workspace")
+		return
+	}
+	s.recordActivity(r, "workspace.created", map[string]interface{}{"workspace": ws.Name})
+	s.jsonResponse(w, ws, http.StatusCreated)
+}
+
+// handleWorkspace returns a workspace with the files changed in it
+func (s *Server) handleWorkspace(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	ws, err := s.workspacesMgr.Get(name)
+	if err != nil {
+		s.mapError(w, err, "Failed to get workspace")
+		return
+	}
+	changes, err := s.workspacesMgr.Changes(r.Context(), name)
+	if err != nil {
+		s.mapError(w, err, "Failed to list workspace changes")
+		return
+	}
+	s.jsonResponse(w, workspaceResponse{Workspace: ws, Changes: changes}, http.StatusOK)
+}
+
+// handleWorkspaceDelete discards a workspace and its changes
+func (s *Server) handleWorkspaceDelete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if err := s.workspacesMgr.Delete(name); err != nil {
+		s.mapError(w, err, "Failed to delete workspace")
+		return
+	}
+	s.recordActivity(r, "workspace.deleted", map[string]interface{}{"workspace": name})
+	s.jsonResponse(w, successResponse{Status: StatusSuccess}, http.StatusOK)
+}
+
+// handleWorkspaceMerge writes the changes of a workspace into the live tree and discards the
+// workspace. Files changed live too since the workspace was created are a conflict, unless the
+// body has force. Editors can't merge changes to templates, shortcodes or hugo-manager.yaml.
+func (s *Server) handleWorkspaceMerge(w http.ResponseWriter, r *http.Request) {
+	var req workspaceMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.jsonError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	changes, err := s.workspacesMgr.Changes(r.Context(), name)
+	if err != nil {
+		s.mapError(w, err, "Failed to list workspace changes")
+		return
+	}
+	paths := make([]string, len(changes))
+	for i, c := range changes {
+		paths[i] = c.Path
+	}
+	if err := checkAdminPaths(r, paths...); err != nil {
+		s.mapError(w, err, "Failed to merge workspace")
+		return
+	}
+
+	merged, err := s.workspacesMgr.Merge(r.Context(), name, req.Force)
+	if err != nil {
+		s.mapError(w, err, "Failed to merge workspace")
+		return
+	}
+	for _, c := range merged {
+		event := webhooks.EventFileSaved
+		switch c.Op {
+		case workspaces.OpAdded:
+			event = webhooks.EventFileCreated
+		case workspaces.OpDeleted:
+			event = webhooks.EventFileDeleted
+		}
+		s.fileChanged(r, event, s.withSizes(r, map[string]interface{}{"path": c.Path, "reason": "merge", "workspace": name}, c.Path, nil))
+	}
+	s.jsonResponse(w, workspaceMergeResponse{Merged: merged}, http.StatusOK)
+}
+
+// handleWorkspacePreviewStart starts the Hugo server of a workspace
+func (s *Server) handleWorkspacePreviewStart(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r) // The project doesn't change
+	ws, err := s.workspacesMgr.StartPreview(chi.URLParam(r, "name"))
+	if err != nil {
+		s.mapError(w, err, "Failed to start preview")
+		return
+	}
+	s.jsonResponse(w, ws, http.StatusOK)
+}
+
+// handleWorkspacePreviewStop stops the Hugo server of a workspace
+func (s *Server) handleWorkspacePreviewStop(w http.ResponseWriter, r *http.Request) {
+	skipActivity(r)
+	ws, err := s.workspacesMgr.StopPreview(chi.URLParam(r, "name"))
+	if err != nil {
+		s.mapError(w, err, "Failed to stop preview")
+		return
+	}
+	s.jsonResponse(w, ws, http.StatusOK)
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+)
+
+// wsCommand is sent by clients of /api/ws to change their subscription
+type wsCommand struct {
+	Action string   `json:"action"` // "subscribe" or "unsubscribe"
+	Topics []string `json:"topics"`
+}
+
+// wsTopics is the set of topics a connection receives
+type wsTopics struct {
+	mu     sync.RWMutex
+	topics map[string]bool
+}
+
+func (t *wsTopics) has(topic string) bool {
+	if topic == realtime.TopicSystem {
+		return true
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.topics[topic]
+}
+
+func (t *wsTopics) set(topics []string, on bool) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, topic := range topics {
+		if on {
+			t.topics[topic] = true
+		} else {
+			delete(t.topics, topic)
+		}
+	}
+	current := []string{}
+	for _, topic := range realtime.Topics {
+		if t.topics[topic] {
+			current = append(current, topic)
+		}
+	}
+	return current
+}
+
+// handleWS multiplexes every realtime event on one WebSocket. Clients pick topics with ?topics=logs,status
+// (default: all) and change them by sending {"action": "subscribe"|"unsubscribe", "topics": [...]}.
+// Every message is a realtime.Message; subscribing to status starts with the current status.
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	initial, err := realtime.ParseTopics(r.URL.Query().Get("topics"))
+	if err != nil {
+		s.mapError(w, err, "Invalid topics")
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.WarnContext(r.Context(), "WebSocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	logs := s.hugoMgr.Subscribe()
+	defer s.hugoMgr.Unsubscribe(logs)
+	statuses := s.hugoMgr.SubscribeStatus()
+	defer s.hugoMgr.UnsubscribeStatus(statuses)
+	events := s.hub.Subscribe()
+	defer s.hub.Unsubscribe(events)
+
+	topics := &wsTopics{topics: map[string]bool{}}
+	current := topics.set(initial, true)
+
+	// Commands are read in the background; replies go through the writer below, the only one allowed
+	replies := make(chan realtime.Message, 10)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	reply := func(msg realtime.Message) {
+		select {
+		case replies <- msg:
+		case <-done:
+		}
+	}
+	go func() {
+		defer close(closed)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var cmd wsCommand
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				reply(s.wsReply("error", map[string]interface{}{"error": "Invalid command"}))
+				continue
+			}
+			checked, err := realtime.CheckTopics(cmd.Topics)
+			switch {
+			case err != nil:
+				reply(s.wsReply("error", map[string]interface{}{"error": err.Error()}))
+			case cmd.Action == "subscribe" || cmd.Action == "unsubscribe":
+				subscribed := topics.set(checked, cmd.Action == "subscribe")
+				if cmd.Action == "subscribe" && slices.Contains(checked, realtime.TopicStatus) {
+					reply(s.currentStatus())
+				}
+				reply(s.wsReply("subscribed", map[string]interface{}{"topics": subscribed}))
+			default:
+				reply(s.wsReply("error", map[string]interface{}{"error": "Unknown action " + cmd.Action + " (use subscribe or unsubscribe)"}))
+			}
+		}
+	}()
+
+	send := func(msg realtime.Message) bool {
+		return conn.WriteJSON(msg) == nil
+	}
+	if !send(s.wsReply("subscribed", map[string]interface{}{"topics": current})) {
+		return
+	}
+	if topics.has(realtime.TopicStatus) && !send(s.currentStatus()) {
+		return
+	}
+
+	for {
+		var msg realtime.Message
+		select {
+		case <-closed:
+			return
+		case reply := <-replies:
+			msg = reply
+		case entry, ok := <-logs:
+			if !ok {
+				return
+			}
+			msg = realtime.Message{Topic: realtime.TopicLogs, Type: "log", Time: entry.Time, Data: entry}
+		case event, ok := <-statuses:
+			if !ok {
+				return
+			}
+			msg = realtime.Message{Topic: realtime.TopicStatus, Type: "status", Time: event.Time, Data: event}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			msg = event
+		}
+		if !topics.has(msg.Topic) {
+			continue
+		}
+		if !send(msg) {
+			return
+		}
+	}
+}
+
+// currentStatus returns the Hugo status as a realtime message
+func (s *Server) currentStatus() realtime.Message {
+	status, message := s.hugoMgr.GetStatus()
+	now := time.Now()
+	return realtime.Message{Topic: realtime.TopicStatus, Type: "status", Time: now,
+		Data: hugo.StatusEvent{Type: "status", Time: now, Status: status, Message: message, Port: s.hugoMgr.GetPort()}}
+}
+
+// wsReply returns a system message answering a connection's command
+func (s *Server) wsReply(typ string, data interface{}) realtime.Message {
+	return realtime.Message{Topic: realtime.TopicSystem, Type: typ, Time: time.Now(), Data: data}
+}
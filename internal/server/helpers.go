@@ -1,14 +1,52 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/fernandezvara/hugo-manager/internal/activity"
+	"github.com/fernandezvara/hugo-manager/internal/artifacts"
+	"github.com/fernandezvara/hugo-manager/internal/autosave"
+	"github.com/fernandezvara/hugo-manager/internal/calendar"
+	"github.com/fernandezvara/hugo-manager/internal/catalog"
+	"github.com/fernandezvara/hugo-manager/internal/comments"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/docs"
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/forms"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/importer"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/media"
+	"github.com/fernandezvara/hugo-manager/internal/podcast"
+	"github.com/fernandezvara/hugo-manager/internal/presence"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+	"github.com/fernandezvara/hugo-manager/internal/references"
+	"github.com/fernandezvara/hugo-manager/internal/related"
+	"github.com/fernandezvara/hugo-manager/internal/replace"
+	"github.com/fernandezvara/hugo-manager/internal/scripts"
+	"github.com/fernandezvara/hugo-manager/internal/shortcodes"
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+	"github.com/fernandezvara/hugo-manager/internal/structured"
+	"github.com/fernandezvara/hugo-manager/internal/tasks"
+	"github.com/fernandezvara/hugo-manager/internal/themes"
+	"github.com/fernandezvara/hugo-manager/internal/transfer"
+	"github.com/fernandezvara/hugo-manager/internal/uploads"
+	"github.com/fernandezvara/hugo-manager/internal/users"
+	"github.com/fernandezvara/hugo-manager/internal/workspaces"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -22,26 +60,364 @@ func (s *Server) jsonResponse(w http.ResponseWriter, data interface{}, status in
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		// If encoding fails, we can't send a JSON error response
 		// Log it and send a plain text error
-		s.logError("Failed to encode JSON response: %v", err)
+		slog.Error("Failed to encode JSON response", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
-// jsonError sends a JSON error response
+// maxEditableSize returns the size in bytes of the largest file the editor opens and saves, 0 for
+// no limit
+func (s *Server) maxEditableSize() int64 {
+	return int64(s.config().Editor.MaxFileSizeMB) << 20
+}
+
+// checkEditableSize answers 413 and reports false when content saved from the editor is over
+// editor.max_file_size_mb
+func (s *Server) checkEditableSize(w http.ResponseWriter, path, content string) bool {
+	if limit := s.maxEditableSize(); limit > 0 && int64(len(content)) > limit {
+		s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge,
+			fmt.Sprintf("%s: larger than editor.max_file_size_mb (%d MB)", path, s.config().Editor.MaxFileSizeMB))
+		return false
+	}
+	return true
+}
+
+// notModified sets the ETag of a response and reports whether the request's If-None-Match
+// already has it, in which case it answers 304 Not Modified
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	etag = `"` + etag + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache") // Cache, but revalidate every time
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// jsonError sends a JSON error response with an error code derived from the HTTP status
 func (s *Server) jsonError(w http.ResponseWriter, code int, detail string) {
+	s.jsonErrorCode(w, code, errorCodeForStatus(code), detail)
+}
+
+// jsonErrorCode sends a JSON error response with an explicit machine-readable error code
+func (s *Server) jsonErrorCode(w http.ResponseWriter, code int, errCode, detail string) {
 	errorResp := errorResponse{
-		Code:   code,
-		Detail: detail,
+		Code:      code,
+		ErrorCode: errCode,
+		Detail:    detail,
+	}
+	if w.Header().Get("Deprecation") != "" { // Set by legacyAPIMiddleware
+		errorResp.Error = detail
 	}
 	s.jsonResponse(w, errorResp, code)
 }
 
+// fileChanged records a file changed through the API in the activity log, and notifies webhooks
+// and realtime subscribers of it and of who changed it. Changes in a workspace don't reach the
+// site, so webhooks only hear of them once the workspace is merged.
+func (s *Server) fileChanged(r *http.Request, event string, data map[string]interface{}) {
+	if ws := requestWorkspaceOf(r); ws != nil {
+		data["workspace"] = ws.name
+		s.recordActivity(r, event, data)
+		s.hub.Publish(realtime.TopicFiles, event, data)
+		return
+	}
+	s.recordActivity(r, event, data)
+	s.webhooks.Dispatch(event, data)
+	s.hub.Publish(realtime.TopicFiles, event, data)
+}
+
+// recordActivity adds a file change to the activity log, attributed to the user who made the
+// request. The user is added to data as well. The path comes from data's path or folder, and the
+// sizes from its oldSize and size.
+func (s *Server) recordActivity(r *http.Request, event string, data map[string]interface{}) {
+	entry := activity.Entry{Operation: event}
+	for _, key := range []string{"path", "folder"} {
+		if p, ok := data[key].(string); ok && entry.Path == "" {
+			entry.Path = p
+		}
+	}
+	entry.OldSize, entry.NewSize = sizeValue(data["oldSize"]), sizeValue(data["size"])
+	for key, value := range data {
+		switch key {
+		case "path", "oldSize", "size":
+		default:
+			if entry.Data == nil {
+				entry.Data = map[string]interface{}{}
+			}
+			entry.Data[key] = value
+		}
+	}
+	s.logActivity(r, entry)
+
+	if user := requestUser(r); user.Username != "" {
+		data["user"] = user.Username
+	}
+
+	if record, ok := r.Context().Value(activityKey{}).(*activityRecord); ok {
+		record.recorded = true
+	}
+}
+
+// skipActivity marks a mutating request that changed nothing, such as a dry run, so it gets no entry
+func skipActivity(r *http.Request) {
+	if record, ok := r.Context().Value(activityKey{}).(*activityRecord); ok {
+		record.recorded = true
+	}
+}
+
+// logActivity records an entry with the user who made the request. A failure is only logged, as
+// the change is already made.
+func (s *Server) logActivity(r *http.Request, entry activity.Entry) {
+	if user := requestUser(r); user.Username != "" {
+		entry.User, entry.Author = user.Username, user.Author()
+	}
+	if err := s.activityLog.Record(entry); err != nil {
+		slog.WarnContext(r.Context(), "Failed to record activity", "operation", entry.Operation, "error", err)
+	}
+}
+
+// sizeOf returns the size of a project file, or of the file in the workspace the request works in,
+// nil when it doesn't exist or is a directory
+func (s *Server) sizeOf(r *http.Request, rel string) *int64 {
+	root := s.projectDir
+	if ws := requestWorkspaceOf(r); ws != nil {
+		root = ws.dir
+	}
+	stat, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil || stat.IsDir() {
+		return nil
+	}
+	size := stat.Size()
+	return &size
+}
+
+// withSizes adds the size of a file before a change, when it existed, and after it, when it still
+// exists, to the data of a file event
+func (s *Server) withSizes(r *http.Request, data map[string]interface{}, rel string, before *int64) map[string]interface{} {
+	if before != nil {
+		data["oldSize"] = *before
+	}
+	if after := s.sizeOf(r, rel); after != nil {
+		data["size"] = *after
+	}
+	return data
+}
+
+// sizeValue converts a size from event data, nil when it isn't one
+func sizeValue(v interface{}) *int64 {
+	var size int64
+	switch n := v.(type) {
+	case int64:
+		size = n
+	case int:
+		size = int64(n)
+	default:
+		return nil
+	}
+	return &size
+}
+
+// mapError maps sentinel errors from the internal packages to an error response
+func (s *Server) mapError(w http.ResponseWriter, err error, detail string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		s.jsonErrorCode(w, http.StatusGatewayTimeout, ErrCodeTimeout, detail+": the request took too long")
+	case errors.Is(err, context.Canceled):
+		// Nobody reads the response, but logs and the activity middleware see the status
+		s.jsonErrorCode(w, StatusClientClosedRequest, ErrCodeCanceled, detail+": the request was cancelled")
+	case errors.Is(err, errAdminOnly):
+		s.jsonErrorCode(w, http.StatusForbidden, ErrCodeForbidden, err.Error())
+	case errors.Is(err, users.ErrBadCredentials):
+		s.jsonErrorCode(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error())
+	case errors.Is(err, users.ErrInvalidUser):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, users.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, users.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, comments.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, comments.ErrInvalidComment):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, comments.ErrOpenComments):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeOpenComments, err.Error())
+	case errors.Is(err, hugo.ErrUnknownInstance):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, workspaces.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, workspaces.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, workspaces.ErrInvalidName):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, workspaces.ErrConflict):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeConflict, err.Error())
+	case errors.Is(err, shortcodes.ErrInvalidName):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid shortcode name")
+	case errors.Is(err, shortcodes.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, shortcodes.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, files.ErrInvalidPath):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeInvalidPath, "Invalid path")
+	case errors.Is(err, files.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, "File or directory does not exist")
+	case errors.Is(err, files.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, "Destination already exists")
+	case errors.Is(err, realtime.ErrUnknownTopic):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, hugo.ErrUnknownProfile):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, hugo.ErrRunning), errors.Is(err, hugo.ErrNotRunning):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeConflict, err.Error())
+	case errors.Is(err, images.ErrInvalidAspect), errors.Is(err, images.ErrNotImage), errors.Is(err, images.ErrUnknownPreset), errors.Is(err, images.ErrInvalidInput):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, images.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, images.ErrTooLarge):
+		s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, err.Error())
+	case errors.Is(err, docs.ErrInvalidVersion):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, docs.ErrVersionNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, docs.ErrVersionExists), errors.Is(err, docs.ErrNotGenerated):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, podcast.ErrNotStatic), errors.Is(err, podcast.ErrUnsupportedAudio):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, importer.ErrUnknownKind), errors.Is(err, importer.ErrInvalidExport), errors.Is(err, importer.ErrNotStatic):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, transfer.ErrNothingSelected), errors.Is(err, transfer.ErrInvalidArchive):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, themes.ErrUnknownTheme):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, themes.ErrThemeExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, themes.ErrInvalidSource), errors.Is(err, themes.ErrNoSiteConfig):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, tasks.ErrUnknownTask):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, tasks.ErrRunning), errors.Is(err, tasks.ErrNotRunning):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeConflict, err.Error())
+	case errors.Is(err, structured.ErrUnknownType), errors.Is(err, structured.ErrNoType):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, catalog.ErrInvalidSKU), errors.Is(err, catalog.ErrInvalidProduct):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, catalog.ErrProductNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, catalog.ErrProductExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, forms.ErrInvalidForm):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, forms.ErrUnknownForm):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, scripts.ErrInvalidScript):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, scripts.ErrNotBuilt):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, artifacts.ErrNoBuild):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, autosave.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, autosave.ErrInvalidDraft):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, lint.ErrInvalidWord):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, snippets.ErrInvalidSnippet):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, drafts.ErrInvalidAction), errors.Is(err, drafts.ErrInvalidCriteria):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, related.ErrNotContent):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, calendar.ErrInvalidRange), errors.Is(err, calendar.ErrInvalidReschedule):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, replace.ErrInvalidQuery):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, uploads.ErrInvalidUpload):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, uploads.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, uploads.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, uploads.ErrTooLarge):
+		s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, err.Error())
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeOffsetMismatch, err.Error())
+	case errors.Is(err, uploads.ErrTypeNotAllowed):
+		s.jsonErrorCode(w, http.StatusUnsupportedMediaType, ErrCodeTypeNotAllowed, err.Error())
+	case errors.Is(err, uploads.ErrTypeMismatch):
+		s.jsonErrorCode(w, http.StatusUnsupportedMediaType, ErrCodeTypeMismatch, err.Error())
+	case errors.Is(err, uploads.ErrChecksumMismatch):
+		s.jsonErrorCode(w, http.StatusUnprocessableEntity, ErrCodeChecksumMismatch, err.Error())
+	case errors.Is(err, hugocontent.ErrNotContent), errors.Is(err, hugocontent.ErrUnknownLanguage):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, hugocontent.ErrTranslationExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, hugocontent.ErrInvalidAlias), errors.Is(err, hugocontent.ErrNotBundle):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, references.ErrInvalidMove):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, hugocontent.ErrAliasCollision):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, media.ErrUnsupportedType), errors.Is(err, media.ErrNotMedia), errors.Is(err, media.ErrInvalidName),
+		errors.Is(err, media.ErrUnreadable):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, media.ErrNotFound):
+		s.jsonErrorCode(w, http.StatusNotFound, ErrCodeNotFound, err.Error())
+	case errors.Is(err, media.ErrExists):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeExists, err.Error())
+	case errors.Is(err, media.ErrTooLarge):
+		s.jsonErrorCode(w, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, err.Error())
+	case errors.Is(err, media.ErrNoFFmpeg):
+		s.jsonErrorCode(w, http.StatusNotImplemented, ErrCodeUnavailable, err.Error())
+	case errors.Is(err, files.ErrNotDir):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	case errors.Is(err, files.ErrNotEmpty):
+		s.jsonErrorCode(w, http.StatusConflict, ErrCodeNotEmpty, "Directory not empty")
+	case errors.Is(err, files.ErrBinary):
+		s.jsonErrorCode(w, http.StatusUnsupportedMediaType, ErrCodeBinary, err.Error())
+	case errors.Is(err, presence.ErrLocked):
+		s.jsonErrorCode(w, http.StatusLocked, ErrCodeLocked, err.Error())
+	case errors.Is(err, presence.ErrInvalidSession):
+		s.jsonErrorCode(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
+	default:
+		s.jsonErrorCode(w, http.StatusInternalServerError, ErrCodeInternal, detail+": "+err.Error())
+	}
+}
+
+// errorCodeForStatus returns the default error code for an HTTP status
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeExists
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodeTooLarge
+	case http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	default:
+		return ErrCodeInternal
+	}
+}
+
 // Response structs
 
 // errorResponse represents a JSON error response
 type errorResponse struct {
-	Code   int    `json:"code"`
-	Detail string `json:"detail"`
+	Code      int    `json:"code"`
+	ErrorCode string `json:"errorCode"`
+	Detail    string `json:"detail"`
+	Error     string `json:"error,omitempty"` // Detail again, only on the deprecated /api routes
 }
 
 // successResponse represents a generic success response
@@ -51,14 +427,24 @@ type successResponse struct {
 
 // fileCreateResponse represents the response for file creation
 type fileCreateResponse struct {
-	Path   string `json:"path"`
-	Status string `json:"status"`
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Template string `json:"template,omitempty"` // Template the file was created from
 }
 
 // fileUpdateResponse represents the response for file updates
 type fileUpdateResponse struct {
-	Path   string `json:"path"`
-	Status string `json:"status"`
+	Path     string             `json:"path"`
+	Status   string             `json:"status"`
+	Alias    *hugocontent.Alias `json:"alias,omitempty"`    // Added on rename with addAlias
+	Updated  []string           `json:"updated,omitempty"`  // Files whose references were rewritten on rename
+	Warnings []string           `json:"warnings,omitempty"` // Why a requested alias or rewrite wasn't done, or front matter problems
+	Stats    *hugocontent.Stats `json:"stats,omitempty"`    // Of a saved markdown file, as in its info
+}
+
+// aliasRequest represents an alias to add to a page
+type aliasRequest struct {
+	Alias string `json:"alias"`
 }
 
 // fileDeleteResponse represents the response for file deletion
@@ -73,6 +459,361 @@ type directoryCreateResponse struct {
 	Status string `json:"status"`
 }
 
+// draftSaveRequest is the unsaved content of a file, kept as a draft
+type draftSaveRequest struct {
+	Content string `json:"content"`
+}
+
+// fileGetResponse represents the response for reading a file
+type fileGetResponse struct {
+	Content  string          `json:"content"` // Empty for a binary file or one over editor.max_file_size_mb
+	IsBinary bool            `json:"isBinary"`
+	TooLarge bool            `json:"tooLarge"`
+	Size     int64           `json:"size"`
+	Info     *files.FileInfo `json:"info"`
+	Draft    *autosave.Draft `json:"draft,omitempty"` // Unsaved content the requester left in the editor, to offer to restore
+}
+
+// fileUploadResponse represents the response for a file upload
+type fileUploadResponse struct {
+	Message  string             `json:"message"`
+	Filename string             `json:"filename"`
+	Path     string             `json:"path"`
+	Size     int64              `json:"size"`
+	Files    []fileUploadResult `json:"files,omitempty"` // Every file of the request, in order
+}
+
+// fileUploadResult represents one file of a multi-file upload
+type fileUploadResult struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"` // Why the file wasn't saved
+}
+
+// fileCopyResponse represents the response for a file copy
+type fileCopyResponse struct {
+	Message string `json:"message"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+}
+
+// shortcodeTemplateResponse represents the raw template of a shortcode
+type shortcodeTemplateResponse struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// hugoStatusResponse represents the Hugo server status
+type hugoStatusResponse struct {
+	Status         hugo.Status         `json:"status"`
+	Message        string              `json:"message"`
+	Port           int                 `json:"port"`           // Port Hugo actually runs on
+	ConfiguredPort int                 `json:"configuredPort"` // Differs from port when the configured one was in use
+	Logs           hugo.LogUsage       `json:"logs"`
+	Watchdog       hugo.WatchdogStatus `json:"watchdog"`
+	Options        hugo.Options        `json:"options"` // Content flags and profile Hugo runs with
+}
+
+// hugoOptionsResponse reports the content flags and profile after an update
+type hugoOptionsResponse struct {
+	Options   hugo.Options `json:"options"`
+	Restarted bool         `json:"restarted"` // Hugo was restarted to apply them
+}
+
+// hugoErrorsResponse lists the errors of the current Hugo build
+type hugoErrorsResponse struct {
+	Status hugo.Status     `json:"status"`
+	Count  int             `json:"count"`
+	Errors []hugo.LogEvent `json:"errors"`
+}
+
+// buildErrorEvent is the data of a build.error realtime message: an error Hugo reported in a file
+// of the project
+type buildErrorEvent struct {
+	hugo.LogEvent
+	Instance string `json:"instance,omitempty"` // Unset for the main instance
+}
+
+// permalinkResponse represents the rendered and preview URL of a content file
+type permalinkResponse struct {
+	hugocontent.Permalink
+	PreviewURL string `json:"previewURL"`
+}
+
+// contentTemplateResponse is the template a new content file gets and the front matter it starts with
+type contentTemplateResponse struct {
+	Template string                 `json:"template"` // Empty when no template_paths entry matches
+	Data     map[string]interface{} `json:"data"`
+}
+
+// slugResponse is the slug and file name a new page gets from its title
+type slugResponse struct {
+	Slug     string `json:"slug"`
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	Taken    bool   `json:"taken"` // The title's own slug is used in the folder, so Slug is numbered
+}
+
+// languagesResponse represents the multilingual configuration of the site
+type languagesResponse struct {
+	Multilingual    bool                   `json:"multilingual"`
+	DefaultLanguage string                 `json:"defaultLanguage"`
+	Languages       []hugocontent.Language `json:"languages"`
+}
+
+// mediaUploadResponse represents an uploaded media file and the snippets that embed or link it
+type mediaUploadResponse struct {
+	Item     *media.Item           `json:"item"`
+	Snippet  *media.Snippet        `json:"snippet"`
+	Poster   *images.ProcessResult `json:"poster,omitempty"`   // Poster set of a video uploaded with poster=true
+	Warnings []string              `json:"warnings,omitempty"` // Why a requested poster wasn't made
+}
+
+// mediaPosterRequest represents a request to make the poster set of a video
+type mediaPosterRequest struct {
+	Path  string `json:"path"`
+	Alt   string `json:"alt"`   // Alt text of the poster's image snippets
+	Title string `json:"title"` // Link text of the video snippet
+}
+
+// savePreviewResponse represents a saved content file, its preview URL and the rebuild it triggered
+type savePreviewResponse struct {
+	permalinkResponse
+	Rebuild *hugo.RebuildResult `json:"rebuild"`
+}
+
+// storageUsageResponse represents the disk usage of the .hugo-manager areas and the last GC run
+type storageUsageResponse struct {
+	Areas  []storage.AreaUsage `json:"areas"`
+	LastGC *storage.Report     `json:"lastGC"`
+}
+
+// podcastValidateResponse lists the problems that would get the podcast feed rejected or flagged
+type podcastValidateResponse struct {
+	Valid  bool            `json:"valid"` // No error-level issues
+	Issues []podcast.Issue `json:"issues"`
+}
+
+// catalogValidateResponse lists the problems of the products data file
+type catalogValidateResponse struct {
+	Valid  bool            `json:"valid"` // No error-level issues
+	Issues []catalog.Issue `json:"issues"`
+}
+
+// configValidateResponse lists the problems of a configuration
+type configValidateResponse struct {
+	Valid  bool           `json:"valid"` // No error-level issues
+	Issues []config.Issue `json:"issues"`
+}
+
+// configErrorResponse is the error response to saving a configuration with error-level issues
+type configErrorResponse struct {
+	errorResponse
+	Issues []config.Issue `json:"issues"`
+}
+
+// frontMatterErrorResponse is the error response to saving a page whose front matter doesn't fit
+// its section's schema, when front_matter.on_save is block
+type frontMatterErrorResponse struct {
+	errorResponse
+	Diagnostics []lint.Diagnostic `json:"diagnostics"`
+}
+
+// configSaveResponse is the response to a saved configuration, with its warnings
+type configSaveResponse struct {
+	Status string         `json:"status"`
+	Issues []config.Issue `json:"issues,omitempty"`
+}
+
+// Request structs
+
+// hugoBuildRequest represents a request to build the site with a profile
+type hugoBuildRequest struct {
+	Profile string `json:"profile"` // Empty builds with Hugo's defaults
+}
+
+// hugoBuildResponse is the result of a build with the checks of its output
+type hugoBuildResponse struct {
+	*hugo.BuildResult
+	Checks *artifacts.Checks `json:"checks,omitempty"` // Unset when Hugo failed or the output couldn't be read
+}
+
+// docsVersionCreateRequest represents a request to branch a new docs version from an existing one
+type docsVersionCreateRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Latest bool   `json:"latest"` // Make the new version the latest
+}
+
+// docsIngestRequest represents a request to generate reference pages from an API description
+type docsIngestRequest struct {
+	Source string `json:"source"` // Project-relative OpenAPI or JSON schema file (YAML or JSON)
+	Output string `json:"output"` // Project-relative directory for the generated pages
+	Kind   string `json:"kind"`   // "openapi" or "jsonschema"; detected when empty
+}
+
+// podcastIngestRequest represents a request to read an audio file into an episode page
+type podcastIngestRequest struct {
+	Audio string `json:"audio"` // Project-relative audio file under static/
+	Page  string `json:"page"`  // Episode page to create or update; defaults to the episodes dir
+	Title string `json:"title"` // Episode title (optional)
+}
+
+// structuredDataRequest represents a request to write a page's JSON-LD into its front matter
+type structuredDataRequest struct {
+	Type string `json:"type"` // schema.org type; defaults to the type mapped to the page's section
+}
+
+// draftsCleanupRequest represents a request to archive or delete abandoned drafts
+type draftsCleanupRequest struct {
+	drafts.Criteria
+	Action  string   `json:"action"`  // "archive" or "delete"
+	Paths   []string `json:"paths"`   // Drafts from the stale list; others are skipped
+	Confirm bool     `json:"confirm"` // Without it, only report what would be moved
+}
+
+// calendarRescheduleRequest represents a request to move a page's date on the calendar
+type calendarRescheduleRequest struct {
+	Field string `json:"field"` // date (the default), publishDate or expiryDate
+	Date  string `json:"date"`  // YYYY-MM-DD, keeping the time of day the field had, or RFC 3339
+}
+
+// contentReplaceRequest represents a request to find and replace text across content files
+type contentReplaceRequest struct {
+	replace.Query
+	Confirm bool `json:"confirm"` // Without it, only return the diffs
+}
+
+// uploadCreateRequest represents a request to start a resumable upload
+type uploadCreateRequest struct {
+	Path      string `json:"path"`      // Project-relative destination
+	Size      int64  `json:"size"`      // Total bytes
+	SHA256    string `json:"sha256"`    // Hex checksum of the whole file, verified before it's placed
+	Overwrite bool   `json:"overwrite"` // Replace an existing file
+}
+
+// commentCreateRequest represents a request to comment on a range of lines of a file
+type commentCreateRequest struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"startLine"` // From 1
+	EndLine   int    `json:"endLine"`   // Inclusive; startLine when unset
+	Body      string `json:"body"`
+}
+
+// workspaceCreateRequest represents a request to create a draft workspace
+type workspaceCreateRequest struct {
+	Name string `json:"name"`
+}
+
+// workspaceResponse represents a draft workspace with the files changed in it
+type workspaceResponse struct {
+	*workspaces.Workspace
+	Changes []workspaces.Change `json:"changes"`
+}
+
+// workspaceMergeRequest represents a request to merge a workspace into the live tree
+type workspaceMergeRequest struct {
+	Force bool `json:"force"` // Overwrite the files changed in the live tree too
+}
+
+// workspaceMergeResponse represents the files a merge wrote to the live tree
+type workspaceMergeResponse struct {
+	Merged []workspaces.Change `json:"merged"`
+}
+
+// loginRequest represents a request to sign in to the UI
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginResponse represents a signed-in user and when the session ends
+type loginResponse struct {
+	User    *users.User `json:"user"`
+	Expires time.Time   `json:"expires"`
+}
+
+// authMeResponse represents who made a request
+type authMeResponse struct {
+	User        *users.User `json:"user"` // Without a username when auth is disabled
+	AuthEnabled bool        `json:"authEnabled"`
+}
+
+// userCreateRequest represents a request to add a user
+type userCreateRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`     // admin or editor
+	Password string `json:"password"` // Empty for users that only use an API token
+	Token    bool   `json:"token"`    // Issue an API token
+}
+
+// userResponse represents a user, with its API token when one was just issued
+type userResponse struct {
+	User  *users.User `json:"user"`
+	Token string      `json:"token,omitempty"` // Only shown once
+}
+
+// fileWriteRequest represents a file save or rename request
+type fileWriteRequest struct {
+	Content  string `json:"content"`
+	NewName  string `json:"newName"`
+	AddAlias bool   `json:"addAlias"` // On rename, redirect the page's old URL to its new path
+	// On rename, rewrite the refs, links and images in content that point to the old path
+	RewriteReferences bool `json:"rewriteReferences"`
+}
+
+// contentSaveRequest represents the new content of a file saved for preview
+type contentSaveRequest struct {
+	Content string `json:"content"`
+}
+
+// fileCreateRequest represents a file or directory creation request
+type fileCreateRequest struct {
+	Content  string                 `json:"content"`
+	IsDir    bool                   `json:"isDir"`
+	Template string                 `json:"template"`
+	Data     map[string]interface{} `json:"data"`
+	Slug     string                 `json:"slug,omitempty"` // Written as the slug front matter of a file created from a template
+}
+
+// shortcodeCreateRequest represents a shortcode creation request.
+// If Content is empty, a template is scaffolded from the options.
+type shortcodeCreateRequest struct {
+	shortcodes.ScaffoldOptions
+	Content string `json:"content"`
+}
+
+// shortcodeUpdateRequest represents a shortcode template update
+type shortcodeUpdateRequest struct {
+	Content string `json:"content"`
+}
+
+// lintContentRequest represents unsaved content to lint
+type lintContentRequest struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// markdownFixRequest asks for the markdown problems of a file, or of all content, to be fixed
+type markdownFixRequest struct {
+	Path    string `json:"path"`    // Empty for every markdown file in the content directories
+	Content string `json:"content"` // Unsaved content of path to fix instead; nothing is written
+	Confirm bool   `json:"confirm"` // Without it, only return the corrected content
+}
+
+// dictionaryWordRequest is a word to add to the project word list
+type dictionaryWordRequest struct {
+	Word string `json:"word"`
+}
+
+// dictionaryResponse is the project word list
+type dictionaryResponse struct {
+	Words []string `json:"words"`
+}
+
 // Common status constants
 const (
 	StatusCreated = "created"
@@ -83,24 +824,37 @@ const (
 	StatusError   = "error"
 )
 
-// Logging helpers
-
-// logError logs an error message
-func (s *Server) logError(format string, args ...interface{}) {
-	// In a real implementation, this would use a proper logger
-	// For now, we'll use the standard log package
-	log.Printf("ERROR: "+format, args...)
-}
-
-// logInfo logs an info message
-func (s *Server) logInfo(format string, args ...interface{}) {
-	log.Printf("INFO: "+format, args...)
-}
+// StatusClientClosedRequest is the status of a request the client abandoned before it was answered,
+// as nginx logs it
+const StatusClientClosedRequest = 499
 
-// logDebug logs a debug message
-func (s *Server) logDebug(format string, args ...interface{}) {
-	log.Printf("DEBUG: "+format, args...)
-}
+// Machine-readable error codes included in every error response
+const (
+	ErrCodeExists             = "ERR_EXISTS"
+	ErrCodeNotFound           = "ERR_NOT_FOUND"
+	ErrCodeInvalidPath        = "ERR_INVALID_PATH"
+	ErrCodeNotEmpty           = "ERR_NOT_EMPTY"
+	ErrCodeConflict           = "ERR_CONFLICT"
+	ErrCodeBadRequest         = "ERR_BAD_REQUEST"
+	ErrCodeUnauthorized       = "ERR_UNAUTHORIZED"
+	ErrCodeForbidden          = "ERR_FORBIDDEN"
+	ErrCodeReadOnly           = "ERR_READ_ONLY"
+	ErrCodeFeatureDisabled    = "ERR_FEATURE_DISABLED"
+	ErrCodeTooLarge           = "ERR_TOO_LARGE"
+	ErrCodeOffsetMismatch     = "ERR_OFFSET_MISMATCH"
+	ErrCodeChecksumMismatch   = "ERR_CHECKSUM_MISMATCH"
+	ErrCodeInvalidConfig      = "ERR_INVALID_CONFIG"
+	ErrCodeInvalidFrontMatter = "ERR_INVALID_FRONT_MATTER"
+	ErrCodeUnavailable        = "ERR_UNAVAILABLE"
+	ErrCodeBinary             = "ERR_BINARY"
+	ErrCodeTypeNotAllowed     = "ERR_TYPE_NOT_ALLOWED"
+	ErrCodeTypeMismatch       = "ERR_TYPE_MISMATCH"
+	ErrCodeTimeout            = "ERR_TIMEOUT"
+	ErrCodeCanceled           = "ERR_CANCELED"
+	ErrCodeLocked             = "ERR_LOCKED"
+	ErrCodeOpenComments       = "ERR_OPEN_COMMENTS"
+	ErrCodeInternal           = "ERR_INTERNAL"
+)
 
 // Input validation helpers
 
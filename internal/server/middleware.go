@@ -2,13 +2,29 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
+	"github.com/fernandezvara/hugo-manager/internal/activity"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/users"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // setupMiddleware configures all middleware for the chi router
@@ -16,24 +32,47 @@ func (s *Server) setupMiddleware(r chi.Router) {
 	// Standard chi middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(time.Duration(s.config.Server.Timeout) * time.Second))
+	r.Use(s.tracingMiddleware)
+	r.Use(middleware.Timeout(time.Duration(s.config().Server.Timeout) * time.Second))
 	r.Use(middleware.AllowContentType("application/json", "multipart/form-data", "text/html"))
 
 	// Custom middleware
 	r.Use(s.corsMiddleware)
 	r.Use(s.loggingMiddleware)
+	r.Use(s.readOnlyMiddleware)
 	r.Use(s.requestValidationMiddleware)
 	r.Use(s.rateLimitMiddleware)
 	r.Use(s.contentTypeMiddleware)
+	if s.config().Server.Compression {
+		r.Use(s.compressMiddleware)
+	}
+}
+
+// compressionLevel is the gzip and deflate level of compressed responses, fast with most of the gain
+const compressionLevel = 5
+
+// compressMiddleware compresses JSON and text API responses with gzip or deflate, as the client
+// accepts. WebSocket upgrades are left alone, and so are raw files: most are images or archives
+// compressed already, and they're served with byte ranges of the file as stored.
+func (s *Server) compressMiddleware(next http.Handler) http.Handler {
+	compressed := middleware.Compress(compressionLevel)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := versionedPath(r.URL.Path)
+		if !strings.HasPrefix(p, apiPrefix+"/") || strings.HasPrefix(p, apiPrefix+"/files/raw") ||
+			strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		compressed.ServeHTTP(w, r)
+	})
 }
 
 // corsMiddleware handles CORS headers based on configuration
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers based on configuration
-		origins := s.config.Server.CORSOrigins
+		origins := s.config().Server.CORSOrigins
 		if len(origins) == 0 {
 			origins = []string{"*"}
 		}
@@ -54,13 +93,13 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 
-		methods := s.config.Server.CORSMethods
+		methods := s.config().Server.CORSMethods
 		if len(methods) == 0 {
 			methods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 		}
 		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
 
-		headers := s.config.Server.CORSHeaders
+		headers := s.config().Server.CORSHeaders
 		if len(headers) == 0 {
 			headers = []string{"Content-Type", "Authorization"}
 		}
@@ -75,6 +114,35 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// tracingMiddleware starts a server span per request, continuing any trace propagated by the client
+func (s *Server) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer("github.com/fernandezvara/hugo-manager/internal/server").Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("http.request_id", middleware.GetReqID(r.Context())),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// Name the span after the matched route so traces group by endpoint rather than by path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.Status()))
+		if ww.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(ww.Status()))
+		}
+	})
+}
+
 // loggingMiddleware provides custom request logging
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +156,7 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 
 		// Log request details
 		duration := time.Since(start)
-		s.logInfo("Request completed",
+		slog.InfoContext(r.Context(), "Request completed",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.Status(),
@@ -99,35 +167,318 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// authMiddleware provides authentication for protected routes
+// userKey is the context key of the user who made a request
+type userKey struct{}
+
+// sessionCookie holds the session ID of a user signed in to the UI
+const sessionCookie = "hugo_manager_session"
+
+// anonymous is who makes every request when auth is disabled
+var anonymous = &users.User{Role: config.RoleAdmin}
+
+// authPublic lists API routes that need no token, so users can sign in
+var authPublic = map[string]bool{
+	"/api/v1/auth/login": true,
+}
+
+// authMiddleware identifies who makes API requests when auth is enabled and puts the user in the
+// request context. A token goes in an "Authorization: Bearer" header, or in a token query parameter
+// for WebSocket connections only, which browsers can't add headers to; anywhere else the token would
+// end up in access logs, browser history and Referer headers. The UI uses the session cookie set at
+// login instead.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// For now, we'll implement a simple check
-		// In the future, this could be expanded to support JWT, OAuth, etc.
-
-		// Simple token-based auth for demonstration
-		// This can be configured via environment variables or config in the future
-		token := r.Header.Get("Authorization")
-		if token == "" {
-			// No auth required for now - pass through
+		if !s.config().Server.EnableAuth || r.Method == http.MethodOptions || authPublic[versionedPath(r.URL.Path)] {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, anonymous)))
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && websocket.IsWebSocketUpgrade(r) {
+			token = r.URL.Query().Get("token")
+		}
+		user := s.tokenUser(strings.TrimSpace(token))
+		if user == nil {
+			if cookie, err := r.Cookie(sessionCookie); err == nil {
+				user, _ = s.usersStore.Session(cookie.Value)
+			}
+		}
+		if user == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="hugo-manager"`)
+			s.jsonErrorCode(w, http.StatusUnauthorized, ErrCodeUnauthorized, "A valid auth token or login is required")
+			return
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("auth.user", user.Username), attribute.String("auth.role", user.Role))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// tokenUser returns who an auth token belongs to, nil when it isn't a configured or issued token.
+// Every configured token is compared, in constant time, so the time taken doesn't tell which one
+// is close. Configured tokens stand for users named after them.
+func (s *Server) tokenUser(token string) *users.User {
+	if token == "" {
+		return nil
+	}
+	match := func(configured string) bool {
+		return configured != "" && subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1
+	}
+	var user *users.User
+	if match(s.config().Server.AuthToken) {
+		user = &users.User{Username: "auth_token", Role: config.RoleAdmin}
+	}
+	for _, t := range s.config().Server.Tokens {
+		if match(t.Token) {
+			user = &users.User{Username: t.Name, Role: t.Role}
+		}
+	}
+	if user != nil {
+		return user
+	}
+	user, _ = s.usersStore.Authenticate(token)
+	return user
+}
+
+// requestUser returns who made a request, the anonymous admin when auth is disabled
+func requestUser(r *http.Request) *users.User {
+	if user, ok := r.Context().Value(userKey{}).(*users.User); ok {
+		return user
+	}
+	return anonymous
+}
+
+// isAdmin reports whether the request was made by an admin, or without auth
+func isAdmin(r *http.Request) bool {
+	return requestUser(r).Role == config.RoleAdmin
+}
+
+// requireAdmin rejects requests from editors to routes that change hugo-manager.yaml, templates,
+// shortcodes or deployed builds
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r) {
+			s.jsonErrorCode(w, http.StatusForbidden, ErrCodeForbidden, "This requires the admin role")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminPaths are the project paths only admins can read or change through the file routes:
+// hugo-manager.yaml with its tokens, hugo-manager's own state with the users and their password
+// hashes, and the templates, shortcodes included, of the site and its themes
+var adminPaths = []string{config.ConfigFileName, ".hugo-manager", "layouts", "themes"}
+
+// isAdminPath reports whether a project-relative path is one of adminPaths or inside one
+func isAdminPath(p string) bool {
+	p = strings.ToLower(strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/"))
+	for _, admin := range adminPaths {
+		if p == admin || strings.HasPrefix(p, admin+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// errAdminOnly is returned when an editor's request would change one of adminPaths
+var errAdminOnly = errors.New("only admins can read or change templates, shortcodes, hugo-manager.yaml and .hugo-manager")
+
+// checkAdminPaths returns errAdminOnly when the request isn't an admin's and one of paths is one of
+// adminPaths, for handlers whose target paths are in the request body
+func checkAdminPaths(r *http.Request, paths ...string) error {
+	if isAdmin(r) {
+		return nil
+	}
+	for _, p := range paths {
+		if isAdminPath(p) {
+			return fmt.Errorf("%w: %s", errAdminOnly, p)
+		}
+	}
+	return nil
+}
+
+// requireAdminPath rejects editors' requests for adminPaths through the routes with a {path} parameter
+func (s *Server) requireAdminPath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkAdminPaths(r, s.getURLParam(r, "path")); err != nil {
+			s.mapError(w, err, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// activityKey is the context key of the activityRecord of a mutating API request
+type activityKey struct{}
+
+// activityRecord tracks whether the handler of a request recorded its changes itself
+type activityRecord struct {
+	recorded bool
+}
+
+// activityIgnored lists non-GET API routes that change nothing worth an audit entry
+var activityIgnored = map[string]bool{
+	"/api/v1/auth/login":       true,
+	"/api/v1/auth/logout":      true,
+	"/api/v1/config/validate":  true,
+	"/api/v1/domain/check":     true,
+	"/api/v1/lint/frontmatter": true,
+	"/api/v1/lint/markdown":    true,
+	"/api/v1/lint/prose":       true,
+	"/api/v1/lint/shortcodes":  true,
+}
+
+// activityMiddleware records mutating API requests that succeed in the activity log. Handlers that
+// change files record each change with its path and sizes; any other request gets an entry for its
+// route, so every change made through the API is accounted for.
+func (s *Server) activityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if activityIgnored[strings.TrimSuffix(versionedPath(r.URL.Path), "/")] {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Add user info to context if token is present
-		ctx := context.WithValue(r.Context(), "user", "admin")
-		next.ServeHTTP(w, r.WithContext(ctx))
+		record := &activityRecord{}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		r = r.WithContext(context.WithValue(r.Context(), activityKey{}, record))
+		next.ServeHTTP(ww, r)
+		if record.recorded || ww.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		entry := activity.Entry{Operation: r.Method + " " + versionedPath(r.URL.Path), Status: ww.Status()}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			entry.Operation = r.Method + " " + versionedPath(rctx.RoutePattern())
+			for i, key := range rctx.URLParams.Keys {
+				if key == "*" {
+					continue
+				}
+				if entry.Data == nil {
+					entry.Data = map[string]interface{}{}
+				}
+				entry.Data[key] = rctx.URLParams.Values[i]
+			}
+			if p, err := url.PathUnescape(rctx.URLParam("path")); err == nil {
+				entry.Path = p
+			}
+		}
+		s.logActivity(r, entry)
 	})
 }
 
+// readOnlySafe marks a non-GET route that changes nothing, such as a check, a preview or a
+// download, so it keeps working in read-only mode. It does nothing itself: setupRoutes collects the
+// routes it marks. Routes that write when confirmed check readOnly in their handler.
+func readOnlySafe(next http.Handler) http.Handler {
+	return next
+}
+
+// readOnlySafeRoutes returns the method and pattern of each route marked with readOnlySafe
+func readOnlySafeRoutes(routes chi.Routes) map[string]bool {
+	marker := reflect.ValueOf(readOnlySafe).Pointer()
+	safe := map[string]bool{}
+	chi.Walk(routes, func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		for _, mw := range middlewares {
+			if reflect.ValueOf(mw).Pointer() == marker {
+				safe[method+" "+route] = true
+			}
+		}
+		return nil
+	})
+	return safe
+}
+
+// readOnly answers 403 ERR_READ_ONLY and returns true when the server runs in read-only mode, for
+// read-only-safe routes asked to write
+func (s *Server) readOnly(w http.ResponseWriter) bool {
+	if !s.config().Server.ReadOnly {
+		return false
+	}
+	s.jsonErrorCode(w, http.StatusForbidden, ErrCodeReadOnly,
+		"hugo-manager is running in read-only mode: changes are disabled on this instance")
+	return true
+}
+
+// readOnlyMiddleware rejects mutating API requests when the server runs in read-only mode, except
+// those to routes marked with readOnlySafe
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.config().Server.ReadOnly || !strings.HasPrefix(r.URL.Path, "/api") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Routed as chi routes it, on the escaped path when there is one
+		routePath := r.URL.RawPath
+		if routePath == "" {
+			routePath = r.URL.Path
+		}
+		if s.routes != nil && s.readOnlySafe[r.Method+" "+s.routes.Find(chi.NewRouteContext(), r.Method, routePath)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.readOnly(w)
+	})
+}
+
+// apiPrefix is where the current version of the API is served
+const apiPrefix = "/api/v1"
+
+// versionedPath returns the /api/v1 path of a path of the unversioned, deprecated /api, and any
+// other path as it is
+func versionedPath(p string) string {
+	if rest, ok := strings.CutPrefix(p, "/api/"); ok && rest != "v1" && !strings.HasPrefix(rest, "v1/") {
+		return apiPrefix + "/" + rest
+	}
+	return p
+}
+
+// legacyAPIMiddleware marks the responses of the unversioned /api routes deprecated, linking to
+// their /api/v1 successors. jsonErrorCode then also puts an error's detail under error, the key
+// clients written for the first API read.
+func (s *Server) legacyAPIMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+versionedPath(r.URL.Path)+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireFeature rejects requests to routes whose feature is disabled in configuration
+func (s *Server) requireFeature(name string, enabled func(config.FeaturesConfig) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled(s.config().Features) {
+				s.jsonErrorCode(w, http.StatusForbidden, ErrCodeFeatureDisabled,
+					fmt.Sprintf("The %s feature is disabled on this instance", name))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // requestValidationMiddleware provides request validation based on configuration
 func (s *Server) requestValidationMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Validate request size based on configuration
-		if s.config.Server.MaxRequestSize > 0 {
-			if r.ContentLength > int64(s.config.Server.MaxRequestSize*1024*1024) {
+		if s.config().Server.MaxRequestSize > 0 {
+			if r.ContentLength > int64(s.config().Server.MaxRequestSize*1024*1024) {
 				s.jsonError(w, http.StatusRequestEntityTooLarge,
-					fmt.Sprintf("Request too large. Maximum size is %d MB", s.config.Server.MaxRequestSize))
+					fmt.Sprintf("Request too large. Maximum size is %d MB", s.config().Server.MaxRequestSize))
 				return
 			}
 		}
@@ -145,7 +496,7 @@ func (s *Server) requestValidationMiddleware(next http.Handler) http.Handler {
 // rateLimitMiddleware provides rate limiting (pass-through for now)
 func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement actual rate limiting based on s.config.Server.RateLimit
+		// TODO: Implement actual rate limiting based on s.config().Server.RateLimit
 		// For now, this is a pass-through middleware
 		next.ServeHTTP(w, r)
 	})
@@ -156,7 +507,7 @@ func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only apply to API routes
 		if len(r.URL.Path) >= 4 && r.URL.Path[:4] == "/api" {
-			if strings.HasPrefix(r.URL.Path, "/api/files/raw") {
+			if strings.HasPrefix(versionedPath(r.URL.Path), apiPrefix+"/files/raw") {
 				next.ServeHTTP(w, r)
 				return
 			}
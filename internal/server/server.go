@@ -5,43 +5,209 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/fernandezvara/hugo-manager/internal/activity"
+	"github.com/fernandezvara/hugo-manager/internal/artifacts"
+	"github.com/fernandezvara/hugo-manager/internal/autosave"
+	"github.com/fernandezvara/hugo-manager/internal/calendar"
+	"github.com/fernandezvara/hugo-manager/internal/catalog"
+	"github.com/fernandezvara/hugo-manager/internal/certs"
+	"github.com/fernandezvara/hugo-manager/internal/comments"
 	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/dashboard"
+	"github.com/fernandezvara/hugo-manager/internal/docs"
+	"github.com/fernandezvara/hugo-manager/internal/domain"
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/events"
 	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/forms"
+	"github.com/fernandezvara/hugo-manager/internal/health"
 	"github.com/fernandezvara/hugo-manager/internal/hugo"
 	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/importer"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/media"
+	"github.com/fernandezvara/hugo-manager/internal/podcast"
+	"github.com/fernandezvara/hugo-manager/internal/presence"
+	"github.com/fernandezvara/hugo-manager/internal/realtime"
+	"github.com/fernandezvara/hugo-manager/internal/references"
+	"github.com/fernandezvara/hugo-manager/internal/related"
+	"github.com/fernandezvara/hugo-manager/internal/replace"
+	"github.com/fernandezvara/hugo-manager/internal/scripts"
 	"github.com/fernandezvara/hugo-manager/internal/shortcodes"
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+	"github.com/fernandezvara/hugo-manager/internal/structured"
+	"github.com/fernandezvara/hugo-manager/internal/tasks"
+	"github.com/fernandezvara/hugo-manager/internal/themes"
+	"github.com/fernandezvara/hugo-manager/internal/transfer"
+	"github.com/fernandezvara/hugo-manager/internal/uploads"
+	"github.com/fernandezvara/hugo-manager/internal/users"
+	"github.com/fernandezvara/hugo-manager/internal/webhooks"
+	"github.com/fernandezvara/hugo-manager/internal/workspaces"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
 )
 
 // Server handles HTTP requests
 type Server struct {
-	projectDir   string
-	config       *config.Config
-	hugoMgr      *hugo.Manager
-	fileMgr      *files.Manager
-	shortcodeMgr *shortcodes.Parser
-	imageMgr     *images.Processor
-	webFS        embed.FS
-	upgrader     websocket.Upgrader
+	projectDir    string
+	cfg           atomic.Pointer[config.Config] // Swapped whole on reloads and saves; read it with config
+	cfgMu         sync.Mutex                    // Held while replacing cfg, so reloads and saves don't lose each other's changes
+	hugoMgr       *hugo.Manager
+	fileMgr       *files.Manager
+	shortcodeMgr  *shortcodes.Parser
+	imageMgr      *images.Processor
+	domainMgr     *domain.Checker
+	scLinter      *lint.ShortcodeLinter
+	linkLinter    *lint.LinkLinter
+	proseLinter   *lint.ProseLinter
+	mdLinter      *lint.MarkdownLinter
+	fmLinter      *lint.FrontMatterLinter
+	webhooks      *webhooks.Dispatcher
+	storageMgr    *storage.Manager
+	docsMgr       *docs.Manager
+	eventsGen     *events.Generator
+	podcastMgr    *podcast.Manager
+	importMgr     *importer.Manager
+	transferMgr   *transfer.Manager
+	themesMgr     *themes.Manager
+	tasksMgr      *tasks.Manager
+	structMgr     *structured.Manager
+	catalogMgr    *catalog.Manager
+	formsMgr      *forms.Manager
+	scriptsMgr    *scripts.Manager
+	snippetsGen   *snippets.Generator
+	hub           *realtime.Hub
+	draftsMgr     *drafts.Manager
+	healthMgr     *health.Manager
+	dashboardMgr  *dashboard.Manager
+	uploadsMgr    *uploads.Manager
+	replaceMgr    *replace.Manager
+	calendarMgr   *calendar.Manager
+	relatedMgr    *related.Manager
+	refsMgr       *references.Manager
+	mediaMgr      *media.Manager
+	presence      *presence.Manager
+	commentsStore *comments.Store
+	workspacesMgr *workspaces.Manager
+	artifactsMgr  *artifacts.Manager
+	autosaveStore *autosave.Store
+	usersStore    *users.Store
+	activityLog   *activity.Log
+	webFS         embed.FS
+	upgrader      websocket.Upgrader
+	routes        chi.Routes      // Set by setupRoutes, to find the route of a request before it's routed
+	readOnlySafe  map[string]bool // Method and pattern of the routes marked with readOnlySafe
 }
 
 // New creates a new server
 func New(projectDir string, cfg *config.Config, hugoMgr *hugo.Manager, webFS embed.FS) *Server {
-	return &Server{
-		projectDir:   projectDir,
-		config:       cfg,
-		hugoMgr:      hugoMgr,
-		fileMgr:      files.NewManager(projectDir, cfg.FileTree),
-		shortcodeMgr: shortcodes.NewParser(projectDir),
-		imageMgr:     images.NewProcessor(projectDir, cfg.Images),
-		webFS:        webFS,
+	shortcodeMgr := shortcodes.NewParser(projectDir)
+	snippetsGen := snippets.NewGenerator(cfg.Snippets)
+	imageMgr := images.NewProcessor(projectDir, cfg.Images, snippetsGen)
+	dispatcher := webhooks.NewDispatcher(cfg.Webhooks)
+	storageMgr := storage.NewManager(projectDir, cfg.Storage)
+	scLinter := lint.NewShortcodeLinter(projectDir, shortcodeMgr)
+	structMgr := structured.NewManager(projectDir, cfg.StructuredData)
+	draftsMgr := drafts.NewManager(projectDir, cfg.Drafts, storageMgr.Dir(storage.AreaTrash))
+	healthMgr := health.NewManager(projectDir, cfg.Health, health.Sources{
+		Hugo:       hugoMgr,
+		Linter:     scLinter,
+		Structured: structMgr,
+		Drafts:     draftsMgr,
+	})
+
+	hub := realtime.NewHub()
+	presenceMgr := presence.NewManager()
+	presenceMgr.OnChange(func(state presence.State) {
+		hub.Publish(realtime.TopicPresence, "presence.changed", state)
+	})
+
+	usersStore, err := users.NewStore(projectDir, time.Duration(cfg.Server.SessionHours)*time.Hour)
+	if err != nil {
+		slog.Error("Failed to load users; only the configured tokens can sign in", "error", err)
+	}
+
+	fileMgr := files.NewManager(projectDir, cfg.FileTree)
+
+	commentsStore, err := comments.NewStore(projectDir)
+	if err != nil {
+		slog.Error("Failed to load some review comments", "error", err)
+	}
+
+	hugoMgr.OnBuild(func(event hugo.BuildEvent) {
+		name := webhooks.EventBuildSucceeded
+		if !event.Success {
+			name = webhooks.EventBuildFailed
+		}
+		dispatcher.Dispatch(name, map[string]interface{}{"message": event.Message})
+	})
+
+	domainMgr := domain.NewChecker(projectDir, cfg.Domain)
+	domainMgr.OnChange(func(previous domain.Level, report *domain.Report) {
+		dispatcher.Dispatch(webhooks.EventDomainChanged, map[string]interface{}{
+			"domain":        report.Domain,
+			"level":         report.Level,
+			"previousLevel": previous,
+			"message":       report.Message,
+		})
+	})
+
+	s := &Server{
+		projectDir:    projectDir,
+		hugoMgr:       hugoMgr,
+		fileMgr:       fileMgr,
+		shortcodeMgr:  shortcodeMgr,
+		imageMgr:      imageMgr,
+		domainMgr:     domainMgr,
+		scLinter:      scLinter,
+		linkLinter:    lint.NewLinkLinter(projectDir, cfg.Links),
+		proseLinter:   lint.NewProseLinter(projectDir, cfg.Prose),
+		mdLinter:      lint.NewMarkdownLinter(projectDir),
+		fmLinter:      lint.NewFrontMatterLinter(projectDir, cfg.FrontMatter),
+		webhooks:      dispatcher,
+		storageMgr:    storageMgr,
+		docsMgr:       docs.NewManager(projectDir, cfg.Docs),
+		eventsGen:     events.NewGenerator(projectDir, cfg.Events),
+		podcastMgr:    podcast.NewManager(projectDir, cfg.Podcast),
+		importMgr:     importer.NewManager(projectDir, fileMgr),
+		transferMgr:   transfer.NewManager(projectDir),
+		themesMgr:     themes.NewManager(projectDir, shortcodeMgr),
+		tasksMgr:      tasks.NewManager(projectDir, cfg.Tasks),
+		structMgr:     structMgr,
+		catalogMgr:    catalog.NewManager(projectDir, cfg.Catalog, imageMgr),
+		formsMgr:      forms.NewManager(projectDir, cfg.Forms),
+		scriptsMgr:    scripts.NewManager(projectDir, cfg.Scripts),
+		snippetsGen:   snippetsGen,
+		hub:           hub,
+		draftsMgr:     draftsMgr,
+		healthMgr:     healthMgr,
+		dashboardMgr:  dashboard.NewManager(projectDir, dashboard.Sources{Hugo: hugoMgr, Health: healthMgr, Domain: domainMgr}),
+		uploadsMgr:    uploads.NewManager(projectDir, cfg.Uploads),
+		replaceMgr:    replace.NewManager(projectDir),
+		calendarMgr:   calendar.NewManager(projectDir),
+		relatedMgr:    related.NewManager(projectDir),
+		refsMgr:       references.NewManager(projectDir),
+		mediaMgr:      media.NewManager(projectDir, cfg.Media, imageMgr),
+		presence:      presenceMgr,
+		commentsStore: commentsStore,
+		workspacesMgr: workspaces.NewManager(projectDir, cfg.Workspaces, cfg.Hugo),
+		artifactsMgr:  artifacts.NewManager(projectDir, cfg.BuildChecks),
+		autosaveStore: autosave.NewStore(projectDir),
+		usersStore:    usersStore,
+		activityLog:   activity.NewLog(projectDir),
+		webFS:         webFS,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				// Check if origin is allowed based on configuration
@@ -65,6 +231,14 @@ func New(projectDir string, cfg *config.Config, hugoMgr *hugo.Manager, webFS emb
 			},
 		},
 	}
+	s.cfg.Store(cfg)
+	return s
+}
+
+// config returns the configuration in effect. It's replaced whole, never changed in place, so a
+// request sees one configuration throughout when it keeps what this returns.
+func (s *Server) config() *config.Config {
+	return s.cfg.Load()
 }
 
 // Start starts the HTTP server with chi router and graceful shutdown
@@ -88,16 +262,86 @@ func (s *Server) Start(addr string) error {
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      r,
-		ReadTimeout:  time.Duration(s.config.Server.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(s.config.Server.IdleTimeout) * time.Second,
+		ReadTimeout:  time.Duration(s.config().Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(s.config().Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(s.config().Server.IdleTimeout) * time.Second,
+	}
+
+	certFile, keyFile, err := s.tlsFiles(addr)
+	if err != nil {
+		return err
+	}
+
+	// Start background domain/certificate monitoring
+	s.domainMgr.Start()
+	defer s.domainMgr.Stop()
+
+	// Start webhook delivery
+	s.webhooks.Start()
+	defer s.webhooks.Stop()
+
+	// Start background GC of trash, history and cache, which deletes files a read-only server keeps
+	if !s.config().Server.ReadOnly {
+		s.storageMgr.Start()
+		defer s.storageMgr.Stop()
+	}
+
+	// Start health checks of every Hugo instance
+	for _, inst := range s.hugoMgr.Instances() {
+		inst.StartWatchdog()
+		defer inst.StopWatchdog()
+	}
+
+	// Publish the output of the other Hugo instances, and build errors in project files, to realtime subscribers
+	stopForwarding := make(chan struct{})
+	s.forwardInstances(stopForwarding)
+	s.forwardBuildErrors(stopForwarding)
+	defer close(stopForwarding)
+
+	// Start background generation of recurring event pages, which a read-only server doesn't write
+	if !s.config().Server.ReadOnly {
+		s.eventsGen.Start()
+		defer s.eventsGen.Stop()
+	}
+
+	if auth := s.config().Server; auth.EnableAuth && auth.AuthToken == "" && len(auth.Tokens) == 0 && s.usersStore.Len() == 0 {
+		slog.Warn("Authentication is enabled but there are no tokens or users; add a user with: hugo-manager add-user")
+	}
+
+	if ffmpeg := s.mediaMgr.FFmpeg(); ffmpeg != "" {
+		slog.Info("Video posters enabled", "ffmpeg", ffmpeg)
+	}
+
+	// Expire the presence and locks of editors that went away
+	s.presence.Start()
+	defer s.presence.Stop()
+
+	// Start watching the file tree to serve it from memory
+	s.fileMgr.Start()
+	defer s.fileMgr.Stop()
+
+	// Stop the workspace previews left running by a previous instance
+	s.workspacesMgr.CleanupOrphans()
+	defer s.workspacesMgr.StopAll()
+
+	// Apply the changes to hugo-manager.yaml that don't need a restart
+	if watcher, err := config.Watch(s.projectDir, s.reloadConfig); err != nil {
+		slog.Warn("Configuration reload disabled: failed to watch the configuration file", "error", err)
+	} else {
+		defer watcher.Close()
 	}
 
 	// Start server in a goroutine
 	go func() {
-		s.logInfo("Starting server on %s", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.logError("Server failed to start: %v", err)
+		slog.Info("Starting server", "addr", addr, "tls", certFile != "")
+		var err error
+		if certFile != "" {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Server failed to start", "error", err)
 		}
 	}()
 
@@ -105,77 +349,401 @@ func (s *Server) Start(addr string) error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	s.logInfo("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.Server.ShutdownTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config().Server.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		s.logError("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
 		return err
 	}
 
-	s.logInfo("Server gracefully stopped")
+	slog.Info("Server gracefully stopped")
 	return nil
 }
 
+// StopPreviews stops the Hugo servers of the workspaces, for shutdowns that exit before Start returns
+func (s *Server) StopPreviews() {
+	s.workspacesMgr.StopAll()
+}
+
+// tlsFiles returns the certificate and key to serve HTTPS with, or empty paths for plain HTTP
+func (s *Server) tlsFiles(addr string) (string, string, error) {
+	cfg := s.config().Server
+	switch {
+	case cfg.TLSCert != "" || cfg.TLSKey != "":
+		if cfg.TLSCert == "" || cfg.TLSKey == "" {
+			return "", "", fmt.Errorf("both tls_cert and tls_key are required to serve HTTPS")
+		}
+		return cfg.TLSCert, cfg.TLSKey, nil
+	case cfg.TLSSelfSigned:
+		host, _, _ := net.SplitHostPort(addr)
+		hosts := []string{host}
+		if name, err := os.Hostname(); err == nil {
+			hosts = append(hosts, name)
+		}
+		certFile, keyFile, err := certs.EnsureSelfSigned(filepath.Join(s.projectDir, storage.DirName, "tls"), hosts...)
+		if err != nil {
+			return "", "", err
+		}
+		slog.Warn("Serving HTTPS with a self-signed certificate; browsers will ask to trust it", "cert", certFile)
+		return certFile, keyFile, nil
+	}
+	return "", "", nil
+}
+
+// reloadConfig applies the editor settings, templates, template paths and bodies and image presets of a reloaded configuration.
+// Other sections are read by the managers when the server starts, so changing them needs a restart.
+func (s *Server) reloadConfig(cfg *config.Config) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	// Command line flags still win over the file, and aren't changes waiting for a restart
+	cfg.ReapplyOverrides(s.config())
+
+	next := *s.config()
+	next.Editor = cfg.Editor
+	next.Templates = cfg.Templates
+	next.TemplatePaths = cfg.TemplatePaths
+	next.TemplateBodies = cfg.TemplateBodies
+	next.Images.Presets = cfg.Images.Presets
+	applied := config.Changed(s.config(), &next)
+	s.imageMgr.SetPresets(cfg.Images.Presets)
+	s.cfg.Store(&next)
+
+	if len(applied) > 0 {
+		slog.Info("Configuration reloaded", "sections", applied)
+	}
+	if pending := config.Changed(&next, cfg); len(pending) > 0 {
+		slog.Warn("Configuration changes need a restart to take effect", "sections", pending)
+	}
+}
+
 // setupRoutes configures all routes for the chi router
 func (s *Server) setupRoutes(r chi.Router) {
 	// Main page
 	r.Get("/", s.handleIndex)
 
-	// API routes
-	r.Route("/api", func(r chi.Router) {
-		// File management routes
-		r.Route("/files", func(r chi.Router) {
+	// API routes, versioned under /api/v1. The unversioned /api serves the same routes, marked
+	// deprecated, for clients written before the version prefix.
+	r.Route(apiPrefix, s.apiRoutes)
+	r.With(s.legacyAPIMiddleware).Route("/api", s.apiRoutes)
+
+	s.routes = r
+	s.readOnlySafe = readOnlySafeRoutes(r)
+}
+
+// apiRoutes configures the API routes, mounted under each API prefix
+func (s *Server) apiRoutes(r chi.Router) {
+	// Feature flags
+	imagesEnabled := s.requireFeature("image processing", func(f config.FeaturesConfig) bool { return f.Images })
+	configEditEnabled := s.requireFeature("config editing", func(f config.FeaturesConfig) bool { return f.ConfigEdit })
+	hugoControlEnabled := s.requireFeature("Hugo control", func(f config.FeaturesConfig) bool { return f.HugoControl })
+	uploadsEnabled := s.requireFeature("uploads", func(f config.FeaturesConfig) bool { return f.Uploads })
+
+	// Auth tokens and their roles, with editors kept out of the admin routes below, and the audit trail
+	r.Use(s.authMiddleware)
+	r.Use(s.activityMiddleware)
+
+	// Sign-in and current user routes
+	r.Route("/auth", func(r chi.Router) {
+		r.With(readOnlySafe).Post("/login", s.handleLogin)
+		r.With(readOnlySafe).Post("/logout", s.handleLogout)
+		r.Get("/me", s.handleMe)
+	})
+
+	// User management routes
+	r.Route("/users", func(r chi.Router) {
+		r.Use(s.requireAdmin)
+		r.Get("/", s.handleUsers)
+		r.Post("/", s.handleUserCreate)
+		r.Put("/{username}", s.handleUserUpdate)
+		r.Delete("/{username}", s.handleUserDelete)
+		r.Post("/{username}/token", s.handleUserToken)
+	})
+
+	// Activity log
+	r.Get("/activity", s.handleActivity)
+
+	// Review comment routes; approving a file for publishing is for admins
+	r.Route("/comments", func(r chi.Router) {
+		r.Get("/", s.handleComments)
+		r.Post("/", s.handleCommentCreate)
+		r.Get("/reviews", s.handleReviews)
+		r.Get("/reviews/{path}", s.handleReview)
+		r.With(s.requireAdmin).Put("/reviews/{path}", s.handleReviewApprove)
+		r.With(s.requireAdmin).Delete("/reviews/{path}", s.handleReviewWithdraw)
+		r.Get("/{id}", s.handleComment)
+		r.Put("/{id}", s.handleCommentUpdate)
+		r.Delete("/{id}", s.handleCommentDelete)
+	})
+
+	// Draft workspace routes
+	r.Route("/workspaces", func(r chi.Router) {
+		r.Get("/", s.handleWorkspaces)
+		r.Post("/", s.handleWorkspaceCreate)
+		r.Get("/{name}", s.handleWorkspace)
+		r.Delete("/{name}", s.handleWorkspaceDelete)
+		r.Post("/{name}/merge", s.handleWorkspaceMerge)
+		r.With(hugoControlEnabled).Post("/{name}/preview", s.handleWorkspacePreviewStart)
+		r.With(hugoControlEnabled).Delete("/{name}/preview", s.handleWorkspacePreviewStop)
+	})
+
+	// File management routes
+	r.Route("/files", func(r chi.Router) {
+		// These work in the workspace of ?workspace= when there is one
+		r.Group(func(r chi.Router) {
+			r.Use(s.workspaceMiddleware)
 			r.Get("/", s.handleFiles)
-			r.Get("/search", s.handleFileSearch)
 			r.Get("/raw", s.handleFileRaw)
-			r.Get("/{path}", s.handleFileGet)
-			r.Put("/{path}", s.handleFilePut)
-			r.Post("/{path}", s.handleFilePost)
-			r.Delete("/{path}", s.handleFileDelete)
-			r.Post("/upload", s.handleFileUpload)
-			r.Post("/copy", s.handleFileCopy)
+			r.With(s.requireAdminPath).Get("/{path}", s.handleFileGet)
+			r.With(s.requireAdminPath).Put("/{path}", s.handleFilePut)
+			r.With(s.requireAdminPath).Post("/{path}", s.handleFilePost)
+			r.With(s.requireAdminPath).Delete("/{path}", s.handleFileDelete)
 		})
+		r.Get("/search", s.handleFileSearch)
+		r.Get("/download", s.handleFileDownload)
+		r.Get("/{path}/references", s.handleFileReferences)
+		r.Get("/{path}/presence", s.handlePresence)
+		r.With(readOnlySafe).Post("/{path}/presence", s.handlePresenceJoin)
+		r.With(readOnlySafe).Delete("/{path}/presence", s.handlePresenceLeave)
+		r.Post("/{path}/lock", s.handleLockAcquire)
+		r.Delete("/{path}/lock", s.handleLockRelease)
+		r.Get("/{path}/draft", s.handleDraftGet)
+		r.With(s.requireAdminPath).Put("/{path}/draft", s.handleDraftSave)
+		r.Delete("/{path}/draft", s.handleDraftDelete)
+		r.With(uploadsEnabled).Post("/upload", s.handleFileUpload)
+		r.Post("/copy", s.handleFileCopy)
+	})
 
-		// Shortcode routes
-		r.Route("/shortcodes", func(r chi.Router) {
-			r.Get("/", s.handleShortcodes)
-			r.Get("/{name}", s.handleShortcode)
-		})
+	// Resumable upload routes
+	r.Route("/uploads", func(r chi.Router) {
+		r.Use(uploadsEnabled)
+		r.Get("/", s.handleUploads)
+		r.Post("/", s.handleUploadCreate)
+		r.Get("/{id}", s.handleUpload)
+		r.Put("/{id}", s.handleUploadChunk)
+		r.Post("/{id}/complete", s.handleUploadComplete)
+		r.Delete("/{id}", s.handleUploadAbort)
+	})
 
-		// Image management routes
-		r.Route("/images", func(r chi.Router) {
-			r.Post("/upload", s.handleImageUpload)
-			r.Post("/process", s.handleImageProcess)
-			r.Get("/processed", s.handleImageProcessed)
-			r.Get("/folders", s.handleImageFolders)
-			r.Get("/presets", s.handleImagePresets)
-		})
+	// Shortcode routes
+	r.Route("/shortcodes", func(r chi.Router) {
+		r.Get("/", s.handleShortcodes)
+		r.Get("/{name}", s.handleShortcode)
+		r.Get("/{name}/template", s.handleShortcodeTemplate)
+		r.With(s.requireAdmin).Post("/{name}", s.handleShortcodeCreate)
+		r.With(s.requireAdmin).Put("/{name}", s.handleShortcodeUpdate)
+	})
 
-		// Hugo management routes
-		r.Route("/hugo", func(r chi.Router) {
-			r.Get("/status", s.handleHugoStatus)
-			r.Post("/start", s.handleHugoStart)
-			r.Post("/stop", s.handleHugoStop)
-			r.Post("/restart", s.handleHugoRestart)
-			r.Get("/logs", s.handleHugoLogs)
-			r.Get("/ws", s.handleHugoWS)
-		})
+	// Theme routes; switching and installing change the site's templates
+	r.Route("/themes", func(r chi.Router) {
+		r.Get("/", s.handleThemes)
+		r.Get("/inspect", s.handleThemeInspect)
+		r.With(s.requireAdmin).Put("/active", s.handleThemeActivate)
+		r.With(s.requireAdmin).Post("/", s.handleThemeInstall)
+	})
 
-		// Configuration routes
-		r.Route("/config", func(r chi.Router) {
-			r.Use(s.authMiddleware) // Protect config routes
-			r.Get("/", s.handleConfigGet)
-			r.Put("/", s.handleConfigPut)
-		})
+	// Image management routes
+	r.Route("/images", func(r chi.Router) {
+		r.Use(imagesEnabled)
+		r.With(uploadsEnabled).Post("/upload", s.handleImageUpload)
+		r.With(uploadsEnabled).Post("/paste", s.handleImagePaste)
+		r.Post("/process", s.handleImageProcess)
+		r.Get("/processed", s.handleImageProcessed)
+		r.Get("/folders", s.handleImageFolders)
+		r.Get("/presets", s.handleImagePresets)
+		r.Get("/duplicates", s.handleImageDuplicates)
+		r.Get("/{path}/meta", s.handleImageMetaGet)
+		r.With(s.requireAdminPath).Put("/{path}/meta", s.handleImageMetaSet)
+	})
 
-		// Data files for shortcodes
-		r.Route("/data", func(r chi.Router) {
-			r.Get("/", s.handleDataFiles)
-			r.Get("/*", s.handleDataFiles)
-		})
+	// Media library routes
+	r.Route("/media", func(r chi.Router) {
+		r.Get("/", s.handleMedia)
+		r.With(uploadsEnabled).Post("/", s.handleMediaUpload)
+		r.Get("/types", s.handleMediaTypes)
+		r.Get("/snippet", s.handleMediaSnippet)
+		r.With(imagesEnabled).Post("/poster", s.handleMediaPoster)
+	})
+
+	// Hugo management routes
+	r.Route("/hugo", func(r chi.Router) {
+		r.Get("/instances", s.handleHugoInstances)
+		r.Get("/status", s.handleHugoStatus)
+		r.With(hugoControlEnabled).Post("/start", s.handleHugoStart)
+		r.With(hugoControlEnabled).Post("/stop", s.handleHugoStop)
+		r.With(hugoControlEnabled).Post("/restart", s.handleHugoRestart)
+		r.With(hugoControlEnabled).Put("/options", s.handleHugoOptions)
+		r.Get("/profiles", s.handleHugoProfiles)
+		r.With(hugoControlEnabled, s.requireAdmin).Post("/build", s.handleHugoBuild)
+		r.Get("/logs", s.handleHugoLogs)
+		r.Get("/errors", s.handleHugoErrors)
+		r.Get("/ws", s.handleHugoWS)
+	})
+
+	// Build output routes
+	r.Route("/build", func(r chi.Router) {
+		r.Get("/artifacts", s.handleBuildArtifacts)
+		r.Get("/report", s.handleBuildReport)
+	})
+
+	// Task routes; only the commands of tasks.commands can be run
+	r.Route("/tasks", func(r chi.Router) {
+		r.Get("/", s.handleTasks)
+		r.Get("/{name}", s.handleTask)
+		r.With(s.requireAdmin).Post("/{name}/run", s.handleTaskRun)
+		r.With(s.requireAdmin).Post("/{name}/stop", s.handleTaskStop)
+	})
+
+	// Configuration routes
+	r.Route("/config", func(r chi.Router) {
+		r.Get("/", s.handleConfigGet)
+		r.With(configEditEnabled, s.requireAdmin).Put("/", s.handleConfigPut)
+		r.With(readOnlySafe).Post("/validate", s.handleConfigValidate)
+	})
+
+	// Content routes
+	r.Route("/content", func(r chi.Router) {
+		r.Get("/drafts/cleanup", s.handleDraftsStale)
+		r.With(readOnlySafe).Post("/drafts/cleanup", s.handleDraftsCleanup)
+		r.With(readOnlySafe).Post("/replace", s.handleContentReplace)
+		r.Get("/languages", s.handleContentLanguages)
+		r.Get("/translations/coverage", s.handleTranslationCoverage)
+		r.Get("/{path}/permalink", s.handleContentPermalink)
+		r.Get("/{path}/template", s.handleContentTemplate)
+		r.Get("/{path}/related", s.handleContentRelated)
+		r.Get("/{path}/translations", s.handleTranslations)
+		r.Post("/{path}/translations/{lang}", s.handleTranslationCreate)
+		r.With(s.requireAdminPath).Post("/{path}/save-and-preview", s.handleContentSavePreview)
+		r.Get("/{path}/structured-data", s.handleStructuredData)
+		r.With(s.requireAdminPath).Put("/{path}/structured-data", s.handleStructuredDataPut)
+	})
+
+	// Structured data routes
+	r.Get("/structured-data/report", s.handleStructuredDataReport)
+
+	// Editorial calendar routes
+	r.Route("/calendar", func(r chi.Router) {
+		r.Get("/", s.handleCalendar)
+		r.With(s.requireAdminPath).Put("/{path}", s.handleCalendarReschedule)
+	})
+
+	// Redirect routes
+	r.Route("/redirects", func(r chi.Router) {
+		r.Get("/", s.handleRedirects)
+		r.With(s.requireAdminPath).Post("/{path}", s.handleRedirectCreate)
+	})
+
+	// Documentation site routes
+	r.Route("/docs", func(r chi.Router) {
+		r.Get("/versions", s.handleDocsVersions)
+		r.Post("/versions", s.handleDocsVersionCreate)
+		r.Put("/versions", s.handleDocsVersionsPut)
+		r.Post("/ingest", s.handleDocsIngest)
+	})
+
+	// Content linting routes
+	r.Route("/lint", func(r chi.Router) {
+		r.Get("/shortcodes", s.handleLintShortcodes)
+		r.With(readOnlySafe).Post("/shortcodes", s.handleLintShortcodesContent)
+		r.Get("/links", s.handleLintLinks)
+		r.Get("/markdown", s.handleLintMarkdown)
+		r.With(readOnlySafe).Post("/markdown", s.handleLintMarkdownContent)
+		r.With(readOnlySafe).Post("/markdown/fix", s.handleLintMarkdownFix)
+		r.Get("/prose", s.handleLintProse)
+		r.With(readOnlySafe).Post("/prose", s.handleLintProseContent)
+		r.Get("/dictionary", s.handleDictionary)
+		r.Post("/dictionary", s.handleDictionaryAdd)
+		r.Delete("/dictionary/{word}", s.handleDictionaryRemove)
+		r.Get("/frontmatter", s.handleLintFrontMatter)
+		r.With(readOnlySafe).Post("/frontmatter", s.handleLintFrontMatterContent)
+	})
+
+	// Production domain monitoring routes
+	r.Route("/domain", func(r chi.Router) {
+		r.Get("/", s.handleDomainStatus)
+		r.With(readOnlySafe).Post("/check", s.handleDomainCheck)
+	})
+
+	// Recurring event routes
+	r.Route("/events", func(r chi.Router) {
+		r.Get("/", s.handleEvents)
+		r.Post("/generate", s.handleEventsGenerate)
+	})
+	r.Get("/events.ics", s.handleEventsICal)
+
+	// Content import from other platforms, and archives moved between hugo-manager instances
+	r.With(s.requireAdmin).Post("/import", s.handleImport)
+	r.With(s.requireAdmin).Post("/import/archive", s.handleImportArchive)
+	r.With(readOnlySafe).Post("/export", s.handleExport)
+
+	// Podcast episode routes
+	r.Route("/podcast", func(r chi.Router) {
+		r.Get("/episodes", s.handlePodcastFeed)
+		r.Post("/episodes", s.handlePodcastIngest)
+		r.Get("/validate", s.handlePodcastValidate)
+	})
+
+	// Product catalog routes
+	r.Route("/catalog", func(r chi.Router) {
+		r.Get("/products", s.handleCatalogProducts)
+		r.Post("/products", s.handleCatalogProductCreate)
+		r.Get("/products/{sku}", s.handleCatalogProduct)
+		r.Put("/products/{sku}", s.handleCatalogProductUpdate)
+		r.Delete("/products/{sku}", s.handleCatalogProductDelete)
+		r.With(imagesEnabled, uploadsEnabled).Post("/products/{sku}/images", s.handleCatalogProductImage)
+		r.Get("/validate", s.handleCatalogValidate)
+		r.Post("/generate", s.handleCatalogGenerate)
+	})
+
+	// Form endpoint routes
+	r.Route("/forms", func(r chi.Router) {
+		r.Get("/", s.handleForms)
+		r.Post("/generate", s.handleFormsGenerate)
+		r.Get("/scan", s.handleFormsScan)
+		r.Post("/{name}/test", s.handleFormTest)
+	})
+
+	// Third-party script and consent routes
+	r.Route("/scripts", func(r chi.Router) {
+		r.Get("/", s.handleScripts)
+		r.With(s.requireAdmin).Post("/generate", s.handleScriptsGenerate)
+		r.Get("/audit", s.handleScriptsAudit)
+	})
+
+	// Accessible snippet routes
+	r.Route("/snippets", func(r chi.Router) {
+		r.With(readOnlySafe).Post("/image", s.handleSnippetImage)
+		r.With(readOnlySafe).Post("/gallery", s.handleSnippetGallery)
+		r.With(readOnlySafe).Post("/button", s.handleSnippetButton)
+	})
+
+	// Storage usage and GC routes
+	r.Route("/storage", func(r chi.Router) {
+		r.Get("/", s.handleStorageUsage)
+		r.Post("/gc", s.handleStorageGC)
+	})
+
+	// Project health score
+	r.Get("/health/score", s.handleHealthScore)
+
+	// Dashboard widgets
+	r.Get("/dashboard", s.handleDashboard)
+
+	// Multiplexed realtime events
+	r.Get("/ws", s.handleWS)
+
+	// Utilities for the UI
+	r.Get("/utils/slug", s.handleSlug)
+
+	// OpenAPI specification
+	r.Get("/spec", s.handleSpec)
+
+	// Data files for shortcodes
+	r.Route("/data", func(r chi.Router) {
+		r.Get("/", s.handleDataFiles)
+		r.Get("/*", s.handleDataFiles)
 	})
 }
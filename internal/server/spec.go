@@ -0,0 +1,651 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/fernandezvara/hugo-manager/internal/activity"
+	"github.com/fernandezvara/hugo-manager/internal/artifacts"
+	"github.com/fernandezvara/hugo-manager/internal/autosave"
+	"github.com/fernandezvara/hugo-manager/internal/calendar"
+	"github.com/fernandezvara/hugo-manager/internal/catalog"
+	"github.com/fernandezvara/hugo-manager/internal/comments"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/dashboard"
+	"github.com/fernandezvara/hugo-manager/internal/docs"
+	"github.com/fernandezvara/hugo-manager/internal/domain"
+	"github.com/fernandezvara/hugo-manager/internal/drafts"
+	"github.com/fernandezvara/hugo-manager/internal/events"
+	"github.com/fernandezvara/hugo-manager/internal/files"
+	"github.com/fernandezvara/hugo-manager/internal/forms"
+	"github.com/fernandezvara/hugo-manager/internal/health"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/images"
+	"github.com/fernandezvara/hugo-manager/internal/importer"
+	"github.com/fernandezvara/hugo-manager/internal/lint"
+	"github.com/fernandezvara/hugo-manager/internal/media"
+	"github.com/fernandezvara/hugo-manager/internal/openapi"
+	"github.com/fernandezvara/hugo-manager/internal/podcast"
+	"github.com/fernandezvara/hugo-manager/internal/presence"
+	"github.com/fernandezvara/hugo-manager/internal/references"
+	"github.com/fernandezvara/hugo-manager/internal/related"
+	"github.com/fernandezvara/hugo-manager/internal/replace"
+	"github.com/fernandezvara/hugo-manager/internal/scripts"
+	"github.com/fernandezvara/hugo-manager/internal/shortcodes"
+	"github.com/fernandezvara/hugo-manager/internal/snippets"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+	"github.com/fernandezvara/hugo-manager/internal/structured"
+	"github.com/fernandezvara/hugo-manager/internal/tasks"
+	"github.com/fernandezvara/hugo-manager/internal/themes"
+	"github.com/fernandezvara/hugo-manager/internal/transfer"
+	"github.com/fernandezvara/hugo-manager/internal/uploads"
+	"github.com/fernandezvara/hugo-manager/internal/users"
+	"github.com/fernandezvara/hugo-manager/internal/workspaces"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// apiVersion is the version of the REST API contract described by /api/v1/spec
+const apiVersion = "1.0.0"
+
+// Multipart form types, used only to describe form endpoints in the spec
+
+type imageUploadForm struct {
+	File     openapi.Binary `json:"file"`
+	Folder   string         `json:"folder"`
+	Filename string         `json:"filename"`
+	Quality  int            `json:"quality"`
+	Widths   string         `json:"widths"` // JSON array of widths, e.g. [320,640]
+	Aspect   string         `json:"aspect"` // Crop to W:H, e.g. 1:1
+	Alt      string         `json:"alt"`    // Alt text of the snippets
+	Caption  string         `json:"caption"`
+	Lang     string         `json:"lang"`
+	Dir      string         `json:"dir"` // ltr, rtl or auto
+}
+
+type imagePasteForm struct {
+	Image  openapi.Binary `json:"image"`
+	Page   string         `json:"page"`   // Page the image is pasted into, which names it
+	Folder string         `json:"folder"` // Default static/images followed by the page's folder below content
+	Preset string         `json:"preset"` // Default Inline
+	Alt    string         `json:"alt"`
+}
+
+type imageProcessForm struct {
+	SourcePath string `json:"sourcePath"`
+	Folder     string `json:"folder"`
+	Filename   string `json:"filename"`
+	Quality    int    `json:"quality"`
+	Preset     string `json:"preset"`
+	Widths     string `json:"widths"` // Comma-separated widths
+	Aspect     string `json:"aspect"` // Crop to W:H, e.g. 1:1
+	Alt        string `json:"alt"`    // Alt text of the snippets
+	Caption    string `json:"caption"`
+	Lang       string `json:"lang"`
+	Dir        string `json:"dir"` // ltr, rtl or auto
+}
+
+type productImageForm struct {
+	Image openapi.Binary `json:"image"`
+}
+
+type fileUploadForm struct {
+	File     []openapi.Binary `json:"file"`
+	Path     []string         `json:"path"` // Path of each file below folder, in the order of the files
+	Folder   string           `json:"folder"`
+	Filename string           `json:"filename"` // Name of a single file
+}
+
+type mediaUploadForm struct {
+	File      openapi.Binary `json:"file"`
+	Folder    string         `json:"folder"` // Under static/, the configured media folder when empty
+	Filename  string         `json:"filename"`
+	Overwrite bool           `json:"overwrite"`
+	Title     string         `json:"title"`  // Link text of the snippets
+	Poster    bool           `json:"poster"` // Make the poster set of a video with ffmpeg
+	Alt       string         `json:"alt"`    // Alt text of the poster's image snippets
+}
+
+type importForm struct {
+	File      openapi.Binary `json:"file"`      // WordPress WXR, Ghost JSON or a zip of a Jekyll site
+	Source    string         `json:"source"`    // Project-relative export file or Jekyll folder, instead of file
+	Kind      string         `json:"kind"`      // wordpress, ghost or jekyll; detected when empty
+	Output    string         `json:"output"`    // Folder of posts, content/posts when empty
+	Pages     string         `json:"pages"`     // Folder of pages, content when empty
+	Images    string         `json:"images"`    // Folder of images under static/, static/images/imported when empty
+	SiteURL   string         `json:"siteUrl"`   // Old site, for images with relative URLs
+	Aliases   bool           `json:"aliases"`   // Add each old URL to the aliases of its post
+	Overwrite bool           `json:"overwrite"` // Replace files that already have the path of a post
+}
+
+type importArchiveForm struct {
+	File      openapi.Binary `json:"file"`      // Zip made by /api/export
+	Overwrite bool           `json:"overwrite"` // Replace files that differ from those of the archive
+}
+
+type fileCopyForm struct {
+	SourcePath     string `json:"sourcePath"`
+	Folder         string `json:"folder"`
+	TargetFilename string `json:"targetFilename"`
+}
+
+// sessionParam is the editor session of presence and lock requests
+var sessionParam = openapi.Parameter{Name: "session", Required: true, Description: "Session ID the editor generated, 8 to 64 letters, digits, - or _"}
+
+// instanceParam picks one of the Hugo servers of hugo.instances
+var instanceParam = openapi.Parameter{Name: "instance", Description: "Hugo instance (default main)"}
+
+// workspaceParam points a file route at the copy of the site of a draft workspace
+var workspaceParam = openapi.Parameter{Name: "workspace", Description: "Work in this draft workspace instead of the live tree"}
+
+// apiRoutes describes every REST route registered in setupRoutes
+var apiRoutes = []openapi.Route{
+	// Files
+	{Method: "GET", Path: "/api/v1/files", Tag: "files", Summary: "List the file tree; honors If-None-Match with 304",
+		Query: []openapi.Parameter{
+			{Name: "show", Description: "Comma-separated roots to show"},
+			{Name: "q", Description: "Filter by name"},
+			{Name: "folder", Description: "Restrict to a folder"},
+			workspaceParam,
+		},
+		Response: []files.FileInfo{}},
+	{Method: "GET", Path: "/api/v1/files/search", Tag: "files", Summary: "Search images",
+		Query: []openapi.Parameter{
+			{Name: "q", Description: "Search query"},
+			{Name: "folder", Description: "Folder to search (defaults to all image folders)"},
+		},
+		Response: []files.FileInfo{}},
+	{Method: "GET", Path: "/api/v1/files/raw", Tag: "files", Summary: "Stream a file's raw bytes, with Range requests; honors If-None-Match with 304",
+		Query:       []openapi.Parameter{{Name: "path", Required: true, Description: "Project-relative path"}, workspaceParam},
+		ContentType: "application/octet-stream"},
+	{Method: "GET", Path: "/api/v1/files/download", Tag: "files", Summary: "Download a directory as a zip archive, without hidden files",
+		Query: []openapi.Parameter{
+			{Name: "path", Required: true, Description: "Project-relative directory"},
+			{Name: "format", Description: "Archive format, zip (the default and only one)"},
+		},
+		ContentType: "application/zip"},
+	{Method: "GET", Path: "/api/v1/files/{path}", Tag: "files", Summary: "Read a text file; a binary one or one over editor.max_file_size_mb is flagged with isBinary or tooLarge and no content",
+		Query:    []openapi.Parameter{workspaceParam},
+		Response: fileGetResponse{}},
+	{Method: "GET", Path: "/api/v1/files/{path}/references", Tag: "files", Summary: "References in content a rename would rewrite, without renaming",
+		Query:    []openapi.Parameter{{Name: "newName", Required: true, Description: "New name, relative to the directory of the file as in a rename"}},
+		Response: references.Plan{}},
+	{Method: "GET", Path: "/api/v1/files/{path}/presence", Tag: "files", Summary: "Editor sessions that have a file open, and the lock on it",
+		Response: presence.State{}},
+	{Method: "POST", Path: "/api/v1/files/{path}/presence", Tag: "files", Summary: "Mark a file open in a session; repeat within a minute while it stays open",
+		Query:    []openapi.Parameter{sessionParam},
+		Response: presence.State{}},
+	{Method: "DELETE", Path: "/api/v1/files/{path}/presence", Tag: "files", Summary: "Mark a file closed in a session, releasing the session's lock",
+		Query:    []openapi.Parameter{sessionParam},
+		Response: presence.State{}},
+	{Method: "POST", Path: "/api/v1/files/{path}/lock", Tag: "files", Summary: "Take or renew the advisory lock of a file for editor.lock_ttl seconds; 423 ERR_LOCKED while another session holds it",
+		Query:   []openapi.Parameter{sessionParam},
+		Request: lockRequest{}, Response: presence.Lock{}},
+	{Method: "DELETE", Path: "/api/v1/files/{path}/lock", Tag: "files", Summary: "Release a session's lock of a file; admins break another session's with force",
+		Query: []openapi.Parameter{
+			sessionParam,
+			{Name: "force", Description: "true to release another session's lock (admins only)"},
+		},
+		Response: successResponse{}},
+	{Method: "GET", Path: "/api/v1/files/{path}/draft", Tag: "files", Summary: "The requester's unsaved draft of a file, stale when the file changed after it was saved",
+		Response: autosave.Draft{}},
+	{Method: "PUT", Path: "/api/v1/files/{path}/draft", Tag: "files", Summary: "Keep the unsaved editor content of a file as the requester's draft, without changing the file",
+		Request: draftSaveRequest{}, Response: autosave.Draft{}},
+	{Method: "DELETE", Path: "/api/v1/files/{path}/draft", Tag: "files", Summary: "Discard the requester's draft of a file",
+		Response: successResponse{}},
+	{Method: "PUT", Path: "/api/v1/files/{path}", Tag: "files", Summary: "Save or rename a file; a rename can keep the old URL as an alias and rewrite the references to it. With ?session=, fails with 423 ERR_LOCKED when another session holds the lock",
+		Query:   []openapi.Parameter{workspaceParam},
+		Request: fileWriteRequest{}, Response: fileUpdateResponse{}},
+	{Method: "POST", Path: "/api/v1/files/{path}", Tag: "files", Summary: "Create a file or directory; an empty file gets the template its path is bound to",
+		Query:   []openapi.Parameter{workspaceParam},
+		Request: fileCreateRequest{}, Response: fileCreateResponse{}},
+	{Method: "DELETE", Path: "/api/v1/files/{path}", Tag: "files", Summary: "Delete a file or empty directory; with ?session=, fails with 423 ERR_LOCKED when another session holds the lock",
+		Query:    []openapi.Parameter{workspaceParam},
+		Response: fileDeleteResponse{}},
+	{Method: "POST", Path: "/api/v1/files/upload", Tag: "files", Summary: "Upload one or more files; 207 when only some are saved",
+		Form: fileUploadForm{}, Response: fileUploadResponse{}},
+	{Method: "POST", Path: "/api/v1/files/copy", Tag: "files", Summary: "Copy a file",
+		Form: fileCopyForm{}, Response: fileCopyResponse{}},
+
+	// Uploads
+	{Method: "GET", Path: "/api/v1/uploads", Tag: "uploads", Summary: "Unfinished resumable uploads",
+		Response: []uploads.Upload{}},
+	{Method: "POST", Path: "/api/v1/uploads", Tag: "uploads", Summary: "Start a resumable upload",
+		Request: uploadCreateRequest{}, Response: uploads.Upload{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/uploads/{id}", Tag: "uploads", Summary: "Upload with the offset to resume from",
+		Response: uploads.Upload{}},
+	{Method: "PUT", Path: "/api/v1/uploads/{id}", Tag: "uploads", Summary: "Append the raw request body as the next chunk; 409 ERR_OFFSET_MISMATCH when offset isn't the upload's",
+		Query:    []openapi.Parameter{{Name: "offset", Required: true, Description: "Byte the chunk starts at"}},
+		Response: uploads.Upload{}},
+	{Method: "POST", Path: "/api/v1/uploads/{id}/complete", Tag: "uploads", Summary: "Verify the checksum and place the file; 422 ERR_CHECKSUM_MISMATCH discards it",
+		Response: fileUploadResponse{}},
+	{Method: "DELETE", Path: "/api/v1/uploads/{id}", Tag: "uploads", Summary: "Discard an upload",
+		Response: successResponse{}},
+
+	// Content
+	{Method: "GET", Path: "/api/v1/content/drafts/cleanup", Tag: "content", Summary: "Drafts dated long ago and not edited recently",
+		Query: []openapi.Parameter{
+			{Name: "minAge", Description: "Months since the draft's date (default from drafts.min_age_months)"},
+			{Name: "idle", Description: "Months without edits (default from drafts.idle_months)"},
+		},
+		Response: drafts.List{}},
+	{Method: "POST", Path: "/api/v1/content/drafts/cleanup", Tag: "content", Summary: "Archive or delete abandoned drafts; without confirm, preview the moves",
+		Request: draftsCleanupRequest{}, Response: drafts.Result{}},
+	{Method: "POST", Path: "/api/v1/content/replace", Tag: "content", Summary: "Find and replace text or a regexp across content files; without confirm, return the diffs",
+		Request: contentReplaceRequest{}, Response: replace.Result{}},
+	{Method: "GET", Path: "/api/v1/content/languages", Tag: "content", Summary: "Languages of the site and the content directory of each",
+		Response: languagesResponse{}},
+	{Method: "GET", Path: "/api/v1/content/translations/coverage", Tag: "content", Summary: "Share of each section's pages translated into each language",
+		Response: []hugocontent.SectionCoverage{}},
+	{Method: "GET", Path: "/api/v1/content/{path}/translations", Tag: "content", Summary: "A page in every language, with the path of each missing translation",
+		Response: []hugocontent.Translation{}},
+	{Method: "POST", Path: "/api/v1/content/{path}/translations/{lang}", Tag: "content", Summary: "Create the missing translation of a page as a draft copy of it",
+		Response: hugocontent.Page{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/content/{path}/permalink", Tag: "content", Summary: "Rendered and live preview URL of a content file",
+		Query:    []openapi.Parameter{instanceParam},
+		Response: permalinkResponse{}},
+	{Method: "GET", Path: "/api/v1/content/{path}/related", Tag: "content", Summary: "Pages to link from a content page, from shared taxonomy terms and TF-IDF text similarity",
+		Query:    []openapi.Parameter{{Name: "limit", Description: "Maximum suggestions (default 10, max 50)"}},
+		Response: related.Result{}},
+	{Method: "GET", Path: "/api/v1/content/{path}/template", Tag: "content", Summary: "Template bound to a new file's path or folder, with the front matter it starts with",
+		Response: contentTemplateResponse{}},
+	{Method: "POST", Path: "/api/v1/content/{path}/save-and-preview", Tag: "content", Summary: "Save a content file, wait for Hugo to rebuild it and return its preview URL",
+		Query:   []openapi.Parameter{{Name: "timeout", Description: "Seconds to wait for the rebuild (default 15, max 120)"}, instanceParam},
+		Request: contentSaveRequest{}, Response: savePreviewResponse{}},
+	{Method: "GET", Path: "/api/v1/content/{path}/structured-data", Tag: "content", Summary: "Preview and validate the JSON-LD of a content file",
+		Query:    []openapi.Parameter{{Name: "type", Description: "schema.org type (Article, Recipe, Event, FAQPage); defaults to the section's type"}},
+		Response: structured.Page{}},
+	{Method: "PUT", Path: "/api/v1/content/{path}/structured-data", Tag: "content", Summary: "Write the JSON-LD of a content file into its front matter",
+		Request: structuredDataRequest{}, Response: structured.Page{}},
+	{Method: "GET", Path: "/api/v1/structured-data/report", Tag: "content", Summary: "Pages missing or failing the structured data expected for their section",
+		Response: structured.Report{}},
+
+	// Calendar
+	{Method: "GET", Path: "/api/v1/calendar", Tag: "calendar", Summary: "Content on its date, publishDate and expiryDate between two days, drafts and scheduled pages included",
+		Query: []openapi.Parameter{
+			{Name: "from", Description: "First day, YYYY-MM-DD (default the first day of this month)"},
+			{Name: "to", Description: "Last day, YYYY-MM-DD (default the day before a month after from)"},
+		},
+		Response: calendar.Calendar{}},
+	{Method: "PUT", Path: "/api/v1/calendar/{path}", Tag: "calendar", Summary: "Reschedule a page by moving one of its date fields",
+		Request: calendarRescheduleRequest{}, Response: calendar.Entry{}},
+
+	// Redirects
+	{Method: "GET", Path: "/api/v1/redirects", Tag: "redirects", Summary: "Aliases of every page, with the ones colliding with a page URL or with each other",
+		Response: hugocontent.Redirects{}},
+	{Method: "POST", Path: "/api/v1/redirects/{path}", Tag: "redirects", Summary: "Add an alias to a page",
+		Request: aliasRequest{}, Response: hugocontent.Alias{}, Status: http.StatusCreated},
+
+	// Docs
+	{Method: "GET", Path: "/api/v1/docs/versions", Tag: "docs", Summary: "List documentation versions",
+		Response: docs.VersionList{}},
+	{Method: "POST", Path: "/api/v1/docs/versions", Tag: "docs", Summary: "Create a docs version from an existing one",
+		Request: docsVersionCreateRequest{}, Response: docs.CreateResult{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/v1/docs/versions", Tag: "docs", Summary: "Replace the version list",
+		Request: docs.VersionList{}, Response: docs.VersionList{}},
+	{Method: "POST", Path: "/api/v1/docs/ingest", Tag: "docs", Summary: "Generate reference pages from OpenAPI or JSON schema",
+		Request: docsIngestRequest{}, Response: docs.IngestResult{}},
+
+	// Shortcodes
+	{Method: "GET", Path: "/api/v1/shortcodes", Tag: "shortcodes", Summary: "List detected shortcodes",
+		Response: []shortcodes.Shortcode{}},
+	{Method: "GET", Path: "/api/v1/shortcodes/{name}", Tag: "shortcodes", Summary: "Get a shortcode",
+		Response: shortcodes.Shortcode{}},
+	{Method: "GET", Path: "/api/v1/shortcodes/{name}/template", Tag: "shortcodes", Summary: "Read a shortcode template",
+		Response: shortcodeTemplateResponse{}},
+	{Method: "POST", Path: "/api/v1/shortcodes/{name}", Tag: "shortcodes", Summary: "Create a shortcode template",
+		Request: shortcodeCreateRequest{}, Response: shortcodes.Shortcode{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/v1/shortcodes/{name}", Tag: "shortcodes", Summary: "Update a shortcode template",
+		Request: shortcodeUpdateRequest{}, Response: shortcodes.Shortcode{}},
+
+	// Themes
+	{Method: "GET", Path: "/api/v1/themes", Tag: "themes", Summary: "Themes in themes/ and Hugo Modules, with their shortcodes and archetypes; active ones first",
+		Response: []themes.Theme{}},
+	{Method: "GET", Path: "/api/v1/themes/inspect", Tag: "themes", Summary: "A theme's parsed shortcodes, templates, those the project overrides and default params",
+		Query:    []openapi.Parameter{{Name: "name", Required: true, Description: "Folder under themes/, or module path"}},
+		Response: themes.Inspection{}},
+	{Method: "PUT", Path: "/api/v1/themes/active", Tag: "themes", Summary: "Switch the theme setting of the site configuration",
+		Request: themeActivateRequest{}, Response: themeActivateResponse{}},
+	{Method: "POST", Path: "/api/v1/themes", Tag: "themes", Summary: "Install a theme from a git URL as a submodule, a clone or a Hugo Module",
+		Request: themes.InstallOptions{}, Response: themes.Theme{}, Status: http.StatusCreated},
+
+	// Images
+	{Method: "POST", Path: "/api/v1/images/upload", Tag: "images", Summary: "Upload and process an image",
+		Form: imageUploadForm{}, Response: images.ProcessResult{}},
+	{Method: "POST", Path: "/api/v1/images/paste", Tag: "images", Summary: "Process an image pasted into a page, named after the page and the time",
+		Form: imagePasteForm{}, Response: images.ProcessResult{}},
+	{Method: "POST", Path: "/api/v1/images/process", Tag: "images", Summary: "Process an existing image",
+		Form: imageProcessForm{}, Response: images.ProcessResult{}},
+	{Method: "GET", Path: "/api/v1/images/processed", Tag: "images", Summary: "Build a result from existing variants",
+		Query:    []openapi.Parameter{{Name: "path", Required: true, Description: "Path of any variant"}},
+		Response: images.ProcessResult{}},
+	{Method: "GET", Path: "/api/v1/images/folders", Tag: "images", Summary: "List image folders",
+		Response: []images.FolderInfo{}},
+	{Method: "GET", Path: "/api/v1/images/presets", Tag: "images", Summary: "List image presets",
+		Response: []config.ImagePreset{}},
+	{Method: "GET", Path: "/api/v1/images/duplicates", Tag: "images", Summary: "Groups of identical and near-identical images, the one to keep first",
+		Query: []openapi.Parameter{
+			{Name: "folder", Description: "Project folder to search (default static, assets and content)"},
+			{Name: "distance", Description: "Bits of the 64-bit perceptual hashes that may differ (default 6, at most 16)"},
+		},
+		Response: images.DuplicateReport{}},
+	{Method: "GET", Path: "/api/v1/images/{path}/meta", Tag: "images", Summary: "Alt text, caption, credit and license kept for an image and its variants",
+		Response: images.ImageMeta{}},
+	{Method: "PUT", Path: "/api/v1/images/{path}/meta", Tag: "images", Summary: "Replace the metadata of an image; empty metadata removes its sidecar",
+		Request: images.Meta{}, Response: images.ImageMeta{}},
+
+	// Media
+	{Method: "GET", Path: "/api/v1/media", Tag: "media", Summary: "PDFs, video, audio and downloads in static/ with their metadata",
+		Query: []openapi.Parameter{
+			{Name: "kind", Description: "pdf, video, audio or download"},
+			{Name: "folder", Description: "Folder under static/"},
+		},
+		Response: []media.Item{}},
+	{Method: "POST", Path: "/api/v1/media", Tag: "media", Summary: "Upload a media file within the size limit of its type",
+		Form: mediaUploadForm{}, Response: mediaUploadResponse{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/media/types", Tag: "media", Summary: "Media kinds with their extensions and upload limits",
+		Response: []media.Type{}},
+	{Method: "GET", Path: "/api/v1/media/snippet", Tag: "media", Summary: "Markdown, HTML and shortcode that embed or link a media file",
+		Query: []openapi.Parameter{
+			{Name: "path", Required: true, Description: "Project-relative path of the file, in static/"},
+			{Name: "title", Description: "Link text, the file name by default"},
+		},
+		Response: media.Snippet{}},
+	{Method: "POST", Path: "/api/v1/media/poster", Tag: "media", Summary: "Make the responsive poster set of a video with ffmpeg; 501 ERR_UNAVAILABLE without ffmpeg",
+		Request: mediaPosterRequest{}, Response: mediaUploadResponse{}},
+
+	// Hugo
+	{Method: "GET", Path: "/api/v1/hugo/instances", Tag: "hugo", Summary: "The main Hugo server and those of hugo.instances, with their status, port and options",
+		Response: []hugo.Instance{}},
+	{Method: "GET", Path: "/api/v1/hugo/status", Tag: "hugo", Summary: "Hugo server status",
+		Query:    []openapi.Parameter{instanceParam},
+		Response: hugoStatusResponse{}},
+	{Method: "POST", Path: "/api/v1/hugo/start", Tag: "hugo", Summary: "Start Hugo",
+		Query:    []openapi.Parameter{instanceParam},
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/v1/hugo/stop", Tag: "hugo", Summary: "Stop Hugo",
+		Query:    []openapi.Parameter{instanceParam},
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/v1/hugo/restart", Tag: "hugo", Summary: "Restart Hugo",
+		Query:    []openapi.Parameter{instanceParam},
+		Response: successResponse{}},
+	{Method: "PUT", Path: "/api/v1/hugo/options", Tag: "hugo", Summary: "Toggle drafts, future and expired content or switch profile, restarting Hugo if it runs",
+		Query:   []openapi.Parameter{instanceParam},
+		Request: hugo.OptionsUpdate{}, Response: hugoOptionsResponse{}},
+	{Method: "GET", Path: "/api/v1/hugo/profiles", Tag: "hugo", Summary: "Configured environment and baseURL profiles",
+		Response: hugo.ProfilesInfo{}},
+	{Method: "POST", Path: "/api/v1/hugo/build", Tag: "hugo", Summary: "Build the site into its publish directory with a profile",
+		Request: hugoBuildRequest{}, Response: hugoBuildResponse{}},
+	{Method: "GET", Path: "/api/v1/hugo/logs", Tag: "hugo", Summary: "Recent Hugo logs",
+		Query: []openapi.Parameter{
+			{Name: "limit", Description: "Maximum entries", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "level", Description: "Comma-separated levels: error, warn, info, rebuild, livereload"},
+			instanceParam,
+		},
+		Response: []hugo.LogEntry{}},
+	{Method: "GET", Path: "/api/v1/hugo/errors", Tag: "hugo", Summary: "Errors of the current build, with file and line when Hugo reports them",
+		Query: []openapi.Parameter{instanceParam,
+			{Name: "path", Description: "Only the errors in this file, or in files inside this directory", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Response: hugoErrorsResponse{}},
+	{Method: "GET", Path: "/api/v1/hugo/ws", Tag: "hugo", Summary: "WebSocket streaming LogEntry messages and StatusEvent transitions (type \"status\")",
+		Status: http.StatusSwitchingProtocols},
+	{Method: "GET", Path: "/api/v1/build/artifacts", Tag: "build", Summary: "Files of the latest build with sizes, compared with the previous build",
+		Query: []openapi.Parameter{
+			{Name: "path", Description: "List only the files under this path of the publish directory", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Response: artifacts.Report{}},
+	{Method: "GET", Path: "/api/v1/build/report", Tag: "build", Summary: "Checks of the latest build: missing pages, broken asset references and empty titles",
+		Response: artifacts.CheckReport{}},
+	{Method: "GET", Path: "/api/v1/tasks", Tag: "tasks", Summary: "Configured external commands with the status of their last runs",
+		Response: []tasks.Task{}},
+	{Method: "GET", Path: "/api/v1/tasks/{name}", Tag: "tasks", Summary: "A task with the output of its last run",
+		Response: tasks.Task{}},
+	{Method: "POST", Path: "/api/v1/tasks/{name}/run", Tag: "tasks", Summary: "Run a task, streaming NDJSON lines: start, output, then exit (or running if the stream ends first)",
+		Response: taskStreamLine{}, ContentType: "application/x-ndjson"},
+	{Method: "POST", Path: "/api/v1/tasks/{name}/stop", Tag: "tasks", Summary: "Stop a running task and its child processes",
+		Response: tasks.Run{}},
+	{Method: "GET", Path: "/api/v1/ws", Tag: "realtime", Summary: "WebSocket multiplexing realtime Message events by topic; send {action, topics} to change the subscription",
+		Query:  []openapi.Parameter{{Name: "topics", Description: "Comma-separated topics: logs, status, files, jobs (default: all)"}},
+		Status: http.StatusSwitchingProtocols},
+
+	// Configuration
+	{Method: "GET", Path: "/api/v1/config", Tag: "config", Summary: "Read the hugo-manager configuration",
+		Response: config.Config{}},
+	{Method: "PUT", Path: "/api/v1/config", Tag: "config", Summary: "Validate and replace the hugo-manager configuration; 422 ERR_INVALID_CONFIG lists the errors",
+		Request: config.Config{}, Response: configSaveResponse{}},
+	{Method: "POST", Path: "/api/v1/config/validate", Tag: "config", Summary: "Check a configuration without saving it; errors and warnings come with their YAML path",
+		Request: config.Config{}, Response: configValidateResponse{}},
+
+	// Auth and users
+	{Method: "POST", Path: "/api/v1/auth/login", Tag: "auth", Summary: "Sign in and set the session cookie",
+		Request: loginRequest{}, Response: loginResponse{}},
+	{Method: "POST", Path: "/api/v1/auth/logout", Tag: "auth", Summary: "End the session",
+		Response: successResponse{}},
+	{Method: "GET", Path: "/api/v1/auth/me", Tag: "auth", Summary: "Who made the request",
+		Response: authMeResponse{}},
+	{Method: "GET", Path: "/api/v1/users", Tag: "auth", Summary: "List users",
+		Response: []users.User{}},
+	{Method: "POST", Path: "/api/v1/users", Tag: "auth", Summary: "Add a user",
+		Request: userCreateRequest{}, Response: userResponse{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/v1/users/{username}", Tag: "auth", Summary: "Change a user",
+		Request: users.Update{}, Response: userResponse{}},
+	{Method: "DELETE", Path: "/api/v1/users/{username}", Tag: "auth", Summary: "Remove a user",
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/v1/users/{username}/token", Tag: "auth", Summary: "Issue a new API token for a user",
+		Response: userResponse{}},
+	{Method: "GET", Path: "/api/v1/activity", Tag: "auth", Summary: "Audit trail of the changes made through the API",
+		Query: []openapi.Parameter{
+			{Name: "since", Description: "RFC 3339 time, or a duration back from now such as 24h"},
+			{Name: "path", Description: "Only changes to this path or the files inside it"},
+			{Name: "user", Description: "Only the changes of this username"},
+			{Name: "limit", Description: "Number of entries (default 100, max 1000)"},
+		},
+		Response: []activity.Entry{}},
+
+	// Review comments
+	{Method: "GET", Path: "/api/v1/comments", Tag: "comments", Summary: "List review comments, by file and line",
+		Query: []openapi.Parameter{
+			{Name: "path", Description: "Only comments on this file or the files inside this folder"},
+			{Name: "status", Description: "open or resolved"},
+			{Name: "author", Description: "Only the comments of this username"},
+		},
+		Response: []comments.Comment{}},
+	{Method: "POST", Path: "/api/v1/comments", Tag: "comments", Summary: "Comment on a range of lines of a text file, withdrawing its approval",
+		Request: commentCreateRequest{}, Response: comments.Comment{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/comments/reviews", Tag: "comments", Summary: "Review state of every file with comments or an approval",
+		Response: []comments.Review{}},
+	{Method: "GET", Path: "/api/v1/comments/reviews/{path}", Tag: "comments", Summary: "Open and resolved comments of a file, and its approval",
+		Response: comments.Review{}},
+	{Method: "PUT", Path: "/api/v1/comments/reviews/{path}", Tag: "comments", Summary: "Approve a file for publishing (admins only); 409 ERR_OPEN_COMMENTS while comments are open",
+		Response: comments.Review{}},
+	{Method: "DELETE", Path: "/api/v1/comments/reviews/{path}", Tag: "comments", Summary: "Withdraw the approval of a file (admins only)",
+		Response: comments.Review{}},
+	{Method: "GET", Path: "/api/v1/comments/{id}", Tag: "comments", Summary: "Get a comment",
+		Response: comments.Comment{}},
+	{Method: "PUT", Path: "/api/v1/comments/{id}", Tag: "comments", Summary: "Edit a comment (its author or an admin) or resolve and reopen it (anyone); reopening withdraws the approval",
+		Request: comments.Update{}, Response: comments.Comment{}},
+	{Method: "DELETE", Path: "/api/v1/comments/{id}", Tag: "comments", Summary: "Delete a comment (its author or an admin)",
+		Response: successResponse{}},
+
+	// Workspaces
+	{Method: "GET", Path: "/api/v1/workspaces", Tag: "workspaces", Summary: "Draft workspaces, with the state of their previews",
+		Response: []workspaces.Workspace{}},
+	{Method: "POST", Path: "/api/v1/workspaces", Tag: "workspaces", Summary: "Copy the site into a new draft workspace; edit it through the file routes with ?workspace=",
+		Request: workspaceCreateRequest{}, Response: workspaces.Workspace{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/workspaces/{name}", Tag: "workspaces", Summary: "A workspace with the files changed in it, flagging those changed in the live tree too",
+		Response: workspaceResponse{}},
+	{Method: "DELETE", Path: "/api/v1/workspaces/{name}", Tag: "workspaces", Summary: "Discard a workspace and its changes",
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/v1/workspaces/{name}/merge", Tag: "workspaces", Summary: "Write the changes of a workspace into the live tree and discard it; 409 ERR_CONFLICT when files changed live too, unless force",
+		Request: workspaceMergeRequest{}, Response: workspaceMergeResponse{}},
+	{Method: "POST", Path: "/api/v1/workspaces/{name}/preview", Tag: "workspaces", Summary: "Start a Hugo server building the workspace, on a port of workspaces.port",
+		Response: workspaces.Workspace{}},
+	{Method: "DELETE", Path: "/api/v1/workspaces/{name}/preview", Tag: "workspaces", Summary: "Stop the Hugo server of a workspace",
+		Response: workspaces.Workspace{}},
+
+	// Linting
+	{Method: "GET", Path: "/api/v1/lint/shortcodes", Tag: "lint", Summary: "Validate shortcode calls in content files",
+		Query:    []openapi.Parameter{{Name: "path", Description: "Lint a single file"}},
+		Response: lint.Report{}},
+	{Method: "POST", Path: "/api/v1/lint/shortcodes", Tag: "lint", Summary: "Validate shortcode calls in unsaved content",
+		Request: lintContentRequest{}, Response: lint.Report{}},
+	{Method: "GET", Path: "/api/v1/lint/links", Tag: "lint", Summary: "Find broken refs, links and images in content files",
+		Query: []openapi.Parameter{
+			{Name: "path", Description: "Lint a single file"},
+			{Name: "external", Description: "Also request links to other sites, at the configured rate"},
+		},
+		Response: lint.Report{}},
+	{Method: "GET", Path: "/api/v1/lint/markdown", Tag: "lint", Summary: "Find heading jumps, trailing spaces, bare URLs and malformed tables in content files",
+		Query:    []openapi.Parameter{{Name: "path", Description: "Lint a single file"}},
+		Response: lint.Report{}},
+	{Method: "POST", Path: "/api/v1/lint/markdown", Tag: "lint", Summary: "Lint the markdown of unsaved content",
+		Request: lintContentRequest{}, Response: lint.Report{}},
+	{Method: "POST", Path: "/api/v1/lint/markdown/fix", Tag: "lint", Summary: "Fix the markdown of unsaved content, a file or every content file; without confirm only the corrected content is returned",
+		Request: markdownFixRequest{}, Response: lint.FixResult{}},
+	{Method: "GET", Path: "/api/v1/lint/prose", Tag: "lint", Summary: "Check the spelling and style of the prose of a content file",
+		Query:    []openapi.Parameter{{Name: "path", Required: true, Description: "File to check"}},
+		Response: lint.ProseReport{}},
+	{Method: "POST", Path: "/api/v1/lint/prose", Tag: "lint", Summary: "Check the spelling and style of unsaved content",
+		Request: lintContentRequest{}, Response: lint.ProseReport{}},
+	{Method: "GET", Path: "/api/v1/lint/dictionary", Tag: "lint", Summary: "Project word list, accepted as spelled right in every language",
+		Response: dictionaryResponse{}},
+	{Method: "POST", Path: "/api/v1/lint/dictionary", Tag: "lint", Summary: "Add a word to the project word list",
+		Request: dictionaryWordRequest{}, Response: dictionaryResponse{}},
+	{Method: "DELETE", Path: "/api/v1/lint/dictionary/{word}", Tag: "lint", Summary: "Remove a word from the project word list",
+		Response: dictionaryResponse{}},
+	{Method: "GET", Path: "/api/v1/lint/frontmatter", Tag: "lint", Summary: "Check the front matter of pages against the schema of their section",
+		Query:    []openapi.Parameter{{Name: "path", Description: "Lint a single file"}},
+		Response: lint.Report{}},
+	{Method: "POST", Path: "/api/v1/lint/frontmatter", Tag: "lint", Summary: "Check the front matter of unsaved content",
+		Request: lintContentRequest{}, Response: lint.Report{}},
+
+	// Domain
+	{Method: "GET", Path: "/api/v1/domain", Tag: "domain", Summary: "Latest domain DNS/TLS report",
+		Response: domain.Report{}},
+	{Method: "POST", Path: "/api/v1/domain/check", Tag: "domain", Summary: "Re-run domain checks",
+		Response: domain.Report{}},
+
+	// Events
+	{Method: "GET", Path: "/api/v1/events", Tag: "events", Summary: "Upcoming pages of recurring event series",
+		Response: []events.Event{}},
+	{Method: "POST", Path: "/api/v1/events/generate", Tag: "events", Summary: "Generate pages for the next occurrences of every series",
+		Response: events.GenerateResult{}},
+	{Method: "GET", Path: "/api/v1/events.ics", Tag: "events", Summary: "iCalendar feed of upcoming events",
+		ContentType: "text/calendar"},
+
+	// Import
+	{Method: "POST", Path: "/api/v1/import", Tag: "import", Summary: "Convert a WordPress, Ghost or Jekyll export to content, with its images and a map of old URLs to new paths",
+		Form: importForm{}, Response: importer.Result{}},
+	{Method: "POST", Path: "/api/v1/import/archive", Tag: "import", Summary: "Write the files of an archive made by /api/export, checked against its manifest first",
+		Form: importArchiveForm{}, Response: transfer.Result{}},
+	{Method: "POST", Path: "/api/v1/export", Tag: "import", Summary: "Zip selected sections and pages with their bundles, the static files they point to and a manifest",
+		Request: exportRequest{}, ContentType: "application/zip"},
+
+	// Podcast
+	{Method: "GET", Path: "/api/v1/podcast/episodes", Tag: "podcast", Summary: "Preview the feed channel and episode enclosures",
+		Response: podcast.Feed{}},
+	{Method: "POST", Path: "/api/v1/podcast/episodes", Tag: "podcast", Summary: "Read an audio file's enclosure metadata into an episode page",
+		Request: podcastIngestRequest{}, Response: podcast.IngestResult{}},
+	{Method: "GET", Path: "/api/v1/podcast/validate", Tag: "podcast", Summary: "Check the fields Apple Podcasts requires",
+		Response: podcastValidateResponse{}},
+
+	// Product catalog
+	{Method: "GET", Path: "/api/v1/catalog/products", Tag: "catalog", Summary: "List the products of the data file",
+		Response: []catalog.Product{}},
+	{Method: "POST", Path: "/api/v1/catalog/products", Tag: "catalog", Summary: "Add a product",
+		Request: catalog.Product{}, Response: catalog.Product{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v1/catalog/products/{sku}", Tag: "catalog", Summary: "Read a product",
+		Response: catalog.Product{}},
+	{Method: "PUT", Path: "/api/v1/catalog/products/{sku}", Tag: "catalog", Summary: "Replace or rename a product",
+		Request: catalog.Product{}, Response: catalog.Product{}},
+	{Method: "DELETE", Path: "/api/v1/catalog/products/{sku}", Tag: "catalog", Summary: "Remove a product from the data file",
+		Response: successResponse{}},
+	{Method: "POST", Path: "/api/v1/catalog/products/{sku}/images", Tag: "catalog", Summary: "Upload a product photo, cropped to the catalog aspect ratio",
+		Form: productImageForm{}, Response: catalog.ImageResult{}},
+	{Method: "GET", Path: "/api/v1/catalog/validate", Tag: "catalog", Summary: "Check prices, SKUs, photos and pages",
+		Response: catalogValidateResponse{}},
+	{Method: "POST", Path: "/api/v1/catalog/generate", Tag: "catalog", Summary: "Create or refresh a page for every product",
+		Response: catalog.GenerateResult{}},
+
+	// Forms
+	{Method: "GET", Path: "/api/v1/forms", Tag: "forms", Summary: "Endpoints of the configured forms",
+		Response: map[string]forms.Endpoint{}},
+	{Method: "POST", Path: "/api/v1/forms/generate", Tag: "forms", Summary: "Write the form endpoints to the forms data file",
+		Response: forms.GenerateResult{}},
+	{Method: "GET", Path: "/api/v1/forms/scan", Tag: "forms", Summary: "Find forms in templates and content that post to dead or unconfigured endpoints",
+		Query:    []openapi.Parameter{{Name: "probe", Description: "Request remote endpoints to detect dead ones"}},
+		Response: forms.ScanReport{}},
+	{Method: "POST", Path: "/api/v1/forms/{name}/test", Tag: "forms", Summary: "Send a test submission to a form's endpoint",
+		Response: forms.TestResult{}},
+
+	// Scripts
+	{Method: "GET", Path: "/api/v1/scripts", Tag: "scripts", Summary: "Managed scripts, consent settings and the state of their partials",
+		Response: scripts.Overview{}},
+	{Method: "POST", Path: "/api/v1/scripts/generate", Tag: "scripts", Summary: "Write the script and consent banner partials",
+		Response: scripts.GenerateResult{}},
+	{Method: "GET", Path: "/api/v1/scripts/audit", Tag: "scripts", Summary: "Find scripts and embeds in the built site that load before consent",
+		Response: scripts.AuditReport{}},
+
+	// Snippets
+	{Method: "POST", Path: "/api/v1/snippets/image", Tag: "snippets", Summary: "Accessible image markup, in a figure when captioned",
+		Request: snippets.Image{}, Response: snippets.Result{}},
+	{Method: "POST", Path: "/api/v1/snippets/gallery", Tag: "snippets", Summary: "Accessible gallery markup with a named group of images",
+		Request: snippets.Gallery{}, Response: snippets.Result{}},
+	{Method: "POST", Path: "/api/v1/snippets/button", Tag: "snippets", Summary: "Accessible link or action button markup",
+		Request: snippets.Button{}, Response: snippets.Result{}},
+
+	// Health
+	{Method: "GET", Path: "/api/v1/health/score", Tag: "health", Summary: "Weighted project health score from links, alt text, stale drafts, validation, build and asset checks, with its trend",
+		Response: health.Report{}},
+
+	// Dashboard
+	{Method: "GET", Path: "/api/v1/dashboard", Tag: "dashboard", Summary: "Configured dashboard widgets in order, with their data or why they have none",
+		Response: dashboard.Dashboard{}},
+
+	// Storage
+	{Method: "GET", Path: "/api/v1/storage", Tag: "storage", Summary: "Disk usage of trash, history and cache",
+		Response: storageUsageResponse{}},
+	{Method: "POST", Path: "/api/v1/storage/gc", Tag: "storage", Summary: "Run storage GC and report reclaimed space",
+		Response: storage.Report{}},
+
+	// Data files
+	{Method: "GET", Path: "/api/v1/data", Tag: "data", Summary: "List data files for shortcode selectors",
+		Response: []files.FileInfo{}},
+	{Method: "GET", Path: "/api/v1/data/*", Tag: "data", Summary: "List data files of a type",
+		Response: []files.FileInfo{}},
+
+	// Utilities
+	{Method: "GET", Path: "/api/v1/utils/slug", Tag: "utils", Summary: "URL-safe slug of a title, numbered when a file in the folder already uses it",
+		Query: []openapi.Parameter{
+			{Name: "title", Required: true, Description: "Title to slugify"},
+			{Name: "dir", Description: "Project-relative folder the file goes in, checked for collisions"},
+		},
+		Response: slugResponse{}},
+
+	// Spec
+	{Method: "GET", Path: "/api/v1/spec", Tag: "meta", Summary: "This OpenAPI document",
+		Response: map[string]interface{}{}},
+}
+
+var (
+	specOnce sync.Once
+	specDoc  *openapi.Document
+)
+
+// apiSpec builds the OpenAPI document from apiRoutes
+func apiSpec() *openapi.Document {
+	specOnce.Do(func() {
+		doc := openapi.New("Hugo Manager API", apiVersion,
+			"REST API of hugo-manager. Errors share the errorResponse shape with a stable errorCode.")
+		for _, route := range apiRoutes {
+			doc.Add(route, errorResponse{})
+		}
+		specDoc = doc
+	})
+	return specDoc
+}
+
+// handleSpec serves the OpenAPI document describing the REST API
+func (s *Server) handleSpec(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, apiSpec(), http.StatusOK)
+}
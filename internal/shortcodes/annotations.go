@@ -0,0 +1,168 @@
+package shortcodes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Annotations are read from comment blocks in shortcode templates:
+//
+//	{{/*
+//	  @description "Responsive image with srcset"
+//	  @param src string required "Image URL"
+//	  @param loading string default=lazy "Loading strategy"
+//	  @param author file:personas "Author page"
+//	  @inner "Caption text"
+//	*/}}
+//
+// Annotated values take precedence over the heuristics used for undocumented parameters.
+var (
+	// Match {{/* ... */}} comment blocks, with optional whitespace trimming markers
+	commentBlockRe = regexp.MustCompile(`(?s)\{\{-?\s*/\*(.*?)\*/\s*-?\}\}`)
+
+	// Match key="quoted value", quoted strings or bare words
+	annotationTokenRe = regexp.MustCompile(`\w+="(?:[^"\\]|\\.)*"|"(?:[^"\\]|\\.)*"|\S+`)
+)
+
+// annotations holds the metadata declared in a shortcode's comment blocks
+type annotations struct {
+	Description string
+	Inner       bool
+	InnerHint   string
+	Params      []Parameter
+}
+
+// annotationTypes maps accepted type names to parameter types
+var annotationTypes = map[string]string{
+	"string":  "string",
+	"text":    "string",
+	"bool":    "boolean",
+	"boolean": "boolean",
+	"number":  "number",
+	"int":     "number",
+	"float":   "number",
+	"file":    "file",
+}
+
+// parseAnnotations extracts @description, @param and @inner annotations from content
+func parseAnnotations(content string) annotations {
+	var ann annotations
+
+	for _, block := range commentBlockRe.FindAllStringSubmatch(content, -1) {
+		for _, line := range strings.Split(block[1], "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*"))
+			if !strings.HasPrefix(line, "@") {
+				continue
+			}
+
+			tokens := annotationTokenRe.FindAllString(line, -1)
+			switch tokens[0] {
+			case "@description":
+				ann.Description = joinTokens(tokens[1:])
+			case "@inner":
+				ann.Inner = true
+				ann.InnerHint = joinTokens(tokens[1:])
+			case "@param":
+				if param, ok := parseParamAnnotation(tokens[1:]); ok {
+					ann.Params = append(ann.Params, param)
+				}
+			}
+		}
+	}
+
+	return ann
+}
+
+// parseParamAnnotation parses "name type [required|optional] [default=x] [placeholder=x] ["description"]"
+func parseParamAnnotation(tokens []string) (Parameter, bool) {
+	if len(tokens) == 0 {
+		return Parameter{}, false
+	}
+
+	param := Parameter{Name: tokens[0], Type: "string"}
+	rest := tokens[1:]
+
+	if len(rest) > 0 && !isQuoted(rest[0]) {
+		typeName, fileType, _ := strings.Cut(rest[0], ":")
+		if t, ok := annotationTypes[strings.ToLower(typeName)]; ok {
+			param.Type = t
+			param.FileType = fileType
+			rest = rest[1:]
+		}
+	}
+
+	var description []string
+	for _, tok := range rest {
+		switch {
+		case tok == "required":
+			param.Required = true
+		case tok == "optional":
+			param.Required = false
+		case strings.HasPrefix(tok, "default="):
+			param.Default = unquoteToken(strings.TrimPrefix(tok, "default="))
+		case strings.HasPrefix(tok, "placeholder="):
+			param.Placeholder = unquoteToken(strings.TrimPrefix(tok, "placeholder="))
+		default:
+			description = append(description, tok)
+		}
+	}
+	param.Description = joinTokens(description)
+
+	return param, true
+}
+
+// applyAnnotations overrides heuristically detected metadata with annotated values
+func applyAnnotations(sc *Shortcode, params map[string]*Parameter, ann annotations) {
+	if ann.Description != "" {
+		sc.Description = ann.Description
+	}
+	if ann.Inner {
+		sc.HasInner = true
+		if ann.InnerHint != "" {
+			sc.InnerHint = ann.InnerHint
+		}
+	}
+
+	for _, annotated := range ann.Params {
+		param := annotated
+		if param.Description == "" {
+			if detected, ok := params[param.Name]; ok {
+				param.Description = detected.Description
+			}
+		}
+		if param.Type == "file" && param.FileType == "" {
+			param.FileType = inferFileType(param.Name, "")
+		}
+		if param.Placeholder == "" {
+			if param.Default != "" {
+				param.Placeholder = param.Default
+			} else {
+				param.Placeholder = generatePlaceholder(param.Name, param.Type, param.FileType)
+			}
+		}
+		params[param.Name] = &param
+	}
+}
+
+func joinTokens(tokens []string) string {
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		parts[i] = unquoteToken(tok)
+	}
+	return strings.Join(parts, " ")
+}
+
+func isQuoted(tok string) bool {
+	return len(tok) >= 2 && tok[0] == '"' && tok[len(tok)-1] == '"'
+}
+
+func unquoteToken(tok string) string {
+	if isQuoted(tok) {
+		if s, err := strconv.Unquote(tok); err == nil {
+			return s
+		}
+		return tok[1 : len(tok)-1]
+	}
+	return tok
+}
@@ -2,11 +2,14 @@ package shortcodes
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
 )
 
 // Shortcode represents a detected Hugo shortcode
@@ -18,6 +21,7 @@ type Shortcode struct {
 	InnerHint   string      `json:"innerHint,omitempty"`
 	Description string      `json:"description,omitempty"`
 	Template    string      `json:"template"`
+	Source      string      `json:"source"` // "project", "theme:<name>" or "module:<path>"
 }
 
 // Parameter represents a shortcode parameter
@@ -62,52 +66,182 @@ var (
 	ifGetRe = regexp.MustCompile(`{{\s*if\s+\.Get\s+["'\x60]([^"'\x60]+)["'\x60]\s*}}`)
 )
 
-// DetectAll scans the shortcodes directory and detects all shortcodes
+// Shortcode sources
+const (
+	SourceProject = "project"
+	SourceTheme   = "theme"
+	SourceModule  = "module"
+)
+
+// shortcodeRoot is a directory that may contain shortcode templates
+type shortcodeRoot struct {
+	dir    string
+	source string
+}
+
+// shortcodeExts are the template extensions recognized as shortcodes
+var shortcodeExts = map[string]bool{
+	".html": true,
+	".md":   true,
+}
+
+// DetectAll scans the project, module and theme shortcode directories and detects all shortcodes.
+// Project shortcodes take precedence over modules, and modules over themes, as in Hugo.
 func (p *Parser) DetectAll() ([]Shortcode, error) {
-	shortcodesDir := filepath.Join(p.projectDir, "layouts", "shortcodes")
-	
-	if _, err := os.Stat(shortcodesDir); os.IsNotExist(err) {
-		return []Shortcode{}, nil
+	var shortcodes []Shortcode
+	seen := make(map[string]bool)
+
+	for _, root := range p.roots() {
+		found, err := p.DetectDir(root.dir, root.source)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range found {
+			if seen[sc.Name] {
+				continue
+			}
+			seen[sc.Name] = true
+			shortcodes = append(shortcodes, sc)
+		}
 	}
 
+	if shortcodes == nil {
+		shortcodes = []Shortcode{}
+	}
+
+	// Sort alphabetically
+	sort.Slice(shortcodes, func(i, j int) bool {
+		return shortcodes[i].Name < shortcodes[j].Name
+	})
+
+	return shortcodes, nil
+}
+
+// DetectDir detects the shortcodes of one site, theme or module directory, whether it's in use or
+// not. Templates in layouts/_shortcodes take precedence over those in layouts/shortcodes.
+func (p *Parser) DetectDir(dir, source string) ([]Shortcode, error) {
 	var shortcodes []Shortcode
+	seen := make(map[string]bool)
+	for _, layoutsDir := range []string{"_shortcodes", "shortcodes"} {
+		shortcodesDir := filepath.Join(dir, "layouts", layoutsDir)
+		if _, err := os.Stat(shortcodesDir); os.IsNotExist(err) {
+			continue
+		}
 
-	entries, err := os.ReadDir(shortcodesDir)
+		found, err := p.detectIn(shortcodesDir, source)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range found {
+			if seen[sc.Name] {
+				continue
+			}
+			seen[sc.Name] = true
+			shortcodes = append(shortcodes, sc)
+		}
+	}
+	sort.Slice(shortcodes, func(i, j int) bool {
+		return shortcodes[i].Name < shortcodes[j].Name
+	})
+	return shortcodes, nil
+}
+
+// roots returns the directories to scan for shortcodes in precedence order
+func (p *Parser) roots() []shortcodeRoot {
+	roots := []shortcodeRoot{{dir: p.projectDir, source: SourceProject}}
+
+	siteCfg, err := site.Load(p.projectDir)
 	if err != nil {
-		return nil, err
+		return roots
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	for _, mod := range siteCfg.ModuleImports() {
+		if dir := site.ModuleDir(p.projectDir, mod); dir != "" {
+			roots = append(roots, shortcodeRoot{dir: dir, source: SourceModule + ":" + mod})
 		}
+	}
 
-		name := entry.Name()
-		ext := filepath.Ext(name)
-		if ext != ".html" {
+	for _, theme := range siteCfg.Themes() {
+		themeDir := filepath.Join(p.projectDir, "themes", theme)
+		if stat, err := os.Stat(themeDir); err == nil && stat.IsDir() {
+			roots = append(roots, shortcodeRoot{dir: themeDir, source: SourceTheme + ":" + theme})
 			continue
 		}
+		// Themes can also be declared as Hugo Modules
+		if dir := site.ModuleDir(p.projectDir, theme); dir != "" {
+			roots = append(roots, shortcodeRoot{dir: dir, source: SourceTheme + ":" + theme})
+		}
+	}
+
+	return roots
+}
+
+// detectIn parses all shortcode templates under dir, including nested subdirectories
+func (p *Parser) detectIn(dir, source string) ([]Shortcode, error) {
+	// Map shortcode name to template file, preferring the plain template over
+	// output format and language variants (e.g. img.amp.html, note.en.html)
+	files := make(map[string]string)
+	variant := make(map[string]bool)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
 
-		shortcodeName := strings.TrimSuffix(name, ext)
-		filePath := filepath.Join(shortcodesDir, name)
+		ext := filepath.Ext(d.Name())
+		if !shortcodeExts[ext] {
+			return nil
+		}
 
-		content, err := os.ReadFile(filePath)
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, ext))
+
+		isVariant := false
+		base := filepath.Base(rel)
+		if i := strings.Index(base, "."); i > 0 {
+			rel = strings.TrimSuffix(rel, base) + base[:i]
+			isVariant = true
+		}
+
+		// Only replace an existing entry when a variant is superseded by the plain template
+		if _, ok := files[rel]; ok && (isVariant || !variant[rel]) {
+			return nil
+		}
+		files[rel] = path
+		variant[rel] = isVariant
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var shortcodes []Shortcode
+	for name, path := range files {
+		content, err := os.ReadFile(path)
 		if err != nil {
 			continue
 		}
 
-		sc := p.parseShortcode(shortcodeName, name, string(content))
+		sc := p.parseShortcode(name, p.displayPath(path), string(content))
+		sc.Source = source
 		shortcodes = append(shortcodes, sc)
 	}
 
-	// Sort alphabetically
-	sort.Slice(shortcodes, func(i, j int) bool {
-		return shortcodes[i].Name < shortcodes[j].Name
-	})
-
 	return shortcodes, nil
 }
 
+// displayPath returns path relative to the project, or absolute if outside of it
+func (p *Parser) displayPath(path string) string {
+	rel, err := filepath.Rel(p.projectDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
 // parseShortcode parses a single shortcode template
 func (p *Parser) parseShortcode(name, file, content string) Shortcode {
 	sc := Shortcode{
@@ -176,6 +310,9 @@ func (p *Parser) parseShortcode(name, file, content string) Shortcode {
 		}
 	}
 
+	// Explicit annotations take precedence over inferred metadata
+	applyAnnotations(&sc, params, parseAnnotations(content))
+
 	// Convert map to slice, sorted by: required first, then alphabetically
 	for _, param := range params {
 		sc.Parameters = append(sc.Parameters, *param)
@@ -188,7 +325,7 @@ func (p *Parser) parseShortcode(name, file, content string) Shortcode {
 	})
 
 	// Generate inner hint if applicable
-	if sc.HasInner {
+	if sc.HasInner && sc.InnerHint == "" {
 		sc.InnerHint = generateInnerHint(name)
 	}
 
@@ -387,5 +524,5 @@ func (p *Parser) GetShortcode(name string) (*Shortcode, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("shortcode not found: %s", name)
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
 }
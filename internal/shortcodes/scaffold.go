@@ -0,0 +1,203 @@
+package shortcodes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors returned when creating or editing shortcodes
+var (
+	ErrExists      = errors.New("shortcode already exists")
+	ErrNotFound    = errors.New("shortcode not found")
+	ErrInvalidName = errors.New("invalid shortcode name")
+)
+
+// validNameRe allows nested shortcode names such as "blog/card"
+var validNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$`)
+
+// ParamSpec describes a parameter for a scaffolded shortcode
+type ParamSpec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // "string", "boolean", "file", "number"
+	Required    bool   `json:"required"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ScaffoldOptions describes a new shortcode template
+type ScaffoldOptions struct {
+	Description string      `json:"description"`
+	Params      []ParamSpec `json:"params"`
+	Inner       bool        `json:"inner"`
+}
+
+// Create writes a new shortcode template to layouts/shortcodes/<name>.html and returns the parsed definition.
+// If content is empty, a starter template is generated from opts.
+func (p *Parser) Create(name, content string, opts ScaffoldOptions) (*Shortcode, error) {
+	if !validNameRe.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, name)
+	}
+
+	if existing, _ := p.GetShortcode(name); existing != nil && existing.Source == SourceProject {
+		return nil, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+
+	if content == "" {
+		content = Scaffold(name, opts)
+	}
+
+	path := filepath.Join(p.projectDir, "layouts", "shortcodes", filepath.FromSlash(name)+".html")
+	if err := writeTemplate(path, content); err != nil {
+		return nil, err
+	}
+
+	return p.GetShortcode(name)
+}
+
+// Update replaces the template of an existing shortcode and returns the re-parsed definition.
+// Theme and module shortcodes are overridden by a project template with the same name.
+func (p *Parser) Update(name, content string) (*Shortcode, error) {
+	if !validNameRe.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidName, name)
+	}
+
+	existing, err := p.GetShortcode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(p.projectDir, "layouts", "shortcodes", filepath.FromSlash(name)+".html")
+	if existing.Source == SourceProject {
+		path = filepath.Join(p.projectDir, filepath.FromSlash(existing.File))
+	}
+
+	if err := writeTemplate(path, content); err != nil {
+		return nil, err
+	}
+
+	return p.GetShortcode(name)
+}
+
+// ReadTemplate returns the raw template source of a shortcode
+func (p *Parser) ReadTemplate(name string) (string, error) {
+	existing, err := p.GetShortcode(name)
+	if err != nil {
+		return "", err
+	}
+
+	path := existing.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.projectDir, filepath.FromSlash(path))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func writeTemplate(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// Scaffold generates a starter shortcode template with annotations for the given parameters
+func Scaffold(name string, opts ScaffoldOptions) string {
+	var sb strings.Builder
+
+	sb.WriteString("{{/*\n")
+	description := opts.Description
+	if description == "" {
+		description = name + " shortcode"
+	}
+	sb.WriteString(fmt.Sprintf("  @description %s\n", strconv.Quote(description)))
+	for _, param := range opts.Params {
+		sb.WriteString("  @param " + param.Name + " " + annotationType(param.Type))
+		if param.Required {
+			sb.WriteString(" required")
+		}
+		if param.Default != "" {
+			sb.WriteString(" default=" + strconv.Quote(param.Default))
+		}
+		if param.Description != "" {
+			sb.WriteString(" " + strconv.Quote(param.Description))
+		}
+		sb.WriteString("\n")
+	}
+	if opts.Inner {
+		sb.WriteString("  @inner\n")
+	}
+	sb.WriteString("*/}}\n\n")
+
+	for _, param := range opts.Params {
+		sb.WriteString(fmt.Sprintf("{{- $%s := .Get %q", templateVar(param.Name), param.Name))
+		if param.Default != "" {
+			if param.Type == "boolean" || param.Type == "number" {
+				sb.WriteString(" | default " + param.Default)
+			} else {
+				sb.WriteString(fmt.Sprintf(" | default %q", param.Default))
+			}
+		}
+		sb.WriteString(" -}}\n")
+	}
+	if len(opts.Params) > 0 {
+		sb.WriteString("\n")
+	}
+
+	class := strings.ReplaceAll(name, "/", "-")
+	sb.WriteString(fmt.Sprintf("<div class=\"%s\">\n", class))
+	for _, param := range opts.Params {
+		v := templateVar(param.Name)
+		if param.Required {
+			sb.WriteString(fmt.Sprintf("  <span class=\"%s-%s\">{{ $%s }}</span>\n", class, param.Name, v))
+		} else {
+			sb.WriteString(fmt.Sprintf("  {{- with $%s }}\n  <span class=\"%s-%s\">{{ . }}</span>\n  {{- end }}\n", v, class, param.Name))
+		}
+	}
+	if opts.Inner {
+		sb.WriteString("  {{ .Inner | markdownify }}\n")
+	}
+	sb.WriteString("</div>\n")
+
+	return sb.String()
+}
+
+func annotationType(t string) string {
+	switch t {
+	case "boolean", "number", "file":
+		return t
+	default:
+		return "string"
+	}
+}
+
+// templateVar converts a parameter name into a valid template variable name
+func templateVar(name string) string {
+	var sb strings.Builder
+	upper := false
+	for i, r := range name {
+		switch {
+		case r == '-' || r == '_' || r == ' ':
+			upper = i > 0
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9' && i > 0):
+			if upper {
+				sb.WriteString(strings.ToUpper(string(r)))
+				upper = false
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "param"
+	}
+	return sb.String()
+}
@@ -0,0 +1,183 @@
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFiles lists the Hugo site configuration files in lookup order
+var configFiles = []string{
+	"hugo.toml",
+	"hugo.yaml",
+	"hugo.yml",
+	"hugo.json",
+	"config.toml",
+	"config.yaml",
+	"config.yml",
+	"config.json",
+}
+
+// Config is a read-only view of a Hugo site configuration
+type Config struct {
+	File string
+	raw  map[string]interface{}
+}
+
+// Load reads the Hugo site configuration from the project root or config/_default
+func Load(projectDir string) (*Config, error) {
+	dirs := []string{"", filepath.Join("config", "_default")}
+
+	for _, dir := range dirs {
+		for _, name := range configFiles {
+			rel := filepath.Join(dir, name)
+			data, err := os.ReadFile(filepath.Join(projectDir, rel))
+			if err != nil {
+				continue
+			}
+
+			raw, err := decode(name, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", rel, err)
+			}
+			return &Config{File: filepath.ToSlash(rel), raw: lowerKeys(raw)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no Hugo configuration file found")
+}
+
+func decode(name string, data []byte) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	var err error
+
+	switch filepath.Ext(name) {
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		err = fmt.Errorf("unsupported config format: %s", name)
+	}
+	return raw, err
+}
+
+// lowerKeys lowercases map keys recursively, since Hugo config keys are case-insensitive
+func lowerKeys(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = lowerValue(v)
+	}
+	return out
+}
+
+func lowerValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return lowerKeys(val)
+	case []map[string]interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = lowerKeys(item)
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = lowerValue(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// Get returns the value for a dotted, case-insensitive key (e.g. "module.imports")
+func (c *Config) Get(key string) interface{} {
+	var current interface{} = c.raw
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// String returns the string value for key, or "" if missing
+func (c *Config) String(key string) string {
+	if s, ok := c.Get(key).(string); ok {
+		return s
+	}
+	return ""
+}
+
+// Map returns the map value for key, or nil if missing
+func (c *Config) Map(key string) map[string]interface{} {
+	if m, ok := c.Get(key).(map[string]interface{}); ok {
+		return m
+	}
+	return nil
+}
+
+// BaseURL returns the configured baseURL
+func (c *Config) BaseURL() string {
+	return c.String("baseURL")
+}
+
+// PublishDir returns the directory Hugo writes the built site to
+func (c *Config) PublishDir() string {
+	if dir := c.String("publishDir"); dir != "" {
+		return strings.TrimSuffix(dir, "/")
+	}
+	return "public"
+}
+
+// Themes returns the configured theme names in precedence order
+func (c *Config) Themes() []string {
+	return stringList(c.Get("theme"))
+}
+
+// ModuleImports returns the paths of Hugo Module imports
+func (c *Config) ModuleImports() []string {
+	var paths []string
+	imports, _ := c.Get("module.imports").([]interface{})
+	for _, imp := range imports {
+		if m, ok := imp.(map[string]interface{}); ok {
+			if p, ok := m["path"].(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// stringList converts a string or list value to a slice of strings
+func stringList(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return nil
+		}
+		return []string{val}
+	case []interface{}:
+		var out []string
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return val
+	}
+	return nil
+}
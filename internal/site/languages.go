@@ -0,0 +1,115 @@
+package site
+
+import (
+	"sort"
+	"strings"
+)
+
+// Language describes a language configured for a multilingual site
+type Language struct {
+	Code       string `json:"code"`
+	Name       string `json:"name,omitempty"`
+	Weight     int    `json:"weight"`
+	ContentDir string `json:"contentDir"`
+	Default    bool   `json:"default"`
+}
+
+// Bool returns the boolean value for key
+func (c *Config) Bool(key string) bool {
+	b, _ := c.Get(key).(bool)
+	return b
+}
+
+// ContentDir returns the site's content directory
+func (c *Config) ContentDir() string {
+	if dir := c.String("contentDir"); dir != "" {
+		return strings.TrimSuffix(dir, "/")
+	}
+	return "content"
+}
+
+// DefaultLanguage returns the default content language code
+func (c *Config) DefaultLanguage() string {
+	if lang := c.String("defaultContentLanguage"); lang != "" {
+		return strings.ToLower(lang)
+	}
+	return "en"
+}
+
+// Languages returns the configured languages ordered by weight.
+// Sites without a languages section have a single default language.
+func (c *Config) Languages() []Language {
+	defaultLang := c.DefaultLanguage()
+	langs := c.Map("languages")
+
+	if len(langs) == 0 {
+		return []Language{{Code: defaultLang, ContentDir: c.ContentDir(), Default: true}}
+	}
+
+	var result []Language
+	for code, v := range langs {
+		lang := Language{Code: code, ContentDir: c.ContentDir()}
+		if m, ok := v.(map[string]interface{}); ok {
+			if dir, ok := m["contentdir"].(string); ok && dir != "" {
+				lang.ContentDir = strings.TrimSuffix(dir, "/")
+			}
+			if name, ok := m["languagename"].(string); ok {
+				lang.Name = name
+			}
+			lang.Weight = toInt(m["weight"])
+		}
+		lang.Default = code == defaultLang
+		result = append(result, lang)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Weight != result[j].Weight {
+			return result[i].Weight < result[j].Weight
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result
+}
+
+// IsMultilingual reports whether more than one language is configured
+func (c *Config) IsMultilingual() bool {
+	return len(c.Languages()) > 1
+}
+
+// Permalinks returns the permalink patterns per section for pages (kind "page") or sections (kind "section").
+// The legacy flat [permalinks] form applies to pages.
+func (c *Config) Permalinks(kind string) map[string]string {
+	result := map[string]string{}
+	perm := c.Map("permalinks")
+
+	for key, v := range perm {
+		switch val := v.(type) {
+		case string:
+			if kind == "page" {
+				result[key] = val
+			}
+		case map[string]interface{}:
+			if key != kind {
+				continue
+			}
+			for section, pattern := range val {
+				if s, ok := pattern.(string); ok {
+					result[section] = s
+				}
+			}
+		}
+	}
+	return result
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
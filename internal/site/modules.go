@@ -0,0 +1,95 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// requireRe matches module requirements in the project's go.mod
+var requireRe = regexp.MustCompile(`(?m)^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)`)
+
+// ModuleDir resolves the directory of a Hugo Module, looking at _vendor first and then the module cache.
+// It returns "" if the module hasn't been downloaded.
+func ModuleDir(projectDir, modulePath string) string {
+	vendored := filepath.Join(projectDir, "_vendor", filepath.FromSlash(modulePath))
+	if stat, err := os.Stat(vendored); err == nil && stat.IsDir() {
+		return vendored
+	}
+
+	cacheDir := moduleCacheDir()
+	if cacheDir == "" {
+		return ""
+	}
+	escaped := filepath.Join(cacheDir, filepath.FromSlash(escapeModulePath(modulePath)))
+
+	if version := requiredVersion(projectDir, modulePath); version != "" {
+		dir := escaped + "@" + version
+		if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+			return dir
+		}
+	}
+
+	matches, _ := filepath.Glob(escaped + "@*")
+	if len(matches) == 0 {
+		return ""
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1]
+}
+
+// moduleCacheDir returns the directory where Hugo stores downloaded modules
+func moduleCacheDir() string {
+	base := os.Getenv("HUGO_CACHEDIR")
+	if base == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		base = filepath.Join(userCache, "hugo_cache")
+	}
+	return filepath.Join(base, "modules", "filecache", "modules", "pkg", "mod")
+}
+
+// escapeModulePath applies Go's module cache case encoding (uppercase letters become !lowercase)
+func escapeModulePath(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			sb.WriteByte('!')
+			sb.WriteRune(r + ('a' - 'A'))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// requiredVersion returns the version of modulePath required by the project's go.mod
+func requiredVersion(projectDir, modulePath string) string {
+	data, err := os.ReadFile(filepath.Join(projectDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, m := range requireRe.FindAllStringSubmatch(string(data), -1) {
+		if m[1] == modulePath {
+			return m[2]
+		}
+	}
+	return ""
+}
+
+// RequiredModules returns the module paths the project's go.mod requires, in its order
+func RequiredModules(projectDir string) []string {
+	data, err := os.ReadFile(filepath.Join(projectDir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, m := range requireRe.FindAllStringSubmatch(string(data), -1) {
+		paths = append(paths, m[1])
+	}
+	return paths
+}
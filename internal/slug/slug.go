@@ -0,0 +1,123 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxLength caps the runes of a slug; longer titles are cut at a word boundary
+const maxLength = 80
+
+// letters maps each ASCII transliteration to the accented Latin letters that decompose into it
+var letters = map[string]string{
+	"a": "àáâãäåāăąǎǟǡǻȁȃȧḁạảấầẩẫậắằẳẵặ",
+	"b": "ḃḅḇ",
+	"c": "çćĉċčḉ",
+	"d": "ďḋḍḏḑḓđð",
+	"e": "èéêëēĕėęěȅȇȩḕḗḙḛḝẹẻẽếềểễệ",
+	"f": "ḟ",
+	"g": "ĝğġģǧǵḡ",
+	"h": "ĥȟḣḥḧḩḫẖħ",
+	"i": "ìíîïĩīĭįǐȉȋḭḯỉịı",
+	"j": "ĵǰ",
+	"k": "ķǩḱḳḵĸ",
+	"l": "ĺļľḷḹḻḽłŀ",
+	"m": "ḿṁṃ",
+	"n": "ñńņňǹṅṇṉṋŉ",
+	"o": "òóôõöōŏőơǒǫǭȍȏȫȭȯȱṍṏṑṓọỏốồổỗộớờởỡợø",
+	"p": "ṕṗ",
+	"r": "ŕŗřȑȓṙṛṝṟ",
+	"s": "śŝşšșṡṣṥṧṩſ",
+	"t": "ţťțṫṭṯṱẗŧ",
+	"u": "ùúûüũūŭůűųưǔǖǘǚǜȕȗṳṵṷṹṻụủứừửữự",
+	"v": "ṽṿ",
+	"w": "ŵẁẃẅẇẉẘ",
+	"x": "ẋẍ",
+	"y": "ýÿŷȳẏẙỳỵỷỹ",
+	"z": "źżžẑẓẕ",
+
+	"ae": "æ", "oe": "œ", "ss": "ß", "th": "þ", "ij": "ĳ",
+}
+
+// scripts transliterates the Greek and Cyrillic alphabets
+var scripts = map[rune]string{
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z", 'η': "i", 'ή': "i",
+	'θ': "th", 'ι': "i", 'ί': "i", 'ϊ': "i", 'ΐ': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'ύ': "y", 'ϋ': "y",
+	'ΰ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo", 'є': "ye", 'ж': "zh",
+	'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ў': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+}
+
+// transliterations is letters and scripts by rune
+var transliterations = func() map[rune]string {
+	t := make(map[rune]string, len(scripts)+400)
+	for r, s := range scripts {
+		t[r] = s
+	}
+	for ascii, accented := range letters {
+		for _, r := range accented {
+			t[r] = ascii
+		}
+	}
+	return t
+}()
+
+// Transliterate replaces accented Latin letters and Greek and Cyrillic letters with their closest
+// ASCII spelling, lowercasing the text. Other characters are kept.
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+		} else if !unicode.Is(unicode.Mn, r) {
+			// Combining marks are dropped with the accents they add
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Make returns the URL-safe slug of a title: transliterated, lowercase, with words separated by
+// hyphens. Letters and digits of scripts without a transliteration, such as Chinese or Arabic, are
+// kept, as Hugo keeps them in URLs. Apostrophes are dropped, so "Don't panic" becomes dont-panic.
+// The slug is "" when the title has no letters or digits.
+func Make(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range Transliterate(title) {
+		switch {
+		case r == '\'' || r == '’':
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		default:
+			hyphen = true
+		}
+	}
+	return truncate(b.String())
+}
+
+// truncate cuts a slug to maxLength runes, at the last hyphen when there's one
+func truncate(slug string) string {
+	runes := []rune(slug)
+	if len(runes) <= maxLength {
+		return slug
+	}
+	cut := string(runes[:maxLength])
+	if runes[maxLength] == '-' {
+		return cut
+	}
+	if i := strings.LastIndexByte(cut, '-'); i > 0 {
+		cut = cut[:i]
+	}
+	return cut
+}
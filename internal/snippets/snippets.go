@@ -0,0 +1,374 @@
+package snippets
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// ErrInvalidSnippet is returned for snippets that can't be made accessible as requested
+var ErrInvalidSnippet = errors.New("invalid snippet")
+
+// DefaultSizes is the sizes attribute of responsive images
+const DefaultSizes = "(max-width: 640px) 100vw, (max-width: 1024px) 75vw, 50vw"
+
+// maxAlt is the length above which alt text should rather be a caption
+const maxAlt = 150
+
+var (
+	// langRe matches BCP 47 language tags such as "en", "pt-BR" or "zh-Hant-TW"
+	langRe = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	// fileNameRe matches alt text that is a file name rather than a description
+	fileNameRe = regexp.MustCompile(`(?i)^[\w-]+\.(jpe?g|png|gif|webp|avif|svg)$|^(img|image|photo|dsc|pxl)[_-]?\d+$`)
+	// redundantAltRe matches alt text that announces it's an image, which screen readers already do
+	redundantAltRe = regexp.MustCompile(`(?i)^(an? )?(image|picture|photo|graphic) (of|showing)\b`)
+)
+
+// rtlLangs are the languages written right to left
+var rtlLangs = map[string]bool{
+	"ar": true, "arc": true, "dv": true, "fa": true, "ha": true, "he": true,
+	"khw": true, "ks": true, "ku": true, "ps": true, "ur": true, "yi": true,
+}
+
+// genericText are link texts that say nothing out of context, as screen reader link lists show them
+var genericText = map[string]bool{
+	"click here": true, "here": true, "more": true, "read more": true, "learn more": true,
+	"link": true, "this": true, "continue": true, "details": true,
+}
+
+// Options are the accessibility settings shared by every snippet. Empty values use the site defaults.
+type Options struct {
+	Lang    string `json:"lang,omitempty"`    // Language of the snippet's text
+	Dir     string `json:"dir,omitempty"`     // ltr, rtl or auto
+	Caption string `json:"caption,omitempty"` // Wraps the snippet in a <figure> with a <figcaption>
+}
+
+// Image is a single, optionally responsive, image
+type Image struct {
+	Src        string `json:"src"`
+	Srcset     string `json:"srcset,omitempty"`
+	Sizes      string `json:"sizes,omitempty"` // Defaults to DefaultSizes when there's a srcset
+	Alt        string `json:"alt"`
+	Decorative bool   `json:"decorative,omitempty"` // Adds nothing to the text, so it gets an empty alt
+	Class      string `json:"class,omitempty"`
+	Options
+}
+
+// Gallery is a group of images with an accessible name
+type Gallery struct {
+	Images    []Image `json:"images"`
+	AriaLabel string  `json:"ariaLabel,omitempty"` // Names the group when there's no caption
+	Class     string  `json:"class,omitempty"`
+	Options
+}
+
+// Button is a link styled as a button, or an action button when it has no URL
+type Button struct {
+	Text      string `json:"text"`
+	URL       string `json:"url,omitempty"`
+	NewTab    bool   `json:"newTab,omitempty"`
+	AriaLabel string `json:"ariaLabel,omitempty"` // For icon-only buttons, or to add context to the text
+	Class     string `json:"class,omitempty"`
+	Options
+}
+
+// Result is the markup of a snippet as raw HTML and as a shortcode call, with accessibility warnings
+type Result struct {
+	HTML      string   `json:"html"`
+	Shortcode string   `json:"shortcode"`
+	Warnings  []string `json:"warnings"`
+}
+
+// Generator renders accessible snippets with the site's defaults
+type Generator struct {
+	config config.SnippetsConfig
+}
+
+// NewGenerator creates a new snippet generator
+func NewGenerator(cfg config.SnippetsConfig) *Generator {
+	return &Generator{config: cfg}
+}
+
+// Resolve fills the language and direction of options from the site defaults, deriving the
+// direction from right-to-left languages, and validates them
+func (g *Generator) Resolve(opts Options) (Options, error) {
+	if opts.Lang == "" {
+		opts.Lang = g.config.Lang
+	}
+	if opts.Dir == "" {
+		opts.Dir = g.config.Dir
+	}
+	if opts.Lang != "" && !langRe.MatchString(opts.Lang) {
+		return opts, fmt.Errorf("%w: lang %q isn't a language tag such as en or pt-BR", ErrInvalidSnippet, opts.Lang)
+	}
+	opts.Dir = strings.ToLower(opts.Dir)
+	switch opts.Dir {
+	case "":
+		base, _, _ := strings.Cut(strings.ToLower(opts.Lang), "-")
+		if rtlLangs[base] {
+			opts.Dir = "rtl"
+		}
+	case "ltr", "rtl", "auto":
+	default:
+		return opts, fmt.Errorf("%w: dir %q (use ltr, rtl or auto)", ErrInvalidSnippet, opts.Dir)
+	}
+	return opts, nil
+}
+
+// Image renders an image, inside a <figure> when it has a caption
+func (g *Generator) Image(img Image) (*Result, error) {
+	opts, err := g.Resolve(img.Options)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := checkImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if img.Caption != "" {
+		b.WriteString("<figure" + langAttrs(opts) + ">\n  ")
+		b.WriteString(imgTag(img, Options{}))
+		b.WriteString("\n  <figcaption>" + html.EscapeString(img.Caption) + "</figcaption>\n</figure>")
+	} else {
+		b.WriteString(imgTag(img, opts))
+	}
+
+	return &Result{
+		HTML:      b.String(),
+		Shortcode: "{{< img" + imgParams(img) + captionParams(opts) + " >}}",
+		Warnings:  warnings,
+	}, nil
+}
+
+// Gallery renders a group of images. A caption makes it a <figure>; otherwise the aria-label names the group.
+func (g *Generator) Gallery(gallery Gallery) (*Result, error) {
+	if len(gallery.Images) == 0 {
+		return nil, fmt.Errorf("%w: a gallery needs at least one image", ErrInvalidSnippet)
+	}
+	opts, err := g.Resolve(gallery.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	if gallery.Caption == "" && strings.TrimSpace(gallery.AriaLabel) == "" {
+		warnings = append(warnings, "Gallery has no caption or aria-label, so screen readers can't name it")
+	}
+
+	class := strings.TrimSpace("gallery " + gallery.Class)
+	var b, sc strings.Builder
+	if gallery.Caption != "" {
+		b.WriteString(`<figure class="` + html.EscapeString(class) + `"`)
+	} else {
+		b.WriteString(`<div class="` + html.EscapeString(class) + `" role="group"`)
+	}
+	if gallery.AriaLabel != "" {
+		b.WriteString(` aria-label="` + html.EscapeString(gallery.AriaLabel) + `"`)
+	}
+	b.WriteString(langAttrs(opts) + ">\n  <div class=\"gallery-items\">\n")
+
+	sc.WriteString("{{< gallery")
+	sc.WriteString(param("label", gallery.AriaLabel) + param("class", gallery.Class) + captionParams(opts) + " >}}\n")
+
+	for i, img := range gallery.Images {
+		// Images inherit the gallery's language unless they set their own
+		imgOpts := img.Options
+		if imgOpts.Lang != "" || imgOpts.Dir != "" {
+			if imgOpts, err = g.Resolve(imgOpts); err != nil {
+				return nil, err
+			}
+		}
+		imgWarnings, err := checkImage(img)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		for _, w := range imgWarnings {
+			warnings = append(warnings, fmt.Sprintf("Image %d: %s", i+1, w))
+		}
+
+		if img.Caption != "" {
+			b.WriteString("    <figure" + langAttrs(imgOpts) + ">\n      " + imgTag(img, Options{}))
+			b.WriteString("\n      <figcaption>" + html.EscapeString(img.Caption) + "</figcaption>\n    </figure>\n")
+		} else {
+			b.WriteString("    " + imgTag(img, imgOpts) + "\n")
+		}
+		sc.WriteString("{{< img" + imgParams(img) + captionParams(imgOpts) + " >}}\n")
+	}
+
+	b.WriteString("  </div>\n")
+	if gallery.Caption != "" {
+		b.WriteString("  <figcaption>" + html.EscapeString(gallery.Caption) + "</figcaption>\n</figure>")
+	} else {
+		b.WriteString("</div>")
+	}
+	sc.WriteString("{{< /gallery >}}")
+
+	return &Result{HTML: b.String(), Shortcode: sc.String(), Warnings: nonNil(warnings)}, nil
+}
+
+// Button renders a link as a button, or a <button> when there's no URL. Links opening a new tab
+// tell screen readers so, and icon-only buttons need an aria-label.
+func (g *Generator) Button(button Button) (*Result, error) {
+	opts, err := g.Resolve(button.Options)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(button.Text)
+	label := strings.TrimSpace(button.AriaLabel)
+	if text == "" && label == "" {
+		return nil, fmt.Errorf("%w: a button needs text or an aria-label", ErrInvalidSnippet)
+	}
+	if button.URL != "" {
+		u, err := url.Parse(button.URL)
+		if err != nil || strings.EqualFold(u.Scheme, "javascript") || strings.Contains(button.URL, "{{") {
+			return nil, fmt.Errorf("%w: invalid url %q", ErrInvalidSnippet, button.URL)
+		}
+	}
+
+	var warnings []string
+	switch {
+	case label == "" && genericText[strings.ToLower(strings.Trim(text, " .…!"))]:
+		warnings = append(warnings, fmt.Sprintf("%q says nothing out of context; describe where it goes or add an aria-label", text))
+	case label != "" && text != "" && !strings.Contains(strings.ToLower(label), strings.ToLower(text)):
+		warnings = append(warnings, "The aria-label should contain the visible text, so voice control users can say what they see")
+	}
+	if button.NewTab && button.URL == "" {
+		warnings = append(warnings, "newTab only applies to buttons with a url")
+	}
+
+	class := strings.TrimSpace("button " + button.Class)
+	var attrs strings.Builder
+	attrs.WriteString(` class="` + html.EscapeString(class) + `"`)
+	if label != "" {
+		attrs.WriteString(` aria-label="` + html.EscapeString(label) + `"`)
+	}
+	attrs.WriteString(langAttrs(opts))
+
+	var b strings.Builder
+	if button.URL != "" {
+		b.WriteString(`<a href="` + html.EscapeString(button.URL) + `"` + attrs.String())
+		if button.NewTab {
+			b.WriteString(` target="_blank" rel="noopener noreferrer"`)
+		}
+		b.WriteString(">" + html.EscapeString(text))
+		if button.NewTab && label == "" && g.config.NewTabText != "" {
+			b.WriteString(` <span class="` + html.EscapeString(g.config.HiddenClass) + `">` + html.EscapeString(g.config.NewTabText) + "</span>")
+		}
+		b.WriteString("</a>")
+	} else {
+		b.WriteString(`<button type="button"` + attrs.String() + ">" + html.EscapeString(text) + "</button>")
+	}
+	if button.Caption != "" {
+		warnings = append(warnings, "Buttons don't have captions; the caption was left out")
+	}
+
+	var sc strings.Builder
+	sc.WriteString("{{< button" + param("url", button.URL))
+	if button.NewTab && button.URL != "" {
+		sc.WriteString(` newtab="true"`)
+	}
+	sc.WriteString(param("label", label) + param("class", button.Class) + param("lang", opts.Lang) + param("dir", opts.Dir))
+	sc.WriteString(" >}}" + text + "{{< /button >}}")
+
+	return &Result{HTML: b.String(), Shortcode: sc.String(), Warnings: nonNil(warnings)}, nil
+}
+
+// checkImage validates the alt text of an image, returning warnings for text that doesn't describe it
+func checkImage(img Image) ([]string, error) {
+	if strings.TrimSpace(img.Src) == "" {
+		return nil, fmt.Errorf("%w: image src is required", ErrInvalidSnippet)
+	}
+	alt := strings.TrimSpace(img.Alt)
+	warnings := []string{}
+	switch {
+	case img.Decorative:
+		if img.Caption != "" {
+			warnings = append(warnings, "A captioned image isn't decorative; its alt text was left empty")
+		}
+	case alt == "":
+		return nil, fmt.Errorf("%w: %s needs alt text, or decorative if it adds nothing to the text", ErrInvalidSnippet, img.Src)
+	case fileNameRe.MatchString(alt):
+		warnings = append(warnings, fmt.Sprintf("Alt text %q looks like a file name; describe the image", alt))
+	case redundantAltRe.MatchString(alt):
+		warnings = append(warnings, "Screen readers already announce images; start the alt text with what it shows")
+	case len([]rune(alt)) > maxAlt:
+		warnings = append(warnings, fmt.Sprintf("Alt text is over %d characters; move the details to a caption", maxAlt))
+	}
+	return warnings, nil
+}
+
+// imgTag renders an <img>, with the language attributes of opts
+func imgTag(img Image, opts Options) string {
+	alt := img.Alt
+	if img.Decorative {
+		alt = ""
+	}
+	var b strings.Builder
+	b.WriteString(`<img src="` + html.EscapeString(img.Src) + `"`)
+	if img.Srcset != "" {
+		sizes := img.Sizes
+		if sizes == "" {
+			sizes = DefaultSizes
+		}
+		b.WriteString(` srcset="` + html.EscapeString(img.Srcset) + `" sizes="` + html.EscapeString(sizes) + `"`)
+	}
+	b.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+	if img.Class != "" {
+		b.WriteString(` class="` + html.EscapeString(img.Class) + `"`)
+	}
+	b.WriteString(langAttrs(opts) + ` loading="lazy" decoding="async">`)
+	return b.String()
+}
+
+// imgParams renders the img shortcode parameters of an image
+func imgParams(img Image) string {
+	alt := img.Alt
+	if img.Decorative {
+		alt = ""
+	}
+	return ` src="` + escapeParam(img.Src) + `" alt="` + escapeParam(alt) + `"` + param("srcset", img.Srcset) + param("sizes", img.Sizes) + param("class", img.Class)
+}
+
+// captionParams renders the caption and language shortcode parameters
+func captionParams(opts Options) string {
+	return param("caption", opts.Caption) + param("lang", opts.Lang) + param("dir", opts.Dir)
+}
+
+// langAttrs renders the lang and dir attributes that are set
+func langAttrs(opts Options) string {
+	var b strings.Builder
+	if opts.Lang != "" {
+		b.WriteString(` lang="` + html.EscapeString(opts.Lang) + `"`)
+	}
+	if opts.Dir != "" {
+		b.WriteString(` dir="` + opts.Dir + `"`)
+	}
+	return b.String()
+}
+
+// param renders a shortcode parameter, or nothing when value is empty
+func param(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return " " + name + `="` + escapeParam(value) + `"`
+}
+
+// escapeParam escapes quotes in a quoted shortcode parameter value
+func escapeParam(value string) string {
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+func nonNil(warnings []string) []string {
+	if warnings == nil {
+		return []string{}
+	}
+	return warnings
+}
@@ -0,0 +1,260 @@
+package starters
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// CatalogFileName is the user catalog looked up in the user config directory
+const CatalogFileName = "starters.yaml"
+
+// Theme is the Hugo theme a kit is built on
+type Theme struct {
+	Name string `yaml:"name" json:"name"` // Directory under themes/ and value of the theme setting
+	Repo string `yaml:"repo" json:"repo"` // Git repository cloned into themes/<name>
+}
+
+// Kit is a starter kit that new-site can instantiate
+type Kit struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description" json:"description"`
+	Theme       Theme                  `yaml:"theme" json:"theme"`
+	HugoConfig  string                 `yaml:"hugo_config" json:"hugo_config"` // Extra TOML appended to hugo.toml
+	Files       map[string]string      `yaml:"files" json:"files"`             // Project-relative path -> content
+	Source      string                 `yaml:"source" json:"source"`           // Directory copied into the new site (user kits)
+	Templates   config.TemplatesConfig `yaml:"templates" json:"templates"`     // hugo-manager metadata templates
+	Presets     []config.ImagePreset   `yaml:"presets" json:"presets"`         // hugo-manager image presets
+}
+
+// catalogFile is the layout of a user catalog file
+type catalogFile struct {
+	Kits []Kit `yaml:"kits"`
+}
+
+// Catalog holds the available starter kits by name
+type Catalog map[string]Kit
+
+// DefaultCatalogPath returns the user catalog path, or "" if the user config directory is unknown
+func DefaultCatalogPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hugo-manager", CatalogFileName)
+}
+
+// LoadCatalog returns the built-in kits extended by the kits in path. Kits in path replace built-ins of the
+// same name. A missing file is not an error.
+func LoadCatalog(path string) (Catalog, error) {
+	catalog := Catalog{}
+	for _, kit := range builtinKits {
+		catalog[kit.Name] = kit
+	}
+	if path == "" {
+		return catalog, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return catalog, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file catalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, kit := range file.Kits {
+		if kit.Name == "" {
+			return nil, fmt.Errorf("%s: kit name cannot be empty", path)
+		}
+		// Relative sources are resolved against the catalog file
+		if kit.Source != "" && !filepath.IsAbs(kit.Source) {
+			kit.Source = filepath.Join(filepath.Dir(path), kit.Source)
+		}
+		catalog[kit.Name] = kit
+	}
+	return catalog, nil
+}
+
+// Sorted returns the kits ordered by name
+func (c Catalog) Sorted() []Kit {
+	kits := make([]Kit, 0, len(c))
+	for _, kit := range c {
+		kits = append(kits, kit)
+	}
+	sort.Slice(kits, func(i, j int) bool { return kits[i].Name < kits[j].Name })
+	return kits
+}
+
+// builtinKits is the curated catalog shipped with hugo-manager
+var builtinKits = []Kit{
+	{
+		Name:        "blog",
+		Description: "Personal blog with posts, tags and an about page",
+		Theme:       Theme{Name: "PaperMod", Repo: "https://github.com/adityatelange/hugo-PaperMod.git"},
+		HugoConfig: `[params]
+  ShowReadingTime = true
+  ShowShareButtons = false
+
+[taxonomies]
+  tag = "tags"
+  category = "categories"
+
+[[menu.main]]
+  name = "Posts"
+  url = "/posts/"
+  weight = 1
+[[menu.main]]
+  name = "Tags"
+  url = "/tags/"
+  weight = 2
+[[menu.main]]
+  name = "About"
+  url = "/about/"
+  weight = 3
+`,
+		Files: map[string]string{
+			"content/posts/hello-world.md": `---
+title: "Hello World"
+date: 2024-01-01
+draft: false
+tags: ["welcome"]
+description: "The first post on this blog"
+---
+
+Welcome to your new blog. Edit this post or create a new one from the **Blog post** template.
+`,
+			"content/about.md": `---
+title: "About"
+layout: "single"
+---
+
+Tell your readers who you are.
+`,
+		},
+		Templates: config.TemplatesConfig{
+			"Blog post": {
+				"title":       {Type: "text", Default: "New post"},
+				"date":        {Type: "date"},
+				"draft":       {Type: "bool", Default: "true"},
+				"tags":        {Type: "array"},
+				"description": {Type: "textarea"},
+				"cover":       {Type: "image"},
+			},
+		},
+		Presets: []config.ImagePreset{
+			{Name: "Post cover", Widths: []int{640, 1024, 1600}},
+			{Name: "Inline", Widths: []int{320, 640}},
+			{Name: "Social media", Widths: []int{1200}},
+		},
+	},
+	{
+		Name:        "docs",
+		Description: "Documentation site with nested sections and a sidebar",
+		Theme:       Theme{Name: "hugo-book", Repo: "https://github.com/alex-shpak/hugo-book.git"},
+		HugoConfig: `[params]
+  BookSection = "docs"
+  BookSearch = true
+
+[markup.goldmark.renderer]
+  unsafe = true
+`,
+		Files: map[string]string{
+			"content/_index.md": `---
+title: "Introduction"
+type: "docs"
+---
+
+# Documentation
+
+Start with [Getting started](/docs/getting-started/).
+`,
+			"content/docs/_index.md": `---
+title: "Docs"
+weight: 1
+bookFlatSection: true
+---
+`,
+			"content/docs/getting-started.md": `---
+title: "Getting started"
+weight: 1
+---
+
+## Installation
+
+Describe how to install your project.
+
+## First steps
+
+Walk readers through their first task.
+`,
+		},
+		Templates: config.TemplatesConfig{
+			"Docs page": {
+				"title":       {Type: "text", Default: "New page"},
+				"weight":      {Type: "number", Default: "10"},
+				"description": {Type: "textarea"},
+				"draft":       {Type: "bool", Default: "false"},
+			},
+		},
+		Presets: []config.ImagePreset{
+			{Name: "Screenshot", Widths: []int{800, 1600}},
+			{Name: "Diagram", Widths: []int{640, 1280}},
+		},
+	},
+	{
+		Name:        "portfolio",
+		Description: "Portfolio with a projects gallery and a contact page",
+		Theme:       Theme{Name: "hugo-profile", Repo: "https://github.com/gurusabarish/hugo-profile.git"},
+		HugoConfig: `[params]
+  title = "Portfolio"
+  description = "Selected work"
+`,
+		Files: map[string]string{
+			"content/projects/_index.md": `---
+title: "Projects"
+---
+`,
+			"content/projects/first-project.md": `---
+title: "First project"
+date: 2024-01-01
+client: ""
+image: ""
+tags: ["design"]
+---
+
+Describe the project, your role and the outcome.
+`,
+			"content/contact.md": `---
+title: "Contact"
+---
+
+How to get in touch.
+`,
+		},
+		Templates: config.TemplatesConfig{
+			"Project": {
+				"title":  {Type: "text", Default: "New project"},
+				"date":   {Type: "date"},
+				"client": {Type: "text"},
+				"image":  {Type: "image"},
+				"tags":   {Type: "array"},
+				"draft":  {Type: "bool", Default: "true"},
+			},
+		},
+		Presets: []config.ImagePreset{
+			{Name: "Gallery", Widths: []int{480, 960, 1920}},
+			{Name: "Thumbnail", Widths: []int{300, 600}},
+			{Name: "Social media", Widths: []int{1200}},
+		},
+	},
+}
@@ -0,0 +1,190 @@
+package starters
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Options configures a new site
+type Options struct {
+	OutputDir  string
+	Title      string
+	BaseURL    string
+	FetchTheme bool // Clone the kit's theme with git
+}
+
+// Result summarizes a created site
+type Result struct {
+	Files        int
+	ThemeFetched bool
+	ThemeError   error // Set when the theme could not be cloned; the site is still created
+}
+
+// Create instantiates kit in opts.OutputDir, which must be empty or missing
+func Create(kit Kit, opts Options) (*Result, error) {
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if entries, err := os.ReadDir(opts.OutputDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("output directory %s is not empty", opts.OutputDir)
+	}
+	if opts.Title == "" {
+		opts.Title = "My " + kit.Name + " site"
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://example.org/"
+	}
+
+	result := &Result{}
+	write := func(rel, content string) error {
+		path := filepath.Join(opts.OutputDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		result.Files++
+		return nil
+	}
+
+	if kit.Source != "" {
+		n, err := copyTree(kit.Source, opts.OutputDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy kit source: %w", err)
+		}
+		result.Files += n
+	}
+
+	for rel, content := range kit.Files {
+		if err := write(rel, content); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasSiteConfig(opts.OutputDir) {
+		if err := write("hugo.toml", hugoConfig(kit, opts)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.Save(opts.OutputDir, managerConfig(kit)); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", config.ConfigFileName, err)
+	}
+	result.Files++
+
+	if opts.FetchTheme && kit.Theme.Repo != "" {
+		themeDir := filepath.Join(opts.OutputDir, "themes", kit.Theme.Name)
+		if err := cloneTheme(kit.Theme.Repo, themeDir); err != nil {
+			result.ThemeError = err
+		} else {
+			result.ThemeFetched = true
+		}
+	}
+
+	return result, nil
+}
+
+// hugoConfig renders the site's hugo.toml
+func hugoConfig(kit Kit, opts Options) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("baseURL = %q\n", opts.BaseURL))
+	sb.WriteString("languageCode = \"en-us\"\n")
+	sb.WriteString(fmt.Sprintf("title = %q\n", opts.Title))
+	if kit.Theme.Name != "" {
+		sb.WriteString(fmt.Sprintf("theme = %q\n", kit.Theme.Name))
+	}
+	if kit.HugoConfig != "" {
+		sb.WriteString("\n")
+		sb.WriteString(kit.HugoConfig)
+	}
+	return sb.String()
+}
+
+// managerConfig returns the default hugo-manager configuration with the kit's templates and presets
+func managerConfig(kit Kit) *config.Config {
+	cfg := config.Default()
+	for name, fields := range kit.Templates {
+		cfg.Templates[name] = fields
+	}
+	if _, ok := cfg.Templates["Blank File"]; !ok {
+		cfg.Templates["Blank File"] = map[string]config.TemplateField{}
+	}
+	if len(kit.Presets) > 0 {
+		cfg.Images.Presets = append(append([]config.ImagePreset{}, kit.Presets...), config.ImagePreset{Name: "Custom", Widths: []int{}})
+	}
+	return cfg
+}
+
+// cloneTheme shallow-clones a theme repository
+func cloneTheme(repo, dir string) error {
+	cmd := exec.Command("git", "clone", "--depth", "1", repo, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git clone %s: %w: %s", repo, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// copyTree copies the regular files below src into dst
+func copyTree(src, dst string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if err := copyFile(path, filepath.Join(dst, rel)); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// hasSiteConfig reports whether dir already has a Hugo site configuration, e.g. from a kit source
+func hasSiteConfig(dir string) bool {
+	for _, f := range []string{"hugo.toml", "hugo.yaml", "hugo.json", "config.toml", "config.yaml", "config.json"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// DirName is the directory in the project root where hugo-manager keeps its own state
+const DirName = ".hugo-manager"
+
+// Storage areas kept under DirName
+const (
+	AreaTrash   = "trash"
+	AreaHistory = "history"
+	AreaCache   = "cache"
+)
+
+// Areas lists every storage area in GC order
+var Areas = []string{AreaTrash, AreaHistory, AreaCache}
+
+// AreaUsage describes the disk usage and limits of a storage area
+type AreaUsage struct {
+	Area       string `json:"area"`
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	MaxBytes   int64  `json:"maxBytes"`   // 0 = unlimited
+	MaxAgeDays int    `json:"maxAgeDays"` // 0 = keep forever
+}
+
+// AreaResult is the outcome of collecting a single storage area
+type AreaResult struct {
+	Area           string   `json:"area"`
+	RemovedFiles   int      `json:"removedFiles"`
+	ReclaimedBytes int64    `json:"reclaimedBytes"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// Report is the result of a GC run
+type Report struct {
+	RanAt          time.Time    `json:"ranAt"`
+	Duration       string       `json:"duration"`
+	RemovedFiles   int          `json:"removedFiles"`
+	ReclaimedBytes int64        `json:"reclaimedBytes"`
+	Areas          []AreaResult `json:"areas"`
+}
+
+// Manager enforces size and age limits on the trash, history and cache areas
+type Manager struct {
+	projectDir string
+	config     config.StorageConfig
+	report     *Report
+	mu         sync.RWMutex
+	gcMu       sync.Mutex
+	stop       chan struct{}
+}
+
+// NewManager creates a new storage manager
+func NewManager(projectDir string, cfg config.StorageConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// Dir returns the absolute directory of a storage area
+func (m *Manager) Dir(area string) string {
+	return filepath.Join(m.projectDir, DirName, area)
+}
+
+// limits returns the configured limits of an area
+func (m *Manager) limits(area string) config.StorageLimits {
+	switch area {
+	case AreaTrash:
+		return m.config.Trash
+	case AreaHistory:
+		return m.config.History
+	case AreaCache:
+		return m.config.Cache
+	}
+	return config.StorageLimits{}
+}
+
+// Start runs GC in the background at the configured interval
+func (m *Manager) Start() {
+	if m.config.GCInterval <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(m.config.GCInterval) * time.Minute)
+		defer ticker.Stop()
+
+		m.GC()
+		for {
+			select {
+			case <-ticker.C:
+				m.GC()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background GC
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// LastReport returns the most recent GC report, or nil if GC hasn't run yet
+func (m *Manager) LastReport() *Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.report
+}
+
+// Usage returns the current disk usage of every storage area
+func (m *Manager) Usage() []AreaUsage {
+	usage := make([]AreaUsage, 0, len(Areas))
+	for _, area := range Areas {
+		limits := m.limits(area)
+		u := AreaUsage{
+			Area:       area,
+			MaxBytes:   int64(limits.MaxSizeMB) * 1024 * 1024,
+			MaxAgeDays: limits.MaxAgeDays,
+		}
+		entries, _ := scan(m.Dir(area))
+		for _, e := range entries {
+			u.Files++
+			u.Bytes += e.size
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// GC removes expired files from every area, then the oldest files of any area still over its size limit
+func (m *Manager) GC() *Report {
+	m.gcMu.Lock()
+	defer m.gcMu.Unlock()
+
+	start := time.Now()
+	report := &Report{RanAt: start, Areas: make([]AreaResult, 0, len(Areas))}
+
+	for _, area := range Areas {
+		result := m.collect(area, start)
+		report.RemovedFiles += result.RemovedFiles
+		report.ReclaimedBytes += result.ReclaimedBytes
+		report.Areas = append(report.Areas, result)
+	}
+	report.Duration = time.Since(start).String()
+
+	if report.RemovedFiles > 0 {
+		slog.Info("Storage GC", "removed_files", report.RemovedFiles, "reclaimed_bytes", report.ReclaimedBytes)
+	}
+
+	m.mu.Lock()
+	m.report = report
+	m.mu.Unlock()
+	return report
+}
+
+// entry is a file inside a storage area
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// collect applies the limits of an area
+func (m *Manager) collect(area string, now time.Time) AreaResult {
+	result := AreaResult{Area: area}
+	limits := m.limits(area)
+	dir := m.Dir(area)
+
+	entries, err := scan(dir)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	remove := func(e entry) bool {
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			result.Errors = append(result.Errors, err.Error())
+			return false
+		}
+		result.RemovedFiles++
+		result.ReclaimedBytes += e.size
+		return true
+	}
+
+	// Oldest first, so size trimming drops the least recent files
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	var total int64
+	kept := entries[:0]
+	for _, e := range entries {
+		if limits.MaxAgeDays > 0 && now.Sub(e.modTime) > time.Duration(limits.MaxAgeDays)*24*time.Hour {
+			if remove(e) {
+				continue
+			}
+		}
+		total += e.size
+		kept = append(kept, e)
+	}
+
+	maxBytes := int64(limits.MaxSizeMB) * 1024 * 1024
+	if maxBytes > 0 {
+		for _, e := range kept {
+			if total <= maxBytes {
+				break
+			}
+			if remove(e) {
+				total -= e.size
+			}
+		}
+	}
+
+	if result.RemovedFiles > 0 {
+		removeEmptyDirs(dir)
+	}
+	return result
+}
+
+// scan lists the regular files below dir. A missing dir is empty.
+func scan(dir string) ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return entries, fmt.Errorf("failed to scan %s: %w", filepath.Base(dir), err)
+	}
+	return entries, nil
+}
+
+// removeEmptyDirs removes empty directories below root, deepest first, keeping root itself
+func removeEmptyDirs(root string) {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i]) // Fails harmlessly on non-empty directories
+	}
+}
@@ -0,0 +1,264 @@
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Errors returned by the structured data manager
+var (
+	ErrUnknownType = errors.New("unknown structured data type")
+	ErrNoType      = errors.New("no structured data type for this page")
+)
+
+// Page is the structured data generated for a content page
+type Page struct {
+	Path    string                 `json:"path"`
+	Type    string                 `json:"type"`
+	Section string                 `json:"section"`
+	Data    map[string]interface{} `json:"data"`
+	Snippet string                 `json:"snippet"` // <script> tag to embed in the page head
+	Issues  []Issue                `json:"issues"`
+	Valid   bool                   `json:"valid"` // No errors
+}
+
+// ReportEntry lists the problems of a page whose section expects structured data
+type ReportEntry struct {
+	Path    string  `json:"path"`
+	Type    string  `json:"type"`
+	Section string  `json:"section"`
+	Written bool    `json:"written"` // JSON-LD is stored in the front matter
+	Issues  []Issue `json:"issues"`
+}
+
+// Report summarizes the structured data of every page in the mapped sections
+type Report struct {
+	Checked int           `json:"checked"`
+	Valid   int           `json:"valid"`
+	Pages   []ReportEntry `json:"pages"` // Pages with errors, or whose front matter lacks the JSON-LD
+}
+
+// Manager generates and validates JSON-LD structured data for content pages
+type Manager struct {
+	projectDir string
+	config     config.StructuredDataConfig
+}
+
+// NewManager creates a new structured data manager
+func NewManager(projectDir string, cfg config.StructuredDataConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+	}
+}
+
+// frontMatterKey returns the key the JSON-LD is written to
+func (m *Manager) frontMatterKey() string {
+	if m.config.FrontMatterKey == "" {
+		return "structured_data"
+	}
+	return m.config.FrontMatterKey
+}
+
+// typeFor returns the schema.org type of a section, or "" when it has none
+func (m *Manager) typeFor(section string) string {
+	for s, typ := range m.config.Sections {
+		if strings.EqualFold(s, section) {
+			return typ
+		}
+	}
+	return ""
+}
+
+// Page generates the structured data of a project-relative content file. typ overrides the type mapped to its section.
+func (m *Manager) Page(path, typ string) (*Page, error) {
+	data, err := os.ReadFile(filepath.Join(m.projectDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, err
+	}
+	fm, _, _, err := hugocontent.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+	return m.build(siteCfg, filepath.ToSlash(path), fm, typ)
+}
+
+// Apply generates the structured data of a content file and writes it into its front matter,
+// where a theme partial can render it with jsonify
+func (m *Manager) Apply(path, typ string) (*Page, error) {
+	page, err := m.Page(path, typ)
+	if err != nil {
+		return nil, err
+	}
+
+	full := filepath.Join(m.projectDir, filepath.FromSlash(path))
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	out, err := hugocontent.SetFields(data, map[string]interface{}{m.frontMatterKey(): page.Data})
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(full, out, 0644); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Report checks every regular page in the sections mapped to a type
+func (m *Manager) Report() (*Report, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site config: %w", err)
+	}
+
+	report := &Report{Pages: []ReportEntry{}}
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		root := filepath.Join(m.projectDir, filepath.FromSlash(lang.ContentDir))
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fm, _, _, err := hugocontent.Parse(data)
+			if err != nil || fm.Bool("draft") {
+				return nil // Invalid front matter is reported by the linter
+			}
+
+			rel, _ := filepath.Rel(m.projectDir, path)
+			page, err := m.build(siteCfg, filepath.ToSlash(rel), fm, "")
+			if err != nil {
+				return nil // Not a page, or a section without a type
+			}
+
+			report.Checked++
+			if page.Valid {
+				report.Valid++
+			}
+			written := fm.Get(m.frontMatterKey()) != nil
+			if !page.Valid || !written {
+				report.Pages = append(report.Pages, ReportEntry{
+					Path:    page.Path,
+					Type:    page.Type,
+					Section: page.Section,
+					Written: written,
+					Issues:  page.Issues,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(report.Pages, func(i, j int) bool { return report.Pages[i].Path < report.Pages[j].Path })
+	return report, nil
+}
+
+// build generates the structured data of a parsed page
+func (m *Manager) build(siteCfg *site.Config, path string, fm hugocontent.FrontMatter, typ string) (*Page, error) {
+	loc, err := hugocontent.Locate(siteCfg, path)
+	if err != nil {
+		return nil, err
+	}
+	permalink := hugocontent.PermalinkFor(siteCfg, loc, fm, path)
+
+	if typ == "" {
+		if permalink.Kind != hugocontent.KindPage {
+			return nil, fmt.Errorf("%w: %s is a %s page", ErrNoType, path, permalink.Kind)
+		}
+		typ = m.typeFor(permalink.Section)
+		if typ == "" {
+			return nil, fmt.Errorf("%w: section %q has no type", ErrNoType, permalink.Section)
+		}
+	}
+
+	baseURL := siteCfg.BaseURL()
+	data, issues, err := Build(typ, fm, m.config.Templates[typ], hostURL(baseURL, permalink.URL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Search engines need absolute image URLs
+	switch image := data["image"].(type) {
+	case string:
+		data["image"] = absoluteURL(baseURL, image)
+	case []string:
+		for i := range image {
+			image[i] = absoluteURL(baseURL, image[i])
+		}
+	}
+
+	page := &Page{
+		Path:    path,
+		Type:    typ,
+		Section: permalink.Section,
+		Data:    data,
+		Issues:  issues,
+		Valid:   true,
+	}
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			page.Valid = false
+			break
+		}
+	}
+
+	js, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	page.Snippet = "<script type=\"application/ld+json\">\n" + string(js) + "\n</script>"
+	return page, nil
+}
+
+// absoluteURL joins the baseURL with a site-relative URL; absolute URLs are returned unchanged
+func absoluteURL(baseURL, rel string) string {
+	if rel == "" || strings.Contains(rel, "://") || baseURL == "" {
+		return rel
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(rel, "/")
+}
+
+// hostURL joins the scheme and host of baseURL with a permalink, which already includes the baseURL path
+func hostURL(baseURL, rel string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return rel
+	}
+	return base.Scheme + "://" + base.Host + rel
+}
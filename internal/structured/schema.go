@@ -0,0 +1,425 @@
+package structured
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+// Supported schema.org types
+const (
+	TypeArticle = "Article"
+	TypeRecipe  = "Recipe"
+	TypeEvent   = "Event"
+	TypeFAQPage = "FAQPage"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"   // Required by search engines for rich results
+	SeverityWarning = "warning" // Recommended
+)
+
+// Issue is a problem with the structured data of a page
+type Issue struct {
+	Property string `json:"property"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Property value kinds, which decide how front matter values are converted
+const (
+	kindText     = "text"
+	kindDate     = "date"
+	kindDuration = "duration"
+	kindImage    = "image"
+	kindPerson   = "person"
+	kindPlace    = "place"
+	kindList     = "list"
+	kindKeywords = "keywords"
+	kindSteps    = "steps"
+	kindFAQ      = "faq"
+	kindCalories = "calories"
+)
+
+// property maps a schema.org property to its default front matter source
+type property struct {
+	name   string
+	source string // Front matter key, or a template with {{key}} placeholders
+	kind   string
+}
+
+// schema describes how a type is built and which properties search engines expect
+type schema struct {
+	properties  []property
+	required    []string
+	recommended []string
+}
+
+// schemas follow the Google Search rich result requirements for each type
+var schemas = map[string]schema{
+	TypeArticle: {
+		properties: []property{
+			{"headline", "title", kindText},
+			{"description", "description", kindText},
+			{"image", "images", kindImage},
+			{"datePublished", "date", kindDate},
+			{"dateModified", "lastmod", kindDate},
+			{"author", "author", kindPerson},
+			{"keywords", "tags", kindKeywords},
+		},
+		required:    []string{"headline"},
+		recommended: []string{"image", "datePublished", "author"},
+	},
+	TypeRecipe: {
+		properties: []property{
+			{"name", "title", kindText},
+			{"description", "description", kindText},
+			{"image", "images", kindImage},
+			{"author", "author", kindPerson},
+			{"datePublished", "date", kindDate},
+			{"prepTime", "prep_time", kindDuration},
+			{"cookTime", "cook_time", kindDuration},
+			{"totalTime", "total_time", kindDuration},
+			{"recipeYield", "servings", kindText},
+			{"recipeCategory", "categories", kindKeywords},
+			{"recipeCuisine", "cuisine", kindText},
+			{"recipeIngredient", "ingredients", kindList},
+			{"recipeInstructions", "instructions", kindSteps},
+			{"nutrition", "calories", kindCalories},
+			{"keywords", "tags", kindKeywords},
+		},
+		required:    []string{"name", "image"},
+		recommended: []string{"author", "datePublished", "description", "recipeIngredient", "recipeInstructions", "totalTime", "recipeYield"},
+	},
+	TypeEvent: {
+		properties: []property{
+			{"name", "title", kindText},
+			{"description", "description", kindText},
+			{"image", "images", kindImage},
+			{"startDate", "date", kindDate},
+			{"endDate", "endDate", kindDate},
+			{"location", "location", kindPlace},
+			{"organizer", "organizer", kindPerson},
+		},
+		required:    []string{"name", "startDate", "location"},
+		recommended: []string{"description", "endDate", "image", "organizer"},
+	},
+	TypeFAQPage: {
+		properties: []property{
+			{"mainEntity", "faq", kindFAQ},
+		},
+		required: []string{"mainEntity"},
+	},
+}
+
+// Types returns the supported schema.org types
+func Types() []string {
+	types := make([]string, 0, len(schemas))
+	for t := range schemas {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build converts front matter into a JSON-LD object of the given type. overrides replaces the front matter
+// source of properties, and may add text properties the defaults don't cover.
+func Build(typ string, fm hugocontent.FrontMatter, overrides map[string]string, url string) (map[string]interface{}, []Issue, error) {
+	def, ok := schemas[typ]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownType, typ)
+	}
+
+	props := append([]property(nil), def.properties...)
+	for name, source := range overrides {
+		found := false
+		for i := range props {
+			if props[i].name == name {
+				props[i].source = source
+				found = true
+			}
+		}
+		if !found {
+			props = append(props, property{name, source, kindText})
+		}
+	}
+
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    typ,
+	}
+	if url != "" && typ != TypeFAQPage {
+		data["url"] = url
+	}
+
+	issues := []Issue{}
+	invalid := map[string]bool{}
+	for _, p := range props {
+		raw := lookup(fm, p.source)
+		if raw == nil && p.kind == kindImage && p.source == "images" {
+			raw = lookup(fm, "image") // Single image themes
+		}
+		value, err := convert(p.kind, raw)
+		if err != nil {
+			issues = append(issues, Issue{Property: p.name, Severity: SeverityError, Message: err.Error()})
+			invalid[p.name] = true
+			continue
+		}
+		if value != nil {
+			data[p.name] = value
+		}
+	}
+
+	// Recipe fallback: total time is the sum of preparation and cooking
+	if typ == TypeRecipe && data["totalTime"] == nil && data["prepTime"] != nil && data["cookTime"] != nil {
+		prep, _ := parseDuration(lookup(fm, sourceOf(props, "prepTime")))
+		cook, _ := parseDuration(lookup(fm, sourceOf(props, "cookTime")))
+		data["totalTime"] = isoDuration(prep + cook)
+	}
+
+	for _, name := range def.required {
+		if data[name] == nil && !invalid[name] {
+			issues = append(issues, Issue{Property: name, Severity: SeverityError,
+				Message: fmt.Sprintf("%s requires %s (front matter %q)", typ, name, sourceOf(props, name))})
+		}
+	}
+	for _, name := range def.recommended {
+		if data[name] == nil && !invalid[name] {
+			issues = append(issues, Issue{Property: name, Severity: SeverityWarning,
+				Message: fmt.Sprintf("%s is recommended (front matter %q)", name, sourceOf(props, name))})
+		}
+	}
+
+	return data, issues, nil
+}
+
+func sourceOf(props []property, name string) string {
+	for _, p := range props {
+		if p.name == name {
+			return p.source
+		}
+	}
+	return ""
+}
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([\w.]+)\s*\}\}`)
+
+// lookup resolves a front matter key (dotted for nested maps) or a {{key}} template
+func lookup(fm hugocontent.FrontMatter, source string) interface{} {
+	if strings.Contains(source, "{{") {
+		out := placeholderRe.ReplaceAllStringFunc(source, func(m string) string {
+			v := lookup(fm, placeholderRe.FindStringSubmatch(m)[1])
+			if v == nil {
+				return ""
+			}
+			return fmt.Sprint(v)
+		})
+		if strings.TrimSpace(out) == "" {
+			return nil
+		}
+		return out
+	}
+
+	var current interface{} = map[string]interface{}(fm)
+	for _, part := range strings.Split(source, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = hugocontent.FrontMatter(m).Get(part)
+	}
+	return current
+}
+
+// convert turns a front matter value into the JSON-LD representation of a property kind
+func convert(kind string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	switch kind {
+	case kindDate:
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(time.RFC3339), nil
+		case string:
+			parsed, ok := hugocontent.ParseDate(t)
+			if !ok {
+				return nil, fmt.Errorf("%q is not an ISO 8601 date", t)
+			}
+			return parsed.Format(time.RFC3339), nil
+		}
+		return nil, fmt.Errorf("%v is not a date", v)
+
+	case kindDuration:
+		d, err := parseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		return isoDuration(d), nil
+
+	case kindImage, kindList:
+		list := stringList(v)
+		if len(list) == 0 {
+			return nil, nil
+		}
+		if kind == kindImage && len(list) == 1 {
+			return list[0], nil
+		}
+		return list, nil
+
+	case kindKeywords:
+		list := stringList(v)
+		if len(list) == 0 {
+			return nil, nil
+		}
+		return strings.Join(list, ", "), nil
+
+	case kindPerson:
+		var people []interface{}
+		for _, name := range stringList(v) {
+			people = append(people, map[string]interface{}{"@type": "Person", "name": name})
+		}
+		if len(people) == 1 {
+			return people[0], nil
+		}
+		return people, nil
+
+	case kindPlace:
+		if m, ok := v.(map[string]interface{}); ok {
+			place := map[string]interface{}{"@type": "Place"}
+			fm := hugocontent.FrontMatter(m)
+			if name := fm.String("name"); name != "" {
+				place["name"] = name
+			}
+			if address := fm.String("address"); address != "" {
+				place["address"] = address
+			}
+			return place, nil
+		}
+		s := fmt.Sprint(v)
+		if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+			return map[string]interface{}{"@type": "VirtualLocation", "url": s}, nil
+		}
+		return map[string]interface{}{"@type": "Place", "name": s, "address": s}, nil
+
+	case kindSteps:
+		var steps []interface{}
+		for _, text := range stringList(v) {
+			steps = append(steps, map[string]interface{}{"@type": "HowToStep", "text": text})
+		}
+		if len(steps) == 0 {
+			return nil, nil
+		}
+		return steps, nil
+
+	case kindFAQ:
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("faq must be a list of {question, answer} entries")
+		}
+		var questions []interface{}
+		for i, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("faq entry %d must have question and answer", i+1)
+			}
+			entry := hugocontent.FrontMatter(m)
+			q, a := entry.String("question"), entry.String("answer")
+			if q == "" || a == "" {
+				return nil, fmt.Errorf("faq entry %d must have question and answer", i+1)
+			}
+			questions = append(questions, map[string]interface{}{
+				"@type":          "Question",
+				"name":           q,
+				"acceptedAnswer": map[string]interface{}{"@type": "Answer", "text": a},
+			})
+		}
+		if len(questions) == 0 {
+			return nil, nil
+		}
+		return questions, nil
+
+	case kindCalories:
+		s := fmt.Sprint(v)
+		if !strings.Contains(s, "cal") {
+			s += " calories"
+		}
+		return map[string]interface{}{"@type": "NutritionInformation", "calories": s}, nil
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// stringList returns a string or list value as a list of strings
+func stringList(v interface{}) []string {
+	switch val := v.(type) {
+	case []interface{}:
+		var out []string
+		for _, item := range val {
+			if s := strings.TrimSpace(fmt.Sprint(item)); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case []string:
+		return val
+	}
+	if s := strings.TrimSpace(fmt.Sprint(v)); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+var isoDurationRe = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration accepts Go durations ("1h30m"), plain minutes (45) and ISO 8601 durations ("PT1H30M")
+func parseDuration(v interface{}) (time.Duration, error) {
+	switch val := v.(type) {
+	case int:
+		return time.Duration(val) * time.Minute, nil
+	case int64:
+		return time.Duration(val) * time.Minute, nil
+	case float64:
+		return time.Duration(val * float64(time.Minute)), nil
+	}
+
+	s := strings.ToUpper(strings.TrimSpace(fmt.Sprint(v)))
+	if m := isoDurationRe.FindStringSubmatch(s); m != nil && s != "P" && s != "PT" {
+		var d time.Duration
+		units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+		for i, unit := range units {
+			if m[i+1] != "" {
+				var n int
+				fmt.Sscan(m[i+1], &n)
+				d += time.Duration(n) * unit
+			}
+		}
+		return d, nil
+	}
+	if d, err := time.ParseDuration(strings.ToLower(s)); err == nil {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%q is not a duration (use 1h30m, minutes or PT1H30M)", fmt.Sprint(v))
+}
+
+// isoDuration formats a duration as ISO 8601, e.g. PT1H30M
+func isoDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h > 0 && m > 0:
+		return fmt.Sprintf("PT%dH%dM", h, m)
+	case h > 0:
+		return fmt.Sprintf("PT%dH", h)
+	}
+	return fmt.Sprintf("PT%dM", m)
+}
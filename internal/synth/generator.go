@@ -0,0 +1,238 @@
+package synth
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SizeClass is a predefined synthetic project size used for benchmarking
+type SizeClass struct {
+	Name     string
+	Pages    int
+	Images   int
+	Sections int
+}
+
+// SizeClasses are the reference project sizes used to define performance targets
+var SizeClasses = map[string]SizeClass{
+	"small":  {Name: "small", Pages: 100, Images: 50, Sections: 3},
+	"medium": {Name: "medium", Pages: 1000, Images: 500, Sections: 8},
+	"large":  {Name: "large", Pages: 8000, Images: 4000, Sections: 20},
+}
+
+// Options configures the generated project
+type Options struct {
+	OutputDir string
+	Pages     int
+	Images    int
+	Sections  int
+	Variants  []int // Widths of processed image variants to generate for each image
+	Seed      int64
+}
+
+// Stats summarizes what was generated
+type Stats struct {
+	Pages    int
+	Images   int
+	Files    int
+	Duration time.Duration
+}
+
+var words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor
+incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco
+laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse
+cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+deserunt mollit anim id est laborum hugo static site content image gallery release notes guide`)
+
+// Generate creates a synthetic Hugo project in opts.OutputDir
+func Generate(opts Options) (*Stats, error) {
+	start := time.Now()
+
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if opts.Sections <= 0 {
+		opts.Sections = 1
+	}
+	if entries, err := os.ReadDir(opts.OutputDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("output directory %s is not empty", opts.OutputDir)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	stats := &Stats{}
+
+	g := &generator{dir: opts.OutputDir, rng: rng, stats: stats}
+
+	if err := g.writeScaffold(); err != nil {
+		return nil, err
+	}
+
+	sections := make([]string, opts.Sections)
+	for i := range sections {
+		sections[i] = fmt.Sprintf("section-%02d", i+1)
+	}
+
+	imageURLs := make([]string, 0, opts.Images)
+	for i := 0; i < opts.Images; i++ {
+		folder := sections[i%len(sections)]
+		url, err := g.writeImage(folder, i, opts.Variants)
+		if err != nil {
+			return nil, err
+		}
+		imageURLs = append(imageURLs, url)
+		stats.Images++
+	}
+
+	for i := 0; i < opts.Pages; i++ {
+		section := sections[i%len(sections)]
+		if err := g.writePage(section, i, imageURLs); err != nil {
+			return nil, err
+		}
+		stats.Pages++
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+type generator struct {
+	dir   string
+	rng   *rand.Rand
+	stats *Stats
+}
+
+func (g *generator) write(rel, content string) error {
+	path := filepath.Join(g.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	g.stats.Files++
+	return nil
+}
+
+func (g *generator) writeScaffold() error {
+	files := map[string]string{
+		"hugo.toml": `baseURL = "https://example.org/"
+languageCode = "en-us"
+title = "Synthetic benchmark site"
+`,
+		"layouts/_default/baseof.html": `<!doctype html><html><head><title>{{ .Title }}</title></head><body>{{ block "main" . }}{{ end }}</body></html>
+`,
+		"layouts/_default/single.html": `{{ define "main" }}<h1>{{ .Title }}</h1>{{ .Content }}{{ end }}
+`,
+		"layouts/_default/list.html": `{{ define "main" }}<h1>{{ .Title }}</h1><ul>{{ range .Pages }}<li><a href="{{ .RelPermalink }}">{{ .Title }}</a></li>{{ end }}</ul>{{ end }}
+`,
+		"layouts/shortcodes/img.html": `{{- $src := .Get "src" -}}
+{{- $alt := .Get "alt" | default "" -}}
+<img src="{{ $src }}" alt="{{ $alt }}" loading="lazy">
+`,
+		"layouts/shortcodes/note.html": `{{- $type := .Get "type" | default "info" -}}
+<div class="note note-{{ $type }}">{{ .Inner | markdownify }}</div>
+`,
+	}
+
+	for rel, content := range files {
+		if err := g.write(rel, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) sentence(n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[g.rng.Intn(len(words))]
+	}
+	s := strings.Join(parts, " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (g *generator) writePage(section string, index int, imageURLs []string) error {
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, index)
+
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	sb.WriteString(fmt.Sprintf("title: %q\n", g.sentence(4)))
+	sb.WriteString(fmt.Sprintf("date: %s\n", date.Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("draft: %t\n", index%10 == 0))
+	sb.WriteString(fmt.Sprintf("tags: [%q, %q]\n", words[index%len(words)], words[(index*7)%len(words)]))
+	sb.WriteString(fmt.Sprintf("description: %q\n", g.sentence(12)))
+	sb.WriteString("---\n\n")
+
+	paragraphs := 3 + g.rng.Intn(5)
+	for p := 0; p < paragraphs; p++ {
+		if p > 0 && p%2 == 0 {
+			sb.WriteString(fmt.Sprintf("## %s\n\n", g.sentence(3)))
+		}
+		sb.WriteString(g.sentence(40 + g.rng.Intn(60)))
+		sb.WriteString(".\n\n")
+	}
+
+	if len(imageURLs) > 0 {
+		url := imageURLs[g.rng.Intn(len(imageURLs))]
+		sb.WriteString(fmt.Sprintf("{{< img src=%q alt=%q >}}\n\n", url, g.sentence(3)))
+	}
+	if index%5 == 0 {
+		sb.WriteString(fmt.Sprintf("{{< note type=\"warning\" >}}%s{{< /note >}}\n", g.sentence(10)))
+	}
+
+	return g.write(fmt.Sprintf("content/%s/page-%05d.md", section, index+1), sb.String())
+}
+
+func (g *generator) writeImage(folder string, index int, variants []int) (string, error) {
+	const width, height = 320, 180
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	base := color.RGBA{R: uint8(g.rng.Intn(256)), G: uint8(g.rng.Intn(256)), B: uint8(g.rng.Intn(256)), A: 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: base.R + uint8(x/4), G: base.G + uint8(y/4), B: base.B, A: 255})
+		}
+	}
+
+	name := fmt.Sprintf("image-%05d", index+1)
+	rel := fmt.Sprintf("static/images/%s/%s.jpg", folder, name)
+	if err := g.writeJPEG(rel, img); err != nil {
+		return "", err
+	}
+
+	// Processed variants use the same naming scheme as the image processor
+	for _, w := range variants {
+		h := height * w / width
+		variantRel := fmt.Sprintf("static/images/%s/%s.%dx%d.jpg", folder, name, w, h)
+		if err := g.writeJPEG(variantRel, img); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimPrefix(rel, "static"), nil
+}
+
+func (g *generator) writeJPEG(rel string, img image.Image) error {
+	path := filepath.Join(g.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := jpeg.Encode(file, img, &jpeg.Options{Quality: 60}); err != nil {
+		return err
+	}
+	g.stats.Files++
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package tasks
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the command in a new process group led by the command itself, so the
+// processes a script spawns stop with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateGroup sends SIGTERM to the process group led by pid
+func terminateGroup(pid int) error {
+	return signalGroup(pid, syscall.SIGTERM)
+}
+
+// killGroup sends SIGKILL to the process group led by pid
+func killGroup(pid int) error {
+	return signalGroup(pid, syscall.SIGKILL)
+}
+
+func signalGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil // Already gone
+	}
+	return err
+}
@@ -0,0 +1,414 @@
+// Package tasks runs the external commands configured in hugo-manager.yaml, such as npm scripts
+// that build a site's assets, keeping the output and status of each task's last run.
+package tasks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Status is the state of a run
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"  // Exited with an error or couldn't start
+	StatusStopped   Status = "stopped" // Stopped through the API
+	StatusTimeout   Status = "timeout" // Stopped after running longer than its timeout
+)
+
+// Streams an output line comes from
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+const (
+	defaultMaxOutput = 2000
+	maxLineLength    = 4096            // Longer lines are cut
+	killAfter        = 5 * time.Second // How long a stopped task has to exit before it's killed
+)
+
+var (
+	// ErrUnknownTask is returned for a name that isn't in tasks.commands
+	ErrUnknownTask = errors.New("task not found")
+
+	// ErrRunning is returned when running a task that is already running
+	ErrRunning = errors.New("task is already running")
+
+	// ErrNotRunning is returned when stopping a task that isn't running
+	ErrNotRunning = errors.New("task is not running")
+)
+
+// Line is a line a task wrote
+type Line struct {
+	Stream string    `json:"stream"` // stdout or stderr
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// Run is a run of a task
+type Run struct {
+	ID         string     `json:"id"`
+	Task       string     `json:"task"`
+	Status     Status     `json:"status"`
+	ExitCode   int        `json:"exitCode"` // -1 while running, or when it couldn't start
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+	Output     []Line     `json:"output,omitempty"`
+	Dropped    int        `json:"dropped,omitempty"` // Oldest lines not kept, beyond tasks.max_output
+}
+
+// Task is a configured command with its last run, without the output
+type Task struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Command     []string `json:"command"`
+	Dir         string   `json:"dir,omitempty"`
+	Timeout     int      `json:"timeout"` // Seconds, 0 = no limit
+	LastRun     *Run     `json:"lastRun,omitempty"`
+}
+
+// Manager runs the configured tasks, one run of each at a time
+type Manager struct {
+	projectDir string
+	config     config.TasksConfig
+
+	mu   sync.Mutex
+	runs map[string]*Execution // Last run of each task
+}
+
+// NewManager creates a new task manager
+func NewManager(projectDir string, cfg config.TasksConfig) *Manager {
+	return &Manager{projectDir: projectDir, config: cfg, runs: map[string]*Execution{}}
+}
+
+// List returns the configured tasks, in configuration order, with their last runs
+func (m *Manager) List() []Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Task, 0, len(m.config.Commands))
+	for _, cmd := range m.config.Commands {
+		list = append(list, m.task(cmd, false))
+	}
+	return list
+}
+
+// Get returns a task with the output of its last run
+func (m *Manager) Get(name string) (*Task, error) {
+	cmd, err := m.command(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task := m.task(cmd, true)
+	return &task, nil
+}
+
+// Start runs a task. The run goes on until the command exits, is stopped or times out, whatever
+// happens to the caller; follow its output with the returned execution.
+func (m *Manager) Start(name string) (*Execution, error) {
+	cmd, err := m.command(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if last := m.runs[name]; last != nil && last.running() {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrRunning, name)
+	}
+	maxOutput := m.config.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutput
+	}
+	e := &Execution{
+		run:       Run{ID: fmt.Sprintf("task-%d", time.Now().UnixNano()), Task: name, Status: StatusRunning, ExitCode: -1, StartedAt: time.Now()},
+		maxOutput: maxOutput,
+		notify:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	m.runs[name] = e
+	m.mu.Unlock()
+
+	c := exec.Command(cmd.Command[0], cmd.Command[1:]...)
+	c.Dir = filepath.Join(m.projectDir, filepath.FromSlash(cmd.Dir))
+	c.Env = append(os.Environ(), cmd.Env...)
+	setProcessGroup(c) // So stopping reaches the processes the command spawns too
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		e.finish(StatusFailed, -1, err)
+		return e, nil
+	}
+	stderr, err := c.StderrPipe()
+	if err != nil {
+		e.finish(StatusFailed, -1, err)
+		return e, nil
+	}
+	if err := c.Start(); err != nil {
+		e.finish(StatusFailed, -1, err)
+		return e, nil
+	}
+	e.mu.Lock()
+	e.pid = c.Process.Pid
+	stopped := e.stopReason != ""
+	e.mu.Unlock()
+	if stopped {
+		e.terminate(c.Process.Pid) // Stopped while starting
+	}
+
+	var timer *time.Timer
+	if timeout := m.timeout(cmd); timeout > 0 {
+		timer = time.AfterFunc(timeout, func() { e.stop(StatusTimeout) })
+	}
+
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); e.read(stdout, StreamStdout) }()
+		go func() { defer wg.Done(); e.read(stderr, StreamStderr) }()
+		wg.Wait()
+		err := c.Wait()
+
+		e.mu.Lock()
+		status := e.stopReason
+		e.mu.Unlock()
+		switch {
+		case status != "":
+			err = nil // The signal it was stopped with isn't an error of the task
+		case err != nil:
+			status = StatusFailed
+		default:
+			status = StatusSucceeded
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		e.finish(status, c.ProcessState.ExitCode(), err)
+	}()
+	return e, nil
+}
+
+// Stop stops a running task, asking its processes to exit and killing them if they're still
+// running after a few seconds
+func (m *Manager) Stop(name string) (*Run, error) {
+	if _, err := m.command(name); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	e := m.runs[name]
+	m.mu.Unlock()
+	if e == nil || !e.running() {
+		return nil, fmt.Errorf("%w: %s", ErrNotRunning, name)
+	}
+	e.stop(StatusStopped)
+	<-e.done
+	run := e.Result()
+	return &run, nil
+}
+
+// command returns the configuration of a task
+func (m *Manager) command(name string) (config.TaskCommand, error) {
+	for _, cmd := range m.config.Commands {
+		if cmd.Name == name {
+			return cmd, nil
+		}
+	}
+	return config.TaskCommand{}, fmt.Errorf("%w: %s", ErrUnknownTask, name)
+}
+
+// timeout returns how long a task may run, 0 for no limit
+func (m *Manager) timeout(cmd config.TaskCommand) time.Duration {
+	if cmd.Timeout > 0 {
+		return time.Duration(cmd.Timeout) * time.Second
+	}
+	return time.Duration(m.config.Timeout) * time.Second
+}
+
+// task describes a configured command with its last run. The caller holds m.mu.
+func (m *Manager) task(cmd config.TaskCommand, output bool) Task {
+	task := Task{
+		Name:        cmd.Name,
+		Description: cmd.Description,
+		Command:     cmd.Command,
+		Dir:         cmd.Dir,
+		Timeout:     int(m.timeout(cmd) / time.Second),
+	}
+	if e := m.runs[cmd.Name]; e != nil {
+		run := e.snapshot(output)
+		task.LastRun = &run
+	}
+	return task
+}
+
+// Execution is a run of a task, finished or not
+type Execution struct {
+	maxOutput int
+
+	mu         sync.Mutex
+	pid        int
+	run        Run
+	lines      []Line
+	first      int           // Number of lines dropped before lines[0]
+	stopReason Status        // Set when the run is stopped or times out
+	notify     chan struct{} // Closed and replaced when a line is added or the run ends
+	done       chan struct{} // Closed when the run ends
+}
+
+// Done returns a channel that is closed when the run ends
+func (e *Execution) Done() <-chan struct{} {
+	return e.done
+}
+
+// Result returns the run without its output
+func (e *Execution) Result() Run {
+	return e.snapshot(false)
+}
+
+// Follow calls fn with every line of the output kept so far and then with new lines as they're
+// written, until the run ends or ctx is done. Lines dropped while fn is slow are skipped.
+func (e *Execution) Follow(ctx context.Context, fn func(Line)) {
+	next := 0
+	for {
+		e.mu.Lock()
+		if next < e.first {
+			next = e.first
+		}
+		pending := append([]Line(nil), e.lines[next-e.first:]...)
+		next += len(pending)
+		notify := e.notify
+		finished := e.run.Status != StatusRunning
+		e.mu.Unlock()
+
+		for _, line := range pending {
+			fn(line)
+		}
+		if finished {
+			return
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// running reports whether the run hasn't ended
+func (e *Execution) running() bool {
+	select {
+	case <-e.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// snapshot copies the run, with its output if asked
+func (e *Execution) snapshot(output bool) Run {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	run := e.run
+	if run.Status == StatusRunning {
+		run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+	}
+	if output {
+		run.Output = append([]Line{}, e.lines...)
+		run.Dropped = e.first
+	}
+	return run
+}
+
+// read adds the lines of one stream to the output, cutting those longer than maxLineLength
+func (e *Execution) read(r io.Reader, stream string) {
+	reader := bufio.NewReader(r)
+	var buf []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if len(buf) < maxLineLength {
+			buf = append(buf, chunk[:min(len(chunk), maxLineLength-len(buf))]...)
+		}
+		if err != nil {
+			if len(buf) > 0 {
+				e.add(stream, string(buf))
+			}
+			return
+		}
+		if !isPrefix {
+			e.add(stream, string(buf))
+			buf = buf[:0]
+		}
+	}
+}
+
+// add appends a line to the output, dropping the oldest beyond maxOutput
+func (e *Execution) add(stream, text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lines = append(e.lines, Line{Stream: stream, Text: text, Time: time.Now()})
+	if over := len(e.lines) - e.maxOutput; over > 0 {
+		e.lines = append(e.lines[:0:0], e.lines[over:]...)
+		e.first += over
+	}
+	close(e.notify)
+	e.notify = make(chan struct{})
+}
+
+// stop signals the processes of a running task to exit, or has Start do it when they haven't
+// started yet
+func (e *Execution) stop(reason Status) {
+	e.mu.Lock()
+	if e.stopReason != "" || e.run.Status != StatusRunning {
+		e.mu.Unlock()
+		return
+	}
+	e.stopReason = reason
+	pid := e.pid
+	e.mu.Unlock()
+	if pid != 0 {
+		e.terminate(pid)
+	}
+}
+
+// terminate signals the process group of pid to exit, killing it after killAfter
+func (e *Execution) terminate(pid int) {
+	_ = terminateGroup(pid)
+	go func() {
+		select {
+		case <-e.done:
+		case <-time.After(killAfter):
+			_ = killGroup(pid)
+		}
+	}()
+}
+
+// finish records how the run ended and wakes up its followers
+func (e *Execution) finish(status Status, exitCode int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	e.run.Status = status
+	e.run.ExitCode = exitCode
+	e.run.FinishedAt = &now
+	e.run.DurationMs = now.Sub(e.run.StartedAt).Milliseconds()
+	if err != nil {
+		e.run.Error = err.Error()
+	}
+	close(e.notify)
+	e.notify = make(chan struct{})
+	close(e.done)
+}
@@ -0,0 +1,151 @@
+package themes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+var (
+	// tomlThemeRe matches the theme setting of a TOML configuration
+	tomlThemeRe = regexp.MustCompile(`(?i)^\s*["']?theme["']?\s*=`)
+
+	// tomlTableRe matches the header of a TOML table, where the top-level settings end
+	tomlTableRe = regexp.MustCompile(`^\s*\[`)
+
+	// yamlThemeRe matches the top-level theme setting of a YAML configuration
+	yamlThemeRe = regexp.MustCompile(`(?i)^["']?theme["']?\s*:`)
+)
+
+// Activate makes a theme the only one of the theme setting, in the file the site's configuration
+// is read from. Other lines of the file are left as they are. It returns the project-relative path
+// of the file.
+func (m *Manager) Activate(name string) (string, error) {
+	theme, err := m.Get(name)
+	if err != nil {
+		return "", err
+	}
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoSiteConfig, err)
+	}
+
+	full := filepath.Join(m.projectDir, filepath.FromSlash(siteCfg.File))
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	var out []byte
+	switch strings.ToLower(filepath.Ext(siteCfg.File)) {
+	case ".toml":
+		out = setTOML(data, theme.Name)
+	case ".yaml", ".yml":
+		out = setYAML(data, theme.Name)
+	case ".json":
+		if out, err = setJSON(data, theme.Name); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w: unsupported format %s", ErrNoSiteConfig, siteCfg.File)
+	}
+	if err := atomicfile.WriteFile(full, out, 0644); err != nil {
+		return "", err
+	}
+	return siteCfg.File, nil
+}
+
+// setTOML replaces the top-level theme setting, a string or an array that may span lines, or adds
+// it after the last top-level setting
+func setTOML(data []byte, name string) []byte {
+	lines := strings.Split(string(data), "\n")
+	setting := "theme = " + strconv.Quote(name)
+
+	end := len(lines)
+	for i, line := range lines {
+		if tomlTableRe.MatchString(line) {
+			end = i
+			break
+		}
+	}
+	for i := 0; i < end; i++ {
+		if !tomlThemeRe.MatchString(lines[i]) {
+			continue
+		}
+		last := i
+		value := strings.TrimSpace(lines[i][strings.Index(lines[i], "=")+1:])
+		if strings.HasPrefix(value, "[") && !strings.Contains(value, "]") {
+			for last+1 < end && !strings.Contains(lines[last], "]") {
+				last++
+			}
+		}
+		lines = append(lines[:i], append([]string{setting}, lines[last+1:]...)...)
+		return []byte(strings.Join(lines, "\n"))
+	}
+
+	at := end
+	for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	lines = append(lines[:at], append([]string{setting}, lines[at:]...)...)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// setYAML replaces the top-level theme setting, with the list items below it, or adds it at the end
+func setYAML(data []byte, name string) []byte {
+	lines := strings.Split(string(data), "\n")
+	setting := "theme: " + strconv.Quote(name)
+
+	for i, line := range lines {
+		if !yamlThemeRe.MatchString(line) {
+			continue
+		}
+		last := i
+		for last+1 < len(lines) {
+			next := lines[last+1]
+			if next == "" || strings.HasPrefix(next, " ") || strings.HasPrefix(next, "\t") || strings.HasPrefix(next, "-") {
+				last++
+				continue
+			}
+			break
+		}
+		// Blank lines after the setting belong to what follows
+		for last > i && strings.TrimSpace(lines[last]) == "" {
+			last--
+		}
+		lines = append(lines[:i], append([]string{setting}, lines[last+1:]...)...)
+		return []byte(strings.Join(lines, "\n"))
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text != "" {
+		text += "\n"
+	}
+	return []byte(text + setting + "\n")
+}
+
+// setJSON sets the theme key of a JSON configuration. Encoding the object again sorts its keys.
+func setJSON(data []byte, name string) ([]byte, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	key := "theme"
+	for k := range raw {
+		if strings.EqualFold(k, "theme") {
+			key = k
+		}
+	}
+	raw[key] = name
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,141 @@
+package themes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Ways to install a theme
+const (
+	MethodSubmodule = "submodule" // git submodule add to themes/<name>
+	MethodClone     = "clone"     // git clone to themes/<name>
+	MethodModule    = "module"    // hugo mod get; the theme setting names the module
+)
+
+var (
+	// gitURLRe matches the repository URLs git clones: http(s), ssh and git URLs, and scp-like
+	// user@host:path
+	gitURLRe = regexp.MustCompile(`^(?:(?:https?|ssh|git)://[^\s]+|[\w.-]+@[\w.-]+:[^\s]+)$`)
+
+	// themeNameRe matches the names of folders of themes/
+	themeNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// InstallOptions chooses where a theme comes from and how it's added
+type InstallOptions struct {
+	URL      string `json:"url"`      // Git repository of the theme
+	Name     string `json:"name"`     // Folder under themes/ (default the repository's name)
+	Method   string `json:"method"`   // submodule, clone or module (default submodule in a git repository, clone otherwise)
+	Activate bool   `json:"activate"` // Make it the theme of the site once installed
+}
+
+// Install adds a theme from a git repository and returns it. Submodules and clones go to
+// themes/<name>; a module is required in go.mod, which the project must have.
+func (m *Manager) Install(ctx context.Context, opts InstallOptions) (*Theme, error) {
+	url := strings.TrimSpace(opts.URL)
+	if !gitURLRe.MatchString(url) {
+		return nil, fmt.Errorf("%w: %q isn't a git repository URL", ErrInvalidSource, opts.URL)
+	}
+
+	method := opts.Method
+	if method == "" {
+		method = MethodClone
+		if _, err := os.Stat(filepath.Join(m.projectDir, ".git")); err == nil {
+			method = MethodSubmodule
+		}
+	}
+
+	var name string
+	switch method {
+	case MethodSubmodule, MethodClone:
+		name = opts.Name
+		if name == "" {
+			name = strings.TrimSuffix(path.Base(strings.TrimRight(url, "/")), ".git")
+			if i := strings.LastIndex(name, ":"); i >= 0 {
+				name = name[i+1:] // scp-like URL of a repository at the root
+			}
+		}
+		if !themeNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid theme name %q", ErrInvalidSource, name)
+		}
+		dir := path.Join("themes", name)
+		if _, err := os.Stat(filepath.Join(m.projectDir, filepath.FromSlash(dir))); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrThemeExists, dir)
+		}
+		if err := os.MkdirAll(filepath.Join(m.projectDir, "themes"), 0755); err != nil {
+			return nil, err
+		}
+		args := []string{"clone", "--depth", "1", "--", url, dir}
+		if method == MethodSubmodule {
+			if _, err := os.Stat(filepath.Join(m.projectDir, ".git")); err != nil {
+				return nil, fmt.Errorf("%w: the project isn't a git repository; use clone", ErrInvalidSource)
+			}
+			args = []string{"submodule", "add", "--", url, dir}
+		}
+		if err := m.run(ctx, "git", args...); err != nil {
+			return nil, err
+		}
+
+	case MethodModule:
+		if _, err := os.Stat(filepath.Join(m.projectDir, "go.mod")); err != nil {
+			return nil, fmt.Errorf("%w: the project isn't a Hugo Module; run hugo mod init first", ErrInvalidSource)
+		}
+		name = modulePath(url)
+		if name == "" {
+			return nil, fmt.Errorf("%w: no module path in %q", ErrInvalidSource, opts.URL)
+		}
+		if err := m.run(ctx, "hugo", "mod", "get", name); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown method %q (use submodule, clone or module)", ErrInvalidSource, method)
+	}
+
+	if opts.Activate {
+		if _, err := m.Activate(name); err != nil {
+			return nil, err
+		}
+	}
+	return m.Get(name)
+}
+
+// run runs a command in the project directory, with its output in the error when it fails
+func (m *Manager) run(ctx context.Context, command string, args ...string) error {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = m.projectDir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0") // Fail rather than wait for credentials
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", command, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// modulePath returns the module path of a repository URL: its host and path without .git
+func modulePath(url string) string {
+	rest := url
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+		if at := strings.Index(rest, "@"); at >= 0 && at < strings.Index(rest+"/", "/") {
+			rest = rest[at+1:] // User of an ssh URL
+		}
+	} else if at := strings.Index(rest, "@"); at >= 0 {
+		rest = strings.Replace(rest[at+1:], ":", "/", 1) // scp-like user@host:path
+	}
+	rest = strings.TrimSuffix(strings.TrimRight(rest, "/"), ".git")
+	if host, _, ok := strings.Cut(rest, "/"); ok {
+		if h, _, hasPort := strings.Cut(host, ":"); hasPort {
+			rest = h + rest[len(host):]
+		}
+	}
+	if !strings.Contains(rest, "/") {
+		return ""
+	}
+	return rest
+}
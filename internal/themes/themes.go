@@ -0,0 +1,291 @@
+// Package themes lists the Hugo themes of a project, in themes/ and as Hugo Modules, switches the
+// active one and installs new ones from git.
+package themes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/fernandezvara/hugo-manager/internal/shortcodes"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// Where a theme comes from
+const (
+	SourceFolder = "themes" // A folder under themes/
+	SourceModule = "module" // A Hugo Module, vendored or in the module cache
+)
+
+var (
+	// ErrUnknownTheme is returned for a theme that isn't installed
+	ErrUnknownTheme = errors.New("theme not found")
+
+	// ErrThemeExists is returned when installing to a folder of themes/ that already exists
+	ErrThemeExists = errors.New("theme already exists")
+
+	// ErrInvalidSource is returned for a theme URL or name that can't be installed
+	ErrInvalidSource = errors.New("invalid theme source")
+
+	// ErrNoSiteConfig is returned when the project has no Hugo configuration to switch the theme in
+	ErrNoSiteConfig = errors.New("no Hugo configuration file found")
+)
+
+// Theme is a theme installed in the project
+type Theme struct {
+	Name        string   `json:"name"`          // Folder under themes/, or module path; the value of the theme setting
+	Source      string   `json:"source"`        // themes or module
+	Dir         string   `json:"dir,omitempty"` // Project-relative folder; absolute for modules in the cache, empty for modules not downloaded
+	Active      bool     `json:"active"`        // In the theme setting or the module imports
+	Downloaded  bool     `json:"downloaded"`    // The files are on disk
+	Submodule   bool     `json:"submodule,omitempty"`
+	Title       string   `json:"title,omitempty"` // From theme.toml
+	Description string   `json:"description,omitempty"`
+	License     string   `json:"license,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	MinVersion  string   `json:"minVersion,omitempty"` // Oldest Hugo version the theme supports
+	Authors     []string `json:"authors,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Shortcodes  []string `json:"shortcodes"`
+	Archetypes  []string `json:"archetypes"` // Archetype files, relative to the theme's archetypes folder
+}
+
+// Inspection is a theme with its shortcodes parsed and its templates listed
+type Inspection struct {
+	Theme
+	ShortcodeDetails []shortcodes.Shortcode `json:"shortcodeDetails"`
+	Layouts          []string               `json:"layouts"`          // Template files, relative to the theme's layouts folder
+	Overridden       []string               `json:"overridden"`       // Templates the project's layouts/ replaces
+	Params           map[string]interface{} `json:"params,omitempty"` // Defaults of the theme's own site configuration
+}
+
+// themeMeta is the part of theme.toml the manager reads
+type themeMeta struct {
+	Name        string   `toml:"name"`
+	Description string   `toml:"description"`
+	License     string   `toml:"license"`
+	Homepage    string   `toml:"homepage"`
+	MinVersion  string   `toml:"min_version"`
+	Tags        []string `toml:"tags"`
+	Author      struct {
+		Name string `toml:"name"`
+	} `toml:"author"`
+	Authors []struct {
+		Name string `toml:"name"`
+	} `toml:"authors"`
+}
+
+// Manager lists, switches and installs the themes of a project
+type Manager struct {
+	projectDir string
+	parser     *shortcodes.Parser
+}
+
+// NewManager creates a new theme manager
+func NewManager(projectDir string, parser *shortcodes.Parser) *Manager {
+	return &Manager{projectDir: projectDir, parser: parser}
+}
+
+// List returns the folders of themes/ and the project's Hugo Modules: those imported, those named
+// by the theme setting and those go.mod requires. Active themes come first, in precedence order.
+func (m *Manager) List() ([]Theme, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		siteCfg = nil // Nothing is active without a configuration
+	}
+
+	var active []string
+	if siteCfg != nil {
+		active = append(siteCfg.Themes(), siteCfg.ModuleImports()...)
+	}
+	rank := map[string]int{}
+	for i, name := range active {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+
+	list := []Theme{}
+	seen := map[string]bool{}
+	entries, err := os.ReadDir(filepath.Join(m.projectDir, "themes"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			seen[entry.Name()] = true
+			list = append(list, m.folderTheme(entry.Name()))
+		}
+	}
+	modules := append(append([]string{}, active...), site.RequiredModules(m.projectDir)...)
+	for _, name := range modules {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if strings.Contains(name, "/") {
+			list = append(list, m.moduleTheme(name))
+		} else {
+			// The theme setting names a folder that isn't there, as in a clone without its submodules
+			list = append(list, Theme{Name: name, Source: SourceFolder, Dir: path.Join("themes", name), Shortcodes: []string{}, Archetypes: []string{}})
+		}
+	}
+
+	for i := range list {
+		_, list[i].Active = rank[list[i].Name]
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Active != list[j].Active {
+			return list[i].Active
+		}
+		if list[i].Active {
+			return rank[list[i].Name] < rank[list[j].Name]
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Get returns one installed theme by name
+func (m *Manager) Get(name string) (*Theme, error) {
+	list, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == name {
+			return &list[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTheme, name)
+}
+
+// Inspect returns a theme with the details of its shortcodes, its templates and the defaults of its
+// configuration, whether it's active or not
+func (m *Manager) Inspect(name string) (*Inspection, error) {
+	theme, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	in := &Inspection{Theme: *theme, ShortcodeDetails: []shortcodes.Shortcode{}, Layouts: []string{}, Overridden: []string{}}
+	if !theme.Downloaded {
+		return in, nil
+	}
+
+	dir := m.abs(theme.Dir)
+	source := shortcodes.SourceTheme + ":" + theme.Name
+	if theme.Source == SourceModule {
+		source = shortcodes.SourceModule + ":" + theme.Name
+	}
+	if found, err := m.parser.DetectDir(dir, source); err == nil && found != nil {
+		in.ShortcodeDetails = found
+	}
+
+	in.Layouts = listFiles(filepath.Join(dir, "layouts"))
+	for _, layout := range in.Layouts {
+		if _, err := os.Stat(filepath.Join(m.projectDir, "layouts", filepath.FromSlash(layout))); err == nil {
+			in.Overridden = append(in.Overridden, layout)
+		}
+	}
+	if cfg, err := site.Load(dir); err == nil {
+		in.Params = cfg.Map("params")
+	}
+	return in, nil
+}
+
+// folderTheme reads a theme in themes/
+func (m *Manager) folderTheme(name string) Theme {
+	rel := path.Join("themes", name)
+	theme := Theme{Name: name, Source: SourceFolder, Dir: rel, Downloaded: true}
+	// A submodule has a .git file pointing to the project's repository, a clone a .git folder
+	if stat, err := os.Stat(filepath.Join(m.abs(rel), ".git")); err == nil && !stat.IsDir() {
+		theme.Submodule = true
+	}
+	m.describe(&theme)
+	return theme
+}
+
+// moduleTheme reads a theme that is a Hugo Module
+func (m *Manager) moduleTheme(modulePath string) Theme {
+	theme := Theme{Name: modulePath, Source: SourceModule, Shortcodes: []string{}, Archetypes: []string{}}
+	dir := site.ModuleDir(m.projectDir, modulePath)
+	if dir == "" {
+		return theme
+	}
+	theme.Downloaded = true
+	theme.Dir = filepath.ToSlash(dir)
+	if rel, err := filepath.Rel(m.projectDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		theme.Dir = filepath.ToSlash(rel)
+	}
+	m.describe(&theme)
+	return theme
+}
+
+// describe fills in a downloaded theme's metadata, shortcodes and archetypes
+func (m *Manager) describe(theme *Theme) {
+	dir := m.abs(theme.Dir)
+	var meta themeMeta
+	if _, err := toml.DecodeFile(filepath.Join(dir, "theme.toml"), &meta); err == nil {
+		theme.Title = meta.Name
+		theme.Description = meta.Description
+		theme.License = meta.License
+		theme.Homepage = meta.Homepage
+		theme.MinVersion = meta.MinVersion
+		theme.Tags = meta.Tags
+		if meta.Author.Name != "" {
+			theme.Authors = append(theme.Authors, meta.Author.Name)
+		}
+		for _, author := range meta.Authors {
+			if author.Name != "" {
+				theme.Authors = append(theme.Authors, author.Name)
+			}
+		}
+	}
+
+	theme.Shortcodes = []string{}
+	if found, err := m.parser.DetectDir(dir, ""); err == nil {
+		for _, sc := range found {
+			theme.Shortcodes = append(theme.Shortcodes, sc.Name)
+		}
+	}
+	theme.Archetypes = listFiles(filepath.Join(dir, "archetypes"))
+}
+
+// abs returns the absolute path of a theme folder, which is absolute already for cached modules
+func (m *Manager) abs(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(m.projectDir, filepath.FromSlash(dir))
+}
+
+// listFiles returns the visible files below dir, relative to it and sorted
+func listFiles(dir string) []string {
+	files := []string{}
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if rel, err := filepath.Rel(dir, p); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
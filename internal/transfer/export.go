@@ -0,0 +1,309 @@
+package transfer
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+	"github.com/fernandezvara/hugo-manager/pkg/hugocontent"
+)
+
+var (
+	// markdownImageRe matches the URLs of Markdown images
+	markdownImageRe = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+
+	// attrRe matches the image attributes of HTML and shortcodes
+	attrRe = regexp.MustCompile(`(?i)\b(src|srcset|poster|image|thumbnail|cover)\s*=\s*["']([^"']+)["']`)
+)
+
+// imageKeys are the front matter keys that point to images
+var imageKeys = []string{"images", "image", "featured_image", "featuredImage", "cover", "thumbnail"}
+
+// contentExts are the file types Hugo renders as pages
+var contentExts = map[string]bool{".md": true, ".markdown": true, ".html": true}
+
+// exportRun is the state of one export: the files found so far and the references that weren't
+type exportRun struct {
+	roots    roots
+	basePath string // Path of the site's baseURL, which URLs of static files start with
+	kinds    map[string]string
+	missing  map[Missing]bool
+}
+
+// Export lists the files of the selected sections and pages, with their bundles and the static
+// files they point to. Sections give every file below them.
+func (m *Manager) Export(selected []string) (*Manifest, error) {
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	contentSite, err := hugocontent.Open(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &exportRun{roots: projectRoots(siteCfg), kinds: map[string]string{}, missing: map[Missing]bool{}}
+	if base, err := url.Parse(siteCfg.BaseURL()); err == nil {
+		r.basePath = strings.TrimSuffix(base.Path, "/")
+	}
+
+	manifest := &Manifest{Version: ManifestVersion, CreatedAt: time.Now().UTC(), Source: siteCfg.BaseURL(), Selected: []string{}, Files: []File{}}
+	for _, sel := range selected {
+		rel := cleanPath(sel)
+		if rel == "" {
+			continue
+		}
+		if r.roots.contentRoot(rel) == "" || isHidden(rel) {
+			return nil, fmt.Errorf("%w: %s", hugocontent.ErrNotContent, rel)
+		}
+		if err := m.add(r, contentSite, rel); err != nil {
+			return nil, err
+		}
+		manifest.Selected = append(manifest.Selected, rel)
+	}
+	if len(manifest.Selected) == 0 {
+		return nil, ErrNothingSelected
+	}
+
+	// Pages are read once all of them are known, so a reference to a file already exported isn't
+	// looked up again
+	var pages []string
+	for rel, kind := range r.kinds {
+		if kind == KindPage {
+			pages = append(pages, rel)
+		}
+	}
+	sort.Strings(pages)
+	for _, rel := range pages {
+		if err := m.references(r, rel); err != nil {
+			return nil, err
+		}
+	}
+
+	for rel, kind := range r.kinds {
+		file, err := m.describe(rel, kind)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	for missing := range r.missing {
+		manifest.Missing = append(manifest.Missing, missing)
+	}
+	sort.Slice(manifest.Missing, func(i, j int) bool {
+		if manifest.Missing[i].Page != manifest.Missing[j].Page {
+			return manifest.Missing[i].Page < manifest.Missing[j].Page
+		}
+		return manifest.Missing[i].Ref < manifest.Missing[j].Ref
+	})
+	return manifest, nil
+}
+
+// add adds a selected section or page: every visible file below a folder, or a page with the
+// resources of its bundle when it's a bundle's index
+func (m *Manager) add(r *exportRun, contentSite *hugocontent.Site, rel string) error {
+	stat, err := os.Stat(m.abs(rel))
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		root := m.abs(rel)
+		return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !d.Type().IsRegular() {
+				return nil
+			}
+			sub, err := filepath.Rel(m.projectDir, p)
+			if err != nil {
+				return err
+			}
+			r.addContent(filepath.ToSlash(sub))
+			return nil
+		})
+	}
+
+	r.addContent(rel)
+	base := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	if base := strings.SplitN(base, ".", 2)[0]; contentExts[strings.ToLower(path.Ext(rel))] && (base == "index" || base == "_index") {
+		bundle, err := contentSite.Bundle(rel)
+		if err != nil {
+			return err
+		}
+		for _, resource := range bundle.Resources {
+			r.addContent(resource)
+		}
+	}
+	return nil
+}
+
+// addContent adds a file of a content folder, as a page or a resource
+func (r *exportRun) addContent(rel string) {
+	if contentExts[strings.ToLower(path.Ext(rel))] {
+		r.kinds[rel] = KindPage
+	} else {
+		r.kinds[rel] = KindResource
+	}
+}
+
+// references adds the files a page points to in its body and front matter: static files by
+// their URL, and files of the content folders by a path relative to the page
+func (m *Manager) references(r *exportRun, page string) error {
+	data, err := os.ReadFile(m.abs(page))
+	if err != nil {
+		return err
+	}
+	fm, body, _, err := hugocontent.Parse(data)
+	if err != nil {
+		fm, body = nil, string(data) // Its references are still worth carrying
+	}
+
+	var refs []string
+	for _, match := range markdownImageRe.FindAllStringSubmatch(body, -1) {
+		refs = append(refs, match[1])
+	}
+	for _, match := range attrRe.FindAllStringSubmatch(body, -1) {
+		if strings.EqualFold(match[1], "srcset") {
+			for _, candidate := range strings.Split(match[2], ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					refs = append(refs, fields[0])
+				}
+			}
+			continue
+		}
+		refs = append(refs, match[2])
+	}
+	for _, key := range imageKeys {
+		refs = append(refs, fm.Strings(key)...)
+	}
+
+	for _, ref := range refs {
+		m.reference(r, page, ref)
+	}
+	return nil
+}
+
+// reference adds the file of one reference, or records it as missing. External URLs and
+// references to generated files, such as those of templates, are left alone.
+func (m *Manager) reference(r *exportRun, page, ref string) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "//") || strings.Contains(ref, "{{") {
+		return
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return
+	}
+
+	var rel string
+	if strings.HasPrefix(u.Path, "/") {
+		p := u.Path
+		if r.basePath != "" && strings.HasPrefix(p, r.basePath+"/") {
+			p = strings.TrimPrefix(p, r.basePath)
+		}
+		rel = cleanPath(path.Join(r.roots.static, p))
+	} else {
+		rel = cleanPath(path.Join(path.Dir(page), u.Path))
+	}
+	if _, ok := r.kinds[rel]; ok {
+		return
+	}
+	if (!r.roots.isStatic(rel) && r.roots.contentRoot(rel) == "") || isHidden(rel) {
+		r.missing[Missing{Page: page, Ref: ref}] = true
+		return
+	}
+	if stat, err := os.Stat(m.abs(rel)); err != nil || !stat.Mode().IsRegular() {
+		r.missing[Missing{Page: page, Ref: ref}] = true
+		return
+	}
+	if r.roots.isStatic(rel) {
+		r.kinds[rel] = KindStatic
+	} else {
+		r.addContent(rel)
+	}
+}
+
+// describe returns the size and hash of a file
+func (m *Manager) describe(rel, kind string) (File, error) {
+	f, err := os.Open(m.abs(rel))
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: rel, Kind: kind, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// WriteArchive writes a zip of the files of a manifest, each under its project-relative path,
+// with the manifest at its root
+func (m *Manager) WriteArchive(w io.Writer, manifest *Manifest) error {
+	zw := zip.NewWriter(w)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(data); err != nil {
+		return err
+	}
+
+	for _, file := range manifest.Files {
+		if err := m.writeFile(zw, file.Path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeFile copies one file of the project into an archive
+func (m *Manager) writeFile(zw *zip.Writer, rel string) error {
+	f, err := os.Open(m.abs(rel))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	header.Method = zip.Deflate
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,163 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// ImportOptions chooses what an import does with files that already exist
+type ImportOptions struct {
+	Overwrite bool `json:"overwrite"` // Replace files that differ from those of the archive
+}
+
+// Entry is a file of an archive and what the import did with it
+type Entry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+}
+
+// Result reports what an import wrote
+type Result struct {
+	Source    string    `json:"source,omitempty"` // baseURL of the site the archive comes from
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []Entry   `json:"files"`
+	Missing   []Missing `json:"missing,omitempty"` // References the exporting site couldn't carry
+}
+
+// Import writes the files of an archive made by Export to their paths. The whole archive is
+// checked against its manifest first, so a damaged or altered archive writes nothing.
+func (m *Manager) Import(data []byte, opts ImportOptions) (*Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	entries := map[string]*zip.File{}
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+	if entries[ManifestName] == nil {
+		return nil, fmt.Errorf("%w: no %s; export the archive with hugo-manager", ErrInvalidArchive, ManifestName)
+	}
+	raw, err := readEntry(entries[ManifestName], 10<<20)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, ManifestName, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, ManifestName, err)
+	}
+	if manifest.Version < 1 || manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("%w: manifest version %d isn't supported", ErrInvalidArchive, manifest.Version)
+	}
+
+	siteCfg, err := site.Load(m.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	r := projectRoots(siteCfg)
+
+	contents := make([][]byte, len(manifest.Files))
+	for i, file := range manifest.Files {
+		if err := r.check(file); err != nil {
+			return nil, err
+		}
+		entry := entries[file.Path]
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s is in the manifest but not in the archive", ErrInvalidArchive, file.Path)
+		}
+		content, err := readEntry(entry, file.Size)
+		if err != nil && !errors.Is(err, errTooLarge) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, file.Path, err)
+		}
+		sum := sha256.Sum256(content)
+		if err != nil || int64(len(content)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, fmt.Errorf("%w: %s doesn't match its hash in the manifest", ErrInvalidArchive, file.Path)
+		}
+		contents[i] = content
+	}
+
+	result := &Result{Source: manifest.Source, CreatedAt: manifest.CreatedAt, Files: []Entry{}, Missing: manifest.Missing}
+	for i, file := range manifest.Files {
+		status, err := m.write(file.Path, contents[i], opts.Overwrite)
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, Entry{Path: file.Path, Kind: file.Kind, Status: status})
+	}
+	return result, nil
+}
+
+// check verifies that a file of a manifest goes to the folder of its kind in this project
+func (r roots) check(file File) error {
+	if file.Path == "" || cleanPath(file.Path) != file.Path || isHidden(file.Path) {
+		return fmt.Errorf("%w: invalid path %q", ErrInvalidArchive, file.Path)
+	}
+	switch file.Kind {
+	case KindPage, KindResource:
+		if r.contentRoot(file.Path) == "" {
+			return fmt.Errorf("%w: %s isn't in a content folder of this site", ErrInvalidArchive, file.Path)
+		}
+	case KindStatic:
+		if !r.isStatic(file.Path) {
+			return fmt.Errorf("%w: %s isn't in the static folder of this site", ErrInvalidArchive, file.Path)
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown kind %q", ErrInvalidArchive, file.Path, file.Kind)
+	}
+	return nil
+}
+
+// errTooLarge is returned by readEntry for a file larger than its limit
+var errTooLarge = errors.New("file is too large")
+
+// readEntry reads a file of an archive, failing when it has more than limit bytes
+func readEntry(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// write writes one file unless it already has the same bytes, or differs and isn't overwritten
+func (m *Manager) write(rel string, data []byte, overwrite bool) (string, error) {
+	full := m.abs(rel)
+	status := StatusCreated
+	if existing, err := os.ReadFile(full); err == nil {
+		if bytes.Equal(existing, data) {
+			return StatusUnchanged, nil
+		}
+		if !overwrite {
+			return StatusSkipped, nil
+		}
+		status = StatusReplaced
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return "", err
+	}
+	if err := atomicfile.WriteFile(full, data, 0644); err != nil {
+		return "", err
+	}
+	return status, nil
+}
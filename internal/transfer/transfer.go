@@ -0,0 +1,135 @@
+// Package transfer moves content between hugo-manager instances: selected sections and pages are
+// exported with the images they point to as a zip with a manifest, which another instance imports.
+package transfer
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// ManifestName is the name of the manifest at the root of an archive
+const ManifestName = "manifest.json"
+
+// ManifestVersion is the version of the manifest this package writes, and the latest it reads
+const ManifestVersion = 1
+
+// Kinds of files in an archive
+const (
+	KindPage     = "page"     // Content file rendered as a page
+	KindResource = "resource" // Other file of a content folder, such as a page bundle's image
+	KindStatic   = "static"   // File of the static folder a page points to
+)
+
+// Statuses of imported files
+const (
+	StatusCreated   = "created"
+	StatusReplaced  = "replaced"
+	StatusUnchanged = "unchanged" // The file already has the same bytes
+	StatusSkipped   = "skipped"   // The file differs; overwrite replaces it
+)
+
+var (
+	// ErrNothingSelected is returned for an export without paths
+	ErrNothingSelected = errors.New("no sections or pages selected")
+
+	// ErrInvalidArchive is returned for an archive without a valid manifest, or whose files
+	// don't match it
+	ErrInvalidArchive = errors.New("invalid archive")
+)
+
+// Manifest lists the files of an archive
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Source    string    `json:"source,omitempty"` // baseURL of the exporting site
+	Selected  []string  `json:"selected"`         // Sections and pages the export was asked for
+	Files     []File    `json:"files"`
+	Missing   []Missing `json:"missing,omitempty"`
+}
+
+// File is a file of an archive, stored under its project-relative path
+type File struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Missing is a reference of an exported page to a file that isn't in the project
+type Missing struct {
+	Page string `json:"page"`
+	Ref  string `json:"ref"`
+}
+
+// Manager exports and imports content archives
+type Manager struct {
+	projectDir string
+}
+
+// NewManager creates a new transfer manager
+func NewManager(projectDir string) *Manager {
+	return &Manager{projectDir: projectDir}
+}
+
+// abs returns the absolute path of a project-relative path
+func (m *Manager) abs(rel string) string {
+	return filepath.Join(m.projectDir, filepath.FromSlash(rel))
+}
+
+// cleanPath normalizes a project-relative path, so it can't point outside the project
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(strings.TrimSpace(p))), "/")
+}
+
+// roots are the folders of a project content can be exported from and imported to
+type roots struct {
+	content []string
+	static  string
+}
+
+// projectRoots returns the content folders of every language and the static folder of a site
+func projectRoots(siteCfg *site.Config) roots {
+	r := roots{static: "static"}
+	if dir := cleanPath(siteCfg.String("staticDir")); dir != "" {
+		r.static = dir
+	}
+	seen := map[string]bool{}
+	for _, lang := range siteCfg.Languages() {
+		dir := cleanPath(lang.ContentDir)
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			r.content = append(r.content, dir)
+		}
+	}
+	return r
+}
+
+// contentRoot returns the content folder rel is in, or ""
+func (r roots) contentRoot(rel string) string {
+	for _, dir := range r.content {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return dir
+		}
+	}
+	return ""
+}
+
+// isStatic reports whether rel is a file of the static folder
+func (r roots) isStatic(rel string) bool {
+	return strings.HasPrefix(rel, r.static+"/")
+}
+
+// isHidden reports whether a path has a hidden file or folder in it
+func isHidden(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package uploads
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SniffLen is how much of a file CheckType looks at to detect its type
+const SniffLen = 512
+
+// signatures lists the types http.DetectContentType finds in files of the extensions it knows by
+// their signature. Formats it doesn't always recognize also accept application/octet-stream.
+var signatures = map[string][]string{
+	"jpg":   {"image/jpeg"},
+	"jpeg":  {"image/jpeg"},
+	"png":   {"image/png"},
+	"gif":   {"image/gif"},
+	"webp":  {"image/webp"},
+	"bmp":   {"image/bmp"},
+	"ico":   {"image/x-icon"},
+	"pdf":   {"application/pdf"},
+	"zip":   {"application/zip"},
+	"gz":    {"application/x-gzip"},
+	"wav":   {"audio/wave"},
+	"ogg":   {"application/ogg"},
+	"webm":  {"video/webm"},
+	"mp3":   {"audio/mpeg", "application/octet-stream"},
+	"mp4":   {"video/mp4", "application/octet-stream"},
+	"m4a":   {"video/mp4", "application/octet-stream"},
+	"mov":   {"video/mp4", "application/octet-stream"},
+	"woff":  {"font/woff"},
+	"woff2": {"font/woff2"},
+	"ttf":   {"font/ttf"},
+	"otf":   {"font/otf"},
+	"eot":   {"application/vnd.ms-fontobject", "application/octet-stream"},
+}
+
+// textExtensions are the extensions of text files; htmlExtensions those of text that may start
+// like HTML
+var (
+	textExtensions = map[string]bool{
+		"md": true, "markdown": true, "html": true, "htm": true, "css": true, "scss": true, "sass": true,
+		"js": true, "mjs": true, "json": true, "yaml": true, "yml": true, "toml": true, "xml": true,
+		"csv": true, "txt": true, "svg": true, "vtt": true, "srt": true, "webmanifest": true,
+	}
+	htmlExtensions = map[string]bool{"html": true, "htm": true, "md": true, "markdown": true, "xml": true, "svg": true}
+)
+
+// CheckExtension checks that uploads.types allows the extension of dest in its folder
+func (m *Manager) CheckExtension(dest string) error {
+	if len(m.config.Types) == 0 {
+		return nil
+	}
+	dest = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(dest)), "/")
+	ext := extension(dest)
+
+	// The folders of the rules that match are all parents of dest, so the longest is the deepest
+	longest := -1
+	var allowed []string
+	for _, rule := range m.config.Types {
+		folder := strings.Trim(path.Clean("/"+filepath.ToSlash(rule.Folder)), "/")
+		if folder != "" && !strings.HasPrefix(dest, folder+"/") {
+			continue
+		}
+		if len(folder) > longest {
+			longest, allowed = len(folder), rule.Extensions
+		}
+	}
+	for _, a := range allowed {
+		if a = strings.ToLower(strings.TrimPrefix(a, ".")); a == "*" || (a == ext && ext != "") {
+			return nil
+		}
+	}
+	if ext == "" {
+		return fmt.Errorf("%w: %s has no extension", ErrTypeNotAllowed, dest)
+	}
+	return fmt.Errorf("%w: .%s files can't be uploaded to %s", ErrTypeNotAllowed, ext, path.Dir(dest))
+}
+
+// CheckType checks that a file may be uploaded to dest: uploads.types must allow its extension,
+// and the start of its content must look like a file of that extension. HTML is only accepted in
+// files whose extension can hold it.
+func (m *Manager) CheckType(dest string, content io.ReaderAt) error {
+	if err := m.CheckExtension(dest); err != nil {
+		return err
+	}
+	head := make([]byte, SniffLen)
+	n, err := content.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	ext := extension(dest)
+	detected, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+
+	var ok bool
+	switch {
+	case signatures[ext] != nil:
+		ok = slices.Contains(signatures[ext], detected)
+	case textExtensions[ext]:
+		ok = strings.HasPrefix(detected, "text/") && (detected != "text/html" || htmlExtensions[ext])
+	default:
+		ok = detected != "text/html"
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s looks like %s", ErrTypeMismatch, path.Base(filepath.ToSlash(dest)), detected)
+	}
+	return nil
+}
+
+// extension returns the lowercase extension of a path, without the dot
+func extension(p string) string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(p), "."))
+}
@@ -0,0 +1,386 @@
+package uploads
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// Errors returned by resumable uploads
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrInvalidUpload    = errors.New("invalid upload")
+	ErrExists           = errors.New("destination already exists")
+	ErrTooLarge         = errors.New("upload too large")
+	ErrOffsetMismatch   = errors.New("upload offset mismatch")
+	ErrChecksumMismatch = errors.New("upload checksum mismatch")
+	ErrTypeNotAllowed   = errors.New("file type not allowed")
+	ErrTypeMismatch     = errors.New("file content doesn't match its extension")
+)
+
+// dirName is where unfinished uploads are kept, inside the .hugo-manager directory
+const dirName = "uploads"
+
+// Upload is a file being uploaded in chunks
+type Upload struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`      // Project-relative destination
+	Size      int64     `json:"size"`      // Total bytes
+	SHA256    string    `json:"sha256"`    // Expected checksum of the whole file, hex
+	Overwrite bool      `json:"overwrite"` // Replace an existing file at Path
+	Offset    int64     `json:"offset"`    // Bytes received; the next chunk starts here
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"` // Last chunk received
+	Expires   time.Time `json:"expires"` // Discarded after this without new chunks
+}
+
+// Manager keeps unfinished uploads on disk, so they can be resumed after a dropped connection or a restart
+type Manager struct {
+	projectDir string
+	config     config.UploadsConfig
+	dir        string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // Serializes the requests of each upload
+}
+
+// NewManager creates a new upload manager
+func NewManager(projectDir string, cfg config.UploadsConfig) *Manager {
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		dir:        filepath.Join(projectDir, storage.DirName, dirName),
+		locks:      map[string]*sync.Mutex{},
+	}
+}
+
+// MaxChunk returns the largest chunk accepted per request, in bytes
+func (m *Manager) MaxChunk() int64 {
+	return int64(max(m.config.ChunkSizeMB, 1)) << 20
+}
+
+// Create starts an upload of size bytes to a project-relative path. The file is only placed
+// there once every byte arrived and its SHA-256 matches sum.
+func (m *Manager) Create(dest string, size int64, sum string, overwrite bool) (*Upload, error) {
+	dest = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(dest)), "/")
+	if dest == "" {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidUpload)
+	}
+	if err := m.CheckExtension(dest); err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("%w: size can't be negative", ErrInvalidUpload)
+	}
+	if limit := int64(m.config.MaxSizeMB) << 20; limit > 0 && size > limit {
+		return nil, fmt.Errorf("%w: %d bytes is over the %d MB limit", ErrTooLarge, size, m.config.MaxSizeMB)
+	}
+	sum = strings.ToLower(sum)
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return nil, fmt.Errorf("%w: sha256 must be the hex checksum of the file", ErrInvalidUpload)
+	}
+	if stat, err := os.Stat(m.abs(dest)); err == nil && (!overwrite || stat.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrExists, dest)
+	}
+
+	m.prune()
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u := &Upload{ID: hex.EncodeToString(id), Path: dest, Size: size, SHA256: sum, Overwrite: overwrite, Created: now, Updated: now}
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+	if err := os.WriteFile(m.partPath(u.ID), nil, 0644); err != nil {
+		return nil, err
+	}
+	if err := m.save(u); err != nil {
+		os.Remove(m.partPath(u.ID))
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get returns an upload with the offset to resume it from
+func (m *Manager) Get(id string) (*Upload, error) {
+	unlock := m.lock(id)
+	defer unlock()
+	return m.load(id)
+}
+
+// List returns the unfinished uploads, oldest first
+func (m *Manager) List() ([]Upload, error) {
+	m.prune()
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []Upload{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := []Upload{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if u, err := m.Get(id); err == nil {
+			uploads = append(uploads, *u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].Created.Before(uploads[j].Created) })
+	return uploads, nil
+}
+
+// Write appends a chunk starting at offset, which must be the upload's current offset. A chunk
+// cut short by a dropped connection keeps the bytes that arrived, so the upload resumes after them.
+func (m *Manager) Write(id string, offset int64, chunk io.Reader) (*Upload, error) {
+	unlock := m.lock(id)
+	defer unlock()
+
+	u, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return u, fmt.Errorf("%w: the upload continues at byte %d, not %d", ErrOffsetMismatch, u.Offset, offset)
+	}
+
+	f, err := os.OpenFile(m.partPath(id), os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// One byte over the limit is read to tell a chunk that fits from one that doesn't
+	limit := min(m.MaxChunk(), u.Size-offset)
+	n, copyErr := io.Copy(f, io.LimitReader(chunk, limit+1))
+	if n > limit {
+		f.Truncate(offset)
+		if limit == u.Size-offset {
+			return u, fmt.Errorf("%w: the chunk goes past the upload's %d bytes", ErrInvalidUpload, u.Size)
+		}
+		return u, fmt.Errorf("%w: chunks can't be larger than %d MB", ErrTooLarge, max(m.config.ChunkSizeMB, 1))
+	}
+
+	u.Offset += n
+	u.Updated = time.Now()
+	u.Expires = m.expires(u.Updated)
+	if err := m.save(u); err != nil {
+		return nil, err
+	}
+	if copyErr != nil {
+		return u, fmt.Errorf("failed to receive chunk: %w", copyErr)
+	}
+	return u, nil
+}
+
+// Complete checks that every byte arrived and matches the checksum, then moves the file to its
+// destination. A file that doesn't match is discarded, as there's no telling which chunk is wrong.
+func (m *Manager) Complete(id string) (*Upload, error) {
+	unlock := m.lock(id)
+	defer unlock()
+
+	u, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Offset != u.Size {
+		return u, fmt.Errorf("%w: %d of %d bytes received", ErrOffsetMismatch, u.Offset, u.Size)
+	}
+
+	sum, err := checksum(m.partPath(id))
+	if err != nil {
+		return nil, err
+	}
+	if sum != u.SHA256 {
+		m.remove(id)
+		return nil, fmt.Errorf("%w: expected %s, received %s; upload the file again", ErrChecksumMismatch, u.SHA256, sum)
+	}
+	if err := m.checkPart(u); err != nil {
+		if errors.Is(err, ErrTypeMismatch) {
+			m.remove(id) // Uploading it again won't change what it is
+		}
+		return nil, err
+	}
+
+	target := m.abs(u.Path)
+	if stat, err := os.Stat(target); err == nil && (!u.Overwrite || stat.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrExists, u.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(m.partPath(id), target); err != nil {
+		return nil, fmt.Errorf("failed to place upload: %w", err)
+	}
+	m.remove(id)
+	return u, nil
+}
+
+// Abort discards an upload
+func (m *Manager) Abort(id string) error {
+	unlock := m.lock(id)
+	defer unlock()
+
+	if _, err := m.load(id); err != nil {
+		return err
+	}
+	m.remove(id)
+	return nil
+}
+
+// checkPart checks the type of an upload's data against its destination
+func (m *Manager) checkPart(u *Upload) error {
+	f, err := os.Open(m.partPath(u.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.CheckType(u.Path, f)
+}
+
+// load reads an upload, with its offset taken from the bytes on disk
+func (m *Manager) load(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(m.metaPath(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("invalid upload %s: %w", id, err)
+	}
+	stat, err := os.Stat(m.partPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	u.Offset = stat.Size()
+	u.Expires = m.expires(u.Updated)
+	return &u, nil
+}
+
+// save writes the description of an upload next to its data
+func (m *Manager) save(u *Upload) error {
+	u.Expires = m.expires(u.Updated)
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.metaPath(u.ID), data, 0644)
+}
+
+// remove deletes the data and description of an upload
+func (m *Manager) remove(id string) {
+	os.Remove(m.partPath(id))
+	os.Remove(m.metaPath(id))
+
+	m.mu.Lock()
+	delete(m.locks, id)
+	m.mu.Unlock()
+}
+
+// prune discards the uploads that expired
+func (m *Manager) prune() {
+	if m.config.ExpireHours <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		unlock := m.lock(id)
+		if u, err := m.load(id); err == nil && now.After(u.Expires) {
+			m.remove(id)
+		}
+		unlock()
+	}
+}
+
+// lock serializes the requests of an upload and returns the unlock function
+func (m *Manager) lock(id string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[id] = l
+	}
+	m.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// expires returns when an upload last updated at t is discarded
+func (m *Manager) expires(t time.Time) time.Time {
+	if m.config.ExpireHours <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(m.config.ExpireHours) * time.Hour)
+}
+
+func (m *Manager) partPath(id string) string {
+	return filepath.Join(m.dir, id+".part")
+}
+
+func (m *Manager) metaPath(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
+
+// abs returns the absolute path of a project-relative path
+func (m *Manager) abs(rel string) string {
+	return filepath.Join(m.projectDir, filepath.FromSlash(rel))
+}
+
+// validID reports whether id looks like an upload ID, so it can't point outside the uploads directory
+func validID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == 16
+}
+
+// checksum returns the hex SHA-256 of a file
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
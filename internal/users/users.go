@@ -0,0 +1,470 @@
+package users
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// Errors returned by the user store
+var (
+	ErrNotFound       = errors.New("user not found")
+	ErrExists         = errors.New("user already exists")
+	ErrInvalidUser    = errors.New("invalid user")
+	ErrBadCredentials = errors.New("invalid username or password")
+)
+
+// fileName is where the users are kept, inside the .hugo-manager directory
+const fileName = "users.json"
+
+// Password hashing parameters, stored with each hash so they can be raised later
+const (
+	hashIterations = 600000
+	hashLength     = 32
+	minPassword    = 8
+)
+
+// usernameRe matches the usernames accepted, which end up in logs and commit authors
+var usernameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+// User is an account that can sign in to the UI or call the API with its token
+type User struct {
+	Username string    `json:"username"`
+	Name     string    `json:"name,omitempty"`  // Full name, for commit authors
+	Email    string    `json:"email,omitempty"` // For commit authors
+	Role     string    `json:"role"`            // admin or editor
+	HasToken bool      `json:"hasToken"`        // Whether an API token was issued
+	Created  time.Time `json:"created"`
+}
+
+// Author returns the user as a git author, "Name <email>"; the username stands in for a missing name
+func (u *User) Author() string {
+	name := u.Name
+	if name == "" {
+		name = u.Username
+	}
+	if u.Email == "" {
+		return name
+	}
+	return name + " <" + u.Email + ">"
+}
+
+// Update holds the fields of a user to change; nil fields are kept
+type Update struct {
+	Name     *string `json:"name"`
+	Email    *string `json:"email"`
+	Role     *string `json:"role"`
+	Password *string `json:"password"`
+}
+
+// record is a user as stored, with its secrets
+type record struct {
+	User
+	Password string `json:"password,omitempty"` // pbkdf2-sha256$iterations$salt$hash
+	Token    string `json:"token,omitempty"`    // Hex SHA-256 of the API token
+}
+
+// session is a signed-in user of the UI
+type session struct {
+	username string
+	expires  time.Time
+}
+
+// Store keeps the users in a file of the .hugo-manager directory and their login sessions in memory,
+// so a restart signs everyone out
+type Store struct {
+	path       string
+	sessionTTL time.Duration
+
+	mu       sync.RWMutex
+	users    map[string]*record
+	sessions map[string]session
+	loadErr  error // Why the users file couldn't be read; it isn't overwritten then
+}
+
+// NewStore loads the users of a project. sessionTTL is how long a login lasts. A store is returned
+// even when the users file can't be read, without users and refusing changes, so the configured
+// tokens keep working while the file is fixed.
+func NewStore(projectDir string, sessionTTL time.Duration) (*Store, error) {
+	s := &Store{
+		path:       filepath.Join(projectDir, storage.DirName, fileName),
+		sessionTTL: sessionTTL,
+		users:      map[string]*record{},
+		sessions:   map[string]session{},
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		s.loadErr = err
+		return s, err
+	}
+	var records []*record
+	if err := json.Unmarshal(data, &records); err != nil {
+		s.loadErr = fmt.Errorf("failed to read %s: %w", s.path, err)
+		return s, s.loadErr
+	}
+	for _, rec := range records {
+		s.users[rec.Username] = rec
+	}
+	return s, nil
+}
+
+// Len returns the number of users
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// List returns the users sorted by username
+func (s *Store) List() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]User, 0, len(s.users))
+	for _, rec := range s.users {
+		list = append(list, rec.User)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	return list
+}
+
+// Get returns a user by username
+func (s *Store) Get(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, username)
+	}
+	u := rec.User
+	return &u, nil
+}
+
+// Create adds a user with a password, which may be empty for users that only use an API token
+func (s *Store) Create(u User, password string) (*User, error) {
+	u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+	if !usernameRe.MatchString(u.Username) {
+		return nil, fmt.Errorf("%w: usernames are 1-32 lowercase letters, digits, dots, dashes or underscores", ErrInvalidUser)
+	}
+	if err := validateRole(u.Role); err != nil {
+		return nil, err
+	}
+	rec := &record{User: u}
+	rec.Created = time.Now().UTC().Truncate(time.Second)
+	rec.HasToken = false
+	if password != "" {
+		hash, err := hashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		rec.Password = hash
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[u.Username]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrExists, u.Username)
+	}
+	s.users[u.Username] = rec
+	if err := s.save(); err != nil {
+		delete(s.users, u.Username)
+		return nil, err
+	}
+	created := rec.User
+	return &created, nil
+}
+
+// Update changes the fields of a user that are set. A new password signs the user out everywhere.
+func (s *Store) Update(username string, upd Update) (*User, error) {
+	if upd.Role != nil {
+		if err := validateRole(*upd.Role); err != nil {
+			return nil, err
+		}
+	}
+	var hash string
+	if upd.Password != nil && *upd.Password != "" {
+		var err error
+		if hash, err = hashPassword(*upd.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.users[username]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, username)
+	}
+	prev := *rec
+	if upd.Name != nil {
+		rec.Name = *upd.Name
+	}
+	if upd.Email != nil {
+		rec.Email = *upd.Email
+	}
+	if upd.Role != nil {
+		rec.Role = *upd.Role
+	}
+	if upd.Password != nil {
+		rec.Password = hash // An empty password disables password logins
+		s.endSessions(username)
+	}
+	if err := s.save(); err != nil {
+		*rec = prev
+		return nil, err
+	}
+	u := rec.User
+	return &u, nil
+}
+
+// Delete removes a user and signs it out
+func (s *Store) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.users[username]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, username)
+	}
+	delete(s.users, username)
+	if err := s.save(); err != nil {
+		s.users[username] = rec
+		return err
+	}
+	s.endSessions(username)
+	return nil
+}
+
+// NewToken issues an API token for a user, replacing the previous one. Only its hash is kept, so
+// the token can't be shown again.
+func (s *Store) NewToken(username string) (string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.users[username]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, username)
+	}
+	prev := *rec
+	rec.Token, rec.HasToken = hashToken(token), true
+	if err := s.save(); err != nil {
+		*rec = prev
+		return "", err
+	}
+	return token, nil
+}
+
+// Authenticate returns the user an API token was issued to
+func (s *Store) Authenticate(token string) (*User, bool) {
+	if token == "" {
+		return nil, false
+	}
+	hash := []byte(hashToken(token))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found *User
+	for _, rec := range s.users {
+		if rec.Token != "" && subtle.ConstantTimeCompare(hash, []byte(rec.Token)) == 1 {
+			u := rec.User
+			found = &u
+		}
+	}
+	return found, found != nil
+}
+
+// Login checks a user's password and starts a session, returning its ID
+func (s *Store) Login(username, password string) (string, *User, error) {
+	s.mu.RLock()
+	rec, ok := s.users[strings.ToLower(strings.TrimSpace(username))]
+	var hash string
+	if ok {
+		hash = rec.Password
+	}
+	s.mu.RUnlock()
+
+	// Unknown users are checked against a made-up hash, so the response time doesn't tell them apart
+	if !checkPassword(hash, password) || !ok || hash == "" {
+		return "", nil, ErrBadCredentials
+	}
+
+	id, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneSessions()
+	s.sessions[id] = session{username: rec.Username, expires: time.Now().Add(s.sessionTTL)}
+	u := rec.User
+	return id, &u, nil
+}
+
+// Session returns the user signed in with a session ID
+func (s *Store) Session(id string) (*User, bool) {
+	if id == "" {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || time.Now().After(sess.expires) {
+		return nil, false
+	}
+	rec, ok := s.users[sess.username]
+	if !ok {
+		return nil, false
+	}
+	u := rec.User
+	return &u, true
+}
+
+// Logout ends a session
+func (s *Store) Logout(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// SessionTTL returns how long a login lasts
+func (s *Store) SessionTTL() time.Duration {
+	return s.sessionTTL
+}
+
+// endSessions signs a user out; s.mu must be held
+func (s *Store) endSessions(username string) {
+	for id, sess := range s.sessions {
+		if sess.username == username {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// pruneSessions drops expired sessions; s.mu must be held
+func (s *Store) pruneSessions() {
+	now := time.Now()
+	for id, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// save writes the users file, readable by its owner only as it holds password hashes; s.mu must be held
+func (s *Store) save() error {
+	if s.loadErr != nil {
+		return s.loadErr
+	}
+	records := make([]*record, 0, len(s.users))
+	for _, rec := range s.users {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Username < records[j].Username })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.path, data, 0600)
+}
+
+// validateRole checks that a role is one of the known roles
+func validateRole(role string) error {
+	if role != config.RoleAdmin && role != config.RoleEditor {
+		return fmt.Errorf("%w: unknown role '%s' (use %s or %s)", ErrInvalidUser, role, config.RoleAdmin, config.RoleEditor)
+	}
+	return nil
+}
+
+// hashPassword hashes a password with PBKDF2-SHA256 and a random salt
+func hashPassword(password string) (string, error) {
+	if len(password) < minPassword {
+		return "", fmt.Errorf("%w: passwords need at least %d characters", ErrInvalidUser, minPassword)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, hashLength)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// dummyHash is checked for unknown users, so they take as long as known ones
+var dummyHash = "pbkdf2-sha256$" + strconv.Itoa(hashIterations) + "$c2FsdHNhbHRzYWx0c2FsdA$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+// checkPassword reports whether a password matches a hash made by hashPassword
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		hash = dummyHash
+	}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// hashToken returns the hex SHA-256 of an API token; tokens are random, so a plain hash is enough
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes, URL-safe base64 encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
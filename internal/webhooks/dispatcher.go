@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/config"
+)
+
+// Event names
+const (
+	EventFileSaved      = "file.saved"
+	EventFileCreated    = "file.created"
+	EventFileDeleted    = "file.deleted"
+	EventImageUploaded  = "image.uploaded"
+	EventBuildSucceeded = "build.succeeded"
+	EventBuildFailed    = "build.failed"
+	EventDomainChanged  = "domain.changed"
+)
+
+// queueSize is the number of pending events kept before new ones are dropped
+const queueSize = 100
+
+// Payload is the JSON body posted to webhook endpoints
+type Payload struct {
+	Event     string                 `json:"event"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to the configured webhook endpoints in the background
+type Dispatcher struct {
+	config config.WebhooksConfig
+	client *http.Client
+	queue  chan Payload
+	mu     sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(cfg config.WebhooksConfig) *Dispatcher {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Dispatcher{
+		config: cfg,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan Payload, queueSize),
+	}
+}
+
+// Start begins delivering queued events
+func (d *Dispatcher) Start() {
+	if len(d.config.Endpoints) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stop != nil {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+
+	go d.run(d.stop, d.done)
+}
+
+// Stop stops delivering events, waiting for the delivery in progress to finish
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	stop, done := d.stop, d.done
+	d.stop, d.done = nil, nil
+	d.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Dispatch queues an event for delivery. It never blocks; events are dropped if the queue is full.
+func (d *Dispatcher) Dispatch(event string, data map[string]interface{}) {
+	if !d.wants(event) {
+		return
+	}
+
+	payload := Payload{Event: event, Timestamp: time.Now().UTC(), Data: data}
+	select {
+	case d.queue <- payload:
+	default:
+		slog.Warn("Webhook queue full, dropping event", "event", event)
+	}
+}
+
+// wants reports whether any endpoint subscribes to event
+func (d *Dispatcher) wants(event string) bool {
+	for _, endpoint := range d.config.Endpoints {
+		if subscribed(endpoint, event) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) run(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case payload := <-d.queue:
+			d.deliver(payload)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// deliver posts a payload to every endpoint subscribed to its event
+func (d *Dispatcher) deliver(payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Warn("Failed to encode webhook payload", "event", payload.Event, "error", err)
+		return
+	}
+
+	for _, endpoint := range d.config.Endpoints {
+		if !subscribed(endpoint, payload.Event) {
+			continue
+		}
+		if err := d.post(endpoint, payload.Event, body); err != nil {
+			slog.Warn("Webhook delivery failed", "event", payload.Event, "url", endpoint.URL, "error", err)
+		}
+	}
+}
+
+func (d *Dispatcher) post(endpoint config.WebhookEndpoint, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hugo-manager")
+	req.Header.Set("X-Hugo-Manager-Event", event)
+	if endpoint.Secret != "" {
+		req.Header.Set("X-Hugo-Manager-Signature", "sha256="+Sign(endpoint.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func subscribed(endpoint config.WebhookEndpoint, event string) bool {
+	if endpoint.URL == "" {
+		return false
+	}
+	if len(endpoint.Events) == 0 {
+		return true
+	}
+	for _, e := range endpoint.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
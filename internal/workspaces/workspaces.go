@@ -0,0 +1,538 @@
+// Package workspaces keeps draft workspaces: copies of the site in the .hugo-manager directory that
+// editors change and preview with their own Hugo server, without touching the live tree, until
+// the changes are merged into it.
+package workspaces
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/config"
+	"github.com/fernandezvara/hugo-manager/internal/hugo"
+	"github.com/fernandezvara/hugo-manager/internal/storage"
+)
+
+// Errors returned by workspaces
+var (
+	ErrNotFound    = errors.New("workspace not found")
+	ErrExists      = errors.New("workspace already exists")
+	ErrInvalidName = errors.New("invalid workspace name")
+	ErrConflict    = errors.New("changed in the live tree too")
+)
+
+const (
+	// dirName is where workspaces are kept, inside the .hugo-manager directory
+	dirName = "workspaces"
+
+	// manifestFile describes a workspace, next to its copy of the site
+	manifestFile = "workspace.json"
+
+	// siteDir is the copy of the site inside a workspace's directory
+	siteDir = "site"
+)
+
+// Operations of a change
+const (
+	OpAdded    = "added"
+	OpModified = "modified"
+	OpDeleted  = "deleted"
+)
+
+// nameRe matches workspace names, which are directory names and appear in URLs
+var nameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,39}$`)
+
+// skipped are never copied into a workspace, whatever workspaces.exclude says
+var skipped = []string{storage.DirName, ".git"}
+
+// Workspace is a copy of the site being edited
+type Workspace struct {
+	Name    string    `json:"name"`
+	User    string    `json:"user,omitempty"` // Who created it, empty without auth
+	Created time.Time `json:"created"`
+	Preview *Preview  `json:"preview,omitempty"` // Unset until a preview is started
+}
+
+// Preview is the Hugo server of a workspace
+type Preview struct {
+	Status  hugo.Status `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Port    int         `json:"port,omitempty"`
+}
+
+// Change is a file that differs between a workspace and the live tree it was copied from
+type Change struct {
+	Path     string `json:"path"`
+	Op       string `json:"op"`       // added, modified or deleted
+	Conflict bool   `json:"conflict"` // The live file changed too since the workspace was created
+}
+
+// manifest is a workspace as stored, with the SHA-256 of every file copied into it
+type manifest struct {
+	Workspace
+	Base map[string]string `json:"base"`
+}
+
+// Manager creates, previews and merges the workspaces of a project
+type Manager struct {
+	projectDir string
+	config     config.WorkspacesConfig
+	hugoConfig config.HugoConfig
+	dir        string
+
+	opMu     sync.Mutex // Serializes creating, merging and deleting workspaces
+	mu       sync.Mutex
+	previews map[string]*hugo.Manager
+}
+
+// NewManager creates a workspace manager. Previews run Hugo like the live server, on the ports of
+// workspaces.port.
+func NewManager(projectDir string, cfg config.WorkspacesConfig, hugoCfg config.HugoConfig) *Manager {
+	hugoCfg.Instances = nil // A preview is a single server
+	return &Manager{
+		projectDir: projectDir,
+		config:     cfg,
+		hugoConfig: hugoCfg,
+		dir:        filepath.Join(projectDir, storage.DirName, dirName),
+		previews:   map[string]*hugo.Manager{},
+	}
+}
+
+// List returns the workspaces by name
+func (m *Manager) List() ([]Workspace, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	list := []Workspace{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !nameRe.MatchString(entry.Name()) {
+			continue
+		}
+		man, err := m.manifest(entry.Name())
+		if err != nil {
+			continue // Being created or removed
+		}
+		list = append(list, m.withPreview(man.Workspace))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns a workspace
+func (m *Manager) Get(name string) (*Workspace, error) {
+	man, err := m.manifest(name)
+	if err != nil {
+		return nil, err
+	}
+	ws := m.withPreview(man.Workspace)
+	return &ws, nil
+}
+
+// Create copies the site into a new workspace, leaving out workspaces.exclude. Copying a large
+// site takes a while; it stops, removing the partial copy, when ctx is done.
+func (m *Manager) Create(ctx context.Context, name, user string) (*Workspace, error) {
+	if !nameRe.MatchString(name) {
+		return nil, fmt.Errorf("%w: names are 1-40 lowercase letters, digits, dots, dashes or underscores", ErrInvalidName)
+	}
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	wsDir := filepath.Join(m.dir, name)
+	if _, err := os.Stat(wsDir); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		return nil, err
+	}
+
+	man := manifest{
+		Workspace: Workspace{Name: name, User: user, Created: time.Now().UTC().Truncate(time.Second)},
+		Base:      map[string]string{},
+	}
+	if err := m.copySite(ctx, filepath.Join(wsDir, siteDir), man.Base); err != nil {
+		os.RemoveAll(wsDir)
+		return nil, err
+	}
+	// Written last: a directory without a manifest is a copy that never finished
+	if err := m.saveManifest(&man); err != nil {
+		os.RemoveAll(wsDir)
+		return nil, err
+	}
+	ws := man.Workspace
+	return &ws, nil
+}
+
+// Delete stops the preview of a workspace and discards it with its changes
+func (m *Manager) Delete(name string) error {
+	if _, err := m.manifest(name); err != nil {
+		return err
+	}
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+	m.stopPreview(name)
+	return os.RemoveAll(filepath.Join(m.dir, name))
+}
+
+// Dir returns the directory of a workspace's copy of the site, where it's edited
+func (m *Manager) Dir(name string) (string, error) {
+	if _, err := m.manifest(name); err != nil {
+		return "", err
+	}
+	return m.siteDir(name), nil
+}
+
+// Changes lists the files added, modified and deleted in a workspace, by path. Conflict is set
+// on those whose live file changed too since the workspace was created.
+func (m *Manager) Changes(ctx context.Context, name string) ([]Change, error) {
+	man, err := m.manifest(name)
+	if err != nil {
+		return nil, err
+	}
+	site := m.siteDir(name)
+
+	changes := []Change{}
+	seen := map[string]bool{}
+	err = filepath.WalkDir(site, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(mustRel(site, p))
+		if d.IsDir() {
+			if rel != "." && m.excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		seen[rel] = true
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		switch base, ok := man.Base[rel]; {
+		case !ok:
+			changes = append(changes, Change{Path: rel, Op: OpAdded})
+		case base != sum:
+			changes = append(changes, Change{Path: rel, Op: OpModified})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for rel := range man.Base {
+		if !seen[rel] {
+			changes = append(changes, Change{Path: rel, Op: OpDeleted})
+		}
+	}
+
+	for i, c := range changes {
+		live, err := hashFile(filepath.Join(m.projectDir, filepath.FromSlash(c.Path)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		// A file deleted on both sides is no conflict
+		changes[i].Conflict = live != man.Base[c.Path] && !(c.Op == OpDeleted && live == "")
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Merge writes the changes of a workspace into the live tree and discards the workspace,
+// returning the changes applied. While a live file changed too it fails with ErrConflict and
+// the changes, unless force is set to overwrite the live files.
+func (m *Manager) Merge(ctx context.Context, name string, force bool) ([]Change, error) {
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+	changes, err := m.Changes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		var conflicts []string
+		for _, c := range changes {
+			if c.Conflict {
+				conflicts = append(conflicts, c.Path)
+			}
+		}
+		if len(conflicts) > 0 {
+			return changes, fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, ", "))
+		}
+	}
+
+	// Past this point the merge runs to the end, so the live tree isn't left half merged
+	site := m.siteDir(name)
+	var errs []error
+	for _, c := range changes {
+		live := filepath.Join(m.projectDir, filepath.FromSlash(c.Path))
+		if c.Op == OpDeleted {
+			if err := os.Remove(live); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		src := filepath.Join(site, filepath.FromSlash(c.Path))
+		data, err := os.ReadFile(src)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(live), 0755)
+		}
+		if err == nil {
+			err = atomicfile.WriteFile(live, data, fileMode(src))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Path, err))
+		}
+	}
+	if len(errs) > 0 {
+		// The workspace is kept, so the merge can be retried
+		return changes, errors.Join(errs...)
+	}
+
+	m.stopPreview(name)
+	if err := os.RemoveAll(filepath.Join(m.dir, name)); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
+
+// StartPreview starts the Hugo server of a workspace, on the first free port of workspaces.port
+func (m *Manager) StartPreview(name string) (*Workspace, error) {
+	if _, err := m.manifest(name); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	preview := m.previews[name]
+	if preview == nil {
+		cfg := m.hugoConfig
+		cfg.Port, cfg.PortRange = m.config.Port, m.config.PortRange
+		cfg.Watchdog = config.WatchdogConfig{}
+		preview = hugo.NewManager(m.siteDir(name), cfg)
+		m.previews[name] = preview
+	}
+	m.mu.Unlock()
+
+	if err := preview.Start(); err != nil && !errors.Is(err, hugo.ErrRunning) {
+		return nil, err
+	}
+	return m.Get(name)
+}
+
+// StopPreview stops the Hugo server of a workspace. A preview that isn't running is left as it is.
+func (m *Manager) StopPreview(name string) (*Workspace, error) {
+	if _, err := m.manifest(name); err != nil {
+		return nil, err
+	}
+	m.stopPreview(name)
+	return m.Get(name)
+}
+
+// StopAll stops the Hugo servers of every workspace, when hugo-manager shuts down
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.previews))
+	for name := range m.previews {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	for _, name := range names {
+		m.stopPreview(name)
+	}
+}
+
+// stopPreview stops and forgets the Hugo server of a workspace
+func (m *Manager) stopPreview(name string) {
+	m.mu.Lock()
+	preview := m.previews[name]
+	delete(m.previews, name)
+	m.mu.Unlock()
+	if preview != nil {
+		if err := preview.Stop(); err != nil && !errors.Is(err, hugo.ErrNotRunning) {
+			slog.Warn("Failed to stop workspace preview", "workspace", name, "error", err)
+		}
+	}
+}
+
+// CleanupOrphans stops the previews left running by a previous hugo-manager that exited without
+// stopping them
+func (m *Manager) CleanupOrphans() {
+	list, err := m.List()
+	if err != nil {
+		return
+	}
+	for _, ws := range list {
+		if pid, err := hugo.NewManager(m.siteDir(ws.Name), m.hugoConfig).CleanupOrphan(); err != nil {
+			slog.Warn("Failed to stop orphaned workspace preview", "workspace", ws.Name, "error", err)
+		} else if pid != 0 {
+			slog.Info("Stopped orphaned workspace preview", "workspace", ws.Name, "pid", pid)
+		}
+	}
+}
+
+// withPreview adds the state of its Hugo server to a workspace
+func (m *Manager) withPreview(ws Workspace) Workspace {
+	m.mu.Lock()
+	preview := m.previews[ws.Name]
+	m.mu.Unlock()
+	if preview != nil {
+		status, msg := preview.GetStatus()
+		ws.Preview = &Preview{Status: status, Message: msg}
+		if status == hugo.StatusRunning || status == hugo.StatusStarting {
+			ws.Preview.Port = preview.GetPort()
+		}
+	}
+	return ws
+}
+
+// copySite copies the project into dst, recording the SHA-256 of every regular file in sums.
+// Links are copied as links.
+func (m *Manager) copySite(ctx context.Context, dst string, sums map[string]string) error {
+	return filepath.WalkDir(m.projectDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(mustRel(m.projectDir, p))
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		switch {
+		case d.IsDir():
+			if rel != "." && m.excluded(rel) {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !d.Type().IsRegular():
+			return nil
+		}
+		sum, err := copyFile(p, target)
+		if err != nil {
+			return err
+		}
+		sums[rel] = sum
+		return nil
+	})
+}
+
+// excluded reports whether a project-relative directory isn't copied into workspaces
+func (m *Manager) excluded(rel string) bool {
+	for _, dir := range append(append([]string{}, skipped...), m.config.Exclude...) {
+		dir = strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// manifest reads the manifest of a workspace
+func (m *Manager) manifest(name string) (*manifest, error) {
+	if !nameRe.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	data, err := os.ReadFile(filepath.Join(m.dir, name, manifestFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var man manifest
+	if err := json.Unmarshal(data, &man); err != nil {
+		return nil, fmt.Errorf("failed to read workspace %s: %w", name, err)
+	}
+	return &man, nil
+}
+
+// saveManifest writes the manifest of a workspace
+func (m *Manager) saveManifest(man *manifest) error {
+	data, err := json.MarshalIndent(man, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(m.dir, man.Name, manifestFile), data, 0644)
+}
+
+// siteDir returns the copy of the site of a workspace
+func (m *Manager) siteDir(name string) string {
+	return filepath.Join(m.dir, name, siteDir)
+}
+
+// copyFile copies a file with its permissions, returning the hex SHA-256 of its content
+func copyFile(src, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fileMode(src))
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the hex SHA-256 of a file
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileMode returns the permissions of a file, 0644 when it can't be read
+func fileMode(p string) fs.FileMode {
+	if stat, err := os.Stat(p); err == nil {
+		return stat.Mode().Perm()
+	}
+	return 0644
+}
+
+// mustRel returns p relative to root, which it's known to be inside of
+func mustRel(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return p
+	}
+	return rel
+}
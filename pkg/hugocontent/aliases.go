@@ -0,0 +1,206 @@
+package hugocontent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+)
+
+// Errors returned by aliases
+var (
+	ErrInvalidAlias   = errors.New("invalid alias")
+	ErrAliasCollision = errors.New("alias collision")
+)
+
+// Reasons an alias collides
+const (
+	CollisionPage  = "page"  // A page is rendered at the alias, so one overwrites the other
+	CollisionAlias = "alias" // Several pages claim the alias, so only one of them gets the redirect
+	CollisionSelf  = "self"  // The alias is the page's own URL, so the redirect replaces the page
+)
+
+// Alias is a redirect Hugo renders for a page from its aliases front matter
+type Alias struct {
+	Alias    string `json:"alias"`  // As written in the front matter
+	URL      string `json:"url"`    // Where the redirect is served
+	Path     string `json:"path"`   // Page it redirects to
+	Target   string `json:"target"` // URL of the page
+	Language string `json:"language"`
+}
+
+// AliasCollision is a URL claimed by an alias and by something else
+type AliasCollision struct {
+	URL    string   `json:"url"`
+	Reason string   `json:"reason"`
+	Paths  []string `json:"paths"`          // Pages with the alias
+	Page   string   `json:"page,omitempty"` // Page rendered at the URL, for page and self collisions
+}
+
+// Redirects is every alias of the site, with the ones that collide
+type Redirects struct {
+	Aliases    []Alias          `json:"aliases"`
+	Collisions []AliasCollision `json:"collisions"`
+}
+
+// Redirects lists the aliases of every page, sorted by URL, and the URLs they collide on
+func (s *Site) Redirects() (*Redirects, error) {
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	redirects := &Redirects{Aliases: []Alias{}, Collisions: []AliasCollision{}}
+	for _, page := range pages {
+		for _, alias := range page.FrontMatter.Strings("aliases") {
+			if strings.TrimSpace(alias) != "" {
+				redirects.Aliases = append(redirects.Aliases, s.alias(page, alias))
+			}
+		}
+	}
+	sort.SliceStable(redirects.Aliases, func(i, j int) bool {
+		return redirects.Aliases[i].URL < redirects.Aliases[j].URL
+	})
+
+	urls := pageURLs(pages)
+	claims := map[string][]string{}
+	var order []string
+	for _, alias := range redirects.Aliases {
+		paths := claims[alias.URL]
+		if paths == nil {
+			order = append(order, alias.URL)
+		}
+		if !contains(paths, alias.Path) {
+			claims[alias.URL] = append(paths, alias.Path)
+		}
+	}
+	for _, u := range order {
+		paths := claims[u]
+		collision := AliasCollision{URL: u, Paths: paths}
+		if owner, ok := urls[u]; ok {
+			collision.Page = owner
+			collision.Reason = CollisionPage
+			if len(paths) == 1 && paths[0] == owner {
+				collision.Reason = CollisionSelf
+			}
+		} else if len(paths) > 1 {
+			collision.Reason = CollisionAlias
+		} else {
+			continue
+		}
+		redirects.Collisions = append(redirects.Collisions, collision)
+	}
+	return redirects, nil
+}
+
+// AddAlias adds an alias to a page, or to the index file of a bundle directory, keeping the rest
+// of the file as it is. An alias the page already has is left alone; one that is the URL or an
+// alias of another page is refused with ErrAliasCollision.
+func (s *Site) AddAlias(rel, alias string) (*Alias, error) {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || strings.Contains(alias, "://") || strings.ContainsAny(alias, "?# \t\n") {
+		return nil, fmt.Errorf("%w: %q is not a URL path", ErrInvalidAlias, alias)
+	}
+
+	rel = cleanPath(rel)
+	if stat, err := os.Stat(s.abs(rel)); err == nil && stat.IsDir() {
+		index, err := s.indexFile(rel)
+		if err != nil {
+			return nil, err
+		}
+		rel = index
+	}
+	page, err := s.Page(rel)
+	if err != nil {
+		return nil, err
+	}
+	added := s.alias(page, alias)
+	if added.URL == page.URL {
+		return nil, fmt.Errorf("%w: %s is the URL of %s itself", ErrInvalidAlias, added.URL, page.Path)
+	}
+
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+	if owner, ok := pageURLs(pages)[added.URL]; ok {
+		return nil, fmt.Errorf("%w: %s is the URL of %s", ErrAliasCollision, added.URL, owner)
+	}
+	for _, other := range pages {
+		for _, existing := range other.FrontMatter.Strings("aliases") {
+			if s.alias(other, existing).URL != added.URL {
+				continue
+			}
+			if other.Path != page.Path {
+				return nil, fmt.Errorf("%w: %s already redirects to %s", ErrAliasCollision, added.URL, other.Path)
+			}
+			return &added, nil
+		}
+	}
+
+	data, err := os.ReadFile(s.abs(page.Path))
+	if err != nil {
+		return nil, err
+	}
+	aliases := append(page.FrontMatter.Strings("aliases"), alias)
+	out, err := SetFields(data, map[string]interface{}{"aliases": aliases})
+	if err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(s.abs(page.Path), out, 0644); err != nil {
+		return nil, err
+	}
+	return &added, nil
+}
+
+// AliasFor returns the alias that redirects a URL of the site, as a page URL gives it, to
+// another page: the URL without the path of the baseURL, which Hugo adds to aliases itself
+func (s *Site) AliasFor(u string) string {
+	if base := basePath(s.config); base != "" && strings.HasPrefix(u, base+"/") {
+		return strings.TrimPrefix(u, base)
+	}
+	return u
+}
+
+// alias resolves where Hugo serves an alias of a page. Absolute aliases are relative to the site
+// root, under the baseURL path, and keep no language prefix but the one they're written with;
+// relative ones are relative to the page's parent, e.g. "old/" on /blog/post/ is /blog/old/.
+// Aliases without an extension are directories, served by their index.html.
+func (s *Site) alias(page *Page, alias string) Alias {
+	alias = strings.TrimSpace(alias)
+	var u string
+	if strings.HasPrefix(alias, "/") {
+		u = basePath(s.config) + path.Clean(alias)
+	} else {
+		u = path.Join(page.URL, "..", alias)
+	}
+	if path.Ext(u) == "" && !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return Alias{Alias: alias, URL: u, Path: page.Path, Target: page.URL, Language: page.Language}
+}
+
+// pageURLs maps the URL of every page to its path
+func pageURLs(pages []*Page) map[string]string {
+	urls := make(map[string]string, len(pages))
+	for _, page := range pages {
+		if _, ok := urls[page.URL]; !ok && page.URL != "" {
+			urls[page.URL] = page.Path
+		}
+	}
+	return urls
+}
+
+// contains reports whether a list has a string
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package hugocontent
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// ArchetypeFormat returns the front matter format of the archetype Hugo would use for a new content
+// file, given its project-relative path: archetypes/<section>.md, then archetypes/default.md. The
+// project follows its archetypes, so new files written by hand should too; without an archetype
+// it's YAML. As in Hugo, the archetypes of modules and themes fill in for those the project lacks.
+func ArchetypeFormat(projectDir, relPath string) string {
+	var candidates []string
+	rest, ok := strings.CutPrefix(path.Clean(filepath.ToSlash(relPath)), "content/")
+	if section, _, nested := strings.Cut(rest, "/"); ok && nested {
+		candidates = append(candidates, section+".md")
+	}
+	candidates = append(candidates, "default.md")
+
+	dirs := ArchetypeDirs(projectDir)
+	for _, name := range candidates {
+		for _, dir := range dirs {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			if _, _, format, _ := Parse(data); format != FormatNone {
+				return format
+			}
+		}
+	}
+	return FormatYAML
+}
+
+// ArchetypeDirs returns the archetype directories of a project in precedence order: its own, then
+// those of its module imports and themes that are installed
+func ArchetypeDirs(projectDir string) []string {
+	dirs := []string{filepath.Join(projectDir, "archetypes")}
+	siteCfg, err := site.Load(projectDir)
+	if err != nil {
+		return dirs
+	}
+	for _, mod := range siteCfg.ModuleImports() {
+		if dir := site.ModuleDir(projectDir, mod); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "archetypes"))
+		}
+	}
+	for _, theme := range siteCfg.Themes() {
+		themeDir := filepath.Join(projectDir, "themes", theme)
+		if stat, err := os.Stat(themeDir); err == nil && stat.IsDir() {
+			dirs = append(dirs, filepath.Join(themeDir, "archetypes"))
+		} else if dir := site.ModuleDir(projectDir, theme); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "archetypes"))
+		}
+	}
+	return dirs
+}
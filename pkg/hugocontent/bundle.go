@@ -0,0 +1,125 @@
+package hugocontent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotBundle is returned for a path that isn't a page bundle or its index file
+var ErrNotBundle = errors.New("not a page bundle")
+
+// Bundle kinds
+const (
+	BundleLeaf   = "leaf"   // index file: a page with its resources, including nested directories
+	BundleBranch = "branch" // _index file: a section with the files next to it
+)
+
+// Bundle is a page with the resources stored next to it
+type Bundle struct {
+	Dir       string   `json:"dir"` // Project-relative directory of the bundle
+	Kind      string   `json:"kind"`
+	Page      *Page    `json:"page"`
+	Resources []string `json:"resources"` // Project-relative paths of the bundle's other files
+}
+
+// Bundle reads the page bundle of a directory or of its index file. A directory with index files
+// in several languages gives the one without a language suffix, or the first otherwise.
+func (s *Site) Bundle(p string) (*Bundle, error) {
+	p = cleanPath(p)
+	stat, err := os.Stat(s.abs(p))
+	if err != nil {
+		return nil, err
+	}
+
+	file := p
+	if stat.IsDir() {
+		if file, err = s.indexFile(p); err != nil {
+			return nil, err
+		}
+	}
+	page, err := s.Page(file)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Dir: path.Dir(file), Page: page, Resources: []string{}}
+	switch page.location.Base {
+	case "index":
+		b.Kind = BundleLeaf
+	case "_index":
+		b.Kind = BundleBranch
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotBundle, p)
+	}
+
+	// Leaf bundles hold everything below them; in a branch bundle subdirectories are sections and
+	// content files are pages of the section
+	root := s.abs(b.Dir)
+	err = filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if full == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || (d.IsDir() && b.Kind == BundleBranch) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel := path.Join(b.Dir, filepath.ToSlash(strings.TrimPrefix(full, root+string(filepath.Separator))))
+		if isContentFile(rel) && (b.Kind == BundleBranch || s.isIndex(rel, "index")) {
+			return nil
+		}
+		b.Resources = append(b.Resources, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(b.Resources)
+	return b, nil
+}
+
+// indexFile returns the index file of a bundle directory, leaf before branch
+func (s *Site) indexFile(dir string) (string, error) {
+	entries, err := os.ReadDir(s.abs(dir))
+	if err != nil {
+		return "", err
+	}
+	for _, base := range []string{"index", "_index"} {
+		found := ""
+		for _, entry := range entries {
+			rel := path.Join(dir, entry.Name())
+			if entry.IsDir() || !isContentFile(rel) || !s.isIndex(rel, base) {
+				continue
+			}
+			if strings.Count(entry.Name(), ".") == 1 {
+				return rel, nil // Without a language suffix
+			}
+			if found == "" {
+				found = rel
+			}
+		}
+		if found != "" {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotBundle, dir)
+}
+
+// isIndex reports whether a content file is an index file of the given base, in any language
+func (s *Site) isIndex(rel, base string) bool {
+	loc, err := Locate(s.config, rel)
+	return err == nil && loc.Base == base
+}
@@ -0,0 +1,213 @@
+package hugocontent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter formats
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+	FormatNone = ""
+)
+
+// FrontMatter holds the parsed front matter of a content file
+type FrontMatter map[string]interface{}
+
+// Parse splits a content file into its front matter and body
+func Parse(data []byte) (FrontMatter, string, string, error) {
+	text := string(bytes.TrimPrefix(data, []byte("\ufeff")))
+
+	switch {
+	case strings.HasPrefix(text, "---"):
+		raw, body, ok := splitDelimited(text, "---")
+		if !ok {
+			return FrontMatter{}, text, FormatNone, nil
+		}
+		fm := FrontMatter{}
+		if err := yaml.Unmarshal([]byte(raw), &fm); err != nil {
+			return nil, body, FormatYAML, fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+		return fm, body, FormatYAML, nil
+
+	case strings.HasPrefix(text, "+++"):
+		raw, body, ok := splitDelimited(text, "+++")
+		if !ok {
+			return FrontMatter{}, text, FormatNone, nil
+		}
+		fm := FrontMatter{}
+		if _, err := toml.Decode(raw, (*map[string]interface{})(&fm)); err != nil {
+			return nil, body, FormatTOML, fmt.Errorf("invalid TOML front matter: %w", err)
+		}
+		return fm, body, FormatTOML, nil
+
+	case strings.HasPrefix(text, "{"):
+		dec := json.NewDecoder(strings.NewReader(text))
+		fm := FrontMatter{}
+		if err := dec.Decode(&fm); err != nil {
+			return nil, text, FormatJSON, fmt.Errorf("invalid JSON front matter: %w", err)
+		}
+		body := strings.TrimLeft(text[dec.InputOffset():], "\r\n")
+		return fm, body, FormatJSON, nil
+	}
+
+	return FrontMatter{}, text, FormatNone, nil
+}
+
+// splitDelimited splits text that starts with a delimiter line into front matter and body
+func splitDelimited(text, delim string) (string, string, bool) {
+	rest := strings.TrimPrefix(text, delim)
+	nl := strings.IndexByte(rest, '\n')
+	if nl < 0 || strings.TrimSpace(rest[:nl]) != "" {
+		return "", "", false
+	}
+	rest = rest[nl+1:]
+
+	offset := 0
+	for {
+		end := strings.Index(rest[offset:], delim)
+		if end < 0 {
+			return "", "", false
+		}
+		end += offset
+		// The closing delimiter must be on its own line
+		if (end == 0 || rest[end-1] == '\n') && lineIsDelimiter(rest[end:], delim) {
+			body := rest[end+len(delim):]
+			body = strings.TrimPrefix(strings.TrimPrefix(body, "\r"), "\n")
+			return rest[:end], body, true
+		}
+		offset = end + len(delim)
+	}
+}
+
+func lineIsDelimiter(s, delim string) bool {
+	line := s
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		line = s[:nl]
+	}
+	return strings.TrimSpace(line) == delim
+}
+
+// String returns the string value of key, or "" if missing
+func (fm FrontMatter) String(key string) string {
+	switch v := fm.lookup(key).(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Bool returns the boolean value of key
+func (fm FrontMatter) Bool(key string) bool {
+	switch v := fm.lookup(key).(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// Time returns the time value of key, parsing strings in the formats Hugo accepts
+func (fm FrontMatter) Time(key string) (time.Time, bool) {
+	switch v := fm.lookup(key).(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return ParseDate(v)
+	}
+	return time.Time{}, false
+}
+
+// Strings returns a list value of key as strings
+func (fm FrontMatter) Strings(key string) []string {
+	switch v := fm.lookup(key).(type) {
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	case []string:
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	}
+	return nil
+}
+
+// Get returns the raw value of key, or nil if missing
+func (fm FrontMatter) Get(key string) interface{} {
+	return fm.lookup(key)
+}
+
+// lookup finds a key case-insensitively, as Hugo does for front matter
+func (fm FrontMatter) lookup(key string) interface{} {
+	if v, ok := fm[key]; ok {
+		return v
+	}
+	for k, v := range fm {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// dateLayouts are the date formats accepted in front matter
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate parses a front matter date string
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Date is a calendar date front matter value. It's written as a date, unquoted and without a time,
+// in YAML and TOML; JSON has no dates, so it's a string there.
+type Date time.Time
+
+// String returns the date as YYYY-MM-DD
+func (d Date) String() string {
+	return time.Time(d).Format("2006-01-02")
+}
+
+// MarshalYAML writes the date as a YAML timestamp
+func (d Date) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: d.String()}, nil
+}
+
+// MarshalTOML writes the date as a TOML local date
+func (d Date) MarshalTOML() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// MarshalJSON writes the date as a string
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
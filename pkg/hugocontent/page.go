@@ -0,0 +1,94 @@
+package hugocontent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Page is a content file with its front matter and body
+type Page struct {
+	Path        string      `json:"path"` // Project-relative path of the file
+	Language    string      `json:"language"`
+	Kind        string      `json:"kind"` // home, section or page
+	Section     string      `json:"section"`
+	URL         string      `json:"url"`    // URL path Hugo renders the page at
+	Format      string      `json:"format"` // Front matter format, kept when the page is saved
+	FrontMatter FrontMatter `json:"frontMatter"`
+	Body        string      `json:"body"`
+
+	location *Location
+}
+
+// Title returns the title of the page
+func (p *Page) Title() string {
+	return p.FrontMatter.String("title")
+}
+
+// Date returns the date of the page
+func (p *Page) Date() (time.Time, bool) {
+	return p.FrontMatter.Time("date")
+}
+
+// Draft reports whether the page is a draft
+func (p *Page) Draft() bool {
+	return p.FrontMatter.Bool("draft")
+}
+
+// Location returns where the page lives in its language's content directory, or nil for a page
+// that wasn't read or saved through a Site
+func (p *Page) Location() *Location {
+	return p.location
+}
+
+// Bytes encodes the page as a content file
+func (p *Page) Bytes() ([]byte, error) {
+	return Encode(p.FrontMatter, p.Body, p.Format)
+}
+
+// Encode writes front matter in a format followed by the body. Keys are written sorted; use
+// SetFields to change some keys of an existing file while keeping its layout. Without a format the
+// front matter is written as YAML, or left out when it's empty.
+func Encode(fm FrontMatter, body, format string) ([]byte, error) {
+	switch format {
+	case FormatNone:
+		if len(fm) == 0 {
+			return []byte(body), nil
+		}
+		fallthrough
+
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(map[string]interface{}(fm)); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML front matter: %w", err)
+		}
+		enc.Close()
+		out := buf.String()
+		if len(fm) == 0 {
+			out = ""
+		}
+		return []byte("---\n" + out + "---\n" + body), nil
+
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(map[string]interface{}(fm)); err != nil {
+			return nil, fmt.Errorf("failed to encode TOML front matter: %w", err)
+		}
+		return []byte("+++\n" + buf.String() + "+++\n" + body), nil
+
+	case FormatJSON:
+		out, err := json.MarshalIndent(fm, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON front matter: %w", err)
+		}
+		return []byte(string(out) + "\n\n" + body), nil
+	}
+
+	return nil, fmt.Errorf("unsupported front matter format %q", format)
+}
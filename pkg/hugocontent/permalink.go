@@ -0,0 +1,273 @@
+package hugocontent
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// ErrNotContent is returned for a path outside the site's content directories
+var ErrNotContent = errors.New("not inside a content directory")
+
+// Page kinds
+const (
+	KindHome    = "home"
+	KindSection = "section"
+	KindPage    = "page"
+)
+
+// Permalink describes the rendered URL of a content file
+type Permalink struct {
+	Path     string `json:"path"`
+	URL      string `json:"url"`
+	Kind     string `json:"kind"`
+	Section  string `json:"section"`
+	Language string `json:"language"`
+}
+
+// Location describes where a content file lives relative to its language's content directory
+type Location struct {
+	Language string // Language code
+	Rel      string // Path relative to the language content directory, e.g. "blog/post.md"
+	Dir      string // Directory of Rel ("" for the content root)
+	Base     string // Filename without extension and language suffix
+	Ext      string // File extension including the dot
+}
+
+// Locate resolves the language and content-relative path of a project-relative content file
+func Locate(siteCfg *site.Config, relPath string) (*Location, error) {
+	relPath = filepath.ToSlash(path.Clean(relPath))
+	langs := siteCfg.Languages()
+
+	loc := &Location{Language: siteCfg.DefaultLanguage()}
+
+	// Pick the language whose content directory is the longest matching prefix
+	best := ""
+	for _, lang := range langs {
+		prefix := lang.ContentDir + "/"
+		if strings.HasPrefix(relPath, prefix) && len(prefix) > len(best) {
+			best = prefix
+			loc.Language = lang.Code
+		}
+	}
+	if best == "" {
+		return nil, fmt.Errorf("%s is %w", relPath, ErrNotContent)
+	}
+	// Languages sharing the site content directory are told apart by filename suffix
+	if best == siteCfg.ContentDir()+"/" {
+		loc.Language = siteCfg.DefaultLanguage()
+	}
+	loc.Rel = strings.TrimPrefix(relPath, best)
+
+	loc.Ext = path.Ext(loc.Rel)
+	loc.Base = strings.TrimSuffix(path.Base(loc.Rel), loc.Ext)
+	loc.Dir = path.Dir(loc.Rel)
+	if loc.Dir == "." {
+		loc.Dir = ""
+	}
+
+	// Filename language suffix, e.g. post.es.md
+	if i := strings.LastIndex(loc.Base, "."); i > 0 {
+		suffix := strings.ToLower(loc.Base[i+1:])
+		for _, lang := range langs {
+			if lang.Code == suffix {
+				loc.Language = suffix
+				loc.Base = loc.Base[:i]
+				break
+			}
+		}
+	}
+
+	return loc, nil
+}
+
+// ComputePermalink computes the URL path Hugo renders a content file at
+func ComputePermalink(projectDir string, siteCfg *site.Config, relPath string) (*Permalink, error) {
+	loc, err := Locate(siteCfg, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return nil, err
+	}
+	fm, _, _, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return PermalinkFor(siteCfg, loc, fm, filepath.ToSlash(relPath)), nil
+}
+
+// PermalinkFor computes the URL of a located content file with the given front matter
+func PermalinkFor(siteCfg *site.Config, loc *Location, fm FrontMatter, relPath string) *Permalink {
+	result := &Permalink{
+		Path:     relPath,
+		Kind:     KindPage,
+		Language: loc.Language,
+	}
+
+	dir := loc.Dir
+	switch loc.Base {
+	case "_index":
+		result.Kind = KindSection
+		if dir == "" {
+			result.Kind = KindHome
+		}
+	case "index":
+		// Leaf bundle: the page is named after its directory
+	}
+	if dir != "" {
+		result.Section = strings.SplitN(dir, "/", 2)[0]
+	}
+
+	var u string
+	if custom := fm.String("url"); custom != "" {
+		u = custom
+		if !strings.HasPrefix(u, "/") {
+			u = "/" + u
+		}
+		return finalize(siteCfg, result, u, false)
+	}
+
+	switch result.Kind {
+	case KindHome:
+		u = "/"
+	case KindSection:
+		if pattern, ok := siteCfg.Permalinks("section")[result.Section]; ok && !strings.Contains(dir, "/") {
+			u = expandPattern(pattern, dir, loc, fm)
+		} else {
+			u = "/" + dir + "/"
+		}
+	default:
+		pageDir := dir
+		contentBase := loc.Base
+		if loc.Base == "index" {
+			contentBase = path.Base(dir)
+			pageDir = path.Dir(dir)
+			if pageDir == "." {
+				pageDir = ""
+			}
+		}
+
+		if pattern, ok := siteCfg.Permalinks("page")[result.Section]; ok && result.Section != "" {
+			u = expandPattern(pattern, pageDir, &Location{Dir: pageDir, Base: contentBase}, fm)
+		} else {
+			name := fm.String("slug")
+			if name == "" {
+				name = contentBase
+			}
+			u = "/" + joinURL(pageDir, urlize(name)) + "/"
+			if siteCfg.Bool("uglyURLs") {
+				u = strings.TrimSuffix(u, "/") + ".html"
+			}
+		}
+	}
+
+	return finalize(siteCfg, result, u, true)
+}
+
+// finalize applies path lowercasing, language prefix and the baseURL path
+func finalize(siteCfg *site.Config, result *Permalink, u string, lower bool) *Permalink {
+	if lower && !siteCfg.Bool("disablePathToLower") {
+		u = strings.ToLower(u)
+	}
+	u = strings.ReplaceAll(u, "//", "/")
+
+	if siteCfg.IsMultilingual() && (result.Language != siteCfg.DefaultLanguage() || siteCfg.Bool("defaultContentLanguageInSubdir")) {
+		u = "/" + result.Language + u
+	}
+
+	result.URL = basePath(siteCfg) + u
+	return result
+}
+
+// basePath returns the path of the site's baseURL without its trailing slash, "" at the root
+func basePath(siteCfg *site.Config) string {
+	base, err := url.Parse(siteCfg.BaseURL())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(base.Path, "/")
+}
+
+// expandPattern expands a Hugo permalink pattern such as "/:year/:month/:slug/"
+func expandPattern(pattern, dir string, loc *Location, fm FrontMatter) string {
+	date, ok := fm.Time("date")
+	if !ok {
+		date, _ = fm.Time("publishDate")
+	}
+
+	section := ""
+	if dir != "" {
+		section = strings.SplitN(dir, "/", 2)[0]
+	}
+
+	title := urlize(fm.String("title"))
+	slug := urlize(fm.String("slug"))
+	filename := urlize(loc.Base)
+
+	slugOr := func(fallback string) string {
+		if slug != "" {
+			return slug
+		}
+		return fallback
+	}
+
+	replacements := []struct{ token, value string }{
+		// Longer tokens first so :slugorfilename isn't consumed by :slug
+		{":slugorcontentbasename", slugOr(filename)},
+		{":slugorfilename", slugOr(filename)},
+		{":contentbasename", filename},
+		{":filename", filename},
+		{":slug", slugOr(title)},
+		{":title", title},
+		{":sections", dir},
+		{":section", section},
+		{":yearday", fmt.Sprintf("%d", date.YearDay())},
+		{":year", date.Format("2006")},
+		{":monthname", strings.ToLower(date.Month().String())},
+		{":month", date.Format("01")},
+		{":weekdayname", strings.ToLower(date.Weekday().String())},
+		{":weekday", fmt.Sprintf("%d", date.Weekday())},
+		{":day", date.Format("02")},
+	}
+
+	u := pattern
+	for _, r := range replacements {
+		u = strings.ReplaceAll(u, r.token, r.value)
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return u
+}
+
+func joinURL(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// urlize converts a string into a URL path segment the way Hugo does
+func urlize(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case unicode.IsSpace(r):
+			sb.WriteRune('-')
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' || r == '/':
+			sb.WriteRune(r)
+		}
+	}
+	return strings.ToLower(sb.String())
+}
@@ -0,0 +1,96 @@
+package hugocontent
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Section is a directory of content with its index page, pages and nested sections
+type Section struct {
+	Path     string     `json:"path"`            // Content-relative directory, e.g. blog/2024
+	Index    *Page      `json:"index,omitempty"` // The _index file, nil when the section has none
+	Pages    []*Page    `json:"pages"`
+	Sections []*Section `json:"sections"`
+}
+
+// Sections returns the section tree of a language ("" for the default one). As in Hugo, every
+// top-level directory is a section, while nested directories are sections only with an _index file;
+// the pages of other directories belong to the closest section above them.
+func (s *Site) Sections(lang string) ([]*Section, error) {
+	lang = s.language(lang)
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := map[string]*Section{}
+	section := func(dir string) *Section {
+		if sections[dir] == nil {
+			sections[dir] = &Section{Path: dir, Pages: []*Page{}, Sections: []*Section{}}
+		}
+		return sections[dir]
+	}
+
+	var regular []*Page
+	for _, page := range pages {
+		if page.Language != lang {
+			continue
+		}
+		dir := page.location.Dir
+		switch {
+		case page.Kind == KindHome:
+			continue
+		case page.location.Base == "_index":
+			section(dir).Index = page
+			section(strings.SplitN(dir, "/", 2)[0])
+		default:
+			regular = append(regular, page)
+			if page.location.Base == "index" {
+				dir = path.Dir(dir) // A leaf bundle is a page of the directory holding it
+			}
+			if dir != "." && dir != "" {
+				section(strings.SplitN(dir, "/", 2)[0])
+			}
+		}
+	}
+
+	for _, page := range regular {
+		dir := page.location.Dir
+		if page.location.Base == "index" {
+			dir = path.Dir(dir)
+		}
+		if parent := closest(sections, dir); parent != nil {
+			parent.Pages = append(parent.Pages, page)
+		}
+	}
+
+	dirs := make([]string, 0, len(sections))
+	for dir := range sections {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	roots := []*Section{}
+	for _, dir := range dirs {
+		if !strings.Contains(dir, "/") {
+			roots = append(roots, sections[dir])
+			continue
+		}
+		if parent := closest(sections, path.Dir(dir)); parent != nil {
+			parent.Sections = append(parent.Sections, sections[dir])
+		}
+	}
+	return roots, nil
+}
+
+// closest returns the section of a directory or of its closest ancestor
+func closest(sections map[string]*Section, dir string) *Section {
+	for dir != "." && dir != "" && dir != "/" {
+		if s, ok := sections[dir]; ok {
+			return s
+		}
+		dir = path.Dir(dir)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package hugocontent reads and writes the content of a Hugo site: pages with their front matter,
+// page bundles, sections and taxonomies, resolved the way Hugo and hugo-manager resolve them.
+package hugocontent
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+	"github.com/fernandezvara/hugo-manager/internal/site"
+)
+
+// contentExts are the file types Hugo renders as pages
+var contentExts = map[string]bool{".md": true, ".markdown": true, ".html": true}
+
+// Site is the content of a Hugo project
+type Site struct {
+	dir    string
+	config *site.Config
+}
+
+// Open reads the Hugo configuration of a project, which locates its content directories and languages
+func Open(projectDir string) (*Site, error) {
+	cfg, err := site.Load(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Site{dir: projectDir, config: cfg}, nil
+}
+
+// Dir returns the project directory
+func (s *Site) Dir() string {
+	return s.dir
+}
+
+// DefaultLanguage returns the code of the site's default content language
+func (s *Site) DefaultLanguage() string {
+	return s.config.DefaultLanguage()
+}
+
+// Pages returns the pages of every language, sorted by path. Files with invalid front matter are
+// left out, as Hugo can't build them either, and so are content files inside a leaf bundle, which
+// are resources of its page.
+func (s *Site) Pages() ([]*Page, error) {
+	var found []*Page
+	leaves := map[string]bool{}
+	err := s.walk(func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !isContentFile(rel) {
+			return nil
+		}
+		page, err := s.Page(rel)
+		if err != nil {
+			return nil
+		}
+		if page.location.Base == "index" {
+			leaves[path.Dir(rel)] = true
+		}
+		found = append(found, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pages := found[:0]
+	for _, page := range found {
+		if page.location.Base == "index" || !inLeaf(leaves, path.Dir(page.Path)) {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Path < pages[j].Path })
+	return pages, nil
+}
+
+// inLeaf reports whether a directory is a leaf bundle or inside one
+func inLeaf(leaves map[string]bool, dir string) bool {
+	for ; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if leaves[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// Page reads a content file by its project-relative path
+func (s *Site) Page(rel string) (*Page, error) {
+	rel = cleanPath(rel)
+	loc, err := Locate(s.config, rel)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.abs(rel))
+	if err != nil {
+		return nil, err
+	}
+	fm, body, format, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rel, err)
+	}
+
+	page := &Page{Path: rel, Format: format, FrontMatter: fm, Body: body}
+	s.locate(page, loc)
+	return page, nil
+}
+
+// Save writes a page to its path, creating the directories it needs, and refreshes the fields
+// derived from its path and front matter
+func (s *Site) Save(page *Page) error {
+	page.Path = cleanPath(page.Path)
+	loc, err := Locate(s.config, page.Path)
+	if err != nil {
+		return err
+	}
+	if page.FrontMatter == nil {
+		page.FrontMatter = FrontMatter{}
+	}
+	data, err := page.Bytes()
+	if err != nil {
+		return err
+	}
+
+	full := s.abs(page.Path)
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(full); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(full, data, mode); err != nil {
+		return err
+	}
+	s.locate(page, loc)
+	return nil
+}
+
+// locate sets the fields of a page derived from its location and front matter
+func (s *Site) locate(page *Page, loc *Location) {
+	permalink := PermalinkFor(s.config, loc, page.FrontMatter, page.Path)
+	page.Language = loc.Language
+	page.Kind = permalink.Kind
+	page.Section = permalink.Section
+	page.URL = permalink.URL
+	page.location = loc
+}
+
+// walk calls fn with the project-relative path of every visible file and directory in the content
+// directories
+func (s *Site) walk(fn func(rel string, d fs.DirEntry) error) error {
+	seen := map[string]bool{}
+	for _, lang := range s.config.Languages() {
+		root := s.abs(lang.ContentDir)
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && p == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if strings.HasPrefix(d.Name(), ".") && p != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(s.dir, p)
+			if err != nil {
+				return err
+			}
+			return fn(filepath.ToSlash(rel), d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// language returns the language code to use, the default one for ""
+func (s *Site) language(lang string) string {
+	if lang == "" {
+		return s.config.DefaultLanguage()
+	}
+	return strings.ToLower(lang)
+}
+
+// abs returns the absolute path of a project-relative path
+func (s *Site) abs(rel string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(rel))
+}
+
+// cleanPath normalizes a project-relative path, so it can't point outside the project
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// isContentFile reports whether a file is rendered as a page
+func isContentFile(name string) bool {
+	return contentExts[strings.ToLower(path.Ext(name))]
+}
@@ -0,0 +1,116 @@
+package hugocontent
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// wordsPerMinute is the reading speed Hugo assumes for .ReadingTime
+const wordsPerMinute = 213
+
+var (
+	statsHeadingRe   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	statsHeadingIDRe = regexp.MustCompile(`[ \t]*\{[^}]*\}$`)
+	statsFenceRe     = regexp.MustCompile("^ {0,3}(```|~~~)")
+	statsShortcodeRe = regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`)
+	statsTagRe       = regexp.MustCompile(`<[^>]*>`)
+	statsLinkURLRe   = regexp.MustCompile(`\]\([^)]*\)`)
+)
+
+// Stats are figures about a page's body, with a summary of its front matter, for showing a
+// document's stats without the body
+type Stats struct {
+	Words       int                 `json:"words"`
+	ReadingTime int                 `json:"readingTime"` // Minutes, worked out as Hugo's .ReadingTime
+	Headings    []Heading           `json:"headings"`
+	FrontMatter *FrontMatterSummary `json:"frontMatter,omitempty"` // Nil when the front matter can't be parsed
+}
+
+// Heading is an ATX heading of a page's body
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	Line  int    `json:"line"` // In the file, counting the front matter
+}
+
+// FrontMatterSummary is what's most often looked for in a page's front matter
+type FrontMatterSummary struct {
+	Format      string     `json:"format"` // Empty when the page has no front matter
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Date        *time.Time `json:"date,omitempty"`
+	Draft       bool       `json:"draft"`
+	Tags        []string   `json:"tags,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	Fields      int        `json:"fields"` // Number of top-level keys
+}
+
+// PageStats counts the words of a page's body, estimates its reading time and lists its headings.
+// Words are counted in the text as written, leaving out shortcode calls, HTML tags, link targets
+// and markup, so the count is close to the one Hugo makes from the rendered page.
+func PageStats(data []byte) *Stats {
+	stats := &Stats{Headings: []Heading{}}
+	body := string(data)
+	fm, parsedBody, format, err := Parse(data)
+	if err == nil {
+		body = parsedBody
+		stats.FrontMatter = &FrontMatterSummary{
+			Format:      format,
+			Title:       fm.String("title"),
+			Description: fm.String("description"),
+			Draft:       fm.Bool("draft"),
+			Tags:        fm.Strings("tags"),
+			Categories:  fm.Strings("categories"),
+			Fields:      len(fm),
+		}
+		if date, ok := fm.Time("date"); ok {
+			stats.FrontMatter.Date = &date
+		}
+	}
+	offset := 0
+	if strings.HasSuffix(string(data), body) {
+		offset = strings.Count(string(data[:len(data)-len(body)]), "\n")
+	}
+
+	fence := ""
+	for i, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := statsFenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+				continue
+			case fence == m[1]:
+				fence = ""
+				continue
+			}
+		}
+		if fence == "" {
+			if m := statsHeadingRe.FindStringSubmatch(line); m != nil {
+				text := statsHeadingIDRe.ReplaceAllString(strings.TrimSpace(m[2]), "")
+				stats.Headings = append(stats.Headings, Heading{Level: len(m[1]), Text: text, Line: offset + i + 1})
+				stats.Words += countWords(text)
+				continue
+			}
+		}
+		stats.Words += countWords(line)
+	}
+	stats.ReadingTime = (stats.Words + wordsPerMinute - 1) / wordsPerMinute
+	return stats
+}
+
+// countWords counts the words of a line of markdown, those with a letter or digit
+func countWords(line string) int {
+	line = statsShortcodeRe.ReplaceAllString(line, " ")
+	line = statsTagRe.ReplaceAllString(line, " ")
+	line = statsLinkURLRe.ReplaceAllString(line, " ")
+	n := 0
+	for _, field := range strings.Fields(line) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package hugocontent
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultTaxonomies are those of a site that doesn't configure any, singular to plural
+var defaultTaxonomies = map[string]string{"tag": "tags", "category": "categories"}
+
+// Taxonomy is a classification of pages, such as tags, with the pages of each term
+type Taxonomy struct {
+	Name     string              `json:"name"`     // Plural, the front matter key, e.g. tags
+	Singular string              `json:"singular"` // e.g. tag
+	Terms    map[string][]string `json:"terms"`    // Lowercased term to the paths of its pages, sorted
+}
+
+// Taxonomies returns the configured taxonomies of a language ("" for the default one), sorted by
+// name. Terms are matched regardless of case, as Hugo does.
+func (s *Site) Taxonomies(lang string) ([]Taxonomy, error) {
+	lang = s.language(lang)
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	configured := defaultTaxonomies
+	if s.config.Get("taxonomies") != nil {
+		configured = map[string]string{} // An empty map disables taxonomies
+		for singular, plural := range s.config.Map("taxonomies") {
+			if name, ok := plural.(string); ok && name != "" {
+				configured[singular] = name
+			}
+		}
+	}
+
+	taxonomies := make([]Taxonomy, 0, len(configured))
+	for singular, plural := range configured {
+		t := Taxonomy{Name: plural, Singular: singular, Terms: map[string][]string{}}
+		for _, page := range pages {
+			if page.Language != lang {
+				continue
+			}
+			seen := map[string]bool{}
+			for _, term := range page.FrontMatter.Strings(plural) {
+				term = strings.ToLower(strings.TrimSpace(term))
+				if term == "" || seen[term] {
+					continue
+				}
+				seen[term] = true
+				t.Terms[term] = append(t.Terms[term], page.Path)
+			}
+		}
+		taxonomies = append(taxonomies, t)
+	}
+	sort.Slice(taxonomies, func(i, j int) bool { return taxonomies[i].Name < taxonomies[j].Name })
+	return taxonomies, nil
+}
@@ -0,0 +1,254 @@
+package hugocontent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fernandezvara/hugo-manager/internal/atomicfile"
+)
+
+// Errors returned by translations
+var (
+	ErrUnknownLanguage   = errors.New("unknown language")
+	ErrTranslationExists = errors.New("translation already exists")
+)
+
+// Language is a language of the site and where its content lives
+type Language struct {
+	Code       string `json:"code"`
+	Name       string `json:"name,omitempty"`
+	Weight     int    `json:"weight"`
+	ContentDir string `json:"contentDir"`
+	Default    bool   `json:"default"`
+	Suffix     bool   `json:"suffix"` // Shares the site's content directory, so its pages have a filename suffix, e.g. post.es.md
+}
+
+// Translation is a page in one language: the existing file, or where a new translation goes
+type Translation struct {
+	Language string `json:"language"`
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Title    string `json:"title,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Draft    bool   `json:"draft,omitempty"`
+}
+
+// SectionCoverage is how much of a section is translated into each language
+type SectionCoverage struct {
+	Section   string             `json:"section"` // Top-level section, "" for pages at the content root
+	Pages     int                `json:"pages"`   // A page and its translations count once
+	Languages []LanguageCoverage `json:"languages"`
+}
+
+// LanguageCoverage is the share of a section's pages that exist in a language
+type LanguageCoverage struct {
+	Language   string   `json:"language"`
+	Translated int      `json:"translated"`
+	Percent    int      `json:"percent"`
+	Missing    []string `json:"missing"` // A version of each missing page, the default language's when it has one
+}
+
+// Languages returns the languages of the site ordered by weight. A site without a languages
+// section has its default language only.
+func (s *Site) Languages() []Language {
+	langs := s.config.Languages()
+	result := make([]Language, 0, len(langs))
+	for _, lang := range langs {
+		result = append(result, Language{
+			Code:       lang.Code,
+			Name:       lang.Name,
+			Weight:     lang.Weight,
+			ContentDir: lang.ContentDir,
+			Default:    lang.Default,
+			Suffix:     len(langs) > 1 && !lang.Default && lang.ContentDir == s.config.ContentDir(),
+		})
+	}
+	return result
+}
+
+// Translations returns a page in every language of the site, in language order. Pages are
+// translations of each other when they share a translationKey or, without one, their path in the
+// content directory of their language.
+func (s *Site) Translations(rel string) ([]Translation, error) {
+	page, err := s.Page(rel)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.translations(page)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Translation{}
+	for _, lang := range s.Languages() {
+		t := Translation{Language: lang.Code}
+		if p, ok := existing[lang.Code]; ok {
+			t.Path, t.Exists, t.Title, t.URL, t.Draft = p.Path, true, p.Title(), p.URL, p.Draft()
+		} else {
+			t.Path = translationPath(page.location, lang)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// Translate creates the missing translation of a page into a language, with the front matter and
+// body of the page to translate from. It's created as a draft, so it isn't published before it's
+// translated.
+func (s *Site) Translate(rel, lang string) (*Page, error) {
+	source, err := s.Page(rel)
+	if err != nil {
+		return nil, err
+	}
+	var target *Language
+	for _, l := range s.Languages() {
+		if l.Code == strings.ToLower(lang) {
+			target = &l
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLanguage, lang)
+	}
+
+	existing, err := s.translations(source)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := existing[target.Code]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrTranslationExists, p.Path)
+	}
+	dest := translationPath(source.location, *target)
+	if _, err := os.Stat(s.abs(dest)); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTranslationExists, dest)
+	}
+
+	// The source file is copied as is, so front matter keeps its layout and comments
+	data, err := os.ReadFile(s.abs(source.Path))
+	if err != nil {
+		return nil, err
+	}
+	data, err = SetFields(data, map[string]interface{}{"draft": true})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.abs(dest)), 0755); err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(s.abs(dest), data, 0644); err != nil {
+		return nil, err
+	}
+	return s.Page(dest)
+}
+
+// Coverage returns, for every top-level section, how many of its pages exist in each language
+func (s *Site) Coverage() ([]SectionCoverage, error) {
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+	langs := s.Languages()
+
+	// Versions of each page by language, in the section of the page to translate from
+	versions := map[string]map[string]*Page{}
+	for _, page := range pages {
+		key := translationKey(page)
+		if versions[key] == nil {
+			versions[key] = map[string]*Page{}
+		}
+		versions[key][page.Language] = page
+	}
+	sections := map[string]map[string]map[string]*Page{}
+	for key, byLang := range versions {
+		section := sourcePage(byLang, langs).Section
+		if sections[section] == nil {
+			sections[section] = map[string]map[string]*Page{}
+		}
+		sections[section][key] = byLang
+	}
+
+	result := []SectionCoverage{}
+	for name, group := range sections {
+		keys := make([]string, 0, len(group))
+		for key := range group {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		c := SectionCoverage{Section: name, Pages: len(group), Languages: []LanguageCoverage{}}
+		for _, lang := range langs {
+			lc := LanguageCoverage{Language: lang.Code, Missing: []string{}}
+			for _, key := range keys {
+				byLang := group[key]
+				if _, ok := byLang[lang.Code]; ok {
+					lc.Translated++
+					continue
+				}
+				lc.Missing = append(lc.Missing, sourcePage(byLang, langs).Path)
+			}
+			lc.Percent = lc.Translated * 100 / c.Pages
+			c.Languages = append(c.Languages, lc)
+		}
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Section < result[j].Section })
+	return result, nil
+}
+
+// translations returns the existing versions of a page by language, the page included
+func (s *Site) translations(page *Page) (map[string]*Page, error) {
+	pages, err := s.Pages()
+	if err != nil {
+		return nil, err
+	}
+	key := translationKey(page)
+	result := map[string]*Page{}
+	for _, p := range pages {
+		if translationKey(p) != key {
+			continue
+		}
+		// A page in its own language wins over another file claiming the same key
+		if _, ok := result[p.Language]; !ok || p.Path == page.Path {
+			result[p.Language] = p
+		}
+	}
+	return result, nil
+}
+
+// translationKey identifies a page across languages
+func translationKey(page *Page) string {
+	if key := page.FrontMatter.String("translationKey"); key != "" {
+		return "key:" + key
+	}
+	return "path:" + path.Join(page.location.Dir, page.location.Base)
+}
+
+// translationPath returns where the translation of a page into a language goes
+func translationPath(loc *Location, lang Language) string {
+	name := loc.Base + loc.Ext
+	if lang.Suffix {
+		name = loc.Base + "." + lang.Code + loc.Ext
+	}
+	return path.Join(lang.ContentDir, loc.Dir, name)
+}
+
+// sourcePage returns the version of a page to translate from: the first language's that exists,
+// the default language being first
+func sourcePage(versions map[string]*Page, langs []Language) *Page {
+	for _, lang := range langs {
+		if lang.Default && versions[lang.Code] != nil {
+			return versions[lang.Code]
+		}
+	}
+	for _, lang := range langs {
+		if p := versions[lang.Code]; p != nil {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package hugocontent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// SetFields sets front matter keys of a content file, keeping its format and body.
+// YAML front matter keeps its key order and comments; files without front matter get a YAML block.
+func SetFields(data []byte, fields map[string]interface{}) ([]byte, error) {
+	fm, body, format, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case FormatYAML, FormatNone:
+		raw := ""
+		if format == FormatYAML {
+			raw, _, _ = splitDelimited(strings.TrimPrefix(string(data), "\ufeff"), "---")
+		}
+		out, err := setYAMLFields(raw, keys, fields)
+		if err != nil {
+			return nil, err
+		}
+		return []byte("---\n" + out + "---\n" + body), nil
+
+	case FormatTOML:
+		for _, k := range keys {
+			fm[existingKey(fm, k)] = fields[k]
+		}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(map[string]interface{}(fm)); err != nil {
+			return nil, fmt.Errorf("failed to encode TOML front matter: %w", err)
+		}
+		return []byte("+++\n" + buf.String() + "+++\n" + body), nil
+
+	case FormatJSON:
+		for _, k := range keys {
+			fm[existingKey(fm, k)] = fields[k]
+		}
+		out, err := json.MarshalIndent(fm, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON front matter: %w", err)
+		}
+		return []byte(string(out) + "\n\n" + body), nil
+	}
+
+	return nil, fmt.Errorf("unsupported front matter format %q", format)
+}
+
+// setYAMLFields edits a YAML mapping in place, replacing existing values and appending new keys
+func setYAMLFields(raw string, keys []string, fields map[string]interface{}) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &doc); err != nil {
+		return "", fmt.Errorf("invalid YAML front matter: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("YAML front matter is not a mapping")
+	}
+
+	for _, k := range keys {
+		value := &yaml.Node{}
+		if err := value.Encode(fields[k]); err != nil {
+			return "", err
+		}
+
+		found := false
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if strings.EqualFold(root.Content[i].Value, k) {
+				root.Content[i+1] = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", err
+	}
+	enc.Close()
+	return buf.String(), nil
+}
+
+// existingKey returns the spelling of key already used in fm, matching case-insensitively as Hugo does
+func existingKey(fm FrontMatter, key string) string {
+	for k := range fm {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
@@ -1,6 +1,6 @@
 // Hugo Manager - Main Application (ES Module)
-import { EditorView } from "@codemirror/view";
-import { EditorState } from "@codemirror/state";
+import { EditorView, Decoration } from "@codemirror/view";
+import { EditorState, StateField, StateEffect } from "@codemirror/state";
 import { markdown } from "@codemirror/lang-markdown";
 import { oneDark } from "@codemirror/theme-one-dark";
 import { lineNumbers } from "@codemirror/view";
@@ -22,6 +22,60 @@ function slugify(text) {
     .replace(/-+$/, ""); // Trim - from end
 }
 
+// setProseMarks replaces the prose linter's diagnostics underlined in the editor
+const setProseMarks = StateEffect.define();
+
+// proseMarks keeps the underlines of prose diagnostics in place as the text around them changes
+const proseMarks = StateField.define({
+  create: () => Decoration.none,
+  update(marks, tr) {
+    marks = marks.map(tr.changes);
+    for (const effect of tr.effects) {
+      if (effect.is(setProseMarks)) marks = effect.value;
+    }
+    return marks;
+  },
+  provide: (field) => EditorView.decorations.from(field),
+});
+
+// docPosition converts a diagnostic's line and byte column into a position in doc
+function docPosition(doc, line, column) {
+  if (line < 1 || line > doc.lines) return null;
+  const target = doc.line(line);
+  let bytes = 0;
+  let offset = 0;
+  for (const ch of target.text) {
+    if (bytes >= column - 1) break;
+    bytes += new TextEncoder().encode(ch).length;
+    offset += ch.length;
+  }
+  return target.from + offset;
+}
+
+// proseDecorations underlines each diagnostic of a prose lint report in doc
+function proseDecorations(doc, diagnostics) {
+  const ranges = [];
+  for (const d of diagnostics) {
+    const from = docPosition(doc, d.line, d.column);
+    const to = docPosition(doc, d.endLine || d.line, d.endColumn || d.column);
+    if (from === null || to === null || to <= from) continue;
+    ranges.push(
+      Decoration.mark({
+        class: `cm-prose-${d.rule}`,
+        attributes: { title: d.rule === "spelling" ? `${d.message} (Alt+click to add it to the word list)` : d.message },
+      }).range(from, to),
+    );
+  }
+  return Decoration.set(ranges, true);
+}
+
+// newSessionId returns a random ID for this editor session, which presence and locks are kept by
+function newSessionId() {
+  const bytes = new Uint8Array(16);
+  crypto.getRandomValues(bytes);
+  return Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("");
+}
+
 export function createApp() {
   return {
     // Configuration
@@ -33,6 +87,18 @@ export function createApp() {
     showLogs: false,
     showImageModal: false,
     showMetadataModal: false,
+    showRelatedModal: false,
+    relatedSuggestions: [],
+    relatedLoading: false,
+    showCommentsModal: false,
+    comments: [],
+    review: null,
+    commentDraft: { startLine: 1, endLine: 1, body: "" },
+    showWorkspacesModal: false,
+    workspaces: [],
+    workspace: "", // Workspace the file routes work in; empty for the live tree
+    workspaceChanges: [],
+    newWorkspaceName: "",
     showFileModal: false,
     showNewFile: false,
     showFileSelector: false,
@@ -48,6 +114,14 @@ export function createApp() {
     editor: null,
     editorModels: {},
     monacoLoaded: true, // Monaco is already loaded via import
+    savedPath: null, // The file just saved, until the editor jumps to a build error in it
+    draftTimers: {}, // Per file, the pending save of its unsaved content as a draft
+    proseTimer: null, // The pending prose lint of the file being edited
+
+    // Collaboration: who else has the open files open, and their locks
+    sessionId: newSessionId(),
+    presence: {},
+    presenceInterval: null,
 
     // Shortcodes
     shortcodes: [],
@@ -58,6 +132,8 @@ export function createApp() {
     templateForm: {},
     templateFilename: "",
     templateDirectory: "",
+    templateSetSlug: false,
+    slugRequests: 0,
 
     // Image Upload
     uploadFromContext: false,
@@ -84,6 +160,9 @@ export function createApp() {
     renameCallback: null,
     renameItemName: "",
     renameNewName: "",
+    renameIsContent: false, // Offers to keep the old URL as an alias
+    renameAddAlias: true,
+    renameRewriteReferences: true,
 
     // Confirmation Modal
     showConfirmModal: false,
@@ -93,7 +172,16 @@ export function createApp() {
 
     // Hugo Status
     hugoStatus: { status: "stopped", message: "" },
+    hugoProfiles: [],
+    hugoInstances: [], // The main Hugo server and those of hugo.instances
+    previewInstance: "main", // The one the Preview pane shows
+    hugoOptions: [
+      { name: "buildDrafts", label: "Drafts", title: "Render draft content" },
+      { name: "buildFuture", label: "Future", title: "Render content with a future publish date" },
+      { name: "buildExpired", label: "Expired", title: "Render expired content" },
+    ],
     logs: [],
+    logLevel: "",
     ws: null,
     reconnectTimer: null,
     previewReady: false,
@@ -122,6 +210,8 @@ export function createApp() {
     imgShortcodeResult: null,
     imgShortcodeOptions: {
       alt: "",
+      caption: "",
+      lang: "",
       class: "",
       sizes: "",
       loading: "",
@@ -132,9 +222,13 @@ export function createApp() {
       quality: 85,
       preset: "Full responsive",
       customWidths: "",
+      alt: "",
+      keepOriginal: false,
     },
     uploadResult: null,
     uploading: false,
+    imageMeta: null, // Alt text, caption, credit and license of the uploaded image
+    imageMetaPath: "",
 
     // Metadata Modal
     selectedTemplate: "",
@@ -196,6 +290,8 @@ export function createApp() {
         this.refreshFiles(),
         this.loadShortcodes(),
         this.loadHugoStatus(),
+        this.loadHugoProfiles(),
+        this.loadHugoInstances(),
         this.loadImageFolders(),
         this.loadImagePresets(),
       ]);
@@ -209,6 +305,12 @@ export function createApp() {
       }
       this.statusInterval = setInterval(() => this.loadHugoStatus(), 5000);
 
+      // Keep this session in the presence of its open files, and its locks, until they're closed
+      if (this.presenceInterval) {
+        clearInterval(this.presenceInterval);
+      }
+      this.presenceInterval = setInterval(() => this.renewPresence(), 20000);
+
       // Keyboard shortcuts
       document.addEventListener("keydown", (e) => {
         // Ctrl/Cmd+M: open metadata modal
@@ -218,12 +320,9 @@ export function createApp() {
         }
         // Escape: close topmost modal
         if (e.key === "Escape") {
-          if (this.showImageModal && this.metadataImageField) {
-            // Canceling image selector from metadata modal
-            this.showImageModal = false;
-            this.metadataImageField = null;
-            // Restore metadata modal
-            this.showMetadataModal = true;
+          if (this.showImageModal && (this.metadataImageField || this.templateImageField)) {
+            // Canceling image selector from the metadata or template modal
+            this.closeImageModal();
           } else if (this.showMetadataModal) {
             this.showMetadataModal = false;
             this.metadataImageField = null;
@@ -242,7 +341,7 @@ export function createApp() {
 
     async refreshFiles() {
       try {
-        const res = await fetch("/api/files");
+        const res = await fetch(`/api/v1/files${this.workspaceParam()}`);
         this.fileTree = await res.json();
       } catch (err) {
         this.showToast("Failed to load files", "error");
@@ -256,7 +355,7 @@ export function createApp() {
         params.set("show", "images");
         if (this.imgShortcodeQuery) params.set("q", this.imgShortcodeQuery);
 
-        const res = await fetch(`/api/files?${params.toString()}`);
+        const res = await fetch(`/api/v1/files?${params.toString()}`);
         const data = await res.json();
         this.imgShortcodeTree = Array.isArray(data) ? data : [];
 
@@ -347,7 +446,14 @@ export function createApp() {
 
       this.imgShortcodeSelected = null;
       this.imgShortcodeResult = null;
-      this.imgShortcodeOptions = { alt: "", class: "", sizes: "", loading: "" };
+      this.imgShortcodeOptions = {
+        alt: "",
+        caption: "",
+        lang: "",
+        class: "",
+        sizes: "",
+        loading: "",
+      };
       this.showImgShortcodeModal = true;
       this.loadImgShortcodeTree();
     },
@@ -365,11 +471,11 @@ export function createApp() {
       if (!this.imgShortcodeSelected?.path) return;
       try {
         const res = await fetch(
-          `/api/images/processed?path=${encodeURIComponent(this.imgShortcodeSelected.path)}`,
+          `/api/v1/images/processed?path=${encodeURIComponent(this.imgShortcodeSelected.path)}`,
         );
         const data = await res.json();
-        if (data?.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
           this.imgShortcodeResult = null;
           return;
         }
@@ -389,6 +495,8 @@ export function createApp() {
       const cls = (this.imgShortcodeOptions.class || "").trim();
       const sizes = (this.imgShortcodeOptions.sizes || "").trim();
       const loading = (this.imgShortcodeOptions.loading || "").trim();
+      const caption = (this.imgShortcodeOptions.caption || "").trim();
+      const lang = (this.imgShortcodeOptions.lang || "").trim();
       const variants = Array.isArray(this.imgShortcodeResult.variants)
         ? this.imgShortcodeResult.variants
         : [];
@@ -399,6 +507,8 @@ export function createApp() {
         s += ` srcset="${srcset}"`;
       }
       if (sizes) s += ` sizes="${sizes}"`;
+      if (caption) s += ` caption="${caption.replace(/"/g, '\\"')}"`;
+      if (lang) s += ` lang="${lang}"`;
       if (cls) s += ` class="${cls}"`;
       if (loading) s += ` loading="${loading}"`;
       s += " >}}";
@@ -440,7 +550,7 @@ export function createApp() {
         params.set("show", "images");
         if (this.imageBrowseQuery) params.set("q", this.imageBrowseQuery);
 
-        const res = await fetch(`/api/files?${params.toString()}`);
+        const res = await fetch(`/api/v1/files?${params.toString()}`);
         const data = await res.json();
         this.imageBrowseTree = Array.isArray(data) ? data : [];
 
@@ -545,11 +655,20 @@ export function createApp() {
       }
 
       try {
-        const res = await fetch(`/api/files/${encodeURIComponent(path)}`);
+        const res = await fetch(`/api/v1/files/${encodeURIComponent(path)}${this.workspaceParam()}`);
         const data = await res.json();
 
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
+          return;
+        }
+
+        // Binary content would be corrupted by the editor, and large files would slow it down;
+        // open the raw file instead
+        if (data.isBinary || data.tooLarge) {
+          const reason = data.isBinary ? "is a binary file" : `is too large to edit (${this.formatBytes(data.size)})`;
+          this.showToast(`${path} ${reason} and opens in a new tab.`, "info");
+          window.open(this.getRawFileUrl(path), "_blank");
           return;
         }
 
@@ -560,16 +679,150 @@ export function createApp() {
           content: data.content,
           originalContent: data.content,
           modified: false,
+          stats: data.info?.stats || null,
         };
 
         this.tabs.push(tab);
         this.switchTab(path);
         this.updatePreviewUrl(path);
+        this.joinFile(path);
+        if (data.draft) {
+          this.offerDraft(path, data.draft);
+        }
       } catch (err) {
         this.showToast("Failed to open file", "error");
       }
     },
 
+    // Unsaved drafts: with editor.auto_save, the content of modified files is kept on the server
+    // until they're saved, and offered back when the file is opened again
+    offerDraft(path, draft) {
+      const when = new Date(draft.savedAt).toLocaleString();
+      const stale = draft.stale ? " The file has changed since, so restoring it overwrites those changes once saved." : "";
+      this.showConfirmation("Unsaved draft found", `${path} has unsaved changes from ${when}. Restore them?${stale}`, () => {
+        if (this.activeTab !== path || !this.editor) return;
+        this.editor.dispatch({ changes: { from: 0, to: this.editor.state.doc.length, insert: draft.content } });
+      });
+    },
+
+    scheduleDraft(path) {
+      if (!this.config.editor?.auto_save || this.config.readOnly || this.workspace) return;
+      clearTimeout(this.draftTimers[path]);
+      this.draftTimers[path] = setTimeout(() => this.saveDraft(path), this.config.editor.auto_save_delay || 1000);
+    },
+
+    async saveDraft(path) {
+      delete this.draftTimers[path];
+      const tab = this.tabs.find((t) => t.path === path);
+      if (!tab?.modified) return;
+      try {
+        await fetch(`/api/v1/files/${encodeURIComponent(path)}/draft`, {
+          method: "PUT",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ content: tab.content }),
+        });
+      } catch (err) {
+        // Drafts are best effort; the next change tries again
+      }
+    },
+
+    async discardDraft(path) {
+      clearTimeout(this.draftTimers[path]);
+      delete this.draftTimers[path];
+      if (this.workspace) return;
+      try {
+        await fetch(`/api/v1/files/${encodeURIComponent(path)}/draft`, { method: "DELETE" });
+      } catch (err) {
+        // A draft left behind is offered again, and can be declined then
+      }
+    },
+
+    // Checks the spelling and style of the markdown being edited once typing pauses, underlining
+    // what the prose linter finds
+    scheduleProseLint() {
+      clearTimeout(this.proseTimer);
+      const path = this.activeTab;
+      if (!this.editor || !/\.(md|markdown)$/i.test(path || "")) return;
+      this.proseTimer = setTimeout(() => this.lintProse(path), 800);
+    },
+
+    async lintProse(path) {
+      const content = this.editor.state.doc.toString();
+      try {
+        const response = await fetch("/api/v1/lint/prose", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ path, content }),
+        });
+        if (!response.ok) return;
+        const report = await response.json();
+        // Typing or switching files since makes the positions stale; the next check replaces them
+        if (path !== this.activeTab || content !== this.editor.state.doc.toString()) return;
+        this.editor.dispatch({
+          effects: setProseMarks.of(proseDecorations(this.editor.state.doc, report.diagnostics || [])),
+        });
+      } catch (err) {
+        // Squiggles are a hint; the next change tries again
+      }
+    },
+
+    // Fixes the markdown problems of the file being edited in the editor; saving keeps the fixes
+    async fixMarkdown() {
+      if (!this.editor || !this.activeTab) return;
+      try {
+        const response = await fetch("/api/v1/lint/markdown/fix", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ path: this.activeTab, content: this.editor.state.doc.toString() }),
+        });
+        const result = await response.json();
+        if (!response.ok) {
+          this.showToast(result.detail || "Failed to fix markdown", "error");
+          return;
+        }
+        if (result.files.length === 0) {
+          this.showToast("No markdown problems to fix", "info");
+          return;
+        }
+        this.editor.dispatch({
+          changes: { from: 0, to: this.editor.state.doc.length, insert: result.files[0].content },
+        });
+        this.showToast(`Made ${result.fixes} markdown fix${result.fixes === 1 ? "" : "es"}; save to keep them`, "success");
+      } catch (err) {
+        this.showToast("Failed to fix markdown", "error");
+      }
+    },
+
+    // Alt+click on a word underlined as misspelled offers to add it to the project word list
+    proseClick(event) {
+      const mark = event.target.closest?.(".cm-prose-spelling");
+      if (!event.altKey || !mark || this.config.readOnly) return false;
+      const word = mark.textContent;
+      this.showConfirmation("Add to word list", `Accept "${word}" as spelled right in this project?`, () =>
+        this.addToDictionary(word),
+      );
+      return true;
+    },
+
+    async addToDictionary(word) {
+      try {
+        const response = await fetch("/api/v1/lint/dictionary", {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ word }),
+        });
+        if (!response.ok) {
+          const error = await response.json();
+          this.showToast(error.detail || "Failed to add the word", "error");
+          return;
+        }
+        this.showToast(`Added "${word}" to the project word list`, "success");
+        this.scheduleProseLint();
+      } catch (err) {
+        this.showToast("Failed to add the word", "error");
+      }
+    },
+
     switchTab(path) {
       // Save current editor content to the current tab before switching
       if (this.editor && this.activeTab) {
@@ -600,6 +853,7 @@ export function createApp() {
               markdown(),
               oneDark,
               keymap.of(defaultKeymap),
+              proseMarks,
               EditorView.theme({
                 "&": {
                   fontSize: this.config.editor?.fontSize || "14px",
@@ -618,14 +872,37 @@ export function createApp() {
                 ".cm-focused": {
                   outline: "none",
                 },
+                ".cm-prose-spelling": {
+                  textDecoration: "underline wavy #e5534b",
+                  textUnderlineOffset: "3px",
+                },
+                ".cm-prose-repeated-word": {
+                  textDecoration: "underline wavy #d29922",
+                  textUnderlineOffset: "3px",
+                },
+                ".cm-prose-long-sentence": {
+                  textDecoration: "underline dotted #539bf5",
+                  textUnderlineOffset: "3px",
+                },
+              }),
+              EditorView.domEventHandlers({
+                paste: (event) => this.pasteImage(event),
+                click: (event) => this.proseClick(event),
               }),
               EditorView.updateListener.of((update) => {
                 if (update.docChanged) {
                   const tab = this.tabs.find((t) => t.path === this.activeTab);
                   if (tab) {
+                    const wasModified = tab.modified;
                     tab.content = update.state.doc.toString();
                     tab.modified =
                       update.state.doc.toString() !== tab.originalContent;
+                    if (tab.modified) {
+                      this.scheduleDraft(tab.path);
+                    } else if (wasModified && this.config.editor?.auto_save) {
+                      this.discardDraft(tab.path); // Back to the saved content
+                    }
+                    this.scheduleProseLint();
                   }
                 }
               }),
@@ -644,8 +921,10 @@ export function createApp() {
               to: this.editor.state.doc.length,
               insert: tab.content,
             },
+            effects: setProseMarks.of(Decoration.none),
           });
         }
+        this.scheduleProseLint();
 
         this.updatePreviewUrl(path);
       });
@@ -655,10 +934,12 @@ export function createApp() {
       const tab = this.tabs.find((t) => t.path === path);
       if (tab?.modified) {
         if (!confirm("Discard unsaved changes?")) return;
+        this.discardDraft(path);
       }
 
       const index = this.tabs.findIndex((t) => t.path === path);
       this.tabs.splice(index, 1);
+      this.leaveFile(path);
 
       // Dispose Monaco model
       if (this.editorModels[path]) {
@@ -684,34 +965,135 @@ export function createApp() {
       const content = this.editor.state.doc.toString();
 
       try {
-        const res = await fetch(`/api/files/${encodeURIComponent(tab.path)}`, {
+        const res = await fetch(`/api/v1/files/${encodeURIComponent(tab.path)}?session=${this.sessionId}${this.workspaceParam("&")}`, {
           method: "PUT",
           headers: { "Content-Type": "application/json" },
           body: JSON.stringify({ content }),
         });
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          if (data.errorCode === "ERR_LOCKED") {
+            this.showToast(`Not saved: ${data.detail}. Copy your changes and save them once it's unlocked.`, "error");
+            return;
+          }
+          this.showToast(data.detail, "error");
           return;
         }
 
         tab.content = content;
         tab.originalContent = content;
         tab.modified = false;
-
-        this.showToast("File saved", "success");
+        tab.stats = data.stats || null;
+        // The server discarded the draft with the save
+        clearTimeout(this.draftTimers[tab.path]);
+        delete this.draftTimers[tab.path];
+        // Workspaces build in their own preview, whose errors aren't published
+        this.savedPath = this.workspace ? null : tab.path;
+
+        if (data.warnings?.length) {
+          this.showToast(`File saved. ${data.warnings.join("; ")}`, "warning");
+        } else {
+          this.showToast("File saved", "success");
+        }
         this.refreshPreview();
       } catch (err) {
         this.showToast("Failed to save file", "error");
       }
     },
 
+    // Presence and locks
+    presenceUrl(path, kind) {
+      return `/api/v1/files/${encodeURIComponent(path)}/${kind}?session=${this.sessionId}`;
+    },
+
+    async joinFile(path) {
+      try {
+        const res = await fetch(this.presenceUrl(path, "presence"), { method: "POST" });
+        if (res.ok) {
+          this.presence[path] = await res.json();
+        }
+      } catch (err) {
+        // Presence is best effort; the file stays open without it
+      }
+    },
+
+    leaveFile(path) {
+      delete this.presence[path];
+      fetch(this.presenceUrl(path, "presence"), { method: "DELETE" }).catch(() => {});
+    },
+
+    async renewPresence() {
+      for (const tab of this.tabs) {
+        await this.joinFile(tab.path);
+        if (this.lockedByMe(tab.path)) {
+          await fetch(this.presenceUrl(tab.path, "lock"), { method: "POST" }).catch(() => {});
+        }
+      }
+    },
+
+    otherViewers(path) {
+      return (this.presence[path]?.viewers || []).filter((v) => v.session !== this.sessionId);
+    },
+
+    viewerNames(path) {
+      return this.otherViewers(path)
+        .map((v) => v.user || "another session")
+        .join(", ");
+    },
+
+    lockedByMe(path) {
+      return this.presence[path]?.lock?.session === this.sessionId;
+    },
+
+    lockedByOther(path) {
+      const lock = this.presence[path]?.lock;
+      return !!lock && lock.session !== this.sessionId;
+    },
+
+    lockHolder(path) {
+      return this.presence[path]?.lock?.user || "another session";
+    },
+
+    async toggleLock() {
+      const path = this.activeTab;
+      if (!path) return;
+
+      const unlock = this.lockedByMe(path);
+      try {
+        const res = await fetch(this.presenceUrl(path, "lock"), { method: unlock ? "DELETE" : "POST" });
+        const data = await res.json();
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
+          return;
+        }
+        this.showToast(unlock ? "Lock released" : "File locked: others can't save it until you close or unlock it", "success");
+      } catch (err) {
+        this.showToast(unlock ? "Failed to unlock" : "Failed to lock", "error");
+      }
+    },
+
     get currentTabModified() {
       const tab = this.tabs.find((t) => t.path === this.activeTab);
       return tab?.modified || false;
     },
 
+    // Word count, reading time and outline of the open markdown file, as last saved
+    get currentTabStats() {
+      const tab = this.tabs.find((t) => t.path === this.activeTab);
+      return tab?.stats || null;
+    },
+
+    statsLabel(stats) {
+      const words = `${stats.words.toLocaleString()} ${stats.words === 1 ? "word" : "words"}`;
+      return `${words} · ${stats.readingTime} min read`;
+    },
+
+    statsOutline(stats) {
+      const outline = stats.headings.map((h) => `${"  ".repeat(h.level - 1)}${h.text}`).join("\n");
+      return outline ? `Outline:\n${outline}` : "No headings";
+    },
+
     showNewFileDialog() {
       this.newFilePath = "content/";
       this.newFileIsDir = false;
@@ -723,7 +1105,7 @@ export function createApp() {
 
       try {
         const res = await fetch(
-          `/api/files/${encodeURIComponent(this.newFilePath)}`,
+          `/api/v1/files/${encodeURIComponent(this.newFilePath)}${this.workspaceParam()}`,
           {
             method: "POST",
             headers: { "Content-Type": "application/json" },
@@ -735,8 +1117,8 @@ export function createApp() {
         );
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
           return;
         }
 
@@ -770,7 +1152,7 @@ Content goes here...
     // Shortcodes
     async loadShortcodes() {
       try {
-        const res = await fetch("/api/shortcodes");
+        const res = await fetch("/api/v1/shortcodes");
         this.shortcodes = await res.json();
       } catch (err) {
         console.error("Failed to load shortcodes:", err);
@@ -877,7 +1259,7 @@ Content goes here...
     },
 
     getRawFileUrl(path) {
-      return `/api/files/raw?path=${encodeURIComponent(path)}`;
+      return `/api/v1/files/raw?path=${encodeURIComponent(path)}${this.workspaceParam("&")}`;
     },
 
     toPublicImageUrl(path) {
@@ -894,7 +1276,7 @@ Content goes here...
         const params = new URLSearchParams();
         if (this.imageBrowseQuery) params.set("q", this.imageBrowseQuery);
 
-        const res = await fetch(`/api/files/search?${params.toString()}`);
+        const res = await fetch(`/api/v1/files/search?${params.toString()}`);
         const data = await res.json();
         this.imageBrowseResults = Array.isArray(data) ? data : [];
       } catch (err) {
@@ -912,11 +1294,15 @@ Content goes here...
     insertBrowseImage() {
       if (!this.imageBrowseSelected) return;
 
-      // If we're setting a metadata field, use the path directly
+      // If we're setting a metadata or template field, use the path directly
       if (this.metadataImageField) {
         this.onImageSelectedForMetadata(this.imageBrowseSelected.path);
         return;
       }
+      if (this.templateImageField) {
+        this.onImageSelectedForTemplate(this.imageBrowseSelected.path);
+        return;
+      }
 
       // Normal editor insertion
       if (!this.editor) return;
@@ -937,8 +1323,343 @@ Content goes here...
       this.showToast("Image inserted", "success");
     },
 
+    // Images pasted from the clipboard are processed for the open page and inserted at the cursor
+    pasteImage(event) {
+      const file = [...(event.clipboardData?.files || [])].find((f) => f.type.startsWith("image/"));
+      if (!file || !this.activeTab || !this.editor) return false;
+      event.preventDefault();
+
+      const view = this.editor;
+      const page = this.activeTab;
+      const at = view.state.selection.main;
+      const formData = new FormData();
+      formData.append("image", file, file.name || "pasted.png");
+      formData.append("page", page);
+      this.showToast("Processing pasted image...", "info");
+
+      fetch("/api/v1/images/paste", { method: "POST", body: formData })
+        .then(async (res) => {
+          const data = await res.json();
+          if (!res.ok) throw new Error(data.detail || "Failed to process image");
+          const insert = page.endsWith(".html") ? data.html : data.markdown;
+          if (this.activeTab !== page) {
+            // Another file is open now; the snippet is left on the clipboard instead
+            this.copyToClipboard(insert);
+            return;
+          }
+          const from = Math.min(at.from, view.state.doc.length);
+          const to = Math.min(at.to, view.state.doc.length);
+          view.dispatch({
+            changes: { from, to, insert },
+            selection: { anchor: from + insert.length },
+          });
+          view.focus();
+          this.showToast("Image inserted", "success");
+          if (data.warnings?.length) {
+            this.showToast(data.warnings[0], "warning");
+          }
+        })
+        .catch((err) => {
+          console.error("Paste error:", err);
+          this.showToast(err.message, "error");
+        });
+      return true;
+    },
+
+    // Related Content
+    async openRelatedModal() {
+      if (!this.activeTab) {
+        this.showToast("No file open", "error");
+        return;
+      }
+      this.showRelatedModal = true;
+      this.relatedSuggestions = [];
+      this.relatedLoading = true;
+      try {
+        const response = await fetch(
+          `/api/v1/content/${encodeURIComponent(this.activeTab)}/related`,
+        );
+        if (!response.ok) {
+          throw new Error(await response.text());
+        }
+        this.relatedSuggestions = (await response.json()).suggestions;
+      } catch (error) {
+        console.error("Error finding related content:", error);
+        this.showToast("Failed to find related content", "error");
+        this.showRelatedModal = false;
+      } finally {
+        this.relatedLoading = false;
+      }
+    },
+
+    insertRelatedLink(suggestion) {
+      if (!this.editor) return;
+      const view = this.editor;
+      view.dispatch(
+        view.state.changeByRange((range) => ({
+          changes: { from: range.from, to: range.to, insert: suggestion.link },
+          range: {
+            from: range.from + suggestion.link.length,
+            to: range.from + suggestion.link.length,
+          },
+        })),
+      );
+      this.showRelatedModal = false;
+      this.editor.focus();
+    },
+
+    // Review Comments
+    openCommentsModal() {
+      if (!this.activeTab) {
+        this.showToast("No file open", "error");
+        return;
+      }
+      // New comments are on the selected lines
+      let startLine = 1;
+      let endLine = 1;
+      if (this.editor) {
+        const { doc, selection } = this.editor.state;
+        startLine = doc.lineAt(selection.main.from).number;
+        endLine = doc.lineAt(selection.main.to).number;
+      }
+      this.commentDraft = { startLine, endLine, body: "" };
+      this.comments = [];
+      this.review = null;
+      this.showCommentsModal = true;
+      this.loadComments();
+    },
+
+    async loadComments() {
+      const path = this.activeTab;
+      try {
+        const [list, review] = await Promise.all([
+          fetch(`/api/v1/comments?path=${encodeURIComponent(path)}`),
+          fetch(`/api/v1/comments/reviews/${encodeURIComponent(path)}`),
+        ]);
+        if (!list.ok || !review.ok) {
+          throw new Error("Failed to load comments");
+        }
+        // ?path= also matches a folder's files, and only this file's comments are shown
+        this.comments = (await list.json()).filter((c) => c.path === path);
+        this.review = await review.json();
+      } catch (error) {
+        console.error("Error loading comments:", error);
+        this.showToast("Failed to load comments", "error");
+      }
+    },
+
+    async commentRequest(url, method, body) {
+      try {
+        const res = await fetch(url, {
+          method,
+          headers: body ? { "Content-Type": "application/json" } : {},
+          body: body ? JSON.stringify(body) : undefined,
+        });
+        const data = await res.json();
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
+          return false;
+        }
+        await this.loadComments();
+        return true;
+      } catch (error) {
+        this.showToast("Failed to update comments", "error");
+        return false;
+      }
+    },
+
+    async addComment() {
+      const ok = await this.commentRequest("/api/v1/comments", "POST", {
+        path: this.activeTab,
+        startLine: Number(this.commentDraft.startLine),
+        endLine: Number(this.commentDraft.endLine),
+        body: this.commentDraft.body,
+      });
+      if (ok) {
+        this.commentDraft.body = "";
+      }
+    },
+
+    setCommentResolved(comment, resolved) {
+      this.commentRequest(`/api/v1/comments/${comment.id}`, "PUT", { resolved });
+    },
+
+    deleteComment(comment) {
+      this.commentRequest(`/api/v1/comments/${comment.id}`, "DELETE");
+    },
+
+    async setApproved(approved) {
+      const ok = await this.commentRequest(
+        `/api/v1/comments/reviews/${encodeURIComponent(this.activeTab)}`,
+        approved ? "PUT" : "DELETE",
+      );
+      if (ok) {
+        this.showToast(approved ? "Approved for publishing" : "Approval withdrawn", "success");
+      }
+    },
+
+    goToComment(comment) {
+      if (!this.editor) return;
+      const doc = this.editor.state.doc;
+      const from = doc.line(Math.min(comment.startLine, doc.lines)).from;
+      const to = doc.line(Math.min(comment.endLine, doc.lines)).to;
+      this.editor.dispatch({ selection: { anchor: from, head: to }, scrollIntoView: true });
+      this.showCommentsModal = false;
+      this.editor.focus();
+    },
+
+    goToLine(line, column = 1) {
+      if (!this.editor) return;
+      const doc = this.editor.state.doc;
+      const target = doc.line(Math.min(Math.max(line, 1), doc.lines));
+      const pos = Math.min(target.from + Math.max(column, 1) - 1, target.to);
+      this.editor.dispatch({ selection: { anchor: pos }, scrollIntoView: true });
+      this.editor.focus();
+    },
+
+    // Jumps to the first error of the build a save started, when it's in the saved file and that
+    // file is still the one being edited
+    showBuildError(event) {
+      if (event.instance || !event.line || event.file !== this.savedPath || event.file !== this.activeTab) {
+        return;
+      }
+      this.savedPath = null;
+      this.goToLine(event.line, event.column);
+      this.showToast(`Build error on line ${event.line}: ${event.message}`, "error");
+    },
+
+    // Workspaces
+    workspaceParam(sep = "?") {
+      return this.workspace ? `${sep}workspace=${encodeURIComponent(this.workspace)}` : "";
+    },
+
+    openWorkspacesModal() {
+      this.newWorkspaceName = "";
+      this.showWorkspacesModal = true;
+      this.loadWorkspaces();
+    },
+
+    async loadWorkspaces() {
+      try {
+        const res = await fetch("/api/v1/workspaces");
+        if (!res.ok) {
+          throw new Error("Failed to load workspaces");
+        }
+        this.workspaces = await res.json();
+        this.workspaceChanges = [];
+        if (this.workspace) {
+          const detail = await fetch(`/api/v1/workspaces/${encodeURIComponent(this.workspace)}`);
+          if (detail.ok) {
+            this.workspaceChanges = (await detail.json()).changes || [];
+          }
+        }
+      } catch (error) {
+        console.error("Error loading workspaces:", error);
+        this.showToast("Failed to load workspaces", "error");
+      }
+    },
+
+    async workspaceRequest(url, method, body) {
+      try {
+        const res = await fetch(url, {
+          method,
+          headers: body ? { "Content-Type": "application/json" } : {},
+          body: body ? JSON.stringify(body) : undefined,
+        });
+        const data = await res.json();
+        if (!res.ok) {
+          return { error: data };
+        }
+        return { data };
+      } catch (error) {
+        return { error: { detail: "Failed to update workspace" } };
+      }
+    },
+
+    async createWorkspace() {
+      const { data, error } = await this.workspaceRequest("/api/v1/workspaces", "POST", { name: this.newWorkspaceName });
+      if (error) {
+        this.showToast(error.detail, "error");
+        return;
+      }
+      this.newWorkspaceName = "";
+      this.switchWorkspace(data.name);
+    },
+
+    // switchWorkspace edits the files of a workspace, or of the live tree with an empty name. Open
+    // files belong to the tree they were opened from, so they're closed first.
+    switchWorkspace(name) {
+      if (this.tabs.some((t) => t.modified)) {
+        this.showToast("Save or close the modified files first", "error");
+        return;
+      }
+      for (const tab of [...this.tabs]) {
+        this.closeTab(tab.path);
+      }
+      this.workspace = name;
+      this.refreshFiles();
+      this.loadWorkspaces();
+    },
+
+    async setWorkspacePreview(ws, running) {
+      const { error } = await this.workspaceRequest(
+        `/api/v1/workspaces/${encodeURIComponent(ws.name)}/preview`,
+        running ? "POST" : "DELETE",
+      );
+      if (error) {
+        this.showToast(error.detail, "error");
+      }
+      this.loadWorkspaces();
+    },
+
+    openWorkspacePreview(ws) {
+      window.open(`http://${window.location.hostname}:${ws.preview.port}/`, "_blank");
+    },
+
+    async mergeWorkspace(ws, force = false) {
+      const { data, error } = await this.workspaceRequest(
+        `/api/v1/workspaces/${encodeURIComponent(ws.name)}/merge`,
+        "POST",
+        { force },
+      );
+      if (error?.errorCode === "ERR_CONFLICT") {
+        this.showConfirmation(
+          "Merge conflicts",
+          `${error.detail}. Merging overwrites the live changes to these files.`,
+          () => this.mergeWorkspace(ws, true),
+        );
+        return;
+      }
+      if (error) {
+        this.showToast(error.detail, "error");
+        return;
+      }
+      this.showToast(`Merged ${data.merged.length} changed files`, "success");
+      if (this.workspace === ws.name) {
+        this.switchWorkspace("");
+      } else {
+        this.loadWorkspaces();
+      }
+    },
+
+    discardWorkspace(ws) {
+      this.showConfirmation("Discard workspace", `Discard ${ws.name} and all its changes?`, async () => {
+        const { error } = await this.workspaceRequest(`/api/v1/workspaces/${encodeURIComponent(ws.name)}`, "DELETE");
+        if (error) {
+          this.showToast(error.detail, "error");
+          return;
+        }
+        if (this.workspace === ws.name) {
+          this.switchWorkspace("");
+        } else {
+          this.loadWorkspaces();
+        }
+      });
+    },
+
     // Metadata Modal
     metadataImageField: null, // Track which field is being set
+    templateImageField: null, // Field of the new-file template being set
 
     openMetadataModal() {
       if (!this.activeTab) {
@@ -986,6 +1707,24 @@ Content goes here...
       this.showMetadataModal = true;
     },
 
+    openImageSelectorForTemplate(fieldName) {
+      this.templateImageField = fieldName;
+      // Hide the template modal while the image selector is open
+      this.showTemplateModal = false;
+      this.imageModalTab = "browse";
+      this.showImageModal = true;
+      this.loadImageBrowseTree();
+    },
+
+    onImageSelectedForTemplate(path) {
+      if (this.templateImageField) {
+        this.templateForm[this.templateImageField] = path;
+        this.templateImageField = null;
+      }
+      this.showImageModal = false;
+      this.showTemplateModal = true;
+    },
+
     closeImageModal() {
       this.showImageModal = false;
       if (this.metadataImageField) {
@@ -993,6 +1732,35 @@ Content goes here...
         // Restore metadata modal if we were called from it
         this.showMetadataModal = true;
       }
+      if (this.templateImageField) {
+        this.templateImageField = null;
+        this.showTemplateModal = true;
+      }
+    },
+
+    // Splits a comma-separated tags input into a list
+    splitTags(value) {
+      return value
+        .split(",")
+        .map((tag) => tag.trim())
+        .filter((tag) => tag !== "");
+    },
+
+    // Content pages a reference field can link to, relative to content/
+    referenceOptions(field) {
+      const prefix = field.section ? `content/${field.section}/` : "content/";
+      const pages = [];
+      const walk = (items) => {
+        for (const item of items || []) {
+          if (item.isDir) {
+            walk(item.children);
+          } else if (item.path.startsWith(prefix) && /\.(md|markdown|html)$/.test(item.path)) {
+            pages.push(item.path.slice("content/".length));
+          }
+        }
+      };
+      walk(this.fileTree);
+      return pages.sort();
     },
 
     detectTemplate(frontmatter) {
@@ -1253,15 +2021,33 @@ Content goes here...
     },
 
     // Template Operations
-    openTemplateModal() {
+    async openTemplateModal() {
       this.showTemplateModal = true;
+      this.templateSetSlug = false;
       this.selectedTemplate = null;
       this.templateForm = {};
       this.templateFilename = "";
+
+      // Preselect the template bound to the folder in template_paths
+      if (!this.templateDirectory) return;
+      try {
+        const response = await fetch(
+          `/api/v1/content/${encodeURIComponent(this.templateDirectory)}/template`,
+        );
+        if (!response.ok) return;
+        const bound = await response.json();
+        if (bound.template && this.config.templates?.[bound.template]) {
+          this.selectTemplate(bound.template);
+          Object.assign(this.templateForm, bound.data);
+        }
+      } catch (error) {
+        console.error("Error finding the folder's template:", error);
+      }
     },
 
     closeTemplateModal() {
       this.showTemplateModal = false;
+      this.templateSetSlug = false;
       this.selectedTemplate = null;
       this.templateForm = {};
       this.templateFilename = "";
@@ -1291,9 +2077,27 @@ Content goes here...
       }
     },
 
-    updateFilename() {
-      if (this.templateForm.title) {
-        this.templateFilename = slugify(this.templateForm.title) + ".md";
+    async updateFilename() {
+      const title = this.templateForm.title;
+      if (!title) return;
+
+      // Ask for a slug no file in the folder uses; only the latest answer counts
+      const request = ++this.slugRequests;
+      let filename = slugify(title) + ".md";
+      try {
+        const params = new URLSearchParams({
+          title,
+          dir: this.templateDirectory,
+        });
+        const response = await fetch(`/api/v1/utils/slug?${params}`);
+        if (response.ok) {
+          filename = (await response.json()).filename;
+        }
+      } catch (error) {
+        console.error("Error generating slug:", error);
+      }
+      if (request === this.slugRequests) {
+        this.templateFilename = filename;
       }
     },
 
@@ -1313,7 +2117,7 @@ Content goes here...
 
       try {
         const response = await fetch(
-          `/api/files/${encodeURIComponent(fullPath)}`,
+          `/api/v1/files/${encodeURIComponent(fullPath)}${this.workspaceParam()}`,
           {
             method: "POST",
             headers: {
@@ -1322,20 +2126,23 @@ Content goes here...
             body: JSON.stringify({
               template: this.selectedTemplate,
               data: this.templateForm,
+              slug: this.templateSetSlug
+                ? this.templateFilename.replace(/\.[^.]+$/, "")
+                : "",
             }),
           },
         );
 
         if (!response.ok) {
-          const error = await response.text();
-          if (response.status === 409) {
+          const error = await response.json();
+          if (error.errorCode === "ERR_EXISTS") {
             this.showToast(
               "File already exists. Please choose a different filename.",
               "error",
             );
             return;
           }
-          throw new Error(error);
+          throw new Error(error.detail);
         }
 
         this.showToast("File created successfully", "success");
@@ -1395,8 +2202,11 @@ Content goes here...
       this.hideContextMenu();
       this.renameItemName = this.contextMenu.target.name;
       this.renameNewName = this.contextMenu.target.name;
-      this.renameCallback = (newName) =>
-        this.renameItem(this.contextMenu.targetPath, newName);
+      this.renameIsContent = this.contextMenu.targetPath.startsWith("content/");
+      this.renameAddAlias = true;
+      this.renameRewriteReferences = true;
+      this.renameCallback = (newName, options) =>
+        this.renameItem(this.contextMenu.targetPath, newName, options);
       this.showRenameModal = true;
     },
 
@@ -1500,7 +2310,7 @@ Content goes here...
     async createDirectory(dirPath) {
       try {
         const response = await fetch(
-          `/api/files/${encodeURIComponent(dirPath)}`,
+          `/api/v1/files/${encodeURIComponent(dirPath)}${this.workspaceParam()}`,
           {
             method: "POST",
             headers: {
@@ -1513,13 +2323,16 @@ Content goes here...
         );
 
         if (!response.ok) {
-          const errorText = await response.text();
-          if (response.status === 409) {
-            this.showToast("Directory already exists", "error");
-          } else if (response.status === 400) {
-            this.showToast("Invalid directory path", "error");
-          } else {
-            this.showToast("Failed to create directory: " + errorText, "error");
+          const error = await response.json();
+          switch (error.errorCode) {
+            case "ERR_EXISTS":
+              this.showToast("Directory already exists", "error");
+              break;
+            case "ERR_INVALID_PATH":
+              this.showToast("Invalid directory path", "error");
+              break;
+            default:
+              this.showToast("Failed to create directory: " + error.detail, "error");
           }
           return;
         }
@@ -1545,6 +2358,7 @@ Content goes here...
       this.renameCallback = null;
       this.renameItemName = "";
       this.renameNewName = "";
+      this.renameIsContent = false;
     },
 
     confirmRename() {
@@ -1553,7 +2367,10 @@ Content goes here...
         this.renameNewName !== this.renameItemName
       ) {
         if (this.renameCallback) {
-          this.renameCallback(this.renameNewName.trim());
+          this.renameCallback(this.renameNewName.trim(), {
+            addAlias: this.renameIsContent && this.renameAddAlias,
+            rewriteReferences: this.renameRewriteReferences,
+          });
         }
         this.closeRenameModal();
       }
@@ -1582,34 +2399,56 @@ Content goes here...
     },
 
     // File Operations
-    async renameItem(oldPath, newName) {
+    async renameItem(oldPath, newName, options = {}) {
       try {
         const response = await fetch(
-          `/api/files/${encodeURIComponent(oldPath)}`,
+          `/api/v1/files/${encodeURIComponent(oldPath)}?session=${this.sessionId}${this.workspaceParam("&")}`,
           {
             method: "PUT",
             headers: {
               "Content-Type": "application/json",
             },
-            body: JSON.stringify({ newName }),
+            // Aliases and references are rewritten in the live tree only
+            body: JSON.stringify(this.workspace ? { newName } : { newName, ...options }),
           },
         );
 
         if (!response.ok) {
-          const errorText = await response.text();
-          if (response.status === 409) {
-            this.showToast("Destination already exists", "error");
-          } else if (response.status === 404) {
-            this.showToast("Source does not exist", "error");
-          } else if (response.status === 400) {
-            this.showToast("Invalid path or name", "error");
-          } else {
-            this.showToast("Failed to rename: " + errorText, "error");
+          const error = await response.json();
+          switch (error.errorCode) {
+            case "ERR_EXISTS":
+              this.showToast("Destination already exists", "error");
+              break;
+            case "ERR_NOT_FOUND":
+              this.showToast("Source does not exist", "error");
+              break;
+            case "ERR_INVALID_PATH":
+              this.showToast("Invalid path or name", "error");
+              break;
+            case "ERR_LOCKED":
+              this.showToast(`Can't rename: ${error.detail}`, "error");
+              break;
+            default:
+              this.showToast("Failed to rename: " + error.detail, "error");
           }
           return;
         }
 
-        this.showToast("Renamed successfully", "success");
+        const result = await response.json();
+        if (result.warnings && result.warnings.length > 0) {
+          this.showToast("Renamed. " + result.warnings.join("; "), "warning");
+        } else if (result.alias || result.updated) {
+          const notes = [];
+          if (result.alias) {
+            notes.push(`${result.alias.url} redirects here`);
+          }
+          if (result.updated) {
+            notes.push(`links updated in ${result.updated.length} file(s)`);
+          }
+          this.showToast("Renamed, " + notes.join(", "), "success");
+        } else {
+          this.showToast("Renamed successfully", "success");
+        }
         await this.refreshFiles();
       } catch (error) {
         console.error("Error renaming item:", error);
@@ -1619,20 +2458,27 @@ Content goes here...
 
     async deleteItem(path) {
       try {
-        const response = await fetch(`/api/files/${encodeURIComponent(path)}`, {
+        const response = await fetch(`/api/v1/files/${encodeURIComponent(path)}?session=${this.sessionId}${this.workspaceParam("&")}`, {
           method: "DELETE",
         });
 
         if (!response.ok) {
-          const errorText = await response.text();
-          if (response.status === 404) {
-            this.showToast("File or directory does not exist", "error");
-          } else if (response.status === 409) {
-            this.showToast("Directory not empty", "error");
-          } else if (response.status === 400) {
-            this.showToast("Invalid path", "error");
-          } else {
-            this.showToast("Failed to delete: " + errorText, "error");
+          const error = await response.json();
+          switch (error.errorCode) {
+            case "ERR_NOT_FOUND":
+              this.showToast("File or directory does not exist", "error");
+              break;
+            case "ERR_NOT_EMPTY":
+              this.showToast("Directory not empty", "error");
+              break;
+            case "ERR_INVALID_PATH":
+              this.showToast("Invalid path", "error");
+              break;
+            case "ERR_LOCKED":
+              this.showToast(`Can't delete: ${error.detail}`, "error");
+              break;
+            default:
+              this.showToast("Failed to delete: " + error.detail, "error");
           }
           return;
         }
@@ -1653,36 +2499,88 @@ Content goes here...
     // Hugo Control
     async loadHugoStatus() {
       try {
-        const res = await fetch("/api/hugo/status");
-        this.hugoStatus = await res.json();
+        const res = await fetch("/api/v1/hugo/status");
+        this.applyHugoStatus(await res.json());
+      } catch (err) {
+        console.error("Failed to get Hugo status:", err);
+      }
+    },
+
+    // Apply a status from /api/v1/hugo/status or a "status" WebSocket message
+    applyHugoStatus(status) {
+      this.hugoStatus = { ...this.hugoStatus, ...status };
+      if (this.hugoStatus.port) {
+        this.config.hugoPort = this.hugoStatus.port;
+      }
+
+      const main = this.hugoInstances.find((i) => i.name === "main");
+      if (main) {
+        main.status = this.hugoStatus.status;
+      }
 
-        if (this.hugoStatus.status === "running" && !this.previewReady) {
-          this.previewReady = true;
+      if (this.hugoStatus.status === "running" && !this.previewReady) {
+        this.previewReady = true;
+        if (this.previewInstance === "main") {
           this.previewUrl = `http://localhost:${this.config.hugoPort}/`;
         }
-      } catch (err) {
-        console.error("Failed to get Hugo status:", err);
       }
     },
 
     async hugoStart() {
-      await fetch("/api/hugo/start", { method: "POST" });
+      await fetch("/api/v1/hugo/start", { method: "POST" });
       setTimeout(() => this.loadHugoStatus(), 1000);
     },
 
     async hugoStop() {
-      await fetch("/api/hugo/stop", { method: "POST" });
+      await fetch("/api/v1/hugo/stop", { method: "POST" });
       this.previewReady = false;
       this.previewUrl = "about:blank";
       this.loadHugoStatus();
     },
 
     async hugoRestart() {
-      await fetch("/api/hugo/restart", { method: "POST" });
+      await fetch("/api/v1/hugo/restart", { method: "POST" });
       this.previewReady = false;
       setTimeout(() => this.loadHugoStatus(), 2000);
     },
 
+    async loadHugoProfiles() {
+      try {
+        const res = await fetch("/api/v1/hugo/profiles");
+        this.hugoProfiles = (await res.json()).profiles || [];
+      } catch (err) {
+        console.error("Failed to get Hugo profiles:", err);
+      }
+    },
+
+    // Toggle one of the content flags; the server restarts Hugo when it's running
+    async hugoToggleOption(name) {
+      await this.hugoSetOptions({ [name]: !this.hugoStatus.options?.[name] });
+    },
+
+    async hugoSetOptions(update) {
+      try {
+        const res = await fetch("/api/v1/hugo/options", {
+          method: "PUT",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify(update),
+        });
+        const data = await res.json();
+        if (!res.ok) {
+          this.showToast(data.detail || "Failed to change Hugo options", "error");
+          return;
+        }
+        this.hugoStatus = { ...this.hugoStatus, options: data.options };
+        if (data.restarted) {
+          this.previewReady = false;
+          setTimeout(() => this.loadHugoStatus(), 2000);
+        }
+      } catch (err) {
+        console.error("Failed to change Hugo options:", err);
+        this.showToast("Failed to change Hugo options", "error");
+      }
+    },
+
     getStatusText() {
       switch (this.hugoStatus.status) {
         case "running":
@@ -1707,14 +2605,52 @@ Content goes here...
 
       const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
       this.ws = new WebSocket(
-        `${protocol}//${location.host}/api/hugo/ws`,
+        `${protocol}//${location.host}/api/v1/ws?topics=logs,status,files,presence,comments,instances`,
       );
 
       this.ws.onopen = () => {};
 
       this.ws.onmessage = (event) => {
-        const log = JSON.parse(event.data);
-        this.logs.push(log);
+        const msg = JSON.parse(event.data);
+        if (msg.topic === "status") {
+          if (msg.type === "build.error") {
+            this.showBuildError(msg.data);
+            return;
+          }
+          this.applyHugoStatus(msg.data);
+          return;
+        }
+        if (msg.topic === "instances") {
+          if (msg.type === "instance.status") {
+            this.applyInstanceStatus(msg.data);
+          }
+          return;
+        }
+        if (msg.topic === "presence") {
+          // Only the presence of open files is shown
+          if (this.tabs.some((t) => t.path === msg.data.path)) {
+            this.presence[msg.data.path] = msg.data;
+          }
+          return;
+        }
+        if (msg.topic === "comments") {
+          // Someone else's comments on the file under review
+          if (this.showCommentsModal && msg.data.path === this.activeTab) {
+            this.loadComments();
+          }
+          return;
+        }
+        if (msg.topic === "files") {
+          // Saves don't change the tree
+          if (msg.type !== "file.saved") {
+            this.refreshFiles();
+          }
+          return;
+        }
+        if (msg.topic !== "logs") {
+          return;
+        }
+        this.logs.push(msg.data);
         if (this.logs.length > 200) {
           this.logs = this.logs.slice(-200);
         }
@@ -1754,6 +2690,11 @@ Content goes here...
       this.logs = [];
     },
 
+    filteredLogs() {
+      if (!this.logLevel) return this.logs;
+      return this.logs.filter((log) => log.level === this.logLevel);
+    },
+
     destroy() {
       // Clean up WebSocket and reconnect timer
       if (this.reconnectTimer) {
@@ -1771,14 +2712,78 @@ Content goes here...
       return isNaN(date) ? "Invalid Date" : date.toLocaleTimeString();
     },
 
+    // Hugo instances
+    async loadHugoInstances() {
+      try {
+        const res = await fetch("/api/v1/hugo/instances");
+        this.hugoInstances = await res.json();
+      } catch (err) {
+        console.error("Failed to load Hugo instances:", err);
+      }
+    },
+
+    // Apply an instance.status message to the instance it's about
+    applyInstanceStatus(event) {
+      const instance = this.hugoInstances.find((i) => i.name === event.instance);
+      if (!instance) return;
+      instance.status = event.status;
+      instance.message = event.message;
+      if (event.port) {
+        instance.port = event.port;
+      }
+      if (instance.name === this.previewInstance && event.status === "running") {
+        this.switchPreviewInstance();
+      }
+    },
+
+    // previewPort returns the port of the instance the Preview pane shows
+    previewPort() {
+      if (this.previewInstance === "main") {
+        return this.config.hugoPort;
+      }
+      return this.hugoInstances.find((i) => i.name === this.previewInstance)?.port;
+    },
+
+    // previewRunning reports whether the instance the Preview pane shows is serving
+    previewRunning() {
+      if (this.previewInstance === "main") {
+        return this.previewReady;
+      }
+      return this.hugoInstances.find((i) => i.name === this.previewInstance)?.status === "running";
+    },
+
+    switchPreviewInstance() {
+      if (this.activeTab) {
+        this.updatePreviewUrl(this.activeTab);
+      } else if (this.previewRunning()) {
+        this.previewUrl = `http://localhost:${this.previewPort()}/`;
+      }
+    },
+
     // Preview
-    updatePreviewUrl(path) {
+    async updatePreviewUrl(path) {
       // Reset image dimensions when switching to non-image
       this.imageDimensions = null;
 
-      if (!this.previewReady) return;
+      if (!this.previewRunning()) return;
+
+      // Ask the server for the real permalink of content files
+      if (path.endsWith(".md")) {
+        try {
+          const res = await fetch(
+            `/api/v1/content/${encodeURIComponent(path)}/permalink?instance=${encodeURIComponent(this.previewInstance)}`
+          );
+          if (res.ok) {
+            const data = await res.json();
+            this.previewUrl = data.previewURL;
+            return;
+          }
+        } catch (err) {
+          console.error("Failed to resolve permalink:", err);
+        }
+      }
 
-      // Convert file path to URL
+      // Fall back to converting the file path to a URL
       let url = path
         .replace(/^content\//, "/")
         .replace(/\.md$/, "/")
@@ -1787,7 +2792,7 @@ Content goes here...
       if (!url.startsWith("/")) url = "/" + url;
       if (!url.endsWith("/")) url += "/";
 
-      this.previewUrl = `http://localhost:${this.config.hugoPort}${url}`;
+      this.previewUrl = `http://localhost:${this.previewPort()}${url}`;
     },
 
     refreshPreview() {
@@ -1827,7 +2832,7 @@ Content goes here...
     // Image Upload
     async loadImageFolders() {
       try {
-        const res = await fetch("/api/images/folders");
+        const res = await fetch("/api/v1/images/folders");
         this.imageFolders = await res.json();
         if (this.imageFolders.length > 0) {
           this.uploadOptions.folder = this.imageFolders[0].path; // Use full path instead of name
@@ -1839,7 +2844,7 @@ Content goes here...
 
     async loadImagePresets() {
       try {
-        const res = await fetch("/api/images/presets");
+        const res = await fetch("/api/v1/images/presets");
         this.imagePresets = await res.json();
       } catch (err) {
         console.error("Failed to load image presets:", err);
@@ -1870,6 +2875,7 @@ Content goes here...
     setImageFile(file) {
       this.imageFile = file;
       this.uploadOptions.filename = file.name;
+      this.uploadOptions.keepOriginal = !!this.config.images?.keep_originals;
 
       // Create preview
       const reader = new FileReader();
@@ -1879,8 +2885,13 @@ Content goes here...
       reader.readAsDataURL(file);
     },
 
+    // applyPreset shows the quality of a preset that sets one; its widths and format are applied on
+    // the server, by name
     applyPreset() {
-      // Preset is applied on server based on name
+      const preset = this.imagePresets.find((p) => p.name === this.uploadOptions.preset);
+      if (preset?.quality) {
+        this.uploadOptions.quality = preset.quality;
+      }
     },
 
     async uploadImage() {
@@ -1888,12 +2899,17 @@ Content goes here...
 
       this.uploading = true;
       this.uploadResult = null;
+      this.imageMeta = null;
 
       const formData = new FormData();
       formData.append("image", this.imageFile);
       formData.append("folder", this.uploadOptions.folder);
       formData.append("quality", this.uploadOptions.quality);
       formData.append("filename", this.uploadOptions.filename);
+      formData.append("preset", this.uploadOptions.preset);
+      if (this.uploadOptions.alt.trim()) {
+        formData.append("alt", this.uploadOptions.alt.trim());
+      }
 
       // Get widths from preset
       const preset = this.imagePresets.find(
@@ -1914,19 +2930,32 @@ Content goes here...
       if (widths.length > 0) {
         formData.append("widths", JSON.stringify(widths));
       }
+      formData.append("keepOriginal", this.uploadOptions.keepOriginal);
 
       try {
-        const res = await fetch("/api/images/upload", {
+        const res = await fetch("/api/v1/images/upload", {
           method: "POST",
           body: formData,
         });
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail || "Failed to upload image", "error");
         } else {
           this.uploadResult = data;
-          this.showToast("Image processed successfully", "success");
+          if (data.duplicates && data.duplicates.length > 0) {
+            this.showToast("Image processed. It looks like " + data.duplicates.join(", "), "warning");
+          } else {
+            this.showToast("Image processed successfully", "success");
+          }
+          // An upload started from an image field fills it in, with a path like the browser's
+          const uploaded = data.original?.startsWith("/") ? "static" + data.original : data.original;
+          this.loadImageMeta(uploaded);
+          if (this.metadataImageField) {
+            this.onImageSelectedForMetadata(uploaded);
+          } else if (this.templateImageField) {
+            this.onImageSelectedForTemplate(uploaded);
+          }
         }
       } catch (err) {
         this.showToast("Failed to upload image", "error");
@@ -1935,6 +2964,51 @@ Content goes here...
       }
     },
 
+    async loadImageMeta(path) {
+      try {
+        const res = await fetch(`/api/v1/images/${encodeURIComponent(path)}/meta`);
+        if (!res.ok) return;
+        this.imageMeta = await res.json();
+        this.imageMetaPath = path;
+      } catch (err) {
+        this.imageMeta = null;
+      }
+    },
+
+    // saveImageMeta stores the details of the uploaded image, shared by all its variants, and
+    // refreshes the snippets with the new alt text
+    async saveImageMeta() {
+      if (!this.imageMeta) return;
+      try {
+        const { alt, caption, credit, license } = this.imageMeta;
+        const res = await fetch(
+          `/api/v1/images/${encodeURIComponent(this.imageMetaPath)}/meta`,
+          {
+            method: "PUT",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ alt, caption, credit, license }),
+          },
+        );
+        const data = await res.json();
+        if (!res.ok) {
+          this.showToast(data.detail || "Failed to save image details", "error");
+          return;
+        }
+        this.imageMeta = data;
+        this.showToast("Image details saved", "success");
+
+        const processed = await fetch(
+          `/api/v1/images/processed?path=${encodeURIComponent(this.imageMetaPath)}`,
+        );
+        if (processed.ok) {
+          const result = await processed.json();
+          this.uploadResult = { ...this.uploadResult, shortcode: result.shortcode, html: result.html, warnings: result.warnings };
+        }
+      } catch (err) {
+        this.showToast("Failed to save image details", "error");
+      }
+    },
+
     // File Upload Functions
     handleFileSelect(event, type) {
       const file = event.target.files[0];
@@ -1964,14 +3038,14 @@ Content goes here...
       formData.append("filename", this.fileUploadOptions.filename);
 
       try {
-        const res = await fetch("/api/files/upload", {
+        const res = await fetch("/api/v1/files/upload", {
           method: "POST",
           body: formData,
         });
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
         } else {
           this.fileUploadResult = data;
           this.showToast("File uploaded successfully", "success");
@@ -2019,14 +3093,14 @@ Content goes here...
       }
 
       try {
-        const res = await fetch("/api/images/process", {
+        const res = await fetch("/api/v1/images/process", {
           method: "POST",
           body: formData,  // FormData sets Content-Type automatically
         });
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail || "Failed to process image", "error");
         } else {
           this.processResult = data;
           this.showToast("Image processed successfully", "success");
@@ -2050,14 +3124,14 @@ Content goes here...
       formData.append("folder", this.copyOptions.folder);
 
       try {
-        const res = await fetch("/api/files/copy", {
+        const res = await fetch("/api/v1/files/copy", {
           method: "POST",
           body: formData,
         });
 
         const data = await res.json();
-        if (data.error) {
-          this.showToast(data.error, "error");
+        if (!res.ok) {
+          this.showToast(data.detail, "error");
         } else {
           this.showToast("File copied successfully", "success");
           this.showCopyModal = false;
@@ -2076,7 +3150,7 @@ Content goes here...
       this.fileSelectorCallback = callback;
 
       try {
-        const res = await fetch(`/api/data/${dataType}`);
+        const res = await fetch(`/api/v1/data/${dataType}`);
         this.dataFiles = await res.json();
         this.showFileSelector = true;
       } catch (err) {